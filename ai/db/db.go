@@ -14,9 +14,10 @@ var (
 	Client      *mongo.Client
 	Database    *mongo.Database
 	Collections struct {
-		Categories *mongo.Collection
-		Documents  *mongo.Collection
-		Formats    *mongo.Collection
+		Categories      *mongo.Collection
+		Documents       *mongo.Collection
+		Formats         *mongo.Collection
+		ExtractionCache *mongo.Collection
 	}
 )
 
@@ -52,6 +53,7 @@ func Connect(config Config) error {
 	Collections.Categories = Database.Collection("categories")
 	Collections.Documents = Database.Collection("documents")
 	Collections.Formats = Database.Collection("formats")
+	Collections.ExtractionCache = Database.Collection("extraction_cache")
 
 	log.Printf("Successfully connected to MongoDB: %s", config.DatabaseName)
 	return nil
@@ -3,11 +3,11 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -60,6 +60,17 @@ func getCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(categories)
 }
 
+// writeUploadError answers a rejected upload with the code of the reason.
+func writeUploadError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"code":    code,
+		"message": message,
+	})
+}
+
 func uploadDocumentHandler(w http.ResponseWriter, r *http.Request) {
 	enableCORS(w)
 
@@ -73,13 +84,22 @@ func uploadDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form (10MB max)
+	// Reject bodies larger than the upload limit before reading them
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxUploadBytes+1<<20)
 	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
-		http.Error(w, "File too large or invalid form data", http.StatusBadRequest)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeUploadError(w, http.StatusRequestEntityTooLarge, service.UploadFileTooLarge, fmt.Sprintf("The file is larger than the limit of %d bytes", service.MaxUploadBytes))
+			return
+		}
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
 		return
 	}
 
+	// force_refresh bypasses the extraction cache
+	forceRefresh := r.FormValue("force_refresh") == "true"
+
 	// Get the file from form
 	file, header, err := r.FormFile("file")
 	if err != nil {
@@ -88,50 +108,65 @@ func uploadDocumentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Validate file type
-	if !strings.HasSuffix(strings.ToLower(header.Filename), ".pdf") {
-		http.Error(w, "Only PDF files are allowed", http.StatusBadRequest)
+	content, err := io.ReadAll(io.LimitReader(file, service.MaxUploadBytes+1))
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
 		return
 	}
-
-	uploadDir := "./uploads"
-	// Remove the directory if it already exists (and any contents).
-	if err := os.RemoveAll(uploadDir); err != nil {
-		http.Error(w, "Failed to remove existing upload directory: "+err.Error(), http.StatusInternalServerError)
+	if len(content) > service.MaxUploadBytes {
+		writeUploadError(w, http.StatusRequestEntityTooLarge, service.UploadFileTooLarge, fmt.Sprintf("The file is larger than the limit of %d bytes", service.MaxUploadBytes))
 		return
 	}
 
-	// Create the new, empty directory.
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		http.Error(w, "Failed to create upload directory: "+err.Error(), http.StatusInternalServerError)
+	// Validate file type from its content, not its name
+	if !service.IsPDF(content) {
+		writeUploadError(w, http.StatusUnsupportedMediaType, service.UploadUnsupportedType, "Only PDF files are allowed")
 		return
 	}
 
-	// Generate unique filename
-	timestamp := time.Now().Unix()
-	fileName := fmt.Sprintf("%d_%s", timestamp, header.Filename)
-	filePath := filepath.Join(uploadDir, fileName)
-	fmt.Printf("filepath: %s\n", filePath)
-
-	// Save file to disk
-	dst, err := os.Create(filePath)
+	signature, err := service.ScanForMalware(r.Context(), content)
 	if err != nil {
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		log.Printf("Error scanning %s for malware: %v", header.Filename, err)
+		writeUploadError(w, http.StatusServiceUnavailable, service.UploadMalwareScanFailed, "The file could not be scanned for malware, try again later")
+		return
+	}
+	if signature != "" {
+		path, err := service.Quarantine(header.Filename, content)
+		if err != nil {
+			log.Printf("Error quarantining infected file %s: %v", header.Filename, err)
+		}
+		log.Printf("Malware %s found in %s, quarantined as %s", signature, header.Filename, path)
+		writeUploadError(w, http.StatusUnprocessableEntity, service.UploadMalwareDetected, "The file contains malware and was rejected")
 		return
 	}
-	defer dst.Close()
 
-	fileSize, err := io.Copy(dst, file)
+	// Stage the file in a directory of its own until its fields are saved;
+	// it is removed if the upload fails or is never completed.
+	uploadID := primitive.NewObjectID()
+	fileName := filepath.Base(header.Filename)
+	filePath, err := service.StageUpload(uploadID.Hex(), fileName, content)
 	if err != nil {
+		log.Printf("Error staging upload: %v", err)
 		http.Error(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
+	staged := false
+	defer func() {
+		if !staged {
+			service.DiscardUpload(uploadID.Hex())
+		}
+	}()
+	fileSize := int64(len(content))
 
 	pages := npdfpages.PagesAtPath(filePath)
 	fmt.Printf("The PDF file '%s' has %d pages.\n", filePath, pages)
+	if pages > service.MaxUploadPages {
+		writeUploadError(w, http.StatusUnprocessableEntity, service.UploadTooManyPages, fmt.Sprintf("The file has more than %d pages", service.MaxUploadPages))
+		return
+	}
 
 	// Extract and analyse PDF content
-	category, err := service.Docservice(filePath)
+	category, err := service.Docservice(filePath, forceRefresh)
 	fmt.Printf("CATEGORY: %s\n", category)
 	fmt.Printf("MetaData: %s\n", category.Metadata)
 
@@ -158,7 +193,7 @@ func uploadDocumentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract metadata
-	extractedData, err := service.DocMetaDataService(filePath)
+	extractedData, err := service.DocMetaDataService(filePath, forceRefresh)
 	if err != nil {
 		log.Printf("Error extracting metadata: %v", err)
 		http.Error(w, "Failed to extract metadata", http.StatusInternalServerError)
@@ -180,10 +215,32 @@ func uploadDocumentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	fmt.Println("Extracted fields:", extractedFields)
 
-	// Prepare response without creating category or document yet
+	// Keep the document for the fields step, without a category yet if it is new
+	document := &models.Document{
+		ID:         uploadID,
+		CategoryID: primitive.NilObjectID,
+		Status:     models.DocumentStatusStaged,
+		FileName:   fileName,
+		FilePath:   filePath,
+		FileSize:   fileSize,
+		PageCount:  pages,
+		CreatedAt:  time.Now(),
+	}
+	if existingCategory != nil {
+		document.CategoryID = existingCategory.ID
+	}
+	if err := documentRepo.Create(ctx, document); err != nil {
+		log.Printf("Error creating document: %v", err)
+		http.Error(w, "Failed to save document", http.StatusInternalServerError)
+		return
+	}
+	staged = true
+
+	// Prepare response without creating category yet
 	response := models.DocumentUploadResponse{
 		Success:         true,
 		Message:         "Document processed successfully - awaiting schema approval",
+		UploadID:        uploadID.Hex(),
 		CategoryName:    categoryName,
 		CategoryID:      primitive.NilObjectID,
 		FileName:        fileName,
@@ -207,7 +264,7 @@ func uploadDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Error encoding response: %v", err)
 	}
 
-	log.Printf("File processed: %s, Category: %s, IsNew: %v", fileName, categoryName, existingCategory == nil)
+	log.Printf("File processed: %s, Upload: %s, Category: %s, IsNew: %v", fileName, uploadID.Hex(), categoryName, existingCategory == nil)
 }
 
 func ExtractFieldsFromWrapper(wrapper models.ExtractedFieldsWrapper) []models.Field {
@@ -515,6 +572,7 @@ func saveExtractedFieldsHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		ExtractedFields interface{} `json:"extractedFields"`
 		CategorySummary string      `json:"categorySummary,omitempty"`
+		UploadID        string      `json:"uploadId,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -527,6 +585,46 @@ func saveExtractedFieldsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The uploaded document the fields were extracted from, if any. It is
+	// claimed before anything is written so that a single request saves its
+	// fields, and given back if saving them fails.
+	var document *models.Document
+	linked := false
+	if req.UploadID != "" {
+		uploadID, err := primitive.ObjectIDFromHex(req.UploadID)
+		if err != nil {
+			http.Error(w, "Invalid uploadId", http.StatusBadRequest)
+			return
+		}
+		document, err = documentRepo.Claim(ctx, uploadID, time.Now().Add(-service.UploadTTL))
+		if errors.Is(err, repository.ErrDocumentNotStaged) {
+			if existing, _ := documentRepo.GetByID(ctx, uploadID); existing != nil && existing.Status != models.DocumentStatusStaged {
+				http.Error(w, "The fields of this upload were already saved", http.StatusConflict)
+			} else {
+				http.Error(w, "Upload not found or expired, upload the document again", http.StatusGone)
+			}
+			return
+		}
+		if err != nil {
+			log.Printf("Error claiming document: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer func() {
+			if linked {
+				return
+			}
+			unclaimCtx, unclaimCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer unclaimCancel()
+			if err := documentRepo.Unclaim(unclaimCtx, document.ID); err != nil {
+				log.Printf("Error unclaiming document %s: %v", document.ID.Hex(), err)
+			}
+		}()
+	}
+
 	// Convert extractedFields to map[string]interface{}
 	extractedFieldsMap, err := convertExtractedFields(req.ExtractedFields)
 	if err != nil {
@@ -536,9 +634,6 @@ func saveExtractedFieldsHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Extracted fields map: %v", extractedFieldsMap)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	var category *models.Category
 	var objectID primitive.ObjectID
 	isNewCategory := false
@@ -655,6 +750,28 @@ func saveExtractedFieldsHandler(w http.ResponseWriter, r *http.Request) {
 			"providedKeys":  newFieldKeys,
 		}
 
+		if document != nil {
+			// Link the document to the format with the same keys, if there is one
+			formats, err := formatRepo.FindByCategoryID(ctx, objectID)
+			if err != nil {
+				log.Printf("Error fetching formats: %v", err)
+				http.Error(w, "Failed to fetch formats: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			formatID := primitive.NilObjectID
+			if format := findFormatByKeys(formats, newFieldKeys); format != nil {
+				formatID = format.ID
+				response["formatId"] = format.ID.Hex()
+			}
+			if err := linkDocument(ctx, document, objectID, formatID); err != nil {
+				log.Printf("Error linking document %s: %v", document.ID.Hex(), err)
+				http.Error(w, "Failed to link document", http.StatusInternalServerError)
+				return
+			}
+			linked = true
+			response["documentId"] = document.ID.Hex()
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(response)
@@ -726,6 +843,15 @@ func saveExtractedFieldsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if document != nil {
+		if err := linkDocument(ctx, document, objectID, newFormat.ID); err != nil {
+			log.Printf("Error linking document %s: %v", document.ID.Hex(), err)
+			http.Error(w, "Failed to link document", http.StatusInternalServerError)
+			return
+		}
+		linked = true
+	}
+
 	var message string
 	if isNewCategory {
 		message = fmt.Sprintf("New category created with Format 1")
@@ -749,12 +875,45 @@ func saveExtractedFieldsHandler(w http.ResponseWriter, r *http.Request) {
 		"newFieldCount":   len(newFieldNames),
 		"totalFieldCount": len(newFieldKeys),
 	}
+	if document != nil {
+		response["documentId"] = document.ID.Hex()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
 
+// linkDocument moves the staged file of the claimed document to the documents
+// of its category and links the document to the category and format.
+func linkDocument(ctx context.Context, document *models.Document, categoryID primitive.ObjectID, formatID primitive.ObjectID) error {
+	filePath, err := service.KeepUpload(document.ID.Hex(), document.FilePath, categoryID.Hex())
+	if err != nil {
+		return err
+	}
+	return documentRepo.Link(ctx, document.ID, categoryID, formatID, filePath)
+}
+
+// sweepStagedUploads removes the uploads whose fields were not saved within
+// the TTL, with their documents.
+func sweepStagedUploads() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		if removed, err := service.CleanStaleUploads(service.UploadTTL); err != nil {
+			log.Printf("Error removing stale uploads: %v", err)
+		} else if removed > 0 {
+			log.Printf("Removed %d stale uploads", removed)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if _, err := documentRepo.DeleteStagedBefore(ctx, time.Now().Add(-service.UploadTTL)); err != nil {
+			log.Printf("Error removing stale documents: %v", err)
+		}
+		cancel()
+	}
+}
+
 // Helper function to infer field type from value
 func inferFieldType(value interface{}) models.FieldType {
 	if value == nil {
@@ -869,6 +1028,9 @@ func main() {
 	categoryRepo = repository.NewCategoryRepository()
 	documentRepo = repository.NewDocumentRepository()
 	formatRepo = repository.NewFormatRepository()
+	service.UseExtractionCacheStore(repository.NewExtractionCacheRepository())
+
+	go sweepStagedUploads()
 
 	// Routes
 	http.HandleFunc("/api/categories", func(w http.ResponseWriter, r *http.Request) {
@@ -118,11 +118,13 @@ const (
 	FieldTypeTable       FieldType = "table"
 )
 
-// Document represents an uploaded document
+// Document represents an uploaded document. Its ID is the upload ID the
+// client sends back when it saves the extracted fields.
 type Document struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	CategoryID primitive.ObjectID `bson:"categoryId" json:"categoryId"`
 	FormatID   primitive.ObjectID `bson:"formatId,omitempty" json:"formatId,omitempty"`
+	Status     DocumentStatus     `bson:"status" json:"status"`
 	FileName   string             `bson:"fileName" json:"fileName"`
 	FilePath   string             `bson:"filePath" json:"filePath"`
 	FileSize   int64              `bson:"fileSize" json:"fileSize"`
@@ -131,6 +133,17 @@ type Document struct {
 	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
 
+type DocumentStatus string
+
+const (
+	// DocumentStatusStaged is an upload whose fields are not saved yet.
+	DocumentStatusStaged DocumentStatus = "staged"
+	// DocumentStatusLinking is an upload whose fields are being saved.
+	DocumentStatusLinking DocumentStatus = "linking"
+	// DocumentStatusLinked is a document linked to its category and format.
+	DocumentStatusLinked DocumentStatus = "linked"
+)
+
 type KeyValue struct {
 	Key         string `json:"key"`
 	Value       string `json:"value"`
@@ -145,6 +158,7 @@ type ExtractedFieldsWrapper struct {
 type DocumentUploadResponse struct {
 	Success         bool                   `json:"success"`
 	Message         string                 `json:"message"`
+	UploadID        string                 `json:"uploadId"`
 	CategoryName    string                 `json:"categoryName"`
 	CategoryID      primitive.ObjectID     `json:"categoryId,omitempty"`
 	FileName        string                 `json:"fileName"`
@@ -156,6 +170,20 @@ type DocumentUploadResponse struct {
 	IsNewCategory   bool                   `json:"isNewCategory"`
 }
 
+// ExtractionCacheEntry is a cached LLM response keyed by document content, format, prompt version and model
+type ExtractionCacheEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key           string             `bson:"key" json:"key"`
+	DocumentHash  string             `bson:"documentHash" json:"documentHash"`
+	FormatID      string             `bson:"formatId" json:"formatId"`
+	PromptVersion string             `bson:"promptVersion" json:"promptVersion"`
+	Model         string             `bson:"model" json:"model"`
+	Response      string             `bson:"response" json:"response"`
+	HitCount      int                `bson:"hitCount" json:"hitCount"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
 // CategoryWithFormats represents a category with all its formats
 type CategoryWithFormats struct {
 	Category
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/gaeaglobal/exto/ai/db"
@@ -10,8 +11,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrDocumentNotStaged is returned when claiming a document that is not
+// staged, or linking one that was not claimed.
+var ErrDocumentNotStaged = errors.New("document is not staged")
+
 type DocumentRepository struct {
 	collection *mongo.Collection
 }
@@ -68,6 +74,74 @@ func (r *DocumentRepository) GetByID(ctx context.Context, id primitive.ObjectID)
 	return &document, nil
 }
 
+// Claim marks the document staged since the cutoff as being linked, so that
+// a single request saves its fields. It returns ErrDocumentNotStaged when the
+// document was claimed, linked or removed, or was staged before the cutoff.
+func (r *DocumentRepository) Claim(ctx context.Context, id primitive.ObjectID, cutoff time.Time) (*models.Document, error) {
+	filter := bson.M{
+		"_id":       id,
+		"status":    models.DocumentStatusStaged,
+		"createdAt": bson.M{"$gte": cutoff},
+	}
+	update := bson.M{"$set": bson.M{"status": models.DocumentStatusLinking, "updatedAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var document models.Document
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrDocumentNotStaged
+		}
+		return nil, err
+	}
+	return &document, nil
+}
+
+// Unclaim gives back a claimed document that could not be linked, so that
+// its fields can be saved again.
+func (r *DocumentRepository) Unclaim(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.DocumentStatusLinking},
+		bson.M{"$set": bson.M{"status": models.DocumentStatusStaged, "updatedAt": time.Now()}})
+	return err
+}
+
+// Link links the claimed document to its category and format, with the path
+// its file was moved to. It returns ErrDocumentNotStaged when the document was
+// not claimed.
+func (r *DocumentRepository) Link(ctx context.Context, id primitive.ObjectID, categoryID primitive.ObjectID, formatID primitive.ObjectID, filePath string) error {
+	set := bson.M{
+		"categoryId": categoryID,
+		"status":     models.DocumentStatusLinked,
+		"filePath":   filePath,
+		"updatedAt":  time.Now(),
+	}
+	if !formatID.IsZero() {
+		set["formatId"] = formatID
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": models.DocumentStatusLinking}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrDocumentNotStaged
+	}
+	return nil
+}
+
+// DeleteStagedBefore removes the staged documents uploaded before the cutoff,
+// and the documents claimed before it whose linking never completed.
+func (r *DocumentRepository) DeleteStagedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"status": models.DocumentStatusStaged, "createdAt": bson.M{"$lt": cutoff}},
+		bson.M{"status": models.DocumentStatusLinking, "updatedAt": bson.M{"$lt": cutoff}},
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
 // Delete removes a document
 func (r *DocumentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/gaeaglobal/exto/ai/db"
+	"github.com/gaeaglobal/exto/ai/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ExtractionCacheRepository struct {
+	collection *mongo.Collection
+}
+
+// NewExtractionCacheRepository creates a new extraction cache repository
+func NewExtractionCacheRepository() *ExtractionCacheRepository {
+	return &ExtractionCacheRepository{
+		collection: db.Collections.ExtractionCache,
+	}
+}
+
+// FindByKey retrieves a cached response by its key, returns nil if not cached
+func (r *ExtractionCacheRepository) FindByKey(ctx context.Context, key string) (*models.ExtractionCacheEntry, error) {
+	var entry models.ExtractionCacheEntry
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"key": key},
+		bson.M{"$inc": bson.M{"hitCount": 1}},
+	).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Upsert stores the response for the entry key, replacing any previous response
+func (r *ExtractionCacheRepository) Upsert(ctx context.Context, entry *models.ExtractionCacheEntry) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"documentHash":  entry.DocumentHash,
+			"formatId":      entry.FormatID,
+			"promptVersion": entry.PromptVersion,
+			"model":         entry.Model,
+			"response":      entry.Response,
+			"updatedAt":     now,
+		},
+		"$setOnInsert": bson.M{
+			"_id":       primitive.NewObjectID(),
+			"hitCount":  0,
+			"createdAt": now,
+		},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"key": entry.Key}, update, options.Update().SetUpsert(true))
+	return err
+}
@@ -12,7 +12,7 @@ import (
 	"strings"
 )
 
-func DocMetaDataService(pdfPath string) (map[string]interface{}, error) {
+func DocMetaDataService(pdfPath string, forceRefresh bool) (map[string]interface{}, error) {
 
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
@@ -24,12 +24,12 @@ func DocMetaDataService(pdfPath string) (map[string]interface{}, error) {
 
 	// Check if PDF exists
 	if _, err := os.Stat(pdfPath); os.IsNotExist(err) {
-		log.Fatal("Error: PDF file '%s' not found\n", pdfPath)
+		log.Fatalf("Error: PDF file '%s' not found\n", pdfPath)
 	}
 
 	// Extract ALL key-value,description pairs from the PDF
 	fmt.Println("=== Extracting ALL key-value pairs from PDF ===")
-	extractedData, err := ExtractDataFromPDF(apiKey, pdfPath)
+	extractedData, err := ExtractDataFromPDF(apiKey, pdfPath, forceRefresh)
 	if err != nil {
 		log.Fatal("Error extracting data:", err)
 	}
@@ -77,8 +77,9 @@ type OpenAIResponse struct {
 	} `json:"output"`
 }
 
-// ExtractDataFromPDF uses OpenAI Responses API to extract all key-value pairs from a PDF
-func ExtractDataFromPDF(apiKey, pdfPath string) (map[string]interface{}, error) {
+// ExtractDataFromPDF uses OpenAI Responses API to extract all key-value pairs from a PDF.
+// Responses are cached by PDF content unless forceRefresh is set.
+func ExtractDataFromPDF(apiKey, pdfPath string, forceRefresh bool) (map[string]interface{}, error) {
 	// Read PDF and convert to base64
 	fmt.Println("Reading PDF file...")
 	pdfBytes, err := os.ReadFile(pdfPath)
@@ -86,8 +87,39 @@ func ExtractDataFromPDF(apiKey, pdfPath string) (map[string]interface{}, error)
 		return nil, fmt.Errorf("failed to read PDF: %w", err)
 	}
 
-	base64PDF := base64.StdEncoding.EncodeToString(pdfBytes)
+	cacheKey := ExtractionCacheKey{
+		DocumentHash:  hashBytes(pdfBytes),
+		PromptVersion: metadataPromptVersion,
+		Model:         "gpt-4o",
+	}
+
+	content, cached := "", false
+	if !forceRefresh {
+		content, cached = responseCache.get(cacheKey)
+	}
+	if !cached {
+		content, err = requestPDFExtraction(apiKey, base64.StdEncoding.EncodeToString(pdfBytes))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		fmt.Println("Using cached extraction response")
+	}
+
+	// Parse the extracted data
+	var extractedData map[string]interface{}
+	err = json.Unmarshal([]byte(content), &extractedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse extracted data: %w\nCleaned Content: %s", err, content)
+	}
+	if !cached {
+		responseCache.put(cacheKey, content)
+	}
+	return extractedData, nil
+}
 
+// requestPDFExtraction sends the PDF to the OpenAI Responses API and returns the cleaned JSON content
+func requestPDFExtraction(apiKey, base64PDF string) (string, error) {
 	// 	// Create the prompt for extraction
 	// 	prompt := `Analyze this document and extract ALL key-value pairs you can find.
 
@@ -161,14 +193,14 @@ func ExtractDataFromPDF(apiKey, pdfPath string) (map[string]interface{}, error)
 	// Marshal request to JSON
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request to the Responses API endpoint
 	fmt.Println("Sending request to OpenAI Responses API...")
 	req, err := http.NewRequest("POST", "https://api.openai.com/v1/responses", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -178,26 +210,26 @@ func ExtractDataFromPDF(apiKey, pdfPath string) (map[string]interface{}, error)
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return "", fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Check for non-200 status codes
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API error (status %d): %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("OpenAI API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	// Parse OpenAI response
 	var openaiResp OpenAIResponse
 	err = json.Unmarshal(body, &openaiResp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w\nResponse body: %s", err, string(body))
+		return "", fmt.Errorf("failed to parse response: %w\nResponse body: %s", err, string(body))
 	}
 
 	// Get content from the response - try multiple fields
@@ -207,7 +239,7 @@ func ExtractDataFromPDF(apiKey, pdfPath string) (map[string]interface{}, error)
 	}
 
 	if content == "" {
-		return nil, fmt.Errorf("empty response from OpenAI. Full response: %s", string(body))
+		return "", fmt.Errorf("empty response from OpenAI. Full response: %s", string(body))
 	}
 
 	// Remove markdown code blocks if present
@@ -224,13 +256,7 @@ func ExtractDataFromPDF(apiKey, pdfPath string) (map[string]interface{}, error)
 		content = content[startIdx : endIdx+1]
 	}
 
-	// Parse the extracted data
-	var extractedData map[string]interface{}
-	err = json.Unmarshal([]byte(content), &extractedData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse extracted data: %w\nCleaned Content: %s", err, content)
-	}
-	return extractedData, nil
+	return content, nil
 }
 
 // Flatten takes a nested map and returns a new one where nested maps are replaced
@@ -15,7 +15,7 @@ import (
 
 var OPENAI_KEY = os.Getenv("OPENAI_API_KEY")
 
-func Docservice(pdfPath string) (*PDFCategory, error) {
+func Docservice(pdfPath string, forceRefresh bool) (*PDFCategory, error) {
 
 	// Set PDF path directly or use command line
 	// pdfPath := "C:/projects/exto-go/ai/uploads/1765249316_Bikaner-part-11.pdf" // Change this to your PDF path
@@ -37,7 +37,7 @@ func Docservice(pdfPath string) (*PDFCategory, error) {
 	fmt.Printf("Extracted %d pages, %d characters\n", pageCount, len(text))
 
 	// Categorize using OpenAI
-	category, err := categorizePDF(text, apiKey)
+	category, err := categorizePDFCached(text, apiKey, forceRefresh)
 	if err != nil {
 		log.Fatalf("Error categorizing PDF: %v", err)
 	}
@@ -132,6 +132,32 @@ func extractTextFromPDF(pdfPath string) (string, int, error) {
 	return textBuilder.String(), totalPages, nil
 }
 
+// categorizePDFCached reuses the categorization of identical PDF text unless forceRefresh is set
+func categorizePDFCached(text string, apiKey string, forceRefresh bool) (*PDFCategory, error) {
+	cacheKey := ExtractionCacheKey{
+		DocumentHash:  hashBytes([]byte(text)),
+		PromptVersion: categoryPromptVersion,
+		Model:         "gpt-4o-mini",
+	}
+	if !forceRefresh {
+		if cached, ok := responseCache.get(cacheKey); ok {
+			var category PDFCategory
+			if err := json.Unmarshal([]byte(cached), &category); err == nil {
+				return &category, nil
+			}
+		}
+	}
+
+	category, err := categorizePDF(text, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(category); err == nil {
+		responseCache.put(cacheKey, string(data))
+	}
+	return category, nil
+}
+
 // categorizePDF sends the PDF text to OpenAI for categorization
 func categorizePDF(text string, apiKey string) (*PDFCategory, error) {
 	// Limit text length to avoid token limits (approximately 6000 words)
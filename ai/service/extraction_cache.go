@@ -0,0 +1,150 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaeaglobal/exto/ai/models"
+)
+
+// Bump these whenever the corresponding prompt changes so stale responses are not reused.
+const (
+	metadataPromptVersion = "metadata-v1"
+	categoryPromptVersion = "category-v1"
+)
+
+// ExtractionCacheStore is the persistent tier of the extraction cache
+type ExtractionCacheStore interface {
+	FindByKey(ctx context.Context, key string) (*models.ExtractionCacheEntry, error)
+	Upsert(ctx context.Context, entry *models.ExtractionCacheEntry) error
+}
+
+// ExtractionCacheKey identifies a cached LLM response
+type ExtractionCacheKey struct {
+	DocumentHash  string
+	FormatID      string
+	PromptVersion string
+	Model         string
+}
+
+func (k ExtractionCacheKey) String() string {
+	return hashBytes([]byte(strings.Join([]string{k.DocumentHash, k.FormatID, k.PromptVersion, k.Model}, "|")))
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// extractionCache is an in-memory LRU in front of an optional persistent store
+type extractionCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+	store    ExtractionCacheStore
+}
+
+type lruItem struct {
+	key   string
+	value string
+}
+
+var responseCache = newExtractionCache(512)
+
+func newExtractionCache(capacity int) *extractionCache {
+	return &extractionCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// UseExtractionCacheStore sets the persistent tier used behind the in-memory cache
+func UseExtractionCacheStore(store ExtractionCacheStore) {
+	responseCache.mu.Lock()
+	defer responseCache.mu.Unlock()
+	responseCache.store = store
+}
+
+func (c *extractionCache) get(key ExtractionCacheKey) (string, bool) {
+	k := key.String()
+
+	c.mu.Lock()
+	if el, ok := c.items[k]; ok {
+		c.order.MoveToFront(el)
+		value := el.Value.(*lruItem).value
+		c.mu.Unlock()
+		return value, true
+	}
+	store := c.store
+	c.mu.Unlock()
+
+	if store == nil {
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry, err := store.FindByKey(ctx, k)
+	if err != nil {
+		log.Printf("Warning: failed to read extraction cache: %v", err)
+		return "", false
+	}
+	if entry == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	c.add(k, entry.Response)
+	c.mu.Unlock()
+	return entry.Response, true
+}
+
+func (c *extractionCache) put(key ExtractionCacheKey, response string) {
+	k := key.String()
+
+	c.mu.Lock()
+	c.add(k, response)
+	store := c.store
+	c.mu.Unlock()
+
+	if store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := store.Upsert(ctx, &models.ExtractionCacheEntry{
+		Key:           k,
+		DocumentHash:  key.DocumentHash,
+		FormatID:      key.FormatID,
+		PromptVersion: key.PromptVersion,
+		Model:         key.Model,
+		Response:      response,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to write extraction cache: %v", err)
+	}
+}
+
+// add must be called with c.mu held
+func (c *extractionCache) add(key, value string) {
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruItem).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruItem{key: key, value: value})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gaeaglobal/exto/ai/models"
+)
+
+// memoryCacheStore stands in for the Mongo tier of the cache and counts its
+// reads.
+type memoryCacheStore struct {
+	entries map[string]*models.ExtractionCacheEntry
+	reads   int
+}
+
+func newMemoryCacheStore() *memoryCacheStore {
+	return &memoryCacheStore{entries: map[string]*models.ExtractionCacheEntry{}}
+}
+
+func (s *memoryCacheStore) FindByKey(_ context.Context, key string) (*models.ExtractionCacheEntry, error) {
+	s.reads++
+	return s.entries[key], nil
+}
+
+func (s *memoryCacheStore) Upsert(_ context.Context, entry *models.ExtractionCacheEntry) error {
+	s.entries[entry.Key] = entry
+	return nil
+}
+
+func TestExtractionCacheKeyComposition(t *testing.T) {
+	base := ExtractionCacheKey{
+		DocumentHash:  hashBytes([]byte("document")),
+		FormatID:      "format-1",
+		PromptVersion: metadataPromptVersion,
+		Model:         "gpt-4o",
+	}
+	if base.String() != base.String() || len(base.String()) != 64 {
+		t.Fatalf("expected a stable sha256 key, got %q", base.String())
+	}
+
+	for name, key := range map[string]ExtractionCacheKey{
+		"document":       {hashBytes([]byte("other document")), base.FormatID, base.PromptVersion, base.Model},
+		"format":         {base.DocumentHash, "format-2", base.PromptVersion, base.Model},
+		"prompt version": {base.DocumentHash, base.FormatID, categoryPromptVersion, base.Model},
+		"model":          {base.DocumentHash, base.FormatID, base.PromptVersion, "gpt-4o-mini"},
+	} {
+		if key.String() == base.String() {
+			t.Errorf("expected a change of %s to change the key", name)
+		}
+	}
+}
+
+func TestExtractionCacheFallsThroughToStore(t *testing.T) {
+	store := newMemoryCacheStore()
+	cache := newExtractionCache(1)
+	cache.store = store
+	first := ExtractionCacheKey{DocumentHash: hashBytes([]byte("first")), PromptVersion: metadataPromptVersion, Model: "gpt-4o"}
+	second := ExtractionCacheKey{DocumentHash: hashBytes([]byte("second")), PromptVersion: metadataPromptVersion, Model: "gpt-4o"}
+
+	// Written through to the store, and served from memory afterwards.
+	cache.put(first, "first response")
+	if _, stored := store.entries[first.String()]; !stored {
+		t.Fatal("expected put to write the entry to the store")
+	}
+	if response, found := cache.get(first); !found || response != "first response" || store.reads != 0 {
+		t.Fatalf("expected a memory hit, got %q, %v after %d store reads", response, found, store.reads)
+	}
+
+	// Evicted from memory by the next entry, it is read back from the store.
+	cache.put(second, "second response")
+	if response, found := cache.get(first); !found || response != "first response" || store.reads != 1 {
+		t.Fatalf("expected a store hit, got %q, %v after %d store reads", response, found, store.reads)
+	}
+	delete(store.entries, first.String())
+	if _, found := cache.get(first); !found || store.reads != 1 {
+		t.Errorf("expected the entry read from the store to be kept in memory, after %d store reads", store.reads)
+	}
+
+	missing := ExtractionCacheKey{DocumentHash: hashBytes([]byte("missing")), PromptVersion: metadataPromptVersion, Model: "gpt-4o"}
+	if _, found := cache.get(missing); found {
+		t.Error("expected a miss in both tiers")
+	}
+}
+
+func TestExtractionCacheInvalidation(t *testing.T) {
+	cache := newExtractionCache(8)
+	cache.store = newMemoryCacheStore()
+	key := ExtractionCacheKey{DocumentHash: hashBytes([]byte("document")), PromptVersion: "metadata-v1", Model: "gpt-4o"}
+	cache.put(key, "response")
+
+	bumped := key
+	bumped.PromptVersion = "metadata-v2"
+	if _, found := cache.get(bumped); found {
+		t.Error("expected a new prompt version to miss the cache")
+	}
+	otherModel := key
+	otherModel.Model = "gpt-4.1"
+	if _, found := cache.get(otherModel); found {
+		t.Error("expected a new model to miss the cache")
+	}
+	if response, found := cache.get(key); !found || response != "response" {
+		t.Errorf("expected the original key to still hit, got %q, %v", response, found)
+	}
+}
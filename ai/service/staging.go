@@ -0,0 +1,79 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The uploads are staged in a directory of their own under UploadDir until
+// their fields are saved, when they move to DocumentDir/<category>/. Staged
+// uploads older than UploadTTL are removed.
+const (
+	UploadDir   = "./uploads"
+	DocumentDir = "./documents"
+	UploadTTL   = 24 * time.Hour
+)
+
+// StageUpload saves the content of the upload in its staging directory and
+// returns the path of the file.
+func StageUpload(uploadID string, fileName string, content []byte) (string, error) {
+	dir := filepath.Join(UploadDir, uploadID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	filePath := filepath.Join(dir, filepath.Base(fileName))
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	return filePath, nil
+}
+
+// DiscardUpload removes the staging directory of the upload.
+func DiscardUpload(uploadID string) error {
+	return os.RemoveAll(filepath.Join(UploadDir, uploadID))
+}
+
+// KeepUpload moves the staged file of the upload to the documents of the
+// category and returns its new path.
+func KeepUpload(uploadID string, stagedPath string, categoryID string) (string, error) {
+	dir := filepath.Join(DocumentDir, categoryID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create document directory: %w", err)
+	}
+	filePath := filepath.Join(dir, uploadID+"_"+filepath.Base(stagedPath))
+	if err := os.Rename(stagedPath, filePath); err != nil {
+		return "", fmt.Errorf("failed to move file: %w", err)
+	}
+	DiscardUpload(uploadID)
+	return filePath, nil
+}
+
+// CleanStaleUploads removes the staging directories of the uploads older than
+// the TTL, whose fields were never saved.
+func CleanStaleUploads(ttl time.Duration) (int, error) {
+	entries, err := os.ReadDir(UploadDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-ttl)
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(UploadDir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStageUpload(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	path, err := StageUpload("upload-1", "../../invoice.pdf", []byte("%PDF-1.7"))
+	if err != nil {
+		t.Fatalf("StageUpload returned error: %v", err)
+	}
+	// The name of the file cannot take it out of its staging directory.
+	if want := filepath.Join(UploadDir, "upload-1", "invoice.pdf"); path != want {
+		t.Errorf("expected the file staged at %s, got %s", want, path)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "%PDF-1.7" {
+		t.Errorf("expected the content to be staged, got %q, %v", content, err)
+	}
+
+	if err := DiscardUpload("upload-1"); err != nil {
+		t.Fatalf("DiscardUpload returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(UploadDir, "upload-1")); !os.IsNotExist(err) {
+		t.Errorf("expected the staging directory to be removed, got %v", err)
+	}
+}
+
+func TestKeepUpload(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	staged, err := StageUpload("upload-1", "invoice.pdf", []byte("%PDF-1.7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := KeepUpload("upload-1", staged, "category-1")
+	if err != nil {
+		t.Fatalf("KeepUpload returned error: %v", err)
+	}
+	if want := filepath.Join(DocumentDir, "category-1", "upload-1_invoice.pdf"); path != want {
+		t.Errorf("expected the file kept at %s, got %s", want, path)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "%PDF-1.7" {
+		t.Errorf("expected the content to be kept, got %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(UploadDir, "upload-1")); !os.IsNotExist(err) {
+		t.Errorf("expected the staging directory to be removed, got %v", err)
+	}
+
+	// An upload kept or discarded already cannot be kept again.
+	if _, err := KeepUpload("upload-1", staged, "category-1"); err == nil {
+		t.Error("expected keeping a missing upload to fail")
+	}
+}
+
+func TestCleanStaleUploads(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if removed, err := CleanStaleUploads(UploadTTL); err != nil || removed != 0 {
+		t.Fatalf("expected nothing to clean without uploads, got %d, %v", removed, err)
+	}
+
+	for _, id := range []string{"stale", "fresh"} {
+		if _, err := StageUpload(id, "invoice.pdf", []byte("%PDF-1.7")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-UploadTTL - time.Hour)
+	if err := os.Chtimes(filepath.Join(UploadDir, "stale"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := CleanStaleUploads(UploadTTL)
+	if err != nil || removed != 1 {
+		t.Fatalf("expected the stale upload to be removed, got %d, %v", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(UploadDir, "stale")); !os.IsNotExist(err) {
+		t.Errorf("expected the stale upload to be gone, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(UploadDir, "fresh", "invoice.pdf")); err != nil {
+		t.Errorf("expected the fresh upload to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Limits of the documents uploaded for categorization.
+const (
+	MaxUploadBytes = 10 << 20
+	MaxUploadPages = 50
+)
+
+// Codes of the reasons an upload is rejected for.
+const (
+	UploadFileTooLarge      = "file_too_large"
+	UploadUnsupportedType   = "unsupported_file_type"
+	UploadTooManyPages      = "too_many_pages"
+	UploadMalwareDetected   = "malware_detected"
+	UploadMalwareScanFailed = "malware_scan_unavailable"
+)
+
+// QuarantineDir is where the infected uploads are kept.
+const QuarantineDir = "./quarantine"
+
+// CLAMAV_ADDR is the address of the ClamAV daemon that scans the uploads,
+// host:port or unix:/path/to/clamd.sock. Without it, uploads are not scanned.
+var CLAMAV_ADDR = os.Getenv("CLAMAV_ADDR")
+
+// IsPDF tells from its content whether the file is a PDF, whatever its name.
+func IsPDF(content []byte) bool {
+	return bytes.HasPrefix(content, []byte("%PDF-")) || http.DetectContentType(content) == "application/pdf"
+}
+
+// ScanForMalware scans the content with the ClamAV daemon, and returns the
+// name of the malware found in it, or "" when it is clean or no daemon is
+// configured.
+func ScanForMalware(ctx context.Context, content []byte) (string, error) {
+	if CLAMAV_ADDR == "" {
+		return "", nil
+	}
+	network, address := "tcp", CLAMAV_ADDR
+	if path, found := strings.CutPrefix(CLAMAV_ADDR, "unix:"); found {
+		network, address = "unix", path
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	// INSTREAM takes the content in chunks preceded by their length, ended by
+	// an empty chunk.
+	w := bufio.NewWriter(conn)
+	w.WriteString("zINSTREAM\x00")
+	for start := 0; start < len(content); start += 64 * 1024 {
+		chunk := content[start:min(start+64*1024, len(content))]
+		binary.Write(w, binary.BigEndian, uint32(len(chunk)))
+		w.Write(chunk)
+	}
+	binary.Write(w, binary.BigEndian, uint32(0))
+	if err := w.Flush(); err != nil {
+		return "", fmt.Errorf("failed to send file to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	result := strings.TrimPrefix(strings.TrimRight(reply, "\x00\n"), "stream: ")
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("clamd error: %s", reply)
+	}
+}
+
+// Quarantine keeps the infected upload in the quarantine directory.
+func Quarantine(fileName string, content []byte) (string, error) {
+	if err := os.MkdirAll(QuarantineDir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(QuarantineDir, fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(fileName)))
+	return path, os.WriteFile(path, content, 0600)
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
+
+	"github.com/gaeaglobal/exto/server/storage"
 )
 
 // Config holds the application's configuration settings.
@@ -17,9 +20,40 @@ type Config struct {
 	GOOGLE_CLIENT_ID        string
 	OPENAI_API_KEY          string
 	GOOGLE_MOBILE_CLIENT_ID string
-	UPLOAD_DIR              string
-	EXPORT_DIR              string
+	BLOB_BACKEND            string
+	BLOB_LOCAL_DIR          string
+	S3_ENDPOINT             string
+	S3_REGION               string
+	S3_BUCKET               string
+	S3_ACCESS_KEY_ID        string
+	S3_SECRET_ACCESS_KEY    string
+	S3_PATH_STYLE           bool
+	AZURE_STORAGE_ACCOUNT   string
+	AZURE_STORAGE_KEY       string
+	AZURE_STORAGE_CONTAINER string
+	AZURE_BLOB_ENDPOINT     string
+	ENCRYPTION_KEY_FILE     string
+	ENCRYPTION_MASTER_KEY   string
+	PII_LLM_DETECTION       bool
+	CLAMAV_ADDR             string
 	STRIPE_API_KEY          string
+	STRIPE_WEBHOOK_SECRET   string
+	STRIPE_API_URL          string
+	AUTH_JWT_SECRET         string
+	APP_BASE_URL            string
+	SMTP_ADDR               string
+	SMTP_USERNAME           string
+	SMTP_PASSWORD           string
+	SMTP_FROM               string
+	MICROSOFT_CLIENT_ID     string
+	MICROSOFT_JWKS_URL      string
+	MICROSOFT_JWKS_FILE     string
+	USER_CACHE_SIZE         int
+	USER_CACHE_TTL          time.Duration
+	CACHE_CHANGE_STREAMS    bool
+	DELETION_GRACE_PERIOD   time.Duration
+	API_BASE_URL            string
+	DATA_EXPORT_TTL         time.Duration
 }
 
 func NewMockConfig() *Config {
@@ -30,9 +64,40 @@ func NewMockConfig() *Config {
 		GOOGLE_CLIENT_ID:        "mock-google-client-id",
 		OPENAI_API_KEY:          "mock-openai-api-key",
 		GOOGLE_MOBILE_CLIENT_ID: "mock-google-mobile-client-id",
-		UPLOAD_DIR:              "uploads",
-		EXPORT_DIR:              "exports",
+		BLOB_BACKEND:            "local",
+		BLOB_LOCAL_DIR:          ".",
+		S3_ENDPOINT:             "",
+		S3_REGION:               "us-east-1",
+		S3_BUCKET:               "",
+		S3_ACCESS_KEY_ID:        "",
+		S3_SECRET_ACCESS_KEY:    "",
+		S3_PATH_STYLE:           false,
+		AZURE_STORAGE_ACCOUNT:   "",
+		AZURE_STORAGE_KEY:       "",
+		AZURE_STORAGE_CONTAINER: "",
+		AZURE_BLOB_ENDPOINT:     "",
+		ENCRYPTION_KEY_FILE:     "",
+		ENCRYPTION_MASTER_KEY:   "",
+		PII_LLM_DETECTION:       false,
+		CLAMAV_ADDR:             "",
 		STRIPE_API_KEY:          "mock-stripe-api-key",
+		STRIPE_WEBHOOK_SECRET:   "mock-stripe-webhook-secret",
+		STRIPE_API_URL:          "http://localhost:12111",
+		AUTH_JWT_SECRET:         "mock-auth-jwt-secret",
+		APP_BASE_URL:            "http://localhost:3000",
+		SMTP_ADDR:               "",
+		SMTP_USERNAME:           "",
+		SMTP_PASSWORD:           "",
+		SMTP_FROM:               "no-reply@exto.local",
+		MICROSOFT_CLIENT_ID:     "mock-microsoft-client-id",
+		MICROSOFT_JWKS_URL:      "",
+		MICROSOFT_JWKS_FILE:     "",
+		USER_CACHE_SIZE:         100,
+		USER_CACHE_TTL:          time.Minute,
+		CACHE_CHANGE_STREAMS:    false,
+		DELETION_GRACE_PERIOD:   30 * 24 * time.Hour,
+		API_BASE_URL:            "http://localhost:7070",
+		DATA_EXPORT_TTL:         7 * 24 * time.Hour,
 	}
 }
 
@@ -47,9 +112,40 @@ func LoadConfig() (*Config, error) {
 		GOOGLE_CLIENT_ID:        "",
 		OPENAI_API_KEY:          "",
 		GOOGLE_MOBILE_CLIENT_ID: "",
-		UPLOAD_DIR:              "uploads",
-		EXPORT_DIR:              "exports",
+		BLOB_BACKEND:            "local",
+		BLOB_LOCAL_DIR:          ".",
+		S3_ENDPOINT:             "",
+		S3_REGION:               "us-east-1",
+		S3_BUCKET:               "",
+		S3_ACCESS_KEY_ID:        "",
+		S3_SECRET_ACCESS_KEY:    "",
+		S3_PATH_STYLE:           false,
+		AZURE_STORAGE_ACCOUNT:   "",
+		AZURE_STORAGE_KEY:       "",
+		AZURE_STORAGE_CONTAINER: "",
+		AZURE_BLOB_ENDPOINT:     "",
+		ENCRYPTION_KEY_FILE:     "",
+		ENCRYPTION_MASTER_KEY:   "",
+		PII_LLM_DETECTION:       false,
+		CLAMAV_ADDR:             "",
 		STRIPE_API_KEY:          "",
+		STRIPE_WEBHOOK_SECRET:   "",
+		STRIPE_API_URL:          "",
+		AUTH_JWT_SECRET:         "",
+		APP_BASE_URL:            "http://localhost:3000",
+		SMTP_ADDR:               "",
+		SMTP_USERNAME:           "",
+		SMTP_PASSWORD:           "",
+		SMTP_FROM:               "",
+		MICROSOFT_CLIENT_ID:     "",
+		MICROSOFT_JWKS_URL:      "https://login.microsoftonline.com/common/discovery/v2.0/keys",
+		MICROSOFT_JWKS_FILE:     "",
+		USER_CACHE_SIZE:         10000,
+		USER_CACHE_TTL:          5 * time.Minute,
+		CACHE_CHANGE_STREAMS:    false,
+		DELETION_GRACE_PERIOD:   30 * 24 * time.Hour,
+		API_BASE_URL:            "http://localhost:7070",
+		DATA_EXPORT_TTL:         7 * 24 * time.Hour,
 	}
 
 	// Load AppPort from environment variable "APP_PORT"
@@ -88,14 +184,92 @@ func LoadConfig() (*Config, error) {
 		cfg.GOOGLE_MOBILE_CLIENT_ID = envGoogleMobileClientID
 	}
 
-	// Load UPLOAD_DIR from environment variable "UPLOAD_DIR"
-	if envUploadDir, found := os.LookupEnv("UPLOAD_DIR"); found {
-		cfg.UPLOAD_DIR = envUploadDir
+	// Load BLOB_BACKEND from environment variable "BLOB_BACKEND": "local", "s3" or "azure".
+	// Uploaded documents and exports are kept in this store so that every replica sees them.
+	if envBlobBackend, found := os.LookupEnv("BLOB_BACKEND"); found {
+		cfg.BLOB_BACKEND = envBlobBackend
+	}
+
+	// Load BLOB_LOCAL_DIR from environment variable "BLOB_LOCAL_DIR", the root of the local blob store.
+	// The default keeps the files under ./uploads and ./exports readable.
+	if envBlobLocalDir, found := os.LookupEnv("BLOB_LOCAL_DIR"); found {
+		cfg.BLOB_LOCAL_DIR = envBlobLocalDir
+	}
+
+	// Load S3_ENDPOINT from environment variable "S3_ENDPOINT", e.g. http://localhost:9000 for MinIO
+	if envS3Endpoint, found := os.LookupEnv("S3_ENDPOINT"); found {
+		cfg.S3_ENDPOINT = envS3Endpoint
+	}
+
+	// Load S3_REGION from environment variable "S3_REGION"
+	if envS3Region, found := os.LookupEnv("S3_REGION"); found {
+		cfg.S3_REGION = envS3Region
+	}
+
+	// Load S3_BUCKET from environment variable "S3_BUCKET"
+	if envS3Bucket, found := os.LookupEnv("S3_BUCKET"); found {
+		cfg.S3_BUCKET = envS3Bucket
+	}
+
+	// Load S3_ACCESS_KEY_ID from environment variable "S3_ACCESS_KEY_ID"
+	if envS3AccessKeyID, found := os.LookupEnv("S3_ACCESS_KEY_ID"); found {
+		cfg.S3_ACCESS_KEY_ID = envS3AccessKeyID
+	}
+
+	// Load S3_SECRET_ACCESS_KEY from environment variable "S3_SECRET_ACCESS_KEY"
+	if envS3SecretAccessKey, found := os.LookupEnv("S3_SECRET_ACCESS_KEY"); found {
+		cfg.S3_SECRET_ACCESS_KEY = envS3SecretAccessKey
+	}
+
+	// Load S3_PATH_STYLE from environment variable "S3_PATH_STYLE".
+	// MinIO needs path style addressing, where the bucket is part of the path rather than of the host.
+	if envS3PathStyle, found := os.LookupEnv("S3_PATH_STYLE"); found {
+		cfg.S3_PATH_STYLE = (envS3PathStyle == "true" || envS3PathStyle == "1" || envS3PathStyle == "yes")
+	}
+
+	// Load AZURE_STORAGE_ACCOUNT from environment variable "AZURE_STORAGE_ACCOUNT"
+	if envAzureAccount, found := os.LookupEnv("AZURE_STORAGE_ACCOUNT"); found {
+		cfg.AZURE_STORAGE_ACCOUNT = envAzureAccount
+	}
+
+	// Load AZURE_STORAGE_KEY from environment variable "AZURE_STORAGE_KEY", the base64 key of the account
+	if envAzureKey, found := os.LookupEnv("AZURE_STORAGE_KEY"); found {
+		cfg.AZURE_STORAGE_KEY = envAzureKey
 	}
 
-	// Load EXPORT_DIR from environment variable "EXPORT_DIR"
-	if envExportDir, found := os.LookupEnv("EXPORT_DIR"); found {
-		cfg.EXPORT_DIR = envExportDir
+	// Load AZURE_STORAGE_CONTAINER from environment variable "AZURE_STORAGE_CONTAINER"
+	if envAzureContainer, found := os.LookupEnv("AZURE_STORAGE_CONTAINER"); found {
+		cfg.AZURE_STORAGE_CONTAINER = envAzureContainer
+	}
+
+	// Load AZURE_BLOB_ENDPOINT from environment variable "AZURE_BLOB_ENDPOINT",
+	// e.g. http://127.0.0.1:10000/devstoreaccount1 for Azurite
+	if envAzureBlobEndpoint, found := os.LookupEnv("AZURE_BLOB_ENDPOINT"); found {
+		cfg.AZURE_BLOB_ENDPOINT = envAzureBlobEndpoint
+	}
+
+	// Load ENCRYPTION_KEY_FILE from environment variable "ENCRYPTION_KEY_FILE", a JSON file
+	// of the master keys that encrypt the data keys of the organizations
+	if envEncryptionKeyFile, found := os.LookupEnv("ENCRYPTION_KEY_FILE"); found {
+		cfg.ENCRYPTION_KEY_FILE = envEncryptionKeyFile
+	}
+
+	// Load ENCRYPTION_MASTER_KEY from environment variable "ENCRYPTION_MASTER_KEY", a single base64
+	// encoded 32 byte master key used when there is no key file. Without either, data is stored unencrypted.
+	if envEncryptionMasterKey, found := os.LookupEnv("ENCRYPTION_MASTER_KEY"); found {
+		cfg.ENCRYPTION_MASTER_KEY = envEncryptionMasterKey
+	}
+
+	// Load PII_LLM_DETECTION from environment variable "PII_LLM_DETECTION".
+	// The language model is then also asked for the personal data the patterns do not know, like names and addresses.
+	if envPIILLMDetection, found := os.LookupEnv("PII_LLM_DETECTION"); found {
+		cfg.PII_LLM_DETECTION = (envPIILLMDetection == "true" || envPIILLMDetection == "1" || envPIILLMDetection == "yes")
+	}
+
+	// Load CLAMAV_ADDR from environment variable "CLAMAV_ADDR", the address of the ClamAV daemon
+	// that scans the uploads, host:port or unix:/path/to/clamd.sock. Without it, uploads are not scanned.
+	if envClamAVAddr, found := os.LookupEnv("CLAMAV_ADDR"); found {
+		cfg.CLAMAV_ADDR = envClamAVAddr
 	}
 
 	// Load STRIPE_API_KEY from environment variable "STRIPE_API_KEY"
@@ -103,5 +277,128 @@ func LoadConfig() (*Config, error) {
 		cfg.STRIPE_API_KEY = envStripeAPIKey
 	}
 
+	// Load STRIPE_WEBHOOK_SECRET from environment variable "STRIPE_WEBHOOK_SECRET"
+	if envStripeWebhookSecret, found := os.LookupEnv("STRIPE_WEBHOOK_SECRET"); found {
+		cfg.STRIPE_WEBHOOK_SECRET = envStripeWebhookSecret
+	}
+
+	// Load STRIPE_API_URL from environment variable "STRIPE_API_URL", e.g. a local stripe-mock
+	if envStripeAPIURL, found := os.LookupEnv("STRIPE_API_URL"); found {
+		cfg.STRIPE_API_URL = envStripeAPIURL
+	}
+
+	// Load AUTH_JWT_SECRET from environment variable "AUTH_JWT_SECRET", used to sign first-party tokens
+	if envAuthJWTSecret, found := os.LookupEnv("AUTH_JWT_SECRET"); found {
+		cfg.AUTH_JWT_SECRET = envAuthJWTSecret
+	}
+
+	// Load APP_BASE_URL from environment variable "APP_BASE_URL", used in links sent by email
+	if envAppBaseURL, found := os.LookupEnv("APP_BASE_URL"); found {
+		cfg.APP_BASE_URL = envAppBaseURL
+	}
+
+	// Load SMTP_ADDR from environment variable "SMTP_ADDR", e.g. smtp.example.com:587
+	if envSMTPAddr, found := os.LookupEnv("SMTP_ADDR"); found {
+		cfg.SMTP_ADDR = envSMTPAddr
+	}
+
+	// Load SMTP_USERNAME from environment variable "SMTP_USERNAME"
+	if envSMTPUsername, found := os.LookupEnv("SMTP_USERNAME"); found {
+		cfg.SMTP_USERNAME = envSMTPUsername
+	}
+
+	// Load SMTP_PASSWORD from environment variable "SMTP_PASSWORD"
+	if envSMTPPassword, found := os.LookupEnv("SMTP_PASSWORD"); found {
+		cfg.SMTP_PASSWORD = envSMTPPassword
+	}
+
+	// Load SMTP_FROM from environment variable "SMTP_FROM"
+	if envSMTPFrom, found := os.LookupEnv("SMTP_FROM"); found {
+		cfg.SMTP_FROM = envSMTPFrom
+	}
+
+	// Load MICROSOFT_CLIENT_ID from environment variable "MICROSOFT_CLIENT_ID", the audience of Microsoft ID tokens
+	if envMicrosoftClientID, found := os.LookupEnv("MICROSOFT_CLIENT_ID"); found {
+		cfg.MICROSOFT_CLIENT_ID = envMicrosoftClientID
+	}
+
+	// Load MICROSOFT_JWKS_URL from environment variable "MICROSOFT_JWKS_URL"
+	if envMicrosoftJWKSURL, found := os.LookupEnv("MICROSOFT_JWKS_URL"); found {
+		cfg.MICROSOFT_JWKS_URL = envMicrosoftJWKSURL
+	}
+
+	// Load MICROSOFT_JWKS_FILE from environment variable "MICROSOFT_JWKS_FILE".
+	// When set, Microsoft signing keys are read from this file instead of MICROSOFT_JWKS_URL, e.g. in tests.
+	if envMicrosoftJWKSFile, found := os.LookupEnv("MICROSOFT_JWKS_FILE"); found {
+		cfg.MICROSOFT_JWKS_FILE = envMicrosoftJWKSFile
+	}
+
+	// Load USER_CACHE_SIZE from environment variable "USER_CACHE_SIZE", the maximum number of cached users
+	if envUserCacheSize, found := os.LookupEnv("USER_CACHE_SIZE"); found {
+		size, err := strconv.Atoi(envUserCacheSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid USER_CACHE_SIZE environment variable: %w", err)
+		}
+		cfg.USER_CACHE_SIZE = size
+	}
+
+	// Load USER_CACHE_TTL from environment variable "USER_CACHE_TTL", e.g. "5m"
+	if envUserCacheTTL, found := os.LookupEnv("USER_CACHE_TTL"); found {
+		ttl, err := time.ParseDuration(envUserCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid USER_CACHE_TTL environment variable: %w", err)
+		}
+		cfg.USER_CACHE_TTL = ttl
+	}
+
+	// Load CACHE_CHANGE_STREAMS from environment variable "CACHE_CHANGE_STREAMS".
+	// When enabled, caches are invalidated by MongoDB change streams so that updates made by other replicas apply too.
+	if envCacheChangeStreams, found := os.LookupEnv("CACHE_CHANGE_STREAMS"); found {
+		cfg.CACHE_CHANGE_STREAMS = (envCacheChangeStreams == "true" || envCacheChangeStreams == "1" || envCacheChangeStreams == "yes")
+	}
+
+	// Load DELETION_GRACE_PERIOD from environment variable "DELETION_GRACE_PERIOD", e.g. "720h".
+	// Deleted accounts and organizations can be restored until it has passed; their data is purged afterwards.
+	if envGracePeriod, found := os.LookupEnv("DELETION_GRACE_PERIOD"); found {
+		gracePeriod, err := time.ParseDuration(envGracePeriod)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DELETION_GRACE_PERIOD environment variable: %w", err)
+		}
+		cfg.DELETION_GRACE_PERIOD = gracePeriod
+	}
+
+	// Load API_BASE_URL from environment variable "API_BASE_URL", used in signed download links
+	if envAPIBaseURL, found := os.LookupEnv("API_BASE_URL"); found {
+		cfg.API_BASE_URL = envAPIBaseURL
+	}
+
+	// Load DATA_EXPORT_TTL from environment variable "DATA_EXPORT_TTL", e.g. "168h".
+	// Data export archives can be downloaded until it has passed and are removed afterwards.
+	if envDataExportTTL, found := os.LookupEnv("DATA_EXPORT_TTL"); found {
+		ttl, err := time.ParseDuration(envDataExportTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DATA_EXPORT_TTL environment variable: %w", err)
+		}
+		cfg.DATA_EXPORT_TTL = ttl
+	}
+
 	return cfg, nil
 }
+
+// BlobStoreConfig returns the settings of the configured blob store backend.
+func (c *Config) BlobStoreConfig() storage.Config {
+	return storage.Config{
+		Backend:           c.BLOB_BACKEND,
+		LocalDir:          c.BLOB_LOCAL_DIR,
+		S3Endpoint:        c.S3_ENDPOINT,
+		S3Region:          c.S3_REGION,
+		S3Bucket:          c.S3_BUCKET,
+		S3AccessKeyID:     c.S3_ACCESS_KEY_ID,
+		S3SecretAccessKey: c.S3_SECRET_ACCESS_KEY,
+		S3PathStyle:       c.S3_PATH_STYLE,
+		AzureAccount:      c.AZURE_STORAGE_ACCOUNT,
+		AzureKey:          c.AZURE_STORAGE_KEY,
+		AzureContainer:    c.AZURE_STORAGE_CONTAINER,
+		AzureEndpoint:     c.AZURE_BLOB_ENDPOINT,
+	}
+}
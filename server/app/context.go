@@ -2,6 +2,8 @@ package app
 
 import (
 	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/encryption"
+	"github.com/gaeaglobal/exto/server/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -28,12 +30,25 @@ type AppContext struct {
 	Config *Config
 	DB     *db.AppDB
 	Count  int64
+	// MicrosoftKeys verifies the signature of Microsoft ID tokens.
+	MicrosoftKeys *JWKSCache
+	// OIDCKeys verifies the signature of ID tokens of SSO providers.
+	OIDCKeys *OIDCKeyRegistry
+	// Blobs stores the uploaded documents and the generated exports.
+	Blobs storage.BlobStore
+	// MasterKeys wraps the data keys of the organizations. It is nil when
+	// encryption at rest is disabled.
+	MasterKeys encryption.KeyProvider
 }
 
 func NewMockAppContext() *AppContext {
+	cfg := NewMockConfig()
 	return &AppContext{
-		Config: NewMockConfig(),
-		DB:     db.NewMockAppDB(),
+		Config:        cfg,
+		DB:            db.NewMockAppDB(),
+		MicrosoftKeys: NewJWKSCache(cfg.MICROSOFT_JWKS_URL, cfg.MICROSOFT_JWKS_FILE),
+		OIDCKeys:      NewOIDCKeyRegistry(),
+		Blobs:         storage.NewLocalBlobStore(cfg.BLOB_LOCAL_DIR),
 	}
 }
 
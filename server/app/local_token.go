@@ -0,0 +1,51 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+// LocalAuthProvider is the X-Auth-Provider of requests signed with an access
+// token issued by the server's own password and magic-link sign-in.
+const LocalAuthProvider = "exto"
+
+// Token types of the server issued JWTs. Only access tokens authenticate
+// requests; refresh tokens can only be exchanged for a new token pair.
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+)
+
+func GetUserFromLocalToken(c *gin.Context) (*GoogleTokenUser, error) {
+	appCtx, exists := GetAppContext(c)
+	if !exists {
+		return nil, errors.New("appContext not found")
+	}
+	if appCtx.Config.AUTH_JWT_SECRET == "" {
+		return nil, errors.New("local authentication is not configured")
+	}
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		return nil, errors.New("authorization token is required")
+	}
+	// Remove "Bearer " prefix if it exists
+	if len(token) > 7 && token[:7] == "Bearer " {
+		token = token[7:]
+	}
+
+	claims, err := utils.ParseJWT(token, []byte(appCtx.Config.AUTH_JWT_SECRET))
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != AccessTokenType || claims.Email == "" {
+		return nil, errors.New("invalid access token")
+	}
+	return &GoogleTokenUser{
+		Email:         claims.Email,
+		EmailVerified: true,
+		Exp:           claims.ExpiresAt,
+	}, nil
+}
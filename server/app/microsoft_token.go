@@ -1,17 +1,42 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// microsoftIssuerFormat is the issuer of Microsoft identity platform v2.0 ID
+// tokens for the tenant of the signed in account.
+const microsoftIssuerFormat = "https://login.microsoftonline.com/%s/v2.0"
+
+// idTokenClockSkew is the clock difference tolerated when checking the
+// validity period of an ID token.
+const idTokenClockSkew = 2 * time.Minute
+
+// MicrosoftTokenClaims are the claims of a Microsoft ID token that are used
+// to sign a user in.
+type MicrosoftTokenClaims struct {
+	Issuer            string `json:"iss"`
+	Audience          string `json:"aud"`
+	TenantID          string `json:"tid"`
+	Email             string `json:"email"`
+	PreferredUsername string `json:"preferred_username"`
+	IssuedAt          int64  `json:"iat"`
+	NotBefore         int64  `json:"nbf"`
+	ExpiresAt         int64  `json:"exp"`
+}
+
 func GetUserFromMicrosoftToken(c *gin.Context) (*GoogleTokenUser, error) {
+	appCtx, exists := GetAppContext(c)
+	if !exists {
+		return nil, errors.New("appContext not found")
+	}
 	token := c.GetHeader("Authorization")
 	if token == "" {
 		return nil, errors.New("authorization token is required")
@@ -20,62 +45,56 @@ func GetUserFromMicrosoftToken(c *gin.Context) (*GoogleTokenUser, error) {
 	if len(token) > 7 && token[:7] == "Bearer " {
 		token = token[7:]
 	}
-	claims, err := ValidateMicrosoftToken(token)
+	claims, err := ValidateMicrosoftToken(c.Request.Context(), token, appCtx.MicrosoftKeys, appCtx.Config.MICROSOFT_CLIENT_ID)
 	if err != nil {
-		return nil, errors.New("invalid Microsoft access token")
+		return nil, errors.New("invalid Microsoft ID token")
 	}
-	email, ok := claims["email"].(string)
-	if !ok || email == "" {
+	email := claims.Email
+	if email == "" {
+		email = claims.PreferredUsername
+	}
+	if email == "" {
 		return nil, errors.New("email not found in Microsoft token")
 	}
-	exp, _ := claims["exp"].(int64)
 	return &GoogleTokenUser{
-		Email:         email,
+		Email:         strings.ToLower(email),
 		EmailVerified: true,
-		Exp:           exp,
-		Hd:            "",
+		Exp:           claims.ExpiresAt,
+		TenantID:      claims.TenantID,
 	}, nil
 }
 
-func ValidateMicrosoftToken(token string) (map[string]interface{}, error) {
+// ValidateMicrosoftToken verifies the signature of a Microsoft ID token
+// against the cached signing keys and checks its audience, issuer, tenant and
+// validity period.
+func ValidateMicrosoftToken(ctx context.Context, token string, keys *JWKSCache, clientID string) (*MicrosoftTokenClaims, error) {
 	if token == "" {
 		return nil, errors.New("authorization token is required")
 	}
-	if !strings.HasPrefix(token, "Bearer ") {
-		token = "Bearer " + token
+	if keys == nil || clientID == "" {
+		return nil, errors.New("microsoft sign-in is not configured")
 	}
-	accessToken := strings.TrimPrefix(token, "Bearer ")
-	req, _ := http.NewRequest("GET", "https://graph.microsoft.com/v1.0/me", nil)
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := http.DefaultClient.Do(req)
+	payload, err := verifyRS256Token(ctx, token, keys)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Graph API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("graph API returned %d", resp.StatusCode)
+		return nil, err
 	}
 
-	var user struct {
-		Mail              string `json:"mail"`
-		UserPrincipalName string `json:"userPrincipalName"`
+	var claims MicrosoftTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed token")
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		return nil, err
+	if claims.Audience != clientID {
+		return nil, errors.New("token was issued for another application")
 	}
-
-	email := user.Mail
-	if email == "" {
-		email = user.UserPrincipalName
+	if claims.TenantID == "" || claims.Issuer != fmt.Sprintf(microsoftIssuerFormat, claims.TenantID) {
+		return nil, errors.New("token has an unexpected issuer")
 	}
-	if email == "" {
-		return nil, errors.New("email not found in Microsoft token")
+	now := time.Now()
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(idTokenClockSkew)) {
+		return nil, errors.New("token has expired")
 	}
-
-	claims := map[string]interface{}{
-		"email": email,
-		"exp":   time.Now().Add(1 * time.Hour).Unix(), // Graph doesn't return exp
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-idTokenClockSkew)) {
+		return nil, errors.New("token is not valid yet")
 	}
-	return claims, nil
+	return &claims, nil
 }
@@ -0,0 +1,122 @@
+package app_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gaeaglobal/exto/server/app"
+)
+
+const (
+	testClientID = "test-client-id"
+	testTenantID = "11111111-2222-3333-4444-555555555555"
+	testKeyID    = "test-key"
+)
+
+func testJWKS(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	jwks := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"use": "sig",
+		"kid": testKeyID,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// writeTestJWKS writes the public key as a JWKS file, the way the signing keys
+// are provided in test mode through MICROSOFT_JWKS_FILE.
+func writeTestJWKS(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, testJWKS(t, key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":                "https://login.microsoftonline.com/" + testTenantID + "/v2.0",
+		"aud":                testClientID,
+		"tid":                testTenantID,
+		"preferred_username": "jane@contoso.com",
+		"iat":                now.Unix(),
+		"nbf":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestValidateMicrosoftToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := app.NewJWKSCache("", writeTestJWKS(t, key))
+	ctx := context.Background()
+
+	claims, err := app.ValidateMicrosoftToken(ctx, signTestToken(t, key, testKeyID, validClaims()), keys, testClientID)
+	if err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	if claims.TenantID != testTenantID || claims.PreferredUsername != "jane@contoso.com" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	tests := []struct {
+		name  string
+		key   *rsa.PrivateKey
+		kid   string
+		claim func(map[string]any)
+	}{
+		{"wrong audience", key, testKeyID, func(c map[string]any) { c["aud"] = "another-app" }},
+		{"wrong issuer", key, testKeyID, func(c map[string]any) { c["iss"] = "https://login.microsoftonline.com/other/v2.0" }},
+		{"issuer of another tenant", key, testKeyID, func(c map[string]any) { c["tid"] = "other-tenant" }},
+		{"expired", key, testKeyID, func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"not yet valid", key, testKeyID, func(c map[string]any) { c["nbf"] = time.Now().Add(time.Hour).Unix() }},
+		{"unknown key", key, "rotated-key", func(c map[string]any) {}},
+		{"wrong signature", otherKey, testKeyID, func(c map[string]any) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validClaims()
+			tt.claim(c)
+			if _, err := app.ValidateMicrosoftToken(ctx, signTestToken(t, tt.key, tt.kid, c), keys, testClientID); err == nil {
+				t.Errorf("expected token to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// OIDCProvider is the X-Auth-Provider of ID tokens issued by the OpenID
+// Connect provider an organization configured for single sign-on.
+const OIDCProvider = "oidc"
+
+// OIDCTokenUser is the signed in user of an SSO ID token together with the
+// organization whose provider issued it.
+type OIDCTokenUser struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Claims    map[string]any
+	Org       *model.Organization
+}
+
+// audience is the aud claim, which is either a string or a list of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+type oidcClaims struct {
+	Issuer            string   `json:"iss"`
+	Audience          audience `json:"aud"`
+	Email             string   `json:"email"`
+	EmailVerified     *bool    `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	GivenName         string   `json:"given_name"`
+	FamilyName        string   `json:"family_name"`
+	NotBefore         int64    `json:"nbf"`
+	ExpiresAt         int64    `json:"exp"`
+}
+
+// OIDCKeyRegistry caches the signing keys of the organizations' OpenID
+// providers, found through OpenID discovery unless configured explicitly.
+// The providers are configured by the organizations, so by default only https
+// URLs on public hosts are fetched.
+type OIDCKeyRegistry struct {
+	client     *http.Client
+	publicOnly bool
+
+	mu       sync.Mutex
+	jwksURLs map[string]string
+	keySets  map[string]*JWKSCache
+}
+
+func NewOIDCKeyRegistry() *OIDCKeyRegistry {
+	return &OIDCKeyRegistry{
+		client:     NewPublicHTTPClient(10 * time.Second),
+		publicOnly: true,
+		jwksURLs:   map[string]string{},
+		keySets:    map[string]*JWKSCache{},
+	}
+}
+
+// NewOIDCKeyRegistryWithClient returns a registry that fetches any URL with
+// the client, for tests against local providers.
+func NewOIDCKeyRegistryWithClient(client *http.Client) *OIDCKeyRegistry {
+	return &OIDCKeyRegistry{
+		client:   client,
+		jwksURLs: map[string]string{},
+		keySets:  map[string]*JWKSCache{},
+	}
+}
+
+// KeySet returns the signing keys of the provider configured by sso.
+func (r *OIDCKeyRegistry) KeySet(ctx context.Context, sso *model.SSOConfig) (*JWKSCache, error) {
+	if r.publicOnly {
+		if err := ValidatePublicHTTPSURL(sso.Issuer); err != nil {
+			return nil, err
+		}
+	}
+	jwksURL := sso.JWKSURL
+	if jwksURL == "" {
+		var err error
+		if jwksURL, err = r.discoverJWKSURL(ctx, sso.Issuer); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.publicOnly {
+		if err := ValidatePublicHTTPSURL(jwksURL); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys, found := r.keySets[jwksURL]
+	if !found {
+		keys = NewJWKSCache(jwksURL, "")
+		keys.client = r.client
+		r.keySets[jwksURL] = keys
+	}
+	return keys, nil
+}
+
+func (r *OIDCKeyRegistry) discoverJWKSURL(ctx context.Context, issuer string) (string, error) {
+	r.mu.Lock()
+	jwksURL, found := r.jwksURLs[issuer]
+	r.mu.Unlock()
+	if found {
+		return jwksURL, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover OpenID provider: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OpenID discovery returned %d", resp.StatusCode)
+	}
+
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return "", fmt.Errorf("invalid OpenID discovery document: %w", err)
+	}
+	if discovery.Issuer != issuer || discovery.JWKSURI == "" {
+		return "", errors.New("OpenID discovery document does not match the issuer")
+	}
+
+	r.mu.Lock()
+	r.jwksURLs[issuer] = discovery.JWKSURI
+	r.mu.Unlock()
+	return discovery.JWKSURI, nil
+}
+
+// GetUserFromOIDCToken routes the ID token to the organization that
+// configured its issuer and client ID and validates it with that
+// organization's SSO configuration.
+func GetUserFromOIDCToken(c *gin.Context) (*OIDCTokenUser, error) {
+	appCtx, exists := GetAppContext(c)
+	if !exists {
+		return nil, errors.New("appContext not found")
+	}
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		return nil, errors.New("authorization token is required")
+	}
+	// Remove "Bearer " prefix if it exists
+	if len(token) > 7 && token[:7] == "Bearer " {
+		token = token[7:]
+	}
+
+	// The claims are only used to find the organization here; they are
+	// verified below with the organization's keys.
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var unverified oidcClaims
+	if err := decodeTokenSegment(parts[1], &unverified); err != nil {
+		return nil, err
+	}
+	org, err := appCtx.DB.GetOrganizationBySSO(unverified.Issuer, unverified.Audience)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateOIDCToken(c.Request.Context(), token, org, appCtx.OIDCKeys)
+}
+
+// ValidateOIDCToken verifies the ID token against the SSO configuration of
+// the organization: signature, issuer, audience, validity period and the
+// allowed email domains.
+func ValidateOIDCToken(ctx context.Context, token string, org *model.Organization, keys *OIDCKeyRegistry) (*OIDCTokenUser, error) {
+	sso := org.SSO
+	if sso == nil || !sso.Enabled {
+		return nil, errors.New("single sign-on is not enabled for the organization")
+	}
+	keySet, err := keys.KeySet(ctx, sso)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := verifyRS256Token(ctx, token, keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims oidcClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed token")
+	}
+	var rawClaims map[string]any
+	if err := json.Unmarshal(payload, &rawClaims); err != nil {
+		return nil, errors.New("malformed token")
+	}
+	if claims.Issuer != sso.Issuer {
+		return nil, errors.New("token has an unexpected issuer")
+	}
+	if !claims.Audience.contains(sso.ClientID) {
+		return nil, errors.New("token was issued for another application")
+	}
+	now := time.Now()
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(idTokenClockSkew)) {
+		return nil, errors.New("token has expired")
+	}
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-idTokenClockSkew)) {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	email := claims.Email
+	if email == "" && strings.Contains(claims.PreferredUsername, "@") {
+		email = claims.PreferredUsername
+	}
+	if email == "" {
+		return nil, errors.New("email not found in token")
+	}
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return nil, errors.New("email is not verified by the provider")
+	}
+	email = strings.ToLower(email)
+	if !sso.AllowsEmail(email) {
+		return nil, errors.New("email domain is not allowed by the organization")
+	}
+
+	return &OIDCTokenUser{
+		Email:     email,
+		FirstName: claims.GivenName,
+		LastName:  claims.FamilyName,
+		Claims:    rawClaims,
+		Org:       org,
+	}, nil
+}
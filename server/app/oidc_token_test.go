@@ -0,0 +1,121 @@
+package app_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// newFakeOIDCIssuer serves OpenID discovery and the signing keys of an
+// identity provider such as Keycloak.
+func newFakeOIDCIssuer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	var issuer *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.URL,
+			"jwks_uri": issuer.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(testJWKS(t, key))
+	})
+	issuer = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func TestValidateOIDCToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := newFakeOIDCIssuer(t, key)
+	org := &model.Organization{SSO: &model.SSOConfig{
+		Enabled:        true,
+		Issuer:         issuer.URL,
+		ClientID:       testClientID,
+		AllowedDomains: []string{"acme.com"},
+		Domains:        []model.SSODomain{{Domain: "acme.com", Verified: true}},
+	}}
+	keys := app.NewOIDCKeyRegistryWithClient(issuer.Client())
+	ctx := context.Background()
+
+	claims := func() map[string]any {
+		return map[string]any{
+			"iss":         issuer.URL,
+			"aud":         []string{"account", testClientID},
+			"email":       "Jane@Acme.com",
+			"given_name":  "Jane",
+			"family_name": "Doe",
+			"groups":      []string{"finance"},
+			"exp":         time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	user, err := app.ValidateOIDCToken(ctx, signTestToken(t, key, testKeyID, claims()), org, keys)
+	if err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	if user.Email != "jane@acme.com" || user.FirstName != "Jane" || user.Claims["groups"] == nil {
+		t.Errorf("unexpected token user %+v", user)
+	}
+
+	tests := []struct {
+		name  string
+		claim func(map[string]any)
+	}{
+		{"wrong audience", func(c map[string]any) { c["aud"] = "another-app" }},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://idp.example.com" }},
+		{"domain not allowed", func(c map[string]any) { c["email"] = "jane@example.com" }},
+		{"unverified email", func(c map[string]any) { c["email_verified"] = false }},
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := claims()
+			tt.claim(c)
+			if _, err := app.ValidateOIDCToken(ctx, signTestToken(t, key, testKeyID, c), org, keys); err == nil {
+				t.Errorf("expected token to be rejected")
+			}
+		})
+	}
+
+	org.SSO.Enabled = false
+	if _, err := app.ValidateOIDCToken(ctx, signTestToken(t, key, testKeyID, claims()), org, keys); err == nil {
+		t.Errorf("expected token to be rejected when SSO is disabled")
+	}
+}
+
+func TestOIDCKeyRegistryRefusesPrivateProviders(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := newFakeOIDCIssuer(t, key)
+	keys := app.NewOIDCKeyRegistry()
+	for _, sso := range []*model.SSOConfig{
+		{Issuer: issuer.URL},
+		{Issuer: "https://idp.example.com", JWKSURL: "https://169.254.169.254/latest/keys"},
+	} {
+		if _, err := keys.KeySet(context.Background(), sso); !errors.Is(err, app.ErrNonPublicURL) {
+			t.Errorf("expected %s to be refused, got %v", sso.Issuer, err)
+		}
+	}
+
+	// Whatever the URL, the client does not connect to a private address.
+	client := app.NewPublicHTTPClient(time.Second)
+	if _, err := client.Get(issuer.URL); !errors.Is(err, app.ErrNonPublicURL) {
+		t.Errorf("expected the connection to a loopback address to be refused, got %v", err)
+	}
+}
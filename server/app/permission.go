@@ -0,0 +1,107 @@
+package app
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+type Permission string
+
+const (
+	PermScanCreate    Permission = "scan:create"
+	PermScanRead      Permission = "scan:read"
+	PermCategoryRead  Permission = "category:read"
+	PermCategoryWrite Permission = "category:write"
+	PermExportRead    Permission = "export:read"
+	PermUsageRead     Permission = "usage:read"
+	PermBillingRead   Permission = "billing:read"
+	PermBillingManage Permission = "billing:manage"
+	PermOrgRead       Permission = "org:read"
+	PermOrgManage     Permission = "org:manage"
+	PermPlatformAdmin Permission = "platform:admin"
+	// PermPIIRead lets the personal data found in the extracted values be
+	// read unmasked.
+	PermPIIRead Permission = "pii:read"
+
+	// PermAccount covers managing one's own account and memberships. Every
+	// user role has it; API keys never do.
+	PermAccount Permission = "account:manage"
+	// PermIdentity covers acting on one's identity across its organizations:
+	// deleting the account, listing, switching and joining organizations.
+	// Every user role has it, but neither API keys nor SSO sessions, which
+	// only sign in to the organization of their provider.
+	PermIdentity Permission = "identity:manage"
+)
+
+var rolePermissions = map[model.UserRole][]Permission{
+	model.RoleSuperAdmin: {
+		PermScanCreate, PermScanRead, PermCategoryRead, PermCategoryWrite, PermExportRead,
+		PermUsageRead, PermBillingRead, PermBillingManage, PermOrgRead, PermOrgManage, PermPlatformAdmin, PermPIIRead, PermAccount, PermIdentity,
+	},
+	model.RoleOrganizationAdmin: {
+		PermScanCreate, PermScanRead, PermCategoryRead, PermCategoryWrite, PermExportRead,
+		PermUsageRead, PermBillingRead, PermBillingManage, PermOrgRead, PermOrgManage, PermPIIRead, PermAccount, PermIdentity,
+	},
+	model.RoleBillingAdmin: {
+		PermScanRead, PermCategoryRead, PermExportRead, PermUsageRead, PermBillingRead, PermBillingManage, PermOrgRead, PermAccount, PermIdentity,
+	},
+	model.RoleMember: {
+		PermScanCreate, PermScanRead, PermCategoryRead, PermCategoryWrite, PermExportRead, PermUsageRead, PermOrgRead, PermAccount, PermIdentity,
+	},
+	model.RoleGuest: {
+		PermScanRead, PermCategoryRead, PermExportRead, PermOrgRead, PermAccount, PermIdentity,
+	},
+}
+
+// APIKeyScopes are the permissions an organization API key may be given.
+var APIKeyScopes = []Permission{
+	PermScanCreate, PermScanRead, PermCategoryRead, PermCategoryWrite, PermExportRead, PermUsageRead, PermPIIRead,
+}
+
+// HasPermission reports whether the role grants the permission.
+func HasPermission(role model.UserRole, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the user may act with the permission. API key users
+// are limited to the scopes of their key, and SSO sessions to their
+// organization.
+func (r *RequestUser) Can(perm Permission) bool {
+	if r.Role == model.RoleService {
+		return slices.Contains(r.Scopes, perm)
+	}
+	if r.SSOSession && perm == PermIdentity {
+		return false
+	}
+	return HasPermission(r.Role, perm)
+}
+
+// RequirePermission aborts with 403 unless the role of the signed in user
+// grants all the given permissions. The missing permission is returned in the
+// error details.
+func RequirePermission(perms ...Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := GetRequestCtx(c)
+		if reqCtx.IsZero() {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+			return
+		}
+		for _, perm := range perms {
+			if !reqCtx.User.Can(perm) {
+				c.AbortWithStatusJSON(http.StatusForbidden, utils.NewErrorResponseWithDetails("Forbidden", []string{"missing permission: " + string(perm)}))
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicURL is returned for URLs that the server must not fetch because
+// they are not https or point into a private network.
+var ErrNonPublicURL = errors.New("the URL must be https on a public host")
+
+// IsPublicIP reports whether the address is routable on the internet, i.e.
+// not a loopback, link-local, private, multicast or unspecified address.
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsPrivate() || ip.IsUnspecified())
+}
+
+// ValidatePublicHTTPSURL checks that the URL, configured by an organization
+// and fetched by the server, is https and does not name a private host.
+// Hostnames are checked again when they are resolved, by the client of
+// NewPublicHTTPClient.
+func ValidatePublicHTTPSURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return ErrNonPublicURL
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrNonPublicURL
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return ErrNonPublicURL
+	}
+	return nil
+}
+
+// NewPublicHTTPClient returns a client that refuses to connect to non public
+// addresses, whatever the hostnames of the URLs or of their redirects resolve
+// to.
+func NewPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return fmt.Errorf("connection to %s refused: %w", host, ErrNonPublicURL)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return ValidatePublicHTTPSURL(req.URL.String())
+		},
+	}
+}
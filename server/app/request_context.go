@@ -4,16 +4,27 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
 
+	"github.com/gaeaglobal/exto/server/db"
 	"github.com/gaeaglobal/exto/server/model"
 	"github.com/gaeaglobal/exto/server/utils"
 )
 
 const appRequestKey = "app_request"
 
+// APIKeyProvider is the X-Auth-Provider of requests signed with an
+// organization API key instead of a user token.
+const APIKeyProvider = "apikey"
+
+// OrgIDHeader selects the organization of a request for users that belong to
+// more than one organization.
+const OrgIDHeader = "X-Org-ID"
+
 type RequestUser struct {
 	ID             bson.ObjectID  `json:"id"`
 	Email          string         `json:"email"`
@@ -23,6 +34,10 @@ type RequestUser struct {
 	OrganizationID bson.ObjectID  `json:"organization_id"`
 	IdentityID     bson.ObjectID  `json:"identity_id"`
 	IsActive       bool           `json:"is_active"`
+	Scopes         []Permission   `json:"scopes,omitempty"`
+	// SSOSession is set when the user signed in through the SSO provider of
+	// the organization, which only vouches for the user in that organization.
+	SSOSession bool `json:"sso_session,omitempty"`
 }
 
 type RequestOrg struct {
@@ -39,6 +54,10 @@ type RequestContext struct {
 	ctx  context.Context `json:"-"`
 	User RequestUser     `json:"user"`
 	Org  RequestOrg      `json:"organization"`
+
+	// tx is set on the request context of a transaction started with
+	// WithTransaction, whose ctx is then bound to the session.
+	tx *transaction
 }
 
 func (r *RequestContext) IsZero() bool {
@@ -55,8 +74,10 @@ func (r *RequestContext) WithUser(user RequestUser) *RequestContext {
 	return r
 }
 
+// Context returns the context of the request. Inside WithTransaction it is
+// bound to the session of the transaction.
 func (r *RequestContext) Context() context.Context {
-	if r.ctx == nil {
+	if r == nil || r.ctx == nil {
 		return context.Background()
 	}
 	return r.ctx
@@ -118,6 +139,11 @@ func GetRequestCtx(c *gin.Context) *RequestContext {
 	return ctx
 }
 
+// SetRequestCtx stores the request context of the signed in user on the Gin context.
+func SetRequestCtx(c *gin.Context, reqCtx *RequestContext) {
+	c.Set(appRequestKey, reqCtx)
+}
+
 func AppAuthzMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		provider := c.GetHeader("x-auth-provider")
@@ -125,7 +151,13 @@ func AppAuthzMiddleware() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("X-Auth-Provider header is required"))
 			return
 		}
-		var tokenUserEmail string
+		if provider == APIKeyProvider {
+			authenticateAPIKey(c)
+			return
+		}
+
+		var tokenUserEmail, tokenTenantID string
+		var ssoOrgID bson.ObjectID
 		if provider == "google" {
 			tokenUser, err := GetUserFromToken(c)
 			if err != nil {
@@ -140,6 +172,22 @@ func AppAuthzMiddleware() gin.HandlerFunc {
 				return
 			}
 			tokenUserEmail = tokenUser.Email
+			tokenTenantID = tokenUser.TenantID
+		} else if provider == OIDCProvider {
+			tokenUser, err := GetUserFromOIDCToken(c)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+				return
+			}
+			tokenUserEmail = tokenUser.Email
+			ssoOrgID = tokenUser.Org.ID
+		} else if provider == LocalAuthProvider {
+			tokenUser, err := GetUserFromLocalToken(c)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+				return
+			}
+			tokenUserEmail = tokenUser.Email
 		}
 
 		// Implement your authorization logic here.
@@ -150,28 +198,120 @@ func AppAuthzMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// SSO tokens only sign in to the organization whose provider issued them.
+		if !ssoOrgID.IsZero() {
+			if orgHeader := c.GetHeader(OrgIDHeader); orgHeader != "" && orgHeader != ssoOrgID.Hex() {
+				c.AbortWithStatusJSON(http.StatusForbidden, utils.NewErrorResponse("Not a member of the organization"))
+				return
+			}
+			cached, err := appCtx.DB.GetUserByEmailForOrg(tokenUserEmail, ssoOrgID)
+			if err != nil {
+				log.Println("Error retrieving SSO user:", err)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewErrorResponse("User not found or not authorized"))
+				return
+			}
+			setCachedRequestCtx(c, cached)
+			GetRequestCtx(c).User.SSOSession = true
+			c.Next()
+			return
+		}
+
+		// X-Org-ID selects one of the user's organizations for this request only.
+		if orgHeader := c.GetHeader(OrgIDHeader); orgHeader != "" {
+			orgID, err := bson.ObjectIDFromHex(orgHeader)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("Invalid X-Org-ID header"))
+				return
+			}
+			cached, err := appCtx.DB.GetUserByEmailForOrg(tokenUserEmail, orgID)
+			if err != nil {
+				log.Println("Error retrieving user for organization:", err)
+				c.AbortWithStatusJSON(http.StatusForbidden, utils.NewErrorResponse("Not a member of the organization"))
+				return
+			}
+			if provider == "microsoft" && !cached.Org.AllowsMicrosoftTenant(tokenTenantID) {
+				c.AbortWithStatusJSON(http.StatusForbidden, utils.NewErrorResponse("Microsoft tenant is not allowed by the organization"))
+				return
+			}
+			setCachedRequestCtx(c, cached)
+			c.Next()
+			return
+		}
+
 		cached, err := appCtx.DB.GetUserByEmail(tokenUserEmail)
 		if err != nil {
 			log.Println("Error retrieving user:", err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewErrorResponse("User not found or not authorized"))
 			return
 		}
-
-		org := &RequestOrg{
-			ID:   cached.Org.ID,
-			Name: cached.Org.Name,
-			Slug: cached.Org.Slug,
+		if provider == "microsoft" && !cached.Org.AllowsMicrosoftTenant(tokenTenantID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, utils.NewErrorResponse("Microsoft tenant is not allowed by the organization"))
+			return
 		}
-		c.Set(appRequestKey, newRequestContext(&RequestUser{
-			ID:             cached.User.ID,
-			Email:          cached.Email,
-			FirstName:      cached.User.FirstName,
-			LastName:       cached.User.LastName,
-			Role:           cached.User.Role,
-			OrganizationID: cached.User.OrganizationID,
-			IdentityID:     cached.User.IdentityID,
-			IsActive:       cached.User.IsActive,
-		}, org))
+
+		setCachedRequestCtx(c, cached)
 		c.Next()
 	}
 }
+
+func setCachedRequestCtx(c *gin.Context, cached *db.CacheUser) {
+	org := &RequestOrg{
+		ID:   cached.Org.ID,
+		Name: cached.Org.Name,
+		Slug: cached.Org.Slug,
+	}
+	c.Set(appRequestKey, newRequestContext(&RequestUser{
+		ID:             cached.User.ID,
+		Email:          cached.Email,
+		FirstName:      cached.User.FirstName,
+		LastName:       cached.User.LastName,
+		Role:           cached.User.Role,
+		OrganizationID: cached.User.OrganizationID,
+		IdentityID:     cached.User.IdentityID,
+		IsActive:       cached.User.IsActive,
+	}, org))
+}
+
+// authenticateAPIKey resolves the bearer API key into a service user of the
+// organization that owns the key.
+func authenticateAPIKey(c *gin.Context) {
+	appCtx, exists := GetAppContext(c)
+	if !exists {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("AppContext not found"))
+		return
+	}
+
+	prefix, secret, ok := utils.ParseAPIKey(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+	key, org, err := appCtx.DB.GetAPIKeyByPrefix(prefix)
+	if err != nil || !utils.VerifyAPIKeySecret(secret, key.SecretHash) || !key.IsUsable(time.Now()) {
+		if err != nil {
+			log.Println("Error retrieving API key:", err)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+	appCtx.DB.TouchAPIKey(key)
+
+	scopes := make([]Permission, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, Permission(scope))
+	}
+	c.Set(appRequestKey, newRequestContext(&RequestUser{
+		ID:             key.ID,
+		Email:          "api-key+" + key.Prefix,
+		FirstName:      key.Name,
+		Role:           model.RoleService,
+		OrganizationID: org.ID,
+		IsActive:       true,
+		Scopes:         scopes,
+	}, &RequestOrg{
+		ID:   org.ID,
+		Name: org.Name,
+		Slug: org.Slug,
+	}))
+	c.Next()
+}
@@ -13,6 +13,8 @@ type GoogleTokenUser struct {
 	EmailVerified bool   `json:"email_verified"`
 	Exp           int64  `json:"exp"`
 	Hd            string `json:"hd"`
+	// TenantID is the Microsoft tenant of the account, empty for other providers.
+	TenantID string `json:"tid,omitempty"`
 }
 
 func GetUserFromToken(c *gin.Context) (*GoogleTokenUser, error) {
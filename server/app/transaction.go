@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+
+	"github.com/gaeaglobal/exto/server/db"
+)
+
+type transaction struct {
+	afterCommit []func()
+}
+
+// WithTransaction runs fn in a database transaction. The request context
+// passed to fn is bound to the session of the transaction, so the repository
+// calls made with it are committed or aborted together. Transient failures
+// retry fn, so it must not have side effects outside the database; register
+// them with AfterCommit instead. Nested calls join the outer transaction.
+func WithTransaction(reqCtx *RequestContext, sessions db.SessionProvider, fn func(txCtx *RequestContext) error) error {
+	if reqCtx.InTransaction() {
+		return fn(reqCtx)
+	}
+
+	var committed *transaction
+	err := db.WithTransaction(reqCtx.Context(), sessions, func(ctx context.Context) error {
+		txCtx := &RequestContext{ctx: ctx, tx: &transaction{}}
+		if reqCtx != nil {
+			txCtx.User = reqCtx.User
+			txCtx.Org = reqCtx.Org
+		}
+		committed = txCtx.tx
+		return fn(txCtx)
+	})
+	if err != nil {
+		return err
+	}
+	for _, hook := range committed.afterCommit {
+		hook()
+	}
+	return nil
+}
+
+// InTransaction reports whether the request context belongs to a transaction
+// started with WithTransaction.
+func (r *RequestContext) InTransaction() bool {
+	return r != nil && r.tx != nil
+}
+
+// AfterCommit runs fn once the transaction of the request context has been
+// committed; it is dropped when the transaction is aborted. Outside a
+// transaction fn runs immediately.
+func (r *RequestContext) AfterCommit(fn func()) {
+	if !r.InTransaction() {
+		fn()
+		return
+	}
+	r.tx.afterCommit = append(r.tx.afterCommit, fn)
+}
@@ -0,0 +1,74 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+)
+
+func newMockSessions(ctrl *gomock.Controller) (*mocks.MockSessionProvider, *mocks.MockMongoSession) {
+	session := mocks.NewMockMongoSession(ctrl)
+	session.EXPECT().Context(gomock.Any()).AnyTimes().DoAndReturn(func(ctx context.Context) context.Context { return ctx })
+	session.EXPECT().EndSession(gomock.Any())
+	sessions := mocks.NewMockSessionProvider(ctrl)
+	sessions.EXPECT().StartSession().Return(session, nil)
+	return sessions, session
+}
+
+func TestWithTransactionRetriesTransientErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions, session := newMockSessions(ctrl)
+	session.EXPECT().StartTransaction().Times(2).Return(nil)
+	session.EXPECT().AbortTransaction(gomock.Any()).Return(nil)
+	session.EXPECT().CommitTransaction(gomock.Any()).Return(nil)
+
+	transient := mongo.CommandError{Message: "write conflict", Labels: []string{"TransientTransactionError"}}
+	attempts, hooks := 0, 0
+	err := app.WithTransaction(&app.RequestContext{}, sessions, func(txCtx *app.RequestContext) error {
+		attempts++
+		txCtx.AfterCommit(func() { hooks++ })
+		if attempts == 1 {
+			return fmt.Errorf("failed to create user: %w", transient)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction returned error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+	if hooks != 1 {
+		t.Errorf("expected the after-commit hook of the committed attempt only, ran %d", hooks)
+	}
+}
+
+func TestWithTransactionAbortsOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions, session := newMockSessions(ctrl)
+	session.EXPECT().StartTransaction().Return(nil)
+	session.EXPECT().AbortTransaction(gomock.Any()).Return(nil)
+
+	errFailed := errors.New("failed")
+	hookRan := false
+	err := app.WithTransaction(&app.RequestContext{}, sessions, func(txCtx *app.RequestContext) error {
+		if !txCtx.InTransaction() {
+			t.Error("expected a transaction request context")
+		}
+		txCtx.AfterCommit(func() { hookRan = true })
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+	if hookRan {
+		t.Error("after-commit hook ran for an aborted transaction")
+	}
+}
@@ -1,11 +1,14 @@
 package app_di
 
 import (
+	"log"
+
 	"github.com/gin-gonic/gin"
-	"github.com/stripe/stripe-go/v82"
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/encryption"
+	"github.com/gaeaglobal/exto/server/file_utils"
 	"github.com/gaeaglobal/exto/server/repo"
 	"github.com/gaeaglobal/exto/server/service"
 )
@@ -25,6 +28,25 @@ type AppDI struct {
 	ScanService         *service.ScanService
 	PaymentService      *service.PaymentService
 	SubscriptionService *service.SubscriptionService
+
+	ExtractionCacheService *service.ExtractionCacheService
+	QuotaService           *service.QuotaService
+	PlanService            *service.PlanService
+	StripeWebhookService   *service.StripeWebhookService
+	MeterService           *service.MeterService
+	MeterDispatcher        *service.MeterDispatcher
+	InvitationService      *service.InvitationService
+	APIKeyService          *service.APIKeyService
+	AuthService            *service.AuthService
+	SSOService             *service.SSOService
+
+	OrganizationProfileService *service.OrganizationProfileService
+	DeletionService            *service.DeletionService
+	DataExportService          *service.DataExportService
+	RetentionService           *service.RetentionService
+	EncryptionService          *service.EncryptionService
+	PIIService                 *service.PIIService
+	UploadService              *service.UploadService
 }
 
 func NewAppDI(appCtx *app.AppContext) *AppDI {
@@ -34,38 +56,114 @@ func NewAppDI(appCtx *app.AppContext) *AppDI {
 	orgRepo := repo.NewOrganizationRepository(appCtx.DB)
 	categoryRepo := repo.NewCategoryRepository(appCtx.DB)
 	formatRepo := repo.NewFormatRepository(appCtx.DB)
+	dataKeyRepo := repo.NewDataKeyRepository(appCtx.DB)
+
+	// The documents and the sensitive values of the organizations are
+	// encrypted with their data keys when a master key is configured.
+	encryptionService := service.NewEncryptionService(appCtx.MasterKeys, dataKeyRepo)
+	var fieldCipher repo.FieldCipher
+	if encryptionService.Enabled() {
+		if err := encryptionService.EnsureIndexes(); err != nil {
+			log.Printf("failed to initialize data keys: %v", err)
+		}
+		fieldCipher = encryption.NewFieldCipher(encryptionService)
+		appCtx.Blobs = encryption.NewEncryptedBlobStore(appCtx.Blobs, encryptionService, file_utils.KeyOrganization)
+	}
 
 	scanHistoryRepo := repo.NewScanHistoryRepository(appCtx.DB)
-	categoryDataRepo := repo.NewCategoryDataRepository(appCtx.DB)
+	categoryDataRepo := repo.NewCategoryDataRepository(appCtx.DB, fieldCipher)
 	meterEventRepo := repo.NewMeterEventRepository(appCtx.DB)
 
 	batchRepo := repo.NewBatchRepository(appCtx.DB)
 	subscriptionRepo := repo.NewSubscriptionRepository(appCtx.DB)
+	extractionCacheRepo := repo.NewExtractionCacheRepository(appCtx.DB)
+	planRepo := repo.NewPlanRepository(appCtx.DB)
+	monthlyUsageRepo := repo.NewMonthlyUsageRepository(appCtx.DB)
+	quotaOverrideRepo := repo.NewQuotaOverrideRepository(appCtx.DB)
+	stripeEventRepo := repo.NewStripeEventRepository(appCtx.DB)
+	invoiceRepo := repo.NewInvoiceRepository(appCtx.DB)
+	invitationRepo := repo.NewInvitationRepository(appCtx.DB)
+	apiKeyRepo := repo.NewAPIKeyRepository(appCtx.DB)
+	authTokenRepo := repo.NewAuthTokenRepository(appCtx.DB)
+	deletionCertificateRepo := repo.NewDeletionCertificateRepository(appCtx.DB)
+	dataExportRepo := repo.NewDataExportRepository(appCtx.DB)
 
 	dbSessionProvider := db.NewSessionProvider(appCtx.DB.Client)
 
 	// Create Services
 	identityService := service.NewIdentityService(identityRepo)
 	orgService := service.NewOrganizationService(orgRepo)
+	if err := orgService.EnsureIndexes(); err != nil {
+		log.Printf("failed to initialize organizations: %v", err)
+	}
 	userService := service.NewUserService(dbSessionProvider, userRepo, identityService, orgService, service.NewGoogleSheetService())
+	invitationService := service.NewInvitationService(invitationRepo, userRepo, identityService, orgService)
+	if err := invitationService.EnsureIndexes(); err != nil {
+		log.Printf("failed to initialize invitations: %v", err)
+	}
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+	if err := apiKeyService.EnsureIndexes(); err != nil {
+		log.Printf("failed to initialize api keys: %v", err)
+	}
+	authService := service.NewAuthService(appCtx, dbSessionProvider, identityRepo, authTokenRepo, userService, invitationService, service.NewMailer(appCtx.Config))
+	if err := authService.EnsureIndexes(); err != nil {
+		log.Printf("failed to initialize auth tokens: %v", err)
+	}
+	ssoService := service.NewSSOService(userRepo, identityService, orgService)
 	categoryService := service.NewCategoryService(categoryRepo)
+	orgProfileService := service.NewOrganizationProfileService(orgService, categoryService)
 	formatService := service.NewFormatService(formatRepo)
 
+	extractionCacheService := service.NewExtractionCacheService(extractionCacheRepo, orgService)
+	openAIService := service.NewOpenAIService(formatService, extractionCacheService)
+	piiService := service.NewPIIService(appCtx, openAIService)
+
 	scanHistoryService := service.NewScanHistoryService(dbSessionProvider, scanHistoryRepo)
-	categoryDataService := service.NewCategoryDataService(categoryDataRepo, categoryService, orgService, scanHistoryService)
+	categoryDataService := service.NewCategoryDataService(categoryDataRepo, categoryService, orgService, scanHistoryService, piiService)
 
 	exportService := service.NewExportService(appCtx, orgService, scanHistoryService, categoryService, categoryDataService)
-	openAIService := service.NewOpenAIService(formatService)
-
+	dataExportService := service.NewDataExportService(appCtx, dataExportRepo, orgRepo, categoryDataRepo)
+	if err := dataExportService.EnsureIndexes(); err != nil {
+		log.Printf("failed to initialize data exports: %v", err)
+	}
+	retentionService := service.NewRetentionService(appCtx, orgRepo, categoryRepo, categoryDataRepo, batchRepo, scanHistoryRepo)
 	batchService := service.NewBatchService(dbSessionProvider, batchRepo)
 
 	// Stripe Client Initialization
-	sc := stripe.NewClient(appCtx.Config.STRIPE_API_KEY)
+	sc := service.NewStripeClient(appCtx.Config.STRIPE_API_KEY, appCtx.Config.STRIPE_API_URL)
 	subscriptionService := service.NewSubscriptionService(subscriptionRepo)
-	paymentService := service.NewPaymentService(sc, orgService, subscriptionService)
+	planService := service.NewPlanService(planRepo)
+	if err := planService.EnsureDefaultPlans(&app.RequestContext{}); err != nil {
+		log.Printf("failed to initialize billing plans: %v", err)
+	}
+	deletionService := service.NewDeletionService(appCtx, dbSessionProvider, sc, orgRepo, identityRepo, userRepo, invitationRepo, apiKeyRepo, deletionCertificateRepo,
+		dataExportRepo, dataKeyRepo, quotaOverrideRepo, monthlyUsageRepo, meterEventRepo, authTokenRepo)
+	paymentService := service.NewPaymentService(sc, orgService, subscriptionService, planService, invoiceRepo)
 	meterService := service.NewMeterService(sc, meterEventRepo, orgService)
+	if err := meterService.EnsureIndexes(); err != nil {
+		log.Printf("failed to initialize meter outbox: %v", err)
+	}
+	meterDispatcher := service.NewMeterDispatcher(sc, meterEventRepo)
+	quotaService := service.NewQuotaService(planService, monthlyUsageRepo, quotaOverrideRepo, orgService, subscriptionService)
+	if err := quotaService.EnsureIndexes(); err != nil {
+		log.Printf("failed to initialize scan quotas: %v", err)
+	}
 
-	scanService := service.NewScanService(appCtx, orgService, batchService, openAIService, scanHistoryService, categoryDataService, meterService)
+	stripeWebhookService := service.NewStripeWebhookService(appCtx.Config.STRIPE_WEBHOOK_SECRET, stripeEventRepo, invoiceRepo, orgService, subscriptionService, planService)
+	if err := stripeEventRepo.EnsureIndexes(); err != nil {
+		log.Printf("failed to initialize stripe event log: %v", err)
+	}
+
+	// Uploads are scanned for malware when a ClamAV daemon is configured.
+	var malwareScanner file_utils.MalwareScanner
+	if appCtx.Config.CLAMAV_ADDR != "" {
+		malwareScanner = file_utils.NewClamAVScanner(appCtx.Config.CLAMAV_ADDR)
+	} else {
+		log.Printf("CLAMAV_ADDR is not set, uploads are not scanned for malware")
+	}
+	uploadService := service.NewUploadService(appCtx, quotaService, malwareScanner)
+
+	scanService := service.NewScanService(appCtx, dbSessionProvider, orgService, batchService, openAIService, scanHistoryService, categoryDataService, meterService, quotaService, piiService, uploadService)
 	// Return the AppDI instance
 	return &AppDI{
 		UserService:         userService,
@@ -80,12 +178,37 @@ func NewAppDI(appCtx *app.AppContext) *AppDI {
 		ScanService:         scanService,
 		PaymentService:      paymentService,
 		SubscriptionService: subscriptionService,
+
+		ExtractionCacheService: extractionCacheService,
+		QuotaService:           quotaService,
+		PlanService:            planService,
+		StripeWebhookService:   stripeWebhookService,
+		MeterService:           meterService,
+		MeterDispatcher:        meterDispatcher,
+		InvitationService:      invitationService,
+		APIKeyService:          apiKeyService,
+		AuthService:            authService,
+		SSOService:             ssoService,
+
+		OrganizationProfileService: orgProfileService,
+		DeletionService:            deletionService,
+		DataExportService:          dataExportService,
+		RetentionService:           retentionService,
+		EncryptionService:          encryptionService,
+		PIIService:                 piiService,
+		UploadService:              uploadService,
 	}
 }
 
 func (di *AppDI) Close() {
+	di.MeterDispatcher.Stop()
+	di.DeletionService.Stop()
+	di.DataExportService.Stop()
+	di.RetentionService.Stop()
 
 	di.CategoryService.Close()
+	di.ExtractionCacheService.Close()
+	di.EncryptionService.Close()
 }
 
 func AppDIMiddleware(appDI *AppDI) gin.HandlerFunc {
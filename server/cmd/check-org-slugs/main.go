@@ -0,0 +1,54 @@
+// Command check-org-slugs reports organizations whose slugs collide before
+// the unique slug index is created. Organizations with equal slugs share the
+// same sc_<slug> database and must be separated by hand: give all but one a
+// new slug and move their collections to the new database.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/repo"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+func main() {
+	cfg, err := app.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	appDB, err := db.ConnectToDatabase(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+
+	orgs, err := repo.NewOrganizationRepository(appDB).GetOrganizations(&app.RequestContext{}, bson.M{})
+	if err != nil {
+		log.Fatalf("Failed to list organizations: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	databases, err := appDB.Client.ListDatabaseNames(ctx, bson.M{"name": bson.M{"$regex": "^sc_"}})
+	if err != nil {
+		log.Fatalf("Failed to list databases: %v", err)
+	}
+
+	issues := service.CheckOrganizationSlugs(orgs, databases)
+	log.Printf("checked %d organizations and %d databases", len(orgs), len(databases))
+	for _, issue := range issues {
+		log.Println(issue)
+	}
+	if err := db.DisconnectDatabase(appDB); err != nil {
+		log.Printf("Failed to disconnect from the database: %v", err)
+	}
+	if len(issues) > 0 {
+		os.Exit(1)
+	}
+}
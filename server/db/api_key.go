@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// apiKeyTouchInterval limits how often the last used time of a key is written.
+const apiKeyTouchInterval = time.Minute
+
+// GetAPIKeyByPrefix loads the API key with the prefix and its organization,
+// which must be active.
+func (d *AppDB) GetAPIKeyByPrefix(prefix string) (*model.APIKey, *model.Organization, error) {
+	var key model.APIKey
+	err := d.GetCoreDatabase().Collection("api_keys").FindOne(context.Background(), bson.M{"prefix": prefix}).Decode(&key)
+	if err != nil {
+		return nil, nil, errors.New("api key not found")
+	}
+
+	var organization model.Organization
+	orgOpts := options.FindOne().SetProjection(bson.M{"_id": 1, "name": 1, "slug": 1, "is_active": 1})
+	err = d.GetCoreDatabase().Collection("organizations").FindOne(context.Background(), bson.M{"_id": key.OrganizationID}, orgOpts).Decode(&organization)
+	if err != nil {
+		return nil, nil, errors.New("organization not found")
+	}
+	if !organization.IsActive {
+		return nil, nil, errors.New("organization is not active")
+	}
+	return &key, &organization, nil
+}
+
+// TouchAPIKey records that the key was used.
+func (d *AppDB) TouchAPIKey(key *model.APIKey) {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyTouchInterval {
+		return
+	}
+	ctx, cancel := GetDBContext()
+	defer cancel()
+	_, err := d.GetCoreDatabase().Collection("api_keys").UpdateOne(ctx, bson.M{"_id": key.ID}, bson.M{"$set": bson.M{"last_used_at": now}})
+	if err != nil {
+		log.Printf("failed to update api key last used time: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// cacheWatchRetryDelay is the wait before the change stream is reopened after
+// an error.
+const cacheWatchRetryDelay = 5 * time.Second
+
+type cacheChangeEvent struct {
+	NS struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID bson.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+}
+
+// WatchCacheInvalidations publishes the updates and deletes of users,
+// identities and organizations, including those made by other replicas, to
+// CacheInvalidations until the context is cancelled. Change streams need a
+// replica set.
+func (d *AppDB) WatchCacheInvalidations(ctx context.Context) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"ns.coll":       bson.M{"$in": []string{"users", "identities", "organizations"}},
+		"operationType": bson.M{"$in": []string{"update", "replace", "delete"}},
+	}}}}
+
+	for reopened := false; ctx.Err() == nil; reopened = true {
+		stream, err := d.GetCoreDatabase().Watch(ctx, pipeline)
+		if err != nil {
+			log.Printf("failed to watch cache invalidations: %v", err)
+		} else {
+			// Changes made while the stream was down were missed.
+			if reopened {
+				CacheInvalidations.Publish(CacheInvalidation{All: true})
+			}
+			for stream.Next(ctx) {
+				var event cacheChangeEvent
+				if err := stream.Decode(&event); err != nil {
+					log.Printf("failed to decode change event: %v", err)
+					continue
+				}
+				CacheInvalidations.Publish(invalidationForChange(event))
+			}
+			if err := stream.Err(); err != nil && ctx.Err() == nil {
+				log.Printf("cache invalidation stream failed: %v", err)
+			}
+			_ = stream.Close(context.Background())
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(cacheWatchRetryDelay):
+		}
+	}
+}
+
+func invalidationForChange(event cacheChangeEvent) CacheInvalidation {
+	switch event.NS.Coll {
+	case "users":
+		return CacheInvalidation{UserID: event.DocumentKey.ID}
+	case "identities":
+		return CacheInvalidation{IdentityID: event.DocumentKey.ID}
+	default:
+		return CacheInvalidation{OrgID: event.DocumentKey.ID}
+	}
+}
@@ -18,7 +18,12 @@ type CacheUser struct {
 	TTL   time.Time
 }
 
-var cacheUserStore = make(map[string]*CacheUser)
+// cacheUserKey identifies a cached user. A zero OrgID stands for the current
+// organization of the identity.
+type cacheUserKey struct {
+	Email string
+	OrgID bson.ObjectID
+}
 
 type AppDB struct {
 	Client *mongo.Client
@@ -35,33 +40,44 @@ func (d *AppDB) GetCoreDatabase() *mongo.Database {
 
 func (d *AppDB) GetOrgDatabase(orgName string) *mongo.Database {
 	// Assuming "organization" is the name of your organization database.
-	dbName := "sc_" + orgName
+	dbName := model.OrgDatabasePrefix + orgName
 	return d.Client.Database(dbName)
 }
 
 func (d *AppDB) GetUserByEmail(email string) (*CacheUser, error) {
+	return d.GetUserByEmailForOrg(email, bson.NilObjectID)
+}
 
-	if cached, ok := cacheUserStore[email]; ok {
-		if time.Now().Before(cached.TTL) {
-			return cached, nil
-		}
+// GetUserByEmailForOrg loads the user of the email in the given organization,
+// or in the current organization of the identity when orgID is zero. It fails
+// when the email is not an active member of the organization.
+func (d *AppDB) GetUserByEmailForOrg(email string, orgID bson.ObjectID) (*CacheUser, error) {
+	key := cacheUserKey{Email: email, OrgID: orgID}
+	if cached, ok := userCacheStore.get(key); ok {
+		return cached, nil
 	}
 
 	// Get current organization id from identity
 	identitiesCol := d.GetCoreDatabase().Collection("identities")
 	var identity model.Identity
-	identityOpts := options.FindOne().SetProjection(bson.M{"_id": 1, "email": 1, "is_active": 1, "current_org_id": 1})
+	identityOpts := options.FindOne().SetProjection(bson.M{"_id": 1, "email": 1, "is_active": 1, "current_org_id": 1, "deleted_at": 1})
 	err := identitiesCol.FindOne(context.Background(), bson.M{"email": email}, identityOpts).Decode(&identity)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
+	if !identity.DeletedAt.IsZero() {
+		return nil, errors.New("user has been deleted")
+	}
+	if orgID.IsZero() {
+		orgID = identity.CurrentOrgID
+	}
 
 	// load users from org
 	userOrgCol := d.GetCoreDatabase().Collection("users")
 	var user model.User
-	err = userOrgCol.FindOne(context.Background(), bson.M{"email": email, "org_id": identity.CurrentOrgID}).Decode(&user)
+	err = userOrgCol.FindOne(context.Background(), bson.M{"identity_id": identity.ID, "org_id": orgID}).Decode(&user)
 	if err != nil {
-		return nil, errors.New("user don't belong to any organization")
+		return nil, errors.New("user don't belong to the organization")
 	}
 	if !user.IsActive {
 		return nil, errors.New("user is not active")
@@ -70,8 +86,8 @@ func (d *AppDB) GetUserByEmail(email string) (*CacheUser, error) {
 	// Load organization name
 	orgCol := d.GetCoreDatabase().Collection("organizations")
 	var organization model.Organization
-	orgOpts := options.FindOne().SetProjection(bson.M{"_id": 1, "name": 1, "slug": 1, "is_active": 1})
-	err = orgCol.FindOne(context.Background(), bson.M{"_id": identity.CurrentOrgID}, orgOpts).Decode(&organization)
+	orgOpts := options.FindOne().SetProjection(bson.M{"_id": 1, "name": 1, "slug": 1, "is_active": 1, "microsoft_tenant_ids": 1})
+	err = orgCol.FindOne(context.Background(), bson.M{"_id": orgID}, orgOpts).Decode(&organization)
 	if err != nil {
 		return nil, errors.New("organization not found")
 	}
@@ -79,16 +95,14 @@ func (d *AppDB) GetUserByEmail(email string) (*CacheUser, error) {
 		return nil, errors.New("organization is not active")
 	}
 
-	usr := CacheUser{
+	usr := &CacheUser{
 		Email: email,
 		User:  &user,
 		Org:   &organization,
-		TTL:   time.Now().Add(5 * time.Minute), // Cache for 5 minutes
 	}
+	userCacheStore.set(key, usr)
 
-	cacheUserStore[email] = &usr
-
-	return cacheUserStore[email], nil
+	return usr, nil
 
 }
 
@@ -114,10 +128,9 @@ func DisconnectDatabase(db *AppDB) error {
 	return nil
 }
 
-func GetDBContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 30*time.Second)
-}
+// DBTimeout bounds every database operation.
+const DBTimeout = 30 * time.Second
 
-func DeleteCacheUser(email string) {
-	delete(cacheUserStore, email)
+func GetDBContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), DBTimeout)
 }
@@ -15,6 +15,9 @@ type MongoSession interface {
 	CommitTransaction(context.Context) error
 	AbortTransaction(context.Context) error
 	EndSession(context.Context)
+	// Context binds the session to ctx. Operations run with the returned
+	// context are part of the session's transaction.
+	Context(ctx context.Context) context.Context
 }
 
 // SessionProvider is a custom interface for a MongoDB client that can start sessions.
@@ -62,3 +65,7 @@ func (s *mongoSessionWrapper) AbortTransaction(ctx context.Context) error {
 func (s *mongoSessionWrapper) EndSession(ctx context.Context) {
 	s.Session.EndSession(ctx)
 }
+
+func (s *mongoSessionWrapper) Context(ctx context.Context) context.Context {
+	return mongo.NewSessionContext(ctx, &s.Session)
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// GetOrganizationBySSO loads the active organization that enabled single
+// sign-on with the issuer for one of the client IDs.
+func (d *AppDB) GetOrganizationBySSO(issuer string, clientIDs []string) (*model.Organization, error) {
+	if issuer == "" || len(clientIDs) == 0 {
+		return nil, errors.New("organization not found")
+	}
+	filter := bson.M{
+		"sso.enabled":   true,
+		"sso.issuer":    issuer,
+		"sso.client_id": bson.M{"$in": clientIDs},
+		"is_active":     true,
+	}
+	var organization model.Organization
+	if err := d.GetCoreDatabase().Collection("organizations").FindOne(context.Background(), filter).Decode(&organization); err != nil {
+		return nil, errors.New("organization not found")
+	}
+	return &organization, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// maxTransactionAttempts bounds how often WithTransaction runs a transaction
+// that failed with a transient error.
+const maxTransactionAttempts = 3
+
+// WithTransaction runs fn in a transaction of a new session and commits it.
+// The context passed to fn is bound to the session; every operation of the
+// transaction must use it. The whole transaction is retried when it fails with
+// a TransientTransactionError, and the commit alone when its result is
+// unknown.
+func WithTransaction(ctx context.Context, sessions SessionProvider, fn func(txCtx context.Context) error) error {
+	session, err := sessions.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	for attempt := 1; ; attempt++ {
+		if err := session.StartTransaction(); err != nil {
+			return err
+		}
+		err := fn(session.Context(ctx))
+		if err == nil {
+			err = commitTransaction(ctx, session)
+			if err == nil {
+				return nil
+			}
+		} else {
+			_ = session.AbortTransaction(ctx)
+		}
+		if attempt == maxTransactionAttempts || !hasErrorLabel(err, "TransientTransactionError") {
+			return err
+		}
+		log.Printf("retrying transaction after transient error (attempt %d): %v", attempt, err)
+	}
+}
+
+func commitTransaction(ctx context.Context, session MongoSession) error {
+	for attempt := 1; ; attempt++ {
+		err := session.CommitTransaction(ctx)
+		if err == nil || attempt == maxTransactionAttempts || !hasErrorLabel(err, "UnknownTransactionCommitResult") {
+			return err
+		}
+		log.Printf("retrying commit with unknown result (attempt %d): %v", attempt, err)
+	}
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	defaultUserCacheSize = 10000
+	defaultUserCacheTTL  = 5 * time.Minute
+)
+
+// UserCacheStats are the counters of the user cache since the process started.
+type UserCacheStats struct {
+	Size          int   `json:"size"`
+	MaxEntries    int   `json:"max_entries"`
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Expirations   int64 `json:"expirations"`
+	Evictions     int64 `json:"evictions"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+type userCacheEntry struct {
+	key  cacheUserKey
+	user *CacheUser
+}
+
+// userCache is a size bounded LRU cache of the users resolved for requests.
+// Entries expire after the TTL and are dropped when a CacheInvalidation
+// matches them.
+type userCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[cacheUserKey]*list.Element
+	lru        *list.List
+	stats      UserCacheStats
+}
+
+func newUserCache(maxEntries int, ttl time.Duration) *userCache {
+	return &userCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    map[cacheUserKey]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+var userCacheStore = newUserCache(defaultUserCacheSize, defaultUserCacheTTL)
+
+func init() {
+	CacheInvalidations.Subscribe(userCacheStore.invalidate)
+}
+
+// ConfigureUserCache sets the size bound and TTL of the user cache and clears it.
+func ConfigureUserCache(maxEntries int, ttl time.Duration) {
+	if maxEntries <= 0 {
+		maxEntries = defaultUserCacheSize
+	}
+	if ttl <= 0 {
+		ttl = defaultUserCacheTTL
+	}
+	c := userCacheStore
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxEntries = maxEntries
+	c.ttl = ttl
+	c.entries = map[cacheUserKey]*list.Element{}
+	c.lru.Init()
+}
+
+// GetUserCacheStats returns the current counters of the user cache.
+func GetUserCacheStats() UserCacheStats {
+	c := userCacheStore
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	stats.MaxEntries = c.maxEntries
+	return stats
+}
+
+func (c *userCache) get(key cacheUserKey) (*CacheUser, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		c.stats.Misses++
+		return nil, false
+	}
+	entry := elem.Value.(*userCacheEntry)
+	if time.Now().After(entry.user.TTL) {
+		c.remove(elem)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.user, true
+}
+
+// set caches the user and sets its expiry.
+func (c *userCache) set(key cacheUserKey, user *CacheUser) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	user.TTL = time.Now().Add(c.ttl)
+	if elem, found := c.entries[key]; found {
+		elem.Value.(*userCacheEntry).user = user
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&userCacheEntry{key: key, user: user})
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *userCache) invalidate(inv CacheInvalidation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		entry := elem.Value.(*userCacheEntry)
+		if inv.matches(entry.key, entry.user) {
+			c.remove(elem)
+			c.stats.Invalidations++
+		}
+		elem = next
+	}
+}
+
+func (c *userCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*userCacheEntry).key)
+}
+
+// CacheInvalidation names the users, identities or organizations whose cached
+// data is stale. Every non-zero field is matched on its own; All drops
+// everything.
+type CacheInvalidation struct {
+	All        bool          `json:"all,omitempty"`
+	Email      string        `json:"email,omitempty"`
+	UserID     bson.ObjectID `json:"user_id,omitzero"`
+	IdentityID bson.ObjectID `json:"identity_id,omitzero"`
+	OrgID      bson.ObjectID `json:"org_id,omitzero"`
+}
+
+func (inv CacheInvalidation) matches(key cacheUserKey, user *CacheUser) bool {
+	if inv.All {
+		return true
+	}
+	if inv.Email != "" && key.Email == inv.Email {
+		return true
+	}
+	if !inv.UserID.IsZero() && user.User != nil && user.User.ID == inv.UserID {
+		return true
+	}
+	if !inv.IdentityID.IsZero() && user.User != nil && user.User.IdentityID == inv.IdentityID {
+		return true
+	}
+	if !inv.OrgID.IsZero() && user.Org != nil && user.Org.ID == inv.OrgID {
+		return true
+	}
+	return false
+}
+
+// InvalidationBus delivers cache invalidations to the caches of the process.
+type InvalidationBus struct {
+	mu          sync.RWMutex
+	subscribers []func(CacheInvalidation)
+}
+
+// CacheInvalidations is the bus of the process. Updates publish to it directly
+// and, with WatchCacheInvalidations, the updates of other replicas as well.
+var CacheInvalidations = &InvalidationBus{}
+
+func (b *InvalidationBus) Subscribe(fn func(CacheInvalidation)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+}
+
+func (b *InvalidationBus) Publish(inv CacheInvalidation) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subscribers {
+		fn(inv)
+	}
+}
+
+// DeleteCacheUser drops the cached users of the email in every organization.
+func DeleteCacheUser(email string) {
+	CacheInvalidations.Publish(CacheInvalidation{Email: email})
+}
+
+// DeleteCacheIdentity drops the cached users of the identity, e.g. after its
+// current organization changed.
+func DeleteCacheIdentity(identityID bson.ObjectID) {
+	CacheInvalidations.Publish(CacheInvalidation{IdentityID: identityID})
+}
+
+// DeleteCacheOrg drops the cached users of the organization.
+func DeleteCacheOrg(orgID bson.ObjectID) {
+	CacheInvalidations.Publish(CacheInvalidation{OrgID: orgID})
+}
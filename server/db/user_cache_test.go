@@ -0,0 +1,113 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+func newTestCacheUser(email string, orgID bson.ObjectID) *CacheUser {
+	return &CacheUser{
+		Email: email,
+		User:  &model.User{Base: model.Base{ID: bson.NewObjectID()}, IdentityID: bson.NewObjectID(), Email: email, OrganizationID: orgID},
+		Org:   &model.Organization{Base: model.Base{ID: orgID}},
+	}
+}
+
+func TestUserCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newUserCache(2, time.Minute)
+	orgID := bson.NewObjectID()
+	a, b, c := cacheUserKey{Email: "a@example.com"}, cacheUserKey{Email: "b@example.com"}, cacheUserKey{Email: "c@example.com"}
+
+	cache.set(a, newTestCacheUser(a.Email, orgID))
+	cache.set(b, newTestCacheUser(b.Email, orgID))
+	cache.get(a)
+	cache.set(c, newTestCacheUser(c.Email, orgID))
+
+	if _, ok := cache.get(b); ok {
+		t.Errorf("expected the least recently used entry to be evicted")
+	}
+	if _, ok := cache.get(a); !ok {
+		t.Errorf("expected the recently used entry to be kept")
+	}
+	if cache.stats.Evictions != 1 || cache.lru.Len() != 2 {
+		t.Errorf("unexpected stats %+v with %d entries", cache.stats, cache.lru.Len())
+	}
+}
+
+func TestUserCacheExpiresEntries(t *testing.T) {
+	cache := newUserCache(10, time.Millisecond)
+	key := cacheUserKey{Email: "a@example.com"}
+	cache.set(key, newTestCacheUser(key.Email, bson.NewObjectID()))
+
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.get(key); ok {
+		t.Fatalf("expected the entry to expire")
+	}
+	if cache.stats.Expirations != 1 || cache.lru.Len() != 0 {
+		t.Errorf("expected the expired entry to be removed, stats %+v", cache.stats)
+	}
+}
+
+func TestUserCacheInvalidation(t *testing.T) {
+	cache := newUserCache(10, time.Minute)
+	orgA, orgB := bson.NewObjectID(), bson.NewObjectID()
+	jane := newTestCacheUser("jane@example.com", orgA)
+	janeInB := newTestCacheUser("jane@example.com", orgB)
+	john := newTestCacheUser("john@example.com", orgB)
+	cache.set(cacheUserKey{Email: "jane@example.com"}, jane)
+	cache.set(cacheUserKey{Email: "jane@example.com", OrgID: orgB}, janeInB)
+	cache.set(cacheUserKey{Email: "john@example.com"}, john)
+
+	cache.invalidate(CacheInvalidation{Email: "jane@example.com"})
+	if cache.lru.Len() != 1 {
+		t.Fatalf("expected every organization of the email to be dropped, %d entries left", cache.lru.Len())
+	}
+
+	cache.invalidate(CacheInvalidation{IdentityID: bson.NewObjectID()})
+	if cache.lru.Len() != 1 {
+		t.Fatalf("expected an unrelated identity to keep the entry")
+	}
+	cache.invalidate(CacheInvalidation{OrgID: orgB})
+	if cache.lru.Len() != 0 {
+		t.Fatalf("expected the organization's users to be dropped")
+	}
+
+	cache.set(cacheUserKey{Email: "john@example.com"}, john)
+	cache.invalidate(CacheInvalidation{UserID: john.User.ID})
+	if _, ok := cache.get(cacheUserKey{Email: "john@example.com"}); ok {
+		t.Fatalf("expected the user to be dropped by its ID")
+	}
+}
+
+func TestUserCacheConcurrentAccess(t *testing.T) {
+	cache := newUserCache(50, time.Minute)
+	orgID := bson.NewObjectID()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 500 {
+				key := cacheUserKey{Email: fmt.Sprintf("user%d@example.com", (i*j)%100)}
+				if _, ok := cache.get(key); !ok {
+					cache.set(key, newTestCacheUser(key.Email, orgID))
+				}
+				if j%50 == 0 {
+					cache.invalidate(CacheInvalidation{Email: key.Email})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cache.lru.Len() > 50 || len(cache.entries) != cache.lru.Len() {
+		t.Errorf("cache exceeded its bound or lost track of entries: %d listed, %d indexed", cache.lru.Len(), len(cache.entries))
+	}
+}
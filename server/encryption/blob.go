@@ -0,0 +1,264 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gaeaglobal/exto/server/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Encrypted blobs start with a header made of the magic, the version of the
+// data key and the random prefix of the nonces of the chunks. The content
+// follows in chunks of blobChunkSize bytes, each sealed with AES-256-GCM
+// under the nonce prefix and the index of the chunk. The last chunk is always
+// shorter than blobChunkSize, empty if need be, so that a truncated blob
+// fails to decrypt.
+const (
+	blobMagic       = "EXTOENC1"
+	blobNoncePrefix = 8
+	blobHeaderSize  = len(blobMagic) + 4 + blobNoncePrefix
+	blobChunkSize   = 64 * 1024
+	blobTagSize     = 16
+)
+
+// EncryptedBlobStore encrypts the blobs of another store with the data key of
+// the organization they belong to. Blobs stored before encryption was enabled
+// are read as they are.
+type EncryptedBlobStore struct {
+	blobs storage.BlobStore
+	keys  DataKeySource
+	orgOf func(key string) (bson.ObjectID, bool)
+}
+
+// NewEncryptedBlobStore wraps the store. orgOf tells the organization of a
+// blob from its key; blobs that belong to no organization are refused.
+func NewEncryptedBlobStore(blobs storage.BlobStore, keys DataKeySource, orgOf func(key string) (bson.ObjectID, bool)) *EncryptedBlobStore {
+	return &EncryptedBlobStore{blobs: blobs, keys: keys, orgOf: orgOf}
+}
+
+func (s *EncryptedBlobStore) org(key string) (bson.ObjectID, error) {
+	orgID, ok := s.orgOf(key)
+	if !ok {
+		return bson.ObjectID{}, fmt.Errorf("blob %s belongs to no organization and cannot be encrypted", key)
+	}
+	return orgID, nil
+}
+
+// encryptedSize is the size of the encrypted blob of a content of the size.
+func encryptedSize(size int64) int64 {
+	return int64(blobHeaderSize) + size + blobTagSize*(size/blobChunkSize+1)
+}
+
+// decryptedSize is the size of the content of an encrypted blob of the size.
+func decryptedSize(size int64) int64 {
+	body := size - int64(blobHeaderSize)
+	chunks := body/(blobChunkSize+blobTagSize) + 1
+	return body - blobTagSize*chunks
+}
+
+func (s *EncryptedBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	orgID, err := s.org(key)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return errors.New("the size of an encrypted blob must be known")
+	}
+	dataKey, err := s.keys.CurrentDataKey(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to get data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey.Key)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, blobHeaderSize)
+	copy(header, blobMagic)
+	binary.BigEndian.PutUint32(header[len(blobMagic):], dataKey.Version)
+	if _, err := rand.Read(header[len(blobMagic)+4:]); err != nil {
+		return err
+	}
+	enc := &chunkEncrypter{src: r, remaining: size, aead: aead, header: header, key: key}
+	enc.buf = append(enc.buf, header...)
+	return s.blobs.Put(ctx, key, enc, encryptedSize(size), contentType)
+}
+
+func (s *EncryptedBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *storage.BlobInfo, error) {
+	orgID, err := s.org(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, info, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := make([]byte, blobHeaderSize)
+	n, err := io.ReadFull(body, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		body.Close()
+		return nil, nil, err
+	}
+	if n < blobHeaderSize || string(header[:len(blobMagic)]) != blobMagic {
+		// A blob stored before encryption was enabled.
+		return readCloser{io.MultiReader(bytes.NewReader(header[:n]), body), body}, info, nil
+	}
+
+	dataKey, err := s.keys.DataKey(ctx, orgID, binary.BigEndian.Uint32(header[len(blobMagic):]))
+	if err != nil {
+		body.Close()
+		return nil, nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey.Key)
+	if err != nil {
+		body.Close()
+		return nil, nil, err
+	}
+	plainInfo := *info
+	plainInfo.Size = decryptedSize(info.Size)
+	dec := &chunkDecrypter{src: body, aead: aead, header: header, key: key}
+	return readCloser{dec, body}, &plainInfo, nil
+}
+
+// Stat reads the header of the blob to tell the size of its content.
+func (s *EncryptedBlobStore) Stat(ctx context.Context, key string) (*storage.BlobInfo, error) {
+	body, info, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	body.Close()
+	return info, nil
+}
+
+func (s *EncryptedBlobStore) Delete(ctx context.Context, key string) error {
+	return s.blobs.Delete(ctx, key)
+}
+
+func (s *EncryptedBlobStore) DeletePrefix(ctx context.Context, prefix string) error {
+	return s.blobs.DeletePrefix(ctx, prefix)
+}
+
+// List lists the blobs with the size they are stored with, which for the
+// encrypted ones is larger than their content.
+func (s *EncryptedBlobStore) List(ctx context.Context, prefix string, fn func(info storage.BlobInfo) error) error {
+	return s.blobs.List(ctx, prefix, fn)
+}
+
+// PresignGet is not supported: links would serve the encrypted blob, so the
+// downloads are streamed through the server instead.
+func (s *EncryptedBlobStore) PresignGet(ctx context.Context, key string, fileName string, ttl time.Duration) (string, error) {
+	return "", storage.ErrPresignNotSupported
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// chunkNonce is the nonce of the chunk of the index: the prefix of the
+// header followed by the index.
+func chunkNonce(header []byte, index uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, header[len(blobMagic)+4:])
+	binary.BigEndian.PutUint32(nonce[blobNoncePrefix:], index)
+	return nonce
+}
+
+// chunkAdditionalData binds a chunk to the header and the key of its blob,
+// and tells whether it is the last one.
+func chunkAdditionalData(header []byte, key string, last bool) []byte {
+	ad := append(append([]byte{}, header...), key...)
+	if last {
+		return append(ad, 1)
+	}
+	return append(ad, 0)
+}
+
+// chunkEncrypter reads the encrypted blob of a content of known size.
+type chunkEncrypter struct {
+	src       io.Reader
+	remaining int64
+	aead      cipher.AEAD
+	header    []byte
+	key       string
+
+	index uint32
+	buf   []byte
+	done  bool
+}
+
+func (e *chunkEncrypter) Read(p []byte) (int, error) {
+	for len(e.buf) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		n := int64(blobChunkSize)
+		if e.remaining < n {
+			n = e.remaining
+		}
+		chunk := make([]byte, n, n+blobTagSize)
+		if _, err := io.ReadFull(e.src, chunk); err != nil {
+			return 0, fmt.Errorf("failed to read blob content: %w", err)
+		}
+		e.remaining -= n
+		last := n < blobChunkSize
+		e.buf = e.aead.Seal(chunk[:0], chunkNonce(e.header, e.index), chunk, chunkAdditionalData(e.header, e.key, last))
+		e.index++
+		e.done = last
+	}
+	n := copy(p, e.buf)
+	e.buf = e.buf[n:]
+	return n, nil
+}
+
+// chunkDecrypter reads the content of an encrypted blob, whose header was
+// already read from src.
+type chunkDecrypter struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	header []byte
+	key    string
+
+	index uint32
+	buf   []byte
+	done  bool
+	err   error
+}
+
+func (d *chunkDecrypter) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		chunk := make([]byte, blobChunkSize+blobTagSize)
+		n, err := io.ReadFull(d.src, chunk)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			d.err = err
+			continue
+		}
+		last := n < len(chunk)
+		plain, err := d.aead.Open(chunk[:0], chunkNonce(d.header, d.index), chunk[:n], chunkAdditionalData(d.header, d.key, last))
+		if err != nil {
+			d.err = ErrDecrypt
+			continue
+		}
+		d.buf = plain
+		d.index++
+		d.done = last
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
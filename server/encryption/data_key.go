@@ -0,0 +1,30 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrDecrypt is returned when a ciphertext cannot be decrypted because it was
+// altered or encrypted with another key.
+var ErrDecrypt = errors.New("failed to decrypt: invalid ciphertext or key")
+
+// DataKey is an unwrapped data key of an organization. Rotating the data key
+// of an organization adds a version; the older versions are kept to decrypt
+// what was encrypted with them.
+type DataKey struct {
+	Version uint32
+	Key     []byte
+}
+
+// DataKeySource provides the data keys of the organizations.
+type DataKeySource interface {
+	// CurrentDataKey returns the latest version of the data key of the
+	// organization, which new data is encrypted with. It is created on first
+	// use.
+	CurrentDataKey(ctx context.Context, orgID bson.ObjectID) (*DataKey, error)
+	// DataKey returns a version of the data key of the organization.
+	DataKey(ctx context.Context, orgID bson.ObjectID, version uint32) (*DataKey, error)
+}
@@ -0,0 +1,255 @@
+package encryption_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/encryption"
+	"github.com/gaeaglobal/exto/server/storage"
+)
+
+// testDataKeys is a data key source with the versions of a single key.
+type testDataKeys struct {
+	keys []*encryption.DataKey
+}
+
+func newTestDataKeys(t *testing.T, versions int) *testDataKeys {
+	s := &testDataKeys{}
+	for v := 1; v <= versions; v++ {
+		key, err := encryption.NewDataKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.keys = append(s.keys, &encryption.DataKey{Version: uint32(v), Key: key})
+	}
+	return s
+}
+
+func (s *testDataKeys) CurrentDataKey(ctx context.Context, orgID bson.ObjectID) (*encryption.DataKey, error) {
+	return s.keys[len(s.keys)-1], nil
+}
+
+func (s *testDataKeys) DataKey(ctx context.Context, orgID bson.ObjectID, version uint32) (*encryption.DataKey, error) {
+	if version == 0 || int(version) > len(s.keys) {
+		return nil, fmt.Errorf("data key version %d not found", version)
+	}
+	return s.keys[version-1], nil
+}
+
+func writeKeyFile(t *testing.T, path string, primary string, keys map[string][]byte, modTime time.Time) {
+	encoded := ""
+	for id, key := range keys {
+		if encoded != "" {
+			encoded += ","
+		}
+		encoded += fmt.Sprintf("%q:%q", id, base64.StdEncoding.EncodeToString(key))
+	}
+	content := fmt.Sprintf(`{"primary":%q,"keys":{%s}}`, primary, encoded)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalKeyFileProviderRotatesMasterKey(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	oldKey, _ := encryption.NewDataKey()
+	newKey, _ := encryption.NewDataKey()
+	writeKeyFile(t, path, "old", map[string][]byte{"old": oldKey}, time.Now().Add(-time.Hour))
+
+	provider, err := encryption.NewLocalKeyFileProvider(path)
+	if err != nil {
+		t.Fatalf("NewLocalKeyFileProvider returned error: %v", err)
+	}
+	dataKey, _ := encryption.NewDataKey()
+	keyID, wrapped, err := provider.Wrap(ctx, dataKey)
+	if err != nil || keyID != "old" {
+		t.Fatalf("expected the data key to be wrapped with the old key, got %q, %v", keyID, err)
+	}
+
+	// Another instance wraps with a new master key added to the file: this
+	// one reloads the file when it meets the unknown key.
+	writeKeyFile(t, path, "new", map[string][]byte{"old": oldKey, "new": newKey}, time.Now())
+	other, err := encryption.NewLocalKeyFileProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newID, rewrapped, err := other.Wrap(ctx, dataKey)
+	if err != nil || newID != "new" {
+		t.Fatalf("expected the data key to be wrapped with the new key, got %q, %v", newID, err)
+	}
+	for id, w := range map[string][]byte{keyID: wrapped, newID: rewrapped} {
+		unwrapped, err := provider.Unwrap(ctx, id, w)
+		if err != nil || !bytes.Equal(unwrapped, dataKey) {
+			t.Errorf("failed to unwrap the data key wrapped with %q: %v", id, err)
+		}
+	}
+
+	if _, err := provider.Unwrap(ctx, newID, wrapped); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Errorf("expected a data key wrapped with another key to fail, got %v", err)
+	}
+	if _, err := provider.Unwrap(ctx, "missing", wrapped); !errors.Is(err, encryption.ErrUnknownMasterKey) {
+		t.Errorf("expected ErrUnknownMasterKey, got %v", err)
+	}
+}
+
+func TestEncryptedBlobStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	orgID := bson.NewObjectID()
+	prefix := "uploads/" + orgID.Hex() + "/"
+	local := storage.NewLocalBlobStore(t.TempDir())
+	keys := newTestDataKeys(t, 1)
+	orgOf := func(key string) (bson.ObjectID, bool) {
+		return orgID, len(key) > len(prefix) && key[:len(prefix)] == prefix
+	}
+	blobs := encryption.NewEncryptedBlobStore(local, keys, orgOf)
+
+	for _, size := range []int{0, 10, 64 * 1024, 3*64*1024 + 7} {
+		content := make([]byte, size)
+		rand.Read(content)
+		key := fmt.Sprintf("%sdoc-%d.pdf", prefix, size)
+		if err := blobs.Put(ctx, key, bytes.NewReader(content), int64(size), "application/pdf"); err != nil {
+			t.Fatalf("Put of %d bytes returned error: %v", size, err)
+		}
+
+		stored, _, err := local.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := io.ReadAll(stored)
+		stored.Close()
+		if size > 0 && bytes.Contains(raw, content) {
+			t.Errorf("expected the blob of %d bytes to be stored encrypted", size)
+		}
+
+		body, info, err := blobs.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get of %d bytes returned error: %v", size, err)
+		}
+		decrypted, err := io.ReadAll(body)
+		body.Close()
+		if err != nil || !bytes.Equal(decrypted, content) || info.Size != int64(size) {
+			t.Errorf("round trip of %d bytes failed: size %d, err %v", size, info.Size, err)
+		}
+	}
+
+	// Blobs stored before encryption are read as they are.
+	plain := []byte("%PDF-1.4 legacy")
+	if err := local.Put(ctx, prefix+"legacy.pdf", bytes.NewReader(plain), int64(len(plain)), ""); err != nil {
+		t.Fatal(err)
+	}
+	body, _, err := blobs.Get(ctx, prefix+"legacy.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(legacy, plain) {
+		t.Errorf("expected the legacy blob to be read as it is, got %q", legacy)
+	}
+
+	if err := blobs.Put(ctx, "misc/a.pdf", bytes.NewReader(plain), int64(len(plain)), ""); err == nil {
+		t.Error("expected a blob of no organization to be refused")
+	}
+}
+
+func TestEncryptedBlobStoreDetectsTampering(t *testing.T) {
+	ctx := context.Background()
+	orgID := bson.NewObjectID()
+	dir := t.TempDir()
+	local := storage.NewLocalBlobStore(dir)
+	blobs := encryption.NewEncryptedBlobStore(local, newTestDataKeys(t, 1), func(string) (bson.ObjectID, bool) { return orgID, true })
+
+	content := make([]byte, 2*64*1024)
+	if err := blobs.Put(ctx, "uploads/a.pdf", bytes.NewReader(content), int64(len(content)), ""); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "uploads", "a.pdf")
+	stored, _ := os.ReadFile(path)
+
+	for name, altered := range map[string][]byte{
+		"flipped":   append(append([]byte{}, stored[:100]...), append([]byte{stored[100] ^ 1}, stored[101:]...)...),
+		"truncated": stored[:20+64*1024+16],
+	} {
+		if err := os.WriteFile(path, altered, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		body, _, err := blobs.Get(ctx, "uploads/a.pdf")
+		if err == nil {
+			_, err = io.ReadAll(body)
+			body.Close()
+		}
+		if !errors.Is(err, encryption.ErrDecrypt) {
+			t.Errorf("expected the %s blob to fail to decrypt, got %v", name, err)
+		}
+	}
+}
+
+func TestFieldCipherRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	orgID := bson.NewObjectID()
+	keys := newTestDataKeys(t, 1)
+	cipher := encryption.NewFieldCipher(keys)
+
+	fields := map[string]any{
+		"patient_name": "Jane Doe",
+		"diagnosis":    bson.A{"a", "b"},
+		"total":        42.5,
+	}
+	encrypted, err := cipher.EncryptFields(ctx, orgID, fields, []string{"patient_name", "diagnosis", "missing"})
+	if err != nil {
+		t.Fatalf("EncryptFields returned error: %v", err)
+	}
+	if fields["patient_name"] != "Jane Doe" {
+		t.Error("expected the fields to be left unchanged")
+	}
+	if !encryption.IsEncrypted(encrypted["patient_name"]) || !encryption.IsEncrypted(encrypted["diagnosis"]) || encrypted["total"] != 42.5 {
+		t.Fatalf("expected only the sensitive fields to be encrypted, got %v", encrypted)
+	}
+
+	// The record is stored and read back; a key rotation in between does not
+	// prevent reading it.
+	doc, err := bson.Marshal(bson.M{"metadata": encrypted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored struct {
+		MetaData map[string]any `bson:"metadata"`
+	}
+	if err := bson.Unmarshal(doc, &stored); err != nil {
+		t.Fatal(err)
+	}
+	keys.keys = append(keys.keys, newTestDataKeys(t, 1).keys[0])
+	keys.keys[1].Version = 2
+
+	decrypted, err := cipher.DecryptFields(ctx, orgID, stored.MetaData)
+	if err != nil {
+		t.Fatalf("DecryptFields returned error: %v", err)
+	}
+	if decrypted["patient_name"] != "Jane Doe" || fmt.Sprint(decrypted["diagnosis"]) != "[a b]" || decrypted["total"] != 42.5 {
+		t.Errorf("unexpected decrypted fields %v", decrypted)
+	}
+
+	// A value copied to another field or organization does not decrypt.
+	moved := map[string]any{"total": stored.MetaData["patient_name"]}
+	if _, err := cipher.DecryptFields(ctx, orgID, moved); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Errorf("expected a moved value to fail to decrypt, got %v", err)
+	}
+	if _, err := cipher.DecryptFields(ctx, bson.NewObjectID(), stored.MetaData); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Errorf("expected a value of another organization to fail to decrypt, got %v", err)
+	}
+}
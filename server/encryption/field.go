@@ -0,0 +1,153 @@
+package encryption
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// EncryptedValueSubtype is the BSON binary subtype, from the user defined
+// range, of the encrypted values. Their data is the version of the data key
+// followed by the sealed value.
+const EncryptedValueSubtype byte = 0x80
+
+// FieldCipher encrypts the values of the sensitive fields of records with the
+// data key of their organization. A value is bound to the organization and
+// the name of its field, so it cannot be copied to another one.
+type FieldCipher struct {
+	keys DataKeySource
+}
+
+func NewFieldCipher(keys DataKeySource) *FieldCipher {
+	return &FieldCipher{keys: keys}
+}
+
+func fieldAdditionalData(orgID bson.ObjectID, name string) []byte {
+	return []byte(orgID.Hex() + "." + name)
+}
+
+// IsEncrypted reports whether the value is an encrypted value.
+func IsEncrypted(value any) bool {
+	b, ok := value.(bson.Binary)
+	return ok && b.Subtype == EncryptedValueSubtype
+}
+
+// EncryptFields returns a copy of the fields with the values of the named
+// ones encrypted.
+func (c *FieldCipher) EncryptFields(ctx context.Context, orgID bson.ObjectID, fields map[string]any, names []string) (map[string]any, error) {
+	if fields == nil || len(names) == 0 {
+		return fields, nil
+	}
+	var dataKey *DataKey
+	encrypted := make(map[string]any, len(fields))
+	for name, value := range fields {
+		encrypted[name] = value
+	}
+	for _, name := range names {
+		value, found := fields[name]
+		if !found || value == nil || IsEncrypted(value) {
+			continue
+		}
+		if dataKey == nil {
+			var err error
+			if dataKey, err = c.keys.CurrentDataKey(ctx, orgID); err != nil {
+				return nil, fmt.Errorf("failed to get data key: %w", err)
+			}
+		}
+		plaintext, err := bson.Marshal(bson.D{{Key: "v", Value: value}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", name, err)
+		}
+		sealed, err := seal(dataKey.Key, plaintext, fieldAdditionalData(orgID, name))
+		if err != nil {
+			return nil, err
+		}
+		data := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(sealed)), dataKey.Version)
+		encrypted[name] = bson.Binary{Subtype: EncryptedValueSubtype, Data: append(data, sealed...)}
+	}
+	return encrypted, nil
+}
+
+// Decrypt returns a copy of the value with the encrypted values of its
+// documents and arrays decrypted, at any depth. Decrypted documents are
+// bson.D, as the driver decodes them into an interface.
+func (c *FieldCipher) Decrypt(ctx context.Context, orgID bson.ObjectID, value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		decrypted := make(map[string]any, len(v))
+		for name, field := range v {
+			field, err := c.decryptField(ctx, orgID, name, field)
+			if err != nil {
+				return nil, err
+			}
+			decrypted[name] = field
+		}
+		return decrypted, nil
+	case bson.M:
+		decrypted, err := c.Decrypt(ctx, orgID, map[string]any(v))
+		if err != nil {
+			return nil, err
+		}
+		return bson.M(decrypted.(map[string]any)), nil
+	case bson.D:
+		decrypted := make(bson.D, len(v))
+		for i, e := range v {
+			field, err := c.decryptField(ctx, orgID, e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			decrypted[i] = bson.E{Key: e.Key, Value: field}
+		}
+		return decrypted, nil
+	case bson.A:
+		decrypted := make(bson.A, len(v))
+		for i, item := range v {
+			item, err := c.Decrypt(ctx, orgID, item)
+			if err != nil {
+				return nil, err
+			}
+			decrypted[i] = item
+		}
+		return decrypted, nil
+	default:
+		return value, nil
+	}
+}
+
+// DecryptFields returns a copy of the fields with their encrypted values
+// decrypted.
+func (c *FieldCipher) DecryptFields(ctx context.Context, orgID bson.ObjectID, fields map[string]any) (map[string]any, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	decrypted, err := c.Decrypt(ctx, orgID, fields)
+	if err != nil {
+		return nil, err
+	}
+	return decrypted.(map[string]any), nil
+}
+
+func (c *FieldCipher) decryptField(ctx context.Context, orgID bson.ObjectID, name string, value any) (any, error) {
+	if !IsEncrypted(value) {
+		return c.Decrypt(ctx, orgID, value)
+	}
+	data := value.(bson.Binary).Data
+	if len(data) < 4 {
+		return nil, fmt.Errorf("field %s: %w", name, ErrDecrypt)
+	}
+	dataKey, err := c.keys.DataKey(ctx, orgID, binary.BigEndian.Uint32(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+	plaintext, err := open(dataKey.Key, data[4:], fieldAdditionalData(orgID, name))
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", name, err)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(plaintext, &doc); err != nil || len(doc) != 1 {
+		return nil, fmt.Errorf("field %s: %w", name, ErrDecrypt)
+	}
+	return doc[0].Value, nil
+}
@@ -0,0 +1,257 @@
+// Package encryption encrypts the documents and the sensitive values of the
+// organizations at rest with envelope encryption: each organization has its
+// own data keys, which are stored wrapped by a master key that never leaves
+// the KeyProvider.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// keySize is the size of the master keys and of the data keys, for AES-256.
+const keySize = 32
+
+// ErrUnknownMasterKey is returned when unwrapping a data key with a master
+// key the provider does not have.
+var ErrUnknownMasterKey = errors.New("unknown master key")
+
+// KeyProvider wraps and unwraps data keys with master keys. It is implemented
+// by LocalKeyProvider for keys from the configuration or a key file, and can
+// be implemented with a KMS.
+type KeyProvider interface {
+	// PrimaryKeyID is the id of the master key new data keys are wrapped with.
+	PrimaryKeyID() string
+	// Wrap encrypts the data key with the primary master key and returns the
+	// id of that key with the wrapped data key.
+	Wrap(ctx context.Context, dataKey []byte) (string, []byte, error)
+	// Unwrap decrypts a data key wrapped with the master key of the id.
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// keyFile is the format of a key file: the master keys by id, base64
+// encoded, and the id of the primary one.
+//
+//	{"primary": "2026-10", "keys": {"2026-10": "...", "2025-01": "..."}}
+type keyFile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// LocalKeyProvider keeps the master keys in memory. Keys loaded from a key
+// file are reloaded when the file changes, so a master key can be rotated
+// without a restart: add the new key as the primary one, keep the old one
+// until the data keys are rewrapped, then remove it.
+type LocalKeyProvider struct {
+	path string
+
+	mu        sync.RWMutex
+	primary   string
+	keys      map[string][]byte
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// keyFileCheckInterval is how often the key file is checked for changes.
+const keyFileCheckInterval = 10 * time.Second
+
+// NewKeyProvider returns the provider of the master keys of the key file, or
+// of the single base64 encoded master key, or nil when neither is set and
+// encryption at rest is disabled.
+func NewKeyProvider(keyFilePath string, masterKey string) (KeyProvider, error) {
+	switch {
+	case keyFilePath != "":
+		return NewLocalKeyFileProvider(keyFilePath)
+	case masterKey != "":
+		key, err := base64.StdEncoding.DecodeString(masterKey)
+		if err != nil {
+			return nil, errors.New("invalid master key: not base64")
+		}
+		return NewLocalKeyProvider("default", map[string][]byte{"default": key})
+	default:
+		return nil, nil
+	}
+}
+
+// NewLocalKeyProvider returns a provider of fixed master keys.
+func NewLocalKeyProvider(primary string, keys map[string][]byte) (*LocalKeyProvider, error) {
+	if err := checkMasterKeys(primary, keys); err != nil {
+		return nil, err
+	}
+	return &LocalKeyProvider{primary: primary, keys: keys}, nil
+}
+
+// NewLocalKeyFileProvider returns a provider of the master keys of the key
+// file.
+func NewLocalKeyFileProvider(path string) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{path: path}
+	if err := p.reload(true); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func checkMasterKeys(primary string, keys map[string][]byte) error {
+	if _, ok := keys[primary]; !ok {
+		return fmt.Errorf("primary master key %q not found", primary)
+	}
+	for id, key := range keys {
+		if len(key) != keySize {
+			return fmt.Errorf("master key %q must be %d bytes", id, keySize)
+		}
+	}
+	return nil
+}
+
+// reload loads the key file again if it changed since it was last loaded.
+// Unless forced, the file is checked at most once per keyFileCheckInterval.
+func (p *LocalKeyProvider) reload(force bool) error {
+	if p.path == "" {
+		return nil
+	}
+	p.mu.RLock()
+	recent := time.Since(p.checkedAt) < keyFileCheckInterval
+	p.mu.RUnlock()
+	if recent && !force {
+		return nil
+	}
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	p.mu.Lock()
+	p.checkedAt = time.Now()
+	unchanged := info.ModTime().Equal(p.modTime) && p.keys != nil
+	p.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	var file keyFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("invalid key file: %w", err)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid master key %q: not base64", id)
+		}
+		keys[id] = key
+	}
+	if err := checkMasterKeys(file.Primary, keys); err != nil {
+		return fmt.Errorf("invalid key file: %w", err)
+	}
+
+	p.mu.Lock()
+	p.primary, p.keys, p.modTime = file.Primary, keys, info.ModTime()
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *LocalKeyProvider) PrimaryKeyID() string {
+	if err := p.reload(false); err != nil {
+		// The keys loaded before are kept until the file is fixed.
+		log.Printf("failed to reload master keys: %v", err)
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.primary
+}
+
+func (p *LocalKeyProvider) Wrap(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	if err := p.reload(false); err != nil {
+		return "", nil, err
+	}
+	p.mu.RLock()
+	keyID, key := p.primary, p.keys[p.primary]
+	p.mu.RUnlock()
+
+	wrapped, err := seal(key, dataKey, []byte(keyID))
+	if err != nil {
+		return "", nil, err
+	}
+	return keyID, wrapped, nil
+}
+
+func (p *LocalKeyProvider) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	p.mu.RLock()
+	key, ok := p.keys[keyID]
+	p.mu.RUnlock()
+	if !ok {
+		// The key may have been added to the file by another instance.
+		if err := p.reload(true); err != nil {
+			return nil, err
+		}
+		p.mu.RLock()
+		key, ok = p.keys[keyID]
+		p.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownMasterKey, keyID)
+		}
+	}
+	return open(key, wrapped, []byte(keyID))
+}
+
+// NewDataKey returns a new random data key.
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// seal encrypts the plaintext with AES-256-GCM under a random nonce, which
+// is prepended to the ciphertext.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a ciphertext of seal.
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -1,37 +1,113 @@
 package file_utils
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"log"
-	"os"
+	"io"
 	"path/filepath"
 	"strings"
 
-	"github.com/gaeaglobal/exto/server/app"
-	"github.com/gaeaglobal/exto/server/utils"
+	"github.com/gaeaglobal/exto/server/storage"
+	"github.com/gin-gonic/gin"
 	"github.com/rs/xid"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-func GetFilePath(appCtx *app.AppContext, reqCtx *app.RequestContext, dirPath string, fileName string, uniqueIDLength int, attempt int) string {
-	if attempt > 5 {
-		log.Printf("Failed to find a unique file path after %d attempts", attempt)
-		return GetFilePath(appCtx, reqCtx, dirPath, fileName, uniqueIDLength+1, 0)
+// The documents uploaded to an organization are kept in the blob store under
+// uploads/<org>/, its exports under exports/<org>/ and the infected files it
+// uploaded under quarantine/<org>/.
+const (
+	uploadsPrefix    = "uploads/"
+	exportsPrefix    = "exports/"
+	quarantinePrefix = "quarantine/"
+)
+
+// UploadPrefix is the prefix of the keys of the documents uploaded to the
+// organization.
+func UploadPrefix(orgID bson.ObjectID) string {
+	return uploadsPrefix + orgID.Hex() + "/"
+}
+
+// ExportPrefix is the prefix of the keys of the exports of the organization.
+func ExportPrefix(orgID bson.ObjectID) string {
+	return exportsPrefix + orgID.Hex() + "/"
+}
+
+// ExportKey is the key of the export of the organization named fileName.
+func ExportKey(orgID bson.ObjectID, fileName string) string {
+	return ExportPrefix(orgID) + fileName
+}
+
+// QuarantinePrefix is the prefix of the keys of the infected files uploaded to
+// the organization.
+func QuarantinePrefix(orgID bson.ObjectID) string {
+	return quarantinePrefix + orgID.Hex() + "/"
+}
+
+// KeyOrganization returns the organization that the upload or the export
+// stored under the key belongs to.
+func KeyOrganization(key string) (bson.ObjectID, bool) {
+	for _, prefix := range []string{uploadsPrefix, exportsPrefix, quarantinePrefix} {
+		if rest, found := strings.CutPrefix(key, prefix); found {
+			orgHex, _, found := strings.Cut(rest, "/")
+			if !found {
+				return bson.ObjectID{}, false
+			}
+			orgID, err := bson.ObjectIDFromHex(orgHex)
+			return orgID, err == nil
+		}
+	}
+	return bson.ObjectID{}, false
+}
+
+// NewUploadKey returns a new key for a document uploaded to the organization,
+// made of a unique id and the lower case name of the file.
+func NewUploadKey(orgID bson.ObjectID, fileName string) string {
+	name := strings.ToLower(filepath.Base(strings.ReplaceAll(fileName, "\\", "/")))
+	return fmt.Sprintf("%s%s_%s", UploadPrefix(orgID), xid.New().String(), name)
+}
+
+// QuarantineKey returns the key an infected file stored under the upload key
+// is moved to.
+func QuarantineKey(uploadKey string) string {
+	return quarantinePrefix + strings.TrimPrefix(uploadKey, uploadsPrefix)
+}
+
+// Upload is a document stored in the blob store. Its content is kept at hand
+// for the extraction and the thumbnail.
+type Upload struct {
+	Key      string
+	FileName string
+	Content  []byte
+}
+
+// ReadUploadedFile reads the file of the "file" form field, rejecting it
+// without reading it all when it is larger than maxBytes.
+func ReadUploadedFile(c *gin.Context, maxBytes int64) (string, []byte, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return "", nil, &UploadError{Code: UploadFileMissing, Msg: "uploaded file not found in form data (expected key: 'file')"}
 	}
-	if uniqueIDLength > 10 {
-		log.Printf("Unique ID length exceeded maximum limit, using original file name")
-		return ""
+	if header.Size > maxBytes {
+		return "", nil, &UploadError{Code: UploadFileTooLarge, Msg: fmt.Sprintf("the file is larger than the limit of %d bytes", maxBytes)}
 	}
-	orgIDString := reqCtx.User.OrganizationID.Hex()
-	nanoID := xid.New()
-	uniqueFileName := fmt.Sprintf("%s_%s", nanoID.String()[:uniqueIDLength], strings.ToLower(fileName))
-	filePath := filepath.Join(dirPath, orgIDString, uniqueFileName)
-	if utils.FileExists(filePath) {
-		return GetFilePath(appCtx, reqCtx, dirPath, fileName, uniqueIDLength, attempt+1)
+	file, err := header.Open()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read uploaded file: %w", err)
 	}
-	dirPath = filepath.Join(dirPath, orgIDString)
-	err := os.MkdirAll(dirPath, os.ModePerm)
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
 	if err != nil {
-		return ""
+		return "", nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	return header.Filename, content, nil
+}
+
+// StoreUpload stores the content under the key, with the type sniffed from it.
+func StoreUpload(ctx context.Context, blobs storage.BlobStore, key string, content []byte) error {
+	if err := blobs.Put(ctx, key, bytes.NewReader(content), int64(len(content)), SniffContentType(content)); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
 	}
-	return filePath
+	return nil
 }
@@ -0,0 +1,127 @@
+package file_utils_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/gaeaglobal/exto/server/file_utils"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tiffWithPages builds the header and the chain of empty image file
+// directories of a TIFF file of the given number of pages.
+func tiffWithPages(pages int) []byte {
+	content := []byte("II*\x00")
+	content = binary.LittleEndian.AppendUint32(content, 8)
+	for i := range pages {
+		content = binary.LittleEndian.AppendUint16(content, 0)
+		next := uint32(0)
+		if i < pages-1 {
+			next = uint32(len(content) + 4)
+		}
+		content = binary.LittleEndian.AppendUint32(content, next)
+	}
+	return content
+}
+
+func uploadErrorCode(err error) file_utils.UploadErrorCode {
+	var uploadErr *file_utils.UploadError
+	if errors.As(err, &uploadErr) {
+		return uploadErr.Code
+	}
+	return ""
+}
+
+func TestValidateUpload(t *testing.T) {
+	limits := model.UploadLimits{MaxBytes: 1 << 20, MaxPages: 3}
+
+	info, err := file_utils.ValidateUpload(encodePNG(t, 100, 100), limits)
+	if err != nil || info.ContentType != "image/png" || info.Pages != 1 {
+		t.Fatalf("expected a small PNG to be accepted, got %+v, %v", info, err)
+	}
+
+	for name, tc := range map[string]struct {
+		content []byte
+		want    file_utils.UploadErrorCode
+	}{
+		"too large":     {make([]byte, limits.MaxBytes+1), file_utils.UploadFileTooLarge},
+		"executable":    {append([]byte("MZ\x90\x00"), make([]byte, 64)...), file_utils.UploadUnsupportedType},
+		"html as image": {[]byte("<html><body>invoice</body></html>"), file_utils.UploadUnsupportedType},
+		"bomb":          {encodePNG(t, 30_000, 1), file_utils.UploadImageTooLarge},
+		"pages":         {tiffWithPages(4), file_utils.UploadTooManyPages},
+	} {
+		if _, err := file_utils.ValidateUpload(tc.content, limits); uploadErrorCode(err) != tc.want {
+			t.Errorf("%s: expected %s, got %v", name, tc.want, err)
+		}
+	}
+}
+
+// fakeClamd answers every scan with the reply after reading the whole stream.
+func fakeClamd(t *testing.T, reply string) (string, *bytes.Buffer) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := &bytes.Buffer{}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		command := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+			return
+		}
+		for {
+			var size uint32
+			if err := binary.Read(conn, binary.BigEndian, &size); err != nil || size == 0 {
+				break
+			}
+			if _, err := io.CopyN(received, conn, int64(size)); err != nil {
+				return
+			}
+		}
+		conn.Write([]byte(reply + "\x00"))
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestClamAVScanner(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 100_000)
+	addr, received := fakeClamd(t, "stream: OK")
+	verdict, err := file_utils.NewClamAVScanner(addr).Scan(context.Background(), content)
+	if err != nil || verdict.Infected {
+		t.Fatalf("expected a clean verdict, got %+v, %v", verdict, err)
+	}
+	if !bytes.Equal(received.Bytes(), content) {
+		t.Errorf("expected clamd to receive the %d bytes, got %d", len(content), received.Len())
+	}
+
+	addr, _ = fakeClamd(t, "stream: Eicar-Test-Signature FOUND")
+	verdict, err = file_utils.NewClamAVScanner(addr).Scan(context.Background(), []byte("eicar"))
+	if err != nil || !verdict.Infected || verdict.Signature != "Eicar-Test-Signature" {
+		t.Errorf("expected an infected verdict, got %+v, %v", verdict, err)
+	}
+
+	addr, _ = fakeClamd(t, "INSTREAM size limit exceeded. ERROR")
+	if _, err := file_utils.NewClamAVScanner(addr).Scan(context.Background(), []byte("x")); err == nil {
+		t.Error("expected a clamd error to fail the scan")
+	}
+}
@@ -0,0 +1,97 @@
+package file_utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// MalwareScanner checks the uploaded files for malware before they are
+// stored.
+type MalwareScanner interface {
+	Scan(ctx context.Context, content []byte) (*ScanVerdict, error)
+}
+
+// ScanVerdict is the result of a malware scan. Signature names the malware
+// found in an infected file.
+type ScanVerdict struct {
+	Infected  bool
+	Signature string
+}
+
+const (
+	clamAVTimeout   = 30 * time.Second
+	clamAVChunkSize = 64 * 1024
+)
+
+// ClamAVScanner scans the files with a ClamAV daemon, through the INSTREAM
+// command of its protocol.
+type ClamAVScanner struct {
+	network string
+	address string
+}
+
+// NewClamAVScanner returns a scanner of the daemon listening at the address,
+// host:port or unix:/path/to/clamd.sock.
+func NewClamAVScanner(addr string) *ClamAVScanner {
+	if path, found := strings.CutPrefix(addr, "unix:"); found {
+		return &ClamAVScanner{network: "unix", address: path}
+	}
+	return &ClamAVScanner{network: "tcp", address: strings.TrimPrefix(addr, "tcp://")}
+}
+
+func (s *ClamAVScanner) Scan(ctx context.Context, content []byte) (*ScanVerdict, error) {
+	ctx, cancel := context.WithTimeout(ctx, clamAVTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The content is sent in chunks, each preceded by its length, and ends
+	// with an empty chunk.
+	w := bufio.NewWriter(conn)
+	w.WriteString("zINSTREAM\x00")
+	for start := 0; start < len(content); start += clamAVChunkSize {
+		chunk := content[start:min(start+clamAVChunkSize, len(content))]
+		binary.Write(w, binary.BigEndian, uint32(len(chunk)))
+		w.Write(chunk)
+	}
+	binary.Write(w, binary.BigEndian, uint32(0))
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to send file to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamAVReply(reply)
+}
+
+// parseClamAVReply reads replies like "stream: OK" and
+// "stream: Eicar-Signature FOUND".
+func parseClamAVReply(reply string) (*ScanVerdict, error) {
+	reply = strings.TrimRight(reply, "\x00\n")
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return &ScanVerdict{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &ScanVerdict{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	case reply == "":
+		return nil, errors.New("empty clamd reply")
+	default:
+		return nil, fmt.Errorf("clamd error: %s", reply)
+	}
+}
@@ -0,0 +1,131 @@
+package file_utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"slices"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// UploadErrorCode tells the client why an upload was rejected.
+type UploadErrorCode string
+
+const (
+	UploadFileMissing       UploadErrorCode = "file_missing"
+	UploadFileTooLarge      UploadErrorCode = "file_too_large"
+	UploadUnsupportedType   UploadErrorCode = "unsupported_file_type"
+	UploadTooManyPages      UploadErrorCode = "too_many_pages"
+	UploadImageTooLarge     UploadErrorCode = "image_too_large"
+	UploadMalwareDetected   UploadErrorCode = "malware_detected"
+	UploadMalwareScanFailed UploadErrorCode = "malware_scan_unavailable"
+)
+
+// UploadError is returned when an uploaded file is rejected.
+type UploadError struct {
+	Code UploadErrorCode
+	Msg  string
+}
+
+func (e *UploadError) Error() string {
+	return e.Msg
+}
+
+// Details describes the rejection in a form suitable for an error response.
+func (e *UploadError) Details() []string {
+	return []string{"code: " + string(e.Code)}
+}
+
+// AllowedUploadTypes are the types of the documents that can be scanned,
+// which are the image formats the extraction decodes.
+var AllowedUploadTypes = []string{"image/jpeg", "image/png", "image/gif", "image/bmp", "image/tiff"}
+
+// Decoding an image takes memory in proportion to its pixels, whatever the
+// size of its file: images larger than this are rejected before they are
+// decoded.
+const (
+	MaxImagePixels    = 50_000_000
+	MaxImageDimension = 20_000
+)
+
+// UploadInfo is what the validation found out about an uploaded file.
+type UploadInfo struct {
+	ContentType string
+	Pages       int
+}
+
+// SniffContentType tells the type of the file from its content, whatever its
+// name or the type the client claimed.
+func SniffContentType(content []byte) string {
+	// http.DetectContentType does not know TIFF.
+	if bytes.HasPrefix(content, []byte("II*\x00")) || bytes.HasPrefix(content, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+	return http.DetectContentType(content)
+}
+
+// ValidateUpload checks the content of an uploaded file against the allowed
+// types and the limits, and checks images for decompression bombs.
+func ValidateUpload(content []byte, limits model.UploadLimits) (*UploadInfo, error) {
+	if int64(len(content)) > limits.MaxBytes {
+		return nil, &UploadError{Code: UploadFileTooLarge, Msg: fmt.Sprintf("the file is larger than the limit of %d bytes", limits.MaxBytes)}
+	}
+	contentType := SniffContentType(content)
+	if !slices.Contains(AllowedUploadTypes, contentType) {
+		return nil, &UploadError{Code: UploadUnsupportedType, Msg: fmt.Sprintf("files of type %s are not supported", contentType)}
+	}
+
+	info := &UploadInfo{ContentType: contentType, Pages: 1}
+	if contentType == "image/tiff" {
+		info.Pages = tiffPages(content)
+	}
+	if info.Pages > limits.MaxPages {
+		return nil, &UploadError{Code: UploadTooManyPages, Msg: fmt.Sprintf("the file has more than %d pages", limits.MaxPages)}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, &UploadError{Code: UploadUnsupportedType, Msg: "the image cannot be read"}
+	}
+	if config.Width > MaxImageDimension || config.Height > MaxImageDimension || int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return nil, &UploadError{Code: UploadImageTooLarge, Msg: fmt.Sprintf("the image of %dx%d pixels is too large", config.Width, config.Height)}
+	}
+	return info, nil
+}
+
+// tiffPages counts the images of a TIFF file by following the chain of its
+// image file directories.
+func tiffPages(content []byte) int {
+	if len(content) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if content[0] == 'M' {
+		order = binary.BigEndian
+	}
+	pages := 0
+	seen := map[uint32]bool{}
+	for offset := order.Uint32(content[4:]); offset != 0 && !seen[offset]; {
+		seen[offset] = true
+		if int64(offset)+2 > int64(len(content)) {
+			break
+		}
+		entries := int64(order.Uint16(content[offset:]))
+		next := int64(offset) + 2 + 12*entries
+		pages++
+		if next+4 > int64(len(content)) {
+			break
+		}
+		offset = order.Uint32(content[next:])
+	}
+	return pages
+}
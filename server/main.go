@@ -12,7 +12,9 @@ import (
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/app_di"
 	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/encryption"
 	"github.com/gaeaglobal/exto/server/routes"
+	"github.com/gaeaglobal/exto/server/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -32,14 +34,35 @@ func main() {
 		log.Fatalf("Failed to connect to the database: %v", err)
 	}
 	log.Println("--- Database Connection Established ---")
+	db.ConfigureUserCache(cfg.USER_CACHE_SIZE, cfg.USER_CACHE_TTL)
+
+	blobs, err := storage.NewBlobStore(cfg.BlobStoreConfig())
+	if err != nil {
+		log.Fatalf("Failed to configure the blob store: %v", err)
+	}
+	masterKeys, err := encryption.NewKeyProvider(cfg.ENCRYPTION_KEY_FILE, cfg.ENCRYPTION_MASTER_KEY)
+	if err != nil {
+		log.Fatalf("Failed to load the encryption master keys: %v", err)
+	}
+	if masterKeys == nil {
+		log.Println("Encryption at rest is disabled: no ENCRYPTION_KEY_FILE or ENCRYPTION_MASTER_KEY")
+	}
 
 	// Initialize the AppContext with the loaded configuration.
 	appCtx := &app.AppContext{
-		Config: cfg,
-		DB:     appDB,
+		Config:        cfg,
+		DB:            appDB,
+		MicrosoftKeys: app.NewJWKSCache(cfg.MICROSOFT_JWKS_URL, cfg.MICROSOFT_JWKS_FILE),
+		OIDCKeys:      app.NewOIDCKeyRegistry(),
+		Blobs:         blobs,
+		MasterKeys:    masterKeys,
 	}
 
 	appDI := app_di.NewAppDI(appCtx)
+	appDI.MeterDispatcher.Start()
+	appDI.DeletionService.Start()
+	appDI.DataExportService.Start()
+	appDI.RetentionService.Start()
 
 	// Set Gin to release mode if not in debug mode
 	if !cfg.DebugMode {
@@ -50,6 +73,11 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Keep the caches of all replicas consistent.
+	if cfg.CACHE_CHANGE_STREAMS {
+		go appDB.WatchCacheInvalidations(ctx)
+	}
+
 	// Creates a router without any middleware by default
 	router := getRouter(appCtx, appDI)
 
@@ -104,20 +132,19 @@ func getRouter(appCtx *app.AppContext, appDI *app_di.AppDI) *gin.Engine {
 	auth := router.Group("/auth")
 	routes.AddAuthenticationRoutes(auth)
 
+	// Webhook Routes, authenticated by their signature
+	webhooks := router.Group("/webhooks")
+	routes.AddWebhookRoutes(webhooks)
+
+	// Download Routes, authenticated by their signed link
+	downloads := router.Group("/downloads")
+	routes.AddDownloadRoutes(downloads)
+
 	// Protected Routes
 	protected := router.Group("/v1")
 	protected.Use(app.AppAuthzMiddleware())
 	protected.Use(app.LastActiveMiddleware(appCtx))
-	routes.AddMeRoutes(protected)
-	routes.AddOpenAiRoutes(protected)
-	routes.AddCategoryRoutes(protected)
-	routes.AddCategoryDataRoutes(protected)
-	routes.AddScanHistoryRoutes(protected)
-	routes.AddExportRoutes(protected)
-	routes.AddBillingRoutes(protected)
-	routes.AddScanRoutes(protected)
-	routes.AddBatchRoutes(protected)
-	routes.AddPaymentRoutes(protected)
+	routes.AddProtectedRoutes(protected)
 
 	return router
 }
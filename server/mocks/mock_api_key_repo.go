@@ -0,0 +1,163 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: api_key_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=api_key_repo.go -destination=../mocks/mock_api_key_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAPIKeyRepository is a mock of APIKeyRepository interface.
+type MockAPIKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockAPIKeyRepositoryMockRecorder is the mock recorder for MockAPIKeyRepository.
+type MockAPIKeyRepositoryMockRecorder struct {
+	mock *MockAPIKeyRepository
+}
+
+// NewMockAPIKeyRepository creates a new mock instance.
+func NewMockAPIKeyRepository(ctrl *gomock.Controller) *MockAPIKeyRepository {
+	mock := &MockAPIKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyRepository) EXPECT() *MockAPIKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockAPIKeyRepository) CreateAPIKey(reqCtx *app.RequestContext, key *model.APIKey) (*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", reqCtx, key)
+	ret0, _ := ret[0].(*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockAPIKeyRepositoryMockRecorder) CreateAPIKey(reqCtx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockAPIKeyRepository)(nil).CreateAPIKey), reqCtx, key)
+}
+
+// DeleteAPIKeysByOrg mocks base method.
+func (m *MockAPIKeyRepository) DeleteAPIKeysByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKeysByOrg", reqCtx, orgID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKeysByOrg indicates an expected call of DeleteAPIKeysByOrg.
+func (mr *MockAPIKeyRepositoryMockRecorder) DeleteAPIKeysByOrg(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKeysByOrg", reflect.TypeOf((*MockAPIKeyRepository)(nil).DeleteAPIKeysByOrg), reqCtx, orgID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockAPIKeyRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockAPIKeyRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockAPIKeyRepository)(nil).EnsureIndexes))
+}
+
+// GetCollection mocks base method.
+func (m *MockAPIKeyRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockAPIKeyRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockAPIKeyRepository)(nil).GetCollection), orgName...)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockAPIKeyRepository) ListAPIKeys(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", reqCtx, orgID)
+	ret0, _ := ret[0].([]*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockAPIKeyRepositoryMockRecorder) ListAPIKeys(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockAPIKeyRepository)(nil).ListAPIKeys), reqCtx, orgID)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockAPIKeyRepository) RevokeAPIKey(reqCtx *app.RequestContext, orgID, id bson.ObjectID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", reqCtx, orgID, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockAPIKeyRepositoryMockRecorder) RevokeAPIKey(reqCtx, orgID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockAPIKeyRepository)(nil).RevokeAPIKey), reqCtx, orgID, id)
+}
+
+// RotateAPIKey mocks base method.
+func (m *MockAPIKeyRepository) RotateAPIKey(reqCtx *app.RequestContext, orgID, id bson.ObjectID, prefix, secretHash string) (*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateAPIKey", reqCtx, orgID, id, prefix, secretHash)
+	ret0, _ := ret[0].(*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateAPIKey indicates an expected call of RotateAPIKey.
+func (mr *MockAPIKeyRepositoryMockRecorder) RotateAPIKey(reqCtx, orgID, id, prefix, secretHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateAPIKey", reflect.TypeOf((*MockAPIKeyRepository)(nil).RotateAPIKey), reqCtx, orgID, id, prefix, secretHash)
+}
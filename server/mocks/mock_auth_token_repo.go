@@ -0,0 +1,146 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: auth_token_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=auth_token_repo.go -destination=../mocks/mock_auth_token_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAuthTokenRepository is a mock of AuthTokenRepository interface.
+type MockAuthTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthTokenRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockAuthTokenRepositoryMockRecorder is the mock recorder for MockAuthTokenRepository.
+type MockAuthTokenRepositoryMockRecorder struct {
+	mock *MockAuthTokenRepository
+}
+
+// NewMockAuthTokenRepository creates a new mock instance.
+func NewMockAuthTokenRepository(ctrl *gomock.Controller) *MockAuthTokenRepository {
+	mock := &MockAuthTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockAuthTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthTokenRepository) EXPECT() *MockAuthTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ConsumeAuthToken mocks base method.
+func (m *MockAuthTokenRepository) ConsumeAuthToken(reqCtx *app.RequestContext, purpose model.AuthTokenPurpose, tokenHash string) (*model.AuthToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeAuthToken", reqCtx, purpose, tokenHash)
+	ret0, _ := ret[0].(*model.AuthToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeAuthToken indicates an expected call of ConsumeAuthToken.
+func (mr *MockAuthTokenRepositoryMockRecorder) ConsumeAuthToken(reqCtx, purpose, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeAuthToken", reflect.TypeOf((*MockAuthTokenRepository)(nil).ConsumeAuthToken), reqCtx, purpose, tokenHash)
+}
+
+// CreateAuthToken mocks base method.
+func (m *MockAuthTokenRepository) CreateAuthToken(reqCtx *app.RequestContext, token *model.AuthToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthToken", reqCtx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuthToken indicates an expected call of CreateAuthToken.
+func (mr *MockAuthTokenRepositoryMockRecorder) CreateAuthToken(reqCtx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthToken", reflect.TypeOf((*MockAuthTokenRepository)(nil).CreateAuthToken), reqCtx, token)
+}
+
+// DeleteAuthTokensByIdentity mocks base method.
+func (m *MockAuthTokenRepository) DeleteAuthTokensByIdentity(reqCtx *app.RequestContext, identityID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthTokensByIdentity", reqCtx, identityID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthTokensByIdentity indicates an expected call of DeleteAuthTokensByIdentity.
+func (mr *MockAuthTokenRepositoryMockRecorder) DeleteAuthTokensByIdentity(reqCtx, identityID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthTokensByIdentity", reflect.TypeOf((*MockAuthTokenRepository)(nil).DeleteAuthTokensByIdentity), reqCtx, identityID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockAuthTokenRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockAuthTokenRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockAuthTokenRepository)(nil).EnsureIndexes))
+}
+
+// GetCollection mocks base method.
+func (m *MockAuthTokenRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockAuthTokenRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockAuthTokenRepository)(nil).GetCollection), orgName...)
+}
+
+// RevokeAuthTokens mocks base method.
+func (m *MockAuthTokenRepository) RevokeAuthTokens(reqCtx *app.RequestContext, identityID bson.ObjectID, purposes []model.AuthTokenPurpose) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAuthTokens", reqCtx, identityID, purposes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAuthTokens indicates an expected call of RevokeAuthTokens.
+func (mr *MockAuthTokenRepositoryMockRecorder) RevokeAuthTokens(reqCtx, identityID, purposes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAuthTokens", reflect.TypeOf((*MockAuthTokenRepository)(nil).RevokeAuthTokens), reqCtx, identityID, purposes)
+}
@@ -0,0 +1,150 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: batch_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=batch_repo.go -destination=../mocks/mock_batch_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockBatchRepo is a mock of BatchRepo interface.
+type MockBatchRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockBatchRepoMockRecorder is the mock recorder for MockBatchRepo.
+type MockBatchRepoMockRecorder struct {
+	mock *MockBatchRepo
+}
+
+// NewMockBatchRepo creates a new mock instance.
+func NewMockBatchRepo(ctrl *gomock.Controller) *MockBatchRepo {
+	mock := &MockBatchRepo{ctrl: ctrl}
+	mock.recorder = &MockBatchRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatchRepo) EXPECT() *MockBatchRepoMockRecorder {
+	return m.recorder
+}
+
+// CreateBatch mocks base method.
+func (m *MockBatchRepo) CreateBatch(reqCtx *app.RequestContext, batch *model.CreateBatchRequest) (*model.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", reqCtx, batch)
+	ret0, _ := ret[0].(*model.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockBatchRepoMockRecorder) CreateBatch(reqCtx, batch any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockBatchRepo)(nil).CreateBatch), reqCtx, batch)
+}
+
+// GetBatchByID mocks base method.
+func (m *MockBatchRepo) GetBatchByID(reqCtx *app.RequestContext, BatchID bson.ObjectID) (*model.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBatchByID", reqCtx, BatchID)
+	ret0, _ := ret[0].(*model.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBatchByID indicates an expected call of GetBatchByID.
+func (mr *MockBatchRepoMockRecorder) GetBatchByID(reqCtx, BatchID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBatchByID", reflect.TypeOf((*MockBatchRepo)(nil).GetBatchByID), reqCtx, BatchID)
+}
+
+// GetCollection mocks base method.
+func (m *MockBatchRepo) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockBatchRepoMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockBatchRepo)(nil).GetCollection), orgName...)
+}
+
+// GetLegalHoldBatchIDs mocks base method.
+func (m *MockBatchRepo) GetLegalHoldBatchIDs(reqCtx *app.RequestContext) ([]bson.ObjectID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLegalHoldBatchIDs", reqCtx)
+	ret0, _ := ret[0].([]bson.ObjectID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLegalHoldBatchIDs indicates an expected call of GetLegalHoldBatchIDs.
+func (mr *MockBatchRepoMockRecorder) GetLegalHoldBatchIDs(reqCtx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLegalHoldBatchIDs", reflect.TypeOf((*MockBatchRepo)(nil).GetLegalHoldBatchIDs), reqCtx)
+}
+
+// SetLegalHold mocks base method.
+func (m *MockBatchRepo) SetLegalHold(reqCtx *app.RequestContext, batchID bson.ObjectID, hold bool) (*model.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLegalHold", reqCtx, batchID, hold)
+	ret0, _ := ret[0].(*model.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetLegalHold indicates an expected call of SetLegalHold.
+func (mr *MockBatchRepoMockRecorder) SetLegalHold(reqCtx, batchID, hold any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLegalHold", reflect.TypeOf((*MockBatchRepo)(nil).SetLegalHold), reqCtx, batchID, hold)
+}
+
+// UpdateBatchStatus mocks base method.
+func (m *MockBatchRepo) UpdateBatchStatus(reqCtx *app.RequestContext, batchID bson.ObjectID, status string) (*model.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBatchStatus", reqCtx, batchID, status)
+	ret0, _ := ret[0].(*model.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateBatchStatus indicates an expected call of UpdateBatchStatus.
+func (mr *MockBatchRepoMockRecorder) UpdateBatchStatus(reqCtx, batchID, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBatchStatus", reflect.TypeOf((*MockBatchRepo)(nil).UpdateBatchStatus), reqCtx, batchID, status)
+}
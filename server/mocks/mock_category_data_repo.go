@@ -23,7 +23,9 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	app "github.com/gaeaglobal/exto/server/app"
 	model "github.com/gaeaglobal/exto/server/model"
@@ -32,6 +34,75 @@ import (
 	gomock "go.uber.org/mock/gomock"
 )
 
+// MockFieldCipher is a mock of FieldCipher interface.
+type MockFieldCipher struct {
+	ctrl     *gomock.Controller
+	recorder *MockFieldCipherMockRecorder
+	isgomock struct{}
+}
+
+// MockFieldCipherMockRecorder is the mock recorder for MockFieldCipher.
+type MockFieldCipherMockRecorder struct {
+	mock *MockFieldCipher
+}
+
+// NewMockFieldCipher creates a new mock instance.
+func NewMockFieldCipher(ctrl *gomock.Controller) *MockFieldCipher {
+	mock := &MockFieldCipher{ctrl: ctrl}
+	mock.recorder = &MockFieldCipherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFieldCipher) EXPECT() *MockFieldCipherMockRecorder {
+	return m.recorder
+}
+
+// Decrypt mocks base method.
+func (m *MockFieldCipher) Decrypt(ctx context.Context, orgID bson.ObjectID, value any) (any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decrypt", ctx, orgID, value)
+	ret0, _ := ret[0].(any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Decrypt indicates an expected call of Decrypt.
+func (mr *MockFieldCipherMockRecorder) Decrypt(ctx, orgID, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decrypt", reflect.TypeOf((*MockFieldCipher)(nil).Decrypt), ctx, orgID, value)
+}
+
+// DecryptFields mocks base method.
+func (m *MockFieldCipher) DecryptFields(ctx context.Context, orgID bson.ObjectID, fields map[string]any) (map[string]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptFields", ctx, orgID, fields)
+	ret0, _ := ret[0].(map[string]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptFields indicates an expected call of DecryptFields.
+func (mr *MockFieldCipherMockRecorder) DecryptFields(ctx, orgID, fields any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptFields", reflect.TypeOf((*MockFieldCipher)(nil).DecryptFields), ctx, orgID, fields)
+}
+
+// EncryptFields mocks base method.
+func (m *MockFieldCipher) EncryptFields(ctx context.Context, orgID bson.ObjectID, fields map[string]any, names []string) (map[string]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptFields", ctx, orgID, fields, names)
+	ret0, _ := ret[0].(map[string]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptFields indicates an expected call of EncryptFields.
+func (mr *MockFieldCipherMockRecorder) EncryptFields(ctx, orgID, fields, names any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptFields", reflect.TypeOf((*MockFieldCipher)(nil).EncryptFields), ctx, orgID, fields, names)
+}
+
 // MockCategoryDataRepository is a mock of CategoryDataRepository interface.
 type MockCategoryDataRepository struct {
 	ctrl     *gomock.Controller
@@ -56,6 +127,35 @@ func (m *MockCategoryDataRepository) EXPECT() *MockCategoryDataRepositoryMockRec
 	return m.recorder
 }
 
+// ApplyRetention mocks base method.
+func (m *MockCategoryDataRepository) ApplyRetention(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, stage model.RetentionStage, purgedDocuments int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyRetention", reqCtx, categorySlug, id, stage, purgedDocuments)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyRetention indicates an expected call of ApplyRetention.
+func (mr *MockCategoryDataRepositoryMockRecorder) ApplyRetention(reqCtx, categorySlug, id, stage, purgedDocuments any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyRetention", reflect.TypeOf((*MockCategoryDataRepository)(nil).ApplyRetention), reqCtx, categorySlug, id, stage, purgedDocuments)
+}
+
+// CountRetentionDue mocks base method.
+func (m *MockCategoryDataRepository) CountRetentionDue(reqCtx *app.RequestContext, categorySlug string, stage model.RetentionStage, cutoff time.Time, exempt []bson.ObjectID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountRetentionDue", reqCtx, categorySlug, stage, cutoff, exempt)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountRetentionDue indicates an expected call of CountRetentionDue.
+func (mr *MockCategoryDataRepositoryMockRecorder) CountRetentionDue(reqCtx, categorySlug, stage, cutoff, exempt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountRetentionDue", reflect.TypeOf((*MockCategoryDataRepository)(nil).CountRetentionDue), reqCtx, categorySlug, stage, cutoff, exempt)
+}
+
 // CreateCategoryData mocks base method.
 func (m *MockCategoryDataRepository) CreateCategoryData(reqCtx *app.RequestContext, categorySlug string, categoryData *model.CreateCategoryDataRequest) (*model.CategoryData, error) {
 	m.ctrl.T.Helper()
@@ -71,6 +171,36 @@ func (mr *MockCategoryDataRepositoryMockRecorder) CreateCategoryData(reqCtx, cat
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCategoryData", reflect.TypeOf((*MockCategoryDataRepository)(nil).CreateCategoryData), reqCtx, categorySlug, categoryData)
 }
 
+// DecryptDocument mocks base method.
+func (m *MockCategoryDataRepository) DecryptDocument(reqCtx *app.RequestContext, doc bson.Raw) (bson.Raw, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptDocument", reqCtx, doc)
+	ret0, _ := ret[0].(bson.Raw)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptDocument indicates an expected call of DecryptDocument.
+func (mr *MockCategoryDataRepositoryMockRecorder) DecryptDocument(reqCtx, doc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptDocument", reflect.TypeOf((*MockCategoryDataRepository)(nil).DecryptDocument), reqCtx, doc)
+}
+
+// FindRetentionDue mocks base method.
+func (m *MockCategoryDataRepository) FindRetentionDue(reqCtx *app.RequestContext, categorySlug string, stage model.RetentionStage, cutoff time.Time, exempt []bson.ObjectID, limit int64) ([]*model.CategoryData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRetentionDue", reqCtx, categorySlug, stage, cutoff, exempt, limit)
+	ret0, _ := ret[0].([]*model.CategoryData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRetentionDue indicates an expected call of FindRetentionDue.
+func (mr *MockCategoryDataRepositoryMockRecorder) FindRetentionDue(reqCtx, categorySlug, stage, cutoff, exempt, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRetentionDue", reflect.TypeOf((*MockCategoryDataRepository)(nil).FindRetentionDue), reqCtx, categorySlug, stage, cutoff, exempt, limit)
+}
+
 // GetCategoryDataByID mocks base method.
 func (m *MockCategoryDataRepository) GetCategoryDataByID(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID) (*model.CategoryData, error) {
 	m.ctrl.T.Helper()
@@ -115,6 +245,36 @@ func (mr *MockCategoryDataRepositoryMockRecorder) ListCategoryData(reqCtx, categ
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCategoryData", reflect.TypeOf((*MockCategoryDataRepository)(nil).ListCategoryData), reqCtx, categorySlug, pageReq)
 }
 
+// ListDataCollections mocks base method.
+func (m *MockCategoryDataRepository) ListDataCollections(reqCtx *app.RequestContext) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDataCollections", reqCtx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDataCollections indicates an expected call of ListDataCollections.
+func (mr *MockCategoryDataRepositoryMockRecorder) ListDataCollections(reqCtx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDataCollections", reflect.TypeOf((*MockCategoryDataRepository)(nil).ListDataCollections), reqCtx)
+}
+
+// SetLegalHold mocks base method.
+func (m *MockCategoryDataRepository) SetLegalHold(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, hold bool) (*model.CategoryData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLegalHold", reqCtx, categorySlug, id, hold)
+	ret0, _ := ret[0].(*model.CategoryData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetLegalHold indicates an expected call of SetLegalHold.
+func (mr *MockCategoryDataRepositoryMockRecorder) SetLegalHold(reqCtx, categorySlug, id, hold any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLegalHold", reflect.TypeOf((*MockCategoryDataRepository)(nil).SetLegalHold), reqCtx, categorySlug, id, hold)
+}
+
 // UpdateCategoryData mocks base method.
 func (m *MockCategoryDataRepository) UpdateCategoryData(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, updateMetaData *model.UpdateCategoryDataRequest) (*model.CategoryData, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,250 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: data_export_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=data_export_repo.go -destination=../mocks/mock_data_export_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDataExportRepository is a mock of DataExportRepository interface.
+type MockDataExportRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDataExportRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockDataExportRepositoryMockRecorder is the mock recorder for MockDataExportRepository.
+type MockDataExportRepositoryMockRecorder struct {
+	mock *MockDataExportRepository
+}
+
+// NewMockDataExportRepository creates a new mock instance.
+func NewMockDataExportRepository(ctrl *gomock.Controller) *MockDataExportRepository {
+	mock := &MockDataExportRepository{ctrl: ctrl}
+	mock.recorder = &MockDataExportRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataExportRepository) EXPECT() *MockDataExportRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimNextPending mocks base method.
+func (m *MockDataExportRepository) ClaimNextPending(reqCtx *app.RequestContext, now time.Time, lease time.Duration) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimNextPending", reqCtx, now, lease)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimNextPending indicates an expected call of ClaimNextPending.
+func (mr *MockDataExportRepositoryMockRecorder) ClaimNextPending(reqCtx, now, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimNextPending", reflect.TypeOf((*MockDataExportRepository)(nil).ClaimNextPending), reqCtx, now, lease)
+}
+
+// CreateDataExport mocks base method.
+func (m *MockDataExportRepository) CreateDataExport(reqCtx *app.RequestContext) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDataExport", reqCtx)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDataExport indicates an expected call of CreateDataExport.
+func (mr *MockDataExportRepositoryMockRecorder) CreateDataExport(reqCtx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDataExport", reflect.TypeOf((*MockDataExportRepository)(nil).CreateDataExport), reqCtx)
+}
+
+// DeleteDataExportsByOrg mocks base method.
+func (m *MockDataExportRepository) DeleteDataExportsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDataExportsByOrg", reqCtx, orgID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDataExportsByOrg indicates an expected call of DeleteDataExportsByOrg.
+func (mr *MockDataExportRepositoryMockRecorder) DeleteDataExportsByOrg(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDataExportsByOrg", reflect.TypeOf((*MockDataExportRepository)(nil).DeleteDataExportsByOrg), reqCtx, orgID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockDataExportRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockDataExportRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockDataExportRepository)(nil).EnsureIndexes))
+}
+
+// ForEachDocument mocks base method.
+func (m *MockDataExportRepository) ForEachDocument(reqCtx *app.RequestContext, orgSlug, collection string, filter bson.M, fn func(bson.Raw) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForEachDocument", reqCtx, orgSlug, collection, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForEachDocument indicates an expected call of ForEachDocument.
+func (mr *MockDataExportRepositoryMockRecorder) ForEachDocument(reqCtx, orgSlug, collection, filter, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForEachDocument", reflect.TypeOf((*MockDataExportRepository)(nil).ForEachDocument), reqCtx, orgSlug, collection, filter, fn)
+}
+
+// GetCollection mocks base method.
+func (m *MockDataExportRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockDataExportRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockDataExportRepository)(nil).GetCollection), orgName...)
+}
+
+// GetDataExport mocks base method.
+func (m *MockDataExportRepository) GetDataExport(reqCtx *app.RequestContext, id bson.ObjectID) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataExport", reqCtx, id)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataExport indicates an expected call of GetDataExport.
+func (mr *MockDataExportRepositoryMockRecorder) GetDataExport(reqCtx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataExport", reflect.TypeOf((*MockDataExportRepository)(nil).GetDataExport), reqCtx, id)
+}
+
+// GetExpiredDataExports mocks base method.
+func (m *MockDataExportRepository) GetExpiredDataExports(reqCtx *app.RequestContext, now time.Time) ([]*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiredDataExports", reqCtx, now)
+	ret0, _ := ret[0].([]*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiredDataExports indicates an expected call of GetExpiredDataExports.
+func (mr *MockDataExportRepositoryMockRecorder) GetExpiredDataExports(reqCtx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredDataExports", reflect.TypeOf((*MockDataExportRepository)(nil).GetExpiredDataExports), reqCtx, now)
+}
+
+// HasActiveDataExport mocks base method.
+func (m *MockDataExportRepository) HasActiveDataExport(reqCtx *app.RequestContext, orgID bson.ObjectID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasActiveDataExport", reqCtx, orgID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasActiveDataExport indicates an expected call of HasActiveDataExport.
+func (mr *MockDataExportRepositoryMockRecorder) HasActiveDataExport(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasActiveDataExport", reflect.TypeOf((*MockDataExportRepository)(nil).HasActiveDataExport), reqCtx, orgID)
+}
+
+// ListDataExports mocks base method.
+func (m *MockDataExportRepository) ListDataExports(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDataExports", reqCtx, orgID)
+	ret0, _ := ret[0].([]*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDataExports indicates an expected call of ListDataExports.
+func (mr *MockDataExportRepositoryMockRecorder) ListDataExports(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDataExports", reflect.TypeOf((*MockDataExportRepository)(nil).ListDataExports), reqCtx, orgID)
+}
+
+// MarkExpired mocks base method.
+func (m *MockDataExportRepository) MarkExpired(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkExpired", reqCtx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkExpired indicates an expected call of MarkExpired.
+func (mr *MockDataExportRepositoryMockRecorder) MarkExpired(reqCtx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkExpired", reflect.TypeOf((*MockDataExportRepository)(nil).MarkExpired), reqCtx, id)
+}
+
+// MarkFailed mocks base method.
+func (m *MockDataExportRepository) MarkFailed(reqCtx *app.RequestContext, id bson.ObjectID, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkFailed", reqCtx, id, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkFailed indicates an expected call of MarkFailed.
+func (mr *MockDataExportRepositoryMockRecorder) MarkFailed(reqCtx, id, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkFailed", reflect.TypeOf((*MockDataExportRepository)(nil).MarkFailed), reqCtx, id, lastError)
+}
+
+// MarkReady mocks base method.
+func (m *MockDataExportRepository) MarkReady(reqCtx *app.RequestContext, id bson.ObjectID, fileName string, size int64, sha256 string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkReady", reqCtx, id, fileName, size, sha256, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkReady indicates an expected call of MarkReady.
+func (mr *MockDataExportRepositoryMockRecorder) MarkReady(reqCtx, id, fileName, size, sha256, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkReady", reflect.TypeOf((*MockDataExportRepository)(nil).MarkReady), reqCtx, id, fileName, size, sha256, expiresAt)
+}
@@ -0,0 +1,176 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: data_key_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=data_key_repo.go -destination=../mocks/mock_data_key_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDataKeyRepository is a mock of DataKeyRepository interface.
+type MockDataKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDataKeyRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockDataKeyRepositoryMockRecorder is the mock recorder for MockDataKeyRepository.
+type MockDataKeyRepositoryMockRecorder struct {
+	mock *MockDataKeyRepository
+}
+
+// NewMockDataKeyRepository creates a new mock instance.
+func NewMockDataKeyRepository(ctrl *gomock.Controller) *MockDataKeyRepository {
+	mock := &MockDataKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockDataKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataKeyRepository) EXPECT() *MockDataKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateDataKey mocks base method.
+func (m *MockDataKeyRepository) CreateDataKey(reqCtx *app.RequestContext, key *model.DataKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDataKey", reqCtx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDataKey indicates an expected call of CreateDataKey.
+func (mr *MockDataKeyRepositoryMockRecorder) CreateDataKey(reqCtx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDataKey", reflect.TypeOf((*MockDataKeyRepository)(nil).CreateDataKey), reqCtx, key)
+}
+
+// DeleteDataKeysByOrg mocks base method.
+func (m *MockDataKeyRepository) DeleteDataKeysByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDataKeysByOrg", reqCtx, orgID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDataKeysByOrg indicates an expected call of DeleteDataKeysByOrg.
+func (mr *MockDataKeyRepositoryMockRecorder) DeleteDataKeysByOrg(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDataKeysByOrg", reflect.TypeOf((*MockDataKeyRepository)(nil).DeleteDataKeysByOrg), reqCtx, orgID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockDataKeyRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockDataKeyRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockDataKeyRepository)(nil).EnsureIndexes))
+}
+
+// GetCollection mocks base method.
+func (m *MockDataKeyRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockDataKeyRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockDataKeyRepository)(nil).GetCollection), orgName...)
+}
+
+// GetDataKey mocks base method.
+func (m *MockDataKeyRepository) GetDataKey(reqCtx *app.RequestContext, orgID bson.ObjectID, version int) (*model.DataKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataKey", reqCtx, orgID, version)
+	ret0, _ := ret[0].(*model.DataKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataKey indicates an expected call of GetDataKey.
+func (mr *MockDataKeyRepositoryMockRecorder) GetDataKey(reqCtx, orgID, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataKey", reflect.TypeOf((*MockDataKeyRepository)(nil).GetDataKey), reqCtx, orgID, version)
+}
+
+// GetLatestDataKey mocks base method.
+func (m *MockDataKeyRepository) GetLatestDataKey(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.DataKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestDataKey", reqCtx, orgID)
+	ret0, _ := ret[0].(*model.DataKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestDataKey indicates an expected call of GetLatestDataKey.
+func (mr *MockDataKeyRepositoryMockRecorder) GetLatestDataKey(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestDataKey", reflect.TypeOf((*MockDataKeyRepository)(nil).GetLatestDataKey), reqCtx, orgID)
+}
+
+// ListDataKeysNotWrappedWith mocks base method.
+func (m *MockDataKeyRepository) ListDataKeysNotWrappedWith(reqCtx *app.RequestContext, masterKeyID string, afterID bson.ObjectID, limit int64) ([]*model.DataKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDataKeysNotWrappedWith", reqCtx, masterKeyID, afterID, limit)
+	ret0, _ := ret[0].([]*model.DataKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDataKeysNotWrappedWith indicates an expected call of ListDataKeysNotWrappedWith.
+func (mr *MockDataKeyRepositoryMockRecorder) ListDataKeysNotWrappedWith(reqCtx, masterKeyID, afterID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDataKeysNotWrappedWith", reflect.TypeOf((*MockDataKeyRepository)(nil).ListDataKeysNotWrappedWith), reqCtx, masterKeyID, afterID, limit)
+}
+
+// RewrapDataKey mocks base method.
+func (m *MockDataKeyRepository) RewrapDataKey(reqCtx *app.RequestContext, id bson.ObjectID, fromMasterKeyID, masterKeyID string, wrappedKey []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RewrapDataKey", reqCtx, id, fromMasterKeyID, masterKeyID, wrappedKey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RewrapDataKey indicates an expected call of RewrapDataKey.
+func (mr *MockDataKeyRepositoryMockRecorder) RewrapDataKey(reqCtx, id, fromMasterKeyID, masterKeyID, wrappedKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RewrapDataKey", reflect.TypeOf((*MockDataKeyRepository)(nil).RewrapDataKey), reqCtx, id, fromMasterKeyID, masterKeyID, wrappedKey)
+}
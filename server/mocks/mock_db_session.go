@@ -83,6 +83,20 @@ func (mr *MockMongoSessionMockRecorder) CommitTransaction(arg0 any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitTransaction", reflect.TypeOf((*MockMongoSession)(nil).CommitTransaction), arg0)
 }
 
+// Context mocks base method.
+func (m *MockMongoSession) Context(ctx context.Context) context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context", ctx)
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockMongoSessionMockRecorder) Context(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockMongoSession)(nil).Context), ctx)
+}
+
 // EndSession mocks base method.
 func (m *MockMongoSession) EndSession(arg0 context.Context) {
 	m.ctrl.T.Helper()
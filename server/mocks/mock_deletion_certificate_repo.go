@@ -0,0 +1,104 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: deletion_certificate_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=deletion_certificate_repo.go -destination=../mocks/mock_deletion_certificate_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDeletionCertificateRepository is a mock of DeletionCertificateRepository interface.
+type MockDeletionCertificateRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeletionCertificateRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockDeletionCertificateRepositoryMockRecorder is the mock recorder for MockDeletionCertificateRepository.
+type MockDeletionCertificateRepositoryMockRecorder struct {
+	mock *MockDeletionCertificateRepository
+}
+
+// NewMockDeletionCertificateRepository creates a new mock instance.
+func NewMockDeletionCertificateRepository(ctrl *gomock.Controller) *MockDeletionCertificateRepository {
+	mock := &MockDeletionCertificateRepository{ctrl: ctrl}
+	mock.recorder = &MockDeletionCertificateRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeletionCertificateRepository) EXPECT() *MockDeletionCertificateRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateDeletionCertificate mocks base method.
+func (m *MockDeletionCertificateRepository) CreateDeletionCertificate(reqCtx *app.RequestContext, cert *model.DeletionCertificate) (*model.DeletionCertificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeletionCertificate", reqCtx, cert)
+	ret0, _ := ret[0].(*model.DeletionCertificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDeletionCertificate indicates an expected call of CreateDeletionCertificate.
+func (mr *MockDeletionCertificateRepositoryMockRecorder) CreateDeletionCertificate(reqCtx, cert any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeletionCertificate", reflect.TypeOf((*MockDeletionCertificateRepository)(nil).CreateDeletionCertificate), reqCtx, cert)
+}
+
+// GetCollection mocks base method.
+func (m *MockDeletionCertificateRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockDeletionCertificateRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockDeletionCertificateRepository)(nil).GetCollection), orgName...)
+}
+
+// ListDeletionCertificates mocks base method.
+func (m *MockDeletionCertificateRepository) ListDeletionCertificates(reqCtx *app.RequestContext, pageReq *app.PageRequest) ([]*model.DeletionCertificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeletionCertificates", reqCtx, pageReq)
+	ret0, _ := ret[0].([]*model.DeletionCertificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeletionCertificates indicates an expected call of ListDeletionCertificates.
+func (mr *MockDeletionCertificateRepositoryMockRecorder) ListDeletionCertificates(reqCtx, pageReq any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeletionCertificates", reflect.TypeOf((*MockDeletionCertificateRepository)(nil).ListDeletionCertificates), reqCtx, pageReq)
+}
@@ -24,6 +24,7 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
 	app "github.com/gaeaglobal/exto/server/app"
 	model "github.com/gaeaglobal/exto/server/model"
@@ -72,17 +73,17 @@ func (mr *MockIdentityRepoMockRecorder) CreateIdentity(reqCtx, identity any) *go
 }
 
 // DeleteIdentity mocks base method.
-func (m *MockIdentityRepo) DeleteIdentity(reqCtx *app.RequestContext) error {
+func (m *MockIdentityRepo) DeleteIdentity(reqCtx *app.RequestContext, id bson.ObjectID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteIdentity", reqCtx)
+	ret := m.ctrl.Call(m, "DeleteIdentity", reqCtx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteIdentity indicates an expected call of DeleteIdentity.
-func (mr *MockIdentityRepoMockRecorder) DeleteIdentity(reqCtx any) *gomock.Call {
+func (mr *MockIdentityRepoMockRecorder) DeleteIdentity(reqCtx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdentity", reflect.TypeOf((*MockIdentityRepo)(nil).DeleteIdentity), reqCtx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdentity", reflect.TypeOf((*MockIdentityRepo)(nil).DeleteIdentity), reqCtx, id)
 }
 
 // GetCollection mocks base method.
@@ -103,6 +104,21 @@ func (mr *MockIdentityRepoMockRecorder) GetCollection(orgName ...any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockIdentityRepo)(nil).GetCollection), orgName...)
 }
 
+// GetIdentitiesDeletedBefore mocks base method.
+func (m *MockIdentityRepo) GetIdentitiesDeletedBefore(reqCtx *app.RequestContext, before time.Time) ([]*model.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdentitiesDeletedBefore", reqCtx, before)
+	ret0, _ := ret[0].([]*model.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdentitiesDeletedBefore indicates an expected call of GetIdentitiesDeletedBefore.
+func (mr *MockIdentityRepoMockRecorder) GetIdentitiesDeletedBefore(reqCtx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdentitiesDeletedBefore", reflect.TypeOf((*MockIdentityRepo)(nil).GetIdentitiesDeletedBefore), reqCtx, before)
+}
+
 // GetIdentityByEmail mocks base method.
 func (m *MockIdentityRepo) GetIdentityByEmail(reqCtx *app.RequestContext, email string) (*model.Identity, error) {
 	m.ctrl.T.Helper()
@@ -133,6 +149,77 @@ func (mr *MockIdentityRepoMockRecorder) IsIdentityExists(reqCtx, email any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsIdentityExists", reflect.TypeOf((*MockIdentityRepo)(nil).IsIdentityExists), reqCtx, email)
 }
 
+// MarkEmailVerified mocks base method.
+func (m *MockIdentityRepo) MarkEmailVerified(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailVerified", reqCtx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailVerified indicates an expected call of MarkEmailVerified.
+func (mr *MockIdentityRepoMockRecorder) MarkEmailVerified(reqCtx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailVerified", reflect.TypeOf((*MockIdentityRepo)(nil).MarkEmailVerified), reqCtx, id)
+}
+
+// MarkIdentityDeleted mocks base method.
+func (m *MockIdentityRepo) MarkIdentityDeleted(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkIdentityDeleted", reqCtx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkIdentityDeleted indicates an expected call of MarkIdentityDeleted.
+func (mr *MockIdentityRepoMockRecorder) MarkIdentityDeleted(reqCtx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkIdentityDeleted", reflect.TypeOf((*MockIdentityRepo)(nil).MarkIdentityDeleted), reqCtx, id)
+}
+
+// RestoreIdentity mocks base method.
+func (m *MockIdentityRepo) RestoreIdentity(reqCtx *app.RequestContext, id bson.ObjectID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreIdentity", reqCtx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreIdentity indicates an expected call of RestoreIdentity.
+func (mr *MockIdentityRepoMockRecorder) RestoreIdentity(reqCtx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreIdentity", reflect.TypeOf((*MockIdentityRepo)(nil).RestoreIdentity), reqCtx, id)
+}
+
+// SetCurrentOrg mocks base method.
+func (m *MockIdentityRepo) SetCurrentOrg(reqCtx *app.RequestContext, id, orgID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCurrentOrg", reqCtx, id, orgID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCurrentOrg indicates an expected call of SetCurrentOrg.
+func (mr *MockIdentityRepoMockRecorder) SetCurrentOrg(reqCtx, id, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCurrentOrg", reflect.TypeOf((*MockIdentityRepo)(nil).SetCurrentOrg), reqCtx, id, orgID)
+}
+
+// SetPasswordHash mocks base method.
+func (m *MockIdentityRepo) SetPasswordHash(reqCtx *app.RequestContext, id bson.ObjectID, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPasswordHash", reqCtx, id, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPasswordHash indicates an expected call of SetPasswordHash.
+func (mr *MockIdentityRepoMockRecorder) SetPasswordHash(reqCtx, id, passwordHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPasswordHash", reflect.TypeOf((*MockIdentityRepo)(nil).SetPasswordHash), reqCtx, id, passwordHash)
+}
+
 // UpdateIdentity mocks base method.
 func (m *MockIdentityRepo) UpdateIdentity(reqCtx *app.RequestContext, id bson.ObjectID, identity *model.UpdateIdentity) (*model.Identity, error) {
 	m.ctrl.T.Helper()
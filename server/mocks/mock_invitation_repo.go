@@ -0,0 +1,193 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: invitation_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=invitation_repo.go -destination=../mocks/mock_invitation_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInvitationRepository is a mock of InvitationRepository interface.
+type MockInvitationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvitationRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockInvitationRepositoryMockRecorder is the mock recorder for MockInvitationRepository.
+type MockInvitationRepositoryMockRecorder struct {
+	mock *MockInvitationRepository
+}
+
+// NewMockInvitationRepository creates a new mock instance.
+func NewMockInvitationRepository(ctrl *gomock.Controller) *MockInvitationRepository {
+	mock := &MockInvitationRepository{ctrl: ctrl}
+	mock.recorder = &MockInvitationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInvitationRepository) EXPECT() *MockInvitationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateInvitation mocks base method.
+func (m *MockInvitationRepository) CreateInvitation(reqCtx *app.RequestContext, email string, role model.UserRole, tokenHash string, expiresAt time.Time) (*model.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInvitation", reqCtx, email, role, tokenHash, expiresAt)
+	ret0, _ := ret[0].(*model.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateInvitation indicates an expected call of CreateInvitation.
+func (mr *MockInvitationRepositoryMockRecorder) CreateInvitation(reqCtx, email, role, tokenHash, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvitation", reflect.TypeOf((*MockInvitationRepository)(nil).CreateInvitation), reqCtx, email, role, tokenHash, expiresAt)
+}
+
+// DeleteInvitationsByOrg mocks base method.
+func (m *MockInvitationRepository) DeleteInvitationsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteInvitationsByOrg", reqCtx, orgID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteInvitationsByOrg indicates an expected call of DeleteInvitationsByOrg.
+func (mr *MockInvitationRepositoryMockRecorder) DeleteInvitationsByOrg(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteInvitationsByOrg", reflect.TypeOf((*MockInvitationRepository)(nil).DeleteInvitationsByOrg), reqCtx, orgID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockInvitationRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockInvitationRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockInvitationRepository)(nil).EnsureIndexes))
+}
+
+// GetCollection mocks base method.
+func (m *MockInvitationRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockInvitationRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockInvitationRepository)(nil).GetCollection), orgName...)
+}
+
+// GetInvitationByTokenHash mocks base method.
+func (m *MockInvitationRepository) GetInvitationByTokenHash(reqCtx *app.RequestContext, tokenHash string) (*model.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitationByTokenHash", reqCtx, tokenHash)
+	ret0, _ := ret[0].(*model.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitationByTokenHash indicates an expected call of GetInvitationByTokenHash.
+func (mr *MockInvitationRepositoryMockRecorder) GetInvitationByTokenHash(reqCtx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitationByTokenHash", reflect.TypeOf((*MockInvitationRepository)(nil).GetInvitationByTokenHash), reqCtx, tokenHash)
+}
+
+// ListInvitations mocks base method.
+func (m *MockInvitationRepository) ListInvitations(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListInvitations", reqCtx, orgID)
+	ret0, _ := ret[0].([]*model.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListInvitations indicates an expected call of ListInvitations.
+func (mr *MockInvitationRepositoryMockRecorder) ListInvitations(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInvitations", reflect.TypeOf((*MockInvitationRepository)(nil).ListInvitations), reqCtx, orgID)
+}
+
+// MarkAccepted mocks base method.
+func (m *MockInvitationRepository) MarkAccepted(reqCtx *app.RequestContext, id, userID bson.ObjectID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAccepted", reqCtx, id, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkAccepted indicates an expected call of MarkAccepted.
+func (mr *MockInvitationRepositoryMockRecorder) MarkAccepted(reqCtx, id, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAccepted", reflect.TypeOf((*MockInvitationRepository)(nil).MarkAccepted), reqCtx, id, userID)
+}
+
+// RevokeInvitation mocks base method.
+func (m *MockInvitationRepository) RevokeInvitation(reqCtx *app.RequestContext, orgID, id bson.ObjectID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeInvitation", reqCtx, orgID, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeInvitation indicates an expected call of RevokeInvitation.
+func (mr *MockInvitationRepositoryMockRecorder) RevokeInvitation(reqCtx, orgID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeInvitation", reflect.TypeOf((*MockInvitationRepository)(nil).RevokeInvitation), reqCtx, orgID, id)
+}
+
+// RevokePendingInvitations mocks base method.
+func (m *MockInvitationRepository) RevokePendingInvitations(reqCtx *app.RequestContext, orgID bson.ObjectID, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokePendingInvitations", reqCtx, orgID, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokePendingInvitations indicates an expected call of RevokePendingInvitations.
+func (mr *MockInvitationRepositoryMockRecorder) RevokePendingInvitations(reqCtx, orgID, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokePendingInvitations", reflect.TypeOf((*MockInvitationRepository)(nil).RevokePendingInvitations), reqCtx, orgID, email)
+}
@@ -0,0 +1,105 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: invoice_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=invoice_repo.go -destination=../mocks/mock_invoice_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInvoiceRepository is a mock of InvoiceRepository interface.
+type MockInvoiceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvoiceRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockInvoiceRepositoryMockRecorder is the mock recorder for MockInvoiceRepository.
+type MockInvoiceRepositoryMockRecorder struct {
+	mock *MockInvoiceRepository
+}
+
+// NewMockInvoiceRepository creates a new mock instance.
+func NewMockInvoiceRepository(ctrl *gomock.Controller) *MockInvoiceRepository {
+	mock := &MockInvoiceRepository{ctrl: ctrl}
+	mock.recorder = &MockInvoiceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInvoiceRepository) EXPECT() *MockInvoiceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetCollection mocks base method.
+func (m *MockInvoiceRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockInvoiceRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockInvoiceRepository)(nil).GetCollection), orgName...)
+}
+
+// ListInvoices mocks base method.
+func (m *MockInvoiceRepository) ListInvoices(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListInvoices", reqCtx, orgID)
+	ret0, _ := ret[0].([]*model.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListInvoices indicates an expected call of ListInvoices.
+func (mr *MockInvoiceRepositoryMockRecorder) ListInvoices(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInvoices", reflect.TypeOf((*MockInvoiceRepository)(nil).ListInvoices), reqCtx, orgID)
+}
+
+// UpsertInvoice mocks base method.
+func (m *MockInvoiceRepository) UpsertInvoice(reqCtx *app.RequestContext, invoice *model.SyncInvoice) (*model.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertInvoice", reqCtx, invoice)
+	ret0, _ := ret[0].(*model.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertInvoice indicates an expected call of UpsertInvoice.
+func (mr *MockInvoiceRepositoryMockRecorder) UpsertInvoice(reqCtx, invoice any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertInvoice", reflect.TypeOf((*MockInvoiceRepository)(nil).UpsertInvoice), reqCtx, invoice)
+}
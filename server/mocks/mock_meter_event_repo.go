@@ -0,0 +1,191 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: meter_event_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=meter_event_repo.go -destination=../mocks/mock_meter_event_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMeterEventRepository is a mock of MeterEventRepository interface.
+type MockMeterEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockMeterEventRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockMeterEventRepositoryMockRecorder is the mock recorder for MockMeterEventRepository.
+type MockMeterEventRepositoryMockRecorder struct {
+	mock *MockMeterEventRepository
+}
+
+// NewMockMeterEventRepository creates a new mock instance.
+func NewMockMeterEventRepository(ctrl *gomock.Controller) *MockMeterEventRepository {
+	mock := &MockMeterEventRepository{ctrl: ctrl}
+	mock.recorder = &MockMeterEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMeterEventRepository) EXPECT() *MockMeterEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimNextDue mocks base method.
+func (m *MockMeterEventRepository) ClaimNextDue(reqCtx *app.RequestContext, now time.Time, lease time.Duration) (*model.MeterEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimNextDue", reqCtx, now, lease)
+	ret0, _ := ret[0].(*model.MeterEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimNextDue indicates an expected call of ClaimNextDue.
+func (mr *MockMeterEventRepositoryMockRecorder) ClaimNextDue(reqCtx, now, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimNextDue", reflect.TypeOf((*MockMeterEventRepository)(nil).ClaimNextDue), reqCtx, now, lease)
+}
+
+// CreateMeterEvent mocks base method.
+func (m *MockMeterEventRepository) CreateMeterEvent(reqCtx *app.RequestContext, name string, value int, stripeCustomerID string) (*model.MeterEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMeterEvent", reqCtx, name, value, stripeCustomerID)
+	ret0, _ := ret[0].(*model.MeterEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMeterEvent indicates an expected call of CreateMeterEvent.
+func (mr *MockMeterEventRepositoryMockRecorder) CreateMeterEvent(reqCtx, name, value, stripeCustomerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMeterEvent", reflect.TypeOf((*MockMeterEventRepository)(nil).CreateMeterEvent), reqCtx, name, value, stripeCustomerID)
+}
+
+// DeleteMeterEventsByOrg mocks base method.
+func (m *MockMeterEventRepository) DeleteMeterEventsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMeterEventsByOrg", reqCtx, orgID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMeterEventsByOrg indicates an expected call of DeleteMeterEventsByOrg.
+func (mr *MockMeterEventRepositoryMockRecorder) DeleteMeterEventsByOrg(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMeterEventsByOrg", reflect.TypeOf((*MockMeterEventRepository)(nil).DeleteMeterEventsByOrg), reqCtx, orgID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockMeterEventRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockMeterEventRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockMeterEventRepository)(nil).EnsureIndexes))
+}
+
+// GetCollection mocks base method.
+func (m *MockMeterEventRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockMeterEventRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockMeterEventRepository)(nil).GetCollection), orgName...)
+}
+
+// MarkFailed mocks base method.
+func (m *MockMeterEventRepository) MarkFailed(reqCtx *app.RequestContext, id bson.ObjectID, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkFailed", reqCtx, id, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkFailed indicates an expected call of MarkFailed.
+func (mr *MockMeterEventRepositoryMockRecorder) MarkFailed(reqCtx, id, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkFailed", reflect.TypeOf((*MockMeterEventRepository)(nil).MarkFailed), reqCtx, id, lastError)
+}
+
+// MarkRetry mocks base method.
+func (m *MockMeterEventRepository) MarkRetry(reqCtx *app.RequestContext, id bson.ObjectID, nextAttemptAt time.Time, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRetry", reqCtx, id, nextAttemptAt, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkRetry indicates an expected call of MarkRetry.
+func (mr *MockMeterEventRepositoryMockRecorder) MarkRetry(reqCtx, id, nextAttemptAt, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRetry", reflect.TypeOf((*MockMeterEventRepository)(nil).MarkRetry), reqCtx, id, nextAttemptAt, lastError)
+}
+
+// MarkSent mocks base method.
+func (m *MockMeterEventRepository) MarkSent(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkSent", reqCtx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkSent indicates an expected call of MarkSent.
+func (mr *MockMeterEventRepositoryMockRecorder) MarkSent(reqCtx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkSent", reflect.TypeOf((*MockMeterEventRepository)(nil).MarkSent), reqCtx, id)
+}
+
+// SumMeterEvents mocks base method.
+func (m *MockMeterEventRepository) SumMeterEvents(reqCtx *app.RequestContext, orgID bson.ObjectID, start, end time.Time) ([]*model.MeterEventTotal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumMeterEvents", reqCtx, orgID, start, end)
+	ret0, _ := ret[0].([]*model.MeterEventTotal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumMeterEvents indicates an expected call of SumMeterEvents.
+func (mr *MockMeterEventRepositoryMockRecorder) SumMeterEvents(reqCtx, orgID, start, end any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumMeterEvents", reflect.TypeOf((*MockMeterEventRepository)(nil).SumMeterEvents), reqCtx, orgID, start, end)
+}
@@ -0,0 +1,147 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: monthly_usage_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=monthly_usage_repo.go -destination=../mocks/mock_monthly_usage_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMonthlyUsageRepository is a mock of MonthlyUsageRepository interface.
+type MockMonthlyUsageRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockMonthlyUsageRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockMonthlyUsageRepositoryMockRecorder is the mock recorder for MockMonthlyUsageRepository.
+type MockMonthlyUsageRepositoryMockRecorder struct {
+	mock *MockMonthlyUsageRepository
+}
+
+// NewMockMonthlyUsageRepository creates a new mock instance.
+func NewMockMonthlyUsageRepository(ctrl *gomock.Controller) *MockMonthlyUsageRepository {
+	mock := &MockMonthlyUsageRepository{ctrl: ctrl}
+	mock.recorder = &MockMonthlyUsageRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMonthlyUsageRepository) EXPECT() *MockMonthlyUsageRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteUsageByOrg mocks base method.
+func (m *MockMonthlyUsageRepository) DeleteUsageByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUsageByOrg", reqCtx, orgID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUsageByOrg indicates an expected call of DeleteUsageByOrg.
+func (mr *MockMonthlyUsageRepositoryMockRecorder) DeleteUsageByOrg(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUsageByOrg", reflect.TypeOf((*MockMonthlyUsageRepository)(nil).DeleteUsageByOrg), reqCtx, orgID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockMonthlyUsageRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockMonthlyUsageRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockMonthlyUsageRepository)(nil).EnsureIndexes))
+}
+
+// GetCollection mocks base method.
+func (m *MockMonthlyUsageRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockMonthlyUsageRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockMonthlyUsageRepository)(nil).GetCollection), orgName...)
+}
+
+// GetUsage mocks base method.
+func (m *MockMonthlyUsageRepository) GetUsage(reqCtx *app.RequestContext, eventName string, year, month int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsage", reqCtx, eventName, year, month)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsage indicates an expected call of GetUsage.
+func (mr *MockMonthlyUsageRepositoryMockRecorder) GetUsage(reqCtx, eventName, year, month any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsage", reflect.TypeOf((*MockMonthlyUsageRepository)(nil).GetUsage), reqCtx, eventName, year, month)
+}
+
+// Release mocks base method.
+func (m *MockMonthlyUsageRepository) Release(reqCtx *app.RequestContext, eventName string, year, month int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", reqCtx, eventName, year, month)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockMonthlyUsageRepositoryMockRecorder) Release(reqCtx, eventName, year, month any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockMonthlyUsageRepository)(nil).Release), reqCtx, eventName, year, month)
+}
+
+// Reserve mocks base method.
+func (m *MockMonthlyUsageRepository) Reserve(reqCtx *app.RequestContext, eventName string, year, month, limit int) (*model.MonthlyUsageEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", reqCtx, eventName, year, month, limit)
+	ret0, _ := ret[0].(*model.MonthlyUsageEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockMonthlyUsageRepositoryMockRecorder) Reserve(reqCtx, eventName, year, month, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockMonthlyUsageRepository)(nil).Reserve), reqCtx, eventName, year, month, limit)
+}
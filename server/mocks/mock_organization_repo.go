@@ -71,19 +71,46 @@ func (mr *MockOrganizationRepoMockRecorder) CreateOrganization(reqCtx, org, crea
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrganization", reflect.TypeOf((*MockOrganizationRepo)(nil).CreateOrganization), reqCtx, org, createdByIdentity)
 }
 
-// DeleteOrganizationByOwner mocks base method.
-func (m *MockOrganizationRepo) DeleteOrganizationByOwner(reqCtx *app.RequestContext) ([]*model.Organization, error) {
+// DeleteOrganization mocks base method.
+func (m *MockOrganizationRepo) DeleteOrganization(reqCtx *app.RequestContext, id bson.ObjectID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteOrganizationByOwner", reqCtx)
-	ret0, _ := ret[0].([]*model.Organization)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "DeleteOrganization", reqCtx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOrganization indicates an expected call of DeleteOrganization.
+func (mr *MockOrganizationRepoMockRecorder) DeleteOrganization(reqCtx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrganization", reflect.TypeOf((*MockOrganizationRepo)(nil).DeleteOrganization), reqCtx, id)
+}
+
+// DropOrganizationDatabase mocks base method.
+func (m *MockOrganizationRepo) DropOrganizationDatabase(reqCtx *app.RequestContext, slug string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DropOrganizationDatabase", reqCtx, slug)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DropOrganizationDatabase indicates an expected call of DropOrganizationDatabase.
+func (mr *MockOrganizationRepoMockRecorder) DropOrganizationDatabase(reqCtx, slug any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropOrganizationDatabase", reflect.TypeOf((*MockOrganizationRepo)(nil).DropOrganizationDatabase), reqCtx, slug)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockOrganizationRepo) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// DeleteOrganizationByOwner indicates an expected call of DeleteOrganizationByOwner.
-func (mr *MockOrganizationRepoMockRecorder) DeleteOrganizationByOwner(reqCtx any) *gomock.Call {
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockOrganizationRepoMockRecorder) EnsureIndexes() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrganizationByOwner", reflect.TypeOf((*MockOrganizationRepo)(nil).DeleteOrganizationByOwner), reqCtx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockOrganizationRepo)(nil).EnsureIndexes))
 }
 
 // GenerateNextScanCode mocks base method.
@@ -179,6 +206,21 @@ func (mr *MockOrganizationRepoMockRecorder) IsOrganizationExists(reqCtx, name an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsOrganizationExists", reflect.TypeOf((*MockOrganizationRepo)(nil).IsOrganizationExists), reqCtx, name)
 }
 
+// IsSlugTaken mocks base method.
+func (m *MockOrganizationRepo) IsSlugTaken(reqCtx *app.RequestContext, slug string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSlugTaken", reqCtx, slug)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSlugTaken indicates an expected call of IsSlugTaken.
+func (mr *MockOrganizationRepoMockRecorder) IsSlugTaken(reqCtx, slug any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSlugTaken", reflect.TypeOf((*MockOrganizationRepo)(nil).IsSlugTaken), reqCtx, slug)
+}
+
 // ListOrganizations mocks base method.
 func (m *MockOrganizationRepo) ListOrganizations(reqCtx *app.RequestContext, pageReq *app.PageRequest) (*app.PageResponse[*model.Organization], error) {
 	m.ctrl.T.Helper()
@@ -194,6 +236,79 @@ func (mr *MockOrganizationRepoMockRecorder) ListOrganizations(reqCtx, pageReq an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrganizations", reflect.TypeOf((*MockOrganizationRepo)(nil).ListOrganizations), reqCtx, pageReq)
 }
 
+// MarkOrganizationsDeleted mocks base method.
+func (m *MockOrganizationRepo) MarkOrganizationsDeleted(reqCtx *app.RequestContext, ids []bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOrganizationsDeleted", reqCtx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOrganizationsDeleted indicates an expected call of MarkOrganizationsDeleted.
+func (mr *MockOrganizationRepoMockRecorder) MarkOrganizationsDeleted(reqCtx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOrganizationsDeleted", reflect.TypeOf((*MockOrganizationRepo)(nil).MarkOrganizationsDeleted), reqCtx, ids)
+}
+
+// RestoreOrganizations mocks base method.
+func (m *MockOrganizationRepo) RestoreOrganizations(reqCtx *app.RequestContext, ids []bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreOrganizations", reqCtx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreOrganizations indicates an expected call of RestoreOrganizations.
+func (mr *MockOrganizationRepoMockRecorder) RestoreOrganizations(reqCtx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreOrganizations", reflect.TypeOf((*MockOrganizationRepo)(nil).RestoreOrganizations), reqCtx, ids)
+}
+
+// SetExtractionCacheDisabled mocks base method.
+func (m *MockOrganizationRepo) SetExtractionCacheDisabled(reqCtx *app.RequestContext, id bson.ObjectID, disabled bool) (*model.Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetExtractionCacheDisabled", reqCtx, id, disabled)
+	ret0, _ := ret[0].(*model.Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetExtractionCacheDisabled indicates an expected call of SetExtractionCacheDisabled.
+func (mr *MockOrganizationRepoMockRecorder) SetExtractionCacheDisabled(reqCtx, id, disabled any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetExtractionCacheDisabled", reflect.TypeOf((*MockOrganizationRepo)(nil).SetExtractionCacheDisabled), reqCtx, id, disabled)
+}
+
+// SetMicrosoftTenantIDs mocks base method.
+func (m *MockOrganizationRepo) SetMicrosoftTenantIDs(reqCtx *app.RequestContext, id bson.ObjectID, tenantIDs []string) (*model.Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMicrosoftTenantIDs", reqCtx, id, tenantIDs)
+	ret0, _ := ret[0].(*model.Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetMicrosoftTenantIDs indicates an expected call of SetMicrosoftTenantIDs.
+func (mr *MockOrganizationRepoMockRecorder) SetMicrosoftTenantIDs(reqCtx, id, tenantIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMicrosoftTenantIDs", reflect.TypeOf((*MockOrganizationRepo)(nil).SetMicrosoftTenantIDs), reqCtx, id, tenantIDs)
+}
+
+// SetSSOConfig mocks base method.
+func (m *MockOrganizationRepo) SetSSOConfig(reqCtx *app.RequestContext, id bson.ObjectID, sso *model.SSOConfig) (*model.Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSSOConfig", reqCtx, id, sso)
+	ret0, _ := ret[0].(*model.Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetSSOConfig indicates an expected call of SetSSOConfig.
+func (mr *MockOrganizationRepoMockRecorder) SetSSOConfig(reqCtx, id, sso any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSSOConfig", reflect.TypeOf((*MockOrganizationRepo)(nil).SetSSOConfig), reqCtx, id, sso)
+}
+
 // UpdateOrganization mocks base method.
 func (m *MockOrganizationRepo) UpdateOrganization(reqCtx *app.RequestContext, id bson.ObjectID, org *model.UpdateOrganization) (*model.Organization, error) {
 	m.ctrl.T.Helper()
@@ -208,3 +323,18 @@ func (mr *MockOrganizationRepoMockRecorder) UpdateOrganization(reqCtx, id, org a
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrganization", reflect.TypeOf((*MockOrganizationRepo)(nil).UpdateOrganization), reqCtx, id, org)
 }
+
+// UpdateOrganizationProfile mocks base method.
+func (m *MockOrganizationRepo) UpdateOrganizationProfile(reqCtx *app.RequestContext, id bson.ObjectID, name string, settings *model.OrganizationSettings) (*model.Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOrganizationProfile", reqCtx, id, name, settings)
+	ret0, _ := ret[0].(*model.Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateOrganizationProfile indicates an expected call of UpdateOrganizationProfile.
+func (mr *MockOrganizationRepoMockRecorder) UpdateOrganizationProfile(reqCtx, id, name, settings any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrganizationProfile", reflect.TypeOf((*MockOrganizationRepo)(nil).UpdateOrganizationProfile), reqCtx, id, name, settings)
+}
@@ -0,0 +1,194 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: plan_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=plan_repo.go -destination=../mocks/mock_plan_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPlanRepository is a mock of PlanRepository interface.
+type MockPlanRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPlanRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockPlanRepositoryMockRecorder is the mock recorder for MockPlanRepository.
+type MockPlanRepositoryMockRecorder struct {
+	mock *MockPlanRepository
+}
+
+// NewMockPlanRepository creates a new mock instance.
+func NewMockPlanRepository(ctrl *gomock.Controller) *MockPlanRepository {
+	mock := &MockPlanRepository{ctrl: ctrl}
+	mock.recorder = &MockPlanRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPlanRepository) EXPECT() *MockPlanRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreatePlan mocks base method.
+func (m *MockPlanRepository) CreatePlan(reqCtx *app.RequestContext, plan *model.CreatePlan) (*model.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePlan", reqCtx, plan)
+	ret0, _ := ret[0].(*model.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePlan indicates an expected call of CreatePlan.
+func (mr *MockPlanRepositoryMockRecorder) CreatePlan(reqCtx, plan any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePlan", reflect.TypeOf((*MockPlanRepository)(nil).CreatePlan), reqCtx, plan)
+}
+
+// EnsurePlan mocks base method.
+func (m *MockPlanRepository) EnsurePlan(reqCtx *app.RequestContext, plan *model.Plan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsurePlan", reqCtx, plan)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsurePlan indicates an expected call of EnsurePlan.
+func (mr *MockPlanRepositoryMockRecorder) EnsurePlan(reqCtx, plan any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsurePlan", reflect.TypeOf((*MockPlanRepository)(nil).EnsurePlan), reqCtx, plan)
+}
+
+// GetCollection mocks base method.
+func (m *MockPlanRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockPlanRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockPlanRepository)(nil).GetCollection), orgName...)
+}
+
+// GetPlanByCode mocks base method.
+func (m *MockPlanRepository) GetPlanByCode(reqCtx *app.RequestContext, code model.PlanCode) (*model.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlanByCode", reqCtx, code)
+	ret0, _ := ret[0].(*model.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPlanByCode indicates an expected call of GetPlanByCode.
+func (mr *MockPlanRepositoryMockRecorder) GetPlanByCode(reqCtx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlanByCode", reflect.TypeOf((*MockPlanRepository)(nil).GetPlanByCode), reqCtx, code)
+}
+
+// GetPlanByID mocks base method.
+func (m *MockPlanRepository) GetPlanByID(reqCtx *app.RequestContext, id bson.ObjectID) (*model.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlanByID", reqCtx, id)
+	ret0, _ := ret[0].(*model.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPlanByID indicates an expected call of GetPlanByID.
+func (mr *MockPlanRepositoryMockRecorder) GetPlanByID(reqCtx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlanByID", reflect.TypeOf((*MockPlanRepository)(nil).GetPlanByID), reqCtx, id)
+}
+
+// GetPlanByStripePriceID mocks base method.
+func (m *MockPlanRepository) GetPlanByStripePriceID(reqCtx *app.RequestContext, priceID string) (*model.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlanByStripePriceID", reqCtx, priceID)
+	ret0, _ := ret[0].(*model.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPlanByStripePriceID indicates an expected call of GetPlanByStripePriceID.
+func (mr *MockPlanRepositoryMockRecorder) GetPlanByStripePriceID(reqCtx, priceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlanByStripePriceID", reflect.TypeOf((*MockPlanRepository)(nil).GetPlanByStripePriceID), reqCtx, priceID)
+}
+
+// IsPlanExists mocks base method.
+func (m *MockPlanRepository) IsPlanExists(reqCtx *app.RequestContext, code model.PlanCode) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsPlanExists", reqCtx, code)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsPlanExists indicates an expected call of IsPlanExists.
+func (mr *MockPlanRepositoryMockRecorder) IsPlanExists(reqCtx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPlanExists", reflect.TypeOf((*MockPlanRepository)(nil).IsPlanExists), reqCtx, code)
+}
+
+// ListPlans mocks base method.
+func (m *MockPlanRepository) ListPlans(reqCtx *app.RequestContext, activeOnly bool) ([]*model.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPlans", reqCtx, activeOnly)
+	ret0, _ := ret[0].([]*model.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPlans indicates an expected call of ListPlans.
+func (mr *MockPlanRepositoryMockRecorder) ListPlans(reqCtx, activeOnly any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPlans", reflect.TypeOf((*MockPlanRepository)(nil).ListPlans), reqCtx, activeOnly)
+}
+
+// UpdatePlan mocks base method.
+func (m *MockPlanRepository) UpdatePlan(reqCtx *app.RequestContext, id bson.ObjectID, plan *model.UpdatePlan) (*model.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePlan", reqCtx, id, plan)
+	ret0, _ := ret[0].(*model.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePlan indicates an expected call of UpdatePlan.
+func (mr *MockPlanRepositoryMockRecorder) UpdatePlan(reqCtx, id, plan any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePlan", reflect.TypeOf((*MockPlanRepository)(nil).UpdatePlan), reqCtx, id, plan)
+}
@@ -0,0 +1,119 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: quota_override_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=quota_override_repo.go -destination=../mocks/mock_quota_override_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockQuotaOverrideRepository is a mock of QuotaOverrideRepository interface.
+type MockQuotaOverrideRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotaOverrideRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockQuotaOverrideRepositoryMockRecorder is the mock recorder for MockQuotaOverrideRepository.
+type MockQuotaOverrideRepositoryMockRecorder struct {
+	mock *MockQuotaOverrideRepository
+}
+
+// NewMockQuotaOverrideRepository creates a new mock instance.
+func NewMockQuotaOverrideRepository(ctrl *gomock.Controller) *MockQuotaOverrideRepository {
+	mock := &MockQuotaOverrideRepository{ctrl: ctrl}
+	mock.recorder = &MockQuotaOverrideRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQuotaOverrideRepository) EXPECT() *MockQuotaOverrideRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteOverride mocks base method.
+func (m *MockQuotaOverrideRepository) DeleteOverride(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOverride", reqCtx, orgID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOverride indicates an expected call of DeleteOverride.
+func (mr *MockQuotaOverrideRepositoryMockRecorder) DeleteOverride(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOverride", reflect.TypeOf((*MockQuotaOverrideRepository)(nil).DeleteOverride), reqCtx, orgID)
+}
+
+// GetActiveOverride mocks base method.
+func (m *MockQuotaOverrideRepository) GetActiveOverride(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.QuotaOverride, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveOverride", reqCtx, orgID)
+	ret0, _ := ret[0].(*model.QuotaOverride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveOverride indicates an expected call of GetActiveOverride.
+func (mr *MockQuotaOverrideRepositoryMockRecorder) GetActiveOverride(reqCtx, orgID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveOverride", reflect.TypeOf((*MockQuotaOverrideRepository)(nil).GetActiveOverride), reqCtx, orgID)
+}
+
+// GetCollection mocks base method.
+func (m *MockQuotaOverrideRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockQuotaOverrideRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockQuotaOverrideRepository)(nil).GetCollection), orgName...)
+}
+
+// UpsertOverride mocks base method.
+func (m *MockQuotaOverrideRepository) UpsertOverride(reqCtx *app.RequestContext, orgID bson.ObjectID, override *model.CreateQuotaOverride) (*model.QuotaOverride, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertOverride", reqCtx, orgID, override)
+	ret0, _ := ret[0].(*model.QuotaOverride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertOverride indicates an expected call of UpsertOverride.
+func (mr *MockQuotaOverrideRepositoryMockRecorder) UpsertOverride(reqCtx, orgID, override any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertOverride", reflect.TypeOf((*MockQuotaOverrideRepository)(nil).UpsertOverride), reqCtx, orgID, override)
+}
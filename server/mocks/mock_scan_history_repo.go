@@ -71,6 +71,21 @@ func (mr *MockScanHistoryRepoMockRecorder) CreateScanHistory(reqCtx, scanHistory
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScanHistory", reflect.TypeOf((*MockScanHistoryRepo)(nil).CreateScanHistory), reqCtx, scanHistory)
 }
 
+// GetCategoryDataIDsByBatches mocks base method.
+func (m *MockScanHistoryRepo) GetCategoryDataIDsByBatches(reqCtx *app.RequestContext, batchIDs []bson.ObjectID) ([]bson.ObjectID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryDataIDsByBatches", reqCtx, batchIDs)
+	ret0, _ := ret[0].([]bson.ObjectID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryDataIDsByBatches indicates an expected call of GetCategoryDataIDsByBatches.
+func (mr *MockScanHistoryRepoMockRecorder) GetCategoryDataIDsByBatches(reqCtx, batchIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryDataIDsByBatches", reflect.TypeOf((*MockScanHistoryRepo)(nil).GetCategoryDataIDsByBatches), reqCtx, batchIDs)
+}
+
 // GetCollection mocks base method.
 func (m *MockScanHistoryRepo) GetCollection(orgName ...string) *mongo.Collection {
 	m.ctrl.T.Helper()
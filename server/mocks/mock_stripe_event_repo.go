@@ -0,0 +1,116 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: stripe_event_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=stripe_event_repo.go -destination=../mocks/mock_stripe_event_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	mongo "go.mongodb.org/mongo-driver/v2/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStripeEventRepository is a mock of StripeEventRepository interface.
+type MockStripeEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockStripeEventRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockStripeEventRepositoryMockRecorder is the mock recorder for MockStripeEventRepository.
+type MockStripeEventRepositoryMockRecorder struct {
+	mock *MockStripeEventRepository
+}
+
+// NewMockStripeEventRepository creates a new mock instance.
+func NewMockStripeEventRepository(ctrl *gomock.Controller) *MockStripeEventRepository {
+	mock := &MockStripeEventRepository{ctrl: ctrl}
+	mock.recorder = &MockStripeEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStripeEventRepository) EXPECT() *MockStripeEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Claim mocks base method.
+func (m *MockStripeEventRepository) Claim(reqCtx *app.RequestContext, eventID, eventType string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Claim", reqCtx, eventID, eventType)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Claim indicates an expected call of Claim.
+func (mr *MockStripeEventRepositoryMockRecorder) Claim(reqCtx, eventID, eventType any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Claim", reflect.TypeOf((*MockStripeEventRepository)(nil).Claim), reqCtx, eventID, eventType)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockStripeEventRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockStripeEventRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockStripeEventRepository)(nil).EnsureIndexes))
+}
+
+// GetCollection mocks base method.
+func (m *MockStripeEventRepository) GetCollection(orgName ...string) *mongo.Collection {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range orgName {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCollection", varargs...)
+	ret0, _ := ret[0].(*mongo.Collection)
+	return ret0
+}
+
+// GetCollection indicates an expected call of GetCollection.
+func (mr *MockStripeEventRepositoryMockRecorder) GetCollection(orgName ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockStripeEventRepository)(nil).GetCollection), orgName...)
+}
+
+// Release mocks base method.
+func (m *MockStripeEventRepository) Release(reqCtx *app.RequestContext, eventID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", reqCtx, eventID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockStripeEventRepositoryMockRecorder) Release(reqCtx, eventID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockStripeEventRepository)(nil).Release), reqCtx, eventID)
+}
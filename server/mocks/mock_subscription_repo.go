@@ -0,0 +1,189 @@
+// /*
+// Copyright 2025 The Exto Project Solutions, Inc.
+// All rights reserved.
+//
+// Author: Vimalraj Arumugam
+//
+// This software is the confidential and proprietary product of The Exto Project Solutions, Inc.
+// and is protected by copyright and trade secret law.
+// Use, reproduction, and distribution of this software is strictly forbidden.
+//
+// For more details, please refer to the LICENSE file in the root directory of this project.
+// */
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: subscription_repo.go
+//
+// Generated by this command:
+//
+//	mockgen -source=subscription_repo.go -destination=../mocks/mock_subscription_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	app "github.com/gaeaglobal/exto/server/app"
+	model "github.com/gaeaglobal/exto/server/model"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSubscriptionRepository is a mock of SubscriptionRepository interface.
+type MockSubscriptionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSubscriptionRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockSubscriptionRepositoryMockRecorder is the mock recorder for MockSubscriptionRepository.
+type MockSubscriptionRepositoryMockRecorder struct {
+	mock *MockSubscriptionRepository
+}
+
+// NewMockSubscriptionRepository creates a new mock instance.
+func NewMockSubscriptionRepository(ctrl *gomock.Controller) *MockSubscriptionRepository {
+	mock := &MockSubscriptionRepository{ctrl: ctrl}
+	mock.recorder = &MockSubscriptionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSubscriptionRepository) EXPECT() *MockSubscriptionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateSubscription mocks base method.
+func (m *MockSubscriptionRepository) CreateSubscription(reqCtx *app.RequestContext, subscription *model.CreateSubscription) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSubscription", reqCtx, subscription)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSubscription indicates an expected call of CreateSubscription.
+func (mr *MockSubscriptionRepositoryMockRecorder) CreateSubscription(reqCtx, subscription any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSubscription", reflect.TypeOf((*MockSubscriptionRepository)(nil).CreateSubscription), reqCtx, subscription)
+}
+
+// DetachPaymentMethod mocks base method.
+func (m *MockSubscriptionRepository) DetachPaymentMethod(reqCtx *app.RequestContext, stripePaymentMethodID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetachPaymentMethod", reqCtx, stripePaymentMethodID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DetachPaymentMethod indicates an expected call of DetachPaymentMethod.
+func (mr *MockSubscriptionRepositoryMockRecorder) DetachPaymentMethod(reqCtx, stripePaymentMethodID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachPaymentMethod", reflect.TypeOf((*MockSubscriptionRepository)(nil).DetachPaymentMethod), reqCtx, stripePaymentMethodID)
+}
+
+// GetFreeTrialInfo mocks base method.
+func (m *MockSubscriptionRepository) GetFreeTrialInfo(reqCtx *app.RequestContext) (*model.GetFreeTrialInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFreeTrialInfo", reqCtx)
+	ret0, _ := ret[0].(*model.GetFreeTrialInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFreeTrialInfo indicates an expected call of GetFreeTrialInfo.
+func (mr *MockSubscriptionRepositoryMockRecorder) GetFreeTrialInfo(reqCtx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFreeTrialInfo", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetFreeTrialInfo), reqCtx)
+}
+
+// GetMySubscription mocks base method.
+func (m *MockSubscriptionRepository) GetMySubscription(reqCtx *app.RequestContext) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMySubscription", reqCtx)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMySubscription indicates an expected call of GetMySubscription.
+func (mr *MockSubscriptionRepositoryMockRecorder) GetMySubscription(reqCtx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMySubscription", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetMySubscription), reqCtx)
+}
+
+// GetSubscriptionByID mocks base method.
+func (m *MockSubscriptionRepository) GetSubscriptionByID(reqCtx *app.RequestContext, subscriptionID bson.ObjectID) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriptionByID", reqCtx, subscriptionID)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptionByID indicates an expected call of GetSubscriptionByID.
+func (mr *MockSubscriptionRepositoryMockRecorder) GetSubscriptionByID(reqCtx, subscriptionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionByID", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetSubscriptionByID), reqCtx, subscriptionID)
+}
+
+// GetSubscriptionByStripeID mocks base method.
+func (m *MockSubscriptionRepository) GetSubscriptionByStripeID(reqCtx *app.RequestContext, stripeSubID string) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriptionByStripeID", reqCtx, stripeSubID)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptionByStripeID indicates an expected call of GetSubscriptionByStripeID.
+func (mr *MockSubscriptionRepositoryMockRecorder) GetSubscriptionByStripeID(reqCtx, stripeSubID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionByStripeID", reflect.TypeOf((*MockSubscriptionRepository)(nil).GetSubscriptionByStripeID), reqCtx, stripeSubID)
+}
+
+// SyncPaymentMethod mocks base method.
+func (m *MockSubscriptionRepository) SyncPaymentMethod(reqCtx *app.RequestContext, paymentMethod *model.PaymentMethod) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncPaymentMethod", reqCtx, paymentMethod)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SyncPaymentMethod indicates an expected call of SyncPaymentMethod.
+func (mr *MockSubscriptionRepositoryMockRecorder) SyncPaymentMethod(reqCtx, paymentMethod any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncPaymentMethod", reflect.TypeOf((*MockSubscriptionRepository)(nil).SyncPaymentMethod), reqCtx, paymentMethod)
+}
+
+// SyncStripeSubscription mocks base method.
+func (m *MockSubscriptionRepository) SyncStripeSubscription(reqCtx *app.RequestContext, sync *model.SyncSubscription) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncStripeSubscription", reqCtx, sync)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncStripeSubscription indicates an expected call of SyncStripeSubscription.
+func (mr *MockSubscriptionRepositoryMockRecorder) SyncStripeSubscription(reqCtx, sync any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncStripeSubscription", reflect.TypeOf((*MockSubscriptionRepository)(nil).SyncStripeSubscription), reqCtx, sync)
+}
+
+// UpdateSubscription mocks base method.
+func (m *MockSubscriptionRepository) UpdateSubscription(reqCtx *app.RequestContext, subscriptionID bson.ObjectID, update *model.UpdateSubscription) (*model.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscription", reqCtx, subscriptionID, update)
+	ret0, _ := ret[0].(*model.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSubscription indicates an expected call of UpdateSubscription.
+func (mr *MockSubscriptionRepositoryMockRecorder) UpdateSubscription(reqCtx, subscriptionID, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscription", reflect.TypeOf((*MockSubscriptionRepository)(nil).UpdateSubscription), reqCtx, subscriptionID, update)
+}
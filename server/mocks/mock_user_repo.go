@@ -85,6 +85,20 @@ func (mr *MockUserRepoMockRecorder) DeleteUserByOrgIDs(reqCtx, orgIDs any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserByOrgIDs", reflect.TypeOf((*MockUserRepo)(nil).DeleteUserByOrgIDs), reqCtx, orgIDs)
 }
 
+// DeleteUsersByIdentityID mocks base method.
+func (m *MockUserRepo) DeleteUsersByIdentityID(reqCtx *app.RequestContext, identityID bson.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUsersByIdentityID", reqCtx, identityID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUsersByIdentityID indicates an expected call of DeleteUsersByIdentityID.
+func (mr *MockUserRepoMockRecorder) DeleteUsersByIdentityID(reqCtx, identityID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUsersByIdentityID", reflect.TypeOf((*MockUserRepo)(nil).DeleteUsersByIdentityID), reqCtx, identityID)
+}
+
 // GetCollection mocks base method.
 func (m *MockUserRepo) GetCollection(orgName ...string) *mongo.Collection {
 	m.ctrl.T.Helper()
@@ -118,6 +132,36 @@ func (mr *MockUserRepoMockRecorder) GetUserByID(reqCtx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserRepo)(nil).GetUserByID), reqCtx, id)
 }
 
+// GetUserByOrgAndEmail mocks base method.
+func (m *MockUserRepo) GetUserByOrgAndEmail(reqCtx *app.RequestContext, orgID bson.ObjectID, email string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByOrgAndEmail", reqCtx, orgID, email)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByOrgAndEmail indicates an expected call of GetUserByOrgAndEmail.
+func (mr *MockUserRepoMockRecorder) GetUserByOrgAndEmail(reqCtx, orgID, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByOrgAndEmail", reflect.TypeOf((*MockUserRepo)(nil).GetUserByOrgAndEmail), reqCtx, orgID, email)
+}
+
+// GetUsersByIdentityID mocks base method.
+func (m *MockUserRepo) GetUsersByIdentityID(reqCtx *app.RequestContext, identityID bson.ObjectID) ([]*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByIdentityID", reqCtx, identityID)
+	ret0, _ := ret[0].([]*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersByIdentityID indicates an expected call of GetUsersByIdentityID.
+func (mr *MockUserRepoMockRecorder) GetUsersByIdentityID(reqCtx, identityID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByIdentityID", reflect.TypeOf((*MockUserRepo)(nil).GetUsersByIdentityID), reqCtx, identityID)
+}
+
 // GetUsersByOrgID mocks base method.
 func (m *MockUserRepo) GetUsersByOrgID(reqCtx *app.RequestContext, orgID bson.ObjectID, pageReq *app.PageRequest) (*app.PageResponse[*model.User], error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type AuthTokenPurpose string
+
+const (
+	AuthTokenEmailVerification AuthTokenPurpose = "email_verification"
+	AuthTokenMagicLink         AuthTokenPurpose = "magic_link"
+	AuthTokenRefresh           AuthTokenPurpose = "refresh"
+)
+
+// AuthToken is a single-use token of the first-party identity provider. Only
+// the SHA-256 hash of the token is stored; for refresh tokens it is the hash
+// of the token's jti claim.
+type AuthToken struct {
+	Base       `json:",inline" bson:",inline"`
+	IdentityID bson.ObjectID    `json:"identity_id" bson:"identity_id"`
+	Email      string           `json:"email" bson:"email"`
+	Purpose    AuthTokenPurpose `json:"purpose" bson:"purpose"`
+	TokenHash  string           `json:"-" bson:"token_hash"`
+	ExpiresAt  time.Time        `json:"expires_at" bson:"expires_at"`
+	UsedAt     *time.Time       `json:"used_at,omitempty" bson:"used_at,omitempty"`
+}
+
+// TokenPair is returned on a successful first-party sign-in.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type PasswordSignUp struct {
+	Email       string `json:"email" binding:"required,email"`
+	Password    string `json:"password" binding:"required"`
+	FirstName   string `json:"first_name" binding:"required"`
+	LastName    string `json:"last_name" binding:"required"`
+	InviteToken string `json:"invite_token"`
+}
+
+type PasswordLogin struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type AuthTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -11,6 +11,9 @@ type Batch struct {
 	Base   `json:",inline" bson:",inline"`
 	Name   string      `json:"name" bson:"name"`
 	Status BatchStatus `json:"status" bson:"status"` // e.g., "Open", "Closed"
+	// LegalHold exempts the records scanned in the batch from the retention
+	// policies.
+	LegalHold bool `json:"legal_hold" bson:"legal_hold,omitempty"`
 }
 
 type CreateBatchRequest struct {
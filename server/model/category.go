@@ -17,10 +17,38 @@ type Field struct {
 	Options  []FieldOption `json:"options,omitempty" bson:"options,omitempty"`
 	Required bool          `json:"required" bson:"required"`
 	Unique   bool          `json:"unique" bson:"unique"`
+	// Sensitive values are encrypted at rest with the data key of the
+	// organization.
+	Sensitive bool `json:"sensitive" bson:"sensitive,omitempty"`
 
 	Children []Field `json:"children,omitempty" bson:"children,omitempty"`
 }
 
+// SensitiveFields returns the names of the fields whose values are encrypted
+// at rest. A field with a sensitive child, like a column of a table, is
+// encrypted as a whole.
+func (c *Category) SensitiveFields() []string {
+	var names []string
+	for _, field := range c.Fields {
+		if field.isSensitive() {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+func (f *Field) isSensitive() bool {
+	if f.Sensitive {
+		return true
+	}
+	for i := range f.Children {
+		if f.Children[i].isSensitive() {
+			return true
+		}
+	}
+	return false
+}
+
 type FieldOption struct {
 	Name             string   `json:"name" bson:"name"`
 	AlternativeNames []string `json:"alternative_name,omitempty" bson:"alternative_name,omitempty"`
@@ -1,26 +1,80 @@
 package model
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type CategoryData struct {
-	Base           `json:",inline" bson:",inline"`
-	FormatID       bson.ObjectID  `json:"format_id" bson:"format_id"`
-	CategoryID     bson.ObjectID  `json:"category_id" bson:"category_id"`
-	MetaData       map[string]any `json:"metadata,omitempty" bson:"metadata,omitempty"`
-	RawData        map[string]any `json:"rawdata,omitempty" bson:"rawdata,omitempty"`
-	DocumentPaths  []string       `json:"document_paths" bson:"document_paths"`
-	OrganizationID bson.ObjectID  `json:"org_id" bson:"org_id"`
+	Base       `json:",inline" bson:",inline"`
+	FormatID   bson.ObjectID  `json:"format_id" bson:"format_id"`
+	CategoryID bson.ObjectID  `json:"category_id" bson:"category_id"`
+	MetaData   map[string]any `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	RawData    map[string]any `json:"rawdata,omitempty" bson:"rawdata,omitempty"`
+	// DocumentPaths are the blob store keys of the uploaded documents.
+	DocumentPaths  []string      `json:"document_paths" bson:"document_paths"`
+	OrganizationID bson.ObjectID `json:"org_id" bson:"org_id"`
+	// LegalHold exempts the record from the retention policies.
+	LegalHold bool                `json:"legal_hold" bson:"legal_hold,omitempty"`
+	Retention *RetentionTombstone `json:"retention,omitempty" bson:"retention,omitempty"`
+	// PII tags the extracted fields that hold personal data, by name.
+	PII map[string]PIIField `json:"pii,omitempty" bson:"pii,omitempty"`
+}
+
+// RetentionStage is the part of the records that a retention policy expires:
+// the uploaded documents after DocumentDays, everything after DataDays.
+type RetentionStage string
+
+const (
+	RetentionStageDocuments RetentionStage = "documents"
+	RetentionStageData      RetentionStage = "data"
+)
+
+// RetentionTombstone records what the retention sweeper removed from a
+// record. A record whose data was purged keeps only its identifiers.
+type RetentionTombstone struct {
+	DocumentsPurgedAt *time.Time `json:"documents_purged_at,omitempty" bson:"documents_purged_at,omitempty"`
+	DataPurgedAt      *time.Time `json:"data_purged_at,omitempty" bson:"data_purged_at,omitempty"`
+	PurgedDocuments   int        `json:"purged_documents,omitempty" bson:"purged_documents,omitempty"`
+}
+
+// RetentionReport lists how many records of each category the retention
+// policies expire. A dry run only counts them.
+type RetentionReport struct {
+	OrganizationID bson.ObjectID             `json:"org_id"`
+	DryRun         bool                      `json:"dry_run"`
+	GeneratedAt    time.Time                 `json:"generated_at"`
+	HeldBatches    int                       `json:"held_batches"`
+	Categories     []RetentionCategoryReport `json:"categories"`
+}
+
+type RetentionCategoryReport struct {
+	CategorySlug     string          `json:"category_slug"`
+	Policy           RetentionPolicy `json:"policy"`
+	DocumentsExpired int64           `json:"documents_expired"`
+	DataExpired      int64           `json:"data_expired"`
+	Failed           int64           `json:"failed,omitempty"`
+}
+
+// LegalHoldRequest places or lifts a legal hold.
+type LegalHoldRequest struct {
+	LegalHold bool `json:"legal_hold"`
 }
 
 type CreateCategoryDataRequest struct {
-	CategoryID    bson.ObjectID  `json:"category_id" bson:"category_id"`
-	MetaData      map[string]any `json:"metadata,omitempty" bson:"metadata,omitempty"`
-	RawData       map[string]any `json:"rawdata,omitempty" bson:"rawdata,omitempty"`
-	DocumentPaths []string       `json:"document_paths" bson:"document_paths"`
+	CategoryID    bson.ObjectID       `json:"category_id" bson:"category_id"`
+	MetaData      map[string]any      `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	RawData       map[string]any      `json:"rawdata,omitempty" bson:"rawdata,omitempty"`
+	DocumentPaths []string            `json:"document_paths" bson:"document_paths"`
+	PII           map[string]PIIField `json:"pii,omitempty" bson:"pii,omitempty"`
+	// SensitiveFields are the fields of the category that are encrypted at
+	// rest.
+	SensitiveFields []string `json:"-" bson:"-"`
 }
 
 type UpdateCategoryDataRequest struct {
-	MetaData map[string]any `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	MetaData        map[string]any      `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	PII             map[string]PIIField `json:"pii,omitempty" bson:"pii,omitempty"`
+	SensitiveFields []string            `json:"-" bson:"-"`
 }
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type DataExportStatus string
+
+const (
+	DataExportPending DataExportStatus = "pending"
+	DataExportRunning DataExportStatus = "running"
+	DataExportReady   DataExportStatus = "ready"
+	DataExportFailed  DataExportStatus = "failed"
+	DataExportExpired DataExportStatus = "expired"
+)
+
+// DataExport is a request to export all the data of an organization. The
+// archive is generated in the background and can be downloaded with a signed
+// link until ExpiresAt, when it is removed.
+type DataExport struct {
+	Base           `json:",inline" bson:",inline"`
+	OrganizationID bson.ObjectID    `json:"org_id" bson:"org_id"`
+	Status         DataExportStatus `json:"status" bson:"status"`
+	Attempts       int              `json:"-" bson:"attempts"`
+	LeaseUntil     time.Time        `json:"-" bson:"lease_until,omitempty"`
+	FileName       string           `json:"file_name,omitempty" bson:"file_name,omitempty"`
+	Size           int64            `json:"size,omitempty" bson:"size,omitempty"`
+	SHA256         string           `json:"sha256,omitempty" bson:"sha256,omitempty"`
+	LastError      string           `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	CompletedAt    *time.Time       `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	ExpiresAt      *time.Time       `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
+
+// DataExportManifest lists the files of a data export archive with their
+// SHA-256 checksums. It is written to manifest.json in the archive.
+type DataExportManifest struct {
+	Version          int               `json:"version"`
+	ExportID         bson.ObjectID     `json:"export_id"`
+	OrganizationID   bson.ObjectID     `json:"org_id"`
+	OrganizationSlug string            `json:"org_slug"`
+	RequestedBy      bson.ObjectID     `json:"requested_by"`
+	GeneratedAt      time.Time         `json:"generated_at"`
+	Files            []DataExportEntry `json:"files"`
+}
+
+// DataExportEntry is a file of a data export archive. Records is the number
+// of documents of NDJSON files.
+type DataExportEntry struct {
+	Path    string `json:"path"`
+	Records int    `json:"records,omitempty"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+}
+
+// DataExportLink is a signed link to download a data export archive without
+// further authentication.
+type DataExportLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
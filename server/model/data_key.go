@@ -0,0 +1,22 @@
+package model
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// DataKey is a version of the data key that the documents and the sensitive
+// values of an organization are encrypted with. It is stored wrapped by the
+// master key of MasterKeyID and only unwrapped in memory.
+type DataKey struct {
+	Base           `json:",inline" bson:",inline"`
+	OrganizationID bson.ObjectID `json:"org_id" bson:"org_id"`
+	Version        int           `json:"version" bson:"version"`
+	MasterKeyID    string        `json:"master_key_id" bson:"master_key_id"`
+	WrappedKey     []byte        `json:"-" bson:"wrapped_key"`
+}
+
+// DataKeyRewrapReport tells how many data keys were rewrapped with the
+// primary master key.
+type DataKeyRewrapReport struct {
+	MasterKeyID string `json:"master_key_id"`
+	Rewrapped   int    `json:"rewrapped"`
+	Failed      int    `json:"failed"`
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type DeletionSubject string
+
+const (
+	DeletionSubjectOrganization DeletionSubject = "organization"
+	DeletionSubjectIdentity     DeletionSubject = "identity"
+)
+
+type DeletionStepStatus string
+
+const (
+	DeletionStepDone    DeletionStepStatus = "done"
+	DeletionStepSkipped DeletionStepStatus = "skipped"
+	DeletionStepFailed  DeletionStepStatus = "failed"
+)
+
+// DeletionStep is one part of purging the data of a deleted organization or
+// identity.
+type DeletionStep struct {
+	Name   string             `json:"name" bson:"name"`
+	Status DeletionStepStatus `json:"status" bson:"status"`
+	Detail string             `json:"detail,omitempty" bson:"detail,omitempty"`
+}
+
+// DeletionCertificate records that the data of an organization or identity
+// has been purged. It holds no personal data: the email of a purged identity
+// is kept only as a SHA-256 hash, so that a deletion request can be matched
+// to its certificate later.
+type DeletionCertificate struct {
+	Base        `json:",inline" bson:",inline"`
+	Subject     DeletionSubject `json:"subject" bson:"subject"`
+	SubjectID   bson.ObjectID   `json:"subject_id" bson:"subject_id"`
+	Slug        string          `json:"slug,omitempty" bson:"slug,omitempty"`
+	EmailHash   string          `json:"email_hash,omitempty" bson:"email_hash,omitempty"`
+	RequestedAt time.Time       `json:"requested_at" bson:"requested_at"`
+	RequestedBy bson.ObjectID   `json:"requested_by" bson:"requested_by"`
+	PurgedAt    time.Time       `json:"purged_at" bson:"purged_at"`
+	Steps       []DeletionStep  `json:"steps" bson:"steps"`
+}
+
+// ScheduledDeletion tells the user when the data of a deleted account or
+// organization will be purged.
+type ScheduledDeletion struct {
+	OrganizationIDs []bson.ObjectID `json:"organization_ids"`
+	IdentityID      bson.ObjectID   `json:"identity_id,omitzero"`
+	PurgeAfter      time.Time       `json:"purge_after"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+type ExtractionCacheEntry struct {
+	Base          `json:",inline" bson:",inline"`
+	Key           string    `json:"key" bson:"key"`
+	DocumentHash  string    `json:"document_hash" bson:"document_hash"`
+	FormatID      string    `json:"format_id" bson:"format_id"`
+	PromptVersion string    `json:"prompt_version" bson:"prompt_version"`
+	Model         string    `json:"model" bson:"model"`
+	Response      string    `json:"response" bson:"response"`
+	HitCount      int       `json:"hit_count" bson:"hit_count"`
+	LastHitAt     time.Time `json:"last_hit_at,omitzero" bson:"last_hit_at,omitempty"`
+}
+
+type CreateExtractionCacheEntry struct {
+	Key           string
+	DocumentHash  string
+	FormatID      string
+	PromptVersion string
+	Model         string
+	Response      string
+}
@@ -11,6 +11,10 @@ type Identity struct {
 	LastName     string        `json:"last_name" bson:"last_name"`
 	IsActive     bool          `json:"is_active" bson:"is_active"`
 	CurrentOrgID bson.ObjectID `json:"current_org_id" bson:"current_org_id"`
+	// PasswordHash is the argon2id hash of identities that sign in with a
+	// password. It is empty for identities that only use an external provider.
+	PasswordHash  string `json:"-" bson:"password_hash,omitempty"`
+	EmailVerified bool   `json:"email_verified" bson:"email_verified"`
 }
 
 type CreateIdentity struct {
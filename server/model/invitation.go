@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+// Invitation lets the holder of the token join the organization with the given
+// role. Only the SHA-256 hash of the token is stored.
+type Invitation struct {
+	Base           `json:",inline" bson:",inline"`
+	OrganizationID bson.ObjectID    `json:"org_id" bson:"org_id"`
+	Email          string           `json:"email" bson:"email"`
+	Role           UserRole         `json:"role" bson:"role"`
+	TokenHash      string           `json:"-" bson:"token_hash"`
+	Status         InvitationStatus `json:"status" bson:"status"`
+	ExpiresAt      time.Time        `json:"expires_at" bson:"expires_at"`
+	AcceptedAt     *time.Time       `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+	AcceptedBy     bson.ObjectID    `json:"accepted_by,omitzero" bson:"accepted_by,omitempty"`
+}
+
+type CreateInvitation struct {
+	Email string   `json:"email" binding:"required,email"`
+	Role  UserRole `json:"role" binding:"required"`
+}
+
+// CreatedInvitation is returned once when the invitation is created; the
+// token cannot be read back afterwards.
+type CreatedInvitation struct {
+	Invitation *Invitation `json:"invitation"`
+	Token      string      `json:"token"`
+}
+
+type AcceptInvitation struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UpdateUserRole struct {
+	Role UserRole `json:"role" binding:"required"`
+}
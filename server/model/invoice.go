@@ -26,3 +26,16 @@ const (
 	InvoiceUnpaid InvoiceStatus = "unpaid"
 	InvoiceVoid   InvoiceStatus = "void"
 )
+
+// SyncInvoice carries a Stripe invoice into the local invoices collection.
+type SyncInvoice struct {
+	OrganizationID     bson.ObjectID
+	SubscriptionID     bson.ObjectID
+	StripeInvoiceID    string
+	InvoiceNumber      string
+	TotalAmount        float64
+	BillingPeriodStart time.Time
+	BillingPeriodEnd   time.Time
+	PDF_URL            string
+	Status             string
+}
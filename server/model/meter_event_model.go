@@ -1,13 +1,57 @@
 package model
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+type MeterEventStatus string
+
+const (
+	MeterEventPending MeterEventStatus = "pending"
+	MeterEventSent    MeterEventStatus = "sent"
+	MeterEventFailed  MeterEventStatus = "failed"
+)
+
+// MeterEvent is a usage record waiting in the outbox until it is delivered to
+// Stripe. Identifier is sent along with the event so that Stripe drops
+// duplicate deliveries of the same record.
 type MeterEvent struct {
 	Base             `json:",inline" bson:",inline"`
-	EventName        string        `json:"event_name" bson:"event_name"`
-	EventValue       int           `json:"event_value" bson:"event_value"`
-	StripeCustomerID string        `json:"stripe_customer_id" bson:"stripe_customer_id"`
-	OrganizationID   bson.ObjectID `json:"org_id" bson:"org_id"`
+	EventName        string           `json:"event_name" bson:"event_name"`
+	EventValue       int              `json:"event_value" bson:"event_value"`
+	StripeCustomerID string           `json:"stripe_customer_id" bson:"stripe_customer_id"`
+	OrganizationID   bson.ObjectID    `json:"org_id" bson:"org_id"`
+	Identifier       string           `json:"identifier" bson:"identifier"`
+	Timestamp        time.Time        `json:"timestamp" bson:"timestamp"`
+	Status           MeterEventStatus `json:"status" bson:"status"`
+	Attempts         int              `json:"attempts" bson:"attempts"`
+	NextAttemptAt    time.Time        `json:"next_attempt_at" bson:"next_attempt_at"`
+	LastError        string           `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	SentAt           *time.Time       `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+}
+
+// MeterEventTotal is the summed value of an organization's meter events with
+// the same name and status.
+type MeterEventTotal struct {
+	EventName string           `json:"event_name" bson:"event_name"`
+	Status    MeterEventStatus `json:"status" bson:"status"`
+	Value     int64            `json:"value" bson:"value"`
+}
+
+// MeterReconciliation compares the usage recorded locally for one meter with
+// the usage Stripe aggregated over the same period.
+type MeterReconciliation struct {
+	OrganizationID   bson.ObjectID `json:"org_id"`
+	StripeCustomerID string        `json:"stripe_customer_id"`
+	EventName        string        `json:"event_name"`
+	StartTime        time.Time     `json:"start_time"`
+	EndTime          time.Time     `json:"end_time"`
+	Sent             int64         `json:"sent"`
+	Pending          int64         `json:"pending"`
+	Failed           int64         `json:"failed"`
+	StripeTotal      float64       `json:"stripe_total"`
+	Difference       float64       `json:"difference"`
+	InSync           bool          `json:"in_sync"`
 }
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"strings"
+)
+
+const (
+	// OrgDatabasePrefix is prepended to the slug to name the database of the
+	// organization.
+	OrgDatabasePrefix = "sc_"
+	// MaxSlugLength keeps the database name of an organization well within
+	// the limit of MongoDB, leaving room for a collision suffix.
+	MaxSlugLength = 40
+	// maxDatabaseNameLength is the longest database name MongoDB accepts.
+	maxDatabaseNameLength = 63
+)
+
+// SlugFromName derives a readable slug from the name: lower case letters and
+// digits separated by single hyphens. It returns "org" when nothing is left.
+func SlugFromName(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		default:
+			hyphen = true
+		}
+		if b.Len() >= MaxSlugLength {
+			break
+		}
+	}
+	slug := strings.TrimRight(b.String(), "-")
+	if len(slug) > MaxSlugLength {
+		slug = strings.TrimRight(slug[:MaxSlugLength], "-")
+	}
+	if slug == "" {
+		return "org"
+	}
+	return slug
+}
+
+// IsValidSlug reports whether the slug can name the database of an
+// organization. Slugs of organizations created before SlugFromName may
+// contain underscores.
+func IsValidSlug(slug string) bool {
+	if slug == "" || len(OrgDatabasePrefix+slug) > maxDatabaseNameLength {
+		return false
+	}
+	for _, r := range slug {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -16,7 +18,81 @@ type Organization struct {
 	LastActiveAt     time.Time     `json:"last_active_at" bson:"last_active_at"`
 	StripeCustomerId string        `json:"stripe_customer_id" bson:"stripe_customer_id"`
 	Billing          Billing       `json:"billing" bson:"billing"`
+
+	ExtractionCacheDisabled bool `json:"extraction_cache_disabled" bson:"extraction_cache_disabled"`
+	// MicrosoftTenantIDs restricts Microsoft sign-in to accounts of these
+	// tenants. Accounts of any tenant are accepted when it is empty.
+	MicrosoftTenantIDs []string   `json:"microsoft_tenant_ids" bson:"microsoft_tenant_ids,omitempty"`
+	SSO                *SSOConfig `json:"sso,omitempty" bson:"sso,omitempty"`
+
+	Settings OrganizationSettings `json:"settings" bson:"settings"`
+}
+
+// AllowsMicrosoftTenant reports whether Microsoft accounts of the tenant may
+// sign in to the organization.
+func (o *Organization) AllowsMicrosoftTenant(tenantID string) bool {
+	if len(o.MicrosoftTenantIDs) == 0 {
+		return true
+	}
+	for _, id := range o.MicrosoftTenantIDs {
+		if strings.EqualFold(id, tenantID) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultScanCodePrefix is the prefix of scan codes when the organization has
+// not configured one.
+const DefaultScanCodePrefix = "SCAN"
+
+// OrganizationSettings are the profile settings the organization admins can
+// change through the API.
+type OrganizationSettings struct {
+	LogoURL           string          `json:"logo_url" bson:"logo_url,omitempty"`
+	DefaultCategoryID bson.ObjectID   `json:"default_category_id,omitzero" bson:"default_category_id,omitempty"`
+	Timezone          string          `json:"timezone" bson:"timezone,omitempty"`
+	Locale            string          `json:"locale" bson:"locale,omitempty"`
+	Retention         RetentionPolicy `json:"retention" bson:"retention"`
+	ScanCodePrefix    string          `json:"scan_code_prefix" bson:"scan_code_prefix,omitempty"`
+	// CategoryRetention overrides Retention for the data of some categories.
+	CategoryRetention []CategoryRetentionPolicy `json:"category_retention" bson:"category_retention,omitempty"`
+}
+
+// RetentionPolicy is how long uploaded documents and extracted data are kept.
+// Zero keeps them forever.
+type RetentionPolicy struct {
+	DocumentDays int `json:"document_days" bson:"document_days,omitempty"`
+	DataDays     int `json:"data_days" bson:"data_days,omitempty"`
+}
+
+// CategoryRetentionPolicy is the retention policy of the data extracted for
+// one category.
+type CategoryRetentionPolicy struct {
+	CategoryID      bson.ObjectID `json:"category_id" bson:"category_id"`
+	RetentionPolicy `json:",inline" bson:",inline"`
 }
+
+// RetentionFor returns the retention policy of the data of the category: its
+// own policy when it has one, the organization's otherwise.
+func (s OrganizationSettings) RetentionFor(categoryID bson.ObjectID) RetentionPolicy {
+	for _, policy := range s.CategoryRetention {
+		if policy.CategoryID == categoryID {
+			return policy.RetentionPolicy
+		}
+	}
+	return s.Retention
+}
+
+// ScanCode formats the scan counter of the organization as a scan code.
+func (s OrganizationSettings) ScanCode(counter int) string {
+	prefix := s.ScanCodePrefix
+	if prefix == "" {
+		prefix = DefaultScanCodePrefix
+	}
+	return fmt.Sprintf("%s-%d", prefix, counter)
+}
+
 type Billing struct {
 	FullName      string `json:"full_name" bson:"full_name"`
 	Email         string `json:"email" bson:"email"`
@@ -34,6 +110,25 @@ type CreateOrganization struct {
 	Slug string        `json:"slug" bson:"slug"`
 }
 
+// UpdateOrganizationProfile changes the profile of the organization. Nil
+// fields are left unchanged. The billing details are not part of it: they
+// are only set when billing is set up.
+type UpdateOrganizationProfile struct {
+	Name              *string          `json:"name"`
+	LogoURL           *string          `json:"logo_url"`
+	DefaultCategoryID *string          `json:"default_category_id"`
+	Timezone          *string          `json:"timezone"`
+	Locale            *string          `json:"locale"`
+	Retention         *RetentionPolicy `json:"retention"`
+	ScanCodePrefix    *string          `json:"scan_code_prefix"`
+
+	CategoryRetention *[]CategoryRetentionPolicy `json:"category_retention"`
+}
+
+type MicrosoftTenantsSetting struct {
+	TenantIDs []string `json:"tenant_ids"`
+}
+
 type UpdateOrganization struct {
 	OwnerID          bson.ObjectID `json:"owner_id" bson:"owner_id,omitempty"`
 	StripeCustomerId string        `json:"stripe_customer_id" bson:"stripe_customer_id,omitempty"`
@@ -0,0 +1,29 @@
+package model
+
+// PIIType is a kind of personal data found in an extracted value.
+type PIIType string
+
+const (
+	PIIEmail      PIIType = "email"
+	PIIPhone      PIIType = "phone"
+	PIICreditCard PIIType = "credit_card"
+	PIIIBAN       PIIType = "iban"
+	PIIUSSSN      PIIType = "us_ssn"
+	PIIAadhaar    PIIType = "aadhaar"
+	PIIIndianPAN  PIIType = "in_pan"
+	PIIUKNINO     PIIType = "uk_nino"
+	// PIIOther is personal data found by the language model that matches none
+	// of the patterns, like a name or a bank account number.
+	PIIOther PIIType = "other"
+)
+
+// PIIField tags an extracted field that holds personal data. Its values are
+// masked for the users that may not read personal data and encrypted at
+// rest.
+type PIIField struct {
+	Types []PIIType `json:"types" bson:"types"`
+	// Box is where the value is on the document, as the fractions of its
+	// width and height [x_min, y_min, x_max, y_max]. The redacted rendering
+	// of the document blacks it out.
+	Box []float64 `json:"box,omitempty" bson:"box,omitempty"`
+}
@@ -0,0 +1,90 @@
+package model
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+type PlanCode string
+
+const (
+	PlanCodeFreeTrial PlanCode = "free_trial"
+	PlanCodeMonthly   PlanCode = "monthly"
+	PlanCodeYearly    PlanCode = "yearly"
+)
+
+// Plan is an entry of the billing plan catalog. MonthlyScanLimit is the number
+// of scans included each month; when OverageMeterEvent is set, scans beyond it
+// are reported to that Stripe meter instead of being rejected. MaxUploadBytes
+// and MaxUploadPages limit the documents uploaded; zero means the default
+// limits.
+type Plan struct {
+	Base              `json:",inline" bson:",inline"`
+	Code              PlanCode     `json:"code" bson:"code"`
+	Name              string       `json:"name" bson:"name"`
+	BillingCycle      BillingCycle `json:"billing_cycle" bson:"billing_cycle"`
+	StripePriceID     string       `json:"stripe_price_id" bson:"stripe_price_id"`
+	StripeCouponID    string       `json:"stripe_coupon_id" bson:"stripe_coupon_id"`
+	MonthlyScanLimit  int          `json:"monthly_scan_limit" bson:"monthly_scan_limit"`
+	OverageMeterEvent string       `json:"overage_meter_event" bson:"overage_meter_event"`
+	TrialDays         int          `json:"trial_days" bson:"trial_days"`
+	MaxUploadBytes    int64        `json:"max_upload_bytes" bson:"max_upload_bytes,omitempty"`
+	MaxUploadPages    int          `json:"max_upload_pages" bson:"max_upload_pages,omitempty"`
+	IsActive          bool         `json:"is_active" bson:"is_active"`
+}
+
+const (
+	DefaultMaxUploadBytes int64 = 10 << 20
+	DefaultMaxUploadPages       = 10
+)
+
+// UploadLimits are the limits of the documents an organization uploads.
+type UploadLimits struct {
+	MaxBytes int64 `json:"max_bytes"`
+	MaxPages int   `json:"max_pages"`
+}
+
+// UploadLimits returns the limits of the plan, falling back to the defaults.
+func (p *Plan) UploadLimits() UploadLimits {
+	limits := UploadLimits{MaxBytes: p.MaxUploadBytes, MaxPages: p.MaxUploadPages}
+	if limits.MaxBytes <= 0 {
+		limits.MaxBytes = DefaultMaxUploadBytes
+	}
+	if limits.MaxPages <= 0 {
+		limits.MaxPages = DefaultMaxUploadPages
+	}
+	return limits
+}
+
+type CreatePlan struct {
+	Code              PlanCode     `json:"code" binding:"required"`
+	Name              string       `json:"name" binding:"required"`
+	BillingCycle      BillingCycle `json:"billing_cycle" binding:"omitempty,oneof=monthly yearly"`
+	StripePriceID     string       `json:"stripe_price_id"`
+	StripeCouponID    string       `json:"stripe_coupon_id"`
+	MonthlyScanLimit  int          `json:"monthly_scan_limit" binding:"min=0"`
+	OverageMeterEvent string       `json:"overage_meter_event"`
+	TrialDays         int          `json:"trial_days" binding:"min=0"`
+	MaxUploadBytes    int64        `json:"max_upload_bytes" binding:"min=0"`
+	MaxUploadPages    int          `json:"max_upload_pages" binding:"min=0"`
+}
+
+// UpdatePlan changes only the fields that are set.
+type UpdatePlan struct {
+	Name              *string       `json:"name"`
+	BillingCycle      *BillingCycle `json:"billing_cycle" binding:"omitempty,oneof=monthly yearly"`
+	StripePriceID     *string       `json:"stripe_price_id"`
+	StripeCouponID    *string       `json:"stripe_coupon_id"`
+	MonthlyScanLimit  *int          `json:"monthly_scan_limit" binding:"omitempty,min=0"`
+	OverageMeterEvent *string       `json:"overage_meter_event"`
+	TrialDays         *int          `json:"trial_days" binding:"omitempty,min=0"`
+	MaxUploadBytes    *int64        `json:"max_upload_bytes" binding:"omitempty,min=0"`
+	MaxUploadPages    *int          `json:"max_upload_pages" binding:"omitempty,min=0"`
+	IsActive          *bool         `json:"is_active"`
+}
+
+type SubscribeRequest struct {
+	PlanID bson.ObjectID `json:"plan_id" binding:"required"`
+}
+
+type ChangePlanRequest struct {
+	PlanID            bson.ObjectID `json:"plan_id" binding:"required"`
+	ProrationBehavior string        `json:"proration_behavior" binding:"omitempty,oneof=create_prorations always_invoice none"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const UsageEventScan = "scan"
+
+// UsageEventTrialScan counts the scans of a free trial. Its counter is kept
+// for the whole trial under the month the trial started, so that a trial
+// running over two months keeps a single allowance.
+const UsageEventTrialScan = "trial_scan"
+
+// QuotaOverride replaces the plan scan limit of an organization until it expires.
+type QuotaOverride struct {
+	Base             `json:",inline" bson:",inline"`
+	OrganizationID   bson.ObjectID `json:"organization_id" bson:"organization_id"`
+	MonthlyScanLimit int           `json:"monthly_scan_limit" bson:"monthly_scan_limit"`
+	Reason           string        `json:"reason" bson:"reason"`
+	ExpiresAt        time.Time     `json:"expires_at,omitzero" bson:"expires_at,omitempty"`
+}
+
+type CreateQuotaOverride struct {
+	MonthlyScanLimit int       `json:"monthly_scan_limit" binding:"min=0"`
+	Reason           string    `json:"reason" binding:"required"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// QuotaStatus is the scan allowance of an organization for the period of
+// Year and Month, or for the whole free trial when TrialStartedAt is set.
+type QuotaStatus struct {
+	PlanCode       PlanCode  `json:"plan_code"`
+	Year           int       `json:"year"`
+	Month          int       `json:"month"`
+	Limit          int       `json:"limit"`
+	Used           int       `json:"used"`
+	Remaining      int       `json:"remaining"`
+	OverageAllowed bool      `json:"overage_allowed"`
+	HasOverride    bool      `json:"has_override"`
+	TrialStartedAt time.Time `json:"trial_started_at,omitzero"`
+	TrialEndsAt    time.Time `json:"trial_ends_at,omitzero"`
+	TrialExpired   bool      `json:"trial_expired"`
+}
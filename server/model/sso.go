@@ -0,0 +1,119 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// SSOConfig configures sign-in through the organization's own OpenID Connect
+// provider, e.g. Okta, Keycloak or ADFS. ID tokens are routed to the
+// organization by their issuer and audience.
+type SSOConfig struct {
+	Enabled  bool   `json:"enabled" bson:"enabled"`
+	Issuer   string `json:"issuer" bson:"issuer"`
+	ClientID string `json:"client_id" bson:"client_id"`
+	// JWKSURL overrides the signing keys URL found through OpenID discovery.
+	JWKSURL string `json:"jwks_url,omitempty" bson:"jwks_url,omitempty"`
+	// AllowedDomains restricts sign-in to emails of these domains. It must
+	// not be empty: no one may sign in until it is set.
+	AllowedDomains []string `json:"allowed_domains" bson:"allowed_domains"`
+	// Domains tracks the proof that the organization owns each of the
+	// AllowedDomains. It is kept by the server: a domain only lets its emails
+	// sign in once verified.
+	Domains []SSODomain `json:"domains" bson:"domains"`
+	// RoleClaim names the token claim, e.g. "groups", whose values are mapped
+	// to roles with RoleMapping.
+	RoleClaim   string              `json:"role_claim,omitempty" bson:"role_claim,omitempty"`
+	RoleMapping map[string]UserRole `json:"role_mapping,omitempty" bson:"role_mapping,omitempty"`
+	DefaultRole UserRole            `json:"default_role" bson:"default_role"`
+}
+
+// SSODomainTXTPrefix is prepended to a domain to name the DNS TXT record
+// that proves the organization owns it.
+const SSODomainTXTPrefix = "_exto-sso."
+
+// SSODomain is a domain of the SSO of an organization. The organization
+// proves it owns the domain by publishing the TXT record
+// "exto-sso-verification=<VerificationToken>" at SSODomainTXTPrefix+Domain.
+type SSODomain struct {
+	Domain            string    `json:"domain" bson:"domain"`
+	VerificationToken string    `json:"verification_token" bson:"verification_token"`
+	Verified          bool      `json:"verified" bson:"verified"`
+	VerifiedAt        time.Time `json:"verified_at,omitzero" bson:"verified_at,omitempty"`
+}
+
+// TXTRecord returns the content of the TXT record that verifies the domain.
+func (d *SSODomain) TXTRecord() string {
+	return "exto-sso-verification=" + d.VerificationToken
+}
+
+// ssoRoleRank orders the roles that SSO can grant, so a user matching several
+// mapped values gets the most privileged one.
+var ssoRoleRank = map[UserRole]int{
+	RoleGuest:             1,
+	RoleMember:            2,
+	RoleBillingAdmin:      3,
+	RoleOrganizationAdmin: 4,
+}
+
+// IsAssignableBySSO reports whether SSO may grant the role. Super admins are
+// never provisioned from an external provider.
+func (r UserRole) IsAssignableBySSO() bool {
+	_, ok := ssoRoleRank[r]
+	return ok
+}
+
+// AllowsEmail reports whether the email's domain may sign in: it must be
+// allowed and verified as owned by the organization.
+func (c *SSOConfig) AllowsEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range c.AllowedDomains {
+		if strings.EqualFold(allowed, domain) {
+			d := c.Domain(allowed)
+			return d != nil && d.Verified
+		}
+	}
+	return false
+}
+
+// Domain returns the ownership proof of the domain, or nil.
+func (c *SSOConfig) Domain(domain string) *SSODomain {
+	for i := range c.Domains {
+		if strings.EqualFold(c.Domains[i].Domain, domain) {
+			return &c.Domains[i]
+		}
+	}
+	return nil
+}
+
+// RoleFor maps the role claim of a token to a role. The claim may be a single
+// string or a list of strings.
+func (c *SSOConfig) RoleFor(claims map[string]any) UserRole {
+	role := c.DefaultRole
+	if c.RoleClaim == "" {
+		return role
+	}
+
+	var values []string
+	switch v := claims[c.RoleClaim].(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	for _, value := range values {
+		mapped, ok := c.RoleMapping[value]
+		if ok && ssoRoleRank[mapped] > ssoRoleRank[role] {
+			role = mapped
+		}
+	}
+	return role
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// StripeEvent records a processed Stripe webhook event so redeliveries are ignored.
+type StripeEvent struct {
+	Base        `json:",inline" bson:",inline"`
+	EventID     string    `json:"event_id" bson:"event_id"`
+	Type        string    `json:"type" bson:"type"`
+	ProcessedAt time.Time `json:"processed_at" bson:"processed_at"`
+}
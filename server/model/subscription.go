@@ -34,6 +34,27 @@ type Subscription struct {
 	BillingCycle    BillingCycle       `json:"billing_cycle" bson:"billing_cycle"` // e.g., "monthly", "yearly"
 	Status          SubscriptionStatus `json:"status" bson:"status"`               // e.g., "active", "trialing", "canceled", "past_due", "unpaid", "canceled", "incomplete"
 	IsCurrent       bool               `json:"is_current" bson:"is_current"`
+
+	CancelAtPeriodEnd bool          `json:"cancel_at_period_end" bson:"cancel_at_period_end"`
+	BillingPlanID     bson.ObjectID `json:"billing_plan_id,omitzero" bson:"billing_plan_id,omitempty"`
+
+	// DefaultPaymentMethod is the payment method Stripe charges the
+	// subscription to, when it has one of its own.
+	DefaultPaymentMethod *PaymentMethod `json:"default_payment_method,omitempty" bson:"default_payment_method,omitempty"`
+	// StripeEventAt is the creation time of the last Stripe event applied to
+	// the subscription. Older events are not applied.
+	StripeEventAt time.Time `json:"-" bson:"stripe_event_at,omitempty"`
+}
+
+// PaymentMethod is the Stripe payment method of a subscription, with the
+// card details shown to the user.
+type PaymentMethod struct {
+	StripeID string `json:"stripe_id" bson:"stripe_id"`
+	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Brand    string `json:"brand,omitempty" bson:"brand,omitempty"`
+	Last4    string `json:"last4,omitempty" bson:"last4,omitempty"`
+	ExpMonth int64  `json:"exp_month,omitempty" bson:"exp_month,omitempty"`
+	ExpYear  int64  `json:"exp_year,omitempty" bson:"exp_year,omitempty"`
 }
 
 type CreateSubscription struct {
@@ -43,7 +64,7 @@ type CreateSubscription struct {
 	EndDate         time.Time     `json:"ended_at" bson:"ended_at"`
 	TrialPeriodDays int           `json:"trial_period_days" bson:"trial_period_days"`
 	BillingCycle    BillingCycle  `json:"billing_cycle" bson:"billing_cycle"` // e.g., "monthly", "yearly"
-
+	BillingPlanID   bson.ObjectID `json:"billing_plan_id" bson:"billing_plan_id"`
 }
 
 type UpdateSubscription struct {
@@ -52,6 +73,24 @@ type UpdateSubscription struct {
 	Status        SubscriptionStatus `json:"status" bson:"status"`
 }
 
+// SyncSubscription carries the Stripe side of a subscription into the local
+// record. EventCreatedAt is the creation time of the Stripe event it comes
+// from, zero when it was read from the Stripe API.
+type SyncSubscription struct {
+	OrganizationID    bson.ObjectID
+	StripeSubID       string
+	StartDate         time.Time
+	EndDate           time.Time
+	BillingCycle      BillingCycle
+	Status            SubscriptionStatus
+	CancelAtPeriodEnd bool
+	IsCurrent         bool
+	BillingPlanID     bson.ObjectID
+
+	DefaultPaymentMethodID string
+	EventCreatedAt         time.Time
+}
+
 type GetFreeTrialInfo struct {
 	RecordCount    int `json:"record_count" bson:"record_count"`
 	DaysLeft       int `json:"days_left" bson:"days_left"`
@@ -13,8 +13,21 @@ const (
 	RoleOrganizationAdmin UserRole = "organization_admin"
 	RoleMember            UserRole = "member"
 	RoleGuest             UserRole = "guest"
+
+	// RoleService is the role of requests authenticated with an API key. It
+	// grants nothing by itself; the key's scopes decide what is allowed.
+	RoleService UserRole = "service"
 )
 
+// IsValid reports whether the role is one of the known roles.
+func (r UserRole) IsValid() bool {
+	switch r {
+	case RoleSuperAdmin, RoleBillingAdmin, RoleOrganizationAdmin, RoleMember, RoleGuest:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	Base           `json:",inline" bson:",inline"`
 	IdentityID     bson.ObjectID `json:"identity_id" bson:"identity_id"`
@@ -41,3 +54,12 @@ type UpdateUser struct {
 	Role      UserRole `json:"role" bson:"role"`
 	IsActive  bool     `json:"is_active" bson:"is_active"`
 }
+
+// OrganizationMembership is an organization the signed in identity belongs to.
+type OrganizationMembership struct {
+	OrganizationID bson.ObjectID `json:"org_id"`
+	Name           string        `json:"name"`
+	Slug           string        `json:"slug"`
+	Role           UserRole      `json:"role"`
+	IsCurrent      bool          `json:"is_current"`
+}
@@ -0,0 +1,227 @@
+// Package pii finds personal data in the values extracted from documents and
+// masks it. Values are matched against the formats of common identifiers,
+// and the ones with a check digit are validated, so that invoice numbers and
+// amounts are not mistaken for them.
+package pii
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type detector struct {
+	piiType model.PIIType
+	pattern *regexp.Regexp
+	valid   func(match string) bool
+}
+
+// detectors are tried in order. A match claims its characters, so that the
+// digits of an email or of a card number are not also taken for a phone
+// number.
+var detectors = []detector{
+	{model.PIIEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), nil},
+	{model.PIIIBAN, regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`), validIBAN},
+	{model.PIICreditCard, regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), validCard},
+	{model.PIIUSSSN, regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), validSSN},
+	{model.PIIAadhaar, regexp.MustCompile(`\b[2-9]\d{3} ?\d{4} ?\d{4}\b`), validAadhaar},
+	{model.PIIIndianPAN, regexp.MustCompile(`\b[A-Z]{3}[ABCFGHLJPT][A-Z]\d{4}[A-Z]\b`), nil},
+	{model.PIIUKNINO, regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`), validNINO},
+	{model.PIIPhone, regexp.MustCompile(`\+?\(?\d[\d ().-]{7,}\d`), validPhone},
+}
+
+// DetectValue returns the kinds of personal data found in the text.
+func DetectValue(text string) []model.PIIType {
+	var found []model.PIIType
+	var claimed [][]int
+	for _, d := range detectors {
+		for _, loc := range d.pattern.FindAllStringIndex(text, -1) {
+			if overlaps(claimed, loc) || (d.valid != nil && !d.valid(text[loc[0]:loc[1]])) {
+				continue
+			}
+			claimed = append(claimed, loc)
+			if !slices.Contains(found, d.piiType) {
+				found = append(found, d.piiType)
+			}
+		}
+	}
+	return found
+}
+
+func overlaps(claimed [][]int, loc []int) bool {
+	for _, c := range claimed {
+		if loc[0] < c[1] && c[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectFields returns the kinds of personal data found in each of the
+// fields, looking into the text of their documents and arrays. Fields with
+// none are left out.
+func DetectFields(fields map[string]any) map[string][]model.PIIType {
+	found := map[string][]model.PIIType{}
+	for name, value := range fields {
+		var types []model.PIIType
+		walkStrings(value, func(text string) {
+			for _, t := range DetectValue(text) {
+				if !slices.Contains(types, t) {
+					types = append(types, t)
+				}
+			}
+		})
+		if len(types) > 0 {
+			found[name] = types
+		}
+	}
+	return found
+}
+
+// walkStrings calls fn with the strings of the value, at any depth.
+func walkStrings(value any, fn func(string)) {
+	switch v := value.(type) {
+	case string:
+		fn(v)
+	case map[string]any:
+		for _, item := range v {
+			walkStrings(item, fn)
+		}
+	case bson.M:
+		walkStrings(map[string]any(v), fn)
+	case bson.D:
+		for _, e := range v {
+			walkStrings(e.Value, fn)
+		}
+	case bson.A:
+		walkStrings([]any(v), fn)
+	case []any:
+		for _, item := range v {
+			walkStrings(item, fn)
+		}
+	}
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// validCard checks the length and the Luhn check digit of a card number.
+func validCard(match string) bool {
+	number := digits(match)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if (len(number)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validIBAN checks the mod 97 check digits of an IBAN.
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+	for _, r := range rearranged {
+		var value int
+		switch {
+		case r >= '0' && r <= '9':
+			value = int(r - '0')
+		case r >= 'A' && r <= 'Z':
+			value = int(r-'A') + 10
+		default:
+			return false
+		}
+		if value > 9 {
+			remainder = remainder * 100
+		} else {
+			remainder = remainder * 10
+		}
+		remainder = (remainder + value) % 97
+	}
+	return remainder == 1
+}
+
+// validSSN rejects the area, group and serial numbers that are never issued.
+func validSSN(match string) bool {
+	area, group, serial := match[0:3], match[4:6], match[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+var (
+	verhoeffMultiplication = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffPermutation = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+)
+
+// validAadhaar checks the Verhoeff check digit of an Aadhaar number.
+func validAadhaar(match string) bool {
+	number := digits(match)
+	check := 0
+	for i := 0; i < len(number); i++ {
+		d := int(number[len(number)-1-i] - '0')
+		check = verhoeffMultiplication[check][verhoeffPermutation[i%8][d]]
+	}
+	return check == 0
+}
+
+// validNINO rejects the prefixes of National Insurance numbers that are never
+// issued.
+func validNINO(match string) bool {
+	switch match[:2] {
+	case "BG", "GB", "KN", "NK", "NT", "TN", "ZZ":
+		return false
+	}
+	return true
+}
+
+// validPhone accepts 10 to 15 digits written like a phone number: with a
+// country code or with separators, so that a bare reference number is not
+// taken for one.
+func validPhone(match string) bool {
+	n := len(digits(match))
+	if n < 10 || n > 15 {
+		return false
+	}
+	return strings.HasPrefix(match, "+") || strings.ContainsAny(match, " ().-")
+}
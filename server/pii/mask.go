@@ -0,0 +1,122 @@
+package pii
+
+import (
+	"reflect"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+const (
+	maskPrefix = "****"
+	// maskVisibleMin is the number of letters and digits from which the last
+	// maskVisible ones of a value are left visible, so that it can still be
+	// told apart.
+	maskVisibleMin = 8
+	maskVisible    = 4
+)
+
+// Mask hides the value but its last four letters or digits, e.g. ****1234.
+// Values too short to keep any are hidden entirely.
+func Mask(value string) string {
+	var visible []rune
+	for _, r := range value {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			visible = append(visible, r)
+		}
+	}
+	if len(visible) < maskVisibleMin {
+		return maskPrefix
+	}
+	return maskPrefix + string(visible[len(visible)-maskVisible:])
+}
+
+// MaskValue returns a copy of the value with its strings masked and its other
+// scalars hidden, at any depth.
+func MaskValue(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return Mask(v)
+	case map[string]any:
+		masked := make(map[string]any, len(v))
+		for name, item := range v {
+			masked[name] = MaskValue(item)
+		}
+		return masked
+	case bson.M:
+		return bson.M(MaskValue(map[string]any(v)).(map[string]any))
+	case bson.D:
+		masked := make(bson.D, len(v))
+		for i, e := range v {
+			masked[i] = bson.E{Key: e.Key, Value: MaskValue(e.Value)}
+		}
+		return masked
+	case bson.A:
+		return bson.A(MaskValue([]any(v)).([]any))
+	case []any:
+		masked := make([]any, len(v))
+		for i, item := range v {
+			masked[i] = MaskValue(item)
+		}
+		return masked
+	default:
+		return maskPrefix
+	}
+}
+
+// MaskFields returns a copy of the fields with the values of the tagged ones
+// masked.
+func MaskFields(fields map[string]any, tags map[string]model.PIIField) map[string]any {
+	if fields == nil || len(tags) == 0 {
+		return fields
+	}
+	masked := make(map[string]any, len(fields))
+	for name, value := range fields {
+		if _, tagged := tags[name]; tagged {
+			value = MaskValue(value)
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+// IsMasked reports whether the value is the original one as MaskValue
+// renders it, which is how a client sends back a field it could not read.
+func IsMasked(original any, value any) bool {
+	return reflect.DeepEqual(normalize(MaskValue(original)), normalize(value))
+}
+
+// normalize turns the documents and arrays of the value into maps and slices,
+// as they differ whether the value was decoded from JSON or from BSON.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		normalized := make(map[string]any, len(v))
+		for name, item := range v {
+			normalized[name] = normalize(item)
+		}
+		return normalized
+	case bson.M:
+		return normalize(map[string]any(v))
+	case bson.D:
+		normalized := make(map[string]any, len(v))
+		for _, e := range v {
+			normalized[e.Key] = normalize(e.Value)
+		}
+		return normalized
+	case bson.A:
+		return normalize([]any(v))
+	case []any:
+		normalized := make([]any, len(v))
+		for i, item := range v {
+			normalized[i] = normalize(item)
+		}
+		return normalized
+	default:
+		return value
+	}
+}
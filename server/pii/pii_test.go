@@ -0,0 +1,79 @@
+package pii_test
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/pii"
+)
+
+func TestDetectValue(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want []model.PIIType
+	}{
+		{"jane.doe@example.com", []model.PIIType{model.PIIEmail}},
+		{"Card 4111 1111 1111 1111", []model.PIIType{model.PIICreditCard}},
+		{"Card 4111 1111 1111 1112", nil},
+		{"GB82 WEST 1234 5698 7654 32", []model.PIIType{model.PIIIBAN}},
+		{"GB82WEST12345698765433", nil},
+		{"SSN 123-45-6789", []model.PIIType{model.PIIUSSSN}},
+		{"666-45-6789", nil},
+		{"2341 2341 2346", []model.PIIType{model.PIIAadhaar}},
+		{"PAN ABCPE1234F", []model.PIIType{model.PIIIndianPAN}},
+		{"ABCXE1234F", nil},
+		{"AB 12 34 56 C", []model.PIIType{model.PIIUKNINO}},
+		{"GB 12 34 56 C", nil},
+		{"Call +1 415 555 2671 or (415) 555-2671", []model.PIIType{model.PIIPhone}},
+		{"Invoice 1234567890, total 1250.00, due 2025-01-31", nil},
+		{"jane@example.com, 4111-1111-1111-1111", []model.PIIType{model.PIIEmail, model.PIICreditCard}},
+	} {
+		got := pii.DetectValue(tc.text)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("DetectValue(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestDetectFieldsLooksIntoDocuments(t *testing.T) {
+	found := pii.DetectFields(map[string]any{
+		"total":    1250.0,
+		"customer": map[string]any{"name": "Jane Doe", "phone": "+44 20 7946 0958"},
+		"lines":    bson.A{bson.D{{Key: "note", Value: "ssn 123-45-6789"}}},
+	})
+	if len(found) != 2 || !slices.Equal(found["customer"], []model.PIIType{model.PIIPhone}) ||
+		!slices.Equal(found["lines"], []model.PIIType{model.PIIUSSSN}) {
+		t.Errorf("unexpected fields %v", found)
+	}
+}
+
+func TestMaskFields(t *testing.T) {
+	fields := map[string]any{
+		"card":     "4111111111111111",
+		"pin":      "1234",
+		"customer": map[string]any{"phone": "+44 20 7946 0958", "age": 42.0},
+		"total":    1250.0,
+	}
+	tags := map[string]model.PIIField{"card": {}, "pin": {}, "customer": {}}
+	masked := pii.MaskFields(fields, tags)
+
+	customer := masked["customer"].(map[string]any)
+	if masked["card"] != "****1111" || masked["pin"] != "****" || customer["phone"] != "****0958" ||
+		customer["age"] != "****" || masked["total"] != 1250.0 {
+		t.Errorf("unexpected masked fields %v", masked)
+	}
+	if fields["card"] != "4111111111111111" {
+		t.Error("expected the fields to be left unchanged")
+	}
+
+	stored := bson.D{{Key: "phone", Value: "+44 20 7946 0958"}, {Key: "age", Value: int32(42)}}
+	if !pii.IsMasked(stored, masked["customer"]) {
+		t.Error("expected the masked value sent back to be recognized")
+	}
+	if pii.IsMasked(stored, map[string]any{"phone": "+44 20 7946 0000", "age": "****"}) {
+		t.Error("expected an edited value not to be taken for the masked one")
+	}
+}
@@ -0,0 +1,163 @@
+//go:generate mockgen -source=api_key_repo.go -destination=../mocks/mock_api_key_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type APIKeyRepository interface {
+	IBaseRepo
+	EnsureIndexes() error
+	CreateAPIKey(reqCtx *app.RequestContext, key *model.APIKey) (*model.APIKey, error)
+	ListAPIKeys(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.APIKey, error)
+	RotateAPIKey(reqCtx *app.RequestContext, orgID bson.ObjectID, id bson.ObjectID, prefix string, secretHash string) (*model.APIKey, error)
+	RevokeAPIKey(reqCtx *app.RequestContext, orgID bson.ObjectID, id bson.ObjectID) (bool, error)
+	DeleteAPIKeysByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error
+}
+
+type MongoAPIKeyRepo struct {
+	BaseRepo
+}
+
+func NewAPIKeyRepository(appDB *db.AppDB) *MongoAPIKeyRepo {
+	return &MongoAPIKeyRepo{
+		BaseRepo: BaseRepo{
+			cname: "api_keys",
+			appDB: appDB,
+		},
+	}
+}
+
+func (r *MongoAPIKeyRepo) EnsureIndexes() error {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "prefix", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "org_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("failed to create api key indexes: %v", err)
+		return newDBError("failed to create api key indexes", err)
+	}
+	return nil
+}
+
+func (r *MongoAPIKeyRepo) CreateAPIKey(reqCtx *app.RequestContext, key *model.APIKey) (*model.APIKey, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	key.ID = bson.NewObjectID()
+	key.CreatedAt = now
+	key.UpdatedAt = now
+	key.CreatedBy = reqCtx.User.IdentityID
+	key.UpdatedBy = reqCtx.User.IdentityID
+	key.OrganizationID = reqCtx.Org.ID
+	if _, err := col.InsertOne(ctx, key); err != nil {
+		log.Printf("failed to create api key: %v", err)
+		return nil, newDBError("failed to create api key", err)
+	}
+	return key, nil
+}
+
+func (r *MongoAPIKeyRepo) ListAPIKeys(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.APIKey, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := col.Find(ctx, bson.M{"org_id": orgID}, opts)
+	if err != nil {
+		log.Printf("failed to list api keys: %v", err)
+		return nil, newDBError("failed to list api keys", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*model.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		log.Printf("failed to decode api keys: %v", err)
+		return nil, newDBError("failed to list api keys", err)
+	}
+	return keys, nil
+}
+
+// RotateAPIKey replaces the prefix and secret of an unrevoked key, which
+// invalidates the previous key at once. It returns nil when there is no such
+// key.
+func (r *MongoAPIKeyRepo) RotateAPIKey(reqCtx *app.RequestContext, orgID bson.ObjectID, id bson.ObjectID, prefix string, secretHash string) (*model.APIKey, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "org_id": orgID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set": bson.M{
+			"prefix":      prefix,
+			"secret_hash": secretHash,
+			"updated_at":  time.Now(),
+			"updated_by":  reqCtx.User.IdentityID,
+		},
+		"$unset": bson.M{"last_used_at": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var key model.APIKey
+	if err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&key); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to rotate api key: %v", err)
+		return nil, newDBError("failed to rotate api key", err)
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey revokes the key. It returns false when the organization has no
+// such unrevoked key.
+func (r *MongoAPIKeyRepo) RevokeAPIKey(reqCtx *app.RequestContext, orgID bson.ObjectID, id bson.ObjectID) (bool, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": id, "org_id": orgID, "revoked_at": bson.M{"$exists": false}}
+	result, err := col.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"revoked_at": now,
+		"updated_at": now,
+		"updated_by": reqCtx.User.IdentityID,
+	}})
+	if err != nil {
+		log.Printf("failed to revoke api key: %v", err)
+		return false, newDBError("failed to revoke api key", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *MongoAPIKeyRepo) DeleteAPIKeysByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteMany(ctx, bson.M{"org_id": orgID}); err != nil {
+		log.Printf("failed to delete api keys: %v", err)
+		return newDBError("failed to delete api keys", err)
+	}
+	return nil
+}
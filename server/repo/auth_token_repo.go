@@ -0,0 +1,143 @@
+//go:generate mockgen -source=auth_token_repo.go -destination=../mocks/mock_auth_token_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type AuthTokenRepository interface {
+	IBaseRepo
+	EnsureIndexes() error
+	CreateAuthToken(reqCtx *app.RequestContext, token *model.AuthToken) error
+	ConsumeAuthToken(reqCtx *app.RequestContext, purpose model.AuthTokenPurpose, tokenHash string) (*model.AuthToken, error)
+	RevokeAuthTokens(reqCtx *app.RequestContext, identityID bson.ObjectID, purposes []model.AuthTokenPurpose) error
+	DeleteAuthTokensByIdentity(reqCtx *app.RequestContext, identityID bson.ObjectID) error
+}
+
+type MongoAuthTokenRepo struct {
+	BaseRepo
+}
+
+func NewAuthTokenRepository(appDB *db.AppDB) *MongoAuthTokenRepo {
+	return &MongoAuthTokenRepo{
+		BaseRepo: BaseRepo{
+			cname: "auth_tokens",
+			appDB: appDB,
+		},
+	}
+}
+
+func (r *MongoAuthTokenRepo) EnsureIndexes() error {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Expired tokens are removed by MongoDB.
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("failed to create auth token indexes: %v", err)
+		return newDBError("failed to create auth token indexes", err)
+	}
+	return nil
+}
+
+func (r *MongoAuthTokenRepo) CreateAuthToken(reqCtx *app.RequestContext, token *model.AuthToken) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	token.ID = bson.NewObjectID()
+	token.CreatedAt = now
+	token.UpdatedAt = now
+	token.CreatedBy = token.IdentityID
+	token.UpdatedBy = token.IdentityID
+	if _, err := col.InsertOne(ctx, token); err != nil {
+		log.Printf("failed to create auth token: %v", err)
+		return newDBError("failed to create auth token", err)
+	}
+	return nil
+}
+
+// ConsumeAuthToken marks an unused, unexpired token as used and returns it.
+// It returns nil when there is no such token, so a token can be used once.
+func (r *MongoAuthTokenRepo) ConsumeAuthToken(reqCtx *app.RequestContext, purpose model.AuthTokenPurpose, tokenHash string) (*model.AuthToken, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now, "updated_at": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var token model.AuthToken
+	if err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&token); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to consume auth token: %v", err)
+		return nil, newDBError("failed to consume auth token", err)
+	}
+	return &token, nil
+}
+
+// RevokeAuthTokens marks the unused tokens of the identity issued for the
+// purposes as used, so they can no longer be consumed.
+func (r *MongoAuthTokenRepo) RevokeAuthTokens(reqCtx *app.RequestContext, identityID bson.ObjectID, purposes []model.AuthTokenPurpose) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"identity_id": identityID,
+		"purpose":     bson.M{"$in": purposes},
+		"used_at":     bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now, "updated_at": now}}
+	if _, err := col.UpdateMany(ctx, filter, update); err != nil {
+		log.Printf("failed to revoke auth tokens: %v", err)
+		return newDBError("failed to revoke auth tokens", err)
+	}
+	return nil
+}
+
+// DeleteAuthTokensByIdentity removes the tokens of the identity, used or not,
+// with the email they were sent to.
+func (r *MongoAuthTokenRepo) DeleteAuthTokensByIdentity(reqCtx *app.RequestContext, identityID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteMany(ctx, bson.M{"identity_id": identityID}); err != nil {
+		log.Printf("failed to delete auth tokens: %v", err)
+		return newDBError("failed to delete auth tokens", err)
+	}
+	return nil
+}
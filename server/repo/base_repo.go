@@ -1,6 +1,9 @@
 package repo
 
 import (
+	"context"
+
+	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/db"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
@@ -23,3 +26,10 @@ func (r *BaseRepo) GetCollection(names ...string) *mongo.Collection {
 	}
 	return r.appDB.GetCoreDatabase().Collection(r.cname)
 }
+
+// GetDBContext returns the context of a database operation made for the
+// request. It carries the session of the request's transaction, if any, so
+// that the operation is part of it.
+func (r *BaseRepo) GetDBContext(reqCtx *app.RequestContext) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(reqCtx.Context(), db.DBTimeout)
+}
@@ -1,3 +1,5 @@
+//go:generate mockgen -source=batch_repo.go -destination=../mocks/mock_batch_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
 package repo
 
 import (
@@ -10,6 +12,7 @@ import (
 	"github.com/gaeaglobal/exto/server/model"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 type BatchRepo interface {
@@ -17,6 +20,8 @@ type BatchRepo interface {
 	GetBatchByID(reqCtx *app.RequestContext, BatchID bson.ObjectID) (*model.Batch, error)
 	CreateBatch(reqCtx *app.RequestContext, batch *model.CreateBatchRequest) (*model.Batch, error)
 	UpdateBatchStatus(reqCtx *app.RequestContext, batchID bson.ObjectID, status string) (*model.Batch, error)
+	SetLegalHold(reqCtx *app.RequestContext, batchID bson.ObjectID, hold bool) (*model.Batch, error)
+	GetLegalHoldBatchIDs(reqCtx *app.RequestContext) ([]bson.ObjectID, error)
 }
 
 type MongoBatchRepo struct {
@@ -45,7 +50,7 @@ func NewBatchRepository(appDB *db.AppDB) *MongoBatchRepo {
 
 func (r *MongoBatchRepo) GetBatchID(reqCtx *app.RequestContext, batchID bson.ObjectID) (*model.Batch, error) {
 	col := r.GetCollection(reqCtx.Org.Slug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	var batch model.Batch
@@ -55,7 +60,7 @@ func (r *MongoBatchRepo) GetBatchID(reqCtx *app.RequestContext, batchID bson.Obj
 			return nil, nil
 		}
 		log.Printf("failed to get batch details by ID: %v", err)
-		return nil, errors.New("failed to get batch details")
+		return nil, newDBError("failed to get batch details", err)
 	}
 
 	return &batch, nil
@@ -63,7 +68,7 @@ func (r *MongoBatchRepo) GetBatchID(reqCtx *app.RequestContext, batchID bson.Obj
 
 func (r *MongoBatchRepo) CreateBatch(reqCtx *app.RequestContext, batch *model.CreateBatchRequest) (*model.Batch, error) {
 	col := r.GetCollection(reqCtx.Org.Slug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	newBatch := &model.Batch{
@@ -79,7 +84,7 @@ func (r *MongoBatchRepo) CreateBatch(reqCtx *app.RequestContext, batch *model.Cr
 	result, err := col.InsertOne(ctx, newBatch)
 	if err != nil {
 		log.Printf("failed to create batch: %v", err)
-		return nil, errors.New("failed to create batch")
+		return nil, newDBError("failed to create batch", err)
 	}
 
 	if !result.Acknowledged {
@@ -92,13 +97,13 @@ func (r *MongoBatchRepo) CreateBatch(reqCtx *app.RequestContext, batch *model.Cr
 // update batch status
 func (r *MongoBatchRepo) UpdateBatchStatus(reqCtx *app.RequestContext, batchID bson.ObjectID, status string) (*model.Batch, error) {
 	col := r.GetCollection(reqCtx.Org.Slug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	result, err := col.UpdateOne(ctx, bson.M{"_id": batchID}, bson.M{"$set": bson.M{"status": status, "updated_at": time.Now(), "updated_by": reqCtx.User.IdentityID}})
 	if err != nil {
 		log.Printf("failed to update batch status: %v", err)
-		return nil, errors.New("failed to update batch status")
+		return nil, newDBError("failed to update batch status", err)
 	}
 
 	if result.MatchedCount == 0 {
@@ -112,3 +117,44 @@ func (r *MongoBatchRepo) UpdateBatchStatus(reqCtx *app.RequestContext, batchID b
 
 	return updatedBatch, nil
 }
+
+func (r *MongoBatchRepo) SetLegalHold(reqCtx *app.RequestContext, batchID bson.ObjectID, hold bool) (*model.Batch, error) {
+	col := r.GetCollection(reqCtx.Org.Slug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	result, err := col.UpdateOne(ctx, bson.M{"_id": batchID}, bson.M{"$set": bson.M{"legal_hold": hold, "updated_at": time.Now(), "updated_by": reqCtx.User.IdentityID}})
+	if err != nil {
+		log.Printf("failed to set batch legal hold: %v", err)
+		return nil, newDBError("failed to set batch legal hold", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("batch not found")
+	}
+	return r.GetBatchID(reqCtx, batchID)
+}
+
+// GetLegalHoldBatchIDs returns the batches of the organization on legal hold.
+func (r *MongoBatchRepo) GetLegalHoldBatchIDs(reqCtx *app.RequestContext) ([]bson.ObjectID, error) {
+	col := r.GetCollection(reqCtx.Org.Slug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	cursor, err := col.Find(ctx, bson.M{"legal_hold": true}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		log.Printf("failed to get batches on legal hold: %v", err)
+		return nil, newDBError("failed to get batches on legal hold", err)
+	}
+	defer cursor.Close(ctx)
+
+	var batches []model.Batch
+	if err := cursor.All(ctx, &batches); err != nil {
+		log.Printf("failed to decode batches on legal hold: %v", err)
+		return nil, newDBError("failed to get batches on legal hold", err)
+	}
+	ids := make([]bson.ObjectID, 0, len(batches))
+	for _, batch := range batches {
+		ids = append(ids, batch.ID)
+	}
+	return ids, nil
+}
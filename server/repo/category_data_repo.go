@@ -15,7 +15,10 @@
 package repo
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gaeaglobal/exto/server/app"
@@ -23,26 +26,105 @@ import (
 	"github.com/gaeaglobal/exto/server/model"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// dataCollectionSuffix is the suffix of the collections that hold the data
+// extracted for a category, named after the category slug.
+const dataCollectionSuffix = "_data"
+
+// rawExtractedDataKey is the key of the values extracted from the document in
+// the raw data of a record. Their sensitive ones are encrypted like those of
+// the metadata.
+const rawExtractedDataKey = "extractedData"
+
+// FieldCipher encrypts the values of the sensitive fields of the records with
+// the data key of their organization. It is implemented by
+// encryption.FieldCipher.
+type FieldCipher interface {
+	EncryptFields(ctx context.Context, orgID bson.ObjectID, fields map[string]any, names []string) (map[string]any, error)
+	DecryptFields(ctx context.Context, orgID bson.ObjectID, fields map[string]any) (map[string]any, error)
+	Decrypt(ctx context.Context, orgID bson.ObjectID, value any) (any, error)
+}
+
 type CategoryDataRepository interface {
 	GetCollection(orgSlug string, categorySlug string) *mongo.Collection
 	GetCategoryDataByID(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID) (*model.CategoryData, error)
 	ListCategoryData(reqCtx *app.RequestContext, categorySlug string, pageReq *app.PageRequest) (*app.PageResponse[*model.CategoryData], error)
 	CreateCategoryData(reqCtx *app.RequestContext, categorySlug string, categoryData *model.CreateCategoryDataRequest) (*model.CategoryData, error)
 	UpdateCategoryData(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, updateMetaData *model.UpdateCategoryDataRequest) (*model.CategoryData, error)
+	SetLegalHold(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, hold bool) (*model.CategoryData, error)
+	ListDataCollections(reqCtx *app.RequestContext) ([]string, error)
+	CountRetentionDue(reqCtx *app.RequestContext, categorySlug string, stage model.RetentionStage, cutoff time.Time, exempt []bson.ObjectID) (int64, error)
+	FindRetentionDue(reqCtx *app.RequestContext, categorySlug string, stage model.RetentionStage, cutoff time.Time, exempt []bson.ObjectID, limit int64) ([]*model.CategoryData, error)
+	ApplyRetention(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, stage model.RetentionStage, purgedDocuments int) error
+	DecryptDocument(reqCtx *app.RequestContext, doc bson.Raw) (bson.Raw, error)
 }
 
+// MongoCategoryDataRepo stores the values of the sensitive fields encrypted
+// with the cipher and decrypts them when reading the records. Without a
+// cipher, encryption at rest is disabled and values are stored as they are.
 type MongoCategoryDataRepo struct {
 	BaseRepo
+	cipher FieldCipher
 }
 
-func NewCategoryDataRepository(appDB *db.AppDB) *MongoCategoryDataRepo {
+func NewCategoryDataRepository(appDB *db.AppDB, cipher FieldCipher) *MongoCategoryDataRepo {
 	return &MongoCategoryDataRepo{
 		BaseRepo: BaseRepo{
 			appDB: appDB,
 		},
+		cipher: cipher,
+	}
+}
+
+// encryptFields returns copies of the metadata and of the raw data with the
+// values of the sensitive fields encrypted.
+func (r *MongoCategoryDataRepo) encryptFields(reqCtx *app.RequestContext, metaData map[string]any, rawData map[string]any, sensitiveFields []string) (map[string]any, map[string]any, error) {
+	if r.cipher == nil || len(sensitiveFields) == 0 {
+		return metaData, rawData, nil
+	}
+	metaData, err := r.cipher.EncryptFields(reqCtx.Context(), reqCtx.Org.ID, metaData, sensitiveFields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt category data: %w", err)
+	}
+	if extracted, ok := rawData[rawExtractedDataKey].(map[string]any); ok {
+		extracted, err = r.cipher.EncryptFields(reqCtx.Context(), reqCtx.Org.ID, extracted, sensitiveFields)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt category data: %w", err)
+		}
+		encrypted := make(map[string]any, len(rawData))
+		for key, value := range rawData {
+			encrypted[key] = value
+		}
+		encrypted[rawExtractedDataKey] = extracted
+		rawData = encrypted
+	} else if rawData != nil {
+		// The raw data of the records entered by hand are the values
+		// themselves.
+		rawData, err = r.cipher.EncryptFields(reqCtx.Context(), reqCtx.Org.ID, rawData, sensitiveFields)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt category data: %w", err)
+		}
+	}
+	return metaData, rawData, nil
+}
+
+// decryptFields decrypts the encrypted values of the record in place.
+func (r *MongoCategoryDataRepo) decryptFields(reqCtx *app.RequestContext, data *model.CategoryData) error {
+	if r.cipher == nil {
+		return nil
+	}
+	metaData, err := r.cipher.DecryptFields(reqCtx.Context(), reqCtx.Org.ID, data.MetaData)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt category data %s: %w", data.ID.Hex(), err)
+	}
+	rawData, err := r.cipher.DecryptFields(reqCtx.Context(), reqCtx.Org.ID, data.RawData)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt category data %s: %w", data.ID.Hex(), err)
 	}
+	data.MetaData, data.RawData = metaData, rawData
+	return nil
 }
 
 func (r *MongoCategoryDataRepo) GetCollection(orgSlug string, categorySlug string) *mongo.Collection {
@@ -52,7 +134,7 @@ func (r *MongoCategoryDataRepo) GetCollection(orgSlug string, categorySlug strin
 func (r *MongoCategoryDataRepo) GetCategoryDataByID(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID) (*model.CategoryData, error) {
 	col := r.GetCollection(reqCtx.Org.Slug, categorySlug)
 	var categoryData model.CategoryData
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	result := col.FindOne(ctx, bson.M{"_id": id})
 	if err := result.Err(); err != nil {
@@ -64,12 +146,15 @@ func (r *MongoCategoryDataRepo) GetCategoryDataByID(reqCtx *app.RequestContext,
 	if err := result.Decode(&categoryData); err != nil {
 		return nil, err
 	}
+	if err := r.decryptFields(reqCtx, &categoryData); err != nil {
+		return nil, err
+	}
 	return &categoryData, nil
 }
 
 func (r *MongoCategoryDataRepo) ListCategoryData(reqCtx *app.RequestContext, categorySlug string, pageReq *app.PageRequest) (*app.PageResponse[*model.CategoryData], error) {
 	col := r.GetCollection(reqCtx.Org.Slug, categorySlug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	filter := bson.M{}
@@ -101,6 +186,10 @@ func (r *MongoCategoryDataRepo) ListCategoryData(reqCtx *app.RequestContext, cat
 				findErr = err
 				return
 			}
+			if err := r.decryptFields(reqCtx, &catData); err != nil {
+				findErr = err
+				return
+			}
 			categoryData = append(categoryData, &catData)
 		}
 		if err := cursor.Err(); err != nil {
@@ -133,7 +222,7 @@ func (r *MongoCategoryDataRepo) ListCategoryData(reqCtx *app.RequestContext, cat
 
 func (r *MongoCategoryDataRepo) CreateCategoryData(reqCtx *app.RequestContext, categorySlug string, categoryData *model.CreateCategoryDataRequest) (*model.CategoryData, error) {
 	col := r.GetCollection(reqCtx.Org.Slug, categorySlug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	data := &model.CategoryData{
@@ -147,29 +236,170 @@ func (r *MongoCategoryDataRepo) CreateCategoryData(reqCtx *app.RequestContext, c
 		RawData:        categoryData.RawData,
 		DocumentPaths:  categoryData.DocumentPaths,
 		OrganizationID: reqCtx.Org.ID,
+		PII:            categoryData.PII,
 	}
-	_, err := col.InsertOne(ctx, data)
+	stored := *data
+	metaData, rawData, err := r.encryptFields(reqCtx, data.MetaData, data.RawData, categoryData.SensitiveFields)
 	if err != nil {
 		return nil, err
 	}
+	stored.MetaData, stored.RawData = metaData, rawData
+	if _, err := col.InsertOne(ctx, &stored); err != nil {
+		return nil, err
+	}
 	return data, nil
 }
 
 func (r *MongoCategoryDataRepo) UpdateCategoryData(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, updateMetaData *model.UpdateCategoryDataRequest) (*model.CategoryData, error) {
 	col := r.GetCollection(reqCtx.Org.Slug, categorySlug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
-	_, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "metadata", Value: updateMetaData.MetaData},
-			{Key: "updated_at", Value: time.Now()},
-			{Key: "updated_by", Value: reqCtx.User.IdentityID},
-		}},
-	})
+	metaData, _, err := r.encryptFields(reqCtx, updateMetaData.MetaData, nil, updateMetaData.SensitiveFields)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.D{
+		{Key: "metadata", Value: metaData},
+		{Key: "updated_at", Value: time.Now()},
+		{Key: "updated_by", Value: reqCtx.User.IdentityID},
+	}
+	update := bson.D{}
+	if len(updateMetaData.PII) > 0 {
+		set = append(set, bson.E{Key: "pii", Value: updateMetaData.PII})
+	} else {
+		update = append(update, bson.E{Key: "$unset", Value: bson.D{{Key: "pii", Value: ""}}})
+	}
+	update = append(update, bson.E{Key: "$set", Value: set})
+	_, err = col.UpdateOne(ctx, bson.M{"_id": id}, update)
 	if err != nil {
 		return nil, err
 	}
 	// Fetch the updated category data
 	return r.GetCategoryDataByID(reqCtx, categorySlug, id)
 }
+
+func (r *MongoCategoryDataRepo) SetLegalHold(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, hold bool) (*model.CategoryData, error) {
+	col := r.GetCollection(reqCtx.Org.Slug, categorySlug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	_, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"legal_hold": hold,
+		"updated_at": time.Now(),
+		"updated_by": reqCtx.User.IdentityID,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetCategoryDataByID(reqCtx, categorySlug, id)
+}
+
+// ListDataCollections returns the names of the category data collections of
+// the organization database.
+func (r *MongoCategoryDataRepo) ListDataCollections(reqCtx *app.RequestContext) ([]string, error) {
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	names, err := r.appDB.GetOrgDatabase(reqCtx.Org.Slug).ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	collections := []string{}
+	for _, name := range names {
+		if strings.HasSuffix(name, dataCollectionSuffix) {
+			collections = append(collections, name)
+		}
+	}
+	return collections, nil
+}
+
+// retentionDueFilter selects the records created before the cutoff that the
+// retention stage has not been applied to yet. Records on legal hold and the
+// exempt records are left out.
+func retentionDueFilter(stage model.RetentionStage, cutoff time.Time, exempt []bson.ObjectID) bson.M {
+	filter := bson.M{
+		"created_at": bson.M{"$lte": cutoff},
+		"legal_hold": bson.M{"$ne": true},
+	}
+	if len(exempt) > 0 {
+		filter["_id"] = bson.M{"$nin": exempt}
+	}
+	if stage == model.RetentionStageDocuments {
+		filter["document_paths.0"] = bson.M{"$exists": true}
+	} else {
+		filter["retention.data_purged_at"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+func (r *MongoCategoryDataRepo) CountRetentionDue(reqCtx *app.RequestContext, categorySlug string, stage model.RetentionStage, cutoff time.Time, exempt []bson.ObjectID) (int64, error) {
+	col := r.GetCollection(reqCtx.Org.Slug, categorySlug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	return col.CountDocuments(ctx, retentionDueFilter(stage, cutoff, exempt))
+}
+
+// FindRetentionDue returns the records with their sensitive values still
+// encrypted: the sweeper only needs their document paths.
+func (r *MongoCategoryDataRepo) FindRetentionDue(reqCtx *app.RequestContext, categorySlug string, stage model.RetentionStage, cutoff time.Time, exempt []bson.ObjectID, limit int64) ([]*model.CategoryData, error) {
+	col := r.GetCollection(reqCtx.Org.Slug, categorySlug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit)
+	cursor, err := col.Find(ctx, retentionDueFilter(stage, cutoff, exempt), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	categoryData := []*model.CategoryData{}
+	if err := cursor.All(ctx, &categoryData); err != nil {
+		return nil, err
+	}
+	return categoryData, nil
+}
+
+// ApplyRetention records the tombstone of a retention stage on the record.
+// The documents stage drops the document paths; the data stage also drops the
+// extracted data.
+func (r *MongoCategoryDataRepo) ApplyRetention(reqCtx *app.RequestContext, categorySlug string, id bson.ObjectID, stage model.RetentionStage, purgedDocuments int) error {
+	col := r.GetCollection(reqCtx.Org.Slug, categorySlug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{"document_paths": []string{}, "updated_at": now},
+		"$inc": bson.M{"retention.purged_documents": purgedDocuments},
+	}
+	if purgedDocuments > 0 {
+		// $min keeps the time the documents were first purged.
+		update["$min"] = bson.M{"retention.documents_purged_at": now}
+	}
+	if stage == model.RetentionStageData {
+		update["$set"].(bson.M)["retention.data_purged_at"] = now
+		update["$unset"] = bson.M{"metadata": "", "rawdata": ""}
+	}
+	_, err := col.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// DecryptDocument returns the raw record with its encrypted values decrypted,
+// for exports of whole collections.
+func (r *MongoCategoryDataRepo) DecryptDocument(reqCtx *app.RequestContext, doc bson.Raw) (bson.Raw, error) {
+	if r.cipher == nil {
+		return doc, nil
+	}
+	var fields bson.D
+	if err := bson.Unmarshal(doc, &fields); err != nil {
+		return nil, err
+	}
+	decrypted, err := r.cipher.Decrypt(reqCtx.Context(), reqCtx.Org.ID, fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt category data: %w", err)
+	}
+	return bson.Marshal(decrypted)
+}
@@ -46,7 +46,7 @@ func NewCategoryRepository(appDB *db.AppDB) *MongoCategoryRepo {
 
 func (r *MongoCategoryRepo) GetCategoryByID(reqCtx *app.RequestContext, categoryID bson.ObjectID) (*model.Category, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	var category model.Category
@@ -56,14 +56,14 @@ func (r *MongoCategoryRepo) GetCategoryByID(reqCtx *app.RequestContext, category
 			return nil, nil
 		}
 		log.Printf("failed to get category by ID: %v", err)
-		return nil, errors.New("failed to get category")
+		return nil, newDBError("failed to get category", err)
 	}
 	return &category, nil
 }
 
 func (r *MongoCategoryRepo) ListCategories(reqCtx *app.RequestContext, pageReq *app.PageRequest) (*app.PageResponse[*model.Category], error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	opts := pageReq.ToFindOptions()
@@ -103,11 +103,11 @@ func (r *MongoCategoryRepo) ListCategories(reqCtx *app.RequestContext, pageReq *
 
 	if findErr != nil {
 		log.Printf("failed to list categories: %v", findErr)
-		return nil, errors.New("failed to list categories")
+		return nil, newDBError("failed to list categories", findErr)
 	}
 	if countErr != nil {
 		log.Printf("failed to count categories: %v", countErr)
-		return nil, errors.New("failed to count categories")
+		return nil, newDBError("failed to count categories", countErr)
 	}
 
 	if categories == nil {
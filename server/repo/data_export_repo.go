@@ -0,0 +1,285 @@
+//go:generate mockgen -source=data_export_repo.go -destination=../mocks/mock_data_export_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// dataExportReadTimeout bounds reading a whole collection into an export
+// archive, which can take much longer than a single query.
+const dataExportReadTimeout = 30 * time.Minute
+
+type DataExportRepository interface {
+	IBaseRepo
+	EnsureIndexes() error
+	CreateDataExport(reqCtx *app.RequestContext) (*model.DataExport, error)
+	GetDataExport(reqCtx *app.RequestContext, id bson.ObjectID) (*model.DataExport, error)
+	ListDataExports(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.DataExport, error)
+	HasActiveDataExport(reqCtx *app.RequestContext, orgID bson.ObjectID) (bool, error)
+	ClaimNextPending(reqCtx *app.RequestContext, now time.Time, lease time.Duration) (*model.DataExport, error)
+	MarkReady(reqCtx *app.RequestContext, id bson.ObjectID, fileName string, size int64, sha256 string, expiresAt time.Time) error
+	MarkFailed(reqCtx *app.RequestContext, id bson.ObjectID, lastError string) error
+	GetExpiredDataExports(reqCtx *app.RequestContext, now time.Time) ([]*model.DataExport, error)
+	MarkExpired(reqCtx *app.RequestContext, id bson.ObjectID) error
+	ForEachDocument(reqCtx *app.RequestContext, orgSlug string, collection string, filter bson.M, fn func(doc bson.Raw) error) error
+	DeleteDataExportsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error
+}
+
+type MongoDataExportRepo struct {
+	BaseRepo
+}
+
+func NewDataExportRepository(appDB *db.AppDB) *MongoDataExportRepo {
+	return &MongoDataExportRepo{
+		BaseRepo: BaseRepo{
+			cname: "data_exports",
+			appDB: appDB,
+		},
+	}
+}
+
+func (r *MongoDataExportRepo) EnsureIndexes() error {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lease_until", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("failed to create data export indexes: %v", err)
+		return newDBError("failed to create data export indexes", err)
+	}
+	return nil
+}
+
+// CreateDataExport queues an export of the organization of the request.
+func (r *MongoDataExportRepo) CreateDataExport(reqCtx *app.RequestContext) (*model.DataExport, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	export := &model.DataExport{
+		Base: model.Base{
+			ID:        bson.NewObjectID(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			CreatedBy: reqCtx.User.IdentityID,
+			UpdatedBy: reqCtx.User.IdentityID,
+		},
+		OrganizationID: reqCtx.Org.ID,
+		Status:         model.DataExportPending,
+	}
+	if _, err := col.InsertOne(ctx, export); err != nil {
+		log.Printf("failed to create data export: %v", err)
+		return nil, newDBError("failed to create data export", err)
+	}
+	return export, nil
+}
+
+func (r *MongoDataExportRepo) GetDataExport(reqCtx *app.RequestContext, id bson.ObjectID) (*model.DataExport, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	var export model.DataExport
+	if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&export); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to get data export: %v", err)
+		return nil, newDBError("failed to get data export", err)
+	}
+	return &export, nil
+}
+
+func (r *MongoDataExportRepo) ListDataExports(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.DataExport, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := col.Find(ctx, bson.M{"org_id": orgID}, opts)
+	if err != nil {
+		log.Printf("failed to list data exports: %v", err)
+		return nil, newDBError("failed to list data exports", err)
+	}
+	defer cursor.Close(ctx)
+
+	exports := []*model.DataExport{}
+	if err := cursor.All(ctx, &exports); err != nil {
+		log.Printf("failed to decode data exports: %v", err)
+		return nil, newDBError("failed to list data exports", err)
+	}
+	return exports, nil
+}
+
+// HasActiveDataExport reports whether an export of the organization is queued
+// or being generated.
+func (r *MongoDataExportRepo) HasActiveDataExport(reqCtx *app.RequestContext, orgID bson.ObjectID) (bool, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{
+		"org_id": orgID,
+		"status": bson.M{"$in": []model.DataExportStatus{model.DataExportPending, model.DataExportRunning}},
+	}
+	count, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Printf("failed to count active data exports: %v", err)
+		return false, newDBError("failed to check data exports", err)
+	}
+	return count > 0, nil
+}
+
+// ClaimNextPending leases the oldest queued export, or a running one whose
+// lease has run out because its worker stopped. It returns nil when there is
+// nothing to generate.
+func (r *MongoDataExportRepo) ClaimNextPending(reqCtx *app.RequestContext, now time.Time, lease time.Duration) (*model.DataExport, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{"$or": []bson.M{
+		{"status": model.DataExportPending},
+		{"status": model.DataExportRunning, "lease_until": bson.M{"$lte": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{"status": model.DataExportRunning, "lease_until": now.Add(lease), "updated_at": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var export model.DataExport
+	if err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&export); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to claim data export: %v", err)
+		return nil, newDBError("failed to claim data export", err)
+	}
+	return &export, nil
+}
+
+func (r *MongoDataExportRepo) MarkReady(reqCtx *app.RequestContext, id bson.ObjectID, fileName string, size int64, sha256 string, expiresAt time.Time) error {
+	now := time.Now()
+	return r.updateDataExport(reqCtx, id, bson.M{
+		"status":       model.DataExportReady,
+		"file_name":    fileName,
+		"size":         size,
+		"sha256":       sha256,
+		"last_error":   "",
+		"completed_at": now,
+		"expires_at":   expiresAt,
+		"updated_at":   now,
+	})
+}
+
+func (r *MongoDataExportRepo) MarkFailed(reqCtx *app.RequestContext, id bson.ObjectID, lastError string) error {
+	return r.updateDataExport(reqCtx, id, bson.M{
+		"status":     model.DataExportFailed,
+		"last_error": lastError,
+		"updated_at": time.Now(),
+	})
+}
+
+func (r *MongoDataExportRepo) MarkExpired(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	return r.updateDataExport(reqCtx, id, bson.M{
+		"status":     model.DataExportExpired,
+		"updated_at": time.Now(),
+	})
+}
+
+func (r *MongoDataExportRepo) updateDataExport(reqCtx *app.RequestContext, id bson.ObjectID, set bson.M) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		log.Printf("failed to update data export %s: %v", id.Hex(), err)
+		return newDBError("failed to update data export", err)
+	}
+	return nil
+}
+
+// GetExpiredDataExports returns the ready exports whose archive has expired.
+func (r *MongoDataExportRepo) GetExpiredDataExports(reqCtx *app.RequestContext, now time.Time) ([]*model.DataExport, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	cursor, err := col.Find(ctx, bson.M{"status": model.DataExportReady, "expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		log.Printf("failed to find expired data exports: %v", err)
+		return nil, newDBError("failed to find expired data exports", err)
+	}
+	defer cursor.Close(ctx)
+
+	exports := []*model.DataExport{}
+	if err := cursor.All(ctx, &exports); err != nil {
+		log.Printf("failed to decode expired data exports: %v", err)
+		return nil, newDBError("failed to find expired data exports", err)
+	}
+	return exports, nil
+}
+
+// ForEachDocument calls fn with every document of the collection that matches
+// the filter, in _id order. The collection is read from the organization
+// database, or from the core database when orgSlug is empty.
+func (r *MongoDataExportRepo) ForEachDocument(reqCtx *app.RequestContext, orgSlug string, collection string, filter bson.M, fn func(doc bson.Raw) error) error {
+	database := r.appDB.GetCoreDatabase()
+	if orgSlug != "" {
+		database = r.appDB.GetOrgDatabase(orgSlug)
+	}
+	ctx, cancel := context.WithTimeout(reqCtx.Context(), dataExportReadTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := database.Collection(collection).Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("failed to read %s for data export: %v", collection, err)
+		return newDBError("failed to read "+collection, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		if err := fn(cursor.Current); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Printf("failed to read %s for data export: %v", collection, err)
+		return newDBError("failed to read "+collection, err)
+	}
+	return nil
+}
+
+// DeleteDataExportsByOrg removes the export records of the organization.
+func (r *MongoDataExportRepo) DeleteDataExportsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteMany(ctx, bson.M{"org_id": orgID}); err != nil {
+		log.Printf("failed to delete data exports: %v", err)
+		return newDBError("failed to delete data exports", err)
+	}
+	return nil
+}
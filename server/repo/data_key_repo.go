@@ -0,0 +1,170 @@
+//go:generate mockgen -source=data_key_repo.go -destination=../mocks/mock_data_key_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// ErrDataKeyExists is returned when creating a version of a data key that
+// another instance created first.
+var ErrDataKeyExists = errors.New("data key version already exists")
+
+type DataKeyRepository interface {
+	IBaseRepo
+	EnsureIndexes() error
+	CreateDataKey(reqCtx *app.RequestContext, key *model.DataKey) error
+	GetDataKey(reqCtx *app.RequestContext, orgID bson.ObjectID, version int) (*model.DataKey, error)
+	GetLatestDataKey(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.DataKey, error)
+	ListDataKeysNotWrappedWith(reqCtx *app.RequestContext, masterKeyID string, afterID bson.ObjectID, limit int64) ([]*model.DataKey, error)
+	RewrapDataKey(reqCtx *app.RequestContext, id bson.ObjectID, fromMasterKeyID string, masterKeyID string, wrappedKey []byte) error
+	DeleteDataKeysByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error
+}
+
+type MongoDataKeyRepo struct {
+	BaseRepo
+}
+
+func NewDataKeyRepository(appDB *db.AppDB) *MongoDataKeyRepo {
+	return &MongoDataKeyRepo{
+		BaseRepo: BaseRepo{
+			cname: "data_keys",
+			appDB: appDB,
+		},
+	}
+}
+
+func (r *MongoDataKeyRepo) EnsureIndexes() error {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "version", Value: -1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "master_key_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("failed to create data key indexes: %v", err)
+		return newDBError("failed to create data key indexes", err)
+	}
+	return nil
+}
+
+// CreateDataKey stores a new version of the data key of an organization. It
+// returns ErrDataKeyExists when the version was created concurrently.
+func (r *MongoDataKeyRepo) CreateDataKey(reqCtx *app.RequestContext, key *model.DataKey) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	key.ID = bson.NewObjectID()
+	key.CreatedAt = now
+	key.UpdatedAt = now
+	key.CreatedBy = reqCtx.User.IdentityID
+	key.UpdatedBy = reqCtx.User.IdentityID
+	_, err := col.InsertOne(ctx, key)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDataKeyExists
+	}
+	if err != nil {
+		log.Printf("failed to create data key: %v", err)
+		return newDBError("failed to create data key", err)
+	}
+	return nil
+}
+
+func (r *MongoDataKeyRepo) GetDataKey(reqCtx *app.RequestContext, orgID bson.ObjectID, version int) (*model.DataKey, error) {
+	return r.findDataKey(reqCtx, bson.M{"org_id": orgID, "version": version}, options.FindOne())
+}
+
+// GetLatestDataKey returns the current version of the data key of the
+// organization, or nil when it has none yet.
+func (r *MongoDataKeyRepo) GetLatestDataKey(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.DataKey, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	return r.findDataKey(reqCtx, bson.M{"org_id": orgID}, opts)
+}
+
+func (r *MongoDataKeyRepo) findDataKey(reqCtx *app.RequestContext, filter bson.M, opts *options.FindOneOptionsBuilder) (*model.DataKey, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	var key model.DataKey
+	if err := col.FindOne(ctx, filter, opts).Decode(&key); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to get data key: %v", err)
+		return nil, newDBError("failed to get data key", err)
+	}
+	return &key, nil
+}
+
+// ListDataKeysNotWrappedWith returns the data keys of any organization that
+// are wrapped with another master key than the one of the id, in the order of
+// their ids from afterID on.
+func (r *MongoDataKeyRepo) ListDataKeysNotWrappedWith(reqCtx *app.RequestContext, masterKeyID string, afterID bson.ObjectID, limit int64) ([]*model.DataKey, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := col.Find(ctx, bson.M{"_id": bson.M{"$gt": afterID}, "master_key_id": bson.M{"$ne": masterKeyID}}, opts)
+	if err != nil {
+		log.Printf("failed to list data keys: %v", err)
+		return nil, newDBError("failed to list data keys", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*model.DataKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		log.Printf("failed to decode data keys: %v", err)
+		return nil, newDBError("failed to list data keys", err)
+	}
+	return keys, nil
+}
+
+// RewrapDataKey replaces the wrapped key of a data key, unless another
+// instance rewrapped it since it was read with fromMasterKeyID.
+func (r *MongoDataKeyRepo) RewrapDataKey(reqCtx *app.RequestContext, id bson.ObjectID, fromMasterKeyID string, masterKeyID string, wrappedKey []byte) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	_, err := col.UpdateOne(ctx, bson.M{"_id": id, "master_key_id": fromMasterKeyID}, bson.M{"$set": bson.M{
+		"master_key_id": masterKeyID,
+		"wrapped_key":   wrappedKey,
+		"updated_at":    time.Now(),
+		"updated_by":    reqCtx.User.IdentityID,
+	}})
+	if err != nil {
+		log.Printf("failed to rewrap data key: %v", err)
+		return newDBError("failed to rewrap data key", err)
+	}
+	return nil
+}
+
+// DeleteDataKeysByOrg removes every version of the data key of the organization; what
+// was encrypted with it can no longer be read, backups included.
+func (r *MongoDataKeyRepo) DeleteDataKeysByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteMany(ctx, bson.M{"org_id": orgID}); err != nil {
+		log.Printf("failed to delete data keys: %v", err)
+		return newDBError("failed to delete data keys", err)
+	}
+	return nil
+}
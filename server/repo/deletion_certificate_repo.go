@@ -0,0 +1,69 @@
+//go:generate mockgen -source=deletion_certificate_repo.go -destination=../mocks/mock_deletion_certificate_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type DeletionCertificateRepository interface {
+	IBaseRepo
+	CreateDeletionCertificate(reqCtx *app.RequestContext, cert *model.DeletionCertificate) (*model.DeletionCertificate, error)
+	ListDeletionCertificates(reqCtx *app.RequestContext, pageReq *app.PageRequest) ([]*model.DeletionCertificate, error)
+}
+
+type MongoDeletionCertificateRepo struct {
+	BaseRepo
+}
+
+func NewDeletionCertificateRepository(appDB *db.AppDB) *MongoDeletionCertificateRepo {
+	return &MongoDeletionCertificateRepo{
+		BaseRepo: BaseRepo{
+			cname: "deletion_certificates",
+			appDB: appDB,
+		},
+	}
+}
+
+func (r *MongoDeletionCertificateRepo) CreateDeletionCertificate(reqCtx *app.RequestContext, cert *model.DeletionCertificate) (*model.DeletionCertificate, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	cert.ID = bson.NewObjectID()
+	cert.CreatedAt = time.Now()
+	if _, err := col.InsertOne(ctx, cert); err != nil {
+		log.Printf("failed to create deletion certificate: %v", err)
+		return nil, newDBError("failed to create deletion certificate", err)
+	}
+	return cert, nil
+}
+
+func (r *MongoDeletionCertificateRepo) ListDeletionCertificates(reqCtx *app.RequestContext, pageReq *app.PageRequest) ([]*model.DeletionCertificate, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"purged_at": -1}).SetSkip(pageReq.GetSkip()).SetLimit(pageReq.GetLimit())
+	cursor, err := col.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		log.Printf("failed to list deletion certificates: %v", err)
+		return nil, newDBError("failed to list deletion certificates", err)
+	}
+	defer cursor.Close(ctx)
+
+	certs := []*model.DeletionCertificate{}
+	if err := cursor.All(ctx, &certs); err != nil {
+		log.Printf("failed to decode deletion certificates: %v", err)
+		return nil, newDBError("failed to list deletion certificates", err)
+	}
+	return certs, nil
+}
@@ -0,0 +1,17 @@
+package repo
+
+// dbError replaces the error of a failed database operation with a message
+// that is safe to return to clients. It unwraps to the driver error, so that
+// db.WithTransaction can still retry on its error labels.
+type dbError struct {
+	msg string
+	err error
+}
+
+func newDBError(msg string, err error) error {
+	return &dbError{msg: msg, err: err}
+}
+
+func (e *dbError) Error() string { return e.msg }
+
+func (e *dbError) Unwrap() error { return e.err }
@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type ExtractionCacheRepository interface {
+	IBaseRepo
+	GetByKey(reqCtx *app.RequestContext, key string) (*model.ExtractionCacheEntry, error)
+	Upsert(reqCtx *app.RequestContext, entry *model.CreateExtractionCacheEntry) error
+	RecordHit(reqCtx *app.RequestContext, key string) error
+}
+
+type MongoExtractionCacheRepo struct {
+	BaseRepo
+}
+
+func NewExtractionCacheRepository(appDB *db.AppDB) *MongoExtractionCacheRepo {
+	return &MongoExtractionCacheRepo{
+		BaseRepo: BaseRepo{
+			cname: "extraction_cache",
+			appDB: appDB,
+		},
+	}
+}
+
+// GetByKey returns the cached extraction for the key, or nil when there is none.
+func (r *MongoExtractionCacheRepo) GetByKey(reqCtx *app.RequestContext, key string) (*model.ExtractionCacheEntry, error) {
+	col := r.GetCollection(reqCtx.Org.Slug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	var entry model.ExtractionCacheEntry
+	err := col.FindOne(ctx, bson.M{"key": key}).Decode(&entry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to get extraction cache entry: %v", err)
+		return nil, newDBError("failed to get extraction cache entry", err)
+	}
+	return &entry, nil
+}
+
+func (r *MongoExtractionCacheRepo) Upsert(reqCtx *app.RequestContext, entry *model.CreateExtractionCacheEntry) error {
+	col := r.GetCollection(reqCtx.Org.Slug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"document_hash":  entry.DocumentHash,
+			"format_id":      entry.FormatID,
+			"prompt_version": entry.PromptVersion,
+			"model":          entry.Model,
+			"response":       entry.Response,
+			"updated_at":     now,
+			"updated_by":     reqCtx.User.IdentityID,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        bson.NewObjectID(),
+			"created_at": now,
+			"created_by": reqCtx.User.IdentityID,
+			"hit_count":  0,
+		},
+	}
+	opts := options.UpdateOne().SetUpsert(true)
+	if _, err := col.UpdateOne(ctx, bson.M{"key": entry.Key}, update, opts); err != nil {
+		log.Printf("failed to upsert extraction cache entry: %v", err)
+		return newDBError("failed to save extraction cache entry", err)
+	}
+	return nil
+}
+
+func (r *MongoExtractionCacheRepo) RecordHit(reqCtx *app.RequestContext, key string) error {
+	col := r.GetCollection(reqCtx.Org.Slug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{
+		"$inc": bson.M{"hit_count": 1},
+		"$set": bson.M{"last_hit_at": time.Now()},
+	}
+	if _, err := col.UpdateOne(ctx, bson.M{"key": key}, update); err != nil {
+		log.Printf("failed to record extraction cache hit: %v", err)
+		return newDBError("failed to record extraction cache hit", err)
+	}
+	return nil
+}
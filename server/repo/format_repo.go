@@ -15,7 +15,6 @@
 package repo
 
 import (
-	"errors"
 	"log"
 
 	"github.com/gaeaglobal/exto/server/app"
@@ -44,19 +43,19 @@ func NewFormatRepository(appDb *db.AppDB) *MongoFormatRepo {
 
 func (r *MongoFormatRepo) GetFormatsByCategoryID(reqCtx *app.RequestContext, categoryID bson.ObjectID) ([]model.Format, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	var formats []model.Format
 	cursor, err := col.Find(ctx, bson.M{"category_id": categoryID})
 	if err != nil {
 		log.Println("Error finding formats:", err)
-		return nil, errors.New("error fetching formats")
+		return nil, newDBError("error fetching formats", err)
 	}
 	defer cursor.Close(ctx)
 
 	if err := cursor.All(ctx, &formats); err != nil {
-		return nil, errors.New("error fetching formats")
+		return nil, newDBError("error fetching formats", err)
 	}
 
 	return formats, nil
@@ -32,8 +32,14 @@ type IdentityRepo interface {
 	CreateIdentity(reqCtx *app.RequestContext, identity *model.CreateIdentity) (*model.Identity, error)
 	GetIdentityByEmail(reqCtx *app.RequestContext, email string) (*model.Identity, error)
 	UpdateIdentity(reqCtx *app.RequestContext, id bson.ObjectID, identity *model.UpdateIdentity) (*model.Identity, error)
+	SetCurrentOrg(reqCtx *app.RequestContext, id bson.ObjectID, orgID bson.ObjectID) error
+	SetPasswordHash(reqCtx *app.RequestContext, id bson.ObjectID, passwordHash string) error
+	MarkEmailVerified(reqCtx *app.RequestContext, id bson.ObjectID) error
 	IsIdentityExists(reqCtx *app.RequestContext, email string) (bool, error)
-	DeleteIdentity(reqCtx *app.RequestContext) error
+	DeleteIdentity(reqCtx *app.RequestContext, id bson.ObjectID) error
+	MarkIdentityDeleted(reqCtx *app.RequestContext, id bson.ObjectID) error
+	RestoreIdentity(reqCtx *app.RequestContext, id bson.ObjectID) (bool, error)
+	GetIdentitiesDeletedBefore(reqCtx *app.RequestContext, before time.Time) ([]*model.Identity, error)
 }
 
 type MongoIdentityRepo struct {
@@ -51,7 +57,7 @@ func NewIdentityRepository(appDB *db.AppDB) *MongoIdentityRepo {
 
 func (r *MongoIdentityRepo) CreateIdentity(reqCtx *app.RequestContext, identity *model.CreateIdentity) (*model.Identity, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	identityID := bson.NewObjectID()
@@ -78,10 +84,10 @@ func (r *MongoIdentityRepo) CreateIdentity(reqCtx *app.RequestContext, identity
 	result, err := col.InsertOne(ctx, newIdentity)
 	if err != nil {
 		log.Println("Error creating identity:", err)
-		return nil, errors.New("error creating identity")
+		return nil, newDBError("error creating identity", err)
 	}
 	if !result.Acknowledged {
-		return nil, errors.New("error creating identity")
+		return nil, newDBError("error creating identity", err)
 	}
 
 	return &newIdentity, nil
@@ -89,7 +95,7 @@ func (r *MongoIdentityRepo) CreateIdentity(reqCtx *app.RequestContext, identity
 
 func (r *MongoIdentityRepo) GetIdentityByEmail(reqCtx *app.RequestContext, email string) (*model.Identity, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	var identity model.Identity
 	result := col.FindOne(ctx, bson.M{"email": email})
@@ -98,11 +104,11 @@ func (r *MongoIdentityRepo) GetIdentityByEmail(reqCtx *app.RequestContext, email
 			return nil, errors.New("identity not found")
 		}
 		log.Println("Error retrieving identity:", result.Err())
-		return nil, errors.New("error retrieving identity")
+		return nil, newDBError("error retrieving identity", result.Err())
 	}
 	if err := result.Decode(&identity); err != nil {
 		log.Println("Error decoding identity:", err)
-		return nil, errors.New("error decoding identity")
+		return nil, newDBError("error decoding identity", err)
 	}
 	// check if identity empty
 	if identity.IsZero() {
@@ -113,13 +119,13 @@ func (r *MongoIdentityRepo) GetIdentityByEmail(reqCtx *app.RequestContext, email
 
 func (r *MongoIdentityRepo) UpdateIdentity(reqCtx *app.RequestContext, id bson.ObjectID, identity *model.UpdateIdentity) (*model.Identity, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	result := col.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": identity}, opts)
 	if result.Err() != nil {
 		log.Println("Error updating identity:", result.Err())
-		return nil, errors.New("error updating identity")
+		return nil, newDBError("error updating identity", result.Err())
 	}
 	if !result.Acknowledged {
 		return nil, errors.New("error updating identity")
@@ -127,7 +133,7 @@ func (r *MongoIdentityRepo) UpdateIdentity(reqCtx *app.RequestContext, id bson.O
 	var updatedIdentity model.Identity
 	if err := result.Decode(&updatedIdentity); err != nil {
 		log.Println("Error decoding updated identity:", err)
-		return nil, errors.New("error decoding updated identity")
+		return nil, newDBError("error decoding updated identity", err)
 	}
 	// check if identity empty
 	if updatedIdentity.IsZero() {
@@ -136,30 +142,133 @@ func (r *MongoIdentityRepo) UpdateIdentity(reqCtx *app.RequestContext, id bson.O
 	return &updatedIdentity, nil
 }
 
+func (r *MongoIdentityRepo) SetCurrentOrg(reqCtx *app.RequestContext, id bson.ObjectID, orgID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"current_org_id": orgID, "updated_at": time.Now()}}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Println("Error setting current organization:", err)
+		return newDBError("error setting current organization", err)
+	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheIdentity(id) })
+	return nil
+}
+
+func (r *MongoIdentityRepo) SetPasswordHash(reqCtx *app.RequestContext, id bson.ObjectID, passwordHash string) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"password_hash": passwordHash, "updated_at": time.Now()}}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Println("Error setting password:", err)
+		return newDBError("error setting password", err)
+	}
+	return nil
+}
+
+func (r *MongoIdentityRepo) MarkEmailVerified(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"email_verified": true, "updated_at": time.Now()}}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Println("Error verifying email:", err)
+		return newDBError("error verifying email", err)
+	}
+	return nil
+}
+
 func (r *MongoIdentityRepo) IsIdentityExists(reqCtx *app.RequestContext, email string) (bool, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	count, err := col.CountDocuments(ctx, bson.M{"email": email})
 	if err != nil {
 		log.Println("Error checking identity existence:", err)
-		return false, errors.New("error checking identity existence")
+		return false, newDBError("error checking identity existence", err)
 	}
 	return count > 0, nil
 }
 
-func (r *MongoIdentityRepo) DeleteIdentity(reqCtx *app.RequestContext) error {
+func (r *MongoIdentityRepo) DeleteIdentity(reqCtx *app.RequestContext, id bson.ObjectID) error {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
-	result, err := col.DeleteOne(ctx, bson.M{"_id": reqCtx.User.IdentityID})
-	if err != nil {
+	if _, err := col.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
 		log.Println("Error deleting identity:", err)
-		return errors.New("error deleting identity")
+		return newDBError("error deleting identity", err)
 	}
-	if result.DeletedCount == 0 {
-		return errors.New("no identity found to delete")
+	reqCtx.AfterCommit(func() { db.DeleteCacheIdentity(id) })
+	return nil
+}
+
+// MarkIdentityDeleted soft deletes the identity: it can no longer sign in and
+// is purged after the grace period.
+func (r *MongoIdentityRepo) MarkIdentityDeleted(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"deleted_at": now,
+		"deleted_by": reqCtx.User.IdentityID,
+		"updated_at": now,
+		"updated_by": reqCtx.User.IdentityID,
+	}}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": false}}, update); err != nil {
+		log.Println("Error marking identity deleted:", err)
+		return newDBError("error deleting identity", err)
 	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheIdentity(id) })
 	return nil
 }
+
+// RestoreIdentity reverts MarkIdentityDeleted. It returns false when the
+// identity is not pending deletion.
+func (r *MongoIdentityRepo) RestoreIdentity(reqCtx *app.RequestContext, id bson.ObjectID) (bool, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"updated_at": time.Now(),
+			"updated_by": reqCtx.User.IdentityID,
+		},
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+	}
+	result, err := col.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}, update)
+	if err != nil {
+		log.Println("Error restoring identity:", err)
+		return false, newDBError("error restoring identity", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// GetIdentitiesDeletedBefore returns the soft deleted identities whose
+// deletion was requested before the time.
+func (r *MongoIdentityRepo) GetIdentitiesDeletedBefore(reqCtx *app.RequestContext, before time.Time) ([]*model.Identity, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	cursor, err := col.Find(ctx, bson.M{"deleted_at": bson.M{"$lte": before}})
+	if err != nil {
+		log.Println("Error finding deleted identities:", err)
+		return nil, newDBError("error finding deleted identities", err)
+	}
+	defer cursor.Close(ctx)
+
+	identities := []*model.Identity{}
+	if err := cursor.All(ctx, &identities); err != nil {
+		log.Println("Error decoding deleted identities:", err)
+		return nil, newDBError("error finding deleted identities", err)
+	}
+	return identities, nil
+}
@@ -0,0 +1,198 @@
+//go:generate mockgen -source=invitation_repo.go -destination=../mocks/mock_invitation_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type InvitationRepository interface {
+	IBaseRepo
+	EnsureIndexes() error
+	CreateInvitation(reqCtx *app.RequestContext, email string, role model.UserRole, tokenHash string, expiresAt time.Time) (*model.Invitation, error)
+	GetInvitationByTokenHash(reqCtx *app.RequestContext, tokenHash string) (*model.Invitation, error)
+	ListInvitations(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.Invitation, error)
+	MarkAccepted(reqCtx *app.RequestContext, id bson.ObjectID, userID bson.ObjectID) (bool, error)
+	RevokeInvitation(reqCtx *app.RequestContext, orgID bson.ObjectID, id bson.ObjectID) (bool, error)
+	RevokePendingInvitations(reqCtx *app.RequestContext, orgID bson.ObjectID, email string) error
+	DeleteInvitationsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error
+}
+
+type MongoInvitationRepo struct {
+	BaseRepo
+}
+
+func NewInvitationRepository(appDB *db.AppDB) *MongoInvitationRepo {
+	return &MongoInvitationRepo{
+		BaseRepo: BaseRepo{
+			cname: "invitations",
+			appDB: appDB,
+		},
+	}
+}
+
+func (r *MongoInvitationRepo) EnsureIndexes() error {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "email", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("failed to create invitation indexes: %v", err)
+		return newDBError("failed to create invitation indexes", err)
+	}
+	return nil
+}
+
+func (r *MongoInvitationRepo) CreateInvitation(reqCtx *app.RequestContext, email string, role model.UserRole, tokenHash string, expiresAt time.Time) (*model.Invitation, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	invitation := &model.Invitation{
+		Base: model.Base{
+			ID:        bson.NewObjectID(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			CreatedBy: reqCtx.User.IdentityID,
+			UpdatedBy: reqCtx.User.IdentityID,
+		},
+		OrganizationID: reqCtx.Org.ID,
+		Email:          email,
+		Role:           role,
+		TokenHash:      tokenHash,
+		Status:         model.InvitationPending,
+		ExpiresAt:      expiresAt,
+	}
+	if _, err := col.InsertOne(ctx, invitation); err != nil {
+		log.Printf("failed to create invitation: %v", err)
+		return nil, newDBError("failed to create invitation", err)
+	}
+	return invitation, nil
+}
+
+func (r *MongoInvitationRepo) GetInvitationByTokenHash(reqCtx *app.RequestContext, tokenHash string) (*model.Invitation, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	var invitation model.Invitation
+	if err := col.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&invitation); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to get invitation: %v", err)
+		return nil, newDBError("failed to get invitation", err)
+	}
+	return &invitation, nil
+}
+
+func (r *MongoInvitationRepo) ListInvitations(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.Invitation, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := col.Find(ctx, bson.M{"org_id": orgID}, opts)
+	if err != nil {
+		log.Printf("failed to list invitations: %v", err)
+		return nil, newDBError("failed to list invitations", err)
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []*model.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		log.Printf("failed to decode invitations: %v", err)
+		return nil, newDBError("failed to list invitations", err)
+	}
+	return invitations, nil
+}
+
+// MarkAccepted accepts a pending invitation. It returns false when the
+// invitation was accepted or revoked in the meantime.
+func (r *MongoInvitationRepo) MarkAccepted(reqCtx *app.RequestContext, id bson.ObjectID, userID bson.ObjectID) (bool, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	result, err := col.UpdateOne(ctx, bson.M{"_id": id, "status": model.InvitationPending}, bson.M{"$set": bson.M{
+		"status":      model.InvitationAccepted,
+		"accepted_at": now,
+		"accepted_by": userID,
+		"updated_at":  now,
+	}})
+	if err != nil {
+		log.Printf("failed to accept invitation: %v", err)
+		return false, newDBError("failed to accept invitation", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// RevokeInvitation revokes a pending invitation of the organization. It
+// returns false when no such invitation exists.
+func (r *MongoInvitationRepo) RevokeInvitation(reqCtx *app.RequestContext, orgID bson.ObjectID, id bson.ObjectID) (bool, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "org_id": orgID, "status": model.InvitationPending}
+	result, err := col.UpdateOne(ctx, filter, revokeInvitationUpdate(reqCtx))
+	if err != nil {
+		log.Printf("failed to revoke invitation: %v", err)
+		return false, newDBError("failed to revoke invitation", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// RevokePendingInvitations revokes the earlier invitations of the email so that
+// only the newest one can be accepted.
+func (r *MongoInvitationRepo) RevokePendingInvitations(reqCtx *app.RequestContext, orgID bson.ObjectID, email string) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{"org_id": orgID, "email": email, "status": model.InvitationPending}
+	if _, err := col.UpdateMany(ctx, filter, revokeInvitationUpdate(reqCtx)); err != nil {
+		log.Printf("failed to revoke pending invitations: %v", err)
+		return newDBError("failed to revoke pending invitations", err)
+	}
+	return nil
+}
+
+func revokeInvitationUpdate(reqCtx *app.RequestContext) bson.M {
+	return bson.M{"$set": bson.M{
+		"status":     model.InvitationRevoked,
+		"updated_at": time.Now(),
+		"updated_by": reqCtx.User.IdentityID,
+	}}
+}
+
+func (r *MongoInvitationRepo) DeleteInvitationsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteMany(ctx, bson.M{"org_id": orgID}); err != nil {
+		log.Printf("failed to delete invitations: %v", err)
+		return newDBError("failed to delete invitations", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+//go:generate mockgen -source=invoice_repo.go -destination=../mocks/mock_invoice_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type InvoiceRepository interface {
+	IBaseRepo
+	UpsertInvoice(reqCtx *app.RequestContext, invoice *model.SyncInvoice) (*model.Invoice, error)
+	ListInvoices(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.Invoice, error)
+}
+
+type MongoInvoiceRepo struct {
+	BaseRepo
+}
+
+func NewInvoiceRepository(appDB *db.AppDB) *MongoInvoiceRepo {
+	return &MongoInvoiceRepo{
+		BaseRepo: BaseRepo{
+			cname: "invoices",
+			appDB: appDB,
+		},
+	}
+}
+
+func (r *MongoInvoiceRepo) UpsertInvoice(reqCtx *app.RequestContext, invoice *model.SyncInvoice) (*model.Invoice, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	set := bson.M{
+		"organization_id":      invoice.OrganizationID,
+		"invoice_number":       invoice.InvoiceNumber,
+		"total_amount":         invoice.TotalAmount,
+		"billing_period_start": invoice.BillingPeriodStart,
+		"billing_period_end":   invoice.BillingPeriodEnd,
+		"pdf_url":              invoice.PDF_URL,
+		"status":               invoice.Status,
+		"updated_at":           now,
+		"updated_by":           reqCtx.User.IdentityID,
+	}
+	if !invoice.SubscriptionID.IsZero() {
+		set["subscription_id"] = invoice.SubscriptionID
+	}
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"_id":        bson.NewObjectID(),
+			"created_at": now,
+			"created_by": reqCtx.User.IdentityID,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var result model.Invoice
+	err := col.FindOneAndUpdate(ctx, bson.M{"stripe_invoice_id": invoice.StripeInvoiceID}, update, opts).Decode(&result)
+	if err != nil {
+		log.Printf("error upserting invoice %s: %v", invoice.StripeInvoiceID, err)
+		return nil, newDBError("failed to save invoice", err)
+	}
+	return &result, nil
+}
+
+func (r *MongoInvoiceRepo) ListInvoices(reqCtx *app.RequestContext, orgID bson.ObjectID) ([]*model.Invoice, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "billing_period_start", Value: -1}})
+	cursor, err := col.Find(ctx, bson.M{"organization_id": orgID}, opts)
+	if err != nil {
+		log.Printf("error finding invoices: %v", err)
+		return nil, newDBError("failed to get invoices", err)
+	}
+	defer cursor.Close(ctx)
+
+	invoices := []*model.Invoice{}
+	if err := cursor.All(ctx, &invoices); err != nil {
+		log.Printf("error decoding invoices: %v", err)
+		return nil, newDBError("failed to decode invoices", err)
+	}
+	return invoices, nil
+}
@@ -11,21 +11,33 @@
  For more details, please refer to the LICENSE file in the root directory of this project.
 */
 
-//go:generate mockgen -source=category_repo.go -destination=../mocks/mock_category_repo.go -package=mocks -copyright_file=../../copy_right.txt
+//go:generate mockgen -source=meter_event_repo.go -destination=../mocks/mock_meter_event_repo.go -package=mocks -copyright_file=../../copy_right.txt
 package repo
 
 import (
+	"errors"
+	"log"
 	"time"
 
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/db"
 	"github.com/gaeaglobal/exto/server/model"
-	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type MeterEventRepository interface {
 	IBaseRepo
+	EnsureIndexes() error
 	CreateMeterEvent(reqCtx *app.RequestContext, name string, value int, stripeCustomerID string) (*model.MeterEvent, error)
+	ClaimNextDue(reqCtx *app.RequestContext, now time.Time, lease time.Duration) (*model.MeterEvent, error)
+	MarkSent(reqCtx *app.RequestContext, id bson.ObjectID) error
+	MarkRetry(reqCtx *app.RequestContext, id bson.ObjectID, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(reqCtx *app.RequestContext, id bson.ObjectID, lastError string) error
+	SumMeterEvents(reqCtx *app.RequestContext, orgID bson.ObjectID, start time.Time, end time.Time) ([]*model.MeterEventTotal, error)
+	DeleteMeterEventsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error
 }
 
 type MongoMeterEventRepo struct {
@@ -41,22 +53,171 @@ func NewMeterEventRepository(appDB *db.AppDB) *MongoMeterEventRepo {
 	}
 }
 
-func (r *MongoMeterEventRepo) CreateMeterEvent(reqCtx *app.RequestContext, name string, value int, stripeCustomerID string) (*model.MeterEvent, error) {
+func (r *MongoMeterEventRepo) EnsureIndexes() error {
 	col := r.GetCollection()
 	ctx, cancel := db.GetDBContext()
 	defer cancel()
 
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "timestamp", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("failed to create meter event indexes: %v", err)
+		return newDBError("failed to create meter event indexes", err)
+	}
+	return nil
+}
+
+// CreateMeterEvent adds a pending event to the outbox. It is delivered to
+// Stripe by the meter dispatcher.
+func (r *MongoMeterEventRepo) CreateMeterEvent(reqCtx *app.RequestContext, name string, value int, stripeCustomerID string) (*model.MeterEvent, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	id := bson.NewObjectID()
 	meterEvent := &model.MeterEvent{
 		Base: model.Base{
-			ID:        bson.NewObjectID(),
-			CreatedAt: time.Now(),
+			ID:        id,
+			CreatedAt: now,
+			UpdatedAt: now,
 			CreatedBy: reqCtx.User.IdentityID,
+			UpdatedBy: reqCtx.User.IdentityID,
 		},
 		EventName:        name,
 		EventValue:       value,
 		StripeCustomerID: stripeCustomerID,
 		OrganizationID:   reqCtx.Org.ID,
+		Identifier:       id.Hex(),
+		Timestamp:        now,
+		Status:           model.MeterEventPending,
+		NextAttemptAt:    now,
+	}
+	if _, err := col.InsertOne(ctx, meterEvent); err != nil {
+		log.Printf("failed to create meter event: %v", err)
+		return nil, newDBError("failed to create meter event", err)
+	}
+	return meterEvent, nil
+}
+
+// ClaimNextDue leases the oldest pending event that is due for delivery. The
+// lease pushes its next attempt out so that concurrent dispatchers skip it.
+// It returns nil when no event is due.
+func (r *MongoMeterEventRepo) ClaimNextDue(reqCtx *app.RequestContext, now time.Time, lease time.Duration) (*model.MeterEvent, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{
+		"status":          model.MeterEventPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"next_attempt_at": now.Add(lease), "updated_at": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var meterEvent model.MeterEvent
+	if err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&meterEvent); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to claim meter event: %v", err)
+		return nil, newDBError("failed to claim meter event", err)
+	}
+	return &meterEvent, nil
+}
+
+func (r *MongoMeterEventRepo) MarkSent(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	now := time.Now()
+	return r.updateMeterEvent(id, bson.M{
+		"status":     model.MeterEventSent,
+		"sent_at":    now,
+		"last_error": "",
+		"updated_at": now,
+	})
+}
+
+func (r *MongoMeterEventRepo) MarkRetry(reqCtx *app.RequestContext, id bson.ObjectID, nextAttemptAt time.Time, lastError string) error {
+	return r.updateMeterEvent(id, bson.M{
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+		"updated_at":      time.Now(),
+	})
+}
+
+func (r *MongoMeterEventRepo) MarkFailed(reqCtx *app.RequestContext, id bson.ObjectID, lastError string) error {
+	return r.updateMeterEvent(id, bson.M{
+		"status":     model.MeterEventFailed,
+		"last_error": lastError,
+		"updated_at": time.Now(),
+	})
+}
+
+func (r *MongoMeterEventRepo) updateMeterEvent(id bson.ObjectID, set bson.M) error {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		log.Printf("failed to update meter event %s: %v", id.Hex(), err)
+		return newDBError("failed to update meter event", err)
+	}
+	return nil
+}
+
+// SumMeterEvents totals the organization's events with a timestamp in
+// [start, end), grouped by event name and status.
+func (r *MongoMeterEventRepo) SumMeterEvents(reqCtx *app.RequestContext, orgID bson.ObjectID, start time.Time, end time.Time) ([]*model.MeterEventTotal, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"org_id":    orgID,
+			"timestamp": bson.M{"$gte": start, "$lt": end},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"event_name": "$event_name", "status": "$status"},
+			"value": bson.M{"$sum": "$event_value"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":        0,
+			"event_name": "$_id.event_name",
+			"status":     "$_id.status",
+			"value":      1,
+		}}},
+	}
+	cursor, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Printf("failed to aggregate meter events: %v", err)
+		return nil, newDBError("failed to sum meter events", err)
+	}
+	defer cursor.Close(ctx)
+
+	totals := []*model.MeterEventTotal{}
+	if err := cursor.All(ctx, &totals); err != nil {
+		log.Printf("failed to decode meter event totals: %v", err)
+		return nil, newDBError("failed to sum meter events", err)
+	}
+	return totals, nil
+}
+
+// DeleteMeterEventsByOrg removes the meter events of the organization, sent or not.
+func (r *MongoMeterEventRepo) DeleteMeterEventsByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteMany(ctx, bson.M{"org_id": orgID}); err != nil {
+		log.Printf("failed to delete meter events: %v", err)
+		return newDBError("failed to delete meter events", err)
 	}
-	_, err := col.InsertOne(ctx, meterEvent)
-	return meterEvent, err
+	return nil
 }
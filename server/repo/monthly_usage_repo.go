@@ -0,0 +1,159 @@
+//go:generate mockgen -source=monthly_usage_repo.go -destination=../mocks/mock_monthly_usage_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type MonthlyUsageRepository interface {
+	IBaseRepo
+	EnsureIndexes() error
+	GetUsage(reqCtx *app.RequestContext, eventName string, year int, month int) (int, error)
+	Reserve(reqCtx *app.RequestContext, eventName string, year int, month int, limit int) (*model.MonthlyUsageEvent, error)
+	Release(reqCtx *app.RequestContext, eventName string, year int, month int) error
+	DeleteUsageByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error
+}
+
+type MongoMonthlyUsageRepo struct {
+	BaseRepo
+}
+
+func NewMonthlyUsageRepository(appDB *db.AppDB) *MongoMonthlyUsageRepo {
+	return &MongoMonthlyUsageRepo{
+		BaseRepo: BaseRepo{
+			cname: "monthly_usage_events",
+			appDB: appDB,
+		},
+	}
+}
+
+// EnsureIndexes creates the unique index that keeps a single counter document per
+// organization, event and month. Reserve relies on it to stay atomic.
+func (r *MongoMonthlyUsageRepo) EnsureIndexes() error {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "event_name", Value: 1}, {Key: "year", Value: 1}, {Key: "month", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("failed to create monthly usage index: %v", err)
+		return newDBError("failed to create monthly usage index", err)
+	}
+	return nil
+}
+
+func (r *MongoMonthlyUsageRepo) GetUsage(reqCtx *app.RequestContext, eventName string, year int, month int) (int, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	var usage model.MonthlyUsageEvent
+	err := col.FindOne(ctx, r.periodFilter(reqCtx, eventName, year, month)).Decode(&usage)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		log.Printf("failed to get monthly usage: %v", err)
+		return 0, newDBError("failed to get monthly usage", err)
+	}
+	return usage.Quantity, nil
+}
+
+// Reserve increments the counter for the period only while it is below limit.
+// It returns nil when the limit has already been reached.
+func (r *MongoMonthlyUsageRepo) Reserve(reqCtx *app.RequestContext, eventName string, year int, month int, limit int) (*model.MonthlyUsageEvent, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := r.periodFilter(reqCtx, eventName, year, month)
+	now := time.Now()
+
+	// Make sure the counter exists so the conditional increment below never has to upsert.
+	_, err := col.UpdateOne(ctx, filter, bson.M{
+		"$setOnInsert": bson.M{
+			"_id":        bson.NewObjectID(),
+			"created_at": now,
+			"created_by": reqCtx.User.IdentityID,
+			"quantity":   0,
+		},
+	}, options.UpdateOne().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		log.Printf("failed to initialize monthly usage: %v", err)
+		return nil, newDBError("failed to reserve usage", err)
+	}
+
+	reserveFilter := r.periodFilter(reqCtx, eventName, year, month)
+	reserveFilter["quantity"] = bson.M{"$lt": limit}
+	update := bson.M{
+		"$inc": bson.M{"quantity": 1},
+		"$set": bson.M{"updated_at": now, "updated_by": reqCtx.User.IdentityID},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var usage model.MonthlyUsageEvent
+	err = col.FindOneAndUpdate(ctx, reserveFilter, update, opts).Decode(&usage)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to reserve monthly usage: %v", err)
+		return nil, newDBError("failed to reserve usage", err)
+	}
+	return &usage, nil
+}
+
+// Release gives back a reservation made by Reserve.
+func (r *MongoMonthlyUsageRepo) Release(reqCtx *app.RequestContext, eventName string, year int, month int) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := r.periodFilter(reqCtx, eventName, year, month)
+	filter["quantity"] = bson.M{"$gt": 0}
+	update := bson.M{
+		"$inc": bson.M{"quantity": -1},
+		"$set": bson.M{"updated_at": time.Now(), "updated_by": reqCtx.User.IdentityID},
+	}
+	if _, err := col.UpdateOne(ctx, filter, update); err != nil {
+		log.Printf("failed to release monthly usage: %v", err)
+		return newDBError("failed to release usage", err)
+	}
+	return nil
+}
+
+func (r *MongoMonthlyUsageRepo) periodFilter(reqCtx *app.RequestContext, eventName string, year int, month int) bson.M {
+	return bson.M{
+		"organization_id": reqCtx.Org.ID,
+		"event_name":      eventName,
+		"year":            year,
+		"month":           month,
+	}
+}
+
+// DeleteUsageByOrg removes the usage counters of the organization.
+func (r *MongoMonthlyUsageRepo) DeleteUsageByOrg(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteMany(ctx, bson.M{"organization_id": orgID}); err != nil {
+		log.Printf("failed to delete monthly usage: %v", err)
+		return newDBError("failed to delete monthly usage", err)
+	}
+	return nil
+}
@@ -17,7 +17,6 @@ package repo
 
 import (
 	"errors"
-	"fmt"
 	"log"
 	"time"
 
@@ -29,17 +28,30 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// ErrSlugTaken is returned by CreateOrganization when another organization
+// has the slug.
+var ErrSlugTaken = errors.New("organization slug is already taken")
+
 type OrganizationRepo interface {
 	IBaseRepo
+	EnsureIndexes() error
 	CreateOrganization(reqCtx *app.RequestContext, org *model.CreateOrganization, createdByIdentity bson.ObjectID) (*model.Organization, error)
 	GetOrganizationByID(reqCtx *app.RequestContext, id bson.ObjectID) (*model.Organization, error)
 	GetOrganizations(reqCtx *app.RequestContext, filter bson.M) ([]*model.Organization, error)
 	UpdateOrganization(reqCtx *app.RequestContext, id bson.ObjectID, org *model.UpdateOrganization) (*model.Organization, error)
 	ListOrganizations(reqCtx *app.RequestContext, pageReq *app.PageRequest) (*app.PageResponse[*model.Organization], error)
 	IsOrganizationExists(reqCtx *app.RequestContext, name string) (bool, error)
+	IsSlugTaken(reqCtx *app.RequestContext, slug string) (bool, error)
 	GetOrganizationCount(reqCtx *app.RequestContext) (int64, error)
 	GenerateNextScanCode(reqCtx *app.RequestContext) (string, error)
-	DeleteOrganizationByOwner(reqCtx *app.RequestContext) ([]*model.Organization, error)
+	MarkOrganizationsDeleted(reqCtx *app.RequestContext, ids []bson.ObjectID) error
+	RestoreOrganizations(reqCtx *app.RequestContext, ids []bson.ObjectID) error
+	DropOrganizationDatabase(reqCtx *app.RequestContext, slug string) error
+	DeleteOrganization(reqCtx *app.RequestContext, id bson.ObjectID) error
+	SetExtractionCacheDisabled(reqCtx *app.RequestContext, id bson.ObjectID, disabled bool) (*model.Organization, error)
+	SetMicrosoftTenantIDs(reqCtx *app.RequestContext, id bson.ObjectID, tenantIDs []string) (*model.Organization, error)
+	SetSSOConfig(reqCtx *app.RequestContext, id bson.ObjectID, sso *model.SSOConfig) (*model.Organization, error)
+	UpdateOrganizationProfile(reqCtx *app.RequestContext, id bson.ObjectID, name string, settings *model.OrganizationSettings) (*model.Organization, error)
 }
 
 type MongoOrganizationRepo struct {
@@ -55,11 +67,37 @@ func NewOrganizationRepository(appDB *db.AppDB) *MongoOrganizationRepo {
 	}
 }
 
-func (r *MongoOrganizationRepo) CreateOrganization(reqCtx *app.RequestContext, org *model.CreateOrganization, createdByIdentity bson.ObjectID) (*model.Organization, error) {
+// EnsureIndexes makes the slug of an organization unique, so that no two
+// organizations share a database, and its SSO issuer and client ID, so that
+// ID tokens are routed to exactly one organization.
+func (r *MongoOrganizationRepo) EnsureIndexes() error {
 	col := r.GetCollection()
 	ctx, cancel := db.GetDBContext()
 	defer cancel()
 
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "slug", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "sso.issuer", Value: 1}, {Key: "sso.client_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"sso.issuer": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		log.Printf("failed to create organization indexes: %v", err)
+		return newDBError("failed to create organization indexes", err)
+	}
+	return nil
+}
+
+func (r *MongoOrganizationRepo) CreateOrganization(reqCtx *app.RequestContext, org *model.CreateOrganization, createdByIdentity bson.ObjectID) (*model.Organization, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
 	newOrg := model.Organization{
 		Base: model.Base{
 			ID:        org.ID,
@@ -76,9 +114,12 @@ func (r *MongoOrganizationRepo) CreateOrganization(reqCtx *app.RequestContext, o
 	}
 
 	result, err := col.InsertOne(ctx, newOrg)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrSlugTaken
+	}
 	if err != nil {
 		log.Println("Error creating organization:", err)
-		return nil, errors.New("error creating organization")
+		return nil, newDBError("error creating organization", err)
 	}
 	if !result.Acknowledged {
 		return nil, errors.New("error creating organization")
@@ -88,7 +129,7 @@ func (r *MongoOrganizationRepo) CreateOrganization(reqCtx *app.RequestContext, o
 
 func (r *MongoOrganizationRepo) GetOrganizationByID(reqCtx *app.RequestContext, id bson.ObjectID) (*model.Organization, error) {
 	var col = r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	var org model.Organization
 	err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&org)
@@ -97,14 +138,14 @@ func (r *MongoOrganizationRepo) GetOrganizationByID(reqCtx *app.RequestContext,
 			return nil, errors.New("organization not found")
 		}
 		log.Printf("failed to get organization by id: %v", err)
-		return nil, errors.New("failed to get organization")
+		return nil, newDBError("failed to get organization", err)
 	}
 	return &org, nil
 }
 
 func (r *MongoOrganizationRepo) UpdateOrganization(reqCtx *app.RequestContext, id bson.ObjectID, org *model.UpdateOrganization) (*model.Organization, error) {
 	var col = r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	update := &model.MongoUpdateOrganization{
@@ -122,12 +163,12 @@ func (r *MongoOrganizationRepo) UpdateOrganization(reqCtx *app.RequestContext, i
 	// bsonBytes, err := bson.Marshal(update)
 	// if err != nil {
 	// 	log.Printf("failed to marshal organization update: %v", err)
-	// 	return nil, errors.New("failed to update organization")
+	// 	return nil, newDBError("failed to update organization", err)
 	// }
 	// var updateMap bson.M
 	// if err := bson.Unmarshal(bsonBytes, &updateMap); err != nil {
 	// 	log.Printf("failed to unmarshal organization update: %v", err)
-	// 	return nil, errors.New("failed to update organization")
+	// 	return nil, newDBError("failed to update organization", err)
 	// }
 	// delete(updateMap, "_id") // Remove _id field
 	// log.Printf("Update Map: %+v", updateMap)
@@ -135,14 +176,15 @@ func (r *MongoOrganizationRepo) UpdateOrganization(reqCtx *app.RequestContext, i
 	_, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
 	if err != nil {
 		log.Printf("failed to update organization: %v", err)
-		return nil, errors.New("failed to update organization")
+		return nil, newDBError("failed to update organization", err)
 	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheOrg(id) })
 	return r.GetOrganizationByID(reqCtx, id)
 }
 
 func (r *MongoOrganizationRepo) ListOrganizations(reqCtx *app.RequestContext, pageReq *app.PageRequest) (*app.PageResponse[*model.Organization], error) {
 	var col = r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	// get total count and find with pagination
@@ -183,11 +225,11 @@ func (r *MongoOrganizationRepo) ListOrganizations(reqCtx *app.RequestContext, pa
 
 	if findErr != nil {
 		log.Printf("failed to list organizations: %v", findErr)
-		return nil, errors.New("failed to list organizations")
+		return nil, newDBError("failed to list organizations", findErr)
 	}
 	if countErr != nil {
 		log.Printf("failed to count organizations: %v", countErr)
-		return nil, errors.New("failed to count organizations")
+		return nil, newDBError("failed to count organizations", countErr)
 	}
 
 	if orgs == nil {
@@ -199,24 +241,36 @@ func (r *MongoOrganizationRepo) ListOrganizations(reqCtx *app.RequestContext, pa
 
 func (r *MongoOrganizationRepo) IsOrganizationExists(reqCtx *app.RequestContext, name string) (bool, error) {
 	var col = r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	count, err := col.CountDocuments(ctx, bson.M{"name": name})
 	if err != nil {
 		log.Printf("failed to check organization by name: %v", err)
-		return false, errors.New("failed to check organization by name")
+		return false, newDBError("failed to check organization by name", err)
+	}
+	return count > 0, nil
+}
+
+func (r *MongoOrganizationRepo) IsSlugTaken(reqCtx *app.RequestContext, slug string) (bool, error) {
+	var col = r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+	count, err := col.CountDocuments(ctx, bson.M{"slug": slug}, options.Count().SetLimit(1))
+	if err != nil {
+		log.Printf("failed to check organization slug: %v", err)
+		return false, newDBError("failed to check organization slug", err)
 	}
 	return count > 0, nil
 }
 
 func (r *MongoOrganizationRepo) GetOrganizationCount(reqCtx *app.RequestContext) (int64, error) {
 	var col = r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	count, err := col.CountDocuments(ctx, bson.M{})
 	if err != nil {
 		log.Printf("failed to count organizations: %v", err)
-		return 0, errors.New("failed to count organizations")
+		return 0, newDBError("failed to count organizations", err)
 	}
 	return count, nil
 }
@@ -228,61 +282,180 @@ func (r *MongoOrganizationRepo) GenerateNextScanCode(reqCtx *app.RequestContext)
 
 	// Set options for the update.
 	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
-	var result bson.M
+	var result model.Organization
 	err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&result)
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("SCAN-%d", result["scan_counter"]), nil
+	return result.Settings.ScanCode(result.ScanCounter), nil
 }
 
 func (r *MongoOrganizationRepo) GetOrganizations(reqCtx *app.RequestContext, filter bson.M) ([]*model.Organization, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	cursor, err := col.Find(ctx, filter)
 	if err != nil {
 		log.Printf("error finding organizations: %v", err)
-		return nil, errors.New("failed to get organizations")
+		return nil, newDBError("failed to get organizations", err)
 	}
 	defer cursor.Close(ctx)
 
 	var orgs []*model.Organization
 	if err := cursor.All(ctx, &orgs); err != nil {
 		log.Printf("error decoding organizations: %v", err)
-		return nil, errors.New("failed to decode organizations")
+		return nil, newDBError("failed to decode organizations", err)
 	}
 
 	return orgs, nil
 }
 
-func (r *MongoOrganizationRepo) DeleteOrganizationByOwner(reqCtx *app.RequestContext) ([]*model.Organization, error) {
+func (r *MongoOrganizationRepo) SetExtractionCacheDisabled(reqCtx *app.RequestContext, id bson.ObjectID, disabled bool) (*model.Organization, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
-	filter := bson.M{"owner_id": reqCtx.User.IdentityID}
-	orgs, err := r.GetOrganizations(reqCtx, filter)
-	if err != nil {
-		log.Printf("error getting organizations for deletion: %v", err)
-		return nil, errors.New("failed to get organizations for deletion")
+
+	update := bson.M{"$set": bson.M{
+		"extraction_cache_disabled": disabled,
+		"updated_at":                time.Now(),
+		"updated_by":                reqCtx.User.IdentityID,
+	}}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Printf("failed to update extraction cache setting: %v", err)
+		return nil, newDBError("failed to update organization", err)
 	}
+	return r.GetOrganizationByID(reqCtx, id)
+}
 
-	for _, org := range orgs {
-		// Drop database
-		if err := r.appDB.GetOrgDatabase(org.Slug).Drop(ctx); err != nil {
-			log.Printf("error dropping database for organization %s: %v", org.Slug, err)
-		}
+func (r *MongoOrganizationRepo) SetMicrosoftTenantIDs(reqCtx *app.RequestContext, id bson.ObjectID, tenantIDs []string) (*model.Organization, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"microsoft_tenant_ids": tenantIDs,
+		"updated_at":           time.Now(),
+		"updated_by":           reqCtx.User.IdentityID,
+	}}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Printf("failed to update microsoft tenants: %v", err)
+		return nil, newDBError("failed to update organization", err)
+	}
+	return r.GetOrganizationByID(reqCtx, id)
+}
+
+func (r *MongoOrganizationRepo) SetSSOConfig(reqCtx *app.RequestContext, id bson.ObjectID, sso *model.SSOConfig) (*model.Organization, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"sso":        sso,
+		"updated_at": time.Now(),
+		"updated_by": reqCtx.User.IdentityID,
+	}}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Printf("failed to update sso config: %v", err)
+		return nil, newDBError("failed to update organization", err)
 	}
+	return r.GetOrganizationByID(reqCtx, id)
+}
 
-	_, err = col.DeleteMany(ctx, filter)
-	if err != nil {
-		log.Printf("error deleting organizations: %v", err)
-		return nil, errors.New("failed to delete organizations")
+func (r *MongoOrganizationRepo) UpdateOrganizationProfile(reqCtx *app.RequestContext, id bson.ObjectID, name string, settings *model.OrganizationSettings) (*model.Organization, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"name":       name,
+		"settings":   settings,
+		"updated_at": time.Now(),
+		"updated_by": reqCtx.User.IdentityID,
+	}}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Printf("failed to update organization profile: %v", err)
+		return nil, newDBError("failed to update organization", err)
 	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheOrg(id) })
+	return r.GetOrganizationByID(reqCtx, id)
+}
 
-	return orgs, nil
+// MarkOrganizationsDeleted soft deletes the organizations: they are
+// deactivated at once and purged after the grace period.
+func (r *MongoOrganizationRepo) MarkOrganizationsDeleted(reqCtx *app.RequestContext, ids []bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": bson.M{"$in": ids}, "deleted_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{
+		"is_active":  false,
+		"deleted_at": now,
+		"deleted_by": reqCtx.User.IdentityID,
+		"updated_at": now,
+		"updated_by": reqCtx.User.IdentityID,
+	}}
+	if _, err := col.UpdateMany(ctx, filter, update); err != nil {
+		log.Printf("failed to mark organizations deleted: %v", err)
+		return newDBError("failed to delete organizations", err)
+	}
+	for _, id := range ids {
+		reqCtx.AfterCommit(func() { db.DeleteCacheOrg(id) })
+	}
+	return nil
+}
+
+// RestoreOrganizations reactivates soft deleted organizations that have not
+// been purged yet.
+func (r *MongoOrganizationRepo) RestoreOrganizations(reqCtx *app.RequestContext, ids []bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{"_id": bson.M{"$in": ids}, "deleted_at": bson.M{"$exists": true}}
+	update := bson.M{
+		"$set": bson.M{
+			"is_active":  true,
+			"updated_at": time.Now(),
+			"updated_by": reqCtx.User.IdentityID,
+		},
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+	}
+	if _, err := col.UpdateMany(ctx, filter, update); err != nil {
+		log.Printf("failed to restore organizations: %v", err)
+		return newDBError("failed to restore organizations", err)
+	}
+	for _, id := range ids {
+		reqCtx.AfterCommit(func() { db.DeleteCacheOrg(id) })
+	}
+	return nil
+}
+
+func (r *MongoOrganizationRepo) DropOrganizationDatabase(reqCtx *app.RequestContext, slug string) error {
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if err := r.appDB.GetOrgDatabase(slug).Drop(ctx); err != nil {
+		log.Printf("error dropping database for organization %s: %v", slug, err)
+		return newDBError("failed to drop organization database", err)
+	}
+	return nil
+}
+
+func (r *MongoOrganizationRepo) DeleteOrganization(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		log.Printf("error deleting organization: %v", err)
+		return newDBError("failed to delete organization", err)
+	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheOrg(id) })
+	return nil
 }
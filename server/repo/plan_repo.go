@@ -0,0 +1,236 @@
+//go:generate mockgen -source=plan_repo.go -destination=../mocks/mock_plan_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type PlanRepository interface {
+	IBaseRepo
+	GetPlanByID(reqCtx *app.RequestContext, id bson.ObjectID) (*model.Plan, error)
+	GetPlanByCode(reqCtx *app.RequestContext, code model.PlanCode) (*model.Plan, error)
+	GetPlanByStripePriceID(reqCtx *app.RequestContext, priceID string) (*model.Plan, error)
+	ListPlans(reqCtx *app.RequestContext, activeOnly bool) ([]*model.Plan, error)
+	IsPlanExists(reqCtx *app.RequestContext, code model.PlanCode) (bool, error)
+	CreatePlan(reqCtx *app.RequestContext, plan *model.CreatePlan) (*model.Plan, error)
+	UpdatePlan(reqCtx *app.RequestContext, id bson.ObjectID, plan *model.UpdatePlan) (*model.Plan, error)
+	EnsurePlan(reqCtx *app.RequestContext, plan *model.Plan) error
+}
+
+type MongoPlanRepo struct {
+	BaseRepo
+}
+
+func NewPlanRepository(appDB *db.AppDB) *MongoPlanRepo {
+	return &MongoPlanRepo{
+		BaseRepo: BaseRepo{
+			cname: "plans",
+			appDB: appDB,
+		},
+	}
+}
+
+// GetPlanByID returns the plan even when it is no longer active, so that
+// existing subscribers keep their allowance.
+func (r *MongoPlanRepo) GetPlanByID(reqCtx *app.RequestContext, id bson.ObjectID) (*model.Plan, error) {
+	plan, err := r.findPlan(bson.M{"_id": id})
+	if err == nil && plan == nil {
+		return nil, errors.New("plan not found")
+	}
+	return plan, err
+}
+
+func (r *MongoPlanRepo) GetPlanByCode(reqCtx *app.RequestContext, code model.PlanCode) (*model.Plan, error) {
+	plan, err := r.findPlan(bson.M{"code": code, "is_active": true})
+	if err == nil && plan == nil {
+		return nil, errors.New("plan not found")
+	}
+	return plan, err
+}
+
+// GetPlanByStripePriceID returns the plan billed with the price, or nil when there is none.
+func (r *MongoPlanRepo) GetPlanByStripePriceID(reqCtx *app.RequestContext, priceID string) (*model.Plan, error) {
+	return r.findPlan(bson.M{"stripe_price_id": priceID})
+}
+
+func (r *MongoPlanRepo) ListPlans(reqCtx *app.RequestContext, activeOnly bool) ([]*model.Plan, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{}
+	if activeOnly {
+		filter["is_active"] = true
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("failed to list plans: %v", err)
+		return nil, newDBError("failed to list plans", err)
+	}
+	defer cursor.Close(ctx)
+
+	plans := []*model.Plan{}
+	if err := cursor.All(ctx, &plans); err != nil {
+		log.Printf("failed to decode plans: %v", err)
+		return nil, newDBError("failed to decode plans", err)
+	}
+	return plans, nil
+}
+
+func (r *MongoPlanRepo) IsPlanExists(reqCtx *app.RequestContext, code model.PlanCode) (bool, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	count, err := col.CountDocuments(ctx, bson.M{"code": code})
+	if err != nil {
+		log.Printf("failed to check plan %s: %v", code, err)
+		return false, newDBError("failed to check plan", err)
+	}
+	return count > 0, nil
+}
+
+func (r *MongoPlanRepo) CreatePlan(reqCtx *app.RequestContext, plan *model.CreatePlan) (*model.Plan, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	newPlan := &model.Plan{
+		Base: model.Base{
+			ID:        bson.NewObjectID(),
+			CreatedAt: time.Now(),
+			CreatedBy: reqCtx.User.IdentityID,
+		},
+		Code:              plan.Code,
+		Name:              plan.Name,
+		BillingCycle:      plan.BillingCycle,
+		StripePriceID:     plan.StripePriceID,
+		StripeCouponID:    plan.StripeCouponID,
+		MonthlyScanLimit:  plan.MonthlyScanLimit,
+		OverageMeterEvent: plan.OverageMeterEvent,
+		TrialDays:         plan.TrialDays,
+		MaxUploadBytes:    plan.MaxUploadBytes,
+		MaxUploadPages:    plan.MaxUploadPages,
+		IsActive:          true,
+	}
+	if _, err := col.InsertOne(ctx, newPlan); err != nil {
+		log.Printf("failed to create plan %s: %v", plan.Code, err)
+		return nil, newDBError("failed to create plan", err)
+	}
+	return newPlan, nil
+}
+
+func (r *MongoPlanRepo) UpdatePlan(reqCtx *app.RequestContext, id bson.ObjectID, plan *model.UpdatePlan) (*model.Plan, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	set := bson.M{
+		"updated_at": time.Now(),
+		"updated_by": reqCtx.User.IdentityID,
+	}
+	if plan.Name != nil {
+		set["name"] = *plan.Name
+	}
+	if plan.BillingCycle != nil {
+		set["billing_cycle"] = *plan.BillingCycle
+	}
+	if plan.StripePriceID != nil {
+		set["stripe_price_id"] = *plan.StripePriceID
+	}
+	if plan.StripeCouponID != nil {
+		set["stripe_coupon_id"] = *plan.StripeCouponID
+	}
+	if plan.MonthlyScanLimit != nil {
+		set["monthly_scan_limit"] = *plan.MonthlyScanLimit
+	}
+	if plan.OverageMeterEvent != nil {
+		set["overage_meter_event"] = *plan.OverageMeterEvent
+	}
+	if plan.TrialDays != nil {
+		set["trial_days"] = *plan.TrialDays
+	}
+	if plan.MaxUploadBytes != nil {
+		set["max_upload_bytes"] = *plan.MaxUploadBytes
+	}
+	if plan.MaxUploadPages != nil {
+		set["max_upload_pages"] = *plan.MaxUploadPages
+	}
+	if plan.IsActive != nil {
+		set["is_active"] = *plan.IsActive
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated model.Plan
+	err := col.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": set}, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("plan not found")
+		}
+		log.Printf("failed to update plan %s: %v", id.Hex(), err)
+		return nil, newDBError("failed to update plan", err)
+	}
+	return &updated, nil
+}
+
+// EnsurePlan inserts the plan when no plan with the same code exists. Existing
+// plans are left untouched so that changes made through the admin API are preserved.
+func (r *MongoPlanRepo) EnsurePlan(reqCtx *app.RequestContext, plan *model.Plan) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":                 bson.NewObjectID(),
+			"created_at":          time.Now(),
+			"created_by":          reqCtx.User.IdentityID,
+			"name":                plan.Name,
+			"billing_cycle":       plan.BillingCycle,
+			"stripe_price_id":     plan.StripePriceID,
+			"stripe_coupon_id":    plan.StripeCouponID,
+			"monthly_scan_limit":  plan.MonthlyScanLimit,
+			"overage_meter_event": plan.OverageMeterEvent,
+			"trial_days":          plan.TrialDays,
+			"max_upload_bytes":    plan.MaxUploadBytes,
+			"max_upload_pages":    plan.MaxUploadPages,
+			"is_active":           true,
+		},
+	}
+	opts := options.UpdateOne().SetUpsert(true)
+	if _, err := col.UpdateOne(ctx, bson.M{"code": plan.Code}, update, opts); err != nil {
+		log.Printf("failed to ensure plan %s: %v", plan.Code, err)
+		return newDBError("failed to ensure plan", err)
+	}
+	return nil
+}
+
+// findPlan returns nil when no plan matches the filter.
+func (r *MongoPlanRepo) findPlan(filter bson.M) (*model.Plan, error) {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	var plan model.Plan
+	err := col.FindOne(ctx, filter).Decode(&plan)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to get plan: %v", err)
+		return nil, newDBError("failed to get plan", err)
+	}
+	return &plan, nil
+}
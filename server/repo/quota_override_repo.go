@@ -0,0 +1,110 @@
+//go:generate mockgen -source=quota_override_repo.go -destination=../mocks/mock_quota_override_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type QuotaOverrideRepository interface {
+	IBaseRepo
+	GetActiveOverride(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.QuotaOverride, error)
+	UpsertOverride(reqCtx *app.RequestContext, orgID bson.ObjectID, override *model.CreateQuotaOverride) (*model.QuotaOverride, error)
+	DeleteOverride(reqCtx *app.RequestContext, orgID bson.ObjectID) error
+}
+
+type MongoQuotaOverrideRepo struct {
+	BaseRepo
+}
+
+func NewQuotaOverrideRepository(appDB *db.AppDB) *MongoQuotaOverrideRepo {
+	return &MongoQuotaOverrideRepo{
+		BaseRepo: BaseRepo{
+			cname: "quota_overrides",
+			appDB: appDB,
+		},
+	}
+}
+
+// GetActiveOverride returns the unexpired override of the organization, or nil when there is none.
+func (r *MongoQuotaOverrideRepo) GetActiveOverride(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.QuotaOverride, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{
+		"organization_id": orgID,
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+	var override model.QuotaOverride
+	err := col.FindOne(ctx, filter).Decode(&override)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to get quota override: %v", err)
+		return nil, newDBError("failed to get quota override", err)
+	}
+	return &override, nil
+}
+
+func (r *MongoQuotaOverrideRepo) UpsertOverride(reqCtx *app.RequestContext, orgID bson.ObjectID, override *model.CreateQuotaOverride) (*model.QuotaOverride, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	set := bson.M{
+		"monthly_scan_limit": override.MonthlyScanLimit,
+		"reason":             override.Reason,
+		"updated_at":         now,
+		"updated_by":         reqCtx.User.IdentityID,
+	}
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"_id":        bson.NewObjectID(),
+			"created_at": now,
+			"created_by": reqCtx.User.IdentityID,
+		},
+	}
+	if override.ExpiresAt.IsZero() {
+		update["$unset"] = bson.M{"expires_at": ""}
+	} else {
+		set["expires_at"] = override.ExpiresAt
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var result model.QuotaOverride
+	err := col.FindOneAndUpdate(ctx, bson.M{"organization_id": orgID}, update, opts).Decode(&result)
+	if err != nil {
+		log.Printf("failed to upsert quota override: %v", err)
+		return nil, newDBError("failed to save quota override", err)
+	}
+	return &result, nil
+}
+
+func (r *MongoQuotaOverrideRepo) DeleteOverride(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteOne(ctx, bson.M{"organization_id": orgID}); err != nil {
+		log.Printf("failed to delete quota override: %v", err)
+		return newDBError("failed to delete quota override", err)
+	}
+	return nil
+}
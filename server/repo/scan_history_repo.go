@@ -25,6 +25,7 @@ import (
 	"github.com/gaeaglobal/exto/server/model"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 type ScanHistoryRepo interface {
@@ -32,6 +33,7 @@ type ScanHistoryRepo interface {
 	ListScanHistories(reqCtx *app.RequestContext, pageReq *app.PageRequest) (*app.PageResponse[*model.ScanHistory], error)
 	GetScanHistoryByID(reqCtx *app.RequestContext, scanHistoryID bson.ObjectID) (*model.ScanHistory, error)
 	CreateScanHistory(reqCtx *app.RequestContext, scanHistory *model.CreateScanHistoryRequest) (*model.ScanHistory, error)
+	GetCategoryDataIDsByBatches(reqCtx *app.RequestContext, batchIDs []bson.ObjectID) ([]bson.ObjectID, error)
 }
 
 type MongoScanHistoryRepo struct {
@@ -49,7 +51,7 @@ func NewScanHistoryRepository(appDB *db.AppDB) *MongoScanHistoryRepo {
 
 func (r *MongoScanHistoryRepo) GetScanHistoryByID(reqCtx *app.RequestContext, scanHistoryID bson.ObjectID) (*model.ScanHistory, error) {
 	col := r.GetCollection(reqCtx.Org.Slug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	var scanHistory model.ScanHistory
@@ -59,7 +61,7 @@ func (r *MongoScanHistoryRepo) GetScanHistoryByID(reqCtx *app.RequestContext, sc
 			return nil, nil
 		}
 		log.Printf("failed to get scan history by ID: %v", err)
-		return nil, errors.New("failed to get scan history")
+		return nil, newDBError("failed to get scan history", err)
 	}
 
 	return &scanHistory, nil
@@ -67,7 +69,7 @@ func (r *MongoScanHistoryRepo) GetScanHistoryByID(reqCtx *app.RequestContext, sc
 
 func (r *MongoScanHistoryRepo) ListScanHistories(reqCtx *app.RequestContext, pageReq *app.PageRequest) (*app.PageResponse[*model.ScanHistory], error) {
 	col := r.GetCollection(reqCtx.Org.Slug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	opts := pageReq.ToFindOptions()
@@ -107,11 +109,11 @@ func (r *MongoScanHistoryRepo) ListScanHistories(reqCtx *app.RequestContext, pag
 
 	if findErr != nil {
 		log.Printf("failed to list scan histories: %v", findErr)
-		return nil, errors.New("failed to list scan histories")
+		return nil, newDBError("failed to list scan histories", findErr)
 	}
 	if countErr != nil {
 		log.Printf("failed to count scan histories: %v", countErr)
-		return nil, errors.New("failed to count scan histories")
+		return nil, newDBError("failed to count scan histories", countErr)
 	}
 
 	if scans == nil {
@@ -123,7 +125,7 @@ func (r *MongoScanHistoryRepo) ListScanHistories(reqCtx *app.RequestContext, pag
 
 func (r *MongoScanHistoryRepo) CreateScanHistory(reqCtx *app.RequestContext, scanHistory *model.CreateScanHistoryRequest) (*model.ScanHistory, error) {
 	col := r.GetCollection(reqCtx.Org.Slug)
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	newScanHistory := &model.ScanHistory{
@@ -145,7 +147,7 @@ func (r *MongoScanHistoryRepo) CreateScanHistory(reqCtx *app.RequestContext, sca
 	result, err := col.InsertOne(ctx, newScanHistory)
 	if err != nil {
 		log.Printf("failed to create scan history: %v", err)
-		return nil, errors.New("failed to create scan history")
+		return nil, newDBError("failed to create scan history", err)
 	}
 
 	if !result.Acknowledged {
@@ -154,3 +156,30 @@ func (r *MongoScanHistoryRepo) CreateScanHistory(reqCtx *app.RequestContext, sca
 
 	return newScanHistory, nil
 }
+
+// GetCategoryDataIDsByBatches returns the category data records scanned in the
+// batches.
+func (r *MongoScanHistoryRepo) GetCategoryDataIDsByBatches(reqCtx *app.RequestContext, batchIDs []bson.ObjectID) ([]bson.ObjectID, error) {
+	col := r.GetCollection(reqCtx.Org.Slug)
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"category_data_id": 1})
+	cursor, err := col.Find(ctx, bson.M{"batch_id": bson.M{"$in": batchIDs}}, opts)
+	if err != nil {
+		log.Printf("failed to get scan histories by batches: %v", err)
+		return nil, newDBError("failed to get scan histories", err)
+	}
+	defer cursor.Close(ctx)
+
+	var scans []model.ScanHistory
+	if err := cursor.All(ctx, &scans); err != nil {
+		log.Printf("failed to decode scan histories by batches: %v", err)
+		return nil, newDBError("failed to get scan histories", err)
+	}
+	ids := make([]bson.ObjectID, 0, len(scans))
+	for _, scan := range scans {
+		ids = append(ids, scan.CategoryDataID)
+	}
+	return ids, nil
+}
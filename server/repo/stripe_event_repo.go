@@ -0,0 +1,92 @@
+//go:generate mockgen -source=stripe_event_repo.go -destination=../mocks/mock_stripe_event_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
+package repo
+
+import (
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+type StripeEventRepository interface {
+	IBaseRepo
+	EnsureIndexes() error
+	Claim(reqCtx *app.RequestContext, eventID string, eventType string) (bool, error)
+	Release(reqCtx *app.RequestContext, eventID string) error
+}
+
+type MongoStripeEventRepo struct {
+	BaseRepo
+}
+
+func NewStripeEventRepository(appDB *db.AppDB) *MongoStripeEventRepo {
+	return &MongoStripeEventRepo{
+		BaseRepo: BaseRepo{
+			cname: "stripe_events",
+			appDB: appDB,
+		},
+	}
+}
+
+func (r *MongoStripeEventRepo) EnsureIndexes() error {
+	col := r.GetCollection()
+	ctx, cancel := db.GetDBContext()
+	defer cancel()
+
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "event_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("failed to create stripe event index: %v", err)
+		return newDBError("failed to create stripe event index", err)
+	}
+	return nil
+}
+
+// Claim records the event as processed. It returns false when the event was
+// already claimed by an earlier delivery.
+func (r *MongoStripeEventRepo) Claim(reqCtx *app.RequestContext, eventID string, eventType string) (bool, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	event := &model.StripeEvent{
+		Base: model.Base{
+			ID:        bson.NewObjectID(),
+			CreatedAt: now,
+		},
+		EventID:     eventID,
+		Type:        eventType,
+		ProcessedAt: now,
+	}
+	if _, err := col.InsertOne(ctx, event); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		log.Printf("failed to record stripe event %s: %v", eventID, err)
+		return false, newDBError("failed to record stripe event", err)
+	}
+	return true, nil
+}
+
+// Release removes the claim so that a redelivery of the event is processed again.
+func (r *MongoStripeEventRepo) Release(reqCtx *app.RequestContext, eventID string) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteOne(ctx, bson.M{"event_id": eventID}); err != nil {
+		log.Printf("failed to release stripe event %s: %v", eventID, err)
+		return newDBError("failed to release stripe event", err)
+	}
+	return nil
+}
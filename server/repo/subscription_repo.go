@@ -1,3 +1,5 @@
+//go:generate mockgen -source=subscription_repo.go -destination=../mocks/mock_subscription_repo.go -package=mocks -copyright_file=../../copy_right.txt
+
 package repo
 
 import (
@@ -7,6 +9,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/db"
@@ -19,8 +22,20 @@ type SubscriptionRepository interface {
 	UpdateSubscription(reqCtx *app.RequestContext, subscriptionID bson.ObjectID, update *model.UpdateSubscription) (*model.Subscription, error)
 	GetMySubscription(reqCtx *app.RequestContext) (*model.Subscription, error)
 	GetFreeTrialInfo(reqCtx *app.RequestContext) (*model.GetFreeTrialInfo, error)
+	GetSubscriptionByStripeID(reqCtx *app.RequestContext, stripeSubID string) (*model.Subscription, error)
+	SyncStripeSubscription(reqCtx *app.RequestContext, sync *model.SyncSubscription) (*model.Subscription, error)
+	SyncPaymentMethod(reqCtx *app.RequestContext, paymentMethod *model.PaymentMethod) error
+	DetachPaymentMethod(reqCtx *app.RequestContext, stripePaymentMethodID string) error
 }
 
+// ErrStaleStripeEvent is returned when a newer Stripe event was already
+// applied to the subscription.
+var ErrStaleStripeEvent = errors.New("a newer stripe event was already applied to the subscription")
+
+// ErrNoCurrentSubscription is returned when the organization has no current
+// subscription.
+var ErrNoCurrentSubscription = errors.New("no current subscription found")
+
 type MongoSubscriptionRepo struct {
 	BaseRepo
 }
@@ -46,18 +61,19 @@ func (r *MongoSubscriptionRepo) CreateSubscription(reqCtx *app.RequestContext, s
 		EndDate:         subscription.EndDate,
 		TrialPeriodDays: subscription.TrialPeriodDays,
 		BillingCycle:    subscription.BillingCycle,
+		BillingPlanID:   subscription.BillingPlanID,
 		Status:          model.SubscriptionStatusActive,
 		IsCurrent:       true,
 	}
 	col := r.GetCollection()
 
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	insertResult, err := col.InsertOne(ctx, sub)
 	if err != nil {
 		log.Printf("error inserting subscription: %v", err)
-		return nil, errors.New("failed to create subscription")
+		return nil, newDBError("failed to create subscription", err)
 	}
 
 	if !insertResult.Acknowledged {
@@ -70,21 +86,21 @@ func (r *MongoSubscriptionRepo) CreateSubscription(reqCtx *app.RequestContext, s
 
 func (r *MongoSubscriptionRepo) GetSubscriptionByID(reqCtx *app.RequestContext, subscriptionID bson.ObjectID) (*model.Subscription, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	var subscription model.Subscription
 	findResult := col.FindOne(ctx, bson.M{"_id": subscriptionID})
 	if err := findResult.Decode(&subscription); err != nil {
 		log.Printf("error finding subscription: %v", err)
-		return nil, errors.New("failed to get subscription")
+		return nil, newDBError("failed to get subscription", err)
 	}
 	return &subscription, nil
 }
 
 func (r *MongoSubscriptionRepo) UpdateSubscription(reqCtx *app.RequestContext, subscriptionID bson.ObjectID, update *model.UpdateSubscription) (*model.Subscription, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	sub := &model.Subscription{
@@ -99,7 +115,7 @@ func (r *MongoSubscriptionRepo) UpdateSubscription(reqCtx *app.RequestContext, s
 	_, err := col.UpdateOne(ctx, bson.M{"_id": subscriptionID}, bson.M{"$set": sub})
 	if err != nil {
 		log.Printf("error updating subscription: %v", err)
-		return nil, errors.New("failed to update subscription")
+		return nil, newDBError("failed to update subscription", err)
 	}
 
 	return sub, nil
@@ -107,24 +123,24 @@ func (r *MongoSubscriptionRepo) UpdateSubscription(reqCtx *app.RequestContext, s
 
 func (r *MongoSubscriptionRepo) GetMySubscription(reqCtx *app.RequestContext) (*model.Subscription, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	var subscription model.Subscription
 	findResult := col.FindOne(ctx, bson.M{"organization_id": reqCtx.User.OrganizationID, "is_current": true})
 	if err := findResult.Decode(&subscription); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("no current subscription found")
+			return nil, ErrNoCurrentSubscription
 		}
 		log.Printf("error finding subscription: %v", err)
-		return nil, errors.New("failed to get subscription")
+		return nil, newDBError("failed to get subscription", err)
 	}
 
 	return &subscription, nil
 }
 
 func (r *MongoSubscriptionRepo) GetFreeTrialInfo(reqCtx *app.RequestContext) (*model.GetFreeTrialInfo, error) {
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	dbOrg := r.appDB.GetOrgDatabase(reqCtx.Org.Slug)
@@ -132,10 +148,157 @@ func (r *MongoSubscriptionRepo) GetFreeTrialInfo(reqCtx *app.RequestContext) (*m
 	count, err := colScanHistory.CountDocuments(ctx, bson.M{})
 	if err != nil {
 		log.Printf("error counting scan_history documents: %v", err)
-		return nil, errors.New("failed to get scan count")
+		return nil, newDBError("failed to get scan count", err)
 	}
 
 	return &model.GetFreeTrialInfo{
 		RecordCount: int(count),
 	}, nil
 }
+
+// GetSubscriptionByStripeID returns the subscription with the Stripe ID, or nil when there is none.
+func (r *MongoSubscriptionRepo) GetSubscriptionByStripeID(reqCtx *app.RequestContext, stripeSubID string) (*model.Subscription, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	var subscription model.Subscription
+	err := col.FindOne(ctx, bson.M{"stripe_sub_id": stripeSubID}).Decode(&subscription)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("error finding subscription %s: %v", stripeSubID, err)
+		return nil, newDBError("failed to get subscription", err)
+	}
+	return &subscription, nil
+}
+
+// SyncStripeSubscription upserts the subscription by its Stripe ID. When it is
+// current, every other subscription of the organization stops being current.
+func (r *MongoSubscriptionRepo) SyncStripeSubscription(reqCtx *app.RequestContext, sync *model.SyncSubscription) (*model.Subscription, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	now := time.Now()
+	set := bson.M{
+		"organization_id":      sync.OrganizationID,
+		"started_at":           sync.StartDate,
+		"ended_at":             sync.EndDate,
+		"billing_cycle":        sync.BillingCycle,
+		"status":               sync.Status,
+		"cancel_at_period_end": sync.CancelAtPeriodEnd,
+		"is_current":           sync.IsCurrent,
+		"updated_at":           now,
+		"updated_by":           reqCtx.User.IdentityID,
+	}
+	// Keep the known plan when the caller could not resolve one.
+	if !sync.BillingPlanID.IsZero() {
+		set["billing_plan_id"] = sync.BillingPlanID
+	}
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"_id":        bson.NewObjectID(),
+			"created_at": now,
+			"created_by": reqCtx.User.IdentityID,
+		},
+	}
+
+	filter := bson.M{"stripe_sub_id": sync.StripeSubID}
+	if !sync.EventCreatedAt.IsZero() {
+		// Stripe does not deliver the events in order: an event older than the
+		// last one applied would bring back an outdated state.
+		set["stripe_event_at"] = sync.EventCreatedAt
+		filter["$or"] = bson.A{
+			bson.M{"stripe_event_at": bson.M{"$exists": false}},
+			bson.M{"stripe_event_at": bson.M{"$lte": sync.EventCreatedAt}},
+		}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var sub model.Subscription
+	err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&sub)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// The subscription is either new or has seen a newer event. Only a new
+		// one is inserted, as the filter above would not match on upsert.
+		count, countErr := col.CountDocuments(ctx, bson.M{"stripe_sub_id": sync.StripeSubID}, options.Count().SetLimit(1))
+		if countErr != nil {
+			log.Printf("error checking subscription %s: %v", sync.StripeSubID, countErr)
+			return nil, newDBError("failed to sync subscription", countErr)
+		}
+		if count > 0 {
+			return nil, ErrStaleStripeEvent
+		}
+		err = col.FindOneAndUpdate(ctx, bson.M{"stripe_sub_id": sync.StripeSubID}, update, opts.SetUpsert(true)).Decode(&sub)
+	}
+	if err != nil {
+		log.Printf("error syncing subscription %s: %v", sync.StripeSubID, err)
+		return nil, newDBError("failed to sync subscription", err)
+	}
+
+	// The card details are kept while the subscription is charged to the same
+	// payment method, and come with its payment_method events otherwise.
+	currentID := ""
+	if sub.DefaultPaymentMethod != nil {
+		currentID = sub.DefaultPaymentMethod.StripeID
+	}
+	if currentID != sync.DefaultPaymentMethodID {
+		paymentUpdate := bson.M{"$unset": bson.M{"default_payment_method": ""}}
+		sub.DefaultPaymentMethod = nil
+		if sync.DefaultPaymentMethodID != "" {
+			sub.DefaultPaymentMethod = &model.PaymentMethod{StripeID: sync.DefaultPaymentMethodID}
+			paymentUpdate = bson.M{"$set": bson.M{"default_payment_method": sub.DefaultPaymentMethod}}
+		}
+		if _, err := col.UpdateOne(ctx, bson.M{"_id": sub.ID}, paymentUpdate); err != nil {
+			log.Printf("error syncing payment method of subscription %s: %v", sync.StripeSubID, err)
+			return nil, newDBError("failed to sync subscription", err)
+		}
+	}
+
+	if sub.IsCurrent {
+		filter := bson.M{"organization_id": sub.OrganizationID, "_id": bson.M{"$ne": sub.ID}, "is_current": true}
+		if _, err := col.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"is_current": false, "updated_at": now}}); err != nil {
+			log.Printf("error clearing current subscriptions: %v", err)
+			return nil, newDBError("failed to sync subscription", err)
+		}
+	}
+
+	return &sub, nil
+}
+
+// SyncPaymentMethod updates the details of the payment method on the
+// subscriptions charged to it.
+func (r *MongoSubscriptionRepo) SyncPaymentMethod(reqCtx *app.RequestContext, paymentMethod *model.PaymentMethod) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{"default_payment_method.stripe_id": paymentMethod.StripeID}
+	update := bson.M{"$set": bson.M{"default_payment_method": paymentMethod, "updated_at": time.Now()}}
+	if _, err := col.UpdateMany(ctx, filter, update); err != nil {
+		log.Printf("error syncing payment method %s: %v", paymentMethod.StripeID, err)
+		return newDBError("failed to sync payment method", err)
+	}
+	return nil
+}
+
+// DetachPaymentMethod removes the payment method from the subscriptions
+// charged to it.
+func (r *MongoSubscriptionRepo) DetachPaymentMethod(reqCtx *app.RequestContext, stripePaymentMethodID string) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	filter := bson.M{"default_payment_method.stripe_id": stripePaymentMethodID}
+	update := bson.M{
+		"$unset": bson.M{"default_payment_method": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+	if _, err := col.UpdateMany(ctx, filter, update); err != nil {
+		log.Printf("error detaching payment method %s: %v", stripePaymentMethodID, err)
+		return newDBError("failed to detach payment method", err)
+	}
+	return nil
+}
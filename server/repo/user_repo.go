@@ -25,6 +25,7 @@ import (
 	"github.com/gaeaglobal/exto/server/db"
 	"github.com/gaeaglobal/exto/server/model"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
@@ -33,9 +34,12 @@ type UserRepo interface {
 	CreateUser(reqCtx *app.RequestContext, createdByIdentityId bson.ObjectID, user *model.CreateUser) (*model.User, error)
 	GetUserByID(reqCtx *app.RequestContext, id bson.ObjectID) (*model.User, error)
 	UpdateUser(reqCtx *app.RequestContext, id bson.ObjectID, user *model.UpdateUser) (*model.User, error)
+	GetUserByOrgAndEmail(reqCtx *app.RequestContext, orgID bson.ObjectID, email string) (*model.User, error)
+	GetUsersByIdentityID(reqCtx *app.RequestContext, identityID bson.ObjectID) ([]*model.User, error)
 	GetUsersByOrgID(reqCtx *app.RequestContext, orgID bson.ObjectID, pageReq *app.PageRequest) (*app.PageResponse[*model.User], error)
 	IsUserExists(reqCtx *app.RequestContext, orgID bson.ObjectID, email string) (bool, error)
 	DeleteUserByOrgIDs(reqCtx *app.RequestContext, orgIDs []bson.ObjectID) error
+	DeleteUsersByIdentityID(reqCtx *app.RequestContext, identityID bson.ObjectID) error
 }
 
 type MongoUserRepo struct {
@@ -70,12 +74,12 @@ func (r *MongoUserRepo) CreateUser(reqCtx *app.RequestContext, createdByIdentity
 		Role:           user.Role,
 		IsActive:       true,
 	}
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	result, err := col.InsertOne(ctx, usr)
 	if err != nil {
 		log.Printf("failed to create user: %v", err)
-		return nil, errors.New("failed to create user")
+		return nil, newDBError("failed to create user", err)
 	}
 	usr.ID = result.InsertedID.(bson.ObjectID)
 	return usr, nil
@@ -83,13 +87,13 @@ func (r *MongoUserRepo) CreateUser(reqCtx *app.RequestContext, createdByIdentity
 
 func (r *MongoUserRepo) GetUserByID(reqCtx *app.RequestContext, id bson.ObjectID) (*model.User, error) {
 	var col = r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	var user model.User
 	err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
 	if err != nil {
 		log.Printf("failed to get user by id: %v", err)
-		return nil, errors.New("user not found")
+		return nil, newDBError("user not found", err)
 	}
 	return &user, nil
 }
@@ -97,36 +101,73 @@ func (r *MongoUserRepo) GetUserByID(reqCtx *app.RequestContext, id bson.ObjectID
 func (r *MongoUserRepo) UpdateUser(reqCtx *app.RequestContext, id bson.ObjectID, user *model.UpdateUser) (*model.User, error) {
 	var col = r.GetCollection()
 
-	usr := &model.User{
-		Base: model.Base{
-			UpdatedAt: time.Now(),
-			UpdatedBy: reqCtx.User.IdentityID,
-		},
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Role:      user.Role,
-		IsActive:  user.IsActive,
-	}
+	update := bson.M{"$set": bson.M{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"role":       user.Role,
+		"is_active":  user.IsActive,
+		"updated_at": time.Now(),
+		"updated_by": reqCtx.User.IdentityID,
+	}}
 
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	updatedUser := &model.User{}
-	result := col.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": usr}, opts)
+	result := col.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts)
 	if result.Err() != nil {
 		log.Printf("failed to update user: %v", result.Err())
-		return nil, errors.New("failed to update user")
+		return nil, newDBError("failed to update user", result.Err())
 	}
 	if err := result.Decode(updatedUser); err != nil {
 		log.Printf("failed to decode updated user: %v", err)
-		return nil, errors.New("failed to update user")
+		return nil, newDBError("failed to update user", err)
 	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheUser(updatedUser.Email) })
 	return updatedUser, nil
 }
 
+// GetUserByOrgAndEmail returns the user of the email in the organization, or
+// nil when the email is not a member.
+func (r *MongoUserRepo) GetUserByOrgAndEmail(reqCtx *app.RequestContext, orgID bson.ObjectID, email string) (*model.User, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	var user model.User
+	if err := col.FindOne(ctx, bson.M{"org_id": orgID, "email": email}).Decode(&user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf("failed to get user by org and email: %v", err)
+		return nil, newDBError("failed to get user", err)
+	}
+	return &user, nil
+}
+
+func (r *MongoUserRepo) GetUsersByIdentityID(reqCtx *app.RequestContext, identityID bson.ObjectID) ([]*model.User, error) {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	cursor, err := col.Find(ctx, bson.M{"identity_id": identityID})
+	if err != nil {
+		log.Printf("failed to get users by identity id: %v", err)
+		return nil, newDBError("failed to get users", err)
+	}
+	defer cursor.Close(ctx)
+
+	users := []*model.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		log.Printf("failed to decode users by identity id: %v", err)
+		return nil, newDBError("failed to get users", err)
+	}
+	return users, nil
+}
+
 func (r *MongoUserRepo) GetUsersByOrgID(reqCtx *app.RequestContext, orgID bson.ObjectID, pageReq *app.PageRequest) (*app.PageResponse[*model.User], error) {
 	var col = r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	opts := pageReq.ToFindOptions()
 
@@ -168,11 +209,11 @@ func (r *MongoUserRepo) GetUsersByOrgID(reqCtx *app.RequestContext, orgID bson.O
 
 	if findErr != nil {
 		log.Printf("failed to count users by org id: %v", findErr)
-		return nil, errors.New("failed to get users count")
+		return nil, newDBError("failed to get users count", findErr)
 	}
 	if countErr != nil {
 		log.Printf("failed to get users by org id: %v", countErr)
-		return nil, errors.New("failed to get users")
+		return nil, newDBError("failed to get users", countErr)
 	}
 
 	if users == nil {
@@ -184,27 +225,40 @@ func (r *MongoUserRepo) GetUsersByOrgID(reqCtx *app.RequestContext, orgID bson.O
 
 func (r *MongoUserRepo) IsUserExists(reqCtx *app.RequestContext, orgID bson.ObjectID, email string) (bool, error) {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 	count, err := col.CountDocuments(ctx, bson.M{"org_id": orgID, "email": email})
 	if err != nil {
 		log.Println("Error checking user existence:", err)
-		return false, errors.New("error checking user existence")
+		return false, newDBError("error checking user existence", err)
 	}
 	return count > 0, nil
 }
 
 func (r *MongoUserRepo) DeleteUserByOrgIDs(reqCtx *app.RequestContext, orgIDs []bson.ObjectID) error {
 	col := r.GetCollection()
-	ctx, cancel := db.GetDBContext()
+	ctx, cancel := r.GetDBContext(reqCtx)
 	defer cancel()
 
 	_, err := col.DeleteMany(ctx, bson.M{"org_id": bson.M{"$in": orgIDs}})
 	if err != nil {
 		log.Printf("error deleting users by org ids: %v", err)
-		return errors.New("failed to delete users")
+		return newDBError("failed to delete users", err)
 	}
 
-	db.DeleteCacheUser(reqCtx.User.Email)
+	reqCtx.AfterCommit(func() { db.DeleteCacheUser(reqCtx.User.Email) })
+	return nil
+}
+
+func (r *MongoUserRepo) DeleteUsersByIdentityID(reqCtx *app.RequestContext, identityID bson.ObjectID) error {
+	col := r.GetCollection()
+	ctx, cancel := r.GetDBContext(reqCtx)
+	defer cancel()
+
+	if _, err := col.DeleteMany(ctx, bson.M{"identity_id": identityID}); err != nil {
+		log.Printf("error deleting users by identity id: %v", err)
+		return newDBError("failed to delete users", err)
+	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheIdentity(identityID) })
 	return nil
 }
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+// superAdminRequest resolves the request of a platform admin endpoint and
+// writes the error response when the caller is not a super admin.
+func superAdminRequest(c *gin.Context) (*app.RequestContext, *app_di.AppDI, bool) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return nil, nil, false
+	}
+	if !app.HasPermission(reqCtx.User.Role, app.PermPlatformAdmin) {
+		c.JSON(http.StatusForbidden, utils.NewErrorResponse("Forbidden"))
+		return nil, nil, false
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return nil, nil, false
+	}
+	return reqCtx, di, true
+}
+
+// orgAdminRequest resolves the request of an endpoint that manages the current
+// organization and writes the error response when the caller is not one of
+// its admins.
+func orgAdminRequest(c *gin.Context) (*app.RequestContext, *app_di.AppDI, bool) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return nil, nil, false
+	}
+	if !app.HasPermission(reqCtx.User.Role, app.PermOrgManage) {
+		c.JSON(http.StatusForbidden, utils.NewErrorResponse("Forbidden"))
+		return nil, nil, false
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return nil, nil, false
+	}
+	return reqCtx, di, true
+}
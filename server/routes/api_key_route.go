@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func AddAPIKeyRoutes(router *gin.RouterGroup) {
+	router.GET("/organization/api-keys", app.RequirePermission(app.PermOrgManage), listAPIKeysHandler)
+	router.POST("/organization/api-keys", app.RequirePermission(app.PermOrgManage), createAPIKeyHandler)
+	router.POST("/organization/api-keys/:id/rotate", app.RequirePermission(app.PermOrgManage), rotateAPIKeyHandler)
+	router.DELETE("/organization/api-keys/:id", app.RequirePermission(app.PermOrgManage), revokeAPIKeyHandler)
+}
+
+func listAPIKeysHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	keys, err := di.APIKeyService.ListAPIKeys(reqCtx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to list api keys"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(keys))
+}
+
+func createAPIKeyHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateAPIKey
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	key, err := di.APIKeyService.CreateAPIKey(reqCtx, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKeyReq) {
+			c.JSON(http.StatusBadRequest, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		log.Printf("failed to create api key: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to create api key"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.NewOkResponse(key))
+}
+
+func rotateAPIKeyHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid api key ID"))
+		return
+	}
+
+	key, err := di.APIKeyService.RotateAPIKey(reqCtx, keyID)
+	if err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to rotate api key"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(key))
+}
+
+func revokeAPIKeyHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid api key ID"))
+		return
+	}
+
+	if err := di.APIKeyService.RevokeAPIKey(reqCtx, keyID); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to revoke api key"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse("api key revoked"))
+}
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/gaeaglobal/exto/server/app"
@@ -11,12 +13,32 @@ import (
 func AddAuthenticationRoutes(auth *gin.RouterGroup) {
 	auth.POST("/sign-up", signUpEndpoint)
 	auth.POST("/login", loginEndpoint)
+
+	// First-party password and magic-link sign-in
+	auth.POST("/password/sign-up", passwordSignUpEndpoint)
+	auth.POST("/password/login", passwordLoginEndpoint)
+	auth.POST("/verify-email", verifyEmailEndpoint)
+	auth.POST("/magic-link", requestMagicLinkEndpoint)
+	auth.POST("/magic-link/verify", verifyMagicLinkEndpoint)
+	auth.POST("/refresh", refreshTokenEndpoint)
+	auth.POST("/logout", logoutEndpoint)
+
+	// Organization single sign-on
+	auth.POST("/sso/login", ssoLoginEndpoint)
 }
 
 type SignUpRequest struct {
-	FirstName string `json:"first_name" binding:"required"`
-	LastName  string `json:"last_name" binding:"required"`
-	Provider  string `json:"provider" binding:"required"`
+	FirstName   string `json:"first_name" binding:"required"`
+	LastName    string `json:"last_name" binding:"required"`
+	Provider    string `json:"provider" binding:"required"`
+	InviteToken string `json:"invite_token"`
+}
+
+// LoginRequest optionally carries an invitation an existing user accepts
+// while signing in.
+type LoginRequest struct {
+	Provider    string `json:"provider"`
+	InviteToken string `json:"invite_token"`
 }
 
 func signUpEndpoint(c *gin.Context) {
@@ -39,24 +61,20 @@ func signUpEndpoint(c *gin.Context) {
 		return
 	}
 
-	var email string
-	if requestBody.Provider == "google" {
-		// Use existing Google token validation
-		tokenUser, err := app.GetUserFromToken(c)
-		if err != nil {
-			c.AbortWithStatusJSON(400, utils.NewErrorResponse("Invalid Google ID token"))
-			return
-		}
-		email = tokenUser.Email
-	} else if requestBody.Provider == "microsoft" {
-		tokenUser, err := app.GetUserFromMicrosoftToken(c)
+	email, err := providerEmail(c, requestBody.Provider)
+	if err != nil {
+		c.AbortWithStatusJSON(400, utils.NewErrorResponse(err.Error()))
+		return
+	}
+
+	// Invited users join the inviting organization instead of creating their own.
+	if requestBody.InviteToken != "" {
+		req, err := di.InvitationService.AcceptInvitation(&app.RequestContext{}, requestBody.InviteToken, email, requestBody.FirstName, requestBody.LastName)
 		if err != nil {
-			c.AbortWithStatusJSON(400, utils.NewErrorResponse("Invalid Microsoft ID token"))
+			respondInvitationError(c, err)
 			return
 		}
-		email = tokenUser.Email
-	} else {
-		c.AbortWithStatusJSON(400, utils.NewErrorResponse("Unsupported provider"))
+		c.JSON(201, utils.NewOkResponse(req))
 		return
 	}
 
@@ -70,6 +88,18 @@ func signUpEndpoint(c *gin.Context) {
 }
 
 func loginEndpoint(c *gin.Context) {
+	var requestBody LoginRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			c.AbortWithStatusJSON(400, utils.NewErrorResponse("Invalid request body"))
+			return
+		}
+	}
+	if requestBody.InviteToken != "" {
+		loginWithInvitation(c, &requestBody)
+		return
+	}
+
 	reqCtx := app.GetRequestCtx(c)
 	if reqCtx.IsZero() {
 		c.AbortWithStatusJSON(400, utils.NewErrorResponse("Failed to get current user"))
@@ -77,3 +107,59 @@ func loginEndpoint(c *gin.Context) {
 	}
 	c.JSON(200, utils.NewOkResponse(reqCtx.User))
 }
+
+func loginWithInvitation(c *gin.Context, requestBody *LoginRequest) {
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	provider := requestBody.Provider
+	if provider == "" {
+		provider = c.GetHeader("x-auth-provider")
+	}
+	email, err := providerEmail(c, provider)
+	if err != nil {
+		c.AbortWithStatusJSON(400, utils.NewErrorResponse(err.Error()))
+		return
+	}
+
+	req, err := di.InvitationService.AcceptInvitation(&app.RequestContext{}, requestBody.InviteToken, email, "", "")
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+	c.JSON(200, utils.NewOkResponse(req))
+}
+
+// providerEmail returns the email of the ID token sent by the given provider.
+func providerEmail(c *gin.Context, provider string) (string, error) {
+	switch provider {
+	case "google":
+		tokenUser, err := app.GetUserFromToken(c)
+		if err != nil {
+			return "", errors.New("Invalid Google ID token")
+		}
+		return tokenUser.Email, nil
+	case "microsoft":
+		tokenUser, err := app.GetUserFromMicrosoftToken(c)
+		if err != nil {
+			return "", errors.New("Invalid Microsoft ID token")
+		}
+		return tokenUser.Email, nil
+	case app.LocalAuthProvider:
+		tokenUser, err := app.GetUserFromLocalToken(c)
+		if err != nil {
+			return "", errors.New("Invalid access token")
+		}
+		return tokenUser.Email, nil
+	case app.OIDCProvider:
+		tokenUser, err := app.GetUserFromOIDCToken(c)
+		if err != nil {
+			return "", errors.New("Invalid SSO ID token")
+		}
+		return tokenUser.Email, nil
+	}
+	return "", errors.New("Unsupported provider")
+}
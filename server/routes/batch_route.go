@@ -15,8 +15,8 @@ import (
 )
 
 func AddBatchRoutes(router *gin.RouterGroup) {
-	router.POST("/batch", newBatchHandler)
-	router.PATCH("/batch/:batchID", updateBatchStatusHandler)
+	router.POST("/batch", app.RequirePermission(app.PermScanCreate), newBatchHandler)
+	router.PATCH("/batch/:batchID", app.RequirePermission(app.PermScanCreate), updateBatchStatusHandler)
 }
 
 func newBatchHandler(c *gin.Context) {
@@ -2,13 +2,16 @@ package routes
 
 import (
 	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
 	"github.com/gaeaglobal/exto/server/service"
 	"github.com/gaeaglobal/exto/server/utils"
 	"github.com/gin-gonic/gin"
 )
 
 func AddBillingRoutes(r *gin.RouterGroup) {
-	r.POST("/billing/payment", paymentRoute)
+	r.POST("/billing/payment", app.RequirePermission(app.PermBillingManage), paymentRoute)
+	r.GET("/billing/invoices", app.RequirePermission(app.PermBillingRead), listInvoicesRoute)
+	r.GET("/billing/invoices/:id/pdf", app.RequirePermission(app.PermBillingRead), getInvoicePDFRoute)
 }
 
 func paymentRoute(c *gin.Context) {
@@ -25,3 +28,47 @@ func paymentRoute(c *gin.Context) {
 	}
 	c.JSON(200, resp)
 }
+
+func listInvoicesRoute(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(401, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(500, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	invoices, err := di.PaymentService.ListInvoices(reqCtx)
+	if err != nil {
+		c.JSON(500, utils.NewErrorResponse("failed to list invoices"))
+		return
+	}
+
+	c.JSON(200, utils.NewOkResponse(invoices))
+}
+
+func getInvoicePDFRoute(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(401, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(500, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	pdfURL, err := di.PaymentService.GetInvoicePDF(reqCtx, c.Param("id"))
+	if err != nil {
+		c.JSON(404, utils.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(200, utils.NewOkResponse(gin.H{"pdf_url": pdfURL}))
+}
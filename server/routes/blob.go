@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/storage"
+	"github.com/gaeaglobal/exto/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// blobLinkTTL is how long the presigned links that downloads are redirected
+// to stay valid. The client follows the redirect right away.
+const blobLinkTTL = 5 * time.Minute
+
+// streamBlob sends the blob stored under the key as an attachment named
+// fileName. Authenticated downloads are streamed rather than redirected: the
+// clients would send their Authorization header along, which object stores
+// reject next to a presigned link.
+func streamBlob(c *gin.Context, key string, fileName string) {
+	appCtx, exists := app.GetAppContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("Application context not found"))
+		return
+	}
+
+	body, info, err := appCtx.Blobs.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			c.JSON(http.StatusNotFound, utils.NewErrorResponse("File not found"))
+			return
+		}
+		log.Printf("failed to read %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to read file"))
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": fileName}),
+	})
+}
+
+// redirectToBlob redirects to a presigned link to the blob stored under the
+// key, or streams the blob when the store cannot sign links.
+func redirectToBlob(c *gin.Context, key string, fileName string) {
+	appCtx, exists := app.GetAppContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("Application context not found"))
+		return
+	}
+
+	link, err := appCtx.Blobs.PresignGet(c.Request.Context(), key, fileName, blobLinkTTL)
+	if errors.Is(err, storage.ErrPresignNotSupported) {
+		streamBlob(c, key, fileName)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to sign a link to %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to read file"))
+		return
+	}
+	c.Redirect(http.StatusFound, link)
+}
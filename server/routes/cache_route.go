@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func AddCacheRoutes(router *gin.RouterGroup) {
+	router.GET("/admin/cache/users", app.RequirePermission(app.PermPlatformAdmin), userCacheStatsHandler)
+}
+
+func userCacheStatsHandler(c *gin.Context) {
+	if _, _, ok := superAdminRequest(c); !ok {
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(db.GetUserCacheStats()))
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/app_di"
-	"github.com/gaeaglobal/exto/server/file_utils"
 	"github.com/gaeaglobal/exto/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -16,13 +15,13 @@ import (
 
 func AddCategoryDataRoutes(router *gin.RouterGroup) {
 	//Not needed create will happen via scan route
-	router.POST("/categories/:categoryID/data", createCategoryDataEndpoint)
-	router.PATCH("/categories/:categoryID/data/:dataID", updateCategoryDataEndpoint)
+	router.POST("/categories/:categoryID/data", app.RequirePermission(app.PermCategoryWrite), createCategoryDataEndpoint)
+	router.PATCH("/categories/:categoryID/data/:dataID", app.RequirePermission(app.PermCategoryWrite), updateCategoryDataEndpoint)
 }
 
 func createCategoryDataEndpoint(c *gin.Context) {
 	// User uploads the file along with category data
-	appCtx, exists := app.GetAppContext(c)
+	_, exists := app.GetAppContext(c)
 	if !exists {
 		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Application context not found"))
 		return
@@ -52,9 +51,12 @@ func createCategoryDataEndpoint(c *gin.Context) {
 		return
 	}
 
-	// Save the uploaded file to disk
-	filePath, err := SaveFileToDisk(appCtx, reqCtx, c)
+	// Validate the uploaded file and save it to the blob store
+	upload, err := di.UploadService.SaveUploadedFile(c, reqCtx)
 	if err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
 		c.AbortWithStatusJSON(500, utils.NewErrorResponse(fmt.Sprintf("Failed to save file: %s", err.Error())))
 		return
 	}
@@ -69,7 +71,7 @@ func createCategoryDataEndpoint(c *gin.Context) {
 	//dummy batchID
 	batchID := bson.NewObjectID()
 
-	createdData, err := di.CategoryDataService.CreateCategoryData(reqCtx, categoryIDObj, data, data, filePath, batchID)
+	createdData, err := di.CategoryDataService.CreateCategoryData(reqCtx, categoryIDObj, data, data, upload, batchID, di.PIIService.Detect(reqCtx, *data, nil))
 
 	if err != nil {
 		log.Printf("Error creating category data: %v", err)
@@ -104,25 +106,6 @@ func GetCategoryRawData(c *gin.Context) (*map[string]any, error) {
 	return &data, nil
 }
 
-func SaveFileToDisk(appCtx *app.AppContext, reqCtx *app.RequestContext, c *gin.Context) (string, error) {
-	// Retrieve the file from the form data
-	file, err := c.FormFile("file")
-	if err != nil {
-		return "", errors.New("file not found in form data")
-	}
-
-	dst := file_utils.GetFilePath(appCtx, reqCtx, appCtx.Config.UPLOAD_DIR, file.Filename, 7, 0)
-	if dst == "" {
-		return "", errors.New("failed to upload file, unique file path not found")
-	}
-
-	// Save the file
-	if err := c.SaveUploadedFile(file, dst); err != nil {
-		return "", fmt.Errorf("failed to save file: %w", err)
-	}
-	return dst, nil
-}
-
 func updateCategoryDataEndpoint(c *gin.Context) {
 	// User uploads the file along with category data
 	_, exists := app.GetAppContext(c)
@@ -11,7 +11,7 @@ import (
 )
 
 func AddCategoryRoutes(router *gin.RouterGroup) {
-	router.GET("/categories", getAllCategoriesEndpoint)
+	router.GET("/categories", app.RequirePermission(app.PermCategoryRead), getAllCategoriesEndpoint)
 }
 
 func getAllCategoriesEndpoint(c *gin.Context) {
@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+// AddDataExportRoutes registers the endpoints the organization admins use to
+// export all the data of their organization.
+func AddDataExportRoutes(router *gin.RouterGroup) {
+	router.POST("/organization/data-exports", app.RequirePermission(app.PermOrgManage), requestDataExportHandler)
+	router.GET("/organization/data-exports", app.RequirePermission(app.PermOrgManage), listDataExportsHandler)
+	router.POST("/organization/data-exports/:id/link", app.RequirePermission(app.PermOrgManage), createDataExportLinkHandler)
+}
+
+// AddDownloadRoutes registers the downloads authenticated by a signed link
+// instead of a signed in user.
+func AddDownloadRoutes(router *gin.RouterGroup) {
+	router.GET("/data-exports/:token", downloadDataExportHandler)
+}
+
+func requestDataExportHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	export, err := di.DataExportService.RequestExport(reqCtx)
+	if err != nil {
+		if errors.Is(err, service.ErrDataExportInProgress) {
+			c.JSON(http.StatusConflict, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		log.Printf("failed to request data export: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to request data export"))
+		return
+	}
+	c.JSON(http.StatusAccepted, utils.NewOkResponse(export))
+}
+
+func listDataExportsHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	exports, err := di.DataExportService.ListExports(reqCtx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to list data exports"))
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(exports))
+}
+
+func createDataExportLinkHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+	exportID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid data export ID"))
+		return
+	}
+
+	link, err := di.DataExportService.CreateDownloadLink(reqCtx, exportID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDataExportNotFound):
+			c.JSON(http.StatusNotFound, utils.NewErrorResponse(err.Error()))
+		case errors.Is(err, service.ErrDataExportNotReady):
+			c.JSON(http.StatusConflict, utils.NewErrorResponse(err.Error()))
+		default:
+			log.Printf("failed to create data export link: %v", err)
+			c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to create download link"))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(link))
+}
+
+func downloadDataExportHandler(c *gin.Context) {
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	export, key, err := di.DataExportService.OpenDownload(&app.RequestContext{}, c.Param("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDownloadLink) {
+			c.JSON(http.StatusNotFound, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		log.Printf("failed to open data export: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to download data export"))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	redirectToBlob(c, key, export.FileName)
+}
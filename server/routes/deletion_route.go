@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+// AddDeletionRoutes registers the platform admin endpoints that revert
+// deletions during their grace period and list the deletion certificates.
+func AddDeletionRoutes(router *gin.RouterGroup) {
+	router.POST("/admin/identities/:id/restore", app.RequirePermission(app.PermPlatformAdmin), restoreAccountHandler)
+	router.POST("/admin/organizations/:id/restore", app.RequirePermission(app.PermPlatformAdmin), restoreOrganizationHandler)
+	router.GET("/admin/deletion-certificates", app.RequirePermission(app.PermPlatformAdmin), listDeletionCertificatesHandler)
+}
+
+func restoreAccountHandler(c *gin.Context) {
+	reqCtx, di, ok := superAdminRequest(c)
+	if !ok {
+		return
+	}
+	identityID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid identity ID"))
+		return
+	}
+
+	if err := di.DeletionService.RestoreAccount(reqCtx, identityID); err != nil {
+		respondRestoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse("Account restored"))
+}
+
+func restoreOrganizationHandler(c *gin.Context) {
+	reqCtx, di, ok := superAdminRequest(c)
+	if !ok {
+		return
+	}
+	orgID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid organization ID"))
+		return
+	}
+
+	if err := di.DeletionService.RestoreOrganization(reqCtx, orgID); err != nil {
+		respondRestoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse("Organization restored"))
+}
+
+func respondRestoreError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrNotPendingDeletion) {
+		c.JSON(http.StatusNotFound, utils.NewErrorResponse(err.Error()))
+		return
+	}
+	log.Printf("failed to restore deletion: %v", err)
+	c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to restore"))
+}
+
+func listDeletionCertificatesHandler(c *gin.Context) {
+	reqCtx, di, ok := superAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	certs, err := di.DeletionService.ListDeletionCertificates(reqCtx, app.NewPageRequest(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to list deletion certificates"))
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(certs))
+}
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+// AddEncryptionRoutes registers the rotation of the data key of the current
+// organization and the platform admin endpoint that rewraps all data keys
+// after the master key was rotated.
+func AddEncryptionRoutes(router *gin.RouterGroup) {
+	router.POST("/organization/encryption/rotate", app.RequirePermission(app.PermOrgManage), rotateDataKeyHandler)
+	router.POST("/admin/encryption/rewrap", app.RequirePermission(app.PermPlatformAdmin), rewrapDataKeysHandler)
+}
+
+func rotateDataKeyHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	dataKey, err := di.EncryptionService.RotateDataKey(reqCtx)
+	if err != nil {
+		respondEncryptionError(c, "failed to rotate data key", err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(dataKey))
+}
+
+func rewrapDataKeysHandler(c *gin.Context) {
+	reqCtx, di, ok := superAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	report, err := di.EncryptionService.RewrapDataKeys(reqCtx)
+	if err != nil {
+		respondEncryptionError(c, "failed to rewrap data keys", err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(report))
+}
+
+func respondEncryptionError(c *gin.Context, msg string, err error) {
+	if errors.Is(err, service.ErrEncryptionDisabled) {
+		c.JSON(http.StatusConflict, utils.NewErrorResponse(err.Error()))
+		return
+	}
+	log.Printf("%s: %v", msg, err)
+	c.JSON(http.StatusInternalServerError, utils.NewErrorResponse(msg))
+}
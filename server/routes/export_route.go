@@ -10,7 +10,7 @@ import (
 )
 
 func AddExportRoutes(router *gin.RouterGroup) {
-	router.GET("/export/:scan_history_id", exportHandler)
+	router.GET("/export/:scan_history_id", app.RequirePermission(app.PermExportRead), exportHandler)
 }
 
 func exportHandler(c *gin.Context) {
@@ -35,6 +35,5 @@ func exportHandler(c *gin.Context) {
 	}
 
 	// Downloadable file
-	c.Header("Content-Disposition", "attachment; filename="+result.FileName)
-	c.File(result.FilePath)
+	streamBlob(c, result.Key, result.FileName)
 }
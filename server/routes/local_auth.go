@@ -0,0 +1,175 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func passwordSignUpEndpoint(c *gin.Context) {
+	var req model.PasswordSignUp
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("Invalid request body"))
+		return
+	}
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	reqCtx, err := di.AuthService.SignUp(&app.RequestContext{}, &req)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.NewOkResponse(reqCtx))
+}
+
+func passwordLoginEndpoint(c *gin.Context) {
+	var req model.PasswordLogin
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("Invalid request body"))
+		return
+	}
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	tokens, err := di.AuthService.Login(&app.RequestContext{}, &req)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(tokens))
+}
+
+func verifyEmailEndpoint(c *gin.Context) {
+	var req model.AuthTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("Invalid request body"))
+		return
+	}
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	if err := di.AuthService.VerifyEmail(&app.RequestContext{}, req.Token); err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse("Email verified"))
+}
+
+// requestMagicLinkEndpoint answers 202 for unknown emails as well so it
+// cannot be used to find out which accounts exist.
+func requestMagicLinkEndpoint(c *gin.Context) {
+	var req model.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("Invalid request body"))
+		return
+	}
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	if err := di.AuthService.RequestMagicLink(&app.RequestContext{}, req.Email); err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, utils.NewOkResponse("If an account exists for this email, a sign-in link has been sent"))
+}
+
+func verifyMagicLinkEndpoint(c *gin.Context) {
+	var req model.AuthTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("Invalid request body"))
+		return
+	}
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	tokens, err := di.AuthService.VerifyMagicLink(&app.RequestContext{}, req.Token)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(tokens))
+}
+
+func refreshTokenEndpoint(c *gin.Context) {
+	var req model.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("Invalid request body"))
+		return
+	}
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	tokens, err := di.AuthService.Refresh(&app.RequestContext{}, req.RefreshToken)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(tokens))
+}
+
+func logoutEndpoint(c *gin.Context) {
+	var req model.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, utils.NewErrorResponse("Invalid request body"))
+		return
+	}
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	if err := di.AuthService.Logout(&app.RequestContext{}, req.RefreshToken); err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse("Logged out"))
+}
+
+func respondAuthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInvalidAuthToken):
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, utils.NewErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrEmailTaken):
+		c.JSON(http.StatusConflict, utils.NewErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrLocalAuthDisabled):
+		c.JSON(http.StatusNotImplemented, utils.NewErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrInvitationNotFound), errors.Is(err, service.ErrInvitationExpired),
+		errors.Is(err, service.ErrInvitationEmailMismatch), errors.Is(err, service.ErrAlreadyMember):
+		respondInvitationError(c, err)
+	default:
+		log.Printf("authentication failed: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("authentication failed"))
+	}
+}
@@ -1,15 +1,21 @@
 package routes
 
 import (
+	"errors"
+
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/service"
 	"github.com/gaeaglobal/exto/server/utils"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 func AddMeRoutes(router *gin.RouterGroup) {
-	router.GET("/me", meHandler)
-	router.DELETE("/me", deleteMeHandler)
+	router.GET("/me", app.RequirePermission(app.PermAccount), meHandler)
+	router.DELETE("/me", app.RequirePermission(app.PermIdentity), deleteMeHandler)
+	router.GET("/me/organizations", app.RequirePermission(app.PermIdentity), listMyOrganizationsHandler)
+	router.POST("/me/organizations/:id/switch", app.RequirePermission(app.PermIdentity), switchOrganizationHandler)
 }
 
 func meHandler(c *gin.Context) {
@@ -34,9 +40,63 @@ func deleteMeHandler(c *gin.Context) {
 		return
 	}
 
-	if err := di.UserService.DeleteUser(reqCtx); err != nil {
+	// The account is signed out at once and purged after the grace period.
+	deletion, err := di.DeletionService.DeleteAccount(reqCtx)
+	if err != nil {
 		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to delete user: "+err.Error()))
 		return
 	}
-	c.JSON(200, utils.NewOkResponse("User deleted successfully"))
+	c.JSON(200, utils.NewOkResponse(deletion))
+}
+
+func listMyOrganizationsHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(401, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	orgs, err := di.UserService.ListMyOrganizations(reqCtx)
+	if err != nil {
+		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to list organizations"))
+		return
+	}
+	c.JSON(200, utils.NewOkResponse(orgs))
+}
+
+func switchOrganizationHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(401, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	orgID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(400, utils.NewErrorResponse("Invalid organization ID"))
+		return
+	}
+
+	org, err := di.UserService.SwitchOrganization(reqCtx, orgID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotMember) {
+			c.AbortWithStatusJSON(403, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to switch organization"))
+		return
+	}
+	c.JSON(200, utils.NewOkResponse(org))
 }
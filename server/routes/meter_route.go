@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func AddMeterRoutes(router *gin.RouterGroup) {
+	router.GET("/admin/organizations/:id/meter-reconciliation", app.RequirePermission(app.PermPlatformAdmin), meterReconciliationHandler)
+}
+
+// meterReconciliationHandler reports the organization's recorded usage against
+// Stripe for the period given by the RFC 3339 start and end query parameters.
+// The period defaults to the current calendar month.
+func meterReconciliationHandler(c *gin.Context) {
+	reqCtx, di, orgID, ok := quotaAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := now
+	if value := c.Query("start"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid start time"))
+			return
+		}
+		start = parsed
+	}
+	if value := c.Query("end"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid end time"))
+			return
+		}
+		end = parsed
+	}
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("start must be before end"))
+		return
+	}
+
+	report, err := di.MeterService.ReconcileUsage(reqCtx, orgID, start, end)
+	if err != nil {
+		log.Printf("failed to reconcile meter usage: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to reconcile meter usage"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(report))
+}
@@ -1,18 +1,22 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type ExtractRequest struct {
-	Base64Image string `json:"base64Image"`
-	CategoryID  string `json:"categoryID"`
+	Base64Image  string `json:"base64Image"`
+	CategoryID   string `json:"categoryID"`
+	ForceRefresh bool   `json:"force_refresh"`
 }
 
 type ExtractResponse struct {
@@ -22,7 +26,7 @@ type ExtractResponse struct {
 }
 
 func AddOpenAiRoutes(router *gin.RouterGroup) {
-	router.POST("/extract", ExtractHandler)
+	router.POST("/extract", app.RequirePermission(app.PermScanCreate), ExtractHandler)
 }
 
 func ExtractHandler(c *gin.Context) {
@@ -50,8 +54,20 @@ func ExtractHandler(c *gin.Context) {
 		return
 	}
 
-	result, err := di.OpenAIService.ExtractDocumentData(reqCtx, categoryObjID, req.Base64Image)
+	reservation, err := di.QuotaService.ReserveScan(reqCtx)
 	if err != nil {
+		var quotaErr *service.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			c.JSON(http.StatusPaymentRequired, utils.NewErrorResponseWithDetails(quotaErr.Error(), quotaErr.Details()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check scan quota"})
+		return
+	}
+
+	result, err := di.OpenAIService.ExtractDocumentData(reqCtx, categoryObjID, req.Base64Image, req.ForceRefresh)
+	if err != nil {
+		di.QuotaService.ReleaseScan(reqCtx, reservation)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "OpenAI extraction failed: " + err.Error()})
 		return
 	}
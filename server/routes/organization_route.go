@@ -0,0 +1,165 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func AddOrganizationRoutes(router *gin.RouterGroup) {
+	router.GET("/organization", app.RequirePermission(app.PermOrgRead), getOrganizationProfileHandler)
+	router.PATCH("/organization", app.RequirePermission(app.PermOrgManage), updateOrganizationProfileHandler)
+	router.DELETE("/organization", app.RequirePermission(app.PermOrgManage), deleteOrganizationHandler)
+	router.PATCH("/organization/extraction-cache", app.RequirePermission(app.PermOrgManage), updateExtractionCacheSettingHandler)
+	router.PUT("/organization/microsoft-tenants", app.RequirePermission(app.PermOrgManage), updateMicrosoftTenantsHandler)
+}
+
+func getOrganizationProfileHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	org, err := di.OrganizationProfileService.GetProfile(reqCtx)
+	if err != nil {
+		log.Printf("failed to get organization: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get organization"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(org))
+}
+
+func updateOrganizationProfileHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	var req model.UpdateOrganizationProfile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	org, err := di.OrganizationProfileService.UpdateProfile(reqCtx, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidOrganizationProfile), errors.Is(err, service.ErrDefaultCategoryNotFound),
+			errors.Is(err, service.ErrRetentionCategoryNotFound):
+			c.JSON(http.StatusBadRequest, utils.NewErrorResponse(err.Error()))
+		default:
+			log.Printf("failed to update organization: %v", err)
+			c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to update organization"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(org))
+}
+
+// deleteOrganizationHandler schedules the deletion of the current
+// organization; its data is purged after the grace period.
+func deleteOrganizationHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	deletion, err := di.DeletionService.DeleteOrganization(reqCtx)
+	if err != nil {
+		if errors.Is(err, service.ErrNotOrganizationOwner) {
+			c.JSON(http.StatusForbidden, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		log.Printf("failed to delete organization: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to delete organization"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(deletion))
+}
+
+type ExtractionCacheSettingRequest struct {
+	Disabled bool `json:"disabled"`
+}
+
+func updateExtractionCacheSettingHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	var req ExtractionCacheSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	org, err := di.OrganizationService.SetExtractionCacheDisabled(reqCtx, req.Disabled)
+	if err != nil {
+		log.Printf("failed to update extraction cache setting: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to update extraction cache setting"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(org))
+}
+
+func updateMicrosoftTenantsHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	var req model.MicrosoftTenantsSetting
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	org, err := di.OrganizationService.SetMicrosoftTenantIDs(reqCtx, req.TenantIDs)
+	if err != nil {
+		log.Printf("failed to update microsoft tenants: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to update microsoft tenants"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(org))
+}
@@ -1,20 +1,25 @@
 package routes
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/app_di"
 	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
 	"github.com/gaeaglobal/exto/server/utils"
 )
 
 func AddPaymentRoutes(r *gin.RouterGroup) {
-	r.GET("/subscription", getMySubscription)
-	r.POST("/payment/setup", setupPayment)
-	r.POST("/payment/subscribe", createSubscription)
-	r.POST("/payment/cancel", cancelSubscription)
-	r.GET("/payment/free-trial", getFreeTrialInfo)
+	r.GET("/subscription", app.RequirePermission(app.PermBillingRead), getMySubscription)
+	r.POST("/payment/setup", app.RequirePermission(app.PermBillingManage), setupPayment)
+	r.POST("/payment/subscribe", app.RequirePermission(app.PermBillingManage), createSubscription)
+	r.POST("/payment/change-plan", app.RequirePermission(app.PermBillingManage), changePlan)
+	r.POST("/payment/cancel", app.RequirePermission(app.PermBillingManage), cancelSubscription)
+	r.GET("/payment/status", app.RequirePermission(app.PermBillingRead), getSubscriptionStatus)
+	r.GET("/payment/free-trial", app.RequirePermission(app.PermBillingRead), getFreeTrialInfo)
 }
 
 func setupPayment(c *gin.Context) {
@@ -58,9 +63,47 @@ func createSubscription(c *gin.Context) {
 		return
 	}
 
-	sub, err := di.PaymentService.CreateSubscription(reqCtx)
+	var req model.SubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, utils.NewErrorResponse("plan_id is required"))
+		return
+	}
+
+	sub, err := di.PaymentService.CreateSubscription(reqCtx, req.PlanID)
 	if err != nil {
-		c.JSON(500, utils.NewErrorResponse("failed to create subscription"))
+		c.JSON(500, utils.NewErrorResponse("failed to create subscription: "+err.Error()))
+		return
+	}
+
+	c.JSON(200, utils.NewOkResponse(sub))
+}
+
+func changePlan(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx == nil {
+		c.JSON(500, utils.NewErrorResponse("missing request context"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(500, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	var req model.ChangePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	sub, err := di.PaymentService.ChangePlan(reqCtx, req.PlanID, req.ProrationBehavior)
+	if err != nil {
+		if errors.Is(err, service.ErrNoCurrentSubscription) {
+			c.JSON(404, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(500, utils.NewErrorResponse("failed to change plan: "+err.Error()))
 		return
 	}
 
@@ -80,13 +123,50 @@ func cancelSubscription(c *gin.Context) {
 		return
 	}
 
-	err := di.PaymentService.CancelSubscription(reqCtx)
+	// mode is "period_end" (default) or "immediate"
+	mode := service.CancelMode(c.DefaultQuery("mode", string(service.CancelAtPeriodEnd)))
+	if mode != service.CancelAtPeriodEnd && mode != service.CancelImmediately {
+		c.JSON(400, utils.NewErrorResponse("invalid cancel mode"))
+		return
+	}
+
+	sub, err := di.PaymentService.CancelSubscription(reqCtx, mode)
 	if err != nil {
+		if errors.Is(err, service.ErrNoCurrentSubscription) {
+			c.JSON(404, utils.NewErrorResponse(err.Error()))
+			return
+		}
 		c.JSON(500, utils.NewErrorResponse("failed to cancel subscription"))
 		return
 	}
 
-	c.JSON(200, utils.NewOkResponse("subscription cancelled successfully"))
+	c.JSON(200, utils.NewOkResponse(sub))
+}
+
+func getSubscriptionStatus(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx == nil {
+		c.JSON(500, utils.NewErrorResponse("missing request context"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(500, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	status, err := di.PaymentService.GetSubscriptionStatus(reqCtx)
+	if err != nil {
+		if errors.Is(err, service.ErrNoCurrentSubscription) {
+			c.JSON(404, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(500, utils.NewErrorResponse("failed to get subscription status"))
+		return
+	}
+
+	c.JSON(200, utils.NewOkResponse(gin.H{"status": status}))
 }
 
 func getMySubscription(c *gin.Context) {
@@ -104,8 +184,8 @@ func getMySubscription(c *gin.Context) {
 
 	sub, err := di.SubscriptionService.GetMySubscription(reqCtx)
 	if err != nil {
-		if err.Error() == "no current subscription found" {
-			c.JSON(404, utils.NewErrorResponse("no current subscription found"))
+		if errors.Is(err, service.ErrNoCurrentSubscription) {
+			c.JSON(404, utils.NewErrorResponse(err.Error()))
 			return
 		}
 		c.JSON(500, utils.NewErrorResponse("failed to get subscription"))
@@ -0,0 +1,204 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/routes"
+)
+
+// routePermissions lists the permission every protected route requires. Routes
+// open to any caller have no permission.
+var routePermissions = map[string]app.Permission{
+	"GET /v1/me":                                             app.PermAccount,
+	"DELETE /v1/me":                                          app.PermIdentity,
+	"GET /v1/me/organizations":                               app.PermIdentity,
+	"POST /v1/me/organizations/:id/switch":                   app.PermIdentity,
+	"POST /v1/extract":                                       app.PermScanCreate,
+	"GET /v1/categories":                                     app.PermCategoryRead,
+	"POST /v1/categories/:categoryID/data":                   app.PermCategoryWrite,
+	"PATCH /v1/categories/:categoryID/data/:dataID":          app.PermCategoryWrite,
+	"GET /v1/scan-history":                                   app.PermScanRead,
+	"GET /v1/scan-history/data/:scanHistoryID":               app.PermScanRead,
+	"GET /v1/scan-history/document/:scanHistoryID":           app.PermScanRead,
+	"GET /v1/scan-history/document/:scanHistoryID/redacted":  app.PermScanRead,
+	"GET /v1/export/:scan_history_id":                        app.PermExportRead,
+	"POST /v1/billing/payment":                               app.PermBillingManage,
+	"GET /v1/billing/invoices":                               app.PermBillingRead,
+	"GET /v1/billing/invoices/:id/pdf":                       app.PermBillingRead,
+	"POST /v1/scan/document":                                 app.PermScanCreate,
+	"POST /v1/batch":                                         app.PermScanCreate,
+	"PATCH /v1/batch/:batchID":                               app.PermScanCreate,
+	"GET /v1/subscription":                                   app.PermBillingRead,
+	"POST /v1/payment/setup":                                 app.PermBillingManage,
+	"POST /v1/payment/subscribe":                             app.PermBillingManage,
+	"POST /v1/payment/change-plan":                           app.PermBillingManage,
+	"POST /v1/payment/cancel":                                app.PermBillingManage,
+	"GET /v1/payment/status":                                 app.PermBillingRead,
+	"GET /v1/payment/free-trial":                             app.PermBillingRead,
+	"GET /v1/organization":                                   app.PermOrgRead,
+	"POST /v1/organization/data-exports":                     app.PermOrgManage,
+	"GET /v1/organization/data-exports":                      app.PermOrgManage,
+	"POST /v1/organization/data-exports/:id/link":            app.PermOrgManage,
+	"GET /v1/organization/retention/report":                  app.PermOrgManage,
+	"PUT /v1/categories/:categoryID/data/:dataID/legal-hold": app.PermOrgManage,
+	"PUT /v1/batch/:batchID/legal-hold":                      app.PermOrgManage,
+	"POST /v1/organization/encryption/rotate":                app.PermOrgManage,
+	"DELETE /v1/organization":                                app.PermOrgManage,
+	"PATCH /v1/organization":                                 app.PermOrgManage,
+	"PATCH /v1/organization/extraction-cache":                app.PermOrgManage,
+	"PUT /v1/organization/microsoft-tenants":                 app.PermOrgManage,
+	"GET /v1/organization/sso":                               app.PermOrgManage,
+	"PUT /v1/organization/sso":                               app.PermOrgManage,
+	"POST /v1/organization/sso/domains/:domain/verify":       app.PermOrgManage,
+	"GET /v1/organization/users":                             app.PermOrgManage,
+	"PATCH /v1/organization/users/:id/role":                  app.PermOrgManage,
+	"POST /v1/organization/users/:id/deactivate":             app.PermOrgManage,
+	"GET /v1/organization/invitations":                       app.PermOrgManage,
+	"POST /v1/organization/invitations":                      app.PermOrgManage,
+	"DELETE /v1/organization/invitations/:id":                app.PermOrgManage,
+	"GET /v1/organization/api-keys":                          app.PermOrgManage,
+	"POST /v1/organization/api-keys":                         app.PermOrgManage,
+	"POST /v1/organization/api-keys/:id/rotate":              app.PermOrgManage,
+	"DELETE /v1/organization/api-keys/:id":                   app.PermOrgManage,
+	"POST /v1/invitations/accept":                            app.PermIdentity,
+	"GET /v1/quota":                                          app.PermUsageRead,
+	"POST /v1/admin/identities/:id/restore":                  app.PermPlatformAdmin,
+	"POST /v1/admin/organizations/:id/restore":               app.PermPlatformAdmin,
+	"GET /v1/admin/deletion-certificates":                    app.PermPlatformAdmin,
+	"GET /v1/admin/organizations/:id/quota-override":         app.PermPlatformAdmin,
+	"PUT /v1/admin/organizations/:id/quota-override":         app.PermPlatformAdmin,
+	"DELETE /v1/admin/organizations/:id/quota-override":      app.PermPlatformAdmin,
+	"GET /v1/plans":                                          "",
+	"GET /v1/admin/plans":                                    app.PermPlatformAdmin,
+	"POST /v1/admin/plans":                                   app.PermPlatformAdmin,
+	"PATCH /v1/admin/plans/:id":                              app.PermPlatformAdmin,
+	"GET /v1/admin/organizations/:id/meter-reconciliation":   app.PermPlatformAdmin,
+	"GET /v1/admin/cache/users":                              app.PermPlatformAdmin,
+	"POST /v1/admin/encryption/rewrap":                       app.PermPlatformAdmin,
+}
+
+var allRoles = []model.UserRole{
+	model.RoleSuperAdmin,
+	model.RoleOrganizationAdmin,
+	model.RoleBillingAdmin,
+	model.RoleMember,
+	model.RoleGuest,
+}
+
+var routeParam = regexp.MustCompile(`:[A-Za-z_]+`)
+
+// newPermissionTestRouter registers the protected routes behind a stub that
+// signs in a user with the given role instead of verifying a token.
+func newPermissionTestRouter(role model.UserRole, scopes ...app.Permission) *gin.Engine {
+	return newPermissionTestRouterFor(app.RequestUser{ID: bson.NewObjectID(), Email: "user@example.com", Role: role, IsActive: true, Scopes: scopes})
+}
+
+// newPermissionTestRouterFor registers the protected routes behind a stub
+// that signs in the given user.
+func newPermissionTestRouterFor(user app.RequestUser) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	protected := router.Group("/v1")
+	protected.Use(func(c *gin.Context) {
+		app.SetRequestCtx(c, &app.RequestContext{
+			User: user,
+			Org:  app.RequestOrg{ID: bson.NewObjectID(), Slug: "org_test"},
+		})
+		c.Next()
+	})
+	routes.AddProtectedRoutes(protected)
+	return router
+}
+
+func TestEveryProtectedRouteHasPermission(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, route := range newPermissionTestRouter(model.RoleGuest).Routes() {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if _, ok := routePermissions[key]; !ok {
+			t.Errorf("route %s is missing from the permission table", key)
+		}
+	}
+	for key := range routePermissions {
+		if !registered[key] {
+			t.Errorf("route %s is in the permission table but not registered", key)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	for _, role := range allRoles {
+		router := newPermissionTestRouter(role)
+		for key, perm := range routePermissions {
+			t.Run(string(role)+" "+key, func(t *testing.T) {
+				method, path, _ := strings.Cut(key, " ")
+				path = routeParam.ReplaceAllString(path, bson.NewObjectID().Hex())
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+				denied := w.Code == http.StatusForbidden && strings.Contains(w.Body.String(), "missing permission")
+				allowed := perm == "" || app.HasPermission(role, perm)
+				if allowed && denied {
+					t.Fatalf("expected %s to be allowed, got %d %s", role, w.Code, w.Body.String())
+				}
+				if !allowed {
+					if !denied {
+						t.Fatalf("expected %s to be denied, got %d %s", role, w.Code, w.Body.String())
+					}
+					if !strings.Contains(w.Body.String(), string(perm)) {
+						t.Fatalf("expected missing permission %s in %s", perm, w.Body.String())
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	router := newPermissionTestRouter(model.RoleService, app.PermScanCreate, app.PermScanRead)
+	for key, perm := range routePermissions {
+		t.Run(key, func(t *testing.T) {
+			method, path, _ := strings.Cut(key, " ")
+			path = routeParam.ReplaceAllString(path, bson.NewObjectID().Hex())
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+			denied := w.Code == http.StatusForbidden && strings.Contains(w.Body.String(), "missing permission")
+			allowed := perm == "" || perm == app.PermScanCreate || perm == app.PermScanRead
+			if allowed == denied {
+				t.Fatalf("expected allowed=%v for api key, got %d %s", allowed, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSSOSessionPermissions(t *testing.T) {
+	router := newPermissionTestRouterFor(app.RequestUser{ID: bson.NewObjectID(), Email: "user@acme.com", Role: model.RoleOrganizationAdmin, IsActive: true, SSOSession: true})
+	for key, perm := range routePermissions {
+		t.Run(key, func(t *testing.T) {
+			method, path, _ := strings.Cut(key, " ")
+			path = routeParam.ReplaceAllString(path, bson.NewObjectID().Hex())
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+			denied := w.Code == http.StatusForbidden && strings.Contains(w.Body.String(), "missing permission")
+			allowed := perm != app.PermIdentity && (perm == "" || app.HasPermission(model.RoleOrganizationAdmin, perm))
+			if allowed == denied {
+				t.Fatalf("expected allowed=%v for sso session, got %d %s", allowed, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func AddPlanRoutes(router *gin.RouterGroup) {
+	router.GET("/plans", listPlansHandler)
+	router.GET("/admin/plans", app.RequirePermission(app.PermPlatformAdmin), adminListPlansHandler)
+	router.POST("/admin/plans", app.RequirePermission(app.PermPlatformAdmin), createPlanHandler)
+	router.PATCH("/admin/plans/:id", app.RequirePermission(app.PermPlatformAdmin), updatePlanHandler)
+}
+
+func listPlansHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	plans, err := di.PlanService.ListPlans(reqCtx, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to list plans"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(plans))
+}
+
+func adminListPlansHandler(c *gin.Context) {
+	reqCtx, di, ok := superAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	plans, err := di.PlanService.ListPlans(reqCtx, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to list plans"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(plans))
+}
+
+func createPlanHandler(c *gin.Context) {
+	reqCtx, di, ok := superAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreatePlan
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	plan, err := di.PlanService.CreatePlan(reqCtx, &req)
+	if err != nil {
+		log.Printf("failed to create plan: %v", err)
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("failed to create plan: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.NewOkResponse(plan))
+}
+
+func updatePlanHandler(c *gin.Context) {
+	reqCtx, di, ok := superAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	planID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid plan ID"))
+		return
+	}
+
+	var req model.UpdatePlan
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	plan, err := di.PlanService.UpdatePlan(reqCtx, planID, &req)
+	if err != nil {
+		if err.Error() == "plan not found" {
+			c.JSON(http.StatusNotFound, utils.NewErrorResponse("plan not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to update plan"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(plan))
+}
@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func AddQuotaRoutes(router *gin.RouterGroup) {
+	router.GET("/quota", app.RequirePermission(app.PermUsageRead), getQuotaStatusHandler)
+	router.GET("/admin/organizations/:id/quota-override", app.RequirePermission(app.PermPlatformAdmin), getQuotaOverrideHandler)
+	router.PUT("/admin/organizations/:id/quota-override", app.RequirePermission(app.PermPlatformAdmin), setQuotaOverrideHandler)
+	router.DELETE("/admin/organizations/:id/quota-override", app.RequirePermission(app.PermPlatformAdmin), clearQuotaOverrideHandler)
+}
+
+func getQuotaStatusHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	status, err := di.QuotaService.GetQuotaStatus(reqCtx)
+	if err != nil {
+		log.Printf("failed to get quota status: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get quota status"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(status))
+}
+
+func getQuotaOverrideHandler(c *gin.Context) {
+	reqCtx, di, orgID, ok := quotaAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	override, err := di.QuotaService.GetOverride(reqCtx, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get quota override"))
+		return
+	}
+	if override == nil {
+		c.JSON(http.StatusNotFound, utils.NewErrorResponse("quota override not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(override))
+}
+
+func setQuotaOverrideHandler(c *gin.Context) {
+	reqCtx, di, orgID, ok := quotaAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateQuotaOverride
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	override, err := di.QuotaService.SetOverride(reqCtx, orgID, &req)
+	if err != nil {
+		log.Printf("failed to set quota override: %v", err)
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("failed to set quota override: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(override))
+}
+
+func clearQuotaOverrideHandler(c *gin.Context) {
+	reqCtx, di, orgID, ok := quotaAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := di.QuotaService.ClearOverride(reqCtx, orgID); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to clear quota override"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse("quota override removed"))
+}
+
+// quotaAdminRequest resolves the request for the override endpoints, which are
+// restricted to platform super admins.
+func quotaAdminRequest(c *gin.Context) (*app.RequestContext, *app_di.AppDI, bson.ObjectID, bool) {
+	reqCtx, di, ok := superAdminRequest(c)
+	if !ok {
+		return nil, nil, bson.NilObjectID, false
+	}
+
+	orgID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid organization ID"))
+		return nil, nil, bson.NilObjectID, false
+	}
+	return reqCtx, di, orgID, true
+}
@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+// AddRetentionRoutes registers the dry-run report of the retention policies
+// and the legal holds that exempt records from them. The policies themselves
+// are part of the organization settings.
+func AddRetentionRoutes(router *gin.RouterGroup) {
+	router.GET("/organization/retention/report", app.RequirePermission(app.PermOrgManage), retentionReportHandler)
+	router.PUT("/categories/:categoryID/data/:dataID/legal-hold", app.RequirePermission(app.PermOrgManage), setCategoryDataLegalHoldHandler)
+	router.PUT("/batch/:batchID/legal-hold", app.RequirePermission(app.PermOrgManage), setBatchLegalHoldHandler)
+}
+
+func retentionReportHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	report, err := di.RetentionService.Report(reqCtx)
+	if err != nil {
+		log.Printf("failed to report retention: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to report retention"))
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(report))
+}
+
+func setCategoryDataLegalHoldHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+	categoryID, err := bson.ObjectIDFromHex(c.Param("categoryID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid category ID"))
+		return
+	}
+	dataID, err := bson.ObjectIDFromHex(c.Param("dataID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid category data ID"))
+		return
+	}
+	var req model.LegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	data, err := di.CategoryDataService.SetLegalHold(reqCtx, categoryID, dataID, req.LegalHold)
+	if err != nil {
+		log.Printf("failed to set legal hold: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to set legal hold"))
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(data))
+}
+
+func setBatchLegalHoldHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+	batchID, err := bson.ObjectIDFromHex(c.Param("batchID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid batch ID"))
+		return
+	}
+	var req model.LegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	batch, err := di.BatchService.SetLegalHold(reqCtx, batchID, req.LegalHold)
+	if err != nil {
+		log.Printf("failed to set batch legal hold: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to set legal hold"))
+		return
+	}
+	c.JSON(http.StatusOK, utils.NewOkResponse(batch))
+}
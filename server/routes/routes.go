@@ -0,0 +1,30 @@
+package routes
+
+import "github.com/gin-gonic/gin"
+
+// AddProtectedRoutes registers the routes that require a signed in user. Each
+// route declares the permissions it needs with app.RequirePermission.
+func AddProtectedRoutes(protected *gin.RouterGroup) {
+	AddMeRoutes(protected)
+	AddOpenAiRoutes(protected)
+	AddCategoryRoutes(protected)
+	AddCategoryDataRoutes(protected)
+	AddScanHistoryRoutes(protected)
+	AddExportRoutes(protected)
+	AddBillingRoutes(protected)
+	AddScanRoutes(protected)
+	AddBatchRoutes(protected)
+	AddPaymentRoutes(protected)
+	AddOrganizationRoutes(protected)
+	AddSSORoutes(protected)
+	AddTeamRoutes(protected)
+	AddAPIKeyRoutes(protected)
+	AddQuotaRoutes(protected)
+	AddPlanRoutes(protected)
+	AddMeterRoutes(protected)
+	AddCacheRoutes(protected)
+	AddDeletionRoutes(protected)
+	AddDataExportRoutes(protected)
+	AddRetentionRoutes(protected)
+	AddEncryptionRoutes(protected)
+}
@@ -14,45 +14,54 @@
 package routes
 
 import (
+	"errors"
 	"log"
+	"mime"
+	"path"
 	"strings"
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/storage"
 	"github.com/gaeaglobal/exto/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 func AddScanHistoryRoutes(router *gin.RouterGroup) {
-	router.GET("/scan-history", getScanHistory)
-	router.GET("/scan-history/data/:scanHistoryID", getScannedDocumentDataEndpoint)
-	router.GET("/scan-history/document/:scanHistoryID", getScannedDocumentEndpoint)
+	router.GET("/scan-history", app.RequirePermission(app.PermScanRead), getScanHistory)
+	router.GET("/scan-history/data/:scanHistoryID", app.RequirePermission(app.PermScanRead), getScannedDocumentDataEndpoint)
+	router.GET("/scan-history/document/:scanHistoryID", app.RequirePermission(app.PermScanRead), getScannedDocumentEndpoint)
+	router.GET("/scan-history/document/:scanHistoryID/redacted", app.RequirePermission(app.PermScanRead), getRedactedDocumentEndpoint)
 }
 
-func getScannedDocumentEndpoint(c *gin.Context) {
+// scannedDocumentData returns the record of the scan of the request, whose
+// document is served. It responds with an error when it returns false.
+func scannedDocumentData(c *gin.Context) (*app_di.AppDI, *app.RequestContext, *model.CategoryData, bool) {
 	di, found := app_di.GetAppDI(c)
 	if !found {
 		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to get application DI"))
-		return
+		return nil, nil, nil, false
 	}
 
 	reqCtx := app.GetRequestCtx(c)
 	if reqCtx.IsZero() {
 		c.AbortWithStatusJSON(400, utils.NewErrorResponse("Current user not found"))
-		return
+		return nil, nil, nil, false
 	}
 
 	scanHistoryID := c.Param("scanHistoryID")
 	if scanHistoryID == "" {
 		c.AbortWithStatusJSON(400, utils.NewErrorResponse("Scan History ID is required"))
-		return
+		return nil, nil, nil, false
 	}
 
 	scanHistoryIDObj, err := bson.ObjectIDFromHex(scanHistoryID)
 	if err != nil {
 		c.AbortWithStatusJSON(400, utils.NewErrorResponse("Invalid scan history ID format"))
-		return
+		return nil, nil, nil, false
 	}
 
 	scanHistory, err := di.ScanHistoryService.GetScanHistoryByID(reqCtx, scanHistoryIDObj)
@@ -60,11 +69,11 @@ func getScannedDocumentEndpoint(c *gin.Context) {
 	if err != nil {
 		log.Printf("Error retrieving scan history: %v", err)
 		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to retrieve scan history"))
-		return
+		return nil, nil, nil, false
 	}
 	if scanHistory == nil {
 		c.AbortWithStatusJSON(404, utils.NewErrorResponse("Scan history not found"))
-		return
+		return nil, nil, nil, false
 	}
 
 	categoryIDObj := scanHistory.CategoryID
@@ -73,17 +82,62 @@ func getScannedDocumentEndpoint(c *gin.Context) {
 	categoryData, err := di.CategoryDataService.GetCategoryDataByID(reqCtx, categoryIDObj, dataIDObj)
 	if err != nil || categoryData == nil {
 		c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to retrieve category data"))
-		return
+		return nil, nil, nil, false
 	}
 	if len(categoryData.DocumentPaths) == 0 || categoryData.DocumentPaths[0] == "" {
 		c.AbortWithStatusJSON(404, utils.NewErrorResponse("Document not found"))
+		return nil, nil, nil, false
+	}
+	return di, reqCtx, categoryData, true
+}
+
+// getScannedDocumentEndpoint serves the uploaded document. Users that may not
+// read personal data get the redacted rendering of the documents holding
+// some.
+func getScannedDocumentEndpoint(c *gin.Context) {
+	di, reqCtx, categoryData, ok := scannedDocumentData(c)
+	if !ok {
+		return
+	}
+	if len(categoryData.PII) > 0 && !di.PIIService.CanRead(reqCtx) {
+		serveRedactedDocument(c, di, reqCtx, categoryData)
+		return
+	}
+
+	documentKey := categoryData.DocumentPaths[0]
+	documentName := documentKey[strings.LastIndex(documentKey, "/")+1:]
+	streamBlob(c, documentKey, documentName)
+}
+
+// getRedactedDocumentEndpoint serves the document with its personal data
+// blacked out, as a PNG image.
+func getRedactedDocumentEndpoint(c *gin.Context) {
+	di, reqCtx, categoryData, ok := scannedDocumentData(c)
+	if !ok {
 		return
 	}
+	serveRedactedDocument(c, di, reqCtx, categoryData)
+}
 
-	documentPath := categoryData.DocumentPaths[0]
-	documentName := documentPath[strings.LastIndex(documentPath, "/")+1:]
-	c.Header("Content-Disposition", "attachment; filename="+documentName)
-	c.File(documentPath)
+func serveRedactedDocument(c *gin.Context, di *app_di.AppDI, reqCtx *app.RequestContext, categoryData *model.CategoryData) {
+	redacted, err := di.PIIService.RedactDocument(reqCtx, categoryData)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRedactionUnsupported), errors.Is(err, service.ErrPIILocationUnknown):
+			c.AbortWithStatusJSON(409, utils.NewErrorResponse(err.Error()))
+		case errors.Is(err, storage.ErrBlobNotFound):
+			c.AbortWithStatusJSON(404, utils.NewErrorResponse("File not found"))
+		default:
+			log.Printf("Error redacting document: %v", err)
+			c.AbortWithStatusJSON(500, utils.NewErrorResponse("Failed to redact document"))
+		}
+		return
+	}
+
+	documentKey := categoryData.DocumentPaths[0]
+	documentName := strings.TrimSuffix(documentKey[strings.LastIndex(documentKey, "/")+1:], path.Ext(documentKey)) + "-redacted.png"
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": documentName}))
+	c.Data(200, "image/png", redacted)
 }
 
 func getScannedDocumentDataEndpoint(c *gin.Context) {
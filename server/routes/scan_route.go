@@ -14,11 +14,14 @@
 package routes
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/file_utils"
+	"github.com/gaeaglobal/exto/server/service"
 	"github.com/gaeaglobal/exto/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -33,7 +36,7 @@ type ExtractResponseParams struct {
 }
 
 func AddScanRoutes(router *gin.RouterGroup) {
-	router.POST("/scan/document", newScanImageEndpoint)
+	router.POST("/scan/document", app.RequirePermission(app.PermScanCreate), newScanImageEndpoint)
 }
 
 func newScanImageEndpoint(c *gin.Context) {
@@ -84,10 +87,21 @@ func newScanImageEndpoint(c *gin.Context) {
 		return
 	}
 
+	// force_refresh bypasses the extraction cache
+	forceRefresh := c.PostForm("force_refresh") == "true"
+
 	// Perform the scan using ScanService
 	scanService := di.ScanService
-	scanResult, err := scanService.PerformScan(appCtx, reqCtx, c, categoryObjID, batchObjID)
+	scanResult, err := scanService.PerformScan(appCtx, reqCtx, c, categoryObjID, batchObjID, forceRefresh)
 	if err != nil {
+		var quotaErr *service.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			c.AbortWithStatusJSON(http.StatusPaymentRequired, utils.NewErrorResponseWithDetails(quotaErr.Error(), quotaErr.Details()))
+			return
+		}
+		if respondUploadError(c, err) {
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to perform scan: "+err.Error()))
 		return
 	}
@@ -101,3 +115,30 @@ func newScanImageEndpoint(c *gin.Context) {
 	}))
 
 }
+
+// uploadErrorStatus is the status of the response to an upload rejected for
+// each reason.
+var uploadErrorStatus = map[file_utils.UploadErrorCode]int{
+	file_utils.UploadFileMissing:       http.StatusBadRequest,
+	file_utils.UploadFileTooLarge:      http.StatusRequestEntityTooLarge,
+	file_utils.UploadUnsupportedType:   http.StatusUnsupportedMediaType,
+	file_utils.UploadTooManyPages:      http.StatusUnprocessableEntity,
+	file_utils.UploadImageTooLarge:     http.StatusUnprocessableEntity,
+	file_utils.UploadMalwareDetected:   http.StatusUnprocessableEntity,
+	file_utils.UploadMalwareScanFailed: http.StatusServiceUnavailable,
+}
+
+// respondUploadError answers a rejected upload with the reason of the
+// rejection, and reports whether err was one.
+func respondUploadError(c *gin.Context, err error) bool {
+	var uploadErr *file_utils.UploadError
+	if !errors.As(err, &uploadErr) {
+		return false
+	}
+	status, found := uploadErrorStatus[uploadErr.Code]
+	if !found {
+		status = http.StatusBadRequest
+	}
+	c.AbortWithStatusJSON(status, utils.NewErrorResponseWithDetails(uploadErr.Error(), uploadErr.Details()))
+	return true
+}
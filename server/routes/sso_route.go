@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func AddSSORoutes(router *gin.RouterGroup) {
+	router.GET("/organization/sso", app.RequirePermission(app.PermOrgManage), getSSOConfigHandler)
+	router.PUT("/organization/sso", app.RequirePermission(app.PermOrgManage), updateSSOConfigHandler)
+	router.POST("/organization/sso/domains/:domain/verify", app.RequirePermission(app.PermOrgManage), verifySSODomainHandler)
+}
+
+func getSSOConfigHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	sso, err := di.SSOService.GetSSOConfig(reqCtx)
+	if err != nil {
+		log.Printf("failed to get sso config: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get sso config"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(sso))
+}
+
+func updateSSOConfigHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.SSOConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	org, err := di.SSOService.UpdateSSOConfig(reqCtx, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSSOConfig):
+			c.JSON(http.StatusBadRequest, utils.NewErrorResponse(err.Error()))
+		case errors.Is(err, service.ErrSSOConfigInUse):
+			c.JSON(http.StatusConflict, utils.NewErrorResponse(err.Error()))
+		default:
+			log.Printf("failed to update sso config: %v", err)
+			c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to update sso config"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(org.SSO))
+}
+
+// verifySSODomainHandler checks the DNS TXT record proving the organization
+// owns one of its SSO domains.
+func verifySSODomainHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	domain, err := di.SSOService.VerifyDomain(reqCtx, c.Param("domain"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSSODomainUnknown):
+			c.JSON(http.StatusNotFound, utils.NewErrorResponse(err.Error()))
+		case errors.Is(err, service.ErrSSODomainUnverified):
+			c.JSON(http.StatusUnprocessableEntity, utils.NewErrorResponse(err.Error()))
+		default:
+			log.Printf("failed to verify sso domain: %v", err)
+			c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to verify sso domain"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(domain))
+}
+
+// ssoLoginEndpoint signs in with an ID token of an organization's SSO
+// provider and provisions the user on the first sign-in.
+func ssoLoginEndpoint(c *gin.Context) {
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to get application dependencies"))
+		return
+	}
+
+	tokenUser, err := app.GetUserFromOIDCToken(c)
+	if err != nil {
+		log.Printf("failed to verify sso token: %v", err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, utils.NewErrorResponse("Invalid SSO ID token"))
+		return
+	}
+
+	reqCtx, err := di.SSOService.Provision(&app.RequestContext{}, tokenUser)
+	if err != nil {
+		if errors.Is(err, service.ErrUserDeactivated) || errors.Is(err, service.ErrSSODomainDenied) {
+			c.AbortWithStatusJSON(http.StatusForbidden, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrSSOAccountExists) {
+			c.AbortWithStatusJSON(http.StatusConflict, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		log.Printf("failed to provision sso user: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewErrorResponse("Failed to sign in"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(reqCtx))
+}
@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func AddTeamRoutes(router *gin.RouterGroup) {
+	router.GET("/organization/users", app.RequirePermission(app.PermOrgManage), listOrganizationUsersHandler)
+	router.PATCH("/organization/users/:id/role", app.RequirePermission(app.PermOrgManage), changeUserRoleHandler)
+	router.POST("/organization/users/:id/deactivate", app.RequirePermission(app.PermOrgManage), deactivateUserHandler)
+	router.GET("/organization/invitations", app.RequirePermission(app.PermOrgManage), listInvitationsHandler)
+	router.POST("/organization/invitations", app.RequirePermission(app.PermOrgManage), createInvitationHandler)
+	router.DELETE("/organization/invitations/:id", app.RequirePermission(app.PermOrgManage), revokeInvitationHandler)
+	router.POST("/invitations/accept", app.RequirePermission(app.PermIdentity), acceptInvitationHandler)
+}
+
+func listOrganizationUsersHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	users, err := di.UserService.ListOrganizationUsers(reqCtx, app.NewPageRequest(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to list users"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(users))
+}
+
+func changeUserRoleHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	userID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid user ID"))
+		return
+	}
+
+	var req model.UpdateUserRole
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	user, err := di.UserService.ChangeUserRole(reqCtx, userID, req.Role)
+	if err != nil {
+		log.Printf("failed to change user role: %v", err)
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("failed to change user role: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(user))
+}
+
+func deactivateUserHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	userID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid user ID"))
+		return
+	}
+
+	user, err := di.UserService.DeactivateUser(reqCtx, userID)
+	if err != nil {
+		log.Printf("failed to deactivate user: %v", err)
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("failed to deactivate user: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(user))
+}
+
+func listInvitationsHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := di.InvitationService.ListInvitations(reqCtx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to list invitations"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(invitations))
+}
+
+func createInvitationHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateInvitation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	invitation, err := di.InvitationService.CreateInvitation(reqCtx, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) || errors.Is(err, service.ErrAlreadyMember) {
+			c.JSON(http.StatusBadRequest, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		log.Printf("failed to create invitation: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to create invitation"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.NewOkResponse(invitation))
+}
+
+func revokeInvitationHandler(c *gin.Context) {
+	reqCtx, di, ok := orgAdminRequest(c)
+	if !ok {
+		return
+	}
+
+	invitationID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid invitation ID"))
+		return
+	}
+
+	if err := di.InvitationService.RevokeInvitation(reqCtx, invitationID); err != nil {
+		if errors.Is(err, service.ErrInvitationNotFound) {
+			c.JSON(http.StatusNotFound, utils.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to revoke invitation"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse("invitation revoked"))
+}
+
+// acceptInvitationHandler lets a signed in user join the organization they
+// were invited to.
+func acceptInvitationHandler(c *gin.Context) {
+	reqCtx := app.GetRequestCtx(c)
+	if reqCtx.IsZero() {
+		c.JSON(http.StatusUnauthorized, utils.NewErrorResponse("Unauthorized"))
+		return
+	}
+
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	var req model.AcceptInvitation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid request payload"))
+		return
+	}
+
+	accepted, err := di.InvitationService.AcceptInvitation(reqCtx, req.Token, reqCtx.User.Email, "", "")
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse(accepted))
+}
+
+func respondInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, utils.NewErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrInvitationExpired):
+		c.JSON(http.StatusGone, utils.NewErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrInvitationEmailMismatch):
+		c.JSON(http.StatusForbidden, utils.NewErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrAlreadyMember):
+		c.JSON(http.StatusConflict, utils.NewErrorResponse(err.Error()))
+	default:
+		log.Printf("failed to accept invitation: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to accept invitation"))
+	}
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app_di"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+// maxWebhookBodyBytes bounds the size of a webhook payload.
+const maxWebhookBodyBytes = 65536
+
+func AddWebhookRoutes(router *gin.RouterGroup) {
+	router.POST("/stripe", stripeWebhookHandler)
+}
+
+func stripeWebhookHandler(c *gin.Context) {
+	di, found := app_di.GetAppDI(c)
+	if !found {
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to get app DI"))
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, utils.NewErrorResponse("failed to read request body"))
+		return
+	}
+
+	event, err := di.StripeWebhookService.ConstructEvent(payload, c.GetHeader("Stripe-Signature"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWebhookSignature) {
+			c.JSON(http.StatusBadRequest, utils.NewErrorResponse("invalid signature"))
+			return
+		}
+		log.Printf("failed to construct stripe event: %v", err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to process webhook"))
+		return
+	}
+
+	if err := di.StripeWebhookService.HandleEvent(event); err != nil {
+		log.Printf("failed to handle stripe event %s: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, utils.NewErrorResponse("failed to process webhook"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewOkResponse("received"))
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+var (
+	ErrAPIKeyNotFound   = errors.New("api key not found")
+	ErrInvalidAPIKeyReq = errors.New("invalid api key request")
+)
+
+// APIKeyService issues the API keys machines use to call the API for an
+// organization. The full key is only returned when it is issued or rotated.
+type APIKeyService struct {
+	repo repo.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repo.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo: repo,
+	}
+}
+
+func (s *APIKeyService) EnsureIndexes() error {
+	return s.repo.EnsureIndexes()
+}
+
+func (s *APIKeyService) ListAPIKeys(reqCtx *app.RequestContext) ([]*model.APIKey, error) {
+	return s.repo.ListAPIKeys(reqCtx, reqCtx.Org.ID)
+}
+
+func (s *APIKeyService) CreateAPIKey(reqCtx *app.RequestContext, req *model.CreateAPIKey) (*model.CreatedAPIKey, error) {
+	for _, scope := range req.Scopes {
+		if !slices.Contains(app.APIKeyScopes, app.Permission(scope)) {
+			return nil, fmt.Errorf("%w: scope %q cannot be granted to an api key", ErrInvalidAPIKeyReq, scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyReq)
+	}
+
+	key, prefix, secretHash, err := utils.NewAPIKey()
+	if err != nil {
+		log.Printf("failed to generate api key: %v", err)
+		return nil, errors.New("failed to generate api key")
+	}
+	apiKey, err := s.repo.CreateAPIKey(reqCtx, &model.APIKey{
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: secretHash,
+		Scopes:     slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// RotateAPIKey issues a new secret for the key. The old secret stops working
+// immediately.
+func (s *APIKeyService) RotateAPIKey(reqCtx *app.RequestContext, id bson.ObjectID) (*model.CreatedAPIKey, error) {
+	key, prefix, secretHash, err := utils.NewAPIKey()
+	if err != nil {
+		log.Printf("failed to generate api key: %v", err)
+		return nil, errors.New("failed to generate api key")
+	}
+	apiKey, err := s.repo.RotateAPIKey(reqCtx, reqCtx.Org.ID, id, prefix, secretHash)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &model.CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	revoked, err := s.repo.RevokeAPIKey(reqCtx, reqCtx.Org.ID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	keyRepo := mocks.NewMockAPIKeyRepository(ctrl)
+	svc := service.NewAPIKeyService(keyRepo)
+	reqCtx := app.NewMockRequestContext()
+
+	keyRepo.EXPECT().CreateAPIKey(reqCtx, gomock.Any()).DoAndReturn(func(_ *app.RequestContext, key *model.APIKey) (*model.APIKey, error) {
+		return key, nil
+	})
+
+	created, err := svc.CreateAPIKey(reqCtx, &model.CreateAPIKey{Name: "scanner", Scopes: []string{"scan:create", "scan:read", "scan:create"}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey returned error: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, utils.APIKeyPrefix+"_"+created.APIKey.Prefix+"_") {
+		t.Errorf("key %q does not carry prefix %q", created.Key, created.APIKey.Prefix)
+	}
+	_, secret, ok := utils.ParseAPIKey(created.Key)
+	if !ok || !utils.VerifyAPIKeySecret(secret, created.APIKey.SecretHash) {
+		t.Errorf("stored hash does not match the issued key")
+	}
+	if len(created.APIKey.Scopes) != 2 {
+		t.Errorf("expected duplicate scopes to be removed, got %v", created.APIKey.Scopes)
+	}
+
+	_, err = svc.CreateAPIKey(reqCtx, &model.CreateAPIKey{Name: "admin", Scopes: []string{"org:manage"}})
+	if !errors.Is(err, service.ErrInvalidAPIKeyReq) {
+		t.Fatalf("expected ErrInvalidAPIKeyReq, got %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+const (
+	accessTokenTTL       = 15 * time.Minute
+	refreshTokenTTL      = 30 * 24 * time.Hour
+	emailVerificationTTL = 24 * time.Hour
+	magicLinkTTL         = 15 * time.Minute
+
+	minPasswordLength = 8
+	maxPasswordLength = 128
+)
+
+var (
+	ErrLocalAuthDisabled  = errors.New("password and magic-link sign-in is not configured")
+	ErrEmailTaken         = errors.New("an account with this email already exists")
+	ErrWeakPassword       = fmt.Errorf("password must be between %d and %d characters", minPasswordLength, maxPasswordLength)
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailNotVerified   = errors.New("email address is not verified")
+	ErrInvalidAuthToken   = errors.New("invalid or expired token")
+)
+
+// AuthService is the first-party identity provider. It signs identities in
+// with a password or an emailed magic link and issues signed access and
+// refresh tokens, which are accepted with the "exto" X-Auth-Provider.
+type AuthService struct {
+	appCtx            *app.AppContext
+	dbSessionProvider db.SessionProvider
+	identityRepo      repo.IdentityRepo
+	tokenRepo         repo.AuthTokenRepository
+	userService       *UserService
+	invitationService *InvitationService
+	mailer            Mailer
+}
+
+func NewAuthService(appCtx *app.AppContext, dbSessionProvider db.SessionProvider, identityRepo repo.IdentityRepo, tokenRepo repo.AuthTokenRepository, userService *UserService, invitationService *InvitationService, mailer Mailer) *AuthService {
+	return &AuthService{
+		appCtx:            appCtx,
+		dbSessionProvider: dbSessionProvider,
+		identityRepo:      identityRepo,
+		tokenRepo:         tokenRepo,
+		userService:       userService,
+		invitationService: invitationService,
+		mailer:            mailer,
+	}
+}
+
+func (s *AuthService) EnsureIndexes() error {
+	return s.tokenRepo.EnsureIndexes()
+}
+
+// SignUp creates an identity with a password, either with its own
+// organization or by accepting an invitation, and emails a verification link.
+// The identity can sign in once the email is verified.
+func (s *AuthService) SignUp(reqCtx *app.RequestContext, req *model.PasswordSignUp) (*app.RequestContext, error) {
+	if err := s.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		return nil, ErrWeakPassword
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	exists, err := s.identityRepo.IsIdentityExists(reqCtx, email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrEmailTaken
+	}
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("failed to hash password: %v", err)
+		return nil, errors.New("failed to create account")
+	}
+
+	// The identity, its organization or membership, its password and its
+	// verification token are created together, so a failed sign-up does not
+	// leave an account without a password behind. Like in
+	// CreateAndSetupIdentity, the transaction is run again when the slug of
+	// the new organization is taken by a concurrent sign-up.
+	var created *app.RequestContext
+	for attempt := 1; ; attempt++ {
+		err = app.WithTransaction(reqCtx, s.dbSessionProvider, func(txCtx *app.RequestContext) error {
+			var err error
+			if req.InviteToken != "" {
+				created, err = s.invitationService.AcceptInvitation(txCtx, req.InviteToken, email, req.FirstName, req.LastName)
+			} else {
+				created, err = s.userService.CreateAndSetupIdentity(s.appCtx, txCtx, email, req.FirstName, req.LastName)
+			}
+			if err != nil {
+				return err
+			}
+			if err := s.identityRepo.SetPasswordHash(txCtx, created.User.IdentityID, passwordHash); err != nil {
+				return err
+			}
+			token, err := s.createAuthToken(txCtx, created.User.IdentityID, email, model.AuthTokenEmailVerification, emailVerificationTTL)
+			if err != nil {
+				return err
+			}
+			txCtx.AfterCommit(func() {
+				// A lost email is recovered with a magic link, which also
+				// verifies the email.
+				if err := s.sendEmailVerification(email, token); err != nil {
+					log.Printf("failed to send email verification of identity %s: %v", created.User.IdentityID.Hex(), err)
+				}
+			})
+			return nil
+		})
+		if !errors.Is(err, repo.ErrSlugTaken) || attempt == maxSlugAttempts {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// VerifyEmail consumes an email verification token.
+func (s *AuthService) VerifyEmail(reqCtx *app.RequestContext, token string) error {
+	authToken, err := s.tokenRepo.ConsumeAuthToken(reqCtx, model.AuthTokenEmailVerification, hashSecretToken(token))
+	if err != nil {
+		return err
+	}
+	if authToken == nil {
+		return ErrInvalidAuthToken
+	}
+	return s.identityRepo.MarkEmailVerified(reqCtx, authToken.IdentityID)
+}
+
+// Login signs an identity in with its password.
+func (s *AuthService) Login(reqCtx *app.RequestContext, req *model.PasswordLogin) (*model.TokenPair, error) {
+	if err := s.checkEnabled(); err != nil {
+		return nil, err
+	}
+	identity, err := s.identityRepo.GetIdentityByEmail(reqCtx, strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil || identity.PasswordHash == "" || !identity.IsActive {
+		return nil, ErrInvalidCredentials
+	}
+	ok, err := utils.VerifyPassword(req.Password, identity.PasswordHash)
+	if err != nil {
+		log.Printf("failed to verify password of identity %s: %v", identity.ID.Hex(), err)
+		return nil, ErrInvalidCredentials
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if !identity.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	return s.issueTokens(reqCtx, identity.ID, identity.Email)
+}
+
+// RequestMagicLink emails a sign-in link to the identity. Unknown emails are
+// ignored without an error so the endpoint does not reveal which accounts exist.
+func (s *AuthService) RequestMagicLink(reqCtx *app.RequestContext, email string) error {
+	if err := s.checkEnabled(); err != nil {
+		return err
+	}
+	identity, err := s.identityRepo.GetIdentityByEmail(reqCtx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil || !identity.IsActive {
+		return nil
+	}
+
+	token, err := s.createAuthToken(reqCtx, identity.ID, identity.Email, model.AuthTokenMagicLink, magicLinkTTL)
+	if err != nil {
+		return err
+	}
+	link := s.appCtx.Config.APP_BASE_URL + "/magic-link?token=" + token
+	return s.mailer.Send(identity.Email, "Your Exto sign-in link",
+		"Use this link to sign in to Exto. It expires in 15 minutes and can be used once.\n\n"+link)
+}
+
+// VerifyMagicLink consumes a magic-link token and signs the identity in. The
+// link proves ownership of the email, so the email is marked as verified.
+// When the email was not verified yet, whoever set the password of the
+// identity did not prove they own the email: the password is cleared and the
+// sessions and pending verifications of the identity are revoked, so an
+// account registered ahead of its owner cannot be taken over.
+func (s *AuthService) VerifyMagicLink(reqCtx *app.RequestContext, token string) (*model.TokenPair, error) {
+	if err := s.checkEnabled(); err != nil {
+		return nil, err
+	}
+	authToken, err := s.tokenRepo.ConsumeAuthToken(reqCtx, model.AuthTokenMagicLink, hashSecretToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if authToken == nil {
+		return nil, ErrInvalidAuthToken
+	}
+	identity, err := s.identityRepo.GetIdentityByEmail(reqCtx, authToken.Email)
+	if err != nil || identity.ID != authToken.IdentityID || !identity.IsActive {
+		return nil, ErrInvalidAuthToken
+	}
+	if !identity.EmailVerified {
+		if identity.PasswordHash != "" {
+			if err := s.identityRepo.SetPasswordHash(reqCtx, identity.ID, ""); err != nil {
+				return nil, err
+			}
+		}
+		revoked := []model.AuthTokenPurpose{model.AuthTokenRefresh, model.AuthTokenEmailVerification}
+		if err := s.tokenRepo.RevokeAuthTokens(reqCtx, identity.ID, revoked); err != nil {
+			return nil, err
+		}
+		if err := s.identityRepo.MarkEmailVerified(reqCtx, identity.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.issueTokens(reqCtx, identity.ID, identity.Email)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Refresh tokens are
+// rotated: each one can be used once.
+func (s *AuthService) Refresh(reqCtx *app.RequestContext, refreshToken string) (*model.TokenPair, error) {
+	if err := s.checkEnabled(); err != nil {
+		return nil, err
+	}
+	authToken, err := s.consumeRefreshToken(reqCtx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := s.identityRepo.GetIdentityByEmail(reqCtx, authToken.Email)
+	if err != nil || !identity.IsActive {
+		return nil, ErrInvalidAuthToken
+	}
+	return s.issueTokens(reqCtx, identity.ID, identity.Email)
+}
+
+// Logout revokes the refresh token. Access tokens stay valid until they expire.
+func (s *AuthService) Logout(reqCtx *app.RequestContext, refreshToken string) error {
+	if err := s.checkEnabled(); err != nil {
+		return err
+	}
+	_, err := s.consumeRefreshToken(reqCtx, refreshToken)
+	if errors.Is(err, ErrInvalidAuthToken) {
+		return nil
+	}
+	return err
+}
+
+func (s *AuthService) consumeRefreshToken(reqCtx *app.RequestContext, refreshToken string) (*model.AuthToken, error) {
+	claims, err := utils.ParseJWT(refreshToken, s.jwtSecret())
+	if err != nil || claims.Type != app.RefreshTokenType || claims.ID == "" {
+		return nil, ErrInvalidAuthToken
+	}
+	authToken, err := s.tokenRepo.ConsumeAuthToken(reqCtx, model.AuthTokenRefresh, hashSecretToken(claims.ID))
+	if err != nil {
+		return nil, err
+	}
+	if authToken == nil || authToken.IdentityID.Hex() != claims.Subject {
+		return nil, ErrInvalidAuthToken
+	}
+	return authToken, nil
+}
+
+func (s *AuthService) issueTokens(reqCtx *app.RequestContext, identityID bson.ObjectID, email string) (*model.TokenPair, error) {
+	now := time.Now()
+	accessToken, err := utils.SignJWT(&utils.JWTClaims{
+		Subject:   identityID.Hex(),
+		Email:     email,
+		Type:      app.AccessTokenType,
+		Issuer:    utils.JWTIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTokenTTL).Unix(),
+	}, s.jwtSecret())
+	if err != nil {
+		log.Printf("failed to sign access token: %v", err)
+		return nil, errors.New("failed to issue tokens")
+	}
+
+	jti, err := s.createAuthToken(reqCtx, identityID, email, model.AuthTokenRefresh, refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.SignJWT(&utils.JWTClaims{
+		Subject:   identityID.Hex(),
+		Email:     email,
+		Type:      app.RefreshTokenType,
+		ID:        jti,
+		Issuer:    utils.JWTIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(refreshTokenTTL).Unix(),
+	}, s.jwtSecret())
+	if err != nil {
+		log.Printf("failed to sign refresh token: %v", err)
+		return nil, errors.New("failed to issue tokens")
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (s *AuthService) sendEmailVerification(email string, token string) error {
+	link := s.appCtx.Config.APP_BASE_URL + "/verify-email?token=" + token
+	return s.mailer.Send(email, "Verify your Exto email address",
+		"Confirm your email address to finish setting up your Exto account.\n\n"+link)
+}
+
+// createAuthToken stores the hash of a new secret token and returns the token.
+func (s *AuthService) createAuthToken(reqCtx *app.RequestContext, identityID bson.ObjectID, email string, purpose model.AuthTokenPurpose, ttl time.Duration) (string, error) {
+	token, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.tokenRepo.CreateAuthToken(reqCtx, &model.AuthToken{
+		IdentityID: identityID,
+		Email:      email,
+		Purpose:    purpose,
+		TokenHash:  hashSecretToken(token),
+		ExpiresAt:  time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AuthService) checkEnabled() error {
+	if s.appCtx.Config.AUTH_JWT_SECRET == "" {
+		return ErrLocalAuthDisabled
+	}
+	return nil
+}
+
+func (s *AuthService) jwtSecret() []byte {
+	return []byte(s.appCtx.Config.AUTH_JWT_SECRET)
+}
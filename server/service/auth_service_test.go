@@ -0,0 +1,277 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+type sentMail struct {
+	to   string
+	body string
+}
+
+type fakeMailer struct {
+	sent []sentMail
+}
+
+func (m *fakeMailer) Send(to string, subject string, body string) error {
+	m.sent = append(m.sent, sentMail{to: to, body: body})
+	return nil
+}
+
+// expectAuthTokenStore backs the token repository with a map so tokens behave
+// as single use.
+func expectAuthTokenStore(tokenRepo *mocks.MockAuthTokenRepository) {
+	tokens := map[string]*model.AuthToken{}
+	tokenRepo.EXPECT().CreateAuthToken(gomock.Any(), gomock.Any()).DoAndReturn(func(_ *app.RequestContext, token *model.AuthToken) error {
+		tokens[token.TokenHash] = token
+		return nil
+	}).AnyTimes()
+	tokenRepo.EXPECT().ConsumeAuthToken(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ *app.RequestContext, purpose model.AuthTokenPurpose, tokenHash string) (*model.AuthToken, error) {
+		token, ok := tokens[tokenHash]
+		if !ok || token.Purpose != purpose || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			return nil, nil
+		}
+		now := time.Now()
+		token.UsedAt = &now
+		return token, nil
+	}).AnyTimes()
+	tokenRepo.EXPECT().RevokeAuthTokens(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ *app.RequestContext, identityID bson.ObjectID, purposes []model.AuthTokenPurpose) error {
+		now := time.Now()
+		for _, token := range tokens {
+			if token.IdentityID == identityID && token.UsedAt == nil && slices.Contains(purposes, token.Purpose) {
+				token.UsedAt = &now
+			}
+		}
+		return nil
+	}).AnyTimes()
+}
+
+func newTestIdentity(t *testing.T, password string, verified bool) *model.Identity {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatalf("HashPassword returned error: %v", err)
+	}
+	return &model.Identity{
+		Base:          model.Base{ID: bson.NewObjectID()},
+		Email:         "jane@example.com",
+		IsActive:      true,
+		PasswordHash:  hash,
+		EmailVerified: verified,
+	}
+}
+
+func TestPasswordLoginAndRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	tokenRepo := mocks.NewMockAuthTokenRepository(ctrl)
+	appCtx := app.NewMockAppContext()
+	svc := service.NewAuthService(appCtx, nil, identityRepo, tokenRepo, nil, nil, &fakeMailer{})
+	reqCtx := &app.RequestContext{}
+	expectAuthTokenStore(tokenRepo)
+
+	identity := newTestIdentity(t, "correct horse battery", false)
+	identityRepo.EXPECT().GetIdentityByEmail(reqCtx, identity.Email).Return(identity, nil).AnyTimes()
+
+	_, err := svc.Login(reqCtx, &model.PasswordLogin{Email: "Jane@Example.com", Password: "correct horse battery"})
+	if !errors.Is(err, service.ErrEmailNotVerified) {
+		t.Fatalf("expected ErrEmailNotVerified, got %v", err)
+	}
+
+	identity.EmailVerified = true
+	_, err = svc.Login(reqCtx, &model.PasswordLogin{Email: identity.Email, Password: "wrong password"})
+	if !errors.Is(err, service.ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+
+	tokens, err := svc.Login(reqCtx, &model.PasswordLogin{Email: identity.Email, Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	claims, err := utils.ParseJWT(tokens.AccessToken, []byte(appCtx.Config.AUTH_JWT_SECRET))
+	if err != nil {
+		t.Fatalf("access token does not verify: %v", err)
+	}
+	if claims.Type != app.AccessTokenType || claims.Email != identity.Email || claims.Subject != identity.ID.Hex() {
+		t.Errorf("unexpected access token claims %+v", claims)
+	}
+	if _, err := utils.ParseJWT(tokens.AccessToken, []byte("another-secret")); err == nil {
+		t.Errorf("access token verified with the wrong secret")
+	}
+
+	// Refresh tokens rotate; a used refresh token is rejected.
+	refreshed, err := svc.Refresh(reqCtx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	if _, err := svc.Refresh(reqCtx, tokens.RefreshToken); !errors.Is(err, service.ErrInvalidAuthToken) {
+		t.Fatalf("expected reused refresh token to fail, got %v", err)
+	}
+	if _, err := svc.Refresh(reqCtx, refreshed.AccessToken); !errors.Is(err, service.ErrInvalidAuthToken) {
+		t.Fatalf("expected access token to be rejected as refresh token, got %v", err)
+	}
+
+	if err := svc.Logout(reqCtx, refreshed.RefreshToken); err != nil {
+		t.Fatalf("Logout returned error: %v", err)
+	}
+	if _, err := svc.Refresh(reqCtx, refreshed.RefreshToken); !errors.Is(err, service.ErrInvalidAuthToken) {
+		t.Fatalf("expected refresh token to be revoked by logout, got %v", err)
+	}
+}
+
+func TestMagicLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	tokenRepo := mocks.NewMockAuthTokenRepository(ctrl)
+	mailer := &fakeMailer{}
+	svc := service.NewAuthService(app.NewMockAppContext(), nil, identityRepo, tokenRepo, nil, nil, mailer)
+	reqCtx := &app.RequestContext{}
+	expectAuthTokenStore(tokenRepo)
+
+	identity := newTestIdentity(t, "correct horse battery", true)
+	identityRepo.EXPECT().GetIdentityByEmail(reqCtx, "nobody@example.com").Return(nil, errors.New("identity not found"))
+	identityRepo.EXPECT().GetIdentityByEmail(reqCtx, identity.Email).Return(identity, nil).Times(2)
+
+	if err := svc.RequestMagicLink(reqCtx, "nobody@example.com"); err != nil {
+		t.Fatalf("expected unknown email to be ignored, got %v", err)
+	}
+	if len(mailer.sent) != 0 {
+		t.Fatalf("expected no email for an unknown account, got %d", len(mailer.sent))
+	}
+
+	if err := svc.RequestMagicLink(reqCtx, identity.Email); err != nil {
+		t.Fatalf("RequestMagicLink returned error: %v", err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].to != identity.Email {
+		t.Fatalf("expected one email to %s, got %+v", identity.Email, mailer.sent)
+	}
+	_, token, found := strings.Cut(mailer.sent[0].body, "token=")
+	if !found {
+		t.Fatalf("email does not contain a sign-in link: %q", mailer.sent[0].body)
+	}
+
+	if _, err := svc.VerifyMagicLink(reqCtx, token); err != nil {
+		t.Fatalf("VerifyMagicLink returned error: %v", err)
+	}
+	if _, err := svc.VerifyMagicLink(reqCtx, token); !errors.Is(err, service.ErrInvalidAuthToken) {
+		t.Fatalf("expected magic link to be single use, got %v", err)
+	}
+}
+
+// An attacker signs up with the victim's email and a password they know; the
+// victim later signs in with a magic link. The attacker's password and session
+// must not survive the verification of the email by the victim.
+func TestMagicLinkRevokesPasswordOfUnverifiedIdentity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	tokenRepo := mocks.NewMockAuthTokenRepository(ctrl)
+	mailer := &fakeMailer{}
+	svc := service.NewAuthService(app.NewMockAppContext(), nil, identityRepo, tokenRepo, nil, nil, mailer)
+	reqCtx := &app.RequestContext{}
+	expectAuthTokenStore(tokenRepo)
+
+	identity := newTestIdentity(t, "attacker password", true)
+	identityRepo.EXPECT().GetIdentityByEmail(reqCtx, identity.Email).DoAndReturn(func(*app.RequestContext, string) (*model.Identity, error) {
+		copied := *identity
+		return &copied, nil
+	}).AnyTimes()
+	attackerTokens, err := svc.Login(reqCtx, &model.PasswordLogin{Email: identity.Email, Password: "attacker password"})
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	identity.EmailVerified = false
+
+	if err := svc.RequestMagicLink(reqCtx, identity.Email); err != nil {
+		t.Fatalf("RequestMagicLink returned error: %v", err)
+	}
+	_, token, _ := strings.Cut(mailer.sent[0].body, "token=")
+	identityRepo.EXPECT().SetPasswordHash(reqCtx, identity.ID, "").DoAndReturn(func(*app.RequestContext, bson.ObjectID, string) error {
+		identity.PasswordHash = ""
+		return nil
+	})
+	identityRepo.EXPECT().MarkEmailVerified(reqCtx, identity.ID).DoAndReturn(func(*app.RequestContext, bson.ObjectID) error {
+		identity.EmailVerified = true
+		return nil
+	})
+	if _, err := svc.VerifyMagicLink(reqCtx, token); err != nil {
+		t.Fatalf("VerifyMagicLink returned error: %v", err)
+	}
+
+	if _, err := svc.Login(reqCtx, &model.PasswordLogin{Email: identity.Email, Password: "attacker password"}); !errors.Is(err, service.ErrInvalidCredentials) {
+		t.Errorf("expected the attacker's password to be cleared, got %v", err)
+	}
+	if _, err := svc.Refresh(reqCtx, attackerTokens.RefreshToken); !errors.Is(err, service.ErrInvalidAuthToken) {
+		t.Errorf("expected the attacker's session to be revoked, got %v", err)
+	}
+}
+
+func TestSignUpIsAtomic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	userRepo := mocks.NewMockUserRepo(ctrl)
+	tokenRepo := mocks.NewMockAuthTokenRepository(ctrl)
+	expectAuthTokenStore(tokenRepo)
+
+	session := mocks.NewMockMongoSession(ctrl)
+	session.EXPECT().Context(gomock.Any()).AnyTimes().DoAndReturn(func(ctx context.Context) context.Context { return ctx })
+	session.EXPECT().StartTransaction().Times(2).Return(nil)
+	session.EXPECT().EndSession(gomock.Any()).Times(2)
+	sessions := mocks.NewMockSessionProvider(ctrl)
+	sessions.EXPECT().StartSession().Times(2).Return(session, nil)
+
+	email := "jane@example.com"
+	identityRepo.EXPECT().IsIdentityExists(gomock.Any(), email).Return(false, nil).AnyTimes()
+	identityRepo.EXPECT().CreateIdentity(inTransaction(), gomock.Any()).Times(2).DoAndReturn(
+		func(_ *app.RequestContext, create *model.CreateIdentity) (*model.Identity, error) {
+			return &model.Identity{Base: model.Base{ID: bson.NewObjectID()}, Email: create.Email, CurrentOrgID: create.CurrentOrgID}, nil
+		})
+	orgRepo.EXPECT().IsOrganizationExists(inTransaction(), gomock.Any()).Times(2).Return(false, nil)
+	orgRepo.EXPECT().IsSlugTaken(inTransaction(), gomock.Any()).Times(2).Return(false, nil)
+	orgRepo.EXPECT().CreateOrganization(inTransaction(), gomock.Any(), gomock.Any()).Times(2).DoAndReturn(
+		func(_ *app.RequestContext, org *model.CreateOrganization, _ bson.ObjectID) (*model.Organization, error) {
+			return &model.Organization{Base: model.Base{ID: org.ID}, Name: org.Name, Slug: org.Slug}, nil
+		})
+	userRepo.EXPECT().IsUserExists(inTransaction(), gomock.Any(), email).Times(2).Return(false, nil)
+	userRepo.EXPECT().CreateUser(inTransaction(), gomock.Any(), gomock.Any()).Times(2).DoAndReturn(
+		func(_ *app.RequestContext, identityID bson.ObjectID, create *model.CreateUser) (*model.User, error) {
+			return &model.User{Base: model.Base{ID: bson.NewObjectID()}, IdentityID: identityID, Email: create.Email, OrganizationID: create.OrganizationID}, nil
+		})
+
+	mailer := &fakeMailer{}
+	userService := service.NewUserService(sessions, userRepo, service.NewIdentityService(identityRepo), service.NewOrganizationService(orgRepo), service.NewGoogleSheetService())
+	svc := service.NewAuthService(app.NewMockAppContext(), sessions, identityRepo, tokenRepo, userService, nil, mailer)
+	req := &model.PasswordSignUp{Email: email, Password: "correct horse battery", FirstName: "Jane", LastName: "Doe"}
+
+	// A failure to store the password aborts the whole sign-up.
+	session.EXPECT().AbortTransaction(gomock.Any()).Return(nil)
+	identityRepo.EXPECT().SetPasswordHash(inTransaction(), gomock.Any(), gomock.Any()).Return(errors.New("error setting password"))
+	if _, err := svc.SignUp(&app.RequestContext{}, req); err == nil {
+		t.Fatal("expected the sign-up to fail")
+	}
+	if len(mailer.sent) != 0 {
+		t.Fatalf("expected no verification email for a failed sign-up, got %d", len(mailer.sent))
+	}
+
+	session.EXPECT().CommitTransaction(gomock.Any()).Return(nil)
+	identityRepo.EXPECT().SetPasswordHash(inTransaction(), gomock.Any(), gomock.Not("")).Return(nil)
+	if _, err := svc.SignUp(&app.RequestContext{}, req); err != nil {
+		t.Fatalf("SignUp returned error: %v", err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].to != email {
+		t.Fatalf("expected the verification email once committed, got %+v", mailer.sent)
+	}
+}
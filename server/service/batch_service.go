@@ -33,3 +33,9 @@ func (s *BatchService) UpdateBatchStatus(reqCtx *app.RequestContext, batchID bso
 	batch, err := s.repo.UpdateBatchStatus(reqCtx, batchID, status)
 	return batch, err
 }
+
+// SetLegalHold places or lifts the legal hold of a batch, which exempts the
+// records scanned in it from the retention policies.
+func (s *BatchService) SetLegalHold(reqCtx *app.RequestContext, batchID bson.ObjectID, hold bool) (*model.Batch, error) {
+	return s.repo.SetLegalHold(reqCtx, batchID, hold)
+}
@@ -3,9 +3,12 @@ package service
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/file_utils"
 	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/pii"
 	"github.com/gaeaglobal/exto/server/repo"
 	"github.com/gaeaglobal/exto/server/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -16,24 +19,46 @@ type CategoryDataService struct {
 	categoryService    *CategoryService
 	orgService         *OrganizationService
 	scanHistoryService *ScanHistoryService
+	piiService         *PIIService
 }
 
-func NewCategoryDataService(r repo.CategoryDataRepository, categoryService *CategoryService, orgService *OrganizationService, scanHistoryService *ScanHistoryService) *CategoryDataService {
+func NewCategoryDataService(r repo.CategoryDataRepository, categoryService *CategoryService, orgService *OrganizationService, scanHistoryService *ScanHistoryService, piiService *PIIService) *CategoryDataService {
 	return &CategoryDataService{
 		r:                  r,
 		categoryService:    categoryService,
 		orgService:         orgService,
 		scanHistoryService: scanHistoryService,
+		piiService:         piiService,
 	}
 }
 
-func (s *CategoryDataService) getCategorySlug(reqCtx *app.RequestContext, categoryID bson.ObjectID) (string, error) {
+// sensitiveFields are the fields of the record encrypted at rest: those of
+// the category marked sensitive and those holding personal data.
+func sensitiveFields(category *model.Category, piiTags map[string]model.PIIField) []string {
+	fields := category.SensitiveFields()
+	for name := range piiTags {
+		if !slices.Contains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func (s *CategoryDataService) getCategory(reqCtx *app.RequestContext, categoryID bson.ObjectID) (*model.Category, error) {
 	category, err := s.categoryService.GetCategoryByID(reqCtx, categoryID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get category: %w", err)
+		return nil, fmt.Errorf("failed to get category: %w", err)
 	}
 	if category == nil {
-		return "", errors.New("category not found")
+		return nil, errors.New("category not found")
+	}
+	return category, nil
+}
+
+func (s *CategoryDataService) getCategorySlug(reqCtx *app.RequestContext, categoryID bson.ObjectID) (string, error) {
+	category, err := s.getCategory(reqCtx, categoryID)
+	if err != nil {
+		return "", err
 	}
 	return category.Slug, nil
 }
@@ -54,7 +79,11 @@ func (s *CategoryDataService) GetCategoryDataByID(reqCtx *app.RequestContext, ca
 	if err != nil {
 		return nil, err
 	}
-	return s.r.GetCategoryDataByID(reqCtx, categorySlug, id)
+	data, err := s.r.GetCategoryDataByID(reqCtx, categorySlug, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.piiService.Mask(reqCtx, data), nil
 }
 
 func (s *CategoryDataService) ListCategoryData(reqCtx *app.RequestContext, categoryID bson.ObjectID, pageReq *app.PageRequest) (*app.PageResponse[*model.CategoryData], error) {
@@ -62,7 +91,14 @@ func (s *CategoryDataService) ListCategoryData(reqCtx *app.RequestContext, categ
 	if err != nil {
 		return nil, err
 	}
-	return s.r.ListCategoryData(reqCtx, categorySlug, pageReq)
+	page, err := s.r.ListCategoryData(reqCtx, categorySlug, pageReq)
+	if err != nil {
+		return nil, err
+	}
+	for i, data := range page.Items {
+		page.Items[i] = s.piiService.Mask(reqCtx, data)
+	}
+	return page, nil
 }
 
 type CreateCategoryDataResult struct {
@@ -70,27 +106,34 @@ type CreateCategoryDataResult struct {
 	ScanHistory  *model.ScanHistory
 }
 
+// CreateCategoryData saves the record of an uploaded document. piiTags are
+// the fields found to hold personal data; their values are encrypted at rest
+// and masked for the users that may not read them.
 func (s *CategoryDataService) CreateCategoryData(
 	reqCtx *app.RequestContext,
 	categoryID bson.ObjectID,
 	data *map[string]any,
 	rawData *map[string]any,
-	filePath string,
+	upload *file_utils.Upload,
 	batchID bson.ObjectID,
+	piiTags map[string]model.PIIField,
 ) (*CreateCategoryDataResult, error) {
-	categorySlug, err := s.getCategorySlug(reqCtx, categoryID)
+	category, err := s.getCategory(reqCtx, categoryID)
 	if err != nil {
 		return nil, err
 	}
-	thumbnail, err := utils.GetThumbnailImage(filePath)
+	categorySlug := category.Slug
+	thumbnail, err := utils.GetThumbnailImage(upload.Content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create thumbnail: %w", err)
 	}
 	newCategoryData := &model.CreateCategoryDataRequest{
-		CategoryID:    categoryID,
-		MetaData:      *data,
-		RawData:       *rawData,
-		DocumentPaths: []string{filePath},
+		CategoryID:      categoryID,
+		MetaData:        *data,
+		RawData:         *rawData,
+		DocumentPaths:   []string{upload.Key},
+		PII:             piiTags,
+		SensitiveFields: sensitiveFields(category, piiTags),
 	}
 
 	primaryField, err := s.getCategoryPrimaryField(reqCtx, categoryID)
@@ -127,17 +170,74 @@ func (s *CategoryDataService) CreateCategoryData(
 	}
 
 	return &CreateCategoryDataResult{
-		CategoryData: catData,
+		CategoryData: s.piiService.Mask(reqCtx, catData),
 		ScanHistory:  scanHistoryRes,
 	}, nil
 }
 
+// UpdateCategoryData replaces the extracted values of the record. The values
+// of personal data sent back masked, by users that may not read them, are
+// kept as they are; the values are then checked for personal data again.
 func (s *CategoryDataService) UpdateCategoryData(reqCtx *app.RequestContext, categoryID bson.ObjectID, dataID bson.ObjectID, updatedExtractedData *map[string]any) (*model.CategoryData, error) {
-	categorySlug, err := s.getCategorySlug(reqCtx, categoryID)
+	category, err := s.getCategory(reqCtx, categoryID)
 	if err != nil {
 		return nil, err
 	}
-	return s.r.UpdateCategoryData(reqCtx, categorySlug, dataID, &model.UpdateCategoryDataRequest{
-		MetaData: *updatedExtractedData,
+	existing, err := s.r.GetCategoryDataByID(reqCtx, category.Slug, dataID)
+	if err != nil {
+		return nil, err
+	}
+
+	metaData := *updatedExtractedData
+	if len(existing.PII) > 0 && !s.piiService.CanRead(reqCtx) {
+		metaData = make(map[string]any, len(*updatedExtractedData))
+		for name, value := range *updatedExtractedData {
+			if _, tagged := existing.PII[name]; tagged && pii.IsMasked(existing.MetaData[name], value) {
+				value = existing.MetaData[name]
+			}
+			metaData[name] = value
+		}
+	}
+
+	boxes := make(map[string][]float64, len(existing.PII))
+	for name, tag := range existing.PII {
+		boxes[name] = tag.Box
+	}
+	piiTags := s.piiService.Detect(reqCtx, metaData, boxes)
+	for name, tag := range existing.PII {
+		// The fields only the language model recognized are kept, as the
+		// patterns cannot confirm them.
+		if _, found := metaData[name]; found && slices.Contains(tag.Types, model.PIIOther) {
+			if _, tagged := piiTags[name]; !tagged {
+				if piiTags == nil {
+					piiTags = map[string]model.PIIField{}
+				}
+				piiTags[name] = tag
+			}
+		}
+	}
+
+	updated, err := s.r.UpdateCategoryData(reqCtx, category.Slug, dataID, &model.UpdateCategoryDataRequest{
+		MetaData:        metaData,
+		PII:             piiTags,
+		SensitiveFields: sensitiveFields(category, piiTags),
 	})
+	if err != nil {
+		return nil, err
+	}
+	return s.piiService.Mask(reqCtx, updated), nil
+}
+
+// SetLegalHold places or lifts the legal hold of a record, which exempts it
+// from the retention policies.
+func (s *CategoryDataService) SetLegalHold(reqCtx *app.RequestContext, categoryID bson.ObjectID, dataID bson.ObjectID, hold bool) (*model.CategoryData, error) {
+	categorySlug, err := s.getCategorySlug(reqCtx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.r.SetLegalHold(reqCtx, categorySlug, dataID, hold)
+	if err != nil {
+		return nil, err
+	}
+	return s.piiService.Mask(reqCtx, data), nil
 }
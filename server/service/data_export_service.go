@@ -0,0 +1,483 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/file_utils"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+	"github.com/gaeaglobal/exto/server/storage"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+const (
+	dataExportInterval    = 30 * time.Second
+	dataExportLease       = 30 * time.Minute
+	dataExportMaxAttempts = 3
+	dataExportLinkTTL     = time.Hour
+	dataExportManifestVer = 1
+
+	// DataExportTokenType is the type of the JWTs of signed data export
+	// download links. They do not authenticate any other request.
+	DataExportTokenType = "data_export"
+)
+
+var (
+	ErrDataExportInProgress  = errors.New("a data export is already in progress")
+	ErrDataExportNotFound    = errors.New("data export not found")
+	ErrDataExportNotReady    = errors.New("data export is not ready")
+	ErrInvalidDownloadLink   = errors.New("invalid or expired download link")
+	ErrDownloadLinksDisabled = errors.New("signed download links are not configured")
+)
+
+// DataExportService exports all the data of an organization for GDPR and
+// CCPA requests. Exports are queued by the organization admins and generated
+// in the background into a zip archive in the blob store, which holds:
+//
+//	organization.json        the organization
+//	users.ndjson             its members
+//	categories.ndjson        the categories its data was extracted for
+//	data/<category>.ndjson   the records of each <category>_data collection
+//	scan_history.ndjson      the scan history
+//	batches.ndjson           the batches
+//	uploads/...              the original uploaded documents
+//	manifest.json            the files above with their SHA-256 checksums
+//
+// Records are written as relaxed MongoDB extended JSON. Records are not
+// versioned, so each one is exported as it is now, with its created and
+// updated audit fields.
+type DataExportService struct {
+	appCtx           *app.AppContext
+	exportRepo       repo.DataExportRepository
+	orgRepo          repo.OrganizationRepo
+	categoryDataRepo repo.CategoryDataRepository
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewDataExportService(appCtx *app.AppContext, exportRepo repo.DataExportRepository, orgRepo repo.OrganizationRepo, categoryDataRepo repo.CategoryDataRepository) *DataExportService {
+	return &DataExportService{
+		appCtx:           appCtx,
+		exportRepo:       exportRepo,
+		orgRepo:          orgRepo,
+		categoryDataRepo: categoryDataRepo,
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+func (s *DataExportService) EnsureIndexes() error {
+	return s.exportRepo.EnsureIndexes()
+}
+
+// RequestExport queues an export of the current organization. Only one
+// export of an organization is generated at a time.
+func (s *DataExportService) RequestExport(reqCtx *app.RequestContext) (*model.DataExport, error) {
+	active, err := s.exportRepo.HasActiveDataExport(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, ErrDataExportInProgress
+	}
+	return s.exportRepo.CreateDataExport(reqCtx)
+}
+
+func (s *DataExportService) ListExports(reqCtx *app.RequestContext) ([]*model.DataExport, error) {
+	return s.exportRepo.ListDataExports(reqCtx, reqCtx.Org.ID)
+}
+
+// CreateDownloadLink signs a short-lived link to the archive of a ready export
+// of the current organization.
+func (s *DataExportService) CreateDownloadLink(reqCtx *app.RequestContext, id bson.ObjectID) (*model.DataExportLink, error) {
+	secret := s.appCtx.Config.AUTH_JWT_SECRET
+	if secret == "" {
+		return nil, ErrDownloadLinksDisabled
+	}
+	export, err := s.exportRepo.GetDataExport(reqCtx, id)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil || export.OrganizationID != reqCtx.Org.ID {
+		return nil, ErrDataExportNotFound
+	}
+	if export.Status != model.DataExportReady || export.ExpiresAt == nil {
+		return nil, ErrDataExportNotReady
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(dataExportLinkTTL)
+	if export.ExpiresAt.Before(expiresAt) {
+		expiresAt = *export.ExpiresAt
+	}
+	token, err := utils.SignJWT(&utils.JWTClaims{
+		Subject:   export.ID.Hex(),
+		Type:      DataExportTokenType,
+		Issuer:    utils.JWTIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	return &model.DataExportLink{
+		URL:       strings.TrimRight(s.appCtx.Config.API_BASE_URL, "/") + "/downloads/data-exports/" + token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// OpenDownload verifies a signed download link and returns the export and the
+// key of its archive.
+func (s *DataExportService) OpenDownload(reqCtx *app.RequestContext, token string) (*model.DataExport, string, error) {
+	secret := s.appCtx.Config.AUTH_JWT_SECRET
+	if secret == "" {
+		return nil, "", ErrDownloadLinksDisabled
+	}
+	claims, err := utils.ParseJWT(token, []byte(secret))
+	if err != nil || claims.Type != DataExportTokenType {
+		return nil, "", ErrInvalidDownloadLink
+	}
+	id, err := bson.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return nil, "", ErrInvalidDownloadLink
+	}
+
+	export, err := s.exportRepo.GetDataExport(reqCtx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if export == nil || export.Status != model.DataExportReady || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, "", ErrInvalidDownloadLink
+	}
+	key := archiveKey(export)
+	if _, err := s.appCtx.Blobs.Stat(reqCtx.Context(), key); err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			log.Printf("archive of data export %s is missing", export.ID.Hex())
+			return nil, "", ErrInvalidDownloadLink
+		}
+		return nil, "", err
+	}
+	return export, key, nil
+}
+
+func archiveKey(export *model.DataExport) string {
+	return file_utils.ExportKey(export.OrganizationID, "data-export-"+export.ID.Hex()+".zip")
+}
+
+// Start runs the export worker in the background until Stop is called.
+func (s *DataExportService) Start() {
+	if !s.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(dataExportInterval)
+		defer ticker.Stop()
+		for {
+			reqCtx := &app.RequestContext{}
+			if _, err := s.ProcessPending(reqCtx); err != nil {
+				log.Printf("data export failed: %v", err)
+			}
+			if err := s.RemoveExpired(reqCtx); err != nil {
+				log.Printf("data export cleanup failed: %v", err)
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running export to finish and stops the worker.
+func (s *DataExportService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if s.started.Load() {
+			<-s.done
+		}
+	})
+}
+
+// ProcessPending generates the queued exports and returns how many archives
+// were written.
+func (s *DataExportService) ProcessPending(reqCtx *app.RequestContext) (int, error) {
+	generated := 0
+	for {
+		select {
+		case <-s.stop:
+			return generated, nil
+		default:
+		}
+
+		export, err := s.exportRepo.ClaimNextPending(reqCtx, time.Now(), dataExportLease)
+		if err != nil {
+			return generated, err
+		}
+		if export == nil {
+			return generated, nil
+		}
+		if export.Attempts > dataExportMaxAttempts {
+			s.fail(reqCtx, export, errors.New("export did not finish after several attempts"))
+			continue
+		}
+		if err := s.generate(reqCtx, export); err != nil {
+			s.fail(reqCtx, export, err)
+			continue
+		}
+		generated++
+	}
+}
+
+func (s *DataExportService) fail(reqCtx *app.RequestContext, export *model.DataExport, cause error) {
+	log.Printf("data export %s failed: %v", export.ID.Hex(), cause)
+	if err := s.exportRepo.MarkFailed(reqCtx, export.ID, cause.Error()); err != nil {
+		log.Printf("failed to mark data export %s as failed: %v", export.ID.Hex(), err)
+	}
+}
+
+// RemoveExpired deletes the archives of the exports that have expired.
+func (s *DataExportService) RemoveExpired(reqCtx *app.RequestContext) error {
+	exports, err := s.exportRepo.GetExpiredDataExports(reqCtx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := s.appCtx.Blobs.Delete(reqCtx.Context(), archiveKey(export)); err != nil {
+			log.Printf("failed to remove archive of data export %s: %v", export.ID.Hex(), err)
+			continue
+		}
+		if err := s.exportRepo.MarkExpired(reqCtx, export.ID); err != nil {
+			log.Printf("failed to mark data export %s as expired: %v", export.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// generate writes the archive of the export to a temporary file and stores
+// it in the blob store once it is complete.
+func (s *DataExportService) generate(reqCtx *app.RequestContext, export *model.DataExport) error {
+	org, err := s.orgRepo.GetOrganizationByID(reqCtx, export.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if org == nil || org.IsDeleted() {
+		return errors.New("organization not found")
+	}
+
+	file, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	archiveHash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(file, archiveHash)}
+	archive := &exportArchive{zw: zip.NewWriter(counter)}
+	if err := s.writeArchive(reqCtx, archive, export, org); err != nil {
+		return err
+	}
+	if err := archive.zw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	key := archiveKey(export)
+	if err := s.appCtx.Blobs.Put(reqCtx.Context(), key, file, counter.n, "application/zip"); err != nil {
+		return fmt.Errorf("failed to store archive: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.appCtx.Config.DATA_EXPORT_TTL)
+	return s.exportRepo.MarkReady(reqCtx, export.ID, path.Base(key), counter.n, hexSum(archiveHash), expiresAt)
+}
+
+func (s *DataExportService) writeArchive(reqCtx *app.RequestContext, archive *exportArchive, export *model.DataExport, org *model.Organization) error {
+	err := archive.writeFile("organization.json", func(w io.Writer) (int, error) {
+		records := 0
+		err := s.exportRepo.ForEachDocument(reqCtx, "", "organizations", bson.M{"_id": org.ID}, func(doc bson.Raw) error {
+			records++
+			return writeExtJSON(w, doc)
+		})
+		return records, err
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.writeCollection(reqCtx, archive, "users.ndjson", "", "users", bson.M{"org_id": org.ID}); err != nil {
+		return err
+	}
+
+	orgCtx := (&app.RequestContext{Org: app.RequestOrg{ID: org.ID, Name: org.Name, Slug: org.Slug}}).WithContext(reqCtx.Context())
+	dataCollections, err := s.categoryDataRepo.ListDataCollections(orgCtx)
+	if err != nil {
+		return err
+	}
+	categorySlugs := make([]string, 0, len(dataCollections))
+	for _, collection := range dataCollections {
+		categorySlugs = append(categorySlugs, strings.TrimSuffix(collection, "_data"))
+	}
+	if err := s.writeCollection(reqCtx, archive, "categories.ndjson", "", "categories", bson.M{"slug": bson.M{"$in": categorySlugs}}); err != nil {
+		return err
+	}
+	for i, collection := range dataCollections {
+		if err := s.writeDataCollection(orgCtx, archive, "data/"+categorySlugs[i]+".ndjson", collection); err != nil {
+			return err
+		}
+	}
+	if err := s.writeCollection(reqCtx, archive, "scan_history.ndjson", org.Slug, "scan_history", bson.M{}); err != nil {
+		return err
+	}
+	if err := s.writeCollection(reqCtx, archive, "batches.ndjson", org.Slug, "batch", bson.M{}); err != nil {
+		return err
+	}
+	if err := s.writeUploads(reqCtx, archive, org.ID); err != nil {
+		return err
+	}
+
+	manifest := &model.DataExportManifest{
+		Version:          dataExportManifestVer,
+		ExportID:         export.ID,
+		OrganizationID:   org.ID,
+		OrganizationSlug: org.Slug,
+		RequestedBy:      export.CreatedBy,
+		GeneratedAt:      time.Now(),
+		Files:            archive.files,
+	}
+	w, err := archive.zw.Create("manifest.json")
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// writeCollection writes the matching documents of a collection to an NDJSON
+// file of the archive.
+func (s *DataExportService) writeCollection(reqCtx *app.RequestContext, archive *exportArchive, name string, orgSlug string, collection string, filter bson.M) error {
+	return archive.writeFile(name, func(w io.Writer) (int, error) {
+		records := 0
+		err := s.exportRepo.ForEachDocument(reqCtx, orgSlug, collection, filter, func(doc bson.Raw) error {
+			records++
+			return writeExtJSON(w, doc)
+		})
+		return records, err
+	})
+}
+
+// writeDataCollection writes the records of a category data collection with
+// their sensitive values decrypted.
+func (s *DataExportService) writeDataCollection(orgCtx *app.RequestContext, archive *exportArchive, name string, collection string) error {
+	return archive.writeFile(name, func(w io.Writer) (int, error) {
+		records := 0
+		err := s.exportRepo.ForEachDocument(orgCtx, orgCtx.Org.Slug, collection, bson.M{}, func(doc bson.Raw) error {
+			doc, err := s.categoryDataRepo.DecryptDocument(orgCtx, doc)
+			if err != nil {
+				return err
+			}
+			records++
+			return writeExtJSON(w, doc)
+		})
+		return records, err
+	})
+}
+
+// writeUploads copies the documents uploaded to the organization from the
+// blob store.
+func (s *DataExportService) writeUploads(reqCtx *app.RequestContext, archive *exportArchive, orgID bson.ObjectID) error {
+	prefix := file_utils.UploadPrefix(orgID)
+	err := s.appCtx.Blobs.List(reqCtx.Context(), prefix, func(info storage.BlobInfo) error {
+		return archive.writeFile("uploads/"+strings.TrimPrefix(info.Key, prefix), func(w io.Writer) (int, error) {
+			src, _, err := s.appCtx.Blobs.Get(reqCtx.Context(), info.Key)
+			if err != nil {
+				return 0, err
+			}
+			defer src.Close()
+			_, err = io.Copy(w, src)
+			return 0, err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export uploads: %w", err)
+	}
+	return nil
+}
+
+func writeExtJSON(w io.Writer, doc bson.Raw) error {
+	line, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return nil
+}
+
+// exportArchive writes the files of a data export archive and records their
+// size and checksum for the manifest.
+type exportArchive struct {
+	zw    *zip.Writer
+	files []model.DataExportEntry
+}
+
+func (a *exportArchive) writeFile(name string, write func(w io.Writer) (int, error)) error {
+	entry, err := a.zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	checksum := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(entry, checksum)}
+	records, err := write(counter)
+	if err != nil {
+		return err
+	}
+	a.files = append(a.files, model.DataExportEntry{
+		Path:    name,
+		Records: records,
+		Size:    counter.n,
+		SHA256:  hexSum(checksum),
+	})
+	return nil
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,171 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/file_utils"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/storage"
+)
+
+func TestProcessPendingWritesArchiveWithManifest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	exportRepo := mocks.NewMockDataExportRepository(ctrl)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	appCtx := app.NewMockAppContext()
+	blobDir := t.TempDir()
+	appCtx.Blobs = storage.NewLocalBlobStore(blobDir)
+	categoryDataRepo := mocks.NewMockCategoryDataRepository(ctrl)
+	svc := service.NewDataExportService(appCtx, exportRepo, orgRepo, categoryDataRepo)
+
+	reqCtx := &app.RequestContext{}
+	org := &model.Organization{Base: model.Base{ID: bson.NewObjectID()}, Slug: "acme"}
+	export := &model.DataExport{Base: model.Base{ID: bson.NewObjectID()}, OrganizationID: org.ID, Attempts: 1}
+	upload := []byte("%PDF-1.4")
+	if err := appCtx.Blobs.Put(context.Background(), file_utils.UploadPrefix(org.ID)+"invoice.pdf", bytes.NewReader(upload), int64(len(upload)), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	gomock.InOrder(
+		exportRepo.EXPECT().ClaimNextPending(reqCtx, gomock.Any(), gomock.Any()).Return(export, nil),
+		exportRepo.EXPECT().ClaimNextPending(reqCtx, gomock.Any(), gomock.Any()).Return(nil, nil),
+	)
+	orgRepo.EXPECT().GetOrganizationByID(reqCtx, org.ID).Return(org, nil)
+	categoryDataRepo.EXPECT().ListDataCollections(gomock.Any()).Return([]string{"invoice_data"}, nil)
+	categoryDataRepo.EXPECT().DecryptDocument(gomock.Any(), gomock.Any()).Times(2).DoAndReturn(
+		func(_ *app.RequestContext, doc bson.Raw) (bson.Raw, error) { return doc, nil })
+	exportRepo.EXPECT().ForEachDocument(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(_ *app.RequestContext, orgSlug string, collection string, _ bson.M, fn func(bson.Raw) error) error {
+			if collection != "invoice_data" && collection != "organizations" {
+				return nil
+			}
+			for i := range 2 {
+				doc, _ := bson.Marshal(bson.M{"_id": i, "collection": collection, "db": orgSlug})
+				if err := fn(doc); err != nil {
+					return err
+				}
+				if collection == "organizations" {
+					break
+				}
+			}
+			return nil
+		})
+
+	var fileName, archiveSum string
+	exportRepo.EXPECT().MarkReady(reqCtx, export.ID, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, _ bson.ObjectID, name string, _ int64, sum string, expiresAt time.Time) error {
+			fileName, archiveSum = name, sum
+			if time.Until(expiresAt) < 6*24*time.Hour {
+				t.Errorf("expected the archive to be kept for DATA_EXPORT_TTL, expires at %v", expiresAt)
+			}
+			return nil
+		})
+
+	generated, err := svc.ProcessPending(reqCtx)
+	if err != nil {
+		t.Fatalf("ProcessPending returned error: %v", err)
+	}
+	if generated != 1 {
+		t.Fatalf("expected 1 archive, got %d", generated)
+	}
+
+	path := filepath.Join(blobDir, "exports", org.ID.Hex(), fileName)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != archiveSum {
+		t.Error("archive checksum does not match the recorded one")
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+
+	var manifest model.DataExportManifest
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	listed := map[string]model.DataExportEntry{}
+	for _, entry := range manifest.Files {
+		listed[entry.Path] = entry
+		sum := sha256.Sum256(files[entry.Path])
+		if hex.EncodeToString(sum[:]) != entry.SHA256 || int64(len(files[entry.Path])) != entry.Size {
+			t.Errorf("manifest entry of %s does not match its content", entry.Path)
+		}
+	}
+	for _, name := range []string{"organization.json", "users.ndjson", "categories.ndjson", "data/invoice.ndjson", "scan_history.ndjson", "batches.ndjson", "uploads/invoice.pdf"} {
+		if _, ok := listed[name]; !ok {
+			t.Errorf("expected %s in the manifest", name)
+		}
+	}
+	if listed["data/invoice.ndjson"].Records != 2 || strings.Count(string(files["data/invoice.ndjson"]), "\n") != 2 {
+		t.Errorf("expected 2 NDJSON records of invoice data, got %q", files["data/invoice.ndjson"])
+	}
+}
+
+func TestDataExportDownloadLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	exportRepo := mocks.NewMockDataExportRepository(ctrl)
+	appCtx := app.NewMockAppContext()
+	appCtx.Blobs = storage.NewLocalBlobStore(t.TempDir())
+	svc := service.NewDataExportService(appCtx, exportRepo, mocks.NewMockOrganizationRepo(ctrl), mocks.NewMockCategoryDataRepository(ctrl))
+
+	orgID := bson.NewObjectID()
+	expiresAt := time.Now().Add(time.Hour * 24)
+	export := &model.DataExport{Base: model.Base{ID: bson.NewObjectID()}, OrganizationID: orgID, Status: model.DataExportReady, ExpiresAt: &expiresAt}
+	archiveKey := file_utils.ExportKey(orgID, "data-export-"+export.ID.Hex()+".zip")
+	if err := appCtx.Blobs.Put(context.Background(), archiveKey, strings.NewReader("zip"), 3, "application/zip"); err != nil {
+		t.Fatal(err)
+	}
+	exportRepo.EXPECT().GetDataExport(gomock.Any(), export.ID).AnyTimes().Return(export, nil)
+
+	otherOrg := &app.RequestContext{Org: app.RequestOrg{ID: bson.NewObjectID()}}
+	if _, err := svc.CreateDownloadLink(otherOrg, export.ID); err != service.ErrDataExportNotFound {
+		t.Errorf("expected ErrDataExportNotFound for another organization, got %v", err)
+	}
+
+	link, err := svc.CreateDownloadLink(&app.RequestContext{Org: app.RequestOrg{ID: orgID}}, export.ID)
+	if err != nil {
+		t.Fatalf("CreateDownloadLink returned error: %v", err)
+	}
+	token := link.URL[strings.LastIndex(link.URL, "/")+1:]
+	_, key, err := svc.OpenDownload(&app.RequestContext{}, token)
+	if err != nil {
+		t.Fatalf("OpenDownload returned error: %v", err)
+	}
+	if key != archiveKey {
+		t.Errorf("expected the archive key %s, got %s", archiveKey, key)
+	}
+	if _, _, err := svc.OpenDownload(&app.RequestContext{}, token+"x"); err != service.ErrInvalidDownloadLink {
+		t.Errorf("expected ErrInvalidDownloadLink for a tampered link, got %v", err)
+	}
+}
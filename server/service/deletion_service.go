@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/file_utils"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+const deletionPurgeInterval = time.Hour
+
+var (
+	ErrNotOrganizationOwner = errors.New("only the owner can delete the organization")
+	ErrNotPendingDeletion   = errors.New("not pending deletion")
+)
+
+// DeletionService deletes accounts and organizations. A deletion first only
+// soft deletes the records with DeletedAt, which signs the users out and can
+// be reverted by a platform admin. Once the grace period has passed, the
+// purge job removes all their data and records a deletion certificate.
+type DeletionService struct {
+	appCtx         *app.AppContext
+	sessions       db.SessionProvider
+	sc             *stripe.Client
+	orgRepo        repo.OrganizationRepo
+	identityRepo   repo.IdentityRepo
+	userRepo       repo.UserRepo
+	invitationRepo repo.InvitationRepository
+	apiKeyRepo     repo.APIKeyRepository
+	certRepo       repo.DeletionCertificateRepository
+	dataExportRepo repo.DataExportRepository
+	dataKeyRepo    repo.DataKeyRepository
+	overrideRepo   repo.QuotaOverrideRepository
+	usageRepo      repo.MonthlyUsageRepository
+	meterEventRepo repo.MeterEventRepository
+	authTokenRepo  repo.AuthTokenRepository
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewDeletionService(appCtx *app.AppContext, sessions db.SessionProvider, sc *stripe.Client, orgRepo repo.OrganizationRepo, identityRepo repo.IdentityRepo,
+	userRepo repo.UserRepo, invitationRepo repo.InvitationRepository, apiKeyRepo repo.APIKeyRepository, certRepo repo.DeletionCertificateRepository,
+	dataExportRepo repo.DataExportRepository, dataKeyRepo repo.DataKeyRepository, overrideRepo repo.QuotaOverrideRepository, usageRepo repo.MonthlyUsageRepository,
+	meterEventRepo repo.MeterEventRepository, authTokenRepo repo.AuthTokenRepository) *DeletionService {
+	return &DeletionService{
+		appCtx:         appCtx,
+		sessions:       sessions,
+		sc:             sc,
+		orgRepo:        orgRepo,
+		identityRepo:   identityRepo,
+		userRepo:       userRepo,
+		invitationRepo: invitationRepo,
+		apiKeyRepo:     apiKeyRepo,
+		certRepo:       certRepo,
+		dataExportRepo: dataExportRepo,
+		dataKeyRepo:    dataKeyRepo,
+		overrideRepo:   overrideRepo,
+		usageRepo:      usageRepo,
+		meterEventRepo: meterEventRepo,
+		authTokenRepo:  authTokenRepo,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+}
+
+func (s *DeletionService) purgeAfter(deletedAt time.Time) time.Time {
+	return deletedAt.Add(s.appCtx.Config.DELETION_GRACE_PERIOD)
+}
+
+// DeleteAccount schedules the deletion of the signed in identity, its
+// memberships and the organizations it owns.
+func (s *DeletionService) DeleteAccount(reqCtx *app.RequestContext) (*model.ScheduledDeletion, error) {
+	identityID := reqCtx.User.IdentityID
+	orgs, err := s.orgRepo.GetOrganizations(reqCtx, bson.M{"owner_id": identityID, "deleted_at": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	orgIDs := make([]bson.ObjectID, 0, len(orgs))
+	for _, org := range orgs {
+		orgIDs = append(orgIDs, org.ID)
+	}
+
+	err = app.WithTransaction(reqCtx, s.sessions, func(txCtx *app.RequestContext) error {
+		if len(orgIDs) > 0 {
+			if err := s.orgRepo.MarkOrganizationsDeleted(txCtx, orgIDs); err != nil {
+				return err
+			}
+		}
+		return s.identityRepo.MarkIdentityDeleted(txCtx, identityID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.ScheduledDeletion{
+		OrganizationIDs: orgIDs,
+		IdentityID:      identityID,
+		PurgeAfter:      s.purgeAfter(time.Now()),
+	}, nil
+}
+
+// DeleteOrganization schedules the deletion of the current organization. Only
+// its owner may delete it.
+func (s *DeletionService) DeleteOrganization(reqCtx *app.RequestContext) (*model.ScheduledDeletion, error) {
+	org, err := s.orgRepo.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil || org.OwnerID != reqCtx.User.IdentityID {
+		return nil, ErrNotOrganizationOwner
+	}
+	if err := s.orgRepo.MarkOrganizationsDeleted(reqCtx, []bson.ObjectID{org.ID}); err != nil {
+		return nil, err
+	}
+	return &model.ScheduledDeletion{
+		OrganizationIDs: []bson.ObjectID{org.ID},
+		PurgeAfter:      s.purgeAfter(time.Now()),
+	}, nil
+}
+
+// RestoreAccount reverts DeleteAccount during the grace period, together with
+// the organizations deleted with the account.
+func (s *DeletionService) RestoreAccount(reqCtx *app.RequestContext, identityID bson.ObjectID) error {
+	return app.WithTransaction(reqCtx, s.sessions, func(txCtx *app.RequestContext) error {
+		restored, err := s.identityRepo.RestoreIdentity(txCtx, identityID)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return ErrNotPendingDeletion
+		}
+		orgs, err := s.orgRepo.GetOrganizations(txCtx, bson.M{"deleted_by": identityID, "deleted_at": bson.M{"$exists": true}})
+		if err != nil {
+			return err
+		}
+		if len(orgs) == 0 {
+			return nil
+		}
+		orgIDs := make([]bson.ObjectID, 0, len(orgs))
+		for _, org := range orgs {
+			orgIDs = append(orgIDs, org.ID)
+		}
+		return s.orgRepo.RestoreOrganizations(txCtx, orgIDs)
+	})
+}
+
+// RestoreOrganization reverts the deletion of an organization during the
+// grace period.
+func (s *DeletionService) RestoreOrganization(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	org, err := s.orgRepo.GetOrganizationByID(reqCtx, orgID)
+	if err != nil {
+		return err
+	}
+	if org == nil || org.DeletedAt.IsZero() {
+		return ErrNotPendingDeletion
+	}
+	return s.orgRepo.RestoreOrganizations(reqCtx, []bson.ObjectID{orgID})
+}
+
+func (s *DeletionService) ListDeletionCertificates(reqCtx *app.RequestContext, pageReq *app.PageRequest) ([]*model.DeletionCertificate, error) {
+	return s.certRepo.ListDeletionCertificates(reqCtx, pageReq)
+}
+
+// Start runs the purge job in the background until Stop is called.
+func (s *DeletionService) Start() {
+	if !s.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(deletionPurgeInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.PurgeDue(&app.RequestContext{}); err != nil {
+				log.Printf("deletion purge failed: %v", err)
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running purge to finish and stops the job.
+func (s *DeletionService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if s.started.Load() {
+			<-s.done
+		}
+	})
+}
+
+// PurgeDue purges the organizations and identities whose grace period has
+// passed and returns how many were purged. A purge that fails in any step is
+// retried on the next run.
+func (s *DeletionService) PurgeDue(reqCtx *app.RequestContext) (int, error) {
+	cutoff := time.Now().Add(-s.appCtx.Config.DELETION_GRACE_PERIOD)
+	purged := 0
+
+	orgs, err := s.orgRepo.GetOrganizations(reqCtx, bson.M{"deleted_at": bson.M{"$lte": cutoff}})
+	if err != nil {
+		return purged, err
+	}
+	for _, org := range orgs {
+		if s.purgeOrganization(reqCtx, org) {
+			purged++
+		}
+	}
+
+	identities, err := s.identityRepo.GetIdentitiesDeletedBefore(reqCtx, cutoff)
+	if err != nil {
+		return purged, err
+	}
+	for _, identity := range identities {
+		if s.purgeIdentity(reqCtx, identity) {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// deletionSteps runs the steps of a purge and records their outcome.
+type deletionSteps struct {
+	steps  []model.DeletionStep
+	failed bool
+}
+
+func (d *deletionSteps) run(name string, fn func() error) {
+	step := model.DeletionStep{Name: name, Status: model.DeletionStepDone}
+	if err := fn(); err != nil {
+		step.Status = model.DeletionStepFailed
+		step.Detail = err.Error()
+		d.failed = true
+	}
+	d.steps = append(d.steps, step)
+}
+
+func (d *deletionSteps) skip(name string, detail string) {
+	d.steps = append(d.steps, model.DeletionStep{Name: name, Status: model.DeletionStepSkipped, Detail: detail})
+}
+
+func (s *DeletionService) purgeOrganization(reqCtx *app.RequestContext, org *model.Organization) bool {
+	steps := &deletionSteps{}
+
+	if org.StripeCustomerId == "" {
+		steps.skip("stripe_customer", "no stripe customer")
+	} else {
+		// Deleting the customer cancels its subscriptions immediately.
+		steps.run("stripe_customer", func() error { return s.deleteStripeCustomer(org.StripeCustomerId) })
+	}
+	if org.Slug == "" {
+		steps.skip("database", "no slug")
+	} else {
+		steps.run("database", func() error { return s.orgRepo.DropOrganizationDatabase(reqCtx, org.Slug) })
+	}
+	steps.run("uploads", func() error { return s.removeBlobs(reqCtx, file_utils.UploadPrefix(org.ID)) })
+	steps.run("exports", func() error { return s.removeBlobs(reqCtx, file_utils.ExportPrefix(org.ID)) })
+	steps.run("quarantine", func() error { return s.removeBlobs(reqCtx, file_utils.QuarantinePrefix(org.ID)) })
+	steps.run("users", func() error { return s.userRepo.DeleteUserByOrgIDs(reqCtx, []bson.ObjectID{org.ID}) })
+	steps.run("invitations", func() error { return s.invitationRepo.DeleteInvitationsByOrg(reqCtx, org.ID) })
+	steps.run("api_keys", func() error { return s.apiKeyRepo.DeleteAPIKeysByOrg(reqCtx, org.ID) })
+	steps.run("data_exports", func() error { return s.dataExportRepo.DeleteDataExportsByOrg(reqCtx, org.ID) })
+	steps.run("quota_overrides", func() error { return s.overrideRepo.DeleteOverride(reqCtx, org.ID) })
+	steps.run("monthly_usage", func() error { return s.usageRepo.DeleteUsageByOrg(reqCtx, org.ID) })
+	steps.run("meter_events", func() error { return s.meterEventRepo.DeleteMeterEventsByOrg(reqCtx, org.ID) })
+	// The data keys go last: once they are deleted, whatever is left of the
+	// encrypted data, backups included, can no longer be read.
+	steps.run("data_keys", func() error { return s.dataKeyRepo.DeleteDataKeysByOrg(reqCtx, org.ID) })
+	steps.skip("categories", "retained: the categories are a catalog shared by all organizations")
+	steps.skip("billing_records", "retained: subscriptions and invoices are kept for accounting")
+	if steps.failed {
+		log.Printf("purge of organization %s incomplete, retrying later: %+v", org.ID.Hex(), steps.steps)
+		return false
+	}
+	steps.run("organization", func() error { return s.orgRepo.DeleteOrganization(reqCtx, org.ID) })
+	if steps.failed {
+		log.Printf("failed to delete organization %s, retrying later", org.ID.Hex())
+		return false
+	}
+
+	s.certify(reqCtx, &model.DeletionCertificate{
+		Subject:     model.DeletionSubjectOrganization,
+		SubjectID:   org.ID,
+		Slug:        org.Slug,
+		RequestedAt: org.DeletedAt,
+		RequestedBy: org.DeletedBy,
+		Steps:       steps.steps,
+	})
+	return true
+}
+
+func (s *DeletionService) purgeIdentity(reqCtx *app.RequestContext, identity *model.Identity) bool {
+	steps := &deletionSteps{}
+	steps.run("memberships", func() error { return s.userRepo.DeleteUsersByIdentityID(reqCtx, identity.ID) })
+	steps.run("auth_tokens", func() error { return s.authTokenRepo.DeleteAuthTokensByIdentity(reqCtx, identity.ID) })
+	if steps.failed {
+		log.Printf("purge of identity %s incomplete, retrying later", identity.ID.Hex())
+		return false
+	}
+	steps.run("identity", func() error { return s.identityRepo.DeleteIdentity(reqCtx, identity.ID) })
+	if steps.failed {
+		log.Printf("failed to delete identity %s, retrying later", identity.ID.Hex())
+		return false
+	}
+
+	emailHash := sha256.Sum256([]byte(strings.ToLower(identity.Email)))
+	s.certify(reqCtx, &model.DeletionCertificate{
+		Subject:     model.DeletionSubjectIdentity,
+		SubjectID:   identity.ID,
+		EmailHash:   hex.EncodeToString(emailHash[:]),
+		RequestedAt: identity.DeletedAt,
+		RequestedBy: identity.DeletedBy,
+		Steps:       steps.steps,
+	})
+	return true
+}
+
+// certify records the certificate of a completed purge. The data is already
+// gone at this point, so a failure is only logged.
+func (s *DeletionService) certify(reqCtx *app.RequestContext, cert *model.DeletionCertificate) {
+	cert.PurgedAt = time.Now()
+	if _, err := s.certRepo.CreateDeletionCertificate(reqCtx, cert); err != nil {
+		log.Printf("failed to record deletion certificate of %s %s: %v", cert.Subject, cert.SubjectID.Hex(), err)
+	}
+}
+
+func (s *DeletionService) deleteStripeCustomer(customerID string) error {
+	_, err := s.sc.V1Customers.Delete(context.Background(), customerID, nil)
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) && stripeErr.HTTPStatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete stripe customer: %w", err)
+	}
+	return nil
+}
+
+func (s *DeletionService) removeBlobs(reqCtx *app.RequestContext, prefix string) error {
+	if err := s.appCtx.Blobs.DeletePrefix(reqCtx.Context(), prefix); err != nil {
+		return fmt.Errorf("failed to remove files: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/file_utils"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/storage"
+)
+
+type deletionMocks struct {
+	sessions       *mocks.MockSessionProvider
+	orgRepo        *mocks.MockOrganizationRepo
+	identityRepo   *mocks.MockIdentityRepo
+	userRepo       *mocks.MockUserRepo
+	invitationRepo *mocks.MockInvitationRepository
+	apiKeyRepo     *mocks.MockAPIKeyRepository
+	certRepo       *mocks.MockDeletionCertificateRepository
+	dataExportRepo *mocks.MockDataExportRepository
+	dataKeyRepo    *mocks.MockDataKeyRepository
+	overrideRepo   *mocks.MockQuotaOverrideRepository
+	usageRepo      *mocks.MockMonthlyUsageRepository
+	meterEventRepo *mocks.MockMeterEventRepository
+	authTokenRepo  *mocks.MockAuthTokenRepository
+}
+
+func newDeletionService(t *testing.T, appCtx *app.AppContext) (*service.DeletionService, *deletionMocks) {
+	ctrl := gomock.NewController(t)
+	m := &deletionMocks{
+		sessions:       mocks.NewMockSessionProvider(ctrl),
+		orgRepo:        mocks.NewMockOrganizationRepo(ctrl),
+		identityRepo:   mocks.NewMockIdentityRepo(ctrl),
+		userRepo:       mocks.NewMockUserRepo(ctrl),
+		invitationRepo: mocks.NewMockInvitationRepository(ctrl),
+		apiKeyRepo:     mocks.NewMockAPIKeyRepository(ctrl),
+		certRepo:       mocks.NewMockDeletionCertificateRepository(ctrl),
+		dataExportRepo: mocks.NewMockDataExportRepository(ctrl),
+		dataKeyRepo:    mocks.NewMockDataKeyRepository(ctrl),
+		overrideRepo:   mocks.NewMockQuotaOverrideRepository(ctrl),
+		usageRepo:      mocks.NewMockMonthlyUsageRepository(ctrl),
+		meterEventRepo: mocks.NewMockMeterEventRepository(ctrl),
+		authTokenRepo:  mocks.NewMockAuthTokenRepository(ctrl),
+	}
+	// The stripe client is never called for organizations without a customer.
+	sc := service.NewStripeClient("sk_test", "http://127.0.0.1:1")
+	svc := service.NewDeletionService(appCtx, m.sessions, sc, m.orgRepo, m.identityRepo, m.userRepo, m.invitationRepo, m.apiKeyRepo, m.certRepo,
+		m.dataExportRepo, m.dataKeyRepo, m.overrideRepo, m.usageRepo, m.meterEventRepo, m.authTokenRepo)
+	return svc, m
+}
+
+func TestDeleteAccountSoftDeletesOwnedOrganizations(t *testing.T) {
+	svc, m := newDeletionService(t, app.NewMockAppContext())
+	ctrl := gomock.NewController(t)
+	session := mocks.NewMockMongoSession(ctrl)
+	session.EXPECT().Context(gomock.Any()).AnyTimes().DoAndReturn(func(ctx context.Context) context.Context { return ctx })
+	session.EXPECT().StartTransaction().Return(nil)
+	session.EXPECT().CommitTransaction(gomock.Any()).Return(nil)
+	session.EXPECT().EndSession(gomock.Any())
+	m.sessions.EXPECT().StartSession().Return(session, nil)
+
+	identityID := bson.NewObjectID()
+	orgID := bson.NewObjectID()
+	reqCtx := &app.RequestContext{User: app.RequestUser{IdentityID: identityID}}
+
+	m.orgRepo.EXPECT().GetOrganizations(reqCtx, gomock.Any()).Return([]*model.Organization{{Base: model.Base{ID: orgID}}}, nil)
+	m.orgRepo.EXPECT().MarkOrganizationsDeleted(gomock.Any(), []bson.ObjectID{orgID}).Return(nil)
+	m.identityRepo.EXPECT().MarkIdentityDeleted(gomock.Any(), identityID).Return(nil)
+
+	deletion, err := svc.DeleteAccount(reqCtx)
+	if err != nil {
+		t.Fatalf("DeleteAccount returned error: %v", err)
+	}
+	if len(deletion.OrganizationIDs) != 1 || deletion.OrganizationIDs[0] != orgID {
+		t.Errorf("expected the owned organization to be scheduled, got %v", deletion.OrganizationIDs)
+	}
+	if time.Until(deletion.PurgeAfter) < 29*24*time.Hour {
+		t.Errorf("expected the purge after the grace period, got %v", deletion.PurgeAfter)
+	}
+}
+
+func TestDeleteOrganizationRequiresOwner(t *testing.T) {
+	svc, m := newDeletionService(t, app.NewMockAppContext())
+	orgID := bson.NewObjectID()
+	reqCtx := &app.RequestContext{User: app.RequestUser{IdentityID: bson.NewObjectID()}, Org: app.RequestOrg{ID: orgID}}
+
+	m.orgRepo.EXPECT().GetOrganizationByID(reqCtx, orgID).Return(&model.Organization{Base: model.Base{ID: orgID}, OwnerID: bson.NewObjectID()}, nil)
+
+	if _, err := svc.DeleteOrganization(reqCtx); err != service.ErrNotOrganizationOwner {
+		t.Errorf("expected ErrNotOrganizationOwner, got %v", err)
+	}
+}
+
+func TestPurgeDueRemovesOrganizationDataAndCertifies(t *testing.T) {
+	appCtx := app.NewMockAppContext()
+	appCtx.Blobs = storage.NewLocalBlobStore(t.TempDir())
+	svc, m := newDeletionService(t, appCtx)
+
+	reqCtx := &app.RequestContext{}
+	org := &model.Organization{Base: model.Base{ID: bson.NewObjectID(), DeletedAt: time.Now().AddDate(0, -2, 0)}, Slug: "acme"}
+	upload := file_utils.UploadPrefix(org.ID) + "abc_invoice.pdf"
+	if err := appCtx.Blobs.Put(context.Background(), upload, strings.NewReader("%PDF-1.4"), 8, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	m.orgRepo.EXPECT().GetOrganizations(reqCtx, gomock.Any()).Return([]*model.Organization{org}, nil)
+	m.orgRepo.EXPECT().DropOrganizationDatabase(reqCtx, "acme").Return(nil)
+	m.userRepo.EXPECT().DeleteUserByOrgIDs(reqCtx, []bson.ObjectID{org.ID}).Return(nil)
+	m.invitationRepo.EXPECT().DeleteInvitationsByOrg(reqCtx, org.ID).Return(nil)
+	m.apiKeyRepo.EXPECT().DeleteAPIKeysByOrg(reqCtx, org.ID).Return(nil)
+	m.dataExportRepo.EXPECT().DeleteDataExportsByOrg(reqCtx, org.ID).Return(nil)
+	m.overrideRepo.EXPECT().DeleteOverride(reqCtx, org.ID).Return(nil)
+	m.usageRepo.EXPECT().DeleteUsageByOrg(reqCtx, org.ID).Return(nil)
+	m.meterEventRepo.EXPECT().DeleteMeterEventsByOrg(reqCtx, org.ID).Return(nil)
+	m.dataKeyRepo.EXPECT().DeleteDataKeysByOrg(reqCtx, org.ID).Return(nil)
+	m.orgRepo.EXPECT().DeleteOrganization(reqCtx, org.ID).Return(nil)
+	m.identityRepo.EXPECT().GetIdentitiesDeletedBefore(reqCtx, gomock.Any()).Return(nil, nil)
+
+	var cert *model.DeletionCertificate
+	m.certRepo.EXPECT().CreateDeletionCertificate(reqCtx, gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, c *model.DeletionCertificate) (*model.DeletionCertificate, error) {
+			cert = c
+			return c, nil
+		})
+
+	purged, err := svc.PurgeDue(reqCtx)
+	if err != nil {
+		t.Fatalf("PurgeDue returned error: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("expected 1 purge, got %d", purged)
+	}
+	if _, err := appCtx.Blobs.Stat(context.Background(), upload); err != storage.ErrBlobNotFound {
+		t.Errorf("expected the uploads of the organization to be removed, stat err %v", err)
+	}
+	if cert == nil || cert.SubjectID != org.ID || cert.Steps[0].Status != model.DeletionStepSkipped {
+		t.Fatalf("unexpected deletion certificate %+v", cert)
+	}
+	// Every piece of data of the organization is accounted for.
+	for _, step := range cert.Steps {
+		if step.Status == model.DeletionStepSkipped && step.Name != "stripe_customer" && !strings.HasPrefix(step.Detail, "retained: ") {
+			t.Errorf("expected the skipped step %s to give its retention reason, got %q", step.Name, step.Detail)
+		}
+	}
+}
+
+func TestPurgeDueRemovesIdentityAuthTokens(t *testing.T) {
+	svc, m := newDeletionService(t, app.NewMockAppContext())
+	reqCtx := &app.RequestContext{}
+	identity := &model.Identity{Base: model.Base{ID: bson.NewObjectID(), DeletedAt: time.Now().AddDate(0, -2, 0)}, Email: "jane@acme.com"}
+
+	m.orgRepo.EXPECT().GetOrganizations(reqCtx, gomock.Any()).Return(nil, nil)
+	m.identityRepo.EXPECT().GetIdentitiesDeletedBefore(reqCtx, gomock.Any()).Return([]*model.Identity{identity}, nil)
+	m.userRepo.EXPECT().DeleteUsersByIdentityID(reqCtx, identity.ID).Return(nil)
+	m.authTokenRepo.EXPECT().DeleteAuthTokensByIdentity(reqCtx, identity.ID).Return(nil)
+	m.identityRepo.EXPECT().DeleteIdentity(reqCtx, identity.ID).Return(nil)
+	m.certRepo.EXPECT().CreateDeletionCertificate(reqCtx, gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, c *model.DeletionCertificate) (*model.DeletionCertificate, error) {
+			if len(c.Steps) != 3 || c.Steps[1].Name != "auth_tokens" || c.Steps[1].Status != model.DeletionStepDone {
+				t.Errorf("expected the auth tokens step in the certificate, got %+v", c.Steps)
+			}
+			return c, nil
+		})
+
+	if purged, err := svc.PurgeDue(reqCtx); err != nil || purged != 1 {
+		t.Fatalf("expected the identity to be purged, got %d, %v", purged, err)
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/encryption"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+// ErrEncryptionDisabled is returned by the key management operations when no
+// master key is configured.
+var ErrEncryptionDisabled = errors.New("encryption at rest is not configured")
+
+const (
+	// currentDataKeyTTL bounds how long an instance keeps encrypting with a
+	// data key after another instance rotated it.
+	currentDataKeyTTL = time.Minute
+	// dataKeyTTL is how long an unwrapped version of a data key is cached.
+	dataKeyTTL = time.Hour
+	// rewrapBatchSize is the number of data keys rewrapped per query.
+	rewrapBatchSize = 100
+)
+
+// EncryptionService manages the data keys of the organizations: it creates
+// them on first use, rotates them and rewraps them when the master key is
+// rotated. It provides the unwrapped keys to the encrypted blob store and to
+// the field cipher of the category data.
+//
+// Rotating a data key only adds a version. What was encrypted with the older
+// versions stays readable, so both rotations happen without downtime.
+type EncryptionService struct {
+	masterKeys encryption.KeyProvider
+	repo       repo.DataKeyRepository
+	cache      *ristretto.Cache[string, *encryption.DataKey]
+}
+
+func NewEncryptionService(masterKeys encryption.KeyProvider, repo repo.DataKeyRepository) *EncryptionService {
+	cache, err := ristretto.NewCache(&ristretto.Config[string, *encryption.DataKey]{
+		NumCounters: 100000,
+		MaxCost:     10000,
+		BufferItems: 64,
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize data key cache: %v", err)
+	}
+	return &EncryptionService{
+		masterKeys: masterKeys,
+		repo:       repo,
+		cache:      cache,
+	}
+}
+
+func (s *EncryptionService) EnsureIndexes() error {
+	return s.repo.EnsureIndexes()
+}
+
+// Enabled reports whether a master key is configured.
+func (s *EncryptionService) Enabled() bool {
+	return s.masterKeys != nil
+}
+
+func currentDataKeyCacheKey(orgID bson.ObjectID) string {
+	return orgID.Hex() + "/current"
+}
+
+func dataKeyCacheKey(orgID bson.ObjectID, version uint32) string {
+	return fmt.Sprintf("%s/%d", orgID.Hex(), version)
+}
+
+// CurrentDataKey returns the latest version of the data key of the
+// organization, creating the first one if need be.
+func (s *EncryptionService) CurrentDataKey(ctx context.Context, orgID bson.ObjectID) (*encryption.DataKey, error) {
+	if !s.Enabled() {
+		return nil, ErrEncryptionDisabled
+	}
+	if key, found := s.cache.Get(currentDataKeyCacheKey(orgID)); found {
+		return key, nil
+	}
+
+	// The key is cached once created, so it is read and created outside the
+	// transaction the caller may be in: an aborted transaction must not take
+	// a key in use with it.
+	reqCtx := (&app.RequestContext{}).WithContext(mongo.NewSessionContext(ctx, nil))
+	stored, err := s.repo.GetLatestDataKey(reqCtx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		stored, err = s.createDataKey(reqCtx, orgID, 1)
+		if errors.Is(err, repo.ErrDataKeyExists) {
+			// Another instance created it first.
+			stored, err = s.repo.GetLatestDataKey(reqCtx, orgID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := s.unwrap(ctx, stored)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.SetWithTTL(currentDataKeyCacheKey(orgID), key, 1, currentDataKeyTTL)
+	s.cache.Wait()
+	return key, nil
+}
+
+// DataKey returns a version of the data key of the organization.
+func (s *EncryptionService) DataKey(ctx context.Context, orgID bson.ObjectID, version uint32) (*encryption.DataKey, error) {
+	if !s.Enabled() {
+		return nil, ErrEncryptionDisabled
+	}
+	if key, found := s.cache.Get(dataKeyCacheKey(orgID, version)); found {
+		return key, nil
+	}
+
+	stored, err := s.repo.GetDataKey((&app.RequestContext{}).WithContext(ctx), orgID, int(version))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("version %d of the data key of organization %s not found", version, orgID.Hex())
+	}
+	return s.unwrap(ctx, stored)
+}
+
+// unwrap decrypts the stored data key with its master key and caches it.
+func (s *EncryptionService) unwrap(ctx context.Context, stored *model.DataKey) (*encryption.DataKey, error) {
+	raw, err := s.masterKeys.Unwrap(ctx, stored.MasterKeyID, stored.WrappedKey)
+	if err != nil {
+		log.Printf("failed to unwrap version %d of the data key of organization %s: %v", stored.Version, stored.OrganizationID.Hex(), err)
+		return nil, errors.New("failed to unwrap data key")
+	}
+	key := &encryption.DataKey{Version: uint32(stored.Version), Key: raw}
+	s.cache.SetWithTTL(dataKeyCacheKey(stored.OrganizationID, key.Version), key, 1, dataKeyTTL)
+	s.cache.Wait()
+	return key, nil
+}
+
+func (s *EncryptionService) createDataKey(reqCtx *app.RequestContext, orgID bson.ObjectID, version int) (*model.DataKey, error) {
+	raw, err := encryption.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	masterKeyID, wrapped, err := s.masterKeys.Wrap(reqCtx.Context(), raw)
+	if err != nil {
+		log.Printf("failed to wrap data key: %v", err)
+		return nil, errors.New("failed to wrap data key")
+	}
+	stored := &model.DataKey{
+		OrganizationID: orgID,
+		Version:        version,
+		MasterKeyID:    masterKeyID,
+		WrappedKey:     wrapped,
+	}
+	if err := s.repo.CreateDataKey(reqCtx, stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// RotateDataKey adds a version to the data key of the organization of the
+// request. New documents and values are encrypted with it; the other
+// instances switch to it within currentDataKeyTTL.
+func (s *EncryptionService) RotateDataKey(reqCtx *app.RequestContext) (*model.DataKey, error) {
+	if !s.Enabled() {
+		return nil, ErrEncryptionDisabled
+	}
+	orgID := reqCtx.Org.ID
+	latest, err := s.repo.GetLatestDataKey(reqCtx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	version := 1
+	if latest != nil {
+		version = latest.Version + 1
+	}
+	stored, err := s.createDataKey(reqCtx, orgID, version)
+	if errors.Is(err, repo.ErrDataKeyExists) {
+		return nil, errors.New("the data key is being rotated by another request")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Del(currentDataKeyCacheKey(orgID))
+	return stored, nil
+}
+
+// RewrapDataKeys wraps the data keys of all organizations with the primary
+// master key, after the master key was rotated. The data itself is not
+// re-encrypted. Keys that fail are counted and left as they are, so the
+// rewrap can be run again once their master key is available.
+func (s *EncryptionService) RewrapDataKeys(reqCtx *app.RequestContext) (*model.DataKeyRewrapReport, error) {
+	if !s.Enabled() {
+		return nil, ErrEncryptionDisabled
+	}
+	report := &model.DataKeyRewrapReport{MasterKeyID: s.masterKeys.PrimaryKeyID()}
+	afterID := bson.NilObjectID
+	for {
+		keys, err := s.repo.ListDataKeysNotWrappedWith(reqCtx, report.MasterKeyID, afterID, rewrapBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, stored := range keys {
+			afterID = stored.ID
+			if err := s.rewrap(reqCtx, stored, report.MasterKeyID); err != nil {
+				log.Printf("failed to rewrap data key %s: %v", stored.ID.Hex(), err)
+				report.Failed++
+				continue
+			}
+			report.Rewrapped++
+		}
+		if len(keys) < rewrapBatchSize {
+			return report, nil
+		}
+	}
+}
+
+func (s *EncryptionService) rewrap(reqCtx *app.RequestContext, stored *model.DataKey, masterKeyID string) error {
+	raw, err := s.masterKeys.Unwrap(reqCtx.Context(), stored.MasterKeyID, stored.WrappedKey)
+	if err != nil {
+		return err
+	}
+	wrappedWith, wrapped, err := s.masterKeys.Wrap(reqCtx.Context(), raw)
+	if err != nil {
+		return err
+	}
+	if wrappedWith != masterKeyID {
+		return fmt.Errorf("the primary master key changed to %q during the rewrap", wrappedWith)
+	}
+	return s.repo.RewrapDataKey(reqCtx, stored.ID, stored.MasterKeyID, wrappedWith, wrapped)
+}
+
+func (s *EncryptionService) Close() {
+	s.cache.Close()
+}
@@ -0,0 +1,138 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/encryption"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+func newMasterKeys(t *testing.T, ids ...string) map[string][]byte {
+	keys := map[string][]byte{}
+	for _, id := range ids {
+		key, err := encryption.NewDataKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = key
+	}
+	return keys
+}
+
+func newKeyProvider(t *testing.T, primary string, keys map[string][]byte) *encryption.LocalKeyProvider {
+	provider, err := encryption.NewLocalKeyProvider(primary, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestEncryptionServiceRotatesDataKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dataKeyRepo := mocks.NewMockDataKeyRepository(ctrl)
+	svc := service.NewEncryptionService(newKeyProvider(t, "m1", newMasterKeys(t, "m1")), dataKeyRepo)
+	defer svc.Close()
+
+	orgID := bson.NewObjectID()
+	var stored []*model.DataKey
+	dataKeyRepo.EXPECT().CreateDataKey(gomock.Any(), gomock.Any()).Times(2).DoAndReturn(
+		func(_ *app.RequestContext, key *model.DataKey) error {
+			key.ID = bson.NewObjectID()
+			stored = append(stored, key)
+			return nil
+		})
+	dataKeyRepo.EXPECT().GetLatestDataKey(gomock.Any(), orgID).AnyTimes().DoAndReturn(
+		func(_ *app.RequestContext, _ bson.ObjectID) (*model.DataKey, error) {
+			if len(stored) == 0 {
+				return nil, nil
+			}
+			return stored[len(stored)-1], nil
+		})
+	dataKeyRepo.EXPECT().GetDataKey(gomock.Any(), orgID, gomock.Any()).AnyTimes().DoAndReturn(
+		func(_ *app.RequestContext, _ bson.ObjectID, version int) (*model.DataKey, error) {
+			return stored[version-1], nil
+		})
+
+	first, err := svc.CurrentDataKey(context.Background(), orgID)
+	if err != nil {
+		t.Fatalf("CurrentDataKey returned error: %v", err)
+	}
+	if first.Version != 1 || len(stored) != 1 || stored[0].MasterKeyID != "m1" || bytes.Equal(stored[0].WrappedKey, first.Key) {
+		t.Fatalf("expected version 1 to be created wrapped with the master key, got %+v", stored)
+	}
+
+	rotated, err := svc.RotateDataKey(&app.RequestContext{Org: app.RequestOrg{ID: orgID}})
+	if err != nil {
+		t.Fatalf("RotateDataKey returned error: %v", err)
+	}
+	if rotated.Version != 2 {
+		t.Fatalf("expected version 2, got %d", rotated.Version)
+	}
+	current, err := svc.CurrentDataKey(context.Background(), orgID)
+	if err != nil || current.Version != 2 {
+		t.Fatalf("expected new data to be encrypted with version 2, got %+v, %v", current, err)
+	}
+	old, err := svc.DataKey(context.Background(), orgID, 1)
+	if err != nil || !bytes.Equal(old.Key, first.Key) {
+		t.Errorf("expected version 1 to stay readable, got %v", err)
+	}
+}
+
+func TestEncryptionServiceRewrapsDataKeysWithPrimaryMasterKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dataKeyRepo := mocks.NewMockDataKeyRepository(ctrl)
+	masterKeys := newMasterKeys(t, "old", "new")
+
+	var created *model.DataKey
+	dataKeyRepo.EXPECT().GetLatestDataKey(gomock.Any(), gomock.Any()).Return(nil, nil)
+	dataKeyRepo.EXPECT().CreateDataKey(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, key *model.DataKey) error {
+			key.ID = bson.NewObjectID()
+			created = key
+			return nil
+		})
+	before := service.NewEncryptionService(newKeyProvider(t, "old", map[string][]byte{"old": masterKeys["old"]}), dataKeyRepo)
+	dataKey, err := before.CurrentDataKey(context.Background(), bson.NewObjectID())
+	before.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The master key was rotated: the new one is primary and the old one is
+	// kept until the data keys are rewrapped.
+	svc := service.NewEncryptionService(newKeyProvider(t, "new", masterKeys), dataKeyRepo)
+	defer svc.Close()
+
+	lost := &model.DataKey{Base: model.Base{ID: bson.NewObjectID()}, Version: 1, MasterKeyID: "lost", WrappedKey: []byte("x")}
+	dataKeyRepo.EXPECT().ListDataKeysNotWrappedWith(gomock.Any(), "new", bson.NilObjectID, gomock.Any()).
+		Return([]*model.DataKey{created, lost}, nil)
+	var rewrapped []byte
+	dataKeyRepo.EXPECT().RewrapDataKey(gomock.Any(), created.ID, "old", "new", gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, _ bson.ObjectID, _ string, _ string, wrapped []byte) error {
+			rewrapped = wrapped
+			return nil
+		})
+
+	report, err := svc.RewrapDataKeys(&app.RequestContext{})
+	if err != nil {
+		t.Fatalf("RewrapDataKeys returned error: %v", err)
+	}
+	if report.MasterKeyID != "new" || report.Rewrapped != 1 || report.Failed != 1 {
+		t.Errorf("unexpected rewrap report %+v", report)
+	}
+
+	// Once the old master key is removed the data key is still readable.
+	after := newKeyProvider(t, "new", map[string][]byte{"new": masterKeys["new"]})
+	unwrapped, err := after.Unwrap(context.Background(), "new", rewrapped)
+	if err != nil || !bytes.Equal(unwrapped, dataKey.Key) {
+		t.Errorf("expected the data key to be rewrapped with the new master key, got %v", err)
+	}
+}
@@ -4,18 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/file_utils"
 	"github.com/gaeaglobal/exto/server/model"
 	"github.com/xuri/excelize/v2"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+const excelContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// ExportResult is an Excel export stored in the blob store under Key.
 type ExportResult struct {
-	FilePath string
+	Key      string
 	FileName string
 	Error    error
 }
@@ -161,16 +163,14 @@ func (s *ExportService) ExportAsExcel(reqCtx *app.RequestContext, scanHistoryID
 	// Save
 	date_string := time.Now().Format("2006_01_02_15_04_05")
 	file_name := fmt.Sprintf("export_%s_%s.xlsx", category.Slug, date_string)
-	dir := filepath.Join(s.appCtx.Config.EXPORT_DIR, reqCtx.User.OrganizationID.Hex())
-	err = os.MkdirAll(dir, os.ModePerm)
+	buf, err := f.WriteToBuffer()
 	if err != nil {
 		return nil, err
 	}
-
-	dst := filepath.Join(dir, file_name)
-	if err := f.SaveAs(dst); err != nil {
+	key := file_utils.ExportKey(reqCtx.User.OrganizationID, file_name)
+	if err := s.appCtx.Blobs.Put(reqCtx.Context(), key, buf, int64(buf.Len()), excelContentType); err != nil {
 		return nil, err
 	}
-	log.Printf("Exported file to: %s", dst)
-	return &ExportResult{FilePath: dst, FileName: file_name}, nil
+	log.Printf("Exported file to: %s", key)
+	return &ExportResult{Key: key, FileName: file_name}, nil
 }
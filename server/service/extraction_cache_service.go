@@ -0,0 +1,115 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+
+	"github.com/dgraph-io/ristretto/v2"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+// ExtractionCacheKey identifies a cached LLM extraction. Two scans with the same
+// document content, formats, prompt version and model produce the same key.
+type ExtractionCacheKey struct {
+	DocumentHash  string
+	FormatID      string
+	PromptVersion string
+	Model         string
+}
+
+func (k ExtractionCacheKey) String() string {
+	return HashContent(strings.Join([]string{k.DocumentHash, k.FormatID, k.PromptVersion, k.Model}, "|"))
+}
+
+// HashContent returns the hex encoded sha256 of the given content.
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ExtractionCacheService keeps LLM extraction responses in a per-org Mongo
+// collection with an in-memory tier in front of it.
+type ExtractionCacheService struct {
+	repo       repo.ExtractionCacheRepository
+	orgService *OrganizationService
+	memory     *ristretto.Cache[string, string]
+}
+
+func NewExtractionCacheService(repo repo.ExtractionCacheRepository, orgService *OrganizationService) *ExtractionCacheService {
+	memory, err := ristretto.NewCache(&ristretto.Config[string, string]{
+		NumCounters: 10000,   // number of keys to track frequency of (10x MaxCost).
+		MaxCost:     1 << 26, // 64 MB of cached responses.
+		BufferItems: 64,      // number of keys per Get buffer.
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize extraction cache: %v", err)
+	}
+	return &ExtractionCacheService{
+		repo:       repo,
+		orgService: orgService,
+		memory:     memory,
+	}
+}
+
+// IsEnabled reports whether the organization of the request uses the cache.
+func (s *ExtractionCacheService) IsEnabled(reqCtx *app.RequestContext) bool {
+	org, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		log.Printf("failed to load organization for extraction cache: %v", err)
+		return false
+	}
+	return !org.ExtractionCacheDisabled
+}
+
+func (s *ExtractionCacheService) Get(reqCtx *app.RequestContext, key ExtractionCacheKey) (string, bool) {
+	k := key.String()
+	if response, found := s.memory.Get(s.memoryKey(reqCtx, k)); found {
+		s.recordHit(reqCtx, k)
+		return response, true
+	}
+
+	entry, err := s.repo.GetByKey(reqCtx, k)
+	if err != nil || entry == nil {
+		return "", false
+	}
+	s.memory.Set(s.memoryKey(reqCtx, k), entry.Response, int64(len(entry.Response)))
+	s.recordHit(reqCtx, k)
+	return entry.Response, true
+}
+
+func (s *ExtractionCacheService) Put(reqCtx *app.RequestContext, key ExtractionCacheKey, response string) {
+	k := key.String()
+	err := s.repo.Upsert(reqCtx, &model.CreateExtractionCacheEntry{
+		Key:           k,
+		DocumentHash:  key.DocumentHash,
+		FormatID:      key.FormatID,
+		PromptVersion: key.PromptVersion,
+		Model:         key.Model,
+		Response:      response,
+	})
+	if err != nil {
+		log.Printf("failed to store extraction cache entry: %v", err)
+	}
+	s.memory.Set(s.memoryKey(reqCtx, k), response, int64(len(response)))
+}
+
+func (s *ExtractionCacheService) Close() {
+	s.memory.Close()
+}
+
+func (s *ExtractionCacheService) recordHit(reqCtx *app.RequestContext, key string) {
+	if err := s.repo.RecordHit(reqCtx, key); err != nil {
+		log.Printf("failed to record extraction cache hit: %v", err)
+	}
+}
+
+// memoryKey scopes in-memory entries to the organization so that tenants never
+// share cached extractions.
+func (s *ExtractionCacheService) memoryKey(reqCtx *app.RequestContext, key string) string {
+	return reqCtx.Org.ID.Hex() + ":" + key
+}
@@ -0,0 +1,187 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+// memoryExtractionCacheRepo stands in for the per-organization Mongo tier and
+// counts its reads.
+type memoryExtractionCacheRepo struct {
+	mu      sync.Mutex
+	entries map[string]*model.ExtractionCacheEntry
+	reads   int
+}
+
+func newMemoryExtractionCacheRepo() *memoryExtractionCacheRepo {
+	return &memoryExtractionCacheRepo{entries: map[string]*model.ExtractionCacheEntry{}}
+}
+
+func (r *memoryExtractionCacheRepo) GetCollection(...string) *mongo.Collection { return nil }
+
+func (r *memoryExtractionCacheRepo) GetByKey(reqCtx *app.RequestContext, key string) (*model.ExtractionCacheEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads++
+	return r.entries[reqCtx.Org.ID.Hex()+":"+key], nil
+}
+
+func (r *memoryExtractionCacheRepo) Upsert(reqCtx *app.RequestContext, entry *model.CreateExtractionCacheEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[reqCtx.Org.ID.Hex()+":"+entry.Key] = &model.ExtractionCacheEntry{Key: entry.Key, Response: entry.Response}
+	return nil
+}
+
+func (r *memoryExtractionCacheRepo) RecordHit(*app.RequestContext, string) error { return nil }
+
+func (r *memoryExtractionCacheRepo) readCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reads
+}
+
+// countingLLM answers every extraction with the same response.
+type countingLLM struct {
+	calls int
+}
+
+func (l *countingLLM) CreateChatCompletion(context.Context, openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	l.calls++
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{
+		{Message: openai.ChatCompletionMessage{Content: `{"keyValues": []}`}},
+	}}, nil
+}
+
+func newExtractionCacheTestService(t *testing.T, cacheRepo *memoryExtractionCacheRepo, orgID bson.ObjectID) *service.ExtractionCacheService {
+	orgRepo := mocks.NewMockOrganizationRepo(gomock.NewController(t))
+	orgRepo.EXPECT().GetOrganizationByID(gomock.Any(), orgID).AnyTimes().Return(&model.Organization{Base: model.Base{ID: orgID}}, nil)
+	cache := service.NewExtractionCacheService(cacheRepo, service.NewOrganizationService(orgRepo))
+	t.Cleanup(cache.Close)
+	return cache
+}
+
+func TestExtractionCacheKeyComposition(t *testing.T) {
+	base := service.ExtractionCacheKey{
+		DocumentHash:  service.HashContent("document"),
+		FormatID:      "format-1,format-2",
+		PromptVersion: "v2-0123456789ab",
+		Model:         "gpt-4o",
+	}
+	if base.String() != base.String() || len(base.String()) != 64 {
+		t.Fatalf("expected a stable sha256 key, got %q", base.String())
+	}
+
+	for name, key := range map[string]service.ExtractionCacheKey{
+		"document":       {service.HashContent("other document"), base.FormatID, base.PromptVersion, base.Model},
+		"formats":        {base.DocumentHash, "format-1", base.PromptVersion, base.Model},
+		"prompt version": {base.DocumentHash, base.FormatID, "v3-0123456789ab", base.Model},
+		"template hash":  {base.DocumentHash, base.FormatID, "v2-ba9876543210", base.Model},
+		"model":          {base.DocumentHash, base.FormatID, base.PromptVersion, "gpt-4.1"},
+	} {
+		if key.String() == base.String() {
+			t.Errorf("expected a change of %s to change the key", name)
+		}
+	}
+}
+
+func TestExtractionCacheFallsThroughToMongo(t *testing.T) {
+	cacheRepo := newMemoryExtractionCacheRepo()
+	orgID := bson.NewObjectID()
+	reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: orgID}}
+	key := service.ExtractionCacheKey{DocumentHash: service.HashContent("document"), FormatID: "format-1", PromptVersion: "v2", Model: "gpt-4o"}
+
+	// Stored by another instance of the server: only Mongo has it.
+	if err := cacheRepo.Upsert(reqCtx, &model.CreateExtractionCacheEntry{Key: key.String(), Response: "cached"}); err != nil {
+		t.Fatal(err)
+	}
+	cache := newExtractionCacheTestService(t, cacheRepo, orgID)
+
+	if response, found := cache.Get(reqCtx, key); !found || response != "cached" {
+		t.Fatalf("expected the Mongo entry, got %q, %v", response, found)
+	}
+	if cacheRepo.readCount() != 1 {
+		t.Fatalf("expected one Mongo read, got %d", cacheRepo.readCount())
+	}
+
+	// The entry is now kept in memory, whose writes are applied asynchronously.
+	delete(cacheRepo.entries, orgID.Hex()+":"+key.String())
+	deadline := time.Now().Add(time.Second)
+	for {
+		if response, found := cache.Get(reqCtx, key); found && response == "cached" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the entry read from Mongo to be served from memory")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Other organizations never see it.
+	otherCtx := &app.RequestContext{Org: app.RequestOrg{ID: bson.NewObjectID()}}
+	if _, found := cache.Get(otherCtx, key); found {
+		t.Error("expected the entry of an organization to be hidden from the others")
+	}
+}
+
+func TestExtractionCacheInvalidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	formatRepo := mocks.NewMockFormatRepository(ctrl)
+	cacheRepo := newMemoryExtractionCacheRepo()
+	orgID := bson.NewObjectID()
+	reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: orgID}}
+	categoryID := bson.NewObjectID()
+	llm := &countingLLM{}
+	svc := service.NewOpenAIServiceWithLLM(service.NewFormatService(formatRepo), newExtractionCacheTestService(t, cacheRepo, orgID), llm)
+
+	format := model.Format{
+		Base:       model.Base{ID: bson.NewObjectID()},
+		CategoryID: categoryID,
+		ExtractionFields: []model.ExtractionField{
+			{Name: "Invoice number", CategoryFieldName: "invoice_number", Prompt: model.ExtractionPrompt{Text: "The invoice number"}},
+		},
+	}
+	formatRepo.EXPECT().GetFormatsByCategoryID(gomock.Any(), categoryID).AnyTimes().DoAndReturn(
+		func(*app.RequestContext, bson.ObjectID) ([]model.Format, error) { return []model.Format{format}, nil })
+
+	scan := func() {
+		t.Helper()
+		if _, err := svc.ExtractDocumentData(reqCtx, categoryID, "data:image/png;base64,AAAA", false); err != nil {
+			t.Fatalf("ExtractDocumentData returned error: %v", err)
+		}
+	}
+
+	scan()
+	scan()
+	if llm.calls != 1 {
+		t.Fatalf("expected the second scan of the document to be cached, got %d LLM calls", llm.calls)
+	}
+
+	// A new prompt of the template gives a new key.
+	format.ExtractionFields[0].Prompt.Text = "The number printed after \"Invoice #\""
+	scan()
+	if llm.calls != 2 {
+		t.Errorf("expected a changed prompt to bypass the cache, got %d LLM calls", llm.calls)
+	}
+
+	// So does a new model.
+	key := service.ExtractionCacheKey{DocumentHash: service.HashContent("document"), FormatID: format.ID.Hex(), PromptVersion: "v2", Model: "gpt-4o"}
+	cache := newExtractionCacheTestService(t, cacheRepo, orgID)
+	cache.Put(reqCtx, key, "cached")
+	key.Model = "gpt-4.1"
+	if _, found := cache.Get(reqCtx, key); found {
+		t.Error("expected a changed model to miss the cache")
+	}
+}
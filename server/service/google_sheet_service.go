@@ -26,17 +26,20 @@ func (s *GoogleSheetService) SyncSheet(sheetID string, organization_name, organi
 
 	b, err := os.ReadFile("google_sheet_auth/credentials.json")
 	if err != nil {
-		log.Fatalf("Unable to read client secret file: %v", err)
+		log.Printf("Unable to read client secret file: %v", err)
+		return
 	}
 
 	creds, err := google.CredentialsFromJSON(ctx, b, sheets.SpreadsheetsScope)
 	if err != nil {
-		log.Fatalf("Unable to parse service account credentials: %v", err)
+		log.Printf("Unable to parse service account credentials: %v", err)
+		return
 	}
 
 	srv, err := sheets.NewService(ctx, option.WithCredentials(creds))
 	if err != nil {
-		log.Fatalf("Unable to retrieve Sheets client: %v", err)
+		log.Printf("Unable to retrieve Sheets client: %v", err)
+		return
 	}
 
 	spreadsheetId := "1QcqsVK2IhhSuM4rIDXnl_Fs-SnqnIyBjjP1fwmha_z4"
@@ -54,7 +57,8 @@ func (s *GoogleSheetService) SyncSheet(sheetID string, organization_name, organi
 	newRow := []interface{}{organization_name, organization_id, ownerFirstName, ownerLastName, ownerEmail, createdAt}
 	err = s.appendRow(srv, spreadsheetId, tabName, newRow)
 	if err != nil {
-		log.Fatalf("Unable to append row: %v", err)
+		log.Printf("Unable to append row: %v", err)
+		return
 	}
 
 	fmt.Println("Successfully appended new row to the sheet.")
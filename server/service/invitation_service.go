@@ -0,0 +1,219 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvitationExpired       = errors.New("invitation has expired")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email")
+	ErrInvalidRole             = errors.New("invalid role")
+	ErrAlreadyMember           = errors.New("user is already a member of the organization")
+)
+
+// InvitationService invites people into an organization. The invitation token
+// is handed to the inviter once and accepted on sign-up or login.
+type InvitationService struct {
+	repo            repo.InvitationRepository
+	userRepo        repo.UserRepo
+	identityService *IdentityService
+	orgService      *OrganizationService
+}
+
+func NewInvitationService(repo repo.InvitationRepository, userRepo repo.UserRepo, identityService *IdentityService, orgService *OrganizationService) *InvitationService {
+	return &InvitationService{
+		repo:            repo,
+		userRepo:        userRepo,
+		identityService: identityService,
+		orgService:      orgService,
+	}
+}
+
+func (s *InvitationService) EnsureIndexes() error {
+	return s.repo.EnsureIndexes()
+}
+
+// CreateInvitation invites the email into the current organization. Earlier
+// pending invitations of the email are revoked.
+func (s *InvitationService) CreateInvitation(reqCtx *app.RequestContext, req *model.CreateInvitation) (*model.CreatedInvitation, error) {
+	if !req.Role.IsValid() || req.Role == model.RoleSuperAdmin {
+		return nil, ErrInvalidRole
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	user, err := s.userRepo.GetUserByOrgAndEmail(reqCtx, reqCtx.Org.ID, email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && user.IsActive {
+		return nil, ErrAlreadyMember
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RevokePendingInvitations(reqCtx, reqCtx.Org.ID, email); err != nil {
+		return nil, err
+	}
+	invitation, err := s.repo.CreateInvitation(reqCtx, email, req.Role, hashSecretToken(token), time.Now().Add(invitationTTL))
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreatedInvitation{Invitation: invitation, Token: token}, nil
+}
+
+func (s *InvitationService) ListInvitations(reqCtx *app.RequestContext) ([]*model.Invitation, error) {
+	return s.repo.ListInvitations(reqCtx, reqCtx.Org.ID)
+}
+
+func (s *InvitationService) RevokeInvitation(reqCtx *app.RequestContext, id bson.ObjectID) error {
+	revoked, err := s.repo.RevokeInvitation(reqCtx, reqCtx.Org.ID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation adds the signed in email to the invited organization and
+// makes it the current organization. The identity is created when the email
+// signs up through the invitation; a deactivated membership is reactivated.
+func (s *InvitationService) AcceptInvitation(reqCtx *app.RequestContext, token string, email string, firstName string, lastName string) (*app.RequestContext, error) {
+	invitation, err := s.repo.GetInvitationByTokenHash(reqCtx, hashSecretToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.Status != model.InvitationPending {
+		return nil, ErrInvitationNotFound
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvitationExpired
+	}
+	if !strings.EqualFold(invitation.Email, email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	org, err := s.orgService.GetOrganizationByID(reqCtx, invitation.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.getOrCreateIdentity(reqCtx, email, firstName, lastName, org.ID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByOrgAndEmail(reqCtx, org.ID, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && user.IsActive {
+		return nil, ErrAlreadyMember
+	}
+
+	accepted, err := s.repo.MarkAccepted(reqCtx, invitation.ID, identity.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvitationNotFound
+	}
+
+	if user != nil {
+		actorCtx := (&app.RequestContext{}).WithUser(app.RequestUser{IdentityID: identity.ID, Email: identity.Email})
+		user, err = s.userRepo.UpdateUser(actorCtx, user.ID, &model.UpdateUser{
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Role:      invitation.Role,
+			IsActive:  true,
+		})
+	} else {
+		user, err = s.userRepo.CreateUser(reqCtx, invitation.CreatedBy, &model.CreateUser{
+			IdentityID:     identity.ID,
+			Email:          identity.Email,
+			FirstName:      identity.FirstName,
+			LastName:       identity.LastName,
+			Role:           invitation.Role,
+			OrganizationID: org.ID,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.identityService.SetCurrentOrg(reqCtx, identity.ID, org.ID); err != nil {
+		return nil, err
+	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheUser(identity.Email) })
+
+	return &app.RequestContext{
+		User: app.RequestUser{
+			ID:             user.ID,
+			Email:          user.Email,
+			FirstName:      user.FirstName,
+			LastName:       user.LastName,
+			Role:           user.Role,
+			OrganizationID: user.OrganizationID,
+			IdentityID:     user.IdentityID,
+			IsActive:       user.IsActive,
+		},
+		Org: app.RequestOrg{
+			ID:   org.ID,
+			Name: org.Name,
+			Slug: org.Slug,
+		},
+	}, nil
+}
+
+func (s *InvitationService) getOrCreateIdentity(reqCtx *app.RequestContext, email string, firstName string, lastName string, orgID bson.ObjectID) (*model.Identity, error) {
+	identity, err := s.identityService.GetIdentityByEmail(reqCtx, email)
+	if err == nil {
+		return identity, nil
+	}
+	if firstName == "" {
+		log.Printf("failed to get identity for invitation: %v", err)
+		return nil, fmt.Errorf("no account found for %s, sign up to accept the invitation", email)
+	}
+	return s.identityService.CreateIdentity(reqCtx, &model.CreateIdentity{
+		Email:        email,
+		FirstName:    firstName,
+		LastName:     lastName,
+		CurrentOrgID: orgID,
+	})
+}
+
+// newSecretToken returns a random URL-safe token for invitations and emailed
+// sign-in links. Only hashSecretToken of it is stored.
+func newSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("failed to generate token: %v", err)
+		return "", errors.New("failed to generate token")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
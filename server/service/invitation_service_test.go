@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+type invitationTestDeps struct {
+	svc          *service.InvitationService
+	inviteRepo   *mocks.MockInvitationRepository
+	userRepo     *mocks.MockUserRepo
+	identityRepo *mocks.MockIdentityRepo
+	orgRepo      *mocks.MockOrganizationRepo
+}
+
+func newInvitationTestDeps(ctrl *gomock.Controller) *invitationTestDeps {
+	d := &invitationTestDeps{
+		inviteRepo:   mocks.NewMockInvitationRepository(ctrl),
+		userRepo:     mocks.NewMockUserRepo(ctrl),
+		identityRepo: mocks.NewMockIdentityRepo(ctrl),
+		orgRepo:      mocks.NewMockOrganizationRepo(ctrl),
+	}
+	d.svc = service.NewInvitationService(d.inviteRepo, d.userRepo, service.NewIdentityService(d.identityRepo), service.NewOrganizationService(d.orgRepo))
+	return d
+}
+
+func invitationTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestAcceptInvitationJoinsOrganization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newInvitationTestDeps(ctrl)
+
+	org := &model.Organization{Base: model.Base{ID: bson.NewObjectID()}, Name: "Acme", Slug: "org_7", IsActive: true}
+	invitation := &model.Invitation{
+		Base:           model.Base{ID: bson.NewObjectID(), CreatedBy: bson.NewObjectID()},
+		OrganizationID: org.ID,
+		Email:          "jane@example.com",
+		Role:           model.RoleMember,
+		Status:         model.InvitationPending,
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+	identity := &model.Identity{Base: model.Base{ID: bson.NewObjectID()}, Email: "Jane@example.com", FirstName: "Jane", LastName: "Doe"}
+
+	d.inviteRepo.EXPECT().GetInvitationByTokenHash(gomock.Any(), invitationTokenHash("tok")).Return(invitation, nil)
+	d.orgRepo.EXPECT().GetOrganizationByID(gomock.Any(), org.ID).Return(org, nil)
+	d.identityRepo.EXPECT().GetIdentityByEmail(gomock.Any(), "Jane@example.com").Return(identity, nil)
+	d.userRepo.EXPECT().GetUserByOrgAndEmail(gomock.Any(), org.ID, identity.Email).Return(nil, nil)
+	d.inviteRepo.EXPECT().MarkAccepted(gomock.Any(), invitation.ID, identity.ID).Return(true, nil)
+	d.userRepo.EXPECT().CreateUser(gomock.Any(), invitation.CreatedBy, &model.CreateUser{
+		IdentityID:     identity.ID,
+		Email:          identity.Email,
+		FirstName:      "Jane",
+		LastName:       "Doe",
+		Role:           model.RoleMember,
+		OrganizationID: org.ID,
+	}).Return(&model.User{
+		Base:           model.Base{ID: bson.NewObjectID()},
+		IdentityID:     identity.ID,
+		Email:          identity.Email,
+		OrganizationID: org.ID,
+		Role:           model.RoleMember,
+		IsActive:       true,
+	}, nil)
+	d.identityRepo.EXPECT().SetCurrentOrg(gomock.Any(), identity.ID, org.ID).Return(nil)
+
+	reqCtx, err := d.svc.AcceptInvitation(&app.RequestContext{}, "tok", "Jane@example.com", "", "")
+	if err != nil {
+		t.Fatalf("AcceptInvitation returned error: %v", err)
+	}
+	if reqCtx.Org.ID != org.ID || reqCtx.User.Role != model.RoleMember {
+		t.Errorf("unexpected request context %+v", reqCtx)
+	}
+}
+
+func TestAcceptInvitationRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		invitation *model.Invitation
+		email      string
+		want       error
+	}{
+		{
+			name:  "unknown token",
+			email: "jane@example.com",
+			want:  service.ErrInvitationNotFound,
+		},
+		{
+			name:       "already accepted",
+			invitation: &model.Invitation{Email: "jane@example.com", Status: model.InvitationAccepted, ExpiresAt: time.Now().Add(time.Hour)},
+			email:      "jane@example.com",
+			want:       service.ErrInvitationNotFound,
+		},
+		{
+			name:       "expired",
+			invitation: &model.Invitation{Email: "jane@example.com", Status: model.InvitationPending, ExpiresAt: time.Now().Add(-time.Minute)},
+			email:      "jane@example.com",
+			want:       service.ErrInvitationExpired,
+		},
+		{
+			name:       "different email",
+			invitation: &model.Invitation{Email: "jane@example.com", Status: model.InvitationPending, ExpiresAt: time.Now().Add(time.Hour)},
+			email:      "mallory@example.com",
+			want:       service.ErrInvitationEmailMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			d := newInvitationTestDeps(ctrl)
+			d.inviteRepo.EXPECT().GetInvitationByTokenHash(gomock.Any(), gomock.Any()).Return(tt.invitation, nil)
+
+			_, err := d.svc.AcceptInvitation(&app.RequestContext{}, "tok", tt.email, "", "")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
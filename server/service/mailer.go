@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/gaeaglobal/exto/server/app"
+)
+
+// Mailer sends transactional emails such as verification and sign-in links.
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// NewMailer returns an SMTP mailer when SMTP_ADDR is configured and a mailer
+// that only logs the messages otherwise, which is enough for local development.
+func NewMailer(cfg *app.Config) Mailer {
+	if cfg.SMTP_ADDR == "" {
+		return &LogMailer{}
+	}
+	return &SMTPMailer{
+		addr:     cfg.SMTP_ADDR,
+		username: cfg.SMTP_USERNAME,
+		password: cfg.SMTP_PASSWORD,
+		from:     cfg.SMTP_FROM,
+	}
+}
+
+type SMTPMailer struct {
+	addr     string
+	username string
+	password string
+	from     string
+}
+
+func (m *SMTPMailer) Send(to string, subject string, body string) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		host, _, err := net.SplitHostPort(m.addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP_ADDR: %w", err)
+		}
+		auth = smtp.PlainAuth("", m.username, m.password, host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	if err := smtp.SendMail(m.addr, auth, m.from, []string{to}, []byte(msg)); err != nil {
+		log.Printf("failed to send email to %s: %v", to, err)
+		return errors.New("failed to send email")
+	}
+	return nil
+}
+
+type LogMailer struct{}
+
+func (m *LogMailer) Send(to string, subject string, body string) error {
+	log.Printf("email to %s: %s\n%s", to, subject, body)
+	return nil
+}
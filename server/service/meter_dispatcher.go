@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+const (
+	meterDispatchInterval    = 15 * time.Second
+	meterDispatchBatchSize   = 100
+	meterDispatchLease       = 2 * time.Minute
+	meterDispatchMaxAttempts = 10
+	meterRetryBaseDelay      = 30 * time.Second
+	meterRetryMaxDelay       = time.Hour
+)
+
+// MeterDispatcher delivers the pending events of the meter outbox to Stripe.
+// Each event carries its outbox identifier so a redelivery after a crash or
+// timeout is dropped by Stripe. Failed deliveries are retried with
+// exponential backoff until meterDispatchMaxAttempts is reached.
+type MeterDispatcher struct {
+	sc   *stripe.Client
+	repo repo.MeterEventRepository
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewMeterDispatcher(sc *stripe.Client, repo repo.MeterEventRepository) *MeterDispatcher {
+	return &MeterDispatcher{
+		sc:   sc,
+		repo: repo,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Start runs the dispatcher in the background until Stop is called.
+func (d *MeterDispatcher) Start() {
+	if !d.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(meterDispatchInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := d.DispatchDue(&app.RequestContext{}); err != nil {
+				log.Printf("meter dispatch failed: %v", err)
+			}
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running batch to finish and stops the dispatcher.
+func (d *MeterDispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+		if d.started.Load() {
+			<-d.done
+		}
+	})
+}
+
+// DispatchDue delivers up to one batch of due events and returns how many were
+// delivered.
+func (d *MeterDispatcher) DispatchDue(reqCtx *app.RequestContext) (int, error) {
+	sent := 0
+	for range meterDispatchBatchSize {
+		meterEvent, err := d.repo.ClaimNextDue(reqCtx, time.Now(), meterDispatchLease)
+		if err != nil {
+			return sent, err
+		}
+		if meterEvent == nil {
+			return sent, nil
+		}
+		if d.deliver(reqCtx, meterEvent) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (d *MeterDispatcher) deliver(reqCtx *app.RequestContext, meterEvent *model.MeterEvent) bool {
+	params := &stripe.BillingMeterEventCreateParams{
+		EventName:  stripe.String(meterEvent.EventName),
+		Identifier: stripe.String(meterEvent.Identifier),
+		Timestamp:  stripe.Int64(meterEvent.Timestamp.Unix()),
+		Payload: map[string]string{
+			"value":              fmt.Sprintf("%d", meterEvent.EventValue),
+			"stripe_customer_id": meterEvent.StripeCustomerID,
+		},
+	}
+	_, err := d.sc.V1BillingMeterEvents.Create(context.TODO(), params)
+	if err == nil {
+		if err := d.repo.MarkSent(reqCtx, meterEvent.ID); err != nil {
+			log.Printf("failed to mark meter event %s as sent: %v", meterEvent.Identifier, err)
+		}
+		return true
+	}
+
+	log.Printf("failed to deliver meter event %s (attempt %d): %v", meterEvent.Identifier, meterEvent.Attempts, err)
+	if !isRetryableStripeError(err) || meterEvent.Attempts >= meterDispatchMaxAttempts {
+		if err := d.repo.MarkFailed(reqCtx, meterEvent.ID, err.Error()); err != nil {
+			log.Printf("failed to mark meter event %s as failed: %v", meterEvent.Identifier, err)
+		}
+		return false
+	}
+	nextAttemptAt := time.Now().Add(meterRetryDelay(meterEvent.Attempts))
+	if err := d.repo.MarkRetry(reqCtx, meterEvent.ID, nextAttemptAt, err.Error()); err != nil {
+		log.Printf("failed to reschedule meter event %s: %v", meterEvent.Identifier, err)
+	}
+	return false
+}
+
+// meterRetryDelay doubles the delay with every attempt, up to meterRetryMaxDelay.
+func meterRetryDelay(attempts int) time.Duration {
+	delay := meterRetryBaseDelay
+	for i := 1; i < attempts && delay < meterRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, meterRetryMaxDelay)
+}
+
+// isRetryableStripeError reports whether the request may succeed when it is
+// sent again. Events Stripe rejected as invalid are not retried; auth and
+// rate limit errors are, since they clear up once the account is fixed.
+func isRetryableStripeError(err error) bool {
+	var stripeErr *stripe.Error
+	if !errors.As(err, &stripeErr) {
+		return true
+	}
+	return stripeErr.HTTPStatusCode != http.StatusBadRequest &&
+		stripeErr.HTTPStatusCode != http.StatusNotFound
+}
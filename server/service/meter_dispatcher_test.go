@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+// newMeterStripeServer answers every meter event request with the given status
+// and records the identifiers it received.
+func newMeterStripeServer(t *testing.T, status int, identifiers *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse meter event request: %v", err)
+		}
+		*identifiers = append(*identifiers, r.PostForm.Get("identifier"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(`{"object":"billing.meter_event","event_name":"scan","identifier":"` + r.PostForm.Get("identifier") + `"}`))
+			return
+		}
+		w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"rejected"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func pendingMeterEvent(attempts int) *model.MeterEvent {
+	id := bson.NewObjectID()
+	return &model.MeterEvent{
+		Base:             model.Base{ID: id},
+		EventName:        "scan",
+		EventValue:       1,
+		StripeCustomerID: "cus_123",
+		Identifier:       id.Hex(),
+		Timestamp:        time.Now(),
+		Status:           model.MeterEventPending,
+		Attempts:         attempts,
+	}
+}
+
+func TestMeterDispatcherDelivery(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		expect   func(r *mocks.MockMeterEventRepository, evt *model.MeterEvent)
+		sent     int
+	}{
+		{
+			name:     "delivered events are marked sent",
+			status:   http.StatusOK,
+			attempts: 1,
+			expect: func(r *mocks.MockMeterEventRepository, evt *model.MeterEvent) {
+				r.EXPECT().MarkSent(gomock.Any(), evt.ID).Return(nil)
+			},
+			sent: 1,
+		},
+		{
+			name:     "transient errors are retried later",
+			status:   http.StatusUnauthorized,
+			attempts: 3,
+			expect: func(r *mocks.MockMeterEventRepository, evt *model.MeterEvent) {
+				r.EXPECT().MarkRetry(gomock.Any(), evt.ID, gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ *app.RequestContext, _ bson.ObjectID, next time.Time, _ string) error {
+						// Third attempt backs off 4x the base delay.
+						if delay := time.Until(next); delay < time.Minute || delay > 2*time.Minute {
+							t.Errorf("unexpected retry delay %v", delay)
+						}
+						return nil
+					})
+			},
+		},
+		{
+			name:     "retries stop after the last attempt",
+			status:   http.StatusUnauthorized,
+			attempts: 10,
+			expect: func(r *mocks.MockMeterEventRepository, evt *model.MeterEvent) {
+				r.EXPECT().MarkFailed(gomock.Any(), evt.ID, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:     "rejected events are not retried",
+			status:   http.StatusBadRequest,
+			attempts: 1,
+			expect: func(r *mocks.MockMeterEventRepository, evt *model.MeterEvent) {
+				r.EXPECT().MarkFailed(gomock.Any(), evt.ID, gomock.Any()).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			meterRepo := mocks.NewMockMeterEventRepository(ctrl)
+
+			var identifiers []string
+			srv := newMeterStripeServer(t, tt.status, &identifiers)
+			dispatcher := service.NewMeterDispatcher(service.NewStripeClient("sk_test_123", srv.URL), meterRepo)
+
+			evt := pendingMeterEvent(tt.attempts)
+			gomock.InOrder(
+				meterRepo.EXPECT().ClaimNextDue(gomock.Any(), gomock.Any(), gomock.Any()).Return(evt, nil),
+				meterRepo.EXPECT().ClaimNextDue(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil),
+			)
+			tt.expect(meterRepo, evt)
+
+			sent, err := dispatcher.DispatchDue(&app.RequestContext{})
+			if err != nil {
+				t.Fatalf("DispatchDue returned error: %v", err)
+			}
+			if sent != tt.sent {
+				t.Errorf("expected %d sent, got %d", tt.sent, sent)
+			}
+			if len(identifiers) != 1 || identifiers[0] != evt.Identifier {
+				t.Errorf("expected one request with identifier %s, got %v", evt.Identifier, identifiers)
+			}
+		})
+	}
+}
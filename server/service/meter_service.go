@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/model"
@@ -25,7 +28,13 @@ func NewMeterService(sc *stripe.Client, repo repo.MeterEventRepository, orgServi
 	}
 }
 
-func (s *MeterService) IncrementMeterEvent(reqCtx *app.RequestContext, name string, value int) (*model.MeterEvent, error) {
+func (s *MeterService) EnsureIndexes() error {
+	return s.repo.EnsureIndexes()
+}
+
+// RecordMeterEvent adds the usage to the meter outbox. Nothing is recorded for
+// organizations without a Stripe customer since there is nobody to bill.
+func (s *MeterService) RecordMeterEvent(reqCtx *app.RequestContext, name string, value int) (*model.MeterEvent, error) {
 	org, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
 	if err != nil {
 		return nil, err
@@ -33,19 +42,113 @@ func (s *MeterService) IncrementMeterEvent(reqCtx *app.RequestContext, name stri
 	if org.StripeCustomerId == "" {
 		return nil, nil
 	}
-	params := &stripe.BillingMeterEventCreateParams{
-		EventName: stripe.String(name),
-		Payload: map[string]string{
-			"value":              fmt.Sprintf("%d", value),
-			"stripe_customer_id": org.StripeCustomerId,
-		},
+	return s.repo.CreateMeterEvent(reqCtx, name, value, org.StripeCustomerId)
+}
+
+// ReconcileUsage compares the organization's recorded usage in [start, end)
+// with the meter event summaries Stripe reports for its customer. Stripe only
+// aggregates whole minutes, so both bounds are truncated to the minute.
+func (s *MeterService) ReconcileUsage(reqCtx *app.RequestContext, orgID bson.ObjectID, start time.Time, end time.Time) ([]*model.MeterReconciliation, error) {
+	start = start.UTC().Truncate(time.Minute)
+	end = end.UTC().Truncate(time.Minute)
+	if !start.Before(end) {
+		return nil, errors.New("start must be before end")
 	}
-	result, err := s.sc.V1BillingMeterEvents.Create(context.TODO(), params)
+
+	org, err := s.orgService.GetOrganizationByID(reqCtx, orgID)
 	if err != nil {
 		return nil, err
 	}
-	if result.Identifier == "" {
-		return nil, errors.New("failed to create meter event in stripe")
+
+	totals, err := s.repo.SumMeterEvents(reqCtx, orgID, start, end)
+	if err != nil {
+		return nil, err
 	}
-	return s.repo.CreateMeterEvent(reqCtx, name, value, org.StripeCustomerId)
+
+	reports := make(map[string]*model.MeterReconciliation)
+	var names []string
+	for _, total := range totals {
+		report, ok := reports[total.EventName]
+		if !ok {
+			report = &model.MeterReconciliation{
+				OrganizationID:   orgID,
+				StripeCustomerID: org.StripeCustomerId,
+				EventName:        total.EventName,
+				StartTime:        start,
+				EndTime:          end,
+			}
+			reports[total.EventName] = report
+			names = append(names, total.EventName)
+		}
+		switch total.Status {
+		case model.MeterEventSent:
+			report.Sent += total.Value
+		case model.MeterEventPending:
+			report.Pending += total.Value
+		case model.MeterEventFailed:
+			report.Failed += total.Value
+		}
+	}
+
+	result := make([]*model.MeterReconciliation, 0, len(names))
+	if len(names) == 0 || org.StripeCustomerId == "" {
+		for _, name := range names {
+			result = append(result, finishReconciliation(reports[name]))
+		}
+		return result, nil
+	}
+
+	meterIDs, err := s.meterIDsByEventName()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		report := reports[name]
+		if meterID, ok := meterIDs[name]; ok {
+			report.StripeTotal, err = s.stripeUsage(meterID, org.StripeCustomerId, start, end)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			log.Printf("no stripe meter found for event %s", name)
+		}
+		result = append(result, finishReconciliation(report))
+	}
+	return result, nil
+}
+
+func finishReconciliation(report *model.MeterReconciliation) *model.MeterReconciliation {
+	report.Difference = report.StripeTotal - float64(report.Sent)
+	report.InSync = report.Difference == 0
+	return report
+}
+
+func (s *MeterService) meterIDsByEventName() (map[string]string, error) {
+	meterIDs := make(map[string]string)
+	for meter, err := range s.sc.V1BillingMeters.List(context.TODO(), &stripe.BillingMeterListParams{}) {
+		if err != nil {
+			log.Printf("failed to list stripe meters: %v", err)
+			return nil, errors.New("failed to list stripe meters")
+		}
+		meterIDs[meter.EventName] = meter.ID
+	}
+	return meterIDs, nil
+}
+
+func (s *MeterService) stripeUsage(meterID string, customerID string, start time.Time, end time.Time) (float64, error) {
+	params := &stripe.BillingMeterEventSummaryListParams{
+		ID:        stripe.String(meterID),
+		Customer:  stripe.String(customerID),
+		StartTime: stripe.Int64(start.Unix()),
+		EndTime:   stripe.Int64(end.Unix()),
+	}
+	var total float64
+	for summary, err := range s.sc.V1BillingMeterEventSummaries.List(context.TODO(), params) {
+		if err != nil {
+			log.Printf("failed to list stripe meter event summaries: %v", err)
+			return 0, errors.New("failed to get stripe meter usage")
+		}
+		total += summary.AggregatedValue
+	}
+	return total, nil
 }
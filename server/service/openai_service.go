@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"strings"
 
@@ -22,21 +24,48 @@ type KeyValue struct {
 	Key             string      `json:"key"`
 	Value           interface{} `json:"value"` // Accepts string, array, or object
 	ConfidenceScore int         `json:"confidenceScore"`
+	// BoundingBox is where the value is on the document image, as fractions
+	// of its width and height: [x_min, y_min, x_max, y_max].
+	BoundingBox []float64 `json:"boundingBox,omitempty"`
 }
 
 type KeyValuesResponse struct {
 	KeyValues []KeyValue `json:"keyValues"`
 }
 
+// Bump extractionPromptVersion whenever the system prompt below changes in a way
+// that affects the extracted values, so that cached responses are not reused.
+const (
+	extractionPromptVersion = "v2"
+	extractionModel         = "gpt-4o"
+)
+
+// LLMProvider is the chat completion backend used for document extraction.
+type LLMProvider interface {
+	CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
 type OpenAIService struct {
-	formatService *FormatService
+	formatService   *FormatService
+	extractionCache *ExtractionCacheService
+	llm             LLMProvider
 }
 
-func NewOpenAIService(formatService *FormatService) *OpenAIService {
-	return &OpenAIService{formatService: formatService}
+func NewOpenAIService(formatService *FormatService, extractionCache *ExtractionCacheService) *OpenAIService {
+	return NewOpenAIServiceWithLLM(formatService, extractionCache, openai.NewClient(os.Getenv("OPENAI_API_KEY")))
 }
 
-func (s *OpenAIService) ExtractDocumentData(reqCtx *app.RequestContext, categoryID bson.ObjectID, base64Image string) (any, error) {
+// NewOpenAIServiceWithLLM returns the service extracting with the given
+// chat completion backend instead of OpenAI.
+func NewOpenAIServiceWithLLM(formatService *FormatService, extractionCache *ExtractionCacheService, llm LLMProvider) *OpenAIService {
+	return &OpenAIService{
+		formatService:   formatService,
+		extractionCache: extractionCache,
+		llm:             llm,
+	}
+}
+
+func (s *OpenAIService) ExtractDocumentData(reqCtx *app.RequestContext, categoryID bson.ObjectID, base64Image string, forceRefresh bool) (any, error) {
 
 	formats, err := s.formatService.GetFormatsByCategoryID(reqCtx, categoryID)
 	if err != nil {
@@ -44,8 +73,10 @@ func (s *OpenAIService) ExtractDocumentData(reqCtx *app.RequestContext, category
 	}
 
 	var extractedTemplateFields []ExtractedTemplateField
+	formatIDs := make([]string, 0, len(formats))
 
 	for _, format := range formats {
+		formatIDs = append(formatIDs, format.ID.Hex())
 		for _, field := range format.ExtractionFields {
 			extracted := ExtractedTemplateField{
 				CategoryFieldName: field.CategoryFieldName,
@@ -62,8 +93,19 @@ func (s *OpenAIService) ExtractDocumentData(reqCtx *app.RequestContext, category
 		return nil, fmt.Errorf("failed to marshal template fields: %v", err)
 	}
 
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	client := openai.NewClient(apiKey)
+	cacheKey := ExtractionCacheKey{
+		DocumentHash:  HashContent(base64Image),
+		FormatID:      strings.Join(formatIDs, ","),
+		PromptVersion: extractionPromptVersion + "-" + HashContent(string(templateFieldsJSON))[:12],
+		Model:         extractionModel,
+	}
+	useCache := s.extractionCache != nil && s.extractionCache.IsEnabled(reqCtx)
+	if useCache && !forceRefresh {
+		if cached, found := s.extractionCache.Get(reqCtx, cacheKey); found {
+			log.Printf("Extraction cache hit for category %s", categoryID.Hex())
+			return cached, nil
+		}
+	}
 
 	systemPrompt := `
 You are an intelligent document data extraction system.
@@ -87,12 +129,15 @@ Use the document image and the template fields to extract key-value pairs. Outpu
     {
     "key": "<category_field_name from template>",
     "value": "<extracted value from image>",
-    "confidenceScore": <percentage from 1 to 100>
+    "confidenceScore": <percentage from 1 to 100>,
+    "boundingBox": [<x_min>, <y_min>, <x_max>, <y_max>]
     },
     ...
 ]
 }
 
+The boundingBox encloses the text of the value on the image, as fractions from 0 to 1 of the width and height of the image, measured from its top left corner. Omit it when the value is not written on the document.
+
 ### Confidence Score Calculation Guidelines:
 Evaluate confidenceScore dynamically based on:
 - **Text Clarity**: Is the value legible and artifact-free?
@@ -123,8 +168,8 @@ Begin extraction based on the provided image and template fields.
 		},
 	}
 
-	resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
-		Model: "gpt-4o",
+	resp, err := s.llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model: extractionModel,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
@@ -138,8 +183,15 @@ Begin extraction based on the provided image and template fields.
 	if err != nil {
 		return nil, err
 	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("empty response from OpenAI")
+	}
 
-	return resp.Choices[0].Message.Content, nil
+	content := resp.Choices[0].Message.Content
+	if useCache {
+		s.extractionCache.Put(reqCtx, cacheKey, content)
+	}
+	return content, nil
 }
 
 func (s *OpenAIService) ConvertKeyValueToMap(jsonStr string) (map[string]any, error) {
@@ -186,6 +238,98 @@ func (s *OpenAIService) ConvertConfidenceScoresToMap(jsonStr string) (map[string
 	return result, nil
 }
 
+// ConvertBoundingBoxesToMap returns the bounding boxes of the values that have
+// a valid one, by key.
+func (s *OpenAIService) ConvertBoundingBoxesToMap(jsonStr string) (map[string][]float64, error) {
+	cleaned := strings.TrimPrefix(jsonStr, "json\n")
+	cleaned = strings.TrimSpace(cleaned)
+	cleaned = strings.TrimPrefix(cleaned, "```json")
+	cleaned = strings.TrimPrefix(cleaned, "```")
+	cleaned = strings.TrimSuffix(cleaned, "```")
+	cleaned = strings.TrimSpace(cleaned)
+
+	var resp KeyValuesResponse
+	if err := json.Unmarshal([]byte(cleaned), &resp); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]float64)
+	for _, kv := range resp.KeyValues {
+		if validBoundingBox(kv.BoundingBox) {
+			result[kv.Key] = kv.BoundingBox
+		}
+	}
+	return result, nil
+}
+
+func validBoundingBox(box []float64) bool {
+	if len(box) != 4 {
+		return false
+	}
+	for _, v := range box {
+		if v < 0 || v > 1 {
+			return false
+		}
+	}
+	return box[0] < box[2] && box[1] < box[3]
+}
+
+// FindPersonalData asks the language model which of the fields hold personal
+// data, such as names, addresses, dates of birth or account numbers.
+func (s *OpenAIService) FindPersonalData(ctx context.Context, fields map[string]any) ([]string, error) {
+	fieldsJSON, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fields: %v", err)
+	}
+
+	systemPrompt := `
+You are a data protection assistant. You will be given the fields extracted from a document as a JSON object.
+
+List the keys of the fields whose value holds personal data of a person: names, postal addresses, dates of birth, email addresses, phone numbers, national identifiers, passport or licence numbers, bank account or card numbers, medical information.
+
+Output a JSON with the following structure:
+
+{"keys": ["<key>", ...]}
+
+Return only JSON in the output. Do not include explanations or additional text.
+`
+
+	resp, err := s.llm.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: extractionModel,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: systemPrompt,
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: string(fieldsJSON),
+			},
+		},
+		MaxTokens: 500,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("empty response from OpenAI")
+	}
+
+	cleaned := strings.TrimSpace(resp.Choices[0].Message.Content)
+	cleaned = strings.TrimPrefix(cleaned, "```json")
+	cleaned = strings.TrimPrefix(cleaned, "```")
+	cleaned = strings.TrimSuffix(cleaned, "```")
+	cleaned = strings.TrimSpace(cleaned)
+
+	var result struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
+		return nil, err
+	}
+	return result.Keys, nil
+}
+
 func (s *OpenAIService) CalculateAverageConfidence(confidenceMap map[string]int) float64 {
 	if len(confidenceMap) == 0 {
 		return 0
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// SlugIssue is a problem with the slugs of existing organizations found by
+// CheckOrganizationSlugs.
+type SlugIssue struct {
+	Slug     string   `json:"slug"`
+	Problem  string   `json:"problem"`
+	OrgIDs   []string `json:"org_ids,omitempty"`
+	Database string   `json:"database,omitempty"`
+}
+
+func (i SlugIssue) String() string {
+	s := fmt.Sprintf("%s: %s", i.Slug, i.Problem)
+	if len(i.OrgIDs) > 0 {
+		s += " (organizations " + strings.Join(i.OrgIDs, ", ") + ")"
+	}
+	if i.Database != "" {
+		s += " (database " + i.Database + ")"
+	}
+	return s
+}
+
+// CheckOrganizationSlugs finds organizations that share a database: equal
+// slugs, and slugs that differ only in case, which MongoDB does not allow as
+// separate databases. It also reports slugs that cannot name a database and
+// organization databases without an organization.
+func CheckOrganizationSlugs(orgs []*model.Organization, databases []string) []SlugIssue {
+	issues := []SlugIssue{}
+
+	byDatabase := map[string][]*model.Organization{}
+	for _, org := range orgs {
+		if !model.IsValidSlug(org.Slug) {
+			issues = append(issues, SlugIssue{Slug: org.Slug, Problem: "invalid slug", OrgIDs: []string{org.ID.Hex()}})
+		}
+		key := strings.ToLower(org.Slug)
+		byDatabase[key] = append(byDatabase[key], org)
+	}
+
+	keys := make([]string, 0, len(byDatabase))
+	for key := range byDatabase {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		shared := byDatabase[key]
+		if len(shared) < 2 {
+			continue
+		}
+		ids := make([]string, 0, len(shared))
+		for _, org := range shared {
+			ids = append(ids, org.ID.Hex())
+		}
+		issues = append(issues, SlugIssue{
+			Slug:     shared[0].Slug,
+			Problem:  "organizations share a database",
+			OrgIDs:   ids,
+			Database: model.OrgDatabasePrefix + shared[0].Slug,
+		})
+	}
+
+	for _, name := range databases {
+		slug, found := strings.CutPrefix(name, model.OrgDatabasePrefix)
+		if !found {
+			continue
+		}
+		if _, owned := byDatabase[strings.ToLower(slug)]; !owned {
+			issues = append(issues, SlugIssue{Slug: slug, Problem: "database has no organization", Database: name})
+		}
+	}
+	return issues
+}
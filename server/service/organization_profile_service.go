@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	maxOrganizationNameLength = 100
+	maxRetentionDays          = 100 * 365
+)
+
+var (
+	ErrInvalidOrganizationProfile = errors.New("invalid organization profile")
+	ErrDefaultCategoryNotFound    = errors.New("default category not found")
+	ErrRetentionCategoryNotFound  = errors.New("retention policy category not found")
+
+	localePattern         = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	scanCodePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_]{0,15}$`)
+)
+
+// OrganizationProfileService reads and changes the profile and settings of
+// the current organization.
+type OrganizationProfileService struct {
+	orgService      *OrganizationService
+	categoryService *CategoryService
+}
+
+func NewOrganizationProfileService(orgService *OrganizationService, categoryService *CategoryService) *OrganizationProfileService {
+	return &OrganizationProfileService{
+		orgService:      orgService,
+		categoryService: categoryService,
+	}
+}
+
+func (s *OrganizationProfileService) GetProfile(reqCtx *app.RequestContext) (*model.Organization, error) {
+	org, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, errors.New("organization not found")
+	}
+	return org, nil
+}
+
+// UpdateProfile validates and applies the changes to the profile of the
+// current organization. Empty strings clear the optional settings.
+func (s *OrganizationProfileService) UpdateProfile(reqCtx *app.RequestContext, req *model.UpdateOrganizationProfile) (*model.Organization, error) {
+	org, err := s.GetProfile(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	name := org.Name
+	settings := org.Settings
+
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > maxOrganizationNameLength {
+			return nil, ErrInvalidOrganizationProfile
+		}
+	}
+	if req.LogoURL != nil {
+		settings.LogoURL = strings.TrimSpace(*req.LogoURL)
+		if settings.LogoURL != "" && !isHTTPURL(settings.LogoURL) {
+			return nil, ErrInvalidOrganizationProfile
+		}
+	}
+	if req.DefaultCategoryID != nil {
+		settings.DefaultCategoryID = bson.NilObjectID
+		if *req.DefaultCategoryID != "" {
+			categoryID, err := bson.ObjectIDFromHex(*req.DefaultCategoryID)
+			if err != nil {
+				return nil, ErrInvalidOrganizationProfile
+			}
+			category, err := s.categoryService.GetCategoryByID(reqCtx, categoryID)
+			if err != nil {
+				return nil, err
+			}
+			if category == nil {
+				return nil, ErrDefaultCategoryNotFound
+			}
+			settings.DefaultCategoryID = categoryID
+		}
+	}
+	if req.Timezone != nil {
+		settings.Timezone = strings.TrimSpace(*req.Timezone)
+		if settings.Timezone != "" {
+			if _, err := time.LoadLocation(settings.Timezone); err != nil {
+				return nil, ErrInvalidOrganizationProfile
+			}
+		}
+	}
+	if req.Locale != nil {
+		settings.Locale = strings.TrimSpace(*req.Locale)
+		if settings.Locale != "" && !localePattern.MatchString(settings.Locale) {
+			return nil, ErrInvalidOrganizationProfile
+		}
+	}
+	if req.Retention != nil {
+		if !isValidRetentionPolicy(*req.Retention) {
+			return nil, ErrInvalidOrganizationProfile
+		}
+		settings.Retention = *req.Retention
+	}
+	if req.CategoryRetention != nil {
+		seen := map[bson.ObjectID]bool{}
+		for _, policy := range *req.CategoryRetention {
+			if policy.CategoryID.IsZero() || seen[policy.CategoryID] || !isValidRetentionPolicy(policy.RetentionPolicy) {
+				return nil, ErrInvalidOrganizationProfile
+			}
+			seen[policy.CategoryID] = true
+			category, err := s.categoryService.GetCategoryByID(reqCtx, policy.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			if category == nil {
+				return nil, ErrRetentionCategoryNotFound
+			}
+		}
+		settings.CategoryRetention = *req.CategoryRetention
+	}
+	if req.ScanCodePrefix != nil {
+		settings.ScanCodePrefix = strings.ToUpper(strings.TrimSpace(*req.ScanCodePrefix))
+		if settings.ScanCodePrefix != "" && !scanCodePrefixPattern.MatchString(settings.ScanCodePrefix) {
+			return nil, ErrInvalidOrganizationProfile
+		}
+	}
+
+	return s.orgService.UpdateOrganizationProfile(reqCtx, name, &settings)
+}
+
+func isValidRetentionPolicy(policy model.RetentionPolicy) bool {
+	return policy.DocumentDays >= 0 && policy.DocumentDays <= maxRetentionDays &&
+		policy.DataDays >= 0 && policy.DataDays <= maxRetentionDays
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
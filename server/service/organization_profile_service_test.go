@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestUpdateOrganizationProfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	categoryRepo := mocks.NewMockCategoryRepository(ctrl)
+	svc := service.NewOrganizationProfileService(service.NewOrganizationService(orgRepo), service.NewCategoryService(categoryRepo))
+
+	orgID := bson.NewObjectID()
+	reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: orgID}}
+	categoryID := bson.NewObjectID()
+	org := &model.Organization{
+		Base:     model.Base{ID: orgID},
+		Name:     "jane@acme.com's Organization",
+		Billing:  model.Billing{Email: "billing@acme.com"},
+		Settings: model.OrganizationSettings{Locale: "en-US"},
+	}
+
+	orgRepo.EXPECT().GetOrganizationByID(reqCtx, orgID).Return(org, nil)
+	categoryRepo.EXPECT().GetCategoryByID(reqCtx, categoryID).Return(&model.Category{Base: model.Base{ID: categoryID}}, nil)
+	orgRepo.EXPECT().UpdateOrganizationProfile(reqCtx, orgID, "Acme", gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, _ bson.ObjectID, name string, settings *model.OrganizationSettings) (*model.Organization, error) {
+			if settings.Locale != "en-US" {
+				t.Errorf("expected unchanged locale to be kept, got %q", settings.Locale)
+			}
+			if settings.DefaultCategoryID != categoryID || settings.Timezone != "Europe/Berlin" || settings.ScanCodePrefix != "INV" {
+				t.Errorf("unexpected settings %+v", settings)
+			}
+			updated := *org
+			updated.Name = name
+			updated.Settings = *settings
+			return &updated, nil
+		})
+
+	updated, err := svc.UpdateProfile(reqCtx, &model.UpdateOrganizationProfile{
+		Name:              strPtr("  Acme "),
+		DefaultCategoryID: strPtr(categoryID.Hex()),
+		Timezone:          strPtr("Europe/Berlin"),
+		ScanCodePrefix:    strPtr("inv"),
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile returned error: %v", err)
+	}
+	if updated.Billing.Email != "billing@acme.com" {
+		t.Errorf("billing email changed to %q", updated.Billing.Email)
+	}
+	if code := updated.Settings.ScanCode(7); code != "INV-7" {
+		t.Errorf("expected scan code INV-7, got %s", code)
+	}
+}
+
+func TestUpdateOrganizationProfileRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		req  model.UpdateOrganizationProfile
+	}{
+		{"empty name", model.UpdateOrganizationProfile{Name: strPtr("  ")}},
+		{"logo not a URL", model.UpdateOrganizationProfile{LogoURL: strPtr("javascript:alert(1)")}},
+		{"unknown timezone", model.UpdateOrganizationProfile{Timezone: strPtr("Mars/Olympus")}},
+		{"malformed locale", model.UpdateOrganizationProfile{Locale: strPtr("english")}},
+		{"negative retention", model.UpdateOrganizationProfile{Retention: &model.RetentionPolicy{DocumentDays: -1}}},
+		{"scan code prefix with separator", model.UpdateOrganizationProfile{ScanCodePrefix: strPtr("SC-AN")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+			svc := service.NewOrganizationProfileService(service.NewOrganizationService(orgRepo), service.NewCategoryService(mocks.NewMockCategoryRepository(ctrl)))
+			orgID := bson.NewObjectID()
+			reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: orgID}}
+
+			orgRepo.EXPECT().GetOrganizationByID(reqCtx, orgID).Return(&model.Organization{Base: model.Base{ID: orgID}, Name: "Acme"}, nil)
+
+			if _, err := svc.UpdateProfile(reqCtx, &tt.req); !errors.Is(err, service.ErrInvalidOrganizationProfile) {
+				t.Errorf("expected ErrInvalidOrganizationProfile, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateOrganizationBillingKeepsEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	svc := service.NewOrganizationService(orgRepo)
+	orgID := bson.NewObjectID()
+	reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: orgID}}
+
+	orgRepo.EXPECT().GetOrganizationByID(reqCtx, orgID).Return(&model.Organization{Base: model.Base{ID: orgID}, Billing: model.Billing{Email: "billing@acme.com"}}, nil)
+	orgRepo.EXPECT().UpdateOrganization(reqCtx, orgID, gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, _ bson.ObjectID, update *model.UpdateOrganization) (*model.Organization, error) {
+			if update.Billing.Email != "billing@acme.com" || update.Billing.City != "Berlin" {
+				t.Errorf("unexpected billing update %+v", update.Billing)
+			}
+			return &model.Organization{Base: model.Base{ID: orgID}, Billing: update.Billing}, nil
+		})
+
+	if _, err := svc.UpdateOrganizationBilling(reqCtx, &model.Billing{Email: "someone@else.com", City: "Berlin"}); err != nil {
+		t.Fatalf("UpdateOrganizationBilling returned error: %v", err)
+	}
+}
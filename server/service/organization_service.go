@@ -1,14 +1,23 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"log"
+	"strings"
 
 	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
 	"github.com/gaeaglobal/exto/server/model"
 	"github.com/gaeaglobal/exto/server/repo"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// maxSlugAttempts bounds the inserts CreateOrganization makes before giving
+// up on finding a free slug.
+const maxSlugAttempts = 5
+
 type OrganizationService struct {
 	repo repo.OrganizationRepo
 }
@@ -19,13 +28,66 @@ func NewOrganizationService(repo repo.OrganizationRepo) *OrganizationService {
 	}
 }
 
+func (s *OrganizationService) EnsureIndexes() error {
+	return s.repo.EnsureIndexes()
+}
+
+// CreateOrganization creates the organization with a unique slug. The slug
+// is derived from the requested slug, or the name when there is none; when
+// another organization has it, a random suffix is appended. The unique index
+// on the slug makes this safe under concurrent sign-ups: a slug taken between
+// the check and the insert gives repo.ErrSlugTaken. Outside a transaction the
+// insert is retried with another slug; inside one, the failed insert has
+// aborted the transaction, so the error is returned for the caller to restart
+// it.
 func (s *OrganizationService) CreateOrganization(reqCtx *app.RequestContext, org *model.CreateOrganization, createdByIdentityId bson.ObjectID) (*model.Organization, error) {
 	if exists, err := s.repo.IsOrganizationExists(reqCtx, org.Name); err != nil {
 		return nil, err
 	} else if exists {
 		return nil, errors.New("organization already exists")
 	}
-	return s.repo.CreateOrganization(reqCtx, org, createdByIdentityId)
+
+	base := org.Slug
+	if base == "" {
+		base = org.Name
+	}
+	base = model.SlugFromName(base)
+
+	create := *org
+	for attempt := 1; ; attempt++ {
+		slug, err := s.freeSlug(reqCtx, base)
+		if err != nil {
+			return nil, err
+		}
+		create.Slug = slug
+		created, err := s.repo.CreateOrganization(reqCtx, &create, createdByIdentityId)
+		if !errors.Is(err, repo.ErrSlugTaken) || reqCtx.InTransaction() {
+			return created, err
+		}
+		if attempt == maxSlugAttempts {
+			log.Printf("failed to allocate a slug for organization %q after %d attempts", org.Name, attempt)
+			return nil, errors.New("failed to allocate organization slug")
+		}
+	}
+}
+
+// freeSlug returns the base slug, or the base with a random suffix when
+// another organization has it.
+func (s *OrganizationService) freeSlug(reqCtx *app.RequestContext, base string) (string, error) {
+	slug := base
+	for attempt := 1; attempt <= maxSlugAttempts; attempt++ {
+		taken, err := s.repo.IsSlugTaken(reqCtx, slug)
+		if err != nil || !taken {
+			return slug, err
+		}
+		suffix, err := newSlugSuffix()
+		if err != nil {
+			return "", err
+		}
+		slug = base + "-" + suffix
+	}
+	log.Printf("failed to find a free slug for %q after %d attempts", base, maxSlugAttempts)
+	return "", errors.New("failed to allocate organization slug")
 }
 
 func (s *OrganizationService) GetOrganizationByID(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.Organization, error) {
@@ -46,12 +108,98 @@ func (s *OrganizationService) GenerateNextScanCode(reqCtx *app.RequestContext) (
 	return s.repo.GenerateNextScanCode(reqCtx)
 }
 
+// UpdateOrganizationBilling stores the billing details of the current
+// organization. Once set, the billing email is kept: it links the
+// organization to its Stripe customer.
 func (s *OrganizationService) UpdateOrganizationBilling(reqCtx *app.RequestContext, billing *model.Billing) (*model.Organization, error) {
+	org, err := s.repo.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return nil, err
+	}
+	updated := *billing
+	if org != nil && org.Billing.Email != "" {
+		updated.Email = org.Billing.Email
+	}
 	return s.repo.UpdateOrganization(reqCtx, reqCtx.Org.ID, &model.UpdateOrganization{
-		Billing: *billing,
+		Billing: updated,
 	})
 }
 
-func (s *OrganizationService) DeleteOrganizationByOwner(reqCtx *app.RequestContext) ([]*model.Organization, error) {
-	return s.repo.DeleteOrganizationByOwner(reqCtx)
+func (s *OrganizationService) SetExtractionCacheDisabled(reqCtx *app.RequestContext, disabled bool) (*model.Organization, error) {
+	return s.repo.SetExtractionCacheDisabled(reqCtx, reqCtx.Org.ID, disabled)
+}
+
+// SetMicrosoftTenantIDs restricts Microsoft sign-in to the given tenants; an
+// empty list accepts every tenant. Cached users of the organization are
+// dropped so the change applies to the next request.
+func (s *OrganizationService) SetMicrosoftTenantIDs(reqCtx *app.RequestContext, tenantIDs []string) (*model.Organization, error) {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, id := range tenantIDs {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	org, err := s.repo.SetMicrosoftTenantIDs(reqCtx, reqCtx.Org.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheOrg(reqCtx.Org.ID) })
+	return org, nil
+}
+
+// GetOrganizationByStripeCustomerID returns the organization billed to the Stripe customer, or nil when there is none.
+func (s *OrganizationService) GetOrganizationByStripeCustomerID(reqCtx *app.RequestContext, stripeCustomerID string) (*model.Organization, error) {
+	orgs, err := s.repo.GetOrganizations(reqCtx, bson.M{"stripe_customer_id": stripeCustomerID})
+	if err != nil {
+		return nil, err
+	}
+	if len(orgs) == 0 {
+		return nil, nil
+	}
+	return orgs[0], nil
+}
+
+func (s *OrganizationService) GetOrganizationsByIDs(reqCtx *app.RequestContext, orgIDs []bson.ObjectID) ([]*model.Organization, error) {
+	return s.repo.GetOrganizations(reqCtx, bson.M{"_id": bson.M{"$in": orgIDs}})
+}
+
+// GetOrganizationBySSOClient returns the organization that configured SSO
+// with the issuer and client ID, or nil when there is none.
+func (s *OrganizationService) GetOrganizationBySSOClient(reqCtx *app.RequestContext, issuer string, clientID string) (*model.Organization, error) {
+	orgs, err := s.repo.GetOrganizations(reqCtx, bson.M{"sso.issuer": issuer, "sso.client_id": clientID})
+	if err != nil {
+		return nil, err
+	}
+	if len(orgs) == 0 {
+		return nil, nil
+	}
+	return orgs[0], nil
+}
+
+// SetSSOConfig stores the SSO configuration of the current organization and
+// drops its cached users so the change applies to the next request.
+func (s *OrganizationService) SetSSOConfig(reqCtx *app.RequestContext, sso *model.SSOConfig) (*model.Organization, error) {
+	org, err := s.repo.SetSSOConfig(reqCtx, reqCtx.Org.ID, sso)
+	if err != nil {
+		return nil, err
+	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheOrg(reqCtx.Org.ID) })
+	return org, nil
+}
+
+func (s *OrganizationService) UpdateOrganizationProfile(reqCtx *app.RequestContext, name string, settings *model.OrganizationSettings) (*model.Organization, error) {
+	return s.repo.UpdateOrganizationProfile(reqCtx, reqCtx.Org.ID, name, settings)
+}
+
+func newSlugSuffix() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("failed to generate slug suffix: %v", err)
+		return "", errors.New("failed to allocate organization slug")
+	}
+	return hex.EncodeToString(b), nil
 }
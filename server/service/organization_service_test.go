@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+func TestCreateOrganizationRetriesTakenSlug(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	svc := service.NewOrganizationService(orgRepo)
+	reqCtx := &app.RequestContext{}
+	ownerID := bson.NewObjectID()
+
+	var slugs []string
+	orgRepo.EXPECT().IsOrganizationExists(reqCtx, "Acme Corp.").Return(false, nil)
+	// The slug is free when checked but taken by another organization before
+	// the insert.
+	gomock.InOrder(
+		orgRepo.EXPECT().IsSlugTaken(reqCtx, "acme-corp").Return(false, nil),
+		orgRepo.EXPECT().IsSlugTaken(reqCtx, "acme-corp").Return(true, nil),
+		orgRepo.EXPECT().IsSlugTaken(reqCtx, gomock.Any()).Return(false, nil),
+	)
+	orgRepo.EXPECT().CreateOrganization(reqCtx, gomock.Any(), ownerID).Times(2).DoAndReturn(
+		func(_ *app.RequestContext, org *model.CreateOrganization, _ bson.ObjectID) (*model.Organization, error) {
+			slugs = append(slugs, org.Slug)
+			if len(slugs) == 1 {
+				return nil, repo.ErrSlugTaken
+			}
+			return &model.Organization{Name: org.Name, Slug: org.Slug}, nil
+		})
+
+	org, err := svc.CreateOrganization(reqCtx, &model.CreateOrganization{Name: "Acme Corp."}, ownerID)
+	if err != nil {
+		t.Fatalf("CreateOrganization returned error: %v", err)
+	}
+	if slugs[0] != "acme-corp" {
+		t.Errorf("expected slug derived from the name, got %q", slugs[0])
+	}
+	if !strings.HasPrefix(org.Slug, "acme-corp-") || org.Slug == slugs[0] {
+		t.Errorf("expected a suffixed slug after the collision, got %q", org.Slug)
+	}
+}
+
+func TestSlugFromName(t *testing.T) {
+	tests := map[string]string{
+		"jane@acme.com's Organization": "jane-acme-com-s-organization",
+		"  Ünïcode & Co ":              "n-code-co",
+		"!!!":                          "org",
+		strings.Repeat("a", 60):        strings.Repeat("a", model.MaxSlugLength),
+	}
+	for name, want := range tests {
+		if got := model.SlugFromName(name); got != want || !model.IsValidSlug(got) {
+			t.Errorf("SlugFromName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCheckOrganizationSlugs(t *testing.T) {
+	orgs := []*model.Organization{
+		{Base: model.Base{ID: bson.NewObjectID()}, Slug: "org_1"},
+		{Base: model.Base{ID: bson.NewObjectID()}, Slug: "org_2"},
+		{Base: model.Base{ID: bson.NewObjectID()}, Slug: "org_2"},
+		{Base: model.Base{ID: bson.NewObjectID()}, Slug: "Org_1"},
+		{Base: model.Base{ID: bson.NewObjectID()}, Slug: "acme"},
+	}
+	issues := service.CheckOrganizationSlugs(orgs, []string{"sc_org_1", "sc_org_2", "sc_acme", "sc_org_9"})
+
+	problems := map[string]int{}
+	for _, issue := range issues {
+		problems[issue.Problem]++
+	}
+	if problems["organizations share a database"] != 2 {
+		t.Errorf("expected org_1/Org_1 and org_2 to be reported as shared, got %v", issues)
+	}
+	if problems["invalid slug"] != 1 {
+		t.Errorf("expected the upper case slug to be invalid, got %v", issues)
+	}
+	if problems["database has no organization"] != 1 {
+		t.Errorf("expected sc_org_9 to be reported, got %v", issues)
+	}
+}
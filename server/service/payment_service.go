@@ -8,25 +8,39 @@ import (
 	"time"
 
 	"github.com/stripe/stripe-go/v82"
+	"go.mongodb.org/mongo-driver/v2/bson"
 
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
 )
 
 type PaymentService struct {
 	sc                  *stripe.Client
 	orgService          *OrganizationService
 	subscriptionService *SubscriptionService
+	planService         *PlanService
+	invoiceRepo         repo.InvoiceRepository
 }
 
-func NewPaymentService(sc *stripe.Client, orgService *OrganizationService, subscriptionService *SubscriptionService) *PaymentService {
+func NewPaymentService(sc *stripe.Client, orgService *OrganizationService, subscriptionService *SubscriptionService, planService *PlanService, invoiceRepo repo.InvoiceRepository) *PaymentService {
 	return &PaymentService{
 		sc:                  sc,
 		orgService:          orgService,
 		subscriptionService: subscriptionService,
+		planService:         planService,
+		invoiceRepo:         invoiceRepo,
 	}
 }
 
+// CancelMode selects when a cancelled subscription stops.
+type CancelMode string
+
+const (
+	CancelAtPeriodEnd CancelMode = "period_end"
+	CancelImmediately CancelMode = "immediate"
+)
+
 type PaymentIntentResponse struct {
 	Customer             *stripe.Customer      `json:"customer"`
 	CustomerEphemeralKey *stripe.EphemeralKey  `json:"customer_ephemeral_key"`
@@ -76,8 +90,8 @@ func (s *PaymentService) CreateSetupIntent(reqCtx *app.RequestContext, billing *
 	}, nil
 }
 
-func (s *PaymentService) CreateSubscription(reqCtx *app.RequestContext) (*model.Subscription, error) {
-	// Price ID for the subscription plan - price_1S86biJgAi1y3OSXfjFBwHO3
+// CreateSubscription subscribes the organization to a plan of the catalog.
+func (s *PaymentService) CreateSubscription(reqCtx *app.RequestContext, planID bson.ObjectID) (*model.Subscription, error) {
 	org, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
 	if err != nil {
 		return nil, err
@@ -86,28 +100,35 @@ func (s *PaymentService) CreateSubscription(reqCtx *app.RequestContext) (*model.
 		return nil, errors.New("stripe customer ID is missing for the organization")
 	}
 
-	billingPlanId := "price_1S86biJgAi1y3OSXfjFBwHO3"
+	plan, err := s.planService.GetSubscribablePlan(reqCtx, planID)
+	if err != nil {
+		return nil, err
+	}
 
 	subscriptionParams := &stripe.SubscriptionCreateParams{
 		Customer:          stripe.String(org.StripeCustomerId),
 		OffSession:        stripe.Bool(true),
-		TrialPeriodDays:   stripe.Int64(7),
 		ProrationBehavior: stripe.String("none"),
 		PaymentBehavior:   stripe.String("error_if_incomplete"),
 		Items: []*stripe.SubscriptionCreateItemParams{
 			{
-				Price: stripe.String(billingPlanId),
-			},
-		},
-		Discounts: []*stripe.SubscriptionCreateDiscountParams{
-			{
-				Coupon: stripe.String("UD3PLsTI"),
+				Price: stripe.String(plan.StripePriceID),
 			},
 		},
 		Expand: []*string{
 			stripe.String("latest_invoice.payment_intent"),
 		},
 	}
+	if plan.TrialDays > 0 {
+		subscriptionParams.TrialPeriodDays = stripe.Int64(int64(plan.TrialDays))
+	}
+	if plan.StripeCouponID != "" {
+		subscriptionParams.Discounts = []*stripe.SubscriptionCreateDiscountParams{
+			{
+				Coupon: stripe.String(plan.StripeCouponID),
+			},
+		}
+	}
 	sub, err := s.sc.V1Subscriptions.Create(context.Background(), subscriptionParams)
 	if err != nil {
 		log.Printf("Error creating subscription: %v", err)
@@ -118,8 +139,9 @@ func (s *PaymentService) CreateSubscription(reqCtx *app.RequestContext) (*model.
 		OrganizationID:  org.ID,
 		StripeSubID:     sub.ID,
 		StartDate:       time.Now(),
-		TrialPeriodDays: 7,
-		BillingCycle:    model.BillingCycleMonthly,
+		TrialPeriodDays: plan.TrialDays,
+		BillingCycle:    plan.BillingCycle,
+		BillingPlanID:   plan.ID,
 	})
 
 	if err != nil {
@@ -127,29 +149,173 @@ func (s *PaymentService) CreateSubscription(reqCtx *app.RequestContext) (*model.
 		return nil, errors.New("failed to save subscription")
 	}
 
-	log.Printf("Successfully created subscription with ID: %s for Org: %s\n", sub.ID, org.Name)
+	log.Printf("Successfully created subscription with ID: %s for Org: %s on plan %s\n", sub.ID, org.Name, plan.Code)
 	log.Printf("App Subscription record created with ID: %s\n", appSub.ID.Hex())
 	return appSub, nil
 }
 
-func (s *PaymentService) CancelSubscription(reqCtx *app.RequestContext) error {
-	//
-	return nil
+// ChangePlan moves the current subscription to another plan. prorationBehavior
+// is passed to Stripe and defaults to "create_prorations".
+func (s *PaymentService) ChangePlan(reqCtx *app.RequestContext, planID bson.ObjectID, prorationBehavior string) (*model.Subscription, error) {
+	sub, err := s.subscriptionService.GetMySubscription(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	if sub.BillingPlanID == planID {
+		return nil, errors.New("subscription is already on this plan")
+	}
+
+	plan, err := s.planService.GetSubscribablePlan(reqCtx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if prorationBehavior == "" {
+		prorationBehavior = "create_prorations"
+	}
+
+	stripeSub, err := s.sc.V1Subscriptions.Retrieve(context.Background(), sub.StripeSubID, nil)
+	if err != nil {
+		log.Printf("Error retrieving subscription %s: %v", sub.StripeSubID, err)
+		return nil, errors.New("failed to change plan")
+	}
+	if stripeSub.Items == nil || len(stripeSub.Items.Data) == 0 {
+		return nil, errors.New("subscription has no items to change")
+	}
+
+	params := &stripe.SubscriptionUpdateParams{
+		ProrationBehavior: stripe.String(prorationBehavior),
+		Items: []*stripe.SubscriptionUpdateItemParams{
+			{
+				ID:    stripe.String(stripeSub.Items.Data[0].ID),
+				Price: stripe.String(plan.StripePriceID),
+			},
+		},
+	}
+	if plan.StripeCouponID != "" {
+		params.Discounts = []*stripe.SubscriptionUpdateDiscountParams{
+			{
+				Coupon: stripe.String(plan.StripeCouponID),
+			},
+		}
+	}
+	updated, err := s.sc.V1Subscriptions.Update(context.Background(), sub.StripeSubID, params)
+	if err != nil {
+		log.Printf("Error changing plan of subscription %s: %v", sub.StripeSubID, err)
+		return nil, errors.New("failed to change plan")
+	}
+
+	sync := newSyncSubscription(sub.OrganizationID, updated)
+	sync.BillingPlanID = plan.ID
+	return s.subscriptionService.SyncStripeSubscription(reqCtx, sync)
 }
 
+// CancelSubscription cancels the current subscription in Stripe, either at the
+// end of the paid period or right away, and mirrors the result locally.
+func (s *PaymentService) CancelSubscription(reqCtx *app.RequestContext, mode CancelMode) (*model.Subscription, error) {
+	sub, err := s.subscriptionService.GetMySubscription(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stripeSub *stripe.Subscription
+	switch mode {
+	case CancelImmediately:
+		stripeSub, err = s.sc.V1Subscriptions.Cancel(context.Background(), sub.StripeSubID, &stripe.SubscriptionCancelParams{
+			InvoiceNow: stripe.Bool(true),
+			Prorate:    stripe.Bool(true),
+		})
+	case CancelAtPeriodEnd:
+		stripeSub, err = s.sc.V1Subscriptions.Update(context.Background(), sub.StripeSubID, &stripe.SubscriptionUpdateParams{
+			CancelAtPeriodEnd: stripe.Bool(true),
+		})
+	default:
+		return nil, fmt.Errorf("unsupported cancel mode: %s", mode)
+	}
+	if err != nil {
+		log.Printf("Error cancelling subscription %s: %v", sub.StripeSubID, err)
+		return nil, errors.New("failed to cancel subscription")
+	}
+
+	return s.subscriptionService.SyncStripeSubscription(reqCtx, newSyncSubscription(sub.OrganizationID, stripeSub))
+}
+
+// GetSubscriptionStatus reads the current subscription status from Stripe and
+// refreshes the local record with it.
 func (s *PaymentService) GetSubscriptionStatus(reqCtx *app.RequestContext) (string, error) {
-	//
-	return "", nil
+	sub, err := s.subscriptionService.GetMySubscription(reqCtx)
+	if err != nil {
+		return "", err
+	}
+
+	stripeSub, err := s.sc.V1Subscriptions.Retrieve(context.Background(), sub.StripeSubID, nil)
+	if err != nil {
+		log.Printf("Error retrieving subscription %s: %v", sub.StripeSubID, err)
+		return "", errors.New("failed to get subscription status")
+	}
+
+	synced, err := s.subscriptionService.SyncStripeSubscription(reqCtx, newSyncSubscription(sub.OrganizationID, stripeSub))
+	if err != nil {
+		return "", err
+	}
+	return string(synced.Status), nil
 }
 
-func (s *PaymentService) ListInvoices(reqCtx *app.RequestContext) ([]*stripe.Invoice, error) {
-	//
-	return nil, nil
+// ListInvoices mirrors the organization's Stripe invoices into the invoices
+// collection and returns the mirrored records.
+func (s *PaymentService) ListInvoices(reqCtx *app.RequestContext) ([]*model.Invoice, error) {
+	org, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if org.StripeCustomerId == "" {
+		return []*model.Invoice{}, nil
+	}
+
+	params := &stripe.InvoiceListParams{
+		Customer: stripe.String(org.StripeCustomerId),
+	}
+	for invoice, err := range s.sc.V1Invoices.List(context.Background(), params) {
+		if err != nil {
+			log.Printf("Error listing invoices: %v", err)
+			return nil, errors.New("failed to list invoices")
+		}
+		sync := newSyncInvoice(org.ID, invoice)
+		if invoice.Parent != nil && invoice.Parent.SubscriptionDetails != nil && invoice.Parent.SubscriptionDetails.Subscription != nil {
+			sub, err := s.subscriptionService.GetSubscriptionByStripeID(reqCtx, invoice.Parent.SubscriptionDetails.Subscription.ID)
+			if err != nil {
+				return nil, err
+			}
+			if sub != nil {
+				sync.SubscriptionID = sub.ID
+			}
+		}
+		if _, err := s.invoiceRepo.UpsertInvoice(reqCtx, sync); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.invoiceRepo.ListInvoices(reqCtx, org.ID)
 }
 
+// GetInvoicePDF returns the PDF URL of a Stripe invoice that belongs to the organization.
 func (s *PaymentService) GetInvoicePDF(reqCtx *app.RequestContext, invoiceID string) (string, error) {
-	//
-	return "", nil
+	org, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return "", err
+	}
+
+	invoice, err := s.sc.V1Invoices.Retrieve(context.Background(), invoiceID, nil)
+	if err != nil {
+		log.Printf("Error retrieving invoice %s: %v", invoiceID, err)
+		return "", errors.New("invoice not found")
+	}
+	if org.StripeCustomerId == "" || invoice.Customer == nil || invoice.Customer.ID != org.StripeCustomerId {
+		return "", errors.New("invoice not found")
+	}
+	if invoice.InvoicePDF == "" {
+		return "", errors.New("invoice PDF is not available")
+	}
+	return invoice.InvoicePDF, nil
 }
 
 func (s *PaymentService) createCustomerIfNotExists(reqCtx *app.RequestContext, billing *model.Billing) (*stripe.Customer, error) {
@@ -287,14 +453,21 @@ func CreatePaymentIntent(appCtx *app.AppContext, amount int64, currency string)
 
 func (s *PaymentService) GetFreeTrialInfo(reqCtx *app.RequestContext) (*model.GetFreeTrialInfo, error) {
 	orgInfo, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
-	log.Print("s", orgInfo.CreatedAt, orgInfo.LastActiveAt)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.planService.GetPlanByCode(reqCtx, model.PlanCodeFreeTrial)
+	if err != nil {
+		return nil, err
+	}
 
 	trialInfo, err := s.subscriptionService.r.GetFreeTrialInfo(reqCtx)
 	if err != nil {
 		return nil, err
 	}
-	remainingScans := 250 - trialInfo.RecordCount
-	trialEndDate := orgInfo.CreatedAt.AddDate(0, 0, 30)
+	remainingScans := plan.MonthlyScanLimit - trialInfo.RecordCount
+	trialEndDate := orgInfo.CreatedAt.AddDate(0, 0, plan.TrialDays)
 
 	nowDate := time.Date(time.Now().UTC().Year(), time.Now().UTC().Month(), time.Now().UTC().Day(), 0, 0, 0, 0, time.UTC)
 	trialDate := time.Date(trialEndDate.UTC().Year(), trialEndDate.UTC().Month(), trialEndDate.UTC().Day(), 0, 0, 0, 0, time.UTC)
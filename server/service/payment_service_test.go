@@ -0,0 +1,173 @@
+package service_test
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+// stripeMockURL returns the address of a running stripe-mock
+// (https://github.com/stripe/stripe-mock) or skips the test.
+func stripeMockURL(t *testing.T) string {
+	t.Helper()
+	rawURL := os.Getenv("STRIPE_MOCK_URL")
+	if rawURL == "" {
+		t.Skip("STRIPE_MOCK_URL is not set")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("invalid STRIPE_MOCK_URL: %v", err)
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, time.Second)
+	if err != nil {
+		t.Skipf("stripe-mock is not reachable at %s", rawURL)
+	}
+	conn.Close()
+	return rawURL
+}
+
+type paymentTestDeps struct {
+	svc      *service.PaymentService
+	orgRepo  *mocks.MockOrganizationRepo
+	subRepo  *mocks.MockSubscriptionRepository
+	invRepo  *mocks.MockInvoiceRepository
+	planRepo *mocks.MockPlanRepository
+}
+
+func newPaymentTestDeps(t *testing.T, ctrl *gomock.Controller) *paymentTestDeps {
+	d := &paymentTestDeps{
+		orgRepo:  mocks.NewMockOrganizationRepo(ctrl),
+		subRepo:  mocks.NewMockSubscriptionRepository(ctrl),
+		invRepo:  mocks.NewMockInvoiceRepository(ctrl),
+		planRepo: mocks.NewMockPlanRepository(ctrl),
+	}
+	sc := service.NewStripeClient("sk_test_123", stripeMockURL(t))
+	d.svc = service.NewPaymentService(sc, service.NewOrganizationService(d.orgRepo), service.NewSubscriptionService(d.subRepo), service.NewPlanService(d.planRepo), d.invRepo)
+	return d
+}
+
+func TestCancelSubscriptionModes(t *testing.T) {
+	for _, mode := range []service.CancelMode{service.CancelAtPeriodEnd, service.CancelImmediately} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			d := newPaymentTestDeps(t, ctrl)
+			reqCtx := app.NewMockRequestContext()
+
+			d.subRepo.EXPECT().GetMySubscription(reqCtx).Return(&model.Subscription{
+				OrganizationID: reqCtx.Org.ID,
+				StripeSubID:    "sub_123",
+				IsCurrent:      true,
+			}, nil)
+			d.subRepo.EXPECT().SyncStripeSubscription(reqCtx, gomock.Any()).
+				DoAndReturn(func(_ *app.RequestContext, sync *model.SyncSubscription) (*model.Subscription, error) {
+					if sync.OrganizationID != reqCtx.Org.ID || sync.StripeSubID == "" {
+						t.Errorf("unexpected sync %+v", sync)
+					}
+					return &model.Subscription{Status: sync.Status}, nil
+				})
+
+			if _, err := d.svc.CancelSubscription(reqCtx, mode); err != nil {
+				t.Fatalf("CancelSubscription() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestChangePlanMovesSubscriptionToPlan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newPaymentTestDeps(t, ctrl)
+	reqCtx := app.NewMockRequestContext()
+	plan := &model.Plan{
+		Base:          model.Base{ID: bson.NewObjectID()},
+		Code:          model.PlanCodeYearly,
+		StripePriceID: "price_yearly",
+		IsActive:      true,
+	}
+
+	d.subRepo.EXPECT().GetMySubscription(reqCtx).Return(&model.Subscription{
+		OrganizationID: reqCtx.Org.ID,
+		StripeSubID:    "sub_123",
+		BillingPlanID:  bson.NewObjectID(),
+		IsCurrent:      true,
+	}, nil)
+	d.planRepo.EXPECT().GetPlanByID(reqCtx, plan.ID).Return(plan, nil)
+	d.subRepo.EXPECT().SyncStripeSubscription(reqCtx, gomock.Any()).
+		DoAndReturn(func(_ *app.RequestContext, sync *model.SyncSubscription) (*model.Subscription, error) {
+			if sync.BillingPlanID != plan.ID {
+				t.Errorf("BillingPlanID = %s, want %s", sync.BillingPlanID.Hex(), plan.ID.Hex())
+			}
+			return &model.Subscription{BillingPlanID: sync.BillingPlanID}, nil
+		})
+
+	if _, err := d.svc.ChangePlan(reqCtx, plan.ID, "always_invoice"); err != nil {
+		t.Fatalf("ChangePlan() error = %v", err)
+	}
+}
+
+func TestListInvoicesMirrorsStripeInvoices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newPaymentTestDeps(t, ctrl)
+	reqCtx := app.NewMockRequestContext()
+
+	d.orgRepo.EXPECT().GetOrganizationByID(reqCtx, reqCtx.Org.ID).Return(&model.Organization{
+		Base:             model.Base{ID: reqCtx.Org.ID},
+		StripeCustomerId: "cus_123",
+	}, nil)
+	d.subRepo.EXPECT().GetSubscriptionByStripeID(reqCtx, gomock.Any()).Return(nil, nil).AnyTimes()
+	d.invRepo.EXPECT().UpsertInvoice(reqCtx, gomock.Any()).
+		DoAndReturn(func(_ *app.RequestContext, sync *model.SyncInvoice) (*model.Invoice, error) {
+			if sync.StripeInvoiceID == "" || sync.OrganizationID != reqCtx.Org.ID {
+				t.Errorf("unexpected invoice sync %+v", sync)
+			}
+			return &model.Invoice{StripeInvoiceID: sync.StripeInvoiceID}, nil
+		}).MinTimes(1)
+	d.invRepo.EXPECT().ListInvoices(reqCtx, reqCtx.Org.ID).Return([]*model.Invoice{{StripeInvoiceID: "in_123"}}, nil)
+
+	invoices, err := d.svc.ListInvoices(reqCtx)
+	if err != nil {
+		t.Fatalf("ListInvoices() error = %v", err)
+	}
+	if len(invoices) != 1 {
+		t.Fatalf("ListInvoices() returned %d invoices, want 1", len(invoices))
+	}
+}
+
+func TestGetInvoicePDFChecksOwnership(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newPaymentTestDeps(t, ctrl)
+	reqCtx := app.NewMockRequestContext()
+
+	// stripe-mock answers with a fixed fixture, so learn its customer first.
+	sc := service.NewStripeClient("sk_test_123", stripeMockURL(t))
+	invoice, err := sc.V1Invoices.Retrieve(context.Background(), "in_123", nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve invoice from stripe-mock: %v", err)
+	}
+
+	d.orgRepo.EXPECT().GetOrganizationByID(reqCtx, reqCtx.Org.ID).Return(&model.Organization{
+		Base:             model.Base{ID: reqCtx.Org.ID},
+		StripeCustomerId: invoice.Customer.ID,
+	}, nil)
+	if _, err := d.svc.GetInvoicePDF(reqCtx, "in_123"); err != nil && err.Error() != "invoice PDF is not available" {
+		t.Fatalf("GetInvoicePDF() error = %v", err)
+	}
+
+	d.orgRepo.EXPECT().GetOrganizationByID(reqCtx, reqCtx.Org.ID).Return(&model.Organization{
+		Base:             model.Base{ID: bson.NewObjectID()},
+		StripeCustomerId: "cus_someone_else",
+	}, nil)
+	if _, err := d.svc.GetInvoicePDF(reqCtx, "in_123"); err == nil || err.Error() != "invoice not found" {
+		t.Fatalf("GetInvoicePDF() error = %v, want invoice not found", err)
+	}
+}
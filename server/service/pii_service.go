@@ -0,0 +1,157 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/pii"
+	"github.com/gaeaglobal/exto/server/utils"
+)
+
+var (
+	// ErrRedactionUnsupported is returned when the document of a record is
+	// not an image that can be redacted.
+	ErrRedactionUnsupported = errors.New("the document cannot be redacted")
+	// ErrPIILocationUnknown is returned when a field holding personal data
+	// was not located on the document, which therefore cannot be redacted
+	// safely.
+	ErrPIILocationUnknown = errors.New("the personal data was not located on the document")
+)
+
+// PIIService finds the personal data in the extracted values and hides it
+// from the users that may not read it: their values are masked in the
+// responses and exports, and the documents are rendered with them blacked
+// out.
+type PIIService struct {
+	appCtx        *app.AppContext
+	openAIService *OpenAIService
+}
+
+func NewPIIService(appCtx *app.AppContext, openAIService *OpenAIService) *PIIService {
+	return &PIIService{
+		appCtx:        appCtx,
+		openAIService: openAIService,
+	}
+}
+
+// Detect tags the fields holding personal data, with their bounding boxes on
+// the document when they are known. When LLM detection is enabled, the
+// language model is also asked for the fields the patterns do not recognize;
+// if it fails, the fields found by the patterns are kept.
+func (s *PIIService) Detect(reqCtx *app.RequestContext, fields map[string]any, boxes map[string][]float64) map[string]model.PIIField {
+	tags := map[string]model.PIIField{}
+	for name, types := range pii.DetectFields(fields) {
+		tags[name] = model.PIIField{Types: types, Box: boxes[name]}
+	}
+
+	if s.appCtx.Config.PII_LLM_DETECTION && len(fields) > len(tags) {
+		keys, err := s.openAIService.FindPersonalData(reqCtx.Context(), fields)
+		if err != nil {
+			log.Printf("failed to detect personal data with the language model: %v", err)
+		}
+		for _, key := range keys {
+			if _, found := fields[key]; !found {
+				continue
+			}
+			if _, tagged := tags[key]; !tagged {
+				tags[key] = model.PIIField{Types: []model.PIIType{model.PIIOther}, Box: boxes[key]}
+			}
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// CanRead reports whether the user of the request may read personal data
+// unmasked.
+func (s *PIIService) CanRead(reqCtx *app.RequestContext) bool {
+	return reqCtx.User.Can(app.PermPIIRead)
+}
+
+// Mask returns the record with the values of its personal data masked, unless
+// the user of the request may read them.
+func (s *PIIService) Mask(reqCtx *app.RequestContext, data *model.CategoryData) *model.CategoryData {
+	if data == nil || len(data.PII) == 0 || s.CanRead(reqCtx) {
+		return data
+	}
+	masked := *data
+	masked.MetaData = pii.MaskFields(data.MetaData, data.PII)
+	if extracted, found := data.RawData[rawExtractedDataKey]; found {
+		masked.RawData = make(map[string]any, len(data.RawData))
+		for key, value := range data.RawData {
+			masked.RawData[key] = value
+		}
+		masked.RawData[rawExtractedDataKey] = maskExtractedData(extracted, data.PII)
+	} else {
+		// The raw data of the records entered by hand are the values
+		// themselves.
+		masked.RawData = pii.MaskFields(data.RawData, data.PII)
+	}
+	return &masked
+}
+
+// rawExtractedDataKey is the key of the extracted values in the raw data of a
+// record.
+const rawExtractedDataKey = "extractedData"
+
+// maskExtractedData masks the extracted values of the raw data, which are a
+// document once read back from the database.
+func maskExtractedData(extracted any, tags map[string]model.PIIField) any {
+	switch v := extracted.(type) {
+	case map[string]any:
+		return pii.MaskFields(v, tags)
+	case bson.D:
+		masked := make(bson.D, len(v))
+		for i, e := range v {
+			if _, tagged := tags[e.Key]; tagged {
+				e.Value = pii.MaskValue(e.Value)
+			}
+			masked[i] = e
+		}
+		return masked
+	default:
+		return extracted
+	}
+}
+
+// RedactDocument renders the document of the record as a PNG image with its
+// personal data blacked out. It fails rather than render a document where a
+// field holding personal data was not located.
+func (s *PIIService) RedactDocument(reqCtx *app.RequestContext, data *model.CategoryData) ([]byte, error) {
+	if len(data.DocumentPaths) == 0 || data.DocumentPaths[0] == "" {
+		return nil, errors.New("document not found")
+	}
+	boxes := make([][]float64, 0, len(data.PII))
+	for _, tag := range data.PII {
+		if len(tag.Box) != 4 {
+			return nil, ErrPIILocationUnknown
+		}
+		boxes = append(boxes, tag.Box)
+	}
+
+	body, _, err := s.appCtx.Blobs.Get(reqCtx.Context(), data.DocumentPaths[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	defer body.Close()
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	redacted, err := utils.RedactImage(content, boxes)
+	if err != nil {
+		log.Printf("failed to redact document of category data %s: %v", data.ID.Hex(), err)
+		return nil, ErrRedactionUnsupported
+	}
+	return redacted, nil
+}
@@ -0,0 +1,76 @@
+package service_test
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+func TestCategoryDataMasksPIIForMembers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	categoryRepo := mocks.NewMockCategoryRepository(ctrl)
+	categoryDataRepo := mocks.NewMockCategoryDataRepository(ctrl)
+	categoryService := service.NewCategoryService(categoryRepo)
+	defer categoryService.Close()
+	piiService := service.NewPIIService(app.NewMockAppContext(), nil)
+	svc := service.NewCategoryDataService(categoryDataRepo, categoryService, nil, nil, piiService)
+
+	categoryID := bson.NewObjectID()
+	dataID := bson.NewObjectID()
+	categoryRepo.EXPECT().GetCategoryByID(gomock.Any(), categoryID).
+		Return(&model.Category{Base: model.Base{ID: categoryID}, Slug: "invoices"}, nil)
+	stored := &model.CategoryData{
+		Base:     model.Base{ID: dataID},
+		MetaData: map[string]any{"iban": "GB82 WEST 1234 5698 7654 32", "total": "1250.00"},
+		RawData: map[string]any{
+			"extractedData": bson.D{{Key: "iban", Value: "GB82 WEST 1234 5698 7654 32"}, {Key: "total", Value: "1250.00"}},
+		},
+		PII: map[string]model.PIIField{"iban": {Types: []model.PIIType{model.PIIIBAN}}},
+	}
+	categoryDataRepo.EXPECT().GetCategoryDataByID(gomock.Any(), "invoices", dataID).Return(stored, nil).AnyTimes()
+
+	member := &app.RequestContext{User: app.RequestUser{Role: model.RoleMember}}
+	data, err := svc.GetCategoryDataByID(member, categoryID, dataID)
+	if err != nil {
+		t.Fatalf("GetCategoryDataByID returned error: %v", err)
+	}
+	extracted := data.RawData["extractedData"].(bson.D)
+	if data.MetaData["iban"] != "****5432" || data.MetaData["total"] != "1250.00" || extracted[0].Value != "****5432" {
+		t.Errorf("expected the IBAN to be masked for a member, got %v %v", data.MetaData, extracted)
+	}
+
+	admin := &app.RequestContext{User: app.RequestUser{Role: model.RoleOrganizationAdmin}}
+	data, err = svc.GetCategoryDataByID(admin, categoryID, dataID)
+	if err != nil || data.MetaData["iban"] != "GB82 WEST 1234 5698 7654 32" {
+		t.Errorf("expected an admin to read the IBAN, got %v, %v", data, err)
+	}
+
+	// A member editing the total sends the IBAN back masked: it is kept, and
+	// the phone number added is tagged and encrypted.
+	categoryDataRepo.EXPECT().UpdateCategoryData(gomock.Any(), "invoices", dataID, gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, _ string, _ bson.ObjectID, req *model.UpdateCategoryDataRequest) (*model.CategoryData, error) {
+			if req.MetaData["iban"] != "GB82 WEST 1234 5698 7654 32" || req.MetaData["total"] != "1300.00" {
+				t.Errorf("unexpected update %v", req.MetaData)
+			}
+			if len(req.PII) != 2 || !slices.Contains(req.SensitiveFields, "iban") || !slices.Contains(req.SensitiveFields, "contact") {
+				t.Errorf("expected the IBAN and the phone number to be tagged and encrypted, got %v %v", req.PII, req.SensitiveFields)
+			}
+			return &model.CategoryData{Base: model.Base{ID: dataID}, MetaData: req.MetaData, PII: req.PII}, nil
+		})
+	updated, err := svc.UpdateCategoryData(member, categoryID, dataID, &map[string]any{
+		"iban": "****5432", "total": "1300.00", "contact": "+44 20 7946 0958",
+	})
+	if err != nil {
+		t.Fatalf("UpdateCategoryData returned error: %v", err)
+	}
+	if updated.MetaData["iban"] != "****5432" || updated.MetaData["contact"] != "****0958" {
+		t.Errorf("expected the updated record to be masked, got %v", updated.MetaData)
+	}
+}
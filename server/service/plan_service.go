@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+// defaultPlans are created in the plans catalog on startup when missing. The
+// monthly plan carries the price and coupon that were used before the catalog existed.
+var defaultPlans = []*model.Plan{
+	{Code: model.PlanCodeFreeTrial, Name: "Free Trial", MonthlyScanLimit: 250, TrialDays: 30, MaxUploadBytes: 10 << 20, MaxUploadPages: 5},
+	{Code: model.PlanCodeMonthly, Name: "Monthly", BillingCycle: model.BillingCycleMonthly, StripePriceID: "price_1S86biJgAi1y3OSXfjFBwHO3", StripeCouponID: "UD3PLsTI", MonthlyScanLimit: 1000, TrialDays: 7, MaxUploadBytes: 25 << 20, MaxUploadPages: 50},
+	{Code: model.PlanCodeYearly, Name: "Yearly", BillingCycle: model.BillingCycleYearly, MonthlyScanLimit: 1000, MaxUploadBytes: 25 << 20, MaxUploadPages: 50},
+}
+
+type PlanService struct {
+	repo repo.PlanRepository
+}
+
+func NewPlanService(repo repo.PlanRepository) *PlanService {
+	return &PlanService{
+		repo: repo,
+	}
+}
+
+func (s *PlanService) EnsureDefaultPlans(reqCtx *app.RequestContext) error {
+	for _, plan := range defaultPlans {
+		if err := s.repo.EnsurePlan(reqCtx, plan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PlanService) ListPlans(reqCtx *app.RequestContext, activeOnly bool) ([]*model.Plan, error) {
+	return s.repo.ListPlans(reqCtx, activeOnly)
+}
+
+func (s *PlanService) GetPlanByID(reqCtx *app.RequestContext, id bson.ObjectID) (*model.Plan, error) {
+	return s.repo.GetPlanByID(reqCtx, id)
+}
+
+func (s *PlanService) GetPlanByCode(reqCtx *app.RequestContext, code model.PlanCode) (*model.Plan, error) {
+	return s.repo.GetPlanByCode(reqCtx, code)
+}
+
+func (s *PlanService) CreatePlan(reqCtx *app.RequestContext, plan *model.CreatePlan) (*model.Plan, error) {
+	if exists, err := s.repo.IsPlanExists(reqCtx, plan.Code); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, errors.New("plan already exists")
+	}
+	return s.repo.CreatePlan(reqCtx, plan)
+}
+
+func (s *PlanService) UpdatePlan(reqCtx *app.RequestContext, id bson.ObjectID, plan *model.UpdatePlan) (*model.Plan, error) {
+	return s.repo.UpdatePlan(reqCtx, id, plan)
+}
+
+// GetSubscribablePlan returns an active plan that can be purchased through Stripe.
+func (s *PlanService) GetSubscribablePlan(reqCtx *app.RequestContext, id bson.ObjectID) (*model.Plan, error) {
+	plan, err := s.repo.GetPlanByID(reqCtx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.IsActive || plan.StripePriceID == "" {
+		return nil, errors.New("plan is not available for subscription")
+	}
+	return plan, nil
+}
+
+// PlanIDForPrice returns the ID of the plan billed with the Stripe price, or a
+// zero ID when the price is not in the catalog.
+func (s *PlanService) PlanIDForPrice(reqCtx *app.RequestContext, priceID string) (bson.ObjectID, error) {
+	if priceID == "" {
+		return bson.NilObjectID, nil
+	}
+	plan, err := s.repo.GetPlanByStripePriceID(reqCtx, priceID)
+	if err != nil || plan == nil {
+		return bson.NilObjectID, err
+	}
+	return plan.ID, nil
+}
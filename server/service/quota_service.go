@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+// QuotaExceededError is returned when an organization has no scans left for the current period.
+type QuotaExceededError struct {
+	Status *model.QuotaStatus
+}
+
+func (e *QuotaExceededError) Error() string {
+	if e.Status.TrialExpired {
+		return "free trial has ended"
+	}
+	return "monthly scan quota exceeded"
+}
+
+// Details describes the quota state in a form suitable for an error response.
+func (e *QuotaExceededError) Details() []string {
+	period := fmt.Sprintf("%04d-%02d", e.Status.Year, e.Status.Month)
+	if !e.Status.TrialStartedAt.IsZero() {
+		period = "trial since " + e.Status.TrialStartedAt.Format(time.DateOnly)
+	}
+	details := []string{
+		fmt.Sprintf("plan: %s", e.Status.PlanCode),
+		fmt.Sprintf("period: %s", period),
+		fmt.Sprintf("limit: %d", e.Status.Limit),
+		fmt.Sprintf("used: %d", e.Status.Used),
+	}
+	if !e.Status.TrialEndsAt.IsZero() {
+		details = append(details, fmt.Sprintf("trial_ends_at: %s", e.Status.TrialEndsAt.Format(time.RFC3339)))
+	}
+	return details
+}
+
+// ScanReservation is a scan counted against the quota before extraction starts.
+type ScanReservation struct {
+	event string
+	year  int
+	month int
+	// overageMeterEvent is the meter of the plan billing the scan, set when
+	// the scan is beyond the allowance of the plan.
+	overageMeterEvent string
+}
+
+// Overage reports whether the scan is beyond the allowance of the plan.
+func (r *ScanReservation) Overage() bool {
+	return r.overageMeterEvent != ""
+}
+
+// MeterEvent returns the meter the scan is reported to: the overage meter of
+// the plan for the scans beyond its allowance, the scan meter otherwise.
+func (r *ScanReservation) MeterEvent() string {
+	if r.Overage() {
+		return r.overageMeterEvent
+	}
+	return model.UsageEventScan
+}
+
+type QuotaService struct {
+	planService         *PlanService
+	usageRepo           repo.MonthlyUsageRepository
+	overrideRepo        repo.QuotaOverrideRepository
+	orgService          *OrganizationService
+	subscriptionService *SubscriptionService
+}
+
+func NewQuotaService(planService *PlanService, usageRepo repo.MonthlyUsageRepository, overrideRepo repo.QuotaOverrideRepository, orgService *OrganizationService, subscriptionService *SubscriptionService) *QuotaService {
+	return &QuotaService{
+		planService:         planService,
+		usageRepo:           usageRepo,
+		overrideRepo:        overrideRepo,
+		orgService:          orgService,
+		subscriptionService: subscriptionService,
+	}
+}
+
+// EnsureIndexes creates the index the atomic usage counter relies on.
+func (s *QuotaService) EnsureIndexes() error {
+	return s.usageRepo.EnsureIndexes()
+}
+
+func (s *QuotaService) GetQuotaStatus(reqCtx *app.RequestContext) (*model.QuotaStatus, error) {
+	status, _, err := s.resolveQuota(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	used, err := s.usageRepo.GetUsage(reqCtx, usageEvent(status), status.Year, status.Month)
+	if err != nil {
+		return nil, err
+	}
+	status.Used = used
+	status.Remaining = max(status.Limit-used, 0)
+	return status, nil
+}
+
+// ReserveScan atomically counts one scan against the organization quota. It
+// returns a *QuotaExceededError when no scans are left. When the plan allows
+// overage, the scans beyond its allowance are reserved as overage.
+func (s *QuotaService) ReserveScan(reqCtx *app.RequestContext) (*ScanReservation, error) {
+	status, plan, err := s.resolveQuota(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !status.TrialExpired {
+		limit := status.Limit
+		if status.OverageAllowed {
+			// Scans beyond the included allowance are billed through the overage meter.
+			limit = math.MaxInt32
+		}
+		usage, err := s.usageRepo.Reserve(reqCtx, usageEvent(status), status.Year, status.Month, limit)
+		if err != nil {
+			return nil, err
+		}
+		if usage != nil {
+			reservation := &ScanReservation{event: usageEvent(status), year: status.Year, month: status.Month}
+			if status.OverageAllowed && usage.Quantity > status.Limit {
+				reservation.overageMeterEvent = plan.OverageMeterEvent
+			}
+			return reservation, nil
+		}
+	}
+
+	used, err := s.usageRepo.GetUsage(reqCtx, usageEvent(status), status.Year, status.Month)
+	if err != nil {
+		return nil, err
+	}
+	status.Used = used
+	status.Remaining = max(status.Limit-used, 0)
+	return nil, &QuotaExceededError{Status: status}
+}
+
+// ReleaseScan returns a reservation for a scan that did not complete.
+func (s *QuotaService) ReleaseScan(reqCtx *app.RequestContext, reservation *ScanReservation) {
+	if reservation == nil {
+		return
+	}
+	if err := s.usageRepo.Release(reqCtx, reservation.event, reservation.year, reservation.month); err != nil {
+		log.Printf("failed to release scan reservation: %v", err)
+	}
+}
+
+func (s *QuotaService) GetOverride(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.QuotaOverride, error) {
+	return s.overrideRepo.GetActiveOverride(reqCtx, orgID)
+}
+
+func (s *QuotaService) SetOverride(reqCtx *app.RequestContext, orgID bson.ObjectID, override *model.CreateQuotaOverride) (*model.QuotaOverride, error) {
+	if !override.ExpiresAt.IsZero() && override.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("override expiry must be in the future")
+	}
+	if _, err := s.orgService.GetOrganizationByID(reqCtx, orgID); err != nil {
+		return nil, err
+	}
+	return s.overrideRepo.UpsertOverride(reqCtx, orgID, override)
+}
+
+func (s *QuotaService) ClearOverride(reqCtx *app.RequestContext, orgID bson.ObjectID) error {
+	return s.overrideRepo.DeleteOverride(reqCtx, orgID)
+}
+
+// currentPlan returns the plan of the subscription of the organization, or the
+// free trial when it has none.
+func (s *QuotaService) currentPlan(reqCtx *app.RequestContext) (*model.Plan, error) {
+	sub, err := s.subscriptionService.GetMySubscription(reqCtx)
+	if err != nil && !errors.Is(err, ErrNoCurrentSubscription) {
+		return nil, err
+	}
+	var plan *model.Plan
+	if sub != nil && (sub.Status == model.SubscriptionStatusActive || sub.Status == model.SubscriptionStatusTrialing) {
+		if !sub.BillingPlanID.IsZero() {
+			plan, err = s.planService.GetPlanByID(reqCtx, sub.BillingPlanID)
+		} else if sub.BillingCycle == model.BillingCycleYearly {
+			// Subscriptions created before the plan catalog only know their cycle.
+			plan, err = s.planService.GetPlanByCode(reqCtx, model.PlanCodeYearly)
+		} else {
+			plan, err = s.planService.GetPlanByCode(reqCtx, model.PlanCodeMonthly)
+		}
+	} else {
+		plan, err = s.planService.GetPlanByCode(reqCtx, model.PlanCodeFreeTrial)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, errors.New("plan not found")
+	}
+	return plan, nil
+}
+
+// UploadLimits returns the limits of the documents the organization uploads,
+// set by its plan.
+func (s *QuotaService) UploadLimits(reqCtx *app.RequestContext) (model.UploadLimits, error) {
+	plan, err := s.currentPlan(reqCtx)
+	if err != nil {
+		return model.UploadLimits{}, err
+	}
+	return plan.UploadLimits(), nil
+}
+
+// usageEvent returns the counter of the scans of the quota period: the whole
+// free trial has one counter, kept under the month it started.
+func usageEvent(status *model.QuotaStatus) string {
+	if !status.TrialStartedAt.IsZero() {
+		return model.UsageEventTrialScan
+	}
+	return model.UsageEventScan
+}
+
+// resolveQuota works out the plan, limit and period of the organization without reading usage.
+func (s *QuotaService) resolveQuota(reqCtx *app.RequestContext) (*model.QuotaStatus, *model.Plan, error) {
+	now := time.Now().UTC()
+	status := &model.QuotaStatus{
+		Year:  now.Year(),
+		Month: int(now.Month()),
+	}
+
+	plan, err := s.currentPlan(reqCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	status.PlanCode = plan.Code
+	status.Limit = plan.MonthlyScanLimit
+	status.OverageAllowed = plan.Code != model.PlanCodeFreeTrial && plan.OverageMeterEvent != ""
+
+	override, err := s.overrideRepo.GetActiveOverride(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if override != nil {
+		status.Limit = override.MonthlyScanLimit
+		status.HasOverride = true
+		return status, plan, nil
+	}
+
+	if status.PlanCode == model.PlanCodeFreeTrial && plan.TrialDays > 0 {
+		org, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The trial allowance covers the trial, not the calendar month, so a
+		// trial running over two months does not get a second allowance.
+		status.TrialStartedAt = org.CreatedAt.UTC()
+		status.TrialEndsAt = status.TrialStartedAt.AddDate(0, 0, plan.TrialDays)
+		status.TrialExpired = now.After(status.TrialEndsAt)
+		status.Year = status.TrialStartedAt.Year()
+		status.Month = int(status.TrialStartedAt.Month())
+	}
+	return status, plan, nil
+}
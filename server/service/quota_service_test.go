@@ -0,0 +1,234 @@
+package service_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+// memoryUsageRepo keeps the usage counters in memory and reserves under a
+// lock, like the conditional increment of the Mongo repository.
+type memoryUsageRepo struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newMemoryUsageRepo() *memoryUsageRepo {
+	return &memoryUsageRepo{counts: map[string]int{}}
+}
+
+func usageKey(eventName string, year int, month int) string {
+	return fmt.Sprintf("%s/%04d-%02d", eventName, year, month)
+}
+
+func (r *memoryUsageRepo) GetCollection(...string) *mongo.Collection { return nil }
+
+func (r *memoryUsageRepo) EnsureIndexes() error { return nil }
+
+func (r *memoryUsageRepo) GetUsage(_ *app.RequestContext, eventName string, year int, month int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[usageKey(eventName, year, month)], nil
+}
+
+func (r *memoryUsageRepo) Reserve(_ *app.RequestContext, eventName string, year int, month int, limit int) (*model.MonthlyUsageEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := usageKey(eventName, year, month)
+	if r.counts[key] >= limit {
+		return nil, nil
+	}
+	r.counts[key]++
+	return &model.MonthlyUsageEvent{Quantity: r.counts[key]}, nil
+}
+
+func (r *memoryUsageRepo) Release(_ *app.RequestContext, eventName string, year int, month int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key := usageKey(eventName, year, month); r.counts[key] > 0 {
+		r.counts[key]--
+	}
+	return nil
+}
+
+func (r *memoryUsageRepo) DeleteUsageByOrg(*app.RequestContext, bson.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.counts)
+	return nil
+}
+
+type quotaTestDeps struct {
+	svc          *service.QuotaService
+	usage        *memoryUsageRepo
+	planRepo     *mocks.MockPlanRepository
+	subRepo      *mocks.MockSubscriptionRepository
+	overrideRepo *mocks.MockQuotaOverrideRepository
+	orgRepo      *mocks.MockOrganizationRepo
+	reqCtx       *app.RequestContext
+}
+
+// newQuotaTestDeps sets up an organization created at orgCreatedAt, without
+// an override, on the plan: the free trial without a subscription, or an
+// active subscription to any other plan.
+func newQuotaTestDeps(t *testing.T, plan *model.Plan, orgCreatedAt time.Time) *quotaTestDeps {
+	ctrl := gomock.NewController(t)
+	d := &quotaTestDeps{
+		usage:        newMemoryUsageRepo(),
+		planRepo:     mocks.NewMockPlanRepository(ctrl),
+		subRepo:      mocks.NewMockSubscriptionRepository(ctrl),
+		overrideRepo: mocks.NewMockQuotaOverrideRepository(ctrl),
+		orgRepo:      mocks.NewMockOrganizationRepo(ctrl),
+		reqCtx:       &app.RequestContext{Org: app.RequestOrg{ID: bson.NewObjectID()}},
+	}
+	if plan.Code == model.PlanCodeFreeTrial {
+		d.subRepo.EXPECT().GetMySubscription(gomock.Any()).AnyTimes().Return(nil, service.ErrNoCurrentSubscription)
+		d.planRepo.EXPECT().GetPlanByCode(gomock.Any(), model.PlanCodeFreeTrial).AnyTimes().Return(plan, nil)
+	} else {
+		plan.ID = bson.NewObjectID()
+		d.subRepo.EXPECT().GetMySubscription(gomock.Any()).AnyTimes().Return(
+			&model.Subscription{Status: model.SubscriptionStatusActive, BillingPlanID: plan.ID}, nil)
+		d.planRepo.EXPECT().GetPlanByID(gomock.Any(), plan.ID).AnyTimes().Return(plan, nil)
+	}
+	d.orgRepo.EXPECT().GetOrganizationByID(gomock.Any(), d.reqCtx.Org.ID).AnyTimes().Return(
+		&model.Organization{Base: model.Base{ID: d.reqCtx.Org.ID, CreatedAt: orgCreatedAt}}, nil)
+	d.svc = service.NewQuotaService(service.NewPlanService(d.planRepo), d.usage, d.overrideRepo,
+		service.NewOrganizationService(d.orgRepo), service.NewSubscriptionService(d.subRepo))
+	return d
+}
+
+func freeTrialPlan(limit int, trialDays int) *model.Plan {
+	return &model.Plan{Code: model.PlanCodeFreeTrial, MonthlyScanLimit: limit, TrialDays: trialDays, IsActive: true}
+}
+
+func TestReserveScanConcurrentlyStopsAtLimit(t *testing.T) {
+	d := newQuotaTestDeps(t, freeTrialPlan(5, 30), time.Now())
+	d.overrideRepo.EXPECT().GetActiveOverride(gomock.Any(), d.reqCtx.Org.ID).AnyTimes().Return(nil, nil)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved, exceeded := 0, 0
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := d.svc.ReserveScan(d.reqCtx)
+			mu.Lock()
+			defer mu.Unlock()
+			var quotaErr *service.QuotaExceededError
+			switch {
+			case err == nil:
+				reserved++
+			case errors.As(err, &quotaErr):
+				exceeded++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if reserved != 5 || exceeded != 15 {
+		t.Errorf("expected 5 reserved and 15 rejected scans, got %d and %d", reserved, exceeded)
+	}
+}
+
+func TestReleaseScanGivesBackTheReservation(t *testing.T) {
+	d := newQuotaTestDeps(t, freeTrialPlan(1, 30), time.Now())
+	d.overrideRepo.EXPECT().GetActiveOverride(gomock.Any(), d.reqCtx.Org.ID).AnyTimes().Return(nil, nil)
+
+	reservation, err := d.svc.ReserveScan(d.reqCtx)
+	if err != nil {
+		t.Fatalf("ReserveScan returned error: %v", err)
+	}
+	if _, err := d.svc.ReserveScan(d.reqCtx); err == nil {
+		t.Fatal("expected the second scan to exceed the limit of 1")
+	}
+
+	// The scan failed: its reservation is given back for the next one.
+	d.svc.ReleaseScan(d.reqCtx, reservation)
+	if _, err := d.svc.ReserveScan(d.reqCtx); err != nil {
+		t.Errorf("expected the released scan to be available again, got %v", err)
+	}
+}
+
+func TestReserveScanUsesOverrideLimit(t *testing.T) {
+	d := newQuotaTestDeps(t, freeTrialPlan(1, 30), time.Now())
+	d.overrideRepo.EXPECT().GetActiveOverride(gomock.Any(), d.reqCtx.Org.ID).AnyTimes().Return(
+		&model.QuotaOverride{OrganizationID: d.reqCtx.Org.ID, MonthlyScanLimit: 3}, nil)
+
+	for i := range 3 {
+		if _, err := d.svc.ReserveScan(d.reqCtx); err != nil {
+			t.Fatalf("scan %d: expected the override to allow 3 scans, got %v", i+1, err)
+		}
+	}
+	_, err := d.svc.ReserveScan(d.reqCtx)
+	var quotaErr *service.QuotaExceededError
+	if !errors.As(err, &quotaErr) || !quotaErr.Status.HasOverride || quotaErr.Status.Limit != 3 {
+		t.Errorf("expected the override limit to be exceeded, got %v", err)
+	}
+}
+
+func TestTrialQuotaSpansMonths(t *testing.T) {
+	// Created 32 days ago, the organization started its trial last month or
+	// before, and the trial of 60 days is still running.
+	createdAt := time.Now().UTC().AddDate(0, 0, -32)
+	d := newQuotaTestDeps(t, freeTrialPlan(10, 60), createdAt)
+	d.overrideRepo.EXPECT().GetActiveOverride(gomock.Any(), d.reqCtx.Org.ID).AnyTimes().Return(nil, nil)
+
+	// The scans of the first month count against the same trial allowance.
+	d.usage.counts[usageKey(model.UsageEventTrialScan, createdAt.Year(), int(createdAt.Month()))] = 10
+
+	_, err := d.svc.ReserveScan(d.reqCtx)
+	var quotaErr *service.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("expected the trial allowance to be used up in the new month, got %v", err)
+	}
+	if quotaErr.Status.TrialExpired || quotaErr.Status.Used != 10 || !quotaErr.Status.TrialStartedAt.Equal(createdAt) {
+		t.Errorf("unexpected quota status: %+v", quotaErr.Status)
+	}
+
+	status, err := d.svc.GetQuotaStatus(d.reqCtx)
+	if err != nil {
+		t.Fatalf("GetQuotaStatus returned error: %v", err)
+	}
+	if status.Used != 10 || status.Remaining != 0 {
+		t.Errorf("expected the trial usage of the first month, got used %d, remaining %d", status.Used, status.Remaining)
+	}
+}
+
+func TestReserveScanBeyondAllowanceIsOverage(t *testing.T) {
+	plan := &model.Plan{Code: model.PlanCodeMonthly, MonthlyScanLimit: 2, OverageMeterEvent: "scan_overage", IsActive: true}
+	d := newQuotaTestDeps(t, plan, time.Now())
+	d.overrideRepo.EXPECT().GetActiveOverride(gomock.Any(), d.reqCtx.Org.ID).AnyTimes().Return(nil, nil)
+	now := time.Now().UTC()
+	d.usage.counts[usageKey(model.UsageEventScan, now.Year(), int(now.Month()))] = 1
+
+	// The last scan of the allowance.
+	reservation, err := d.svc.ReserveScan(d.reqCtx)
+	if err != nil {
+		t.Fatalf("ReserveScan returned error: %v", err)
+	}
+	if reservation.Overage() || reservation.MeterEvent() != model.UsageEventScan {
+		t.Errorf("expected the scan within the allowance on the scan meter, got %s", reservation.MeterEvent())
+	}
+
+	// The first scan beyond it.
+	reservation, err = d.svc.ReserveScan(d.reqCtx)
+	if err != nil {
+		t.Fatalf("expected the plan to allow overage, got %v", err)
+	}
+	if !reservation.Overage() || reservation.MeterEvent() != "scan_overage" {
+		t.Errorf("expected the scan beyond the allowance on the overage meter, got %s", reservation.MeterEvent())
+	}
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/file_utils"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+const (
+	retentionSweepInterval = 6 * time.Hour
+	retentionSweepBatch    = 100
+)
+
+// RetentionService applies the retention policies of the organizations. The
+// uploaded documents of a record are removed DocumentDays after it was
+// created and its extracted data DataDays after; either is kept forever when
+// zero. A category policy replaces the organization's for its records.
+// Records on legal hold, and those scanned in a batch on legal hold, are
+// never expired. The sweeper leaves a tombstone on each record it expires.
+type RetentionService struct {
+	appCtx           *app.AppContext
+	orgRepo          repo.OrganizationRepo
+	categoryRepo     repo.CategoryRepository
+	categoryDataRepo repo.CategoryDataRepository
+	batchRepo        repo.BatchRepo
+	scanHistoryRepo  repo.ScanHistoryRepo
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewRetentionService(appCtx *app.AppContext, orgRepo repo.OrganizationRepo, categoryRepo repo.CategoryRepository, categoryDataRepo repo.CategoryDataRepository,
+	batchRepo repo.BatchRepo, scanHistoryRepo repo.ScanHistoryRepo) *RetentionService {
+	return &RetentionService{
+		appCtx:           appCtx,
+		orgRepo:          orgRepo,
+		categoryRepo:     categoryRepo,
+		categoryDataRepo: categoryDataRepo,
+		batchRepo:        batchRepo,
+		scanHistoryRepo:  scanHistoryRepo,
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+// Start runs the sweeper in the background until Stop is called.
+func (s *RetentionService) Start() {
+	if !s.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(retentionSweepInterval)
+		defer ticker.Stop()
+		for {
+			if err := s.SweepAll(&app.RequestContext{}); err != nil {
+				log.Printf("retention sweep failed: %v", err)
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running sweep to finish and stops the sweeper.
+func (s *RetentionService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if s.started.Load() {
+			<-s.done
+		}
+	})
+}
+
+// SweepAll applies the retention policies of every active organization that
+// has one.
+func (s *RetentionService) SweepAll(reqCtx *app.RequestContext) error {
+	orgs, err := s.orgRepo.GetOrganizations(reqCtx, bson.M{"is_active": true, "deleted_at": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	for _, org := range orgs {
+		if !hasRetentionPolicy(org.Settings) {
+			continue
+		}
+		report, err := s.sweep(reqCtx, org, false)
+		if err != nil {
+			log.Printf("retention sweep of organization %s failed: %v", org.ID.Hex(), err)
+			continue
+		}
+		for _, category := range report.Categories {
+			if category.DocumentsExpired > 0 || category.DataExpired > 0 || category.Failed > 0 {
+				log.Printf("retention sweep of organization %s: %+v", org.ID.Hex(), category)
+			}
+		}
+	}
+	return nil
+}
+
+// Report counts the records of the current organization that the next sweep
+// would expire, without changing anything.
+func (s *RetentionService) Report(reqCtx *app.RequestContext) (*model.RetentionReport, error) {
+	org, err := s.orgRepo.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, errors.New("organization not found")
+	}
+	return s.sweep(reqCtx, org, true)
+}
+
+func hasRetentionPolicy(settings model.OrganizationSettings) bool {
+	if settings.Retention.DocumentDays > 0 || settings.Retention.DataDays > 0 {
+		return true
+	}
+	for _, policy := range settings.CategoryRetention {
+		if policy.DocumentDays > 0 || policy.DataDays > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sweep applies, or with dryRun only counts, the retention policies of the
+// organization.
+func (s *RetentionService) sweep(reqCtx *app.RequestContext, org *model.Organization, dryRun bool) (*model.RetentionReport, error) {
+	orgCtx := &app.RequestContext{User: reqCtx.User, Org: app.RequestOrg{ID: org.ID, Name: org.Name, Slug: org.Slug}}
+
+	// Category policies are keyed by category, the data collections by slug.
+	policies := map[string]model.RetentionPolicy{}
+	for _, policy := range org.Settings.CategoryRetention {
+		category, err := s.categoryRepo.GetCategoryByID(orgCtx, policy.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if category != nil {
+			policies[category.Slug] = policy.RetentionPolicy
+		}
+	}
+
+	heldBatches, err := s.batchRepo.GetLegalHoldBatchIDs(orgCtx)
+	if err != nil {
+		return nil, err
+	}
+	var held []bson.ObjectID
+	if len(heldBatches) > 0 {
+		if held, err = s.scanHistoryRepo.GetCategoryDataIDsByBatches(orgCtx, heldBatches); err != nil {
+			return nil, err
+		}
+	}
+
+	collections, err := s.categoryDataRepo.ListDataCollections(orgCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.RetentionReport{
+		OrganizationID: org.ID,
+		DryRun:         dryRun,
+		GeneratedAt:    time.Now(),
+		HeldBatches:    len(heldBatches),
+		Categories:     []model.RetentionCategoryReport{},
+	}
+	for _, collection := range collections {
+		categorySlug := strings.TrimSuffix(collection, "_data")
+		policy, ok := policies[categorySlug]
+		if !ok {
+			policy = org.Settings.Retention
+		}
+		if policy.DocumentDays == 0 && policy.DataDays == 0 {
+			continue
+		}
+
+		entry := model.RetentionCategoryReport{CategorySlug: categorySlug, Policy: policy}
+		if policy.DocumentDays > 0 {
+			expired, failed, err := s.expire(orgCtx, categorySlug, model.RetentionStageDocuments, policy.DocumentDays, held, dryRun)
+			if err != nil {
+				return nil, err
+			}
+			entry.DocumentsExpired, entry.Failed = expired, entry.Failed+failed
+		}
+		if policy.DataDays > 0 {
+			expired, failed, err := s.expire(orgCtx, categorySlug, model.RetentionStageData, policy.DataDays, held, dryRun)
+			if err != nil {
+				return nil, err
+			}
+			entry.DataExpired, entry.Failed = expired, entry.Failed+failed
+		}
+		report.Categories = append(report.Categories, entry)
+	}
+	return report, nil
+}
+
+// expire applies a retention stage to the due records of a category and
+// returns how many were expired and how many failed. A record whose files
+// cannot be removed is left as it is and retried by the next sweep.
+func (s *RetentionService) expire(orgCtx *app.RequestContext, categorySlug string, stage model.RetentionStage, days int, held []bson.ObjectID, dryRun bool) (int64, int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	if dryRun {
+		count, err := s.categoryDataRepo.CountRetentionDue(orgCtx, categorySlug, stage, cutoff, held)
+		return count, 0, err
+	}
+
+	exempt := append([]bson.ObjectID{}, held...)
+	var expired, failed int64
+	for {
+		records, err := s.categoryDataRepo.FindRetentionDue(orgCtx, categorySlug, stage, cutoff, exempt, retentionSweepBatch)
+		if err != nil {
+			return expired, failed, err
+		}
+		for _, record := range records {
+			if err := s.removeDocuments(orgCtx, orgCtx.Org.ID, record.DocumentPaths); err != nil {
+				log.Printf("failed to remove documents of %s %s: %v", categorySlug, record.ID.Hex(), err)
+				exempt = append(exempt, record.ID)
+				failed++
+				continue
+			}
+			if err := s.categoryDataRepo.ApplyRetention(orgCtx, categorySlug, record.ID, stage, len(record.DocumentPaths)); err != nil {
+				return expired, failed, err
+			}
+			expired++
+		}
+		if len(records) < retentionSweepBatch {
+			return expired, failed, nil
+		}
+	}
+}
+
+// removeDocuments deletes uploaded documents from the blob store. Only the
+// documents uploaded to the organization are deleted.
+func (s *RetentionService) removeDocuments(reqCtx *app.RequestContext, orgID bson.ObjectID, keys []string) error {
+	prefix := file_utils.UploadPrefix(orgID)
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if !strings.HasPrefix(key, prefix) {
+			return fmt.Errorf("document %s was not uploaded to the organization", key)
+		}
+		if err := s.appCtx.Blobs.Delete(reqCtx.Context(), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/file_utils"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/storage"
+)
+
+type retentionMocks struct {
+	orgRepo          *mocks.MockOrganizationRepo
+	categoryRepo     *mocks.MockCategoryRepository
+	categoryDataRepo *mocks.MockCategoryDataRepository
+	batchRepo        *mocks.MockBatchRepo
+	scanHistoryRepo  *mocks.MockScanHistoryRepo
+}
+
+func newRetentionService(t *testing.T, appCtx *app.AppContext) (*service.RetentionService, *retentionMocks) {
+	ctrl := gomock.NewController(t)
+	m := &retentionMocks{
+		orgRepo:          mocks.NewMockOrganizationRepo(ctrl),
+		categoryRepo:     mocks.NewMockCategoryRepository(ctrl),
+		categoryDataRepo: mocks.NewMockCategoryDataRepository(ctrl),
+		batchRepo:        mocks.NewMockBatchRepo(ctrl),
+		scanHistoryRepo:  mocks.NewMockScanHistoryRepo(ctrl),
+	}
+	svc := service.NewRetentionService(appCtx, m.orgRepo, m.categoryRepo, m.categoryDataRepo, m.batchRepo, m.scanHistoryRepo)
+	return svc, m
+}
+
+func TestSweepAllExpiresDocumentsAndSkipsHeldRecords(t *testing.T) {
+	appCtx := app.NewMockAppContext()
+	appCtx.Blobs = storage.NewLocalBlobStore(t.TempDir())
+	svc, m := newRetentionService(t, appCtx)
+
+	reqCtx := &app.RequestContext{}
+	org := &model.Organization{
+		Base:     model.Base{ID: bson.NewObjectID()},
+		Slug:     "acme",
+		IsActive: true,
+		Settings: model.OrganizationSettings{Retention: model.RetentionPolicy{DocumentDays: 90}},
+	}
+	document := file_utils.UploadPrefix(org.ID) + "abc_invoice.pdf"
+	if err := appCtx.Blobs.Put(context.Background(), document, strings.NewReader("%PDF-1.4"), 8, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	outside := file_utils.UploadPrefix(bson.NewObjectID()) + "other.pdf"
+
+	heldBatch, heldRecord := bson.NewObjectID(), bson.NewObjectID()
+	expiredRecord := &model.CategoryData{Base: model.Base{ID: bson.NewObjectID()}, DocumentPaths: []string{document}}
+	foreignRecord := &model.CategoryData{Base: model.Base{ID: bson.NewObjectID()}, DocumentPaths: []string{outside}}
+
+	m.orgRepo.EXPECT().GetOrganizations(reqCtx, gomock.Any()).Return([]*model.Organization{org}, nil)
+	m.batchRepo.EXPECT().GetLegalHoldBatchIDs(gomock.Any()).Return([]bson.ObjectID{heldBatch}, nil)
+	m.scanHistoryRepo.EXPECT().GetCategoryDataIDsByBatches(gomock.Any(), []bson.ObjectID{heldBatch}).Return([]bson.ObjectID{heldRecord}, nil)
+	m.categoryDataRepo.EXPECT().ListDataCollections(gomock.Any()).Return([]string{"invoice_data"}, nil)
+	m.categoryDataRepo.EXPECT().FindRetentionDue(gomock.Any(), "invoice", model.RetentionStageDocuments, gomock.Any(), []bson.ObjectID{heldRecord}, gomock.Any()).
+		Return([]*model.CategoryData{expiredRecord, foreignRecord}, nil)
+	m.categoryDataRepo.EXPECT().ApplyRetention(gomock.Any(), "invoice", expiredRecord.ID, model.RetentionStageDocuments, 1).Return(nil)
+
+	if err := svc.SweepAll(reqCtx); err != nil {
+		t.Fatalf("SweepAll returned error: %v", err)
+	}
+	if _, err := appCtx.Blobs.Stat(context.Background(), document); err != storage.ErrBlobNotFound {
+		t.Errorf("expected the expired document to be removed, stat err %v", err)
+	}
+}
+
+func TestRetentionReportOnlyCounts(t *testing.T) {
+	svc, m := newRetentionService(t, app.NewMockAppContext())
+
+	categoryID := bson.NewObjectID()
+	org := &model.Organization{
+		Base: model.Base{ID: bson.NewObjectID()},
+		Slug: "acme",
+		Settings: model.OrganizationSettings{
+			Retention: model.RetentionPolicy{DocumentDays: 90},
+			CategoryRetention: []model.CategoryRetentionPolicy{
+				{CategoryID: categoryID, RetentionPolicy: model.RetentionPolicy{DataDays: 7 * 365}},
+			},
+		},
+	}
+	reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: org.ID}}
+
+	m.orgRepo.EXPECT().GetOrganizationByID(reqCtx, org.ID).Return(org, nil)
+	m.categoryRepo.EXPECT().GetCategoryByID(gomock.Any(), categoryID).Return(&model.Category{Slug: "contract"}, nil)
+	m.batchRepo.EXPECT().GetLegalHoldBatchIDs(gomock.Any()).Return(nil, nil)
+	m.categoryDataRepo.EXPECT().ListDataCollections(gomock.Any()).Return([]string{"invoice_data", "contract_data"}, nil)
+	m.categoryDataRepo.EXPECT().CountRetentionDue(gomock.Any(), "invoice", model.RetentionStageDocuments, gomock.Any(), gomock.Any()).Return(int64(3), nil)
+	m.categoryDataRepo.EXPECT().CountRetentionDue(gomock.Any(), "contract", model.RetentionStageData, gomock.Any(), gomock.Any()).Return(int64(2), nil)
+
+	report, err := svc.Report(reqCtx)
+	if err != nil {
+		t.Fatalf("Report returned error: %v", err)
+	}
+	if !report.DryRun || len(report.Categories) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Categories[0].DocumentsExpired != 3 || report.Categories[1].DataExpired != 2 || report.Categories[1].DocumentsExpired != 0 {
+		t.Errorf("expected the category policy to replace the organization's, got %+v", report.Categories)
+	}
+}
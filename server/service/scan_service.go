@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
 	"github.com/gaeaglobal/exto/server/file_utils"
 	"github.com/gaeaglobal/exto/server/utils"
 	"github.com/gin-gonic/gin"
@@ -14,23 +15,31 @@ import (
 
 type ScanService struct {
 	appCtx              *app.AppContext
+	dbSessionProvider   db.SessionProvider
 	orgService          *OrganizationService
 	openAIService       *OpenAIService
 	batchService        *BatchService
 	scanHistoryService  *ScanHistoryService
 	categoryDataService *CategoryDataService
 	meterService        *MeterService
+	quotaService        *QuotaService
+	piiService          *PIIService
+	uploadService       *UploadService
 }
 
-func NewScanService(appCtx *app.AppContext, orgService *OrganizationService, batchService *BatchService, openAIService *OpenAIService, scanHistoryService *ScanHistoryService, categoryDataService *CategoryDataService, meterService *MeterService) *ScanService {
+func NewScanService(appCtx *app.AppContext, dbSessionProvider db.SessionProvider, orgService *OrganizationService, batchService *BatchService, openAIService *OpenAIService, scanHistoryService *ScanHistoryService, categoryDataService *CategoryDataService, meterService *MeterService, quotaService *QuotaService, piiService *PIIService, uploadService *UploadService) *ScanService {
 	return &ScanService{
 		appCtx:              appCtx,
+		dbSessionProvider:   dbSessionProvider,
 		orgService:          orgService,
 		batchService:        batchService,
 		openAIService:       openAIService,
 		scanHistoryService:  scanHistoryService,
 		categoryDataService: categoryDataService,
 		meterService:        meterService,
+		quotaService:        quotaService,
+		piiService:          piiService,
+		uploadService:       uploadService,
 	}
 }
 
@@ -42,55 +51,49 @@ type ScanResult struct {
 	Data           map[string]any
 }
 
-// Example method to perform the scan and return ScanResult
-func (s *ScanService) PerformScan(appCtx *app.AppContext, reqCtx *app.RequestContext, c *gin.Context, categoryObjID bson.ObjectID, batchObjID bson.ObjectID) (*ScanResult, error) {
-
-	// Save the uploaded file to disk
-	filePath, fileErr := SaveScanFileToDisk(appCtx, reqCtx, c)
-	if fileErr != nil {
-		return nil, fmt.Errorf("failed to save uploaded file: %w", fileErr)
+// PerformScan counts the scan against the organization quota, then stores the
+// uploaded document and extracts its data. The reservation is given back and
+// the stored document deleted when the scan fails.
+func (s *ScanService) PerformScan(appCtx *app.AppContext, reqCtx *app.RequestContext, c *gin.Context, categoryObjID bson.ObjectID, batchObjID bson.ObjectID, forceRefresh bool) (*ScanResult, error) {
+	// Nothing is stored for an organization without scans left.
+	reservation, err := s.quotaService.ReserveScan(reqCtx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get base64 string from file path
-	base64Image, base64Err := utils.GetBase64FromFilePath(filePath)
-	if base64Err != nil {
-		return nil, fmt.Errorf("failed to get base64 image: %w", base64Err)
+	scanResult, err := s.scanUpload(reqCtx, c, reservation, categoryObjID, batchObjID, forceRefresh)
+	if err != nil {
+		s.quotaService.ReleaseScan(reqCtx, reservation)
+		return nil, err
 	}
-
-	return s.PerformOpenAIScan(reqCtx, categoryObjID, base64Image, batchObjID, filePath)
-
+	return scanResult, nil
 }
 
-func SaveScanFileToDisk(appCtx *app.AppContext, reqCtx *app.RequestContext, c *gin.Context) (string, error) {
-	// Retrieve the file from the form data
-	file, err := c.FormFile("file")
-	if err != nil {
-		return "", errors.New("Uploaded file not found in form data (expected key: 'file')")
+func (s *ScanService) scanUpload(reqCtx *app.RequestContext, c *gin.Context, reservation *ScanReservation, categoryObjID bson.ObjectID, batchObjID bson.ObjectID, forceRefresh bool) (*ScanResult, error) {
+	// Validate the uploaded file and save it to the blob store
+	upload, fileErr := s.uploadService.SaveUploadedFile(c, reqCtx)
+	if fileErr != nil {
+		return nil, fmt.Errorf("failed to save uploaded file: %w", fileErr)
 	}
 
-	dst := file_utils.GetFilePath(appCtx, reqCtx, appCtx.Config.UPLOAD_DIR, file.Filename, 7, 0)
-	if dst == "" {
-		return "", errors.New("failed to generate unique file path for upload (path generation issue)")
+	// Get base64 string from the uploaded image
+	base64Image, err := utils.GetBase64FromImage(upload.Content)
+	if err != nil {
+		s.uploadService.DeleteUpload(reqCtx, upload)
+		return nil, fmt.Errorf("failed to get base64 image: %w", err)
 	}
 
-	// Save the file
-	if err := c.SaveUploadedFile(file, dst); err != nil {
-		return "", fmt.Errorf("failed to save file: %w", err)
+	scanResult, err := s.performOpenAIScan(reqCtx, reservation, categoryObjID, base64Image, batchObjID, upload, forceRefresh)
+	if err != nil {
+		// The document of a failed scan is not kept.
+		s.uploadService.DeleteUpload(reqCtx, upload)
+		return nil, err
 	}
-	return dst, nil
+	return scanResult, nil
 }
 
-func (s *ScanService) PerformOpenAIScan(reqCtx *app.RequestContext, categoryObjID bson.ObjectID, base64Image string, batchID bson.ObjectID, filePath string) (*ScanResult, error) {
-	evt, err := s.meterService.IncrementMeterEvent(reqCtx, "scan", 1)
-	if err != nil {
-		log.Printf("Failed to create meter event: %v", err)
-	}
-	if evt != nil {
-		log.Printf("Created meter event")
-	} else {
-		log.Printf("Failed to create meter event: event is nil")
-	}
-	result, err := s.openAIService.ExtractDocumentData(reqCtx, categoryObjID, base64Image)
+func (s *ScanService) performOpenAIScan(reqCtx *app.RequestContext, reservation *ScanReservation, categoryObjID bson.ObjectID, base64Image string, batchID bson.ObjectID, upload *file_utils.Upload, forceRefresh bool) (*ScanResult, error) {
+	result, err := s.openAIService.ExtractDocumentData(reqCtx, categoryObjID, base64Image, forceRefresh)
 	if err != nil {
 		return nil, errors.New("OpenAI extraction failed")
 	}
@@ -119,14 +122,36 @@ func (s *ScanService) PerformOpenAIScan(reqCtx *app.RequestContext, categoryObjI
 	avg := s.openAIService.CalculateAverageConfidence(confidenceMap)
 	fmt.Println("Average confidence:", avg)
 
+	boundingBoxes, err := s.openAIService.ConvertBoundingBoxesToMap(resultStr)
+	if err != nil {
+		return nil, errors.New("failed to convert bounding boxes to map")
+	}
+	piiTags := s.piiService.Detect(reqCtx, extractedMap, boundingBoxes)
+
 	rawData := map[string]any{
 		"extractedData":     extractedMap,
 		"confidenceScores":  confidenceMapFloat,
 		"averageConfidence": avg,
 	}
 
-	categoryDataRes, err := s.categoryDataService.CreateCategoryData(reqCtx, categoryObjID, &extractedMap, &rawData, filePath, batchID)
+	// The scan is billed if and only if its data is saved: the meter event is
+	// added to the outbox in the transaction of the category data and the scan
+	// history, and the dispatcher reports it to Stripe. Scans beyond the
+	// allowance of the plan are reported to its overage meter.
+	var categoryDataRes *CreateCategoryDataResult
+	err = app.WithTransaction(reqCtx, s.dbSessionProvider, func(txCtx *app.RequestContext) error {
+		res, err := s.categoryDataService.CreateCategoryData(txCtx, categoryObjID, &extractedMap, &rawData, upload, batchID, piiTags)
+		if err != nil {
+			return fmt.Errorf("failed to save category data: %w", err)
+		}
+		if _, err := s.meterService.RecordMeterEvent(txCtx, reservation.MeterEvent(), 1); err != nil {
+			return fmt.Errorf("failed to record meter event: %w", err)
+		}
+		categoryDataRes = res
+		return nil
+	})
 	if err != nil {
+		log.Printf("failed to save scan: %v", err)
 		return nil, errors.New("failed to save category data")
 	}
 
@@ -139,6 +164,9 @@ func (s *ScanService) PerformOpenAIScan(reqCtx *app.RequestContext, categoryObjI
 		ScanCode:       categoryDataRes.ScanHistory.ScanCode,
 		Data:           rawData,
 	}
+	if len(piiTags) > 0 && !s.piiService.CanRead(reqCtx) {
+		scanResult.Data = categoryDataRes.CategoryData.RawData
+	}
 
 	return scanResult, nil
 }
@@ -0,0 +1,205 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io/fs"
+	"mime/multipart"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+	"github.com/gaeaglobal/exto/server/storage"
+)
+
+type scanTestDeps struct {
+	*quotaTestDeps
+	svc          *service.ScanService
+	session      *mocks.MockMongoSession
+	categoryID   bson.ObjectID
+	dataRepo     *mocks.MockCategoryDataRepository
+	historyRepo  *mocks.MockScanHistoryRepo
+	meterRepo    *mocks.MockMeterEventRepository
+	meterOrgRepo *mocks.MockOrganizationRepo
+	blobDir      string
+	document     []byte
+}
+
+// newScanTestDeps sets up the scan of a document of a category by an
+// organization on the plan, billed through its Stripe customer. The language
+// model always answers with no value.
+func newScanTestDeps(t *testing.T, plan *model.Plan) *scanTestDeps {
+	ctrl := gomock.NewController(t)
+	d := &scanTestDeps{
+		quotaTestDeps: newQuotaTestDeps(t, plan, time.Now()),
+		session:       mocks.NewMockMongoSession(ctrl),
+		categoryID:    bson.NewObjectID(),
+		dataRepo:      mocks.NewMockCategoryDataRepository(ctrl),
+		historyRepo:   mocks.NewMockScanHistoryRepo(ctrl),
+		meterRepo:     mocks.NewMockMeterEventRepository(ctrl),
+		meterOrgRepo:  mocks.NewMockOrganizationRepo(ctrl),
+		blobDir:       t.TempDir(),
+	}
+	d.overrideRepo.EXPECT().GetActiveOverride(gomock.Any(), d.reqCtx.Org.ID).AnyTimes().Return(nil, nil)
+
+	formatRepo := mocks.NewMockFormatRepository(ctrl)
+	formatRepo.EXPECT().GetFormatsByCategoryID(gomock.Any(), d.categoryID).AnyTimes().Return([]model.Format{{Base: model.Base{ID: bson.NewObjectID()}, CategoryID: d.categoryID}}, nil)
+	openAIService := service.NewOpenAIServiceWithLLM(service.NewFormatService(formatRepo), newExtractionCacheTestService(t, newMemoryExtractionCacheRepo(), d.reqCtx.Org.ID), &countingLLM{})
+
+	categoryRepo := mocks.NewMockCategoryRepository(ctrl)
+	categoryRepo.EXPECT().GetCategoryByID(gomock.Any(), d.categoryID).AnyTimes().Return(&model.Category{Base: model.Base{ID: d.categoryID}, Slug: "invoices"}, nil)
+	d.meterOrgRepo.EXPECT().GetOrganizationByID(gomock.Any(), d.reqCtx.Org.ID).AnyTimes().Return(
+		&model.Organization{Base: model.Base{ID: d.reqCtx.Org.ID}, StripeCustomerId: "cus_test"}, nil)
+	meterOrgService := service.NewOrganizationService(d.meterOrgRepo)
+	d.meterOrgRepo.EXPECT().GenerateNextScanCode(gomock.Any()).AnyTimes().Return("SC-1", nil)
+
+	sessions := mocks.NewMockSessionProvider(ctrl)
+	sessions.EXPECT().StartSession().AnyTimes().Return(d.session, nil)
+	d.session.EXPECT().Context(gomock.Any()).AnyTimes().DoAndReturn(func(ctx context.Context) context.Context { return ctx })
+	d.session.EXPECT().StartTransaction().AnyTimes().Return(nil)
+	d.session.EXPECT().EndSession(gomock.Any()).AnyTimes()
+
+	appCtx := app.NewMockAppContext()
+	appCtx.Blobs = storage.NewLocalBlobStore(d.blobDir)
+	piiService := service.NewPIIService(appCtx, openAIService)
+	scanHistoryService := service.NewScanHistoryService(sessions, d.historyRepo)
+	categoryDataService := service.NewCategoryDataService(d.dataRepo, service.NewCategoryService(categoryRepo), meterOrgService, scanHistoryService, piiService)
+	d.svc = service.NewScanService(appCtx, sessions, meterOrgService, nil, openAIService, scanHistoryService, categoryDataService,
+		service.NewMeterService(nil, d.meterRepo, meterOrgService), d.quotaTestDeps.svc, piiService, service.NewUploadService(appCtx, d.quotaTestDeps.svc, nil))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	d.document = buf.Bytes()
+	return d
+}
+
+// scan uploads the document to PerformScan.
+func (d *scanTestDeps) scan(t *testing.T) (*service.ScanResult, error) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "invoice.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(d.document); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/scan", &body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+	return d.svc.PerformScan(nil, d.reqCtx, c, d.categoryID, bson.NewObjectID(), false)
+}
+
+// storedFiles returns the number of documents in the blob store.
+func (d *scanTestDeps) storedFiles(t *testing.T) int {
+	t.Helper()
+	count := 0
+	err := filepath.WalkDir(d.blobDir, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			count++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestScanRecordsMeterEventInItsTransaction(t *testing.T) {
+	d := newScanTestDeps(t, freeTrialPlan(5, 30))
+	d.dataRepo.EXPECT().CreateCategoryData(gomock.Any(), "invoices", gomock.Any()).Return(&model.CategoryData{Base: model.Base{ID: bson.NewObjectID()}}, nil)
+	d.historyRepo.EXPECT().CreateScanHistory(gomock.Any(), gomock.Any()).Return(&model.ScanHistory{Base: model.Base{ID: bson.NewObjectID()}, ScanCode: "SC-1"}, nil)
+	d.meterRepo.EXPECT().CreateMeterEvent(gomock.Any(), model.UsageEventScan, 1, "cus_test").DoAndReturn(
+		func(txCtx *app.RequestContext, name string, value int, customerID string) (*model.MeterEvent, error) {
+			if !txCtx.InTransaction() {
+				t.Error("expected the meter event to be recorded in the transaction of the scan")
+			}
+			return &model.MeterEvent{}, nil
+		})
+	d.session.EXPECT().CommitTransaction(gomock.Any()).Return(nil)
+
+	if _, err := d.scan(t); err != nil {
+		t.Fatalf("PerformScan returned error: %v", err)
+	}
+	if status, err := d.quotaTestDeps.svc.GetQuotaStatus(d.reqCtx); err != nil || status.Used != 1 {
+		t.Errorf("expected the scan to be counted, got %+v, %v", status, err)
+	}
+	if d.storedFiles(t) != 1 {
+		t.Errorf("expected the document to be stored, got %d files", d.storedFiles(t))
+	}
+}
+
+func TestScanFailsWhenMeterEventCannotBeRecorded(t *testing.T) {
+	d := newScanTestDeps(t, freeTrialPlan(5, 30))
+	d.dataRepo.EXPECT().CreateCategoryData(gomock.Any(), "invoices", gomock.Any()).Return(&model.CategoryData{Base: model.Base{ID: bson.NewObjectID()}}, nil)
+	d.historyRepo.EXPECT().CreateScanHistory(gomock.Any(), gomock.Any()).Return(&model.ScanHistory{Base: model.Base{ID: bson.NewObjectID()}}, nil)
+	d.meterRepo.EXPECT().CreateMeterEvent(gomock.Any(), model.UsageEventScan, 1, "cus_test").Return(nil, errors.New("write failed"))
+	// The category data and the scan history are rolled back with it.
+	d.session.EXPECT().AbortTransaction(gomock.Any()).Return(nil)
+	d.session.EXPECT().CommitTransaction(gomock.Any()).Times(0)
+
+	if _, err := d.scan(t); err == nil {
+		t.Fatal("expected the scan to fail")
+	}
+	if d.storedFiles(t) != 0 {
+		t.Errorf("expected the document of the failed scan to be deleted, got %d files", d.storedFiles(t))
+	}
+	status, err := d.quotaTestDeps.svc.GetQuotaStatus(d.reqCtx)
+	if err != nil {
+		t.Fatalf("GetQuotaStatus returned error: %v", err)
+	}
+	if status.Used != 0 {
+		t.Errorf("expected the reservation of the failed scan to be released, got %d scans used", status.Used)
+	}
+}
+
+func TestScanBeyondAllowanceIsReportedToOverageMeter(t *testing.T) {
+	d := newScanTestDeps(t, &model.Plan{Code: model.PlanCodeMonthly, MonthlyScanLimit: 1, OverageMeterEvent: "scan_overage", IsActive: true})
+	d.dataRepo.EXPECT().CreateCategoryData(gomock.Any(), "invoices", gomock.Any()).Times(2).Return(&model.CategoryData{Base: model.Base{ID: bson.NewObjectID()}}, nil)
+	d.historyRepo.EXPECT().CreateScanHistory(gomock.Any(), gomock.Any()).Times(2).Return(&model.ScanHistory{Base: model.Base{ID: bson.NewObjectID()}}, nil)
+	d.session.EXPECT().CommitTransaction(gomock.Any()).Times(2).Return(nil)
+	gomock.InOrder(
+		d.meterRepo.EXPECT().CreateMeterEvent(gomock.Any(), model.UsageEventScan, 1, "cus_test").Return(&model.MeterEvent{}, nil),
+		d.meterRepo.EXPECT().CreateMeterEvent(gomock.Any(), "scan_overage", 1, "cus_test").Return(&model.MeterEvent{}, nil),
+	)
+
+	for range 2 {
+		if _, err := d.scan(t); err != nil {
+			t.Fatalf("PerformScan returned error: %v", err)
+		}
+	}
+}
+
+func TestScanOverQuotaStoresNothing(t *testing.T) {
+	d := newScanTestDeps(t, freeTrialPlan(1, 30))
+	trialStart := time.Now().UTC()
+	d.usage.counts[usageKey(model.UsageEventTrialScan, trialStart.Year(), int(trialStart.Month()))] = 1
+
+	_, err := d.scan(t)
+	var quotaErr *service.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("expected the quota to be exceeded, got %v", err)
+	}
+	if d.storedFiles(t) != 0 {
+		t.Errorf("expected nothing stored for a scan over the quota, got %d files", d.storedFiles(t))
+	}
+}
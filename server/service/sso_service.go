@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/db"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+var (
+	ErrInvalidSSOConfig = errors.New("invalid SSO configuration")
+	ErrSSOConfigInUse   = errors.New("the SSO issuer and client ID are used by another organization")
+	ErrUserDeactivated  = errors.New("user has been deactivated in the organization")
+	ErrSSODomainDenied  = errors.New("email domain is not allowed by the organization")
+	ErrSSODomainUnknown = errors.New("the domain is not an allowed SSO domain of the organization")
+	// ErrSSODomainUnverified is returned when the TXT record proving the
+	// organization owns the domain is not found.
+	ErrSSODomainUnverified = errors.New("the verification TXT record of the domain was not found")
+	// ErrSSOAccountExists is returned when an SSO token names the email of an
+	// account that is not a member of the organization. Such accounts join
+	// through an invitation only.
+	ErrSSOAccountExists = errors.New("an account with this email already exists, ask for an invitation to the organization")
+)
+
+// TXTResolver looks up the TXT records of a domain name. net.Resolver
+// implements it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// SSOService manages the OpenID Connect single sign-on of organizations and
+// provisions users just in time when they first sign in through it.
+type SSOService struct {
+	userRepo        repo.UserRepo
+	identityService *IdentityService
+	orgService      *OrganizationService
+	resolver        TXTResolver
+}
+
+func NewSSOService(userRepo repo.UserRepo, identityService *IdentityService, orgService *OrganizationService) *SSOService {
+	return NewSSOServiceWithResolver(userRepo, identityService, orgService, net.DefaultResolver)
+}
+
+// NewSSOServiceWithResolver returns the service verifying domains with the
+// given resolver instead of the system one.
+func NewSSOServiceWithResolver(userRepo repo.UserRepo, identityService *IdentityService, orgService *OrganizationService, resolver TXTResolver) *SSOService {
+	return &SSOService{
+		userRepo:        userRepo,
+		identityService: identityService,
+		orgService:      orgService,
+		resolver:        resolver,
+	}
+}
+
+func (s *SSOService) GetSSOConfig(reqCtx *app.RequestContext) (*model.SSOConfig, error) {
+	org, err := s.orgService.GetOrganizationByID(reqCtx, reqCtx.Org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if org.SSO == nil {
+		return &model.SSOConfig{}, nil
+	}
+	return org.SSO, nil
+}
+
+// UpdateSSOConfig validates and stores the SSO configuration of the current
+// organization.
+func (s *SSOService) UpdateSSOConfig(reqCtx *app.RequestContext, sso *model.SSOConfig) (*model.Organization, error) {
+	sso.Issuer = strings.TrimSpace(sso.Issuer)
+	sso.ClientID = strings.TrimSpace(sso.ClientID)
+	// The server fetches the discovery document and the signing keys of the
+	// provider, which must therefore not be a host of the private network.
+	if app.ValidatePublicHTTPSURL(sso.Issuer) != nil || sso.ClientID == "" {
+		return nil, ErrInvalidSSOConfig
+	}
+	if sso.JWKSURL != "" && app.ValidatePublicHTTPSURL(sso.JWKSURL) != nil {
+		return nil, ErrInvalidSSOConfig
+	}
+	if sso.DefaultRole == "" {
+		sso.DefaultRole = model.RoleMember
+	}
+	if !sso.DefaultRole.IsAssignableBySSO() {
+		return nil, ErrInvalidSSOConfig
+	}
+	for _, role := range sso.RoleMapping {
+		if !role.IsAssignableBySSO() {
+			return nil, ErrInvalidSSOConfig
+		}
+	}
+	// The provider may assert any email, so the organization must say which
+	// domains it answers for.
+	domains := []string{}
+	for _, domain := range sso.AllowedDomains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "@")
+		if domain == "" || slices.Contains(domains, domain) {
+			continue
+		}
+		if !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@/ ") {
+			return nil, ErrInvalidSSOConfig
+		}
+		domains = append(domains, domain)
+	}
+	if len(domains) == 0 {
+		return nil, ErrInvalidSSOConfig
+	}
+	sso.AllowedDomains = domains
+
+	other, err := s.orgService.GetOrganizationBySSOClient(reqCtx, sso.Issuer, sso.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if other != nil && other.ID != reqCtx.Org.ID {
+		return nil, ErrSSOConfigInUse
+	}
+
+	// The ownership proofs are the server's: the ones of the domains still
+	// allowed are kept, and new domains start unverified.
+	current, err := s.GetSSOConfig(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	sso.Domains = make([]model.SSODomain, 0, len(domains))
+	for _, domain := range domains {
+		if existing := current.Domain(domain); existing != nil {
+			sso.Domains = append(sso.Domains, *existing)
+			continue
+		}
+		token, err := newSecretToken()
+		if err != nil {
+			return nil, err
+		}
+		sso.Domains = append(sso.Domains, model.SSODomain{Domain: domain, VerificationToken: token})
+	}
+	return s.orgService.SetSSOConfig(reqCtx, sso)
+}
+
+// VerifyDomain checks the DNS TXT record proving the organization owns the
+// allowed domain, and marks the domain verified when it is found.
+func (s *SSOService) VerifyDomain(reqCtx *app.RequestContext, domain string) (*model.SSODomain, error) {
+	sso, err := s.GetSSOConfig(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	proof := sso.Domain(strings.ToLower(strings.TrimSpace(domain)))
+	if proof == nil {
+		return nil, ErrSSODomainUnknown
+	}
+	if proof.Verified {
+		return proof, nil
+	}
+
+	ctx, cancel := context.WithTimeout(reqCtx.Context(), 10*time.Second)
+	defer cancel()
+	records, err := s.resolver.LookupTXT(ctx, model.SSODomainTXTPrefix+proof.Domain)
+	if err != nil {
+		log.Printf("failed to look up the sso verification record of %s: %v", proof.Domain, err)
+		return nil, ErrSSODomainUnverified
+	}
+	if !slices.Contains(records, proof.TXTRecord()) {
+		return nil, ErrSSODomainUnverified
+	}
+
+	proof.Verified = true
+	proof.VerifiedAt = time.Now()
+	if _, err := s.orgService.SetSSOConfig(reqCtx, sso); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// Provision signs in the user of a verified SSO ID token. The identity and
+// the user are created on the first sign-in with the role mapped from the
+// token. When a role claim is configured, the role is kept in sync with the
+// provider on every sign-in. Only emails of the allowed domains are
+// provisioned, and only for the domains the organization proved it owns.
+// An account that already exists is never attached to the organization by
+// its email: it must be a member already, and its current organization is
+// left as it is, the session being scoped to the SSO organization by its
+// token.
+func (s *SSOService) Provision(reqCtx *app.RequestContext, tokenUser *app.OIDCTokenUser) (*app.RequestContext, error) {
+	org := tokenUser.Org
+	if !org.SSO.AllowsEmail(tokenUser.Email) {
+		return nil, ErrSSODomainDenied
+	}
+	role := org.SSO.RoleFor(tokenUser.Claims)
+
+	identity, err := s.identityService.GetIdentityByEmail(reqCtx, tokenUser.Email)
+	created := false
+	if err != nil {
+		created = true
+		identity, err = s.identityService.CreateIdentity(reqCtx, &model.CreateIdentity{
+			Email:        tokenUser.Email,
+			FirstName:    tokenUser.FirstName,
+			LastName:     tokenUser.LastName,
+			CurrentOrgID: org.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userRepo.GetUserByOrgAndEmail(reqCtx, org.ID, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case user == nil && !created:
+		return nil, ErrSSOAccountExists
+	case user == nil:
+		user, err = s.userRepo.CreateUser(reqCtx, identity.ID, &model.CreateUser{
+			IdentityID:     identity.ID,
+			Email:          identity.Email,
+			FirstName:      identity.FirstName,
+			LastName:       identity.LastName,
+			Role:           role,
+			OrganizationID: org.ID,
+		})
+	case !user.IsActive:
+		return nil, ErrUserDeactivated
+	case org.SSO.RoleClaim != "" && user.Role != role && user.Role != model.RoleSuperAdmin:
+		actorCtx := (&app.RequestContext{}).WithUser(app.RequestUser{IdentityID: identity.ID, Email: identity.Email})
+		user, err = s.userRepo.UpdateUser(actorCtx, user.ID, &model.UpdateUser{
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Role:      role,
+			IsActive:  true,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if identity.CurrentOrgID.IsZero() {
+		if err := s.identityService.SetCurrentOrg(reqCtx, identity.ID, org.ID); err != nil {
+			return nil, err
+		}
+	}
+	reqCtx.AfterCommit(func() { db.DeleteCacheUser(identity.Email) })
+
+	return &app.RequestContext{
+		User: app.RequestUser{
+			ID:             user.ID,
+			Email:          user.Email,
+			FirstName:      user.FirstName,
+			LastName:       user.LastName,
+			Role:           user.Role,
+			OrganizationID: user.OrganizationID,
+			IdentityID:     user.IdentityID,
+			IsActive:       user.IsActive,
+			SSOSession:     true,
+		},
+		Org: app.RequestOrg{
+			ID:   org.ID,
+			Name: org.Name,
+			Slug: org.Slug,
+		},
+	}, nil
+}
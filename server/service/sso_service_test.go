@@ -0,0 +1,256 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+// acmeSSOConfig allows the verified domain acme.com.
+func acmeSSOConfig() *model.SSOConfig {
+	return &model.SSOConfig{
+		Enabled:        true,
+		AllowedDomains: []string{"acme.com"},
+		Domains:        []model.SSODomain{{Domain: "acme.com", Verified: true}},
+		DefaultRole:    model.RoleMember,
+	}
+}
+
+func TestSSOProvisionCreatesUserWithMappedRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepo := mocks.NewMockUserRepo(ctrl)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	svc := service.NewSSOService(userRepo, service.NewIdentityService(identityRepo), service.NewOrganizationService(orgRepo))
+	reqCtx := &app.RequestContext{}
+
+	org := &model.Organization{
+		Base: model.Base{ID: bson.NewObjectID()},
+		Name: "Acme",
+		Slug: "acme",
+		SSO: &model.SSOConfig{
+			Enabled:        true,
+			AllowedDomains: []string{"acme.com"},
+			Domains:        []model.SSODomain{{Domain: "acme.com", Verified: true}},
+			RoleClaim:      "groups",
+			RoleMapping:    map[string]model.UserRole{"finance": model.RoleBillingAdmin, "it": model.RoleOrganizationAdmin},
+			DefaultRole:    model.RoleMember,
+		},
+	}
+	tokenUser := &app.OIDCTokenUser{
+		Email:     "jane@acme.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Claims:    map[string]any{"groups": []any{"finance", "it", "staff"}},
+		Org:       org,
+	}
+	identity := &model.Identity{Base: model.Base{ID: bson.NewObjectID()}, Email: tokenUser.Email, FirstName: "Jane", LastName: "Doe", CurrentOrgID: org.ID}
+
+	identityRepo.EXPECT().GetIdentityByEmail(reqCtx, tokenUser.Email).Return(nil, errors.New("identity not found"))
+	identityRepo.EXPECT().IsIdentityExists(reqCtx, tokenUser.Email).Return(false, nil)
+	identityRepo.EXPECT().CreateIdentity(reqCtx, gomock.Any()).Return(identity, nil)
+	userRepo.EXPECT().GetUserByOrgAndEmail(reqCtx, org.ID, tokenUser.Email).Return(nil, nil)
+	userRepo.EXPECT().CreateUser(reqCtx, identity.ID, gomock.Any()).DoAndReturn(func(_ *app.RequestContext, _ bson.ObjectID, u *model.CreateUser) (*model.User, error) {
+		if u.Role != model.RoleOrganizationAdmin {
+			t.Errorf("expected the most privileged mapped role, got %s", u.Role)
+		}
+		return &model.User{Base: model.Base{ID: bson.NewObjectID()}, IdentityID: u.IdentityID, Email: u.Email, Role: u.Role, OrganizationID: u.OrganizationID, IsActive: true}, nil
+	})
+
+	signedIn, err := svc.Provision(reqCtx, tokenUser)
+	if err != nil {
+		t.Fatalf("Provision returned error: %v", err)
+	}
+	if signedIn.Org.ID != org.ID || signedIn.User.Role != model.RoleOrganizationAdmin {
+		t.Errorf("unexpected request context %+v", signedIn)
+	}
+}
+
+func TestSSOProvisionRejectsDeactivatedUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepo := mocks.NewMockUserRepo(ctrl)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	svc := service.NewSSOService(userRepo, service.NewIdentityService(identityRepo), service.NewOrganizationService(mocks.NewMockOrganizationRepo(ctrl)))
+	reqCtx := &app.RequestContext{}
+
+	org := &model.Organization{Base: model.Base{ID: bson.NewObjectID()}, SSO: acmeSSOConfig()}
+	identity := &model.Identity{Base: model.Base{ID: bson.NewObjectID()}, Email: "jane@acme.com"}
+	identityRepo.EXPECT().GetIdentityByEmail(reqCtx, identity.Email).Return(identity, nil)
+	userRepo.EXPECT().GetUserByOrgAndEmail(reqCtx, org.ID, identity.Email).Return(&model.User{Role: model.RoleMember, IsActive: false}, nil)
+
+	_, err := svc.Provision(reqCtx, &app.OIDCTokenUser{Email: identity.Email, Org: org})
+	if !errors.Is(err, service.ErrUserDeactivated) {
+		t.Fatalf("expected ErrUserDeactivated, got %v", err)
+	}
+}
+
+func TestSSOProvisionKeepsCurrentOrgOfExistingIdentity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepo := mocks.NewMockUserRepo(ctrl)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	svc := service.NewSSOService(userRepo, service.NewIdentityService(identityRepo), service.NewOrganizationService(mocks.NewMockOrganizationRepo(ctrl)))
+	reqCtx := &app.RequestContext{}
+
+	org := &model.Organization{Base: model.Base{ID: bson.NewObjectID()}, SSO: acmeSSOConfig()}
+	identity := &model.Identity{Base: model.Base{ID: bson.NewObjectID()}, Email: "jane@acme.com", CurrentOrgID: bson.NewObjectID()}
+	identityRepo.EXPECT().GetIdentityByEmail(reqCtx, identity.Email).Return(identity, nil)
+	userRepo.EXPECT().GetUserByOrgAndEmail(reqCtx, org.ID, identity.Email).Return(
+		&model.User{Base: model.Base{ID: bson.NewObjectID()}, IdentityID: identity.ID, Role: model.RoleMember, OrganizationID: org.ID, IsActive: true}, nil)
+	identityRepo.EXPECT().SetCurrentOrg(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	signedIn, err := svc.Provision(reqCtx, &app.OIDCTokenUser{Email: identity.Email, Org: org})
+	if err != nil || signedIn.Org.ID != org.ID {
+		t.Fatalf("expected a session in the SSO organization, got %+v, %v", signedIn, err)
+	}
+	if !signedIn.User.SSOSession || signedIn.User.Can(app.PermIdentity) {
+		t.Errorf("expected an SSO session without identity-wide permissions, got %+v", signedIn.User)
+	}
+}
+
+func TestSSOProvisionNeverAttachesExistingAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepo := mocks.NewMockUserRepo(ctrl)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	svc := service.NewSSOService(userRepo, service.NewIdentityService(identityRepo), service.NewOrganizationService(mocks.NewMockOrganizationRepo(ctrl)))
+	reqCtx := &app.RequestContext{}
+
+	// The account signed up elsewhere and is not a member of the organization.
+	org := &model.Organization{Base: model.Base{ID: bson.NewObjectID()}, SSO: acmeSSOConfig()}
+	identity := &model.Identity{Base: model.Base{ID: bson.NewObjectID()}, Email: "jane@acme.com", CurrentOrgID: bson.NewObjectID()}
+	identityRepo.EXPECT().GetIdentityByEmail(reqCtx, identity.Email).Return(identity, nil)
+	userRepo.EXPECT().GetUserByOrgAndEmail(reqCtx, org.ID, identity.Email).Return(nil, nil)
+	userRepo.EXPECT().CreateUser(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := svc.Provision(reqCtx, &app.OIDCTokenUser{Email: identity.Email, Org: org})
+	if !errors.Is(err, service.ErrSSOAccountExists) {
+		t.Fatalf("expected ErrSSOAccountExists, got %v", err)
+	}
+}
+
+func TestSSOProvisionRejectsOtherDomains(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	svc := service.NewSSOService(mocks.NewMockUserRepo(ctrl), service.NewIdentityService(mocks.NewMockIdentityRepo(ctrl)), service.NewOrganizationService(mocks.NewMockOrganizationRepo(ctrl)))
+
+	for _, domains := range [][]string{nil, {"acme.com"}} {
+		org := &model.Organization{Base: model.Base{ID: bson.NewObjectID()}, SSO: &model.SSOConfig{Enabled: true, AllowedDomains: domains, DefaultRole: model.RoleMember}}
+		_, err := svc.Provision(&app.RequestContext{}, &app.OIDCTokenUser{Email: "victim@example.com", Org: org})
+		if !errors.Is(err, service.ErrSSODomainDenied) {
+			t.Errorf("allowed domains %v: expected ErrSSODomainDenied, got %v", domains, err)
+		}
+	}
+
+	// An allowed domain the organization has not proved it owns.
+	org := &model.Organization{Base: model.Base{ID: bson.NewObjectID()}, SSO: &model.SSOConfig{
+		Enabled:        true,
+		AllowedDomains: []string{"example.com"},
+		Domains:        []model.SSODomain{{Domain: "example.com", VerificationToken: "token"}},
+		DefaultRole:    model.RoleMember,
+	}}
+	if _, err := svc.Provision(&app.RequestContext{}, &app.OIDCTokenUser{Email: "victim@example.com", Org: org}); !errors.Is(err, service.ErrSSODomainDenied) {
+		t.Errorf("unverified domain: expected ErrSSODomainDenied, got %v", err)
+	}
+}
+
+func TestUpdateSSOConfigValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	svc := service.NewSSOService(mocks.NewMockUserRepo(ctrl), service.NewIdentityService(mocks.NewMockIdentityRepo(ctrl)), service.NewOrganizationService(mocks.NewMockOrganizationRepo(ctrl)))
+	reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: bson.NewObjectID()}}
+
+	for name, sso := range map[string]model.SSOConfig{
+		"no domains":       {Issuer: "https://idp.acme.com", ClientID: "app"},
+		"blank domains":    {Issuer: "https://idp.acme.com", ClientID: "app", AllowedDomains: []string{" "}},
+		"http issuer":      {Issuer: "http://idp.acme.com", ClientID: "app", AllowedDomains: []string{"acme.com"}},
+		"loopback issuer":  {Issuer: "https://127.0.0.1:8443", ClientID: "app", AllowedDomains: []string{"acme.com"}},
+		"localhost issuer": {Issuer: "https://localhost", ClientID: "app", AllowedDomains: []string{"acme.com"}},
+		"private issuer":   {Issuer: "https://10.0.0.5/realms/acme", ClientID: "app", AllowedDomains: []string{"acme.com"}},
+		"link-local JWKS":  {Issuer: "https://idp.acme.com", ClientID: "app", AllowedDomains: []string{"acme.com"}, JWKSURL: "https://169.254.169.254/keys"},
+		"ipv6 loopback":    {Issuer: "https://[::1]/", ClientID: "app", AllowedDomains: []string{"acme.com"}},
+		"malformed domain": {Issuer: "https://idp.acme.com", ClientID: "app", AllowedDomains: []string{"acme"}},
+	} {
+		if _, err := svc.UpdateSSOConfig(reqCtx, &sso); !errors.Is(err, service.ErrInvalidSSOConfig) {
+			t.Errorf("%s: expected ErrInvalidSSOConfig, got %v", name, err)
+		}
+	}
+}
+
+func TestUpdateSSOConfigKeepsDomainProofs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	svc := service.NewSSOService(mocks.NewMockUserRepo(ctrl), service.NewIdentityService(mocks.NewMockIdentityRepo(ctrl)), service.NewOrganizationService(orgRepo))
+	reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: bson.NewObjectID()}}
+
+	orgRepo.EXPECT().GetOrganizations(reqCtx, gomock.Any()).Return(nil, nil)
+	orgRepo.EXPECT().GetOrganizationByID(reqCtx, reqCtx.Org.ID).Return(&model.Organization{SSO: acmeSSOConfig()}, nil)
+	orgRepo.EXPECT().SetSSOConfig(reqCtx, reqCtx.Org.ID, gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, _ bson.ObjectID, sso *model.SSOConfig) (*model.Organization, error) {
+			return &model.Organization{SSO: sso}, nil
+		})
+
+	// Sent by the admin, the verified flag of a new domain is ignored.
+	org, err := svc.UpdateSSOConfig(reqCtx, &model.SSOConfig{
+		Issuer:         "https://idp.acme.com",
+		ClientID:       "app",
+		AllowedDomains: []string{"acme.com", "Example.com"},
+		Domains:        []model.SSODomain{{Domain: "example.com", Verified: true}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateSSOConfig returned error: %v", err)
+	}
+	if d := org.SSO.Domain("acme.com"); d == nil || !d.Verified {
+		t.Errorf("expected the proof of acme.com to be kept, got %+v", d)
+	}
+	if d := org.SSO.Domain("example.com"); d == nil || d.Verified || d.VerificationToken == "" {
+		t.Errorf("expected example.com to wait for its verification, got %+v", d)
+	}
+	if org.SSO.AllowsEmail("victim@example.com") {
+		t.Error("expected emails of the unverified domain to be denied")
+	}
+}
+
+// staticTXTResolver answers TXT lookups from a map.
+type staticTXTResolver map[string][]string
+
+func (r staticTXTResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	return r[name], nil
+}
+
+func TestVerifySSODomain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	resolver := staticTXTResolver{}
+	svc := service.NewSSOServiceWithResolver(mocks.NewMockUserRepo(ctrl), service.NewIdentityService(mocks.NewMockIdentityRepo(ctrl)), service.NewOrganizationService(orgRepo), resolver)
+	reqCtx := &app.RequestContext{Org: app.RequestOrg{ID: bson.NewObjectID()}}
+
+	sso := &model.SSOConfig{
+		Enabled:        true,
+		AllowedDomains: []string{"example.com"},
+		Domains:        []model.SSODomain{{Domain: "example.com", VerificationToken: "token"}},
+	}
+	orgRepo.EXPECT().GetOrganizationByID(reqCtx, reqCtx.Org.ID).AnyTimes().Return(&model.Organization{SSO: sso}, nil)
+
+	if _, err := svc.VerifyDomain(reqCtx, "other.com"); !errors.Is(err, service.ErrSSODomainUnknown) {
+		t.Errorf("expected ErrSSODomainUnknown, got %v", err)
+	}
+	resolver[model.SSODomainTXTPrefix+"example.com"] = []string{"exto-sso-verification=wrong"}
+	if _, err := svc.VerifyDomain(reqCtx, "example.com"); !errors.Is(err, service.ErrSSODomainUnverified) {
+		t.Errorf("expected ErrSSODomainUnverified, got %v", err)
+	}
+
+	resolver[model.SSODomainTXTPrefix+"example.com"] = []string{"v=spf1 -all", "exto-sso-verification=token"}
+	orgRepo.EXPECT().SetSSOConfig(reqCtx, reqCtx.Org.ID, sso).Return(&model.Organization{SSO: sso}, nil)
+	domain, err := svc.VerifyDomain(reqCtx, "Example.com")
+	if err != nil || !domain.Verified || domain.VerifiedAt.IsZero() {
+		t.Fatalf("expected the domain to be verified, got %+v, %v", domain, err)
+	}
+	if !sso.AllowsEmail("jane@example.com") {
+		t.Error("expected emails of the verified domain to be allowed")
+	}
+}
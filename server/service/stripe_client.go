@@ -0,0 +1,98 @@
+package service
+
+import (
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/gaeaglobal/exto/server/model"
+)
+
+// NewStripeClient creates the Stripe client. A non-empty apiURL points every
+// backend at another server, such as a local stripe-mock.
+func NewStripeClient(apiKey string, apiURL string) *stripe.Client {
+	if apiURL == "" {
+		return stripe.NewClient(apiKey)
+	}
+	backends := stripe.NewBackendsWithConfig(&stripe.BackendConfig{
+		URL: stripe.String(apiURL),
+	})
+	return stripe.NewClient(apiKey, stripe.WithBackends(backends))
+}
+
+// newSyncSubscription maps a Stripe subscription onto the local subscription record.
+func newSyncSubscription(orgID bson.ObjectID, sub *stripe.Subscription) *model.SyncSubscription {
+	status := subscriptionStatusFromStripe(sub.Status)
+	sync := &model.SyncSubscription{
+		OrganizationID:    orgID,
+		StripeSubID:       sub.ID,
+		StartDate:         unixTime(sub.StartDate),
+		BillingCycle:      model.BillingCycleMonthly,
+		Status:            status,
+		CancelAtPeriodEnd: sub.CancelAtPeriodEnd,
+		IsCurrent:         status != model.SubscriptionStatusCanceled,
+	}
+	if sub.Items != nil && len(sub.Items.Data) > 0 {
+		item := sub.Items.Data[0]
+		sync.EndDate = unixTime(item.CurrentPeriodEnd)
+		if item.Price != nil && item.Price.Recurring != nil && item.Price.Recurring.Interval == stripe.PriceRecurringIntervalYear {
+			sync.BillingCycle = model.BillingCycleYearly
+		}
+	}
+	if sub.EndedAt > 0 {
+		sync.EndDate = unixTime(sub.EndedAt)
+	}
+	if sub.DefaultPaymentMethod != nil {
+		sync.DefaultPaymentMethodID = sub.DefaultPaymentMethod.ID
+	}
+	return sync
+}
+
+// newPaymentMethod maps a Stripe payment method onto the one kept on the
+// subscriptions.
+func newPaymentMethod(pm *stripe.PaymentMethod) *model.PaymentMethod {
+	paymentMethod := &model.PaymentMethod{
+		StripeID: pm.ID,
+		Type:     string(pm.Type),
+	}
+	if pm.Card != nil {
+		paymentMethod.Brand = string(pm.Card.Brand)
+		paymentMethod.Last4 = pm.Card.Last4
+		paymentMethod.ExpMonth = pm.Card.ExpMonth
+		paymentMethod.ExpYear = pm.Card.ExpYear
+	}
+	return paymentMethod
+}
+
+// newSyncInvoice maps a Stripe invoice onto the local invoice record.
+func newSyncInvoice(orgID bson.ObjectID, invoice *stripe.Invoice) *model.SyncInvoice {
+	return &model.SyncInvoice{
+		OrganizationID:     orgID,
+		StripeInvoiceID:    invoice.ID,
+		InvoiceNumber:      invoice.Number,
+		TotalAmount:        float64(invoice.Total) / 100,
+		BillingPeriodStart: unixTime(invoice.PeriodStart),
+		BillingPeriodEnd:   unixTime(invoice.PeriodEnd),
+		PDF_URL:            invoice.InvoicePDF,
+		Status:             string(invoice.Status),
+	}
+}
+
+func subscriptionStatusFromStripe(status stripe.SubscriptionStatus) model.SubscriptionStatus {
+	switch status {
+	case stripe.SubscriptionStatusIncompleteExpired:
+		return model.SubscriptionStatusCanceled
+	case stripe.SubscriptionStatusPaused:
+		return model.SubscriptionStatusPastDue
+	default:
+		return model.SubscriptionStatus(status)
+	}
+}
+
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
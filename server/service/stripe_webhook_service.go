@@ -0,0 +1,182 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/webhook"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+)
+
+var ErrInvalidWebhookSignature = errors.New("invalid stripe webhook signature")
+
+// StripeWebhookService applies Stripe webhook events to the local subscription
+// and invoice records. Every event is processed at most once.
+type StripeWebhookService struct {
+	secret              string
+	eventRepo           repo.StripeEventRepository
+	invoiceRepo         repo.InvoiceRepository
+	orgService          *OrganizationService
+	subscriptionService *SubscriptionService
+	planService         *PlanService
+}
+
+func NewStripeWebhookService(secret string, eventRepo repo.StripeEventRepository, invoiceRepo repo.InvoiceRepository, orgService *OrganizationService, subscriptionService *SubscriptionService, planService *PlanService) *StripeWebhookService {
+	return &StripeWebhookService{
+		secret:              secret,
+		eventRepo:           eventRepo,
+		invoiceRepo:         invoiceRepo,
+		orgService:          orgService,
+		subscriptionService: subscriptionService,
+		planService:         planService,
+	}
+}
+
+// ConstructEvent verifies the Stripe-Signature header and decodes the payload.
+func (s *StripeWebhookService) ConstructEvent(payload []byte, signature string) (*stripe.Event, error) {
+	if s.secret == "" {
+		return nil, errors.New("stripe webhook secret is not configured")
+	}
+	event, err := webhook.ConstructEventWithOptions(payload, signature, s.secret, webhook.ConstructEventOptions{
+		IgnoreAPIVersionMismatch: true,
+	})
+	if err != nil {
+		log.Printf("failed to verify stripe webhook: %v", err)
+		return nil, ErrInvalidWebhookSignature
+	}
+	return &event, nil
+}
+
+// HandleEvent processes the event unless it was processed before. When
+// processing fails the event is released so that Stripe's retry is handled.
+func (s *StripeWebhookService) HandleEvent(event *stripe.Event) error {
+	reqCtx := &app.RequestContext{}
+
+	claimed, err := s.eventRepo.Claim(reqCtx, event.ID, string(event.Type))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		log.Printf("skipping already processed stripe event %s", event.ID)
+		return nil
+	}
+
+	if err := s.dispatch(reqCtx, event); err != nil {
+		if releaseErr := s.eventRepo.Release(reqCtx, event.ID); releaseErr != nil {
+			log.Printf("failed to release stripe event %s: %v", event.ID, releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *StripeWebhookService) dispatch(reqCtx *app.RequestContext, event *stripe.Event) error {
+	eventType := string(event.Type)
+	switch {
+	case strings.HasPrefix(eventType, "customer.subscription."):
+		var sub stripe.Subscription
+		if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
+			return fmt.Errorf("failed to decode subscription: %w", err)
+		}
+		return s.syncSubscription(reqCtx, event, &sub)
+	case strings.HasPrefix(eventType, "invoice."):
+		var invoice stripe.Invoice
+		if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
+			return fmt.Errorf("failed to decode invoice: %w", err)
+		}
+		return s.syncInvoice(reqCtx, &invoice)
+	case strings.HasPrefix(eventType, "payment_method."):
+		var pm stripe.PaymentMethod
+		if err := json.Unmarshal(event.Data.Raw, &pm); err != nil {
+			return fmt.Errorf("failed to decode payment method: %w", err)
+		}
+		return s.syncPaymentMethod(reqCtx, event.Type, &pm)
+	default:
+		log.Printf("ignoring stripe event %s of type %s", event.ID, eventType)
+		return nil
+	}
+}
+
+func (s *StripeWebhookService) syncSubscription(reqCtx *app.RequestContext, event *stripe.Event, sub *stripe.Subscription) error {
+	org, err := s.organizationForCustomer(reqCtx, sub.Customer)
+	if err != nil || org == nil {
+		return err
+	}
+
+	sync := newSyncSubscription(org.ID, sub)
+	sync.EventCreatedAt = unixTime(event.Created)
+	if event.Type == stripe.EventTypeCustomerSubscriptionDeleted {
+		sync.IsCurrent = false
+	}
+	if sub.Items != nil && len(sub.Items.Data) > 0 && sub.Items.Data[0].Price != nil {
+		// Plan changes made outside the API are picked up through the price.
+		sync.BillingPlanID, err = s.planService.PlanIDForPrice(reqCtx, sub.Items.Data[0].Price.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.subscriptionService.SyncStripeSubscription(reqCtx, sync)
+	if errors.Is(err, repo.ErrStaleStripeEvent) {
+		log.Printf("skipping stripe event %s older than the last one applied to subscription %s", event.ID, sub.ID)
+		return nil
+	}
+	return err
+}
+
+// syncPaymentMethod keeps the card details of the subscriptions charged to
+// the payment method up to date. Which payment method a subscription is
+// charged to comes with its own events.
+func (s *StripeWebhookService) syncPaymentMethod(reqCtx *app.RequestContext, eventType stripe.EventType, pm *stripe.PaymentMethod) error {
+	if eventType == stripe.EventTypePaymentMethodDetached {
+		return s.subscriptionService.DetachPaymentMethod(reqCtx, pm.ID)
+	}
+	return s.subscriptionService.SyncPaymentMethod(reqCtx, newPaymentMethod(pm))
+}
+
+func (s *StripeWebhookService) syncInvoice(reqCtx *app.RequestContext, invoice *stripe.Invoice) error {
+	org, err := s.organizationForCustomer(reqCtx, invoice.Customer)
+	if err != nil || org == nil {
+		return err
+	}
+
+	sync := newSyncInvoice(org.ID, invoice)
+	if invoice.Parent != nil && invoice.Parent.SubscriptionDetails != nil && invoice.Parent.SubscriptionDetails.Subscription != nil {
+		sub, err := s.subscriptionService.GetSubscriptionByStripeID(reqCtx, invoice.Parent.SubscriptionDetails.Subscription.ID)
+		if err != nil {
+			return err
+		}
+		if sub != nil {
+			sync.SubscriptionID = sub.ID
+		}
+	}
+
+	_, err = s.invoiceRepo.UpsertInvoice(reqCtx, sync)
+	return err
+}
+
+// organizationForCustomer resolves the organization of a Stripe customer and
+// scopes reqCtx to it. Events for unknown customers are acknowledged and dropped.
+func (s *StripeWebhookService) organizationForCustomer(reqCtx *app.RequestContext, customer *stripe.Customer) (*model.Organization, error) {
+	if customer == nil || customer.ID == "" {
+		log.Printf("ignoring stripe event without customer")
+		return nil, nil
+	}
+	org, err := s.orgService.GetOrganizationByStripeCustomerID(reqCtx, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		log.Printf("ignoring stripe event for unknown customer %s", customer.ID)
+		return nil, nil
+	}
+	reqCtx.Org = app.RequestOrg{ID: org.ID, Name: org.Name, Slug: org.Slug}
+	return org, nil
+}
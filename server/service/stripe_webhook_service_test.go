@@ -0,0 +1,254 @@
+package service_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v82/webhook"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/gaeaglobal/exto/server/mocks"
+	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
+	"github.com/gaeaglobal/exto/server/service"
+)
+
+const testWebhookSecret = "whsec_test_secret"
+
+type webhookTestDeps struct {
+	svc       *service.StripeWebhookService
+	eventRepo *mocks.MockStripeEventRepository
+	invRepo   *mocks.MockInvoiceRepository
+	subRepo   *mocks.MockSubscriptionRepository
+	orgRepo   *mocks.MockOrganizationRepo
+	planRepo  *mocks.MockPlanRepository
+	org       *model.Organization
+	plan      *model.Plan
+}
+
+func newWebhookTestDeps(ctrl *gomock.Controller) *webhookTestDeps {
+	d := &webhookTestDeps{
+		eventRepo: mocks.NewMockStripeEventRepository(ctrl),
+		invRepo:   mocks.NewMockInvoiceRepository(ctrl),
+		subRepo:   mocks.NewMockSubscriptionRepository(ctrl),
+		orgRepo:   mocks.NewMockOrganizationRepo(ctrl),
+		planRepo:  mocks.NewMockPlanRepository(ctrl),
+		plan: &model.Plan{
+			Base:          model.Base{ID: bson.NewObjectID()},
+			Code:          model.PlanCodeMonthly,
+			StripePriceID: "price_1S86biJgAi1y3OSXfjFBwHO3",
+		},
+		org: &model.Organization{
+			Base:             model.Base{ID: bson.NewObjectID()},
+			Name:             "Test Organization",
+			Slug:             "org_test-1",
+			StripeCustomerId: "cus_T7fGhIjKlMnOpQ",
+		},
+	}
+	d.svc = service.NewStripeWebhookService(testWebhookSecret, d.eventRepo, d.invRepo,
+		service.NewOrganizationService(d.orgRepo), service.NewSubscriptionService(d.subRepo), service.NewPlanService(d.planRepo))
+	return d
+}
+
+func (d *webhookTestDeps) expectOrgLookup() {
+	d.orgRepo.EXPECT().
+		GetOrganizations(gomock.Any(), gomock.Eq(bson.M{"stripe_customer_id": "cus_T7fGhIjKlMnOpQ"})).
+		Return([]*model.Organization{d.org}, nil)
+}
+
+func (d *webhookTestDeps) expectPlanLookup() {
+	d.planRepo.EXPECT().
+		GetPlanByStripePriceID(gomock.Any(), "price_1S86biJgAi1y3OSXfjFBwHO3").
+		Return(d.plan, nil)
+}
+
+// replayStripeFixture signs a recorded event from testdata/stripe the way Stripe
+// would and runs it through signature verification and processing.
+func replayStripeFixture(t *testing.T, svc *service.StripeWebhookService, name string) error {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "stripe", name+".json"))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", name, err)
+	}
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: testWebhookSecret})
+	event, err := svc.ConstructEvent(signed.Payload, signed.Header)
+	if err != nil {
+		t.Fatalf("failed to construct event from %s: %v", name, err)
+	}
+	return svc.HandleEvent(event)
+}
+
+func TestStripeWebhookSubscriptionUpdated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+
+	d.eventRepo.EXPECT().Claim(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXsub0001", "customer.subscription.updated").Return(true, nil)
+	d.expectOrgLookup()
+	d.expectPlanLookup()
+	d.subRepo.EXPECT().
+		SyncStripeSubscription(gomock.Any(), gomock.Eq(&model.SyncSubscription{
+			OrganizationID: d.org.ID,
+			StripeSubID:    "sub_1SBQ2eJgAi1y3OSXa1b2c3d4",
+			StartDate:      time.Unix(1758585600, 0).UTC(),
+			EndDate:        time.Unix(1761868800, 0).UTC(),
+			BillingCycle:   model.BillingCycleMonthly,
+			Status:         model.SubscriptionStatusPastDue,
+			IsCurrent:      true,
+			BillingPlanID:  d.plan.ID,
+			EventCreatedAt: time.Unix(1759190400, 0).UTC(),
+		})).
+		Return(&model.Subscription{}, nil)
+
+	if err := replayStripeFixture(t, d.svc, "customer.subscription.updated"); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+}
+
+func TestStripeWebhookSubscriptionDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+
+	d.eventRepo.EXPECT().Claim(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXsub0002", "customer.subscription.deleted").Return(true, nil)
+	d.expectOrgLookup()
+	d.expectPlanLookup()
+	d.subRepo.EXPECT().
+		SyncStripeSubscription(gomock.Any(), gomock.Eq(&model.SyncSubscription{
+			OrganizationID: d.org.ID,
+			StripeSubID:    "sub_1SBQ2eJgAi1y3OSXa1b2c3d4",
+			StartDate:      time.Unix(1758585600, 0).UTC(),
+			EndDate:        time.Unix(1761868800, 0).UTC(),
+			BillingCycle:   model.BillingCycleMonthly,
+			Status:         model.SubscriptionStatusCanceled,
+			IsCurrent:      false,
+			BillingPlanID:  d.plan.ID,
+			EventCreatedAt: time.Unix(1761868800, 0).UTC(),
+		})).
+		Return(&model.Subscription{}, nil)
+
+	if err := replayStripeFixture(t, d.svc, "customer.subscription.deleted"); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+}
+
+func TestStripeWebhookInvoicePaid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+	subID := bson.NewObjectID()
+
+	d.eventRepo.EXPECT().Claim(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXinv0001", "invoice.paid").Return(true, nil)
+	d.expectOrgLookup()
+	d.subRepo.EXPECT().
+		GetSubscriptionByStripeID(gomock.Any(), "sub_1SBQ2eJgAi1y3OSXa1b2c3d4").
+		Return(&model.Subscription{Base: model.Base{ID: subID}}, nil)
+	d.invRepo.EXPECT().
+		UpsertInvoice(gomock.Any(), gomock.Eq(&model.SyncInvoice{
+			OrganizationID:     d.org.ID,
+			SubscriptionID:     subID,
+			StripeInvoiceID:    "in_1SBQ2gJgAi1y3OSXe5f6g7h8",
+			InvoiceNumber:      "EXTO-0001",
+			TotalAmount:        20,
+			BillingPeriodStart: time.Unix(1756598400, 0).UTC(),
+			BillingPeriodEnd:   time.Unix(1759190400, 0).UTC(),
+			PDF_URL:            "https://pay.stripe.com/invoice/acct_test/in_1SBQ2gJgAi1y3OSXe5f6g7h8/pdf",
+			Status:             "paid",
+		})).
+		Return(&model.Invoice{}, nil)
+
+	if err := replayStripeFixture(t, d.svc, "invoice.paid"); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+}
+
+func TestStripeWebhookPaymentMethodAttached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+
+	d.eventRepo.EXPECT().Claim(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXpm00001", "payment_method.attached").Return(true, nil)
+	d.subRepo.EXPECT().
+		SyncPaymentMethod(gomock.Any(), gomock.Eq(&model.PaymentMethod{
+			StripeID: "pm_1SBQ2dJgAi1y3OSXcard0001",
+			Type:     "card",
+			Brand:    "visa",
+			Last4:    "4242",
+			ExpMonth: 12,
+			ExpYear:  2030,
+		})).
+		Return(nil)
+
+	if err := replayStripeFixture(t, d.svc, "payment_method.attached"); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+}
+
+func TestStripeWebhookPaymentMethodDetached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+
+	d.eventRepo.EXPECT().Claim(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXpm00002", "payment_method.detached").Return(true, nil)
+	d.subRepo.EXPECT().DetachPaymentMethod(gomock.Any(), "pm_1SBQ2dJgAi1y3OSXcard0001").Return(nil)
+
+	if err := replayStripeFixture(t, d.svc, "payment_method.detached"); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+}
+
+func TestStripeWebhookSkipsOutOfOrderSubscriptionEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+
+	// The deletion was applied first: the update, created before it, must
+	// neither bring the subscription back nor be retried.
+	d.eventRepo.EXPECT().Claim(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXsub0001", "customer.subscription.updated").Return(true, nil)
+	d.expectOrgLookup()
+	d.expectPlanLookup()
+	d.subRepo.EXPECT().SyncStripeSubscription(gomock.Any(), gomock.Any()).Return(nil, repo.ErrStaleStripeEvent)
+
+	if err := replayStripeFixture(t, d.svc, "customer.subscription.updated"); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+}
+
+func TestStripeWebhookSkipsDuplicateEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+
+	// A redelivered event is already claimed, so nothing else may be touched.
+	d.eventRepo.EXPECT().Claim(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXinv0001", "invoice.paid").Return(false, nil)
+
+	if err := replayStripeFixture(t, d.svc, "invoice.paid"); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+}
+
+func TestStripeWebhookReleasesEventOnFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+
+	d.eventRepo.EXPECT().Claim(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXsub0001", "customer.subscription.updated").Return(true, nil)
+	d.expectOrgLookup()
+	d.expectPlanLookup()
+	d.subRepo.EXPECT().SyncStripeSubscription(gomock.Any(), gomock.Any()).Return(nil, errors.New("failed to sync subscription"))
+	d.eventRepo.EXPECT().Release(gomock.Any(), "evt_1SBQ2fJgAi1y3OSXsub0001").Return(nil)
+
+	if err := replayStripeFixture(t, d.svc, "customer.subscription.updated"); err == nil {
+		t.Fatal("HandleEvent() expected error, got nil")
+	}
+}
+
+func TestStripeWebhookRejectsInvalidSignature(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	d := newWebhookTestDeps(ctrl)
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "stripe", "invoice.paid.json"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: "whsec_other_secret"})
+	if _, err := d.svc.ConstructEvent(signed.Payload, signed.Header); !errors.Is(err, service.ErrInvalidWebhookSignature) {
+		t.Fatalf("ConstructEvent() error = %v, want %v", err, service.ErrInvalidWebhookSignature)
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// ErrNoCurrentSubscription is returned when the organization has no current
+// subscription.
+var ErrNoCurrentSubscription = repo.ErrNoCurrentSubscription
+
 type SubscriptionService struct {
 	r repo.SubscriptionRepository
 }
@@ -28,3 +32,19 @@ func (s *SubscriptionService) UpdateSubscription(reqCtx *app.RequestContext, id
 func (s *SubscriptionService) GetMySubscription(reqCtx *app.RequestContext) (*model.Subscription, error) {
 	return s.r.GetMySubscription(reqCtx)
 }
+
+func (s *SubscriptionService) GetSubscriptionByStripeID(reqCtx *app.RequestContext, stripeSubID string) (*model.Subscription, error) {
+	return s.r.GetSubscriptionByStripeID(reqCtx, stripeSubID)
+}
+
+func (s *SubscriptionService) SyncStripeSubscription(reqCtx *app.RequestContext, sync *model.SyncSubscription) (*model.Subscription, error) {
+	return s.r.SyncStripeSubscription(reqCtx, sync)
+}
+
+func (s *SubscriptionService) SyncPaymentMethod(reqCtx *app.RequestContext, paymentMethod *model.PaymentMethod) error {
+	return s.r.SyncPaymentMethod(reqCtx, paymentMethod)
+}
+
+func (s *SubscriptionService) DetachPaymentMethod(reqCtx *app.RequestContext, stripePaymentMethodID string) error {
+	return s.r.DetachPaymentMethod(reqCtx, stripePaymentMethodID)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gaeaglobal/exto/server/app"
+	"github.com/gaeaglobal/exto/server/file_utils"
+)
+
+// UploadService checks the documents uploaded to an organization before they
+// are stored: their type, the size and page limits of its plan and, when a
+// scanner is configured, malware. Infected files are moved to quarantine.
+type UploadService struct {
+	appCtx       *app.AppContext
+	quotaService *QuotaService
+	scanner      file_utils.MalwareScanner
+}
+
+// NewUploadService returns the upload service; scanner may be nil, in which
+// case the uploads are not scanned for malware.
+func NewUploadService(appCtx *app.AppContext, quotaService *QuotaService, scanner file_utils.MalwareScanner) *UploadService {
+	return &UploadService{
+		appCtx:       appCtx,
+		quotaService: quotaService,
+		scanner:      scanner,
+	}
+}
+
+// SaveUploadedFile validates the file of the "file" form field and stores it as
+// a new document of the organization. A rejected file gives a
+// *file_utils.UploadError.
+func (s *UploadService) SaveUploadedFile(c *gin.Context, reqCtx *app.RequestContext) (*file_utils.Upload, error) {
+	limits, err := s.quotaService.UploadLimits(reqCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload limits: %w", err)
+	}
+	fileName, content, err := file_utils.ReadUploadedFile(c, limits.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file_utils.ValidateUpload(content, limits); err != nil {
+		return nil, err
+	}
+
+	upload := &file_utils.Upload{
+		Key:      file_utils.NewUploadKey(reqCtx.User.OrganizationID, fileName),
+		FileName: fileName,
+		Content:  content,
+	}
+	if s.scanner != nil {
+		verdict, err := s.scanner.Scan(reqCtx.Context(), content)
+		if err != nil {
+			log.Printf("failed to scan upload %s for malware: %v", upload.Key, err)
+			return nil, &file_utils.UploadError{Code: file_utils.UploadMalwareScanFailed, Msg: "the file could not be scanned for malware, try again later"}
+		}
+		if verdict.Infected {
+			s.quarantine(reqCtx, upload, verdict.Signature)
+			return nil, &file_utils.UploadError{Code: file_utils.UploadMalwareDetected, Msg: "the file contains malware and was rejected"}
+		}
+	}
+
+	if err := file_utils.StoreUpload(reqCtx.Context(), s.appCtx.Blobs, upload.Key, content); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// DeleteUpload removes the stored document of a scan that failed. A failure is
+// only logged: the retention job removes what is left behind.
+func (s *UploadService) DeleteUpload(reqCtx *app.RequestContext, upload *file_utils.Upload) {
+	if err := s.appCtx.Blobs.Delete(reqCtx.Context(), upload.Key); err != nil {
+		log.Printf("failed to delete upload %s of a failed scan: %v", upload.Key, err)
+	}
+}
+
+// quarantine keeps the infected file out of the uploads of the organization,
+// for an administrator to look into.
+func (s *UploadService) quarantine(reqCtx *app.RequestContext, upload *file_utils.Upload, signature string) {
+	key := file_utils.QuarantineKey(upload.Key)
+	log.Printf("malware %s found in file %q uploaded by user %s of organization %s, quarantined as %s",
+		signature, upload.FileName, reqCtx.User.ID.Hex(), reqCtx.User.OrganizationID.Hex(), key)
+	if err := file_utils.StoreUpload(reqCtx.Context(), s.appCtx.Blobs, key, upload.Content); err != nil {
+		log.Printf("failed to quarantine infected file %s: %v", key, err)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
@@ -12,6 +13,8 @@ import (
 	"github.com/gaeaglobal/exto/server/repo"
 )
 
+var ErrNotMember = errors.New("user is not a member of the organization")
+
 type UserService struct {
 	dbSessionProvider  db.SessionProvider
 	repo               repo.UserRepo
@@ -30,75 +33,83 @@ func NewUserService(dbSessionProvider db.SessionProvider, repo repo.UserRepo, id
 	}
 }
 
+// CreateAndSetupIdentity signs up a new user: the identity, a personal
+// organization and its admin user are created in one transaction. The sign-up
+// is added to the Google Sheet once the transaction has committed. When the
+// slug of the organization is taken by a concurrent sign-up, the insert
+// aborts the transaction, which is then run again with another slug.
 func (s *UserService) CreateAndSetupIdentity(appCtx *app.AppContext, reqCtx *app.RequestContext, email string, firstName, lastName string) (*app.RequestContext, error) {
-
-	session, err := s.dbSessionProvider.StartSession()
-	if err != nil {
-		return nil, err
-	}
-	defer session.EndSession(reqCtx.Context())
-
-	err = session.StartTransaction()
-	if err != nil {
-		return nil, err
+	for attempt := 1; ; attempt++ {
+		signedUp, err := s.createAndSetupIdentity(reqCtx, email, firstName, lastName)
+		if !errors.Is(err, repo.ErrSlugTaken) || reqCtx.InTransaction() {
+			return signedUp, err
+		}
+		if attempt == maxSlugAttempts {
+			log.Printf("failed to allocate an organization slug for a sign-up after %d attempts", attempt)
+			return nil, errors.New("failed to allocate organization slug")
+		}
 	}
+}
 
-	newOrgID := bson.NewObjectID()
-	// Create Identity
-	identity, err := s.identityService.CreateIdentity(reqCtx, &model.CreateIdentity{
-		Email:        email,
-		FirstName:    firstName,
-		LastName:     lastName,
-		CurrentOrgID: newOrgID,
-	})
+func (s *UserService) createAndSetupIdentity(reqCtx *app.RequestContext, email string, firstName, lastName string) (*app.RequestContext, error) {
+	slug, err := personalOrgSlug(firstName, lastName)
 	if err != nil {
-		_ = session.AbortTransaction(reqCtx.Context())
 		return nil, err
 	}
 
-	log.Println("Created Identity:", identity.ID.Hex())
+	var identity *model.Identity
+	var organization *model.Organization
+	var user *model.User
+	err = app.WithTransaction(reqCtx, s.dbSessionProvider, func(txCtx *app.RequestContext) error {
+		newOrgID := bson.NewObjectID()
+		// Create Identity
+		var err error
+		identity, err = s.identityService.CreateIdentity(txCtx, &model.CreateIdentity{
+			Email:        email,
+			FirstName:    firstName,
+			LastName:     lastName,
+			CurrentOrgID: newOrgID,
+		})
+		if err != nil {
+			return err
+		}
 
-	// Create Organization
-	orgCount, err := s.orgService.GetOrganizationCount(reqCtx)
-	if err != nil {
-		log.Println("Error getting organization count:", err)
-		orgCount = 0 // Default to 0 if there's an error
-	}
-	organization, err := s.orgService.CreateOrganization(reqCtx, &model.CreateOrganization{
-		ID:   newOrgID,
-		Name: fmt.Sprintf("%s's Organization", email),
-		Slug: fmt.Sprintf("org_%d", orgCount+1),
-	}, identity.ID)
-	if err != nil {
-		_ = session.AbortTransaction(reqCtx.Context())
-		return nil, err
-	}
+		log.Println("Created Identity:", identity.ID.Hex())
 
-	// Create User
-	if exists, err := s.repo.IsUserExists(reqCtx, organization.ID, email); err != nil {
-		_ = session.AbortTransaction(reqCtx.Context())
-		return nil, err
-	} else if exists {
-		_ = session.AbortTransaction(reqCtx.Context())
-		return nil, fmt.Errorf("user with email %s already exists in organization %s", email, organization.Name)
-	}
-	user, err := s.repo.CreateUser(reqCtx, identity.ID, &model.CreateUser{
-		Email:          email,
-		FirstName:      firstName,
-		LastName:       lastName,
-		Role:           model.RoleOrganizationAdmin,
-		IdentityID:     identity.ID,
-		OrganizationID: organization.ID,
-	})
+		// Create Organization
+		organization, err = s.orgService.CreateOrganization(txCtx, &model.CreateOrganization{
+			ID:   newOrgID,
+			Name: fmt.Sprintf("%s's Organization", email),
+			Slug: slug,
+		}, identity.ID)
+		if err != nil {
+			return err
+		}
 
-	s.GoogleSheetService.SyncSheet("example-sheet-id", organization.Name, organization.Slug, user.FirstName, user.LastName, user.Email, identity.CreatedAt)
+		// Create User
+		if exists, err := s.repo.IsUserExists(txCtx, organization.ID, email); err != nil {
+			return err
+		} else if exists {
+			return fmt.Errorf("user with email %s already exists in organization %s", email, organization.Name)
+		}
+		user, err = s.repo.CreateUser(txCtx, identity.ID, &model.CreateUser{
+			Email:          email,
+			FirstName:      firstName,
+			LastName:       lastName,
+			Role:           model.RoleOrganizationAdmin,
+			IdentityID:     identity.ID,
+			OrganizationID: organization.ID,
+		})
+		if err != nil {
+			return err
+		}
 
+		txCtx.AfterCommit(func() {
+			s.GoogleSheetService.SyncSheet("example-sheet-id", organization.Name, organization.Slug, user.FirstName, user.LastName, user.Email, identity.CreatedAt)
+		})
+		return nil
+	})
 	if err != nil {
-		_ = session.AbortTransaction(reqCtx.Context())
-		return nil, err
-	}
-
-	if err := session.CommitTransaction(reqCtx.Context()); err != nil {
 		return nil, err
 	}
 
@@ -123,28 +134,134 @@ func (s *UserService) CreateAndSetupIdentity(appCtx *app.AppContext, reqCtx *app
 	return _reqCtx, nil
 }
 
-func (s *UserService) DeleteUser(reqCtx *app.RequestContext) error {
-	// Delete the organization if owned by the user
-	orgs, err := s.orgService.DeleteOrganizationByOwner(reqCtx)
+// personalOrgSlug derives the slug of the organization created at sign-up
+// from the name of the user. The email is never used: the slug names the
+// database of the organization and shows in logs, exports and URLs. Without a
+// usable name, the slug is random.
+func personalOrgSlug(firstName, lastName string) (string, error) {
+	if slug := model.SlugFromName(firstName + " " + lastName + " org"); slug != "org" {
+		return slug, nil
+	}
+	suffix, err := newSlugSuffix()
 	if err != nil {
-		return err
+		return "", err
 	}
+	return "org-" + suffix, nil
+}
 
-	// Delete the users in the organizations
-	var orgIDs []bson.ObjectID
-	for _, org := range orgs {
-		orgIDs = append(orgIDs, org.ID)
+func (s *UserService) ListOrganizationUsers(reqCtx *app.RequestContext, pageReq *app.PageRequest) (*app.PageResponse[*model.User], error) {
+	return s.repo.GetUsersByOrgID(reqCtx, reqCtx.Org.ID, pageReq)
+}
+
+// ChangeUserRole changes the role of a member of the current organization.
+// Users cannot change their own role so an organization keeps its admin.
+func (s *UserService) ChangeUserRole(reqCtx *app.RequestContext, userID bson.ObjectID, role model.UserRole) (*model.User, error) {
+	if !role.IsValid() || role == model.RoleSuperAdmin {
+		return nil, ErrInvalidRole
 	}
-	if len(orgIDs) > 0 {
-		if err := s.repo.DeleteUserByOrgIDs(reqCtx, orgIDs); err != nil {
-			return err
+	user, err := s.getOrganizationMember(reqCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateUser(reqCtx, user.ID, &model.UpdateUser{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      role,
+		IsActive:  user.IsActive,
+	})
+}
+
+// DeactivateUser removes a member's access to the current organization. The
+// member can be invited again later.
+func (s *UserService) DeactivateUser(reqCtx *app.RequestContext, userID bson.ObjectID) (*model.User, error) {
+	user, err := s.getOrganizationMember(reqCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateUser(reqCtx, user.ID, &model.UpdateUser{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      user.Role,
+		IsActive:  false,
+	})
+}
+
+func (s *UserService) getOrganizationMember(reqCtx *app.RequestContext, userID bson.ObjectID) (*model.User, error) {
+	if userID == reqCtx.User.ID {
+		return nil, errors.New("users cannot change their own membership")
+	}
+	user, err := s.repo.GetUserByID(reqCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.OrganizationID != reqCtx.Org.ID {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// ListMyOrganizations returns the active organizations the signed in identity
+// is an active member of.
+func (s *UserService) ListMyOrganizations(reqCtx *app.RequestContext) ([]*model.OrganizationMembership, error) {
+	identity, err := s.identityService.GetIdentityByEmail(reqCtx, reqCtx.User.Email)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.repo.GetUsersByIdentityID(reqCtx, identity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[bson.ObjectID]model.UserRole, len(users))
+	orgIDs := make([]bson.ObjectID, 0, len(users))
+	for _, user := range users {
+		if !user.IsActive {
+			continue
 		}
+		roles[user.OrganizationID] = user.Role
+		orgIDs = append(orgIDs, user.OrganizationID)
+	}
+	memberships := []*model.OrganizationMembership{}
+	if len(orgIDs) == 0 {
+		return memberships, nil
 	}
 
-	// Delete the identity
-	if err := s.identityService.DeleteIdentity(reqCtx); err != nil {
-		return err
+	orgs, err := s.orgService.GetOrganizationsByIDs(reqCtx, orgIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, org := range orgs {
+		if !org.IsActive {
+			continue
+		}
+		memberships = append(memberships, &model.OrganizationMembership{
+			OrganizationID: org.ID,
+			Name:           org.Name,
+			Slug:           org.Slug,
+			Role:           roles[org.ID],
+			IsCurrent:      org.ID == identity.CurrentOrgID,
+		})
 	}
+	return memberships, nil
+}
 
-	return nil
+// SwitchOrganization makes the organization the current organization of the
+// signed in identity. The identity must be an active member of it.
+func (s *UserService) SwitchOrganization(reqCtx *app.RequestContext, orgID bson.ObjectID) (*model.OrganizationMembership, error) {
+	memberships, err := s.ListMyOrganizations(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		if membership.OrganizationID != orgID {
+			continue
+		}
+		if err := s.identityService.SetCurrentOrg(reqCtx, reqCtx.User.IdentityID, orgID); err != nil {
+			return nil, err
+		}
+		reqCtx.AfterCommit(func() { db.DeleteCacheUser(reqCtx.User.Email) })
+		membership.IsCurrent = true
+		return membership, nil
+	}
+	return nil, ErrNotMember
 }
@@ -1,6 +1,9 @@
 package service_test
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 	"github.com/gaeaglobal/exto/server/app"
 	"github.com/gaeaglobal/exto/server/mocks"
 	"github.com/gaeaglobal/exto/server/model"
+	"github.com/gaeaglobal/exto/server/repo"
 	"github.com/gaeaglobal/exto/server/service"
 )
 
@@ -25,7 +29,7 @@ func TestCreateAndSetupIdentity(t *testing.T) {
 	newIdentityID := bson.NewObjectID()
 	newOrgID := bson.NewObjectID()
 	identityMockRepo.EXPECT().
-		CreateIdentity(gomock.Eq(reqCtx), matchCreateIdentity(&model.CreateIdentity{
+		CreateIdentity(inTransaction(), matchCreateIdentity(&model.CreateIdentity{
 			Email:     "test@example.com",
 			FirstName: "Test",
 			LastName:  "User",
@@ -44,14 +48,14 @@ func TestCreateAndSetupIdentity(t *testing.T) {
 			CurrentOrgID: newOrgID,
 		}, nil)
 	identityMockRepo.EXPECT().
-		IsIdentityExists(gomock.Eq(reqCtx), gomock.Eq("test@example.com")).
+		IsIdentityExists(inTransaction(), gomock.Eq("test@example.com")).
 		Return(false, nil)
 
 	orgMockRepo := mocks.NewMockOrganizationRepo(ctrl)
 	orgMockRepo.EXPECT().
-		CreateOrganization(gomock.Eq(reqCtx), matchCreateOrganization(&model.CreateOrganization{
+		CreateOrganization(inTransaction(), matchCreateOrganization(&model.CreateOrganization{
 			Name: "test@example.com's Organization",
-			Slug: "test@example.com-org",
+			Slug: "test-user-org",
 		}), gomock.Eq(newIdentityID)).
 		Return(&model.Organization{
 			Base: model.Base{
@@ -62,21 +66,19 @@ func TestCreateAndSetupIdentity(t *testing.T) {
 				UpdatedBy: newIdentityID,
 			},
 			Name:        "test@example.com's Organization",
-			Slug:        "test@example.com-org",
+			Slug:        "test-user-org",
 			IsActive:    true,
 			OwnerID:     newIdentityID,
 			ScanCounter: 0,
 		}, nil)
 	orgMockRepo.EXPECT().
-		IsOrganizationExists(gomock.Eq(reqCtx), gomock.Eq("test@example.com's Organization")).
+		IsOrganizationExists(inTransaction(), gomock.Eq("test@example.com's Organization")).
 		Return(false, nil)
-
-	orgMockRepo.EXPECT().GetOrganizationCount(gomock.Eq(reqCtx)).
-		Return(int64(0), nil)
+	orgMockRepo.EXPECT().IsSlugTaken(inTransaction(), "test-user-org").Return(false, nil)
 
 	userMockRepo := mocks.NewMockUserRepo(ctrl)
 	userMockRepo.EXPECT().
-		CreateUser(gomock.Eq(reqCtx), gomock.Eq(newIdentityID), gomock.Eq(&model.CreateUser{
+		CreateUser(inTransaction(), gomock.Eq(newIdentityID), gomock.Eq(&model.CreateUser{
 			Email:          "test@example.com",
 			FirstName:      "Test",
 			LastName:       "User",
@@ -99,11 +101,12 @@ func TestCreateAndSetupIdentity(t *testing.T) {
 			IdentityID:     newIdentityID,
 			OrganizationID: newOrgID,
 		}, nil)
-	userMockRepo.EXPECT().IsUserExists(gomock.Eq(reqCtx), gomock.Eq(newOrgID), gomock.Eq("test@example.com")).
+	userMockRepo.EXPECT().IsUserExists(inTransaction(), gomock.Eq(newOrgID), gomock.Eq("test@example.com")).
 		Return(false, nil)
 
 	mockSession := mocks.NewMockMongoSession(ctrl)
 	mockSession.EXPECT().StartTransaction().Return(nil)
+	mockSession.EXPECT().Context(gomock.Any()).DoAndReturn(func(ctx context.Context) context.Context { return ctx })
 	mockSession.EXPECT().EndSession(gomock.Any())
 	mockSession.EXPECT().AbortTransaction(gomock.Any()).Times(0)
 	mockSession.EXPECT().CommitTransaction(gomock.Any()).Return(nil)
@@ -150,6 +153,101 @@ func TestCreateAndSetupIdentity(t *testing.T) {
 
 }
 
+func TestCreateAndSetupIdentityRestartsOnSlugCollision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	userRepo := mocks.NewMockUserRepo(ctrl)
+
+	// The first transaction loses the slug to a concurrent sign-up: the
+	// failed insert aborts it, and the second one runs with another slug.
+	session := mocks.NewMockMongoSession(ctrl)
+	session.EXPECT().Context(gomock.Any()).AnyTimes().DoAndReturn(func(ctx context.Context) context.Context { return ctx })
+	session.EXPECT().StartTransaction().Times(2).Return(nil)
+	session.EXPECT().AbortTransaction(gomock.Any()).Return(nil)
+	session.EXPECT().CommitTransaction(gomock.Any()).Return(nil)
+	session.EXPECT().EndSession(gomock.Any()).Times(2)
+	sessions := mocks.NewMockSessionProvider(ctrl)
+	sessions.EXPECT().StartSession().Times(2).Return(session, nil)
+
+	email := "jane@acme.com"
+	identityRepo.EXPECT().IsIdentityExists(inTransaction(), email).Times(2).Return(false, nil)
+	identityRepo.EXPECT().CreateIdentity(inTransaction(), gomock.Any()).Times(2).DoAndReturn(
+		func(_ *app.RequestContext, create *model.CreateIdentity) (*model.Identity, error) {
+			return &model.Identity{Base: model.Base{ID: bson.NewObjectID()}, Email: create.Email, CurrentOrgID: create.CurrentOrgID}, nil
+		})
+	orgRepo.EXPECT().IsOrganizationExists(inTransaction(), gomock.Any()).Times(2).Return(false, nil)
+	base := "jane-doe-org"
+	gomock.InOrder(
+		orgRepo.EXPECT().IsSlugTaken(inTransaction(), base).Return(false, nil),
+		orgRepo.EXPECT().CreateOrganization(inTransaction(), gomock.Any(), gomock.Any()).Return(nil, repo.ErrSlugTaken),
+		orgRepo.EXPECT().IsSlugTaken(inTransaction(), base).Return(true, nil),
+		orgRepo.EXPECT().IsSlugTaken(inTransaction(), gomock.Any()).Return(false, nil),
+		orgRepo.EXPECT().CreateOrganization(inTransaction(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ *app.RequestContext, org *model.CreateOrganization, _ bson.ObjectID) (*model.Organization, error) {
+				return &model.Organization{Base: model.Base{ID: org.ID}, Name: org.Name, Slug: org.Slug}, nil
+			}),
+	)
+	userRepo.EXPECT().IsUserExists(inTransaction(), gomock.Any(), email).Return(false, nil)
+	userRepo.EXPECT().CreateUser(inTransaction(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, identityID bson.ObjectID, create *model.CreateUser) (*model.User, error) {
+			return &model.User{Base: model.Base{ID: bson.NewObjectID()}, IdentityID: identityID, Email: create.Email, OrganizationID: create.OrganizationID}, nil
+		})
+
+	userService := service.NewUserService(sessions, userRepo, service.NewIdentityService(identityRepo), service.NewOrganizationService(orgRepo), service.NewGoogleSheetService())
+	signedUp, err := userService.CreateAndSetupIdentity(app.NewMockAppContext(), &app.RequestContext{}, email, "Jane", "Doe")
+	if err != nil {
+		t.Fatalf("CreateAndSetupIdentity returned error: %v", err)
+	}
+	if !strings.HasPrefix(signedUp.Org.Slug, base+"-") {
+		t.Errorf("expected a suffixed slug after the collision, got %q", signedUp.Org.Slug)
+	}
+}
+
+func TestCreateAndSetupIdentityKeepsEmailOutOfSlug(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	userRepo := mocks.NewMockUserRepo(ctrl)
+
+	session := mocks.NewMockMongoSession(ctrl)
+	session.EXPECT().Context(gomock.Any()).AnyTimes().DoAndReturn(func(ctx context.Context) context.Context { return ctx })
+	session.EXPECT().StartTransaction().Return(nil)
+	session.EXPECT().CommitTransaction(gomock.Any()).Return(nil)
+	session.EXPECT().EndSession(gomock.Any())
+	sessions := mocks.NewMockSessionProvider(ctrl)
+	sessions.EXPECT().StartSession().Return(session, nil)
+
+	email := "jane.doe@acme.com"
+	identityRepo.EXPECT().IsIdentityExists(inTransaction(), email).Return(false, nil)
+	identityRepo.EXPECT().CreateIdentity(inTransaction(), gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, create *model.CreateIdentity) (*model.Identity, error) {
+			return &model.Identity{Base: model.Base{ID: bson.NewObjectID()}, Email: create.Email, CurrentOrgID: create.CurrentOrgID}, nil
+		})
+	orgRepo.EXPECT().IsOrganizationExists(inTransaction(), gomock.Any()).Return(false, nil)
+	orgRepo.EXPECT().IsSlugTaken(inTransaction(), gomock.Any()).Return(false, nil)
+	orgRepo.EXPECT().CreateOrganization(inTransaction(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, org *model.CreateOrganization, _ bson.ObjectID) (*model.Organization, error) {
+			return &model.Organization{Base: model.Base{ID: org.ID}, Name: org.Name, Slug: org.Slug}, nil
+		})
+	userRepo.EXPECT().IsUserExists(inTransaction(), gomock.Any(), email).Return(false, nil)
+	userRepo.EXPECT().CreateUser(inTransaction(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ *app.RequestContext, identityID bson.ObjectID, create *model.CreateUser) (*model.User, error) {
+			return &model.User{Base: model.Base{ID: bson.NewObjectID()}, IdentityID: identityID, Email: create.Email, OrganizationID: create.OrganizationID}, nil
+		})
+
+	// Without a name to derive it from, the slug is random.
+	userService := service.NewUserService(sessions, userRepo, service.NewIdentityService(identityRepo), service.NewOrganizationService(orgRepo), service.NewGoogleSheetService())
+	signedUp, err := userService.CreateAndSetupIdentity(app.NewMockAppContext(), &app.RequestContext{}, email, "", "")
+	if err != nil {
+		t.Fatalf("CreateAndSetupIdentity returned error: %v", err)
+	}
+	slug := signedUp.Org.Slug
+	if !strings.HasPrefix(slug, "org-") || strings.Contains(slug, "jane") || strings.Contains(slug, "acme") || !model.IsValidSlug(slug) {
+		t.Errorf("expected a random slug without the email, got %q", slug)
+	}
+}
+
 func matchCreateIdentity(expected *model.CreateIdentity) gomock.Matcher {
 	return gomock.Cond(func(x interface{}) bool {
 		arg, ok := x.(*model.CreateIdentity)
@@ -166,6 +264,15 @@ func matchCreateIdentity(expected *model.CreateIdentity) gomock.Matcher {
 	})
 }
 
+// inTransaction matches the request context of a transaction started with
+// app.WithTransaction.
+func inTransaction() gomock.Matcher {
+	return gomock.Cond(func(x interface{}) bool {
+		reqCtx, ok := x.(*app.RequestContext)
+		return ok && reqCtx.InTransaction()
+	})
+}
+
 func matchCreateOrganization(expected *model.CreateOrganization) gomock.Matcher {
 	return gomock.Cond(func(x interface{}) bool {
 		arg, ok := x.(*model.CreateOrganization)
@@ -180,3 +287,41 @@ func matchCreateOrganization(expected *model.CreateOrganization) gomock.Matcher
 			!arg.ID.IsZero()
 	})
 }
+
+func TestSwitchOrganization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	identityRepo := mocks.NewMockIdentityRepo(ctrl)
+	userRepo := mocks.NewMockUserRepo(ctrl)
+	orgRepo := mocks.NewMockOrganizationRepo(ctrl)
+	userService := service.NewUserService(mocks.NewMockSessionProvider(ctrl), userRepo, service.NewIdentityService(identityRepo), service.NewOrganizationService(orgRepo), service.NewGoogleSheetService())
+
+	reqCtx := app.NewMockRequestContext()
+	currentOrgID := reqCtx.Org.ID
+	otherOrgID := bson.NewObjectID()
+	identity := &model.Identity{Base: model.Base{ID: reqCtx.User.IdentityID}, Email: reqCtx.User.Email, CurrentOrgID: currentOrgID}
+
+	identityRepo.EXPECT().GetIdentityByEmail(gomock.Any(), reqCtx.User.Email).Return(identity, nil).Times(2)
+	userRepo.EXPECT().GetUsersByIdentityID(gomock.Any(), identity.ID).Return([]*model.User{
+		{OrganizationID: currentOrgID, Role: model.RoleOrganizationAdmin, IsActive: true},
+		{OrganizationID: otherOrgID, Role: model.RoleMember, IsActive: true},
+	}, nil).Times(2)
+	orgRepo.EXPECT().GetOrganizations(gomock.Any(), gomock.Any()).Return([]*model.Organization{
+		{Base: model.Base{ID: currentOrgID}, Name: "Current", IsActive: true},
+		{Base: model.Base{ID: otherOrgID}, Name: "Other", IsActive: true},
+	}, nil).Times(2)
+	identityRepo.EXPECT().SetCurrentOrg(gomock.Any(), identity.ID, otherOrgID).Return(nil)
+
+	membership, err := userService.SwitchOrganization(reqCtx, otherOrgID)
+	if err != nil {
+		t.Fatalf("SwitchOrganization returned error: %v", err)
+	}
+	if !membership.IsCurrent || membership.Role != model.RoleMember {
+		t.Errorf("unexpected membership %+v", membership)
+	}
+
+	if _, err := userService.SwitchOrganization(reqCtx, bson.NewObjectID()); !errors.Is(err, service.ErrNotMember) {
+		t.Fatalf("expected ErrNotMember, got %v", err)
+	}
+}
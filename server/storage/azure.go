@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	azureVersion    = "2021-08-06"
+	azureTimeFormat = "2006-01-02T15:04:05Z"
+)
+
+// AzureBlobStore keeps the blobs as block blobs of a container of Azure Blob
+// Storage, or of the Azurite emulator. Requests are authorized with the
+// shared key of the account and links with service SAS tokens.
+type AzureBlobStore struct {
+	endpoint  *url.URL
+	account   string
+	key       []byte
+	container string
+}
+
+// NewAzureBlobStore creates a store for the container. The endpoint defaults
+// to the one of the account in the public cloud; for Azurite it is like
+// http://127.0.0.1:10000/devstoreaccount1.
+func NewAzureBlobStore(endpoint, account, accountKey, container string) (*AzureBlobStore, error) {
+	if account == "" || accountKey == "" || container == "" {
+		return nil, errors.New("azure blob store needs an account, its key and a container")
+	}
+	key, err := base64.StdEncoding.DecodeString(accountKey)
+	if err != nil {
+		return nil, errors.New("invalid azure storage account key")
+	}
+	if endpoint == "" {
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid azure blob endpoint %q", endpoint)
+	}
+	return &AzureBlobStore{endpoint: u, account: account, key: key, container: container}, nil
+}
+
+// blobURL returns the URL of the blob with the key, or of the container when
+// the key is empty, with its path already encoded.
+func (s *AzureBlobStore) blobURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	path := u.Path + "/" + s.container
+	if key != "" {
+		path += "/" + key
+	}
+	u.Path = path
+	u.RawPath = uriEncode(path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (s *AzureBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	body := r
+	if size == 0 {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.blobURL(key, nil).String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError("azure", req.Method, key, resp)
+	}
+	return nil
+}
+
+func (s *AzureBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error) {
+	if err := checkKey(key); err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.blobURL(key, nil).String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, nil, ErrBlobNotFound
+		}
+		return nil, nil, responseError("azure", req.Method, key, resp)
+	}
+	return resp.Body, responseBlobInfo(key, resp), nil
+}
+
+func (s *AzureBlobStore) Stat(ctx context.Context, key string) (*BlobInfo, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.blobURL(key, nil).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return responseBlobInfo(key, resp), nil
+	case http.StatusNotFound:
+		return nil, ErrBlobNotFound
+	default:
+		return nil, responseError("azure", req.Method, key, resp)
+	}
+}
+
+func (s *AzureBlobStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.blobURL(key, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNotFound {
+		return responseError("azure", req.Method, key, resp)
+	}
+	return nil
+}
+
+func (s *AzureBlobStore) DeletePrefix(ctx context.Context, prefix string) error {
+	if err := checkPrefix(prefix); err != nil {
+		return err
+	}
+	var keys []string
+	err := s.List(ctx, prefix, func(info BlobInfo) error {
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type azureListResult struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			ContentLength int64  `xml:"Content-Length"`
+			ContentType   string `xml:"Content-Type"`
+			LastModified  string `xml:"Last-Modified"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+func (s *AzureBlobStore) List(ctx context.Context, prefix string, fn func(info BlobInfo) error) error {
+	if err := checkPrefix(prefix); err != nil {
+		return err
+	}
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.blobURL("", query).String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := responseError("azure", "LIST", prefix, resp)
+			resp.Body.Close()
+			return err
+		}
+		var result azureListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("azure LIST %s: %w", prefix, err)
+		}
+
+		for _, blob := range result.Blobs {
+			info := BlobInfo{Key: blob.Name, Size: blob.Properties.ContentLength, ContentType: blob.Properties.ContentType}
+			if modified, err := http.ParseTime(blob.Properties.LastModified); err == nil {
+				info.LastModified = modified
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+		if result.NextMarker == "" {
+			return nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+// PresignGet signs a read of the blob with a service SAS token.
+func (s *AzureBlobStore) PresignGet(ctx context.Context, key string, fileName string, ttl time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 {
+		return "", fmt.Errorf("invalid presigned link lifetime %s", ttl)
+	}
+	expiry := utcNow().Add(ttl).Format(azureTimeFormat)
+	disposition := ""
+	if fileName != "" {
+		disposition = attachmentDisposition(fileName)
+	}
+
+	resource := "/blob/" + s.account + "/" + s.container + "/" + key
+
+	// The fields of the string to sign of a blob SAS of this version, in
+	// order; the unused ones are left empty.
+	stringToSign := strings.Join([]string{
+		"r",          // signed permissions
+		"",           // signed start
+		expiry,       // signed expiry
+		resource,     // canonicalized resource
+		"",           // signed identifier
+		"",           // signed IP
+		"",           // signed protocol
+		azureVersion, // signed version
+		"b",          // signed resource
+		"",           // signed snapshot time
+		"",           // signed encryption scope
+		"",           // response cache control
+		disposition,  // response content disposition
+		"",           // response content encoding
+		"",           // response content language
+		"",           // response content type
+	}, "\n")
+
+	query := url.Values{
+		"sv":  {azureVersion},
+		"sr":  {"b"},
+		"sp":  {"r"},
+		"se":  {expiry},
+		"sig": {base64.StdEncoding.EncodeToString(hmacSHA256(s.key, stringToSign))},
+	}
+	if disposition != "" {
+		query.Set("rscd", disposition)
+	}
+	return s.blobURL(key, query).String(), nil
+}
+
+// do authorizes the request with the shared key of the account and sends it.
+func (s *AzureBlobStore) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Ms-Date", utcNow().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureVersion)
+	req.Header.Set("Authorization", "SharedKey "+s.account+":"+s.signature(req))
+	return blobHTTPClient.Do(req)
+}
+
+// signature signs the standard headers, the x-ms- headers and the resource
+// of the request with the key of the account.
+func (s *AzureBlobStore) signature(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-Md5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, replaced by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		azureCanonicalHeaders(req.Header) + s.canonicalResource(req.URL),
+	}, "\n")
+	return base64.StdEncoding.EncodeToString(hmacSHA256(s.key, stringToSign))
+}
+
+// azureCanonicalHeaders lists the x-ms- headers sorted by name, each on its
+// own line.
+func azureCanonicalHeaders(header http.Header) string {
+	var names []string
+	for name := range header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.TrimSpace(header.Get(name)) + "\n")
+	}
+	return b.String()
+}
+
+// canonicalResource is the account followed by the encoded path of the
+// request, which with Azurite starts with the account again, and the query
+// parameters sorted by name.
+func (s *AzureBlobStore) canonicalResource(u *url.URL) string {
+	var b strings.Builder
+	b.WriteString("/" + s.account + u.EscapedPath())
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		b.WriteString("\n" + strings.ToLower(name) + ":" + strings.Join(values, ","))
+	}
+	return b.String()
+}
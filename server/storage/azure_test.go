@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// azuriteAccountKey is the well-known key of the devstoreaccount1 account of
+// the Azurite emulator.
+const azuriteAccountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+// azureExampleSignature signs the string with the key as the Shared Key and
+// SAS documentation of Azure Storage describes it.
+func azureExampleSignature(t *testing.T, stringToSign string) string {
+	t.Helper()
+	key, err := base64.StdEncoding.DecodeString(azuriteAccountKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// The strings to sign of the Shared Key examples of the Azure Storage
+// documentation: a Put Blob, a List Blobs with its query parameters, and a
+// Get Blob against the emulator, whose path repeats the account.
+func TestAzureSignatureMatchesSharedKeyExamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		account  string
+		method   string
+		rawURL   string
+		length   int64
+		header   map[string]string
+		want     string
+	}{
+		{
+			name:    "Put Blob",
+			account: "myaccount",
+			method:  http.MethodPut,
+			rawURL:  "https://myaccount.blob.core.windows.net/mycontainer/myblob",
+			length:  11,
+			header: map[string]string{
+				"Content-Type":   "text/plain; charset=UTF-8",
+				"X-Ms-Blob-Type": "BlockBlob",
+				"X-Ms-Date":      "Sun, 11 Oct 2009 21:49:13 GMT",
+				"X-Ms-Version":   "2009-09-19",
+			},
+			want: "PUT\n\n\n11\n\ntext/plain; charset=UTF-8\n\n\n\n\n\n\n" +
+				"x-ms-blob-type:BlockBlob\nx-ms-date:Sun, 11 Oct 2009 21:49:13 GMT\nx-ms-version:2009-09-19\n" +
+				"/myaccount/mycontainer/myblob",
+		},
+		{
+			name:    "List Blobs",
+			account: "myaccount",
+			method:  http.MethodGet,
+			rawURL:  "https://myaccount.blob.core.windows.net/mycontainer?restype=container&comp=list&include=metadata,snapshots",
+			header: map[string]string{
+				"X-Ms-Date":    "Fri, 26 Jun 2015 23:39:12 GMT",
+				"X-Ms-Version": "2015-02-21",
+			},
+			want: "GET\n\n\n\n\n\n\n\n\n\n\n\n" +
+				"x-ms-date:Fri, 26 Jun 2015 23:39:12 GMT\nx-ms-version:2015-02-21\n" +
+				"/myaccount/mycontainer\ncomp:list\ninclude:metadata,snapshots\nrestype:container",
+		},
+		{
+			name:    "Get Blob from the emulator",
+			account: "devstoreaccount1",
+			method:  http.MethodGet,
+			rawURL:  "http://127.0.0.1:10000/devstoreaccount1/mycontainer/photos/a%20b.jpg",
+			header: map[string]string{
+				"Range":        "bytes=0-9",
+				"X-Ms-Date":    "Fri, 26 Jun 2015 23:39:12 GMT",
+				"X-Ms-Version": "2021-08-06",
+			},
+			want: "GET\n\n\n\n\n\n\n\n\n\n\nbytes=0-9\n" +
+				"x-ms-date:Fri, 26 Jun 2015 23:39:12 GMT\nx-ms-version:2021-08-06\n" +
+				"/devstoreaccount1/devstoreaccount1/mycontainer/photos/a%20b.jpg",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobs, err := NewAzureBlobStore("", tt.account, azuriteAccountKey, "mycontainer")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(tt.method, tt.rawURL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.ContentLength = tt.length
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			if got, want := blobs.signature(req), azureExampleSignature(t, tt.want); got != want {
+				t.Errorf("signature = %s, want the signature of %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The fields of the string to sign of a service SAS of version 2020-12-06
+// and later, as listed by the documentation, for a download with a file
+// name.
+func TestAzurePresignGetMatchesServiceSASExample(t *testing.T) {
+	utcNow = func() time.Time { return time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC) }
+	defer func() { utcNow = func() time.Time { return time.Now().UTC() } }()
+
+	blobs, err := NewAzureBlobStore("", "myaccount", azuriteAccountKey, "mycontainer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := blobs.PresignGet(context.Background(), "exports/report 1.pdf", "report.pdf", time.Hour)
+	if err != nil {
+		t.Fatalf("PresignGet returned error: %v", err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "myaccount.blob.core.windows.net" || u.EscapedPath() != "/mycontainer/exports/report%201.pdf" {
+		t.Errorf("unexpected presigned link %s", link)
+	}
+
+	query := u.Query()
+	stringToSign := strings.Join([]string{
+		"r", "", "2013-05-24T01:00:00Z", "/blob/myaccount/mycontainer/exports/report 1.pdf",
+		"", "", "", "2021-08-06", "b", "", "",
+		"", `attachment; filename=report.pdf`, "", "", "",
+	}, "\n")
+	if query.Get("sv") != "2021-08-06" || query.Get("sr") != "b" || query.Get("sp") != "r" ||
+		query.Get("se") != "2013-05-24T01:00:00Z" || query.Get("rscd") != "attachment; filename=report.pdf" {
+		t.Errorf("unexpected SAS parameters %v", query)
+	}
+	if got, want := query.Get("sig"), azureExampleSignature(t, stringToSign); got != want {
+		t.Errorf("sig = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlobNotFound        = errors.New("blob not found")
+	ErrPresignNotSupported = errors.New("blob store does not support presigned links")
+)
+
+// BlobStore stores the files of the application, such as the uploaded
+// documents and the generated exports, under slash separated keys like
+// "uploads/<org>/<file>". Every replica of the server sees the same files.
+type BlobStore interface {
+	// Put stores size bytes read from r under the key, replacing any blob
+	// already stored under it.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under the key. It returns ErrBlobNotFound
+	// when there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	// Stat returns the blob stored under the key, or ErrBlobNotFound.
+	Stat(ctx context.Context, key string) (*BlobInfo, error)
+	// Delete removes the blob stored under the key. Removing a missing blob
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every blob whose key starts with the prefix.
+	DeletePrefix(ctx context.Context, prefix string) error
+	// List calls fn with every blob whose key starts with the prefix, in key
+	// order.
+	List(ctx context.Context, prefix string, fn func(info BlobInfo) error) error
+	// PresignGet returns a link that downloads the blob without further
+	// authentication until ttl has passed, as an attachment named fileName
+	// when it is set. It returns ErrPresignNotSupported when the store
+	// cannot sign links.
+	PresignGet(ctx context.Context, key string, fileName string, ttl time.Duration) (string, error)
+}
+
+type BlobInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Config selects and configures the backend of the blob store.
+type Config struct {
+	// Backend is "local", "s3" or "azure".
+	Backend  string
+	LocalDir string
+
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool
+
+	AzureAccount   string
+	AzureKey       string
+	AzureContainer string
+	AzureEndpoint  string
+}
+
+// NewBlobStore creates the blob store of the configured backend.
+func NewBlobStore(cfg Config) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalBlobStore(cfg.LocalDir), nil
+	case "s3":
+		return NewS3BlobStore(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey, cfg.S3PathStyle)
+	case "azure":
+		return NewAzureBlobStore(cfg.AzureEndpoint, cfg.AzureAccount, cfg.AzureKey, cfg.AzureContainer)
+	default:
+		return nil, fmt.Errorf("unknown blob store backend %q", cfg.Backend)
+	}
+}
+
+// checkKey rejects keys that are empty or that could escape the root of the
+// store, such as absolute paths or keys with "..".
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
+
+// checkPrefix is checkKey for key prefixes, which may end with a slash.
+func checkPrefix(prefix string) error {
+	return checkKey(strings.TrimSuffix(prefix, "/"))
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// blobServiceURL returns the address of a running object store from the
+// environment variable or skips the test.
+func blobServiceURL(t *testing.T, name string) string {
+	t.Helper()
+	rawURL := os.Getenv(name)
+	if rawURL == "" {
+		t.Skipf("%s is not set", name)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", name, err)
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, time.Second)
+	if err != nil {
+		t.Skipf("object store is not reachable at %s", rawURL)
+	}
+	conn.Close()
+	return rawURL
+}
+
+// testBlobStoreRoundTrip stores, reads, lists, links and deletes blobs under
+// a prefix of its own.
+func testBlobStoreRoundTrip(t *testing.T, blobs BlobStore) {
+	ctx := context.Background()
+	prefix := "uploads/" + bson.NewObjectID().Hex() + "/"
+	other := "uploads/" + bson.NewObjectID().Hex() + "/kept.pdf"
+	defer blobs.Delete(ctx, other)
+
+	for _, key := range []string{prefix + "a.pdf", prefix + "b c.jpg", other} {
+		if err := blobs.Put(ctx, key, strings.NewReader(key), int64(len(key)), "application/pdf"); err != nil {
+			t.Fatalf("Put %s returned error: %v", key, err)
+		}
+	}
+
+	body, info, err := blobs.Get(ctx, prefix+"a.pdf")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	content, _ := io.ReadAll(body)
+	body.Close()
+	if string(content) != prefix+"a.pdf" || info.Size != int64(len(content)) || info.ContentType != "application/pdf" {
+		t.Errorf("unexpected blob %q %+v", content, info)
+	}
+	if info, err := blobs.Stat(ctx, prefix+"b c.jpg"); err != nil || info.Size != int64(len(prefix+"b c.jpg")) {
+		t.Errorf("unexpected Stat %+v, err %v", info, err)
+	}
+
+	var listed []string
+	err = blobs.List(ctx, prefix, func(info BlobInfo) error {
+		listed = append(listed, info.Key)
+		return nil
+	})
+	if err != nil || strings.Join(listed, ",") != prefix+"a.pdf,"+prefix+"b c.jpg" {
+		t.Errorf("unexpected listing %v, err %v", listed, err)
+	}
+
+	link, err := blobs.PresignGet(ctx, prefix+"b c.jpg", "photo.jpg", time.Minute)
+	if err != nil {
+		t.Fatalf("PresignGet returned error: %v", err)
+	}
+	resp, err := http.Get(link)
+	if err != nil {
+		t.Fatalf("GET of the presigned link returned error: %v", err)
+	}
+	content, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(content) != prefix+"b c.jpg" ||
+		resp.Header.Get("Content-Disposition") != attachmentDisposition("photo.jpg") {
+		t.Errorf("unexpected download %s %q %v", resp.Status, content, resp.Header)
+	}
+
+	if err := blobs.DeletePrefix(ctx, prefix); err != nil {
+		t.Fatalf("DeletePrefix returned error: %v", err)
+	}
+	if _, err := blobs.Stat(ctx, prefix+"a.pdf"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound after DeletePrefix, got %v", err)
+	}
+	if _, _, err := blobs.Get(ctx, prefix+"b c.jpg"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound after DeletePrefix, got %v", err)
+	}
+	if _, err := blobs.Stat(ctx, other); err != nil {
+		t.Errorf("expected the blobs of other prefixes to be kept, got %v", err)
+	}
+	if err := blobs.Delete(ctx, prefix+"a.pdf"); err != nil {
+		t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+	}
+}
+
+// TestS3BlobStoreAgainstMinIO runs against a MinIO server at MINIO_TEST_URL
+// with its default credentials, such as one started by
+// docker run -p 9000:9000 minio/minio server /data.
+func TestS3BlobStoreAgainstMinIO(t *testing.T) {
+	endpoint := blobServiceURL(t, "MINIO_TEST_URL")
+	blobs, err := NewS3BlobStore(endpoint, "us-east-1", "exto-test", "minioadmin", "minioadmin", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, blobs.objectURL("", nil).String(), http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := blobs.do(req)
+	if err != nil {
+		t.Fatalf("creating the bucket returned error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		t.Fatal(responseError("s3", req.Method, "exto-test", resp))
+	}
+
+	testBlobStoreRoundTrip(t, blobs)
+}
+
+// TestAzureBlobStoreAgainstAzurite runs against the blob service of Azurite
+// at AZURITE_TEST_URL, like http://127.0.0.1:10000, with its well-known
+// account.
+func TestAzureBlobStoreAgainstAzurite(t *testing.T) {
+	endpoint := blobServiceURL(t, "AZURITE_TEST_URL")
+	blobs, err := NewAzureBlobStore(strings.TrimRight(endpoint, "/")+"/devstoreaccount1", "devstoreaccount1", azuriteAccountKey, "exto-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, blobs.blobURL("", url.Values{"restype": {"container"}}).String(), http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := blobs.do(req)
+	if err != nil {
+		t.Fatalf("creating the container returned error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		t.Fatal(responseError("azure", req.Method, "exto-test", resp))
+	}
+
+	testBlobStoreRoundTrip(t, blobs)
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// blobHTTPClient is shared by the backends that talk to an object store over
+// HTTP. Transfers of large blobs are bounded by the context of the request
+// rather than by a client timeout.
+var blobHTTPClient = &http.Client{}
+
+// uriEncode percent-encodes everything but the unreserved characters of RFC
+// 3986, keeping slashes when encodeSlash is false. Both S3 and Azure sign
+// the paths and query parameters encoded this way.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// attachmentDisposition is the Content-Disposition of a download named
+// fileName.
+func attachmentDisposition(fileName string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": fileName})
+}
+
+// responseBlobInfo reads the size, type and modification time of a blob from
+// the headers of a GET or HEAD response.
+func responseBlobInfo(key string, resp *http.Response) *BlobInfo {
+	info := &BlobInfo{Key: key, Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified
+	}
+	if info.ContentType == "" {
+		info.ContentType = "application/octet-stream"
+	}
+	return info
+}
+
+// responseError describes a failed request to an object store, with the
+// start of the error document it returned.
+func responseError(backend string, method string, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s %s %s: %s: %s", backend, method, key, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// utcNow is replaced by the tests of the request signatures.
+var utcNow = func() time.Time { return time.Now().UTC() }
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalBlobStore keeps the blobs in a directory of the local disk, the key
+// being the path of the file in it. It is meant for development and single
+// replica deployments.
+type LocalBlobStore struct {
+	root string
+}
+
+func NewLocalBlobStore(root string) *LocalBlobStore {
+	if root == "" {
+		root = "."
+	}
+	return &LocalBlobStore{root: root}
+}
+
+func (s *LocalBlobStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// Put writes the blob next to its final path and moves it in place once it
+// is complete, so that readers never see a partial file.
+func (s *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	dst := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), dst)
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error) {
+	if err := checkKey(key); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrBlobNotFound
+		}
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if !stat.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, ErrBlobNotFound
+	}
+	return file, localBlobInfo(key, stat.Size(), stat.ModTime()), nil
+}
+
+func (s *LocalBlobStore) Stat(ctx context.Context, key string) (*BlobInfo, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, err
+	}
+	if !stat.Mode().IsRegular() {
+		return nil, ErrBlobNotFound
+	}
+	return localBlobInfo(key, stat.Size(), stat.ModTime()), nil
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeletePrefix removes the whole directory when the prefix names one.
+func (s *LocalBlobStore) DeletePrefix(ctx context.Context, prefix string) error {
+	if err := checkPrefix(prefix); err != nil {
+		return err
+	}
+	if strings.HasSuffix(prefix, "/") {
+		return os.RemoveAll(s.path(prefix))
+	}
+	return s.List(ctx, prefix, func(info BlobInfo) error {
+		return s.Delete(ctx, info.Key)
+	})
+}
+
+func (s *LocalBlobStore) List(ctx context.Context, prefix string, fn func(info BlobInfo) error) error {
+	if err := checkPrefix(prefix); err != nil {
+		return err
+	}
+	// Walk the deepest directory that holds every key with the prefix.
+	dir := strings.TrimSuffix(prefix, "/")
+	if !strings.HasSuffix(prefix, "/") {
+		dir = path.Dir(dir)
+	}
+	root := s.path(dir)
+	if dir == "." {
+		root = s.root
+	}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || (strings.HasPrefix(d.Name(), ".") && strings.HasSuffix(d.Name(), ".tmp")) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		stat, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(*localBlobInfo(key, stat.Size(), stat.ModTime()))
+	})
+	if errors.Is(err, filepath.SkipDir) {
+		return nil
+	}
+	return err
+}
+
+// PresignGet is not supported: the files on the local disk are streamed by
+// the server.
+func (s *LocalBlobStore) PresignGet(ctx context.Context, key string, fileName string, ttl time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+func localBlobInfo(key string, size int64, modTime time.Time) *BlobInfo {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &BlobInfo{Key: key, Size: size, ContentType: contentType, LastModified: modTime}
+}
@@ -0,0 +1,66 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gaeaglobal/exto/server/storage"
+)
+
+func TestLocalBlobStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	blobs := storage.NewLocalBlobStore(t.TempDir())
+
+	for _, key := range []string{"uploads/org1/a.pdf", "uploads/org1/b.jpg", "uploads/org2/c.pdf"} {
+		if err := blobs.Put(ctx, key, strings.NewReader(key), int64(len(key)), ""); err != nil {
+			t.Fatalf("Put %s returned error: %v", key, err)
+		}
+	}
+
+	body, info, err := blobs.Get(ctx, "uploads/org1/a.pdf")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	content, _ := io.ReadAll(body)
+	body.Close()
+	if string(content) != "uploads/org1/a.pdf" || info.Size != int64(len(content)) || info.ContentType != "application/pdf" {
+		t.Errorf("unexpected blob %q %+v", content, info)
+	}
+
+	var listed []string
+	err = blobs.List(ctx, "uploads/org1/", func(info storage.BlobInfo) error {
+		listed = append(listed, info.Key)
+		return nil
+	})
+	if err != nil || strings.Join(listed, ",") != "uploads/org1/a.pdf,uploads/org1/b.jpg" {
+		t.Errorf("unexpected listing %v, err %v", listed, err)
+	}
+
+	if err := blobs.DeletePrefix(ctx, "uploads/org1/"); err != nil {
+		t.Fatalf("DeletePrefix returned error: %v", err)
+	}
+	if _, err := blobs.Stat(ctx, "uploads/org1/b.jpg"); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound after DeletePrefix, got %v", err)
+	}
+	if _, err := blobs.Stat(ctx, "uploads/org2/c.pdf"); err != nil {
+		t.Errorf("expected the blobs of other prefixes to be kept, got %v", err)
+	}
+	if err := blobs.Delete(ctx, "uploads/org1/a.pdf"); err != nil {
+		t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+	}
+	if _, err := blobs.PresignGet(ctx, "uploads/org2/c.pdf", "c.pdf", 0); !errors.Is(err, storage.ErrPresignNotSupported) {
+		t.Errorf("expected ErrPresignNotSupported, got %v", err)
+	}
+}
+
+func TestLocalBlobStoreRejectsEscapingKeys(t *testing.T) {
+	blobs := storage.NewLocalBlobStore(t.TempDir())
+	for _, key := range []string{"", "/etc/passwd", "uploads/../../etc/passwd", "uploads//a", `uploads\a`} {
+		if err := blobs.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+	s3MaxPresignTTL   = 7 * 24 * time.Hour
+)
+
+// S3BlobStore keeps the blobs in a bucket of Amazon S3 or of an S3
+// compatible store such as MinIO. Requests are signed with AWS Signature
+// Version 4; payloads are not hashed, which S3 allows on every request.
+type S3BlobStore struct {
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	accessKeyID     string
+	secretAccessKey string
+	pathStyle       bool
+}
+
+// NewS3BlobStore creates a store for the bucket. The endpoint defaults to
+// the one of AWS for the region; MinIO needs its own endpoint and path style
+// addressing, where the bucket is the first segment of the path rather than
+// a subdomain.
+func NewS3BlobStore(endpoint, region, bucket, accessKeyID, secretAccessKey string, pathStyle bool) (*S3BlobStore, error) {
+	if bucket == "" || accessKeyID == "" || secretAccessKey == "" {
+		return nil, errors.New("s3 blob store needs a bucket and credentials")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+	return &S3BlobStore{
+		endpoint:        u,
+		region:          region,
+		bucket:          bucket,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		pathStyle:       pathStyle,
+	}, nil
+}
+
+// objectURL returns the URL of the object with the key, or of the bucket
+// when the key is empty, with its path already encoded.
+func (s *S3BlobStore) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	path := u.Path + "/"
+	if s.pathStyle {
+		path += s.bucket + "/"
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	path += key
+	u.Path = path
+	u.RawPath = uriEncode(path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	body := r
+	if size == 0 {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key, nil).String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError("s3", req.Method, key, resp)
+	}
+	return nil
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error) {
+	if err := checkKey(key); err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, nil, ErrBlobNotFound
+		}
+		return nil, nil, responseError("s3", req.Method, key, resp)
+	}
+	return resp.Body, responseBlobInfo(key, resp), nil
+}
+
+func (s *S3BlobStore) Stat(ctx context.Context, key string) (*BlobInfo, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return responseBlobInfo(key, resp), nil
+	case http.StatusNotFound:
+		return nil, ErrBlobNotFound
+	default:
+		return nil, responseError("s3", req.Method, key, resp)
+	}
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError("s3", req.Method, key, resp)
+	}
+	return nil
+}
+
+func (s *S3BlobStore) DeletePrefix(ctx context.Context, prefix string) error {
+	if err := checkPrefix(prefix); err != nil {
+		return err
+	}
+	var keys []string
+	err := s.List(ctx, prefix, func(info BlobInfo) error {
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List pages through the keys with ListObjectsV2. S3 does not return the
+// content type of the objects it lists.
+func (s *S3BlobStore) List(ctx context.Context, prefix string, fn func(info BlobInfo) error) error {
+	if err := checkPrefix(prefix); err != nil {
+		return err
+	}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL("", query).String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := responseError("s3", "LIST", prefix, resp)
+			resp.Body.Close()
+			return err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("s3 LIST %s: %w", prefix, err)
+		}
+
+		for _, object := range result.Contents {
+			info := BlobInfo{Key: object.Key, Size: object.Size, LastModified: object.LastModified}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// PresignGet signs a GET of the object in the query string of its URL.
+func (s *S3BlobStore) PresignGet(ctx context.Context, key string, fileName string, ttl time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > s3MaxPresignTTL {
+		return "", fmt.Errorf("invalid presigned link lifetime %s", ttl)
+	}
+	now := utcNow()
+	query := url.Values{
+		"X-Amz-Algorithm":     {s3Algorithm},
+		"X-Amz-Credential":    {s.accessKeyID + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format(s3TimeFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(ttl.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if fileName != "" {
+		query.Set("response-content-disposition", attachmentDisposition(fileName))
+	}
+	u := s.objectURL(key, query)
+	header := http.Header{}
+	header.Set("Host", u.Host)
+	signature := s.signature(now, http.MethodGet, u, header, []string{"host"}, s3UnsignedPayload)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+// do signs the request in its Authorization header and sends it.
+func (s *S3BlobStore) do(req *http.Request) (*http.Response, error) {
+	now := utcNow()
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	req.Header.Set("Host", req.URL.Host)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	signature := s.signature(now, req.Method, req.URL, req.Header, signedHeaders, s3UnsignedPayload)
+	req.Header.Del("Host")
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKeyID, s.scope(now), strings.Join(signedHeaders, ";"), signature))
+	return blobHTTPClient.Do(req)
+}
+
+func (s *S3BlobStore) scope(now time.Time) string {
+	return now.Format(s3DateFormat) + "/" + s.region + "/s3/aws4_request"
+}
+
+// signature computes the Signature Version 4 of a request. signedHeaders
+// must be lower case and sorted.
+func (s *S3BlobStore) signature(now time.Time, method string, u *url.URL, header http.Header, signedHeaders []string, payloadHash string) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(header.Get(name)) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3TimeFormat),
+		s.scope(now),
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.Format(s3DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery encodes the query sorted by name, as both S3 and the
+// signature expect.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
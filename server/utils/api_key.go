@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix starts every organization API key so leaked keys are easy to
+// recognise. A key reads exto_<prefix>_<secret>; the prefix identifies the key
+// and only a hash of the secret is stored.
+const APIKeyPrefix = "exto"
+
+// NewAPIKey generates a key and returns it together with its prefix and the
+// hash of its secret.
+func NewAPIKey() (key string, prefix string, secretHash string, err error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(prefixBytes)
+	secret := hex.EncodeToString(secretBytes)
+	return APIKeyPrefix + "_" + prefix + "_" + secret, prefix, HashAPIKeySecret(secret), nil
+}
+
+// ParseAPIKey splits a key into its prefix and secret.
+func ParseAPIKey(key string) (prefix string, secret string, ok bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != APIKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAPIKeySecret compares the secret with the stored hash in constant time.
+func VerifyAPIKeySecret(secret string, secretHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKeySecret(secret)), []byte(secretHash)) == 1
+}
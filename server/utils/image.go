@@ -4,17 +4,16 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"image"
+	"image/draw"
 	"image/jpeg"
+	"image/png"
 
 	"github.com/disintegration/imaging"
 )
 
-func GetThumbnailImage(filePath string) (string, error) {
-	img, err := imaging.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open image: %w", err)
-	}
-
+func GetThumbnailImage(content []byte) (string, error) {
+	img, err := imaging.Decode(bytes.NewReader(content))
 	if err != nil {
 		return "", fmt.Errorf("failed to decode image: %w", err)
 	}
@@ -36,11 +35,11 @@ func GetThumbnailImage(filePath string) (string, error) {
 	return "data:image/jpeg;base64," + base64String, nil
 }
 
-// get base64 string from file path
-func GetBase64FromFilePath(filePath string) (string, error) {
-	img, err := imaging.Open(filePath)
+// get base64 string from the content of an image file
+func GetBase64FromImage(content []byte) (string, error) {
+	img, err := imaging.Decode(bytes.NewReader(content))
 	if err != nil {
-		return "", fmt.Errorf("failed to open image: %w", err)
+		return "", fmt.Errorf("failed to decode image: %w", err)
 	}
 
 	// Encode image to a buffer
@@ -72,3 +71,37 @@ func GetImageFromBase64(base64String string) ([]byte, error) {
 
 	return imageData, nil
 }
+
+// redactionPadding widens the redacted boxes by this fraction of the size of
+// the image on every side, as the boxes located by the model are approximate.
+const redactionPadding = 0.01
+
+// RedactImage blacks out the boxes of the image and returns it as a PNG. The
+// boxes are fractions of the width and height of the image: [x_min, y_min,
+// x_max, y_max].
+func RedactImage(content []byte, boxes [][]float64) ([]byte, error) {
+	img, err := imaging.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	redacted := imaging.Clone(img)
+	bounds := redacted.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	for _, box := range boxes {
+		if len(box) != 4 {
+			return nil, fmt.Errorf("invalid redaction box %v", box)
+		}
+		rect := image.Rect(
+			int((box[0]-redactionPadding)*w), int((box[1]-redactionPadding)*h),
+			int((box[2]+redactionPadding)*w+1), int((box[3]+redactionPadding)*h+1),
+		).Add(bounds.Min).Intersect(bounds)
+		draw.Draw(redacted, rect, image.Black, image.Point{}, draw.Src)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, redacted); err != nil {
+		return nil, fmt.Errorf("failed to encode redacted image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
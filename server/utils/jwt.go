@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// JWTIssuer is the issuer of the tokens signed by the server.
+const JWTIssuer = "exto"
+
+var (
+	ErrInvalidJWT = errors.New("invalid token")
+	ErrExpiredJWT = errors.New("token has expired")
+)
+
+// JWTClaims are the claims of the HS256 tokens issued by the server.
+type JWTClaims struct {
+	Subject   string `json:"sub"`
+	Email     string `json:"email"`
+	Type      string `json:"typ"`
+	ID        string `json:"jti,omitempty"`
+	Issuer    string `json:"iss"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+var hs256Header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// SignJWT signs the claims with HMAC-SHA256.
+func SignJWT(claims *JWTClaims, secret []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := hs256Header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + signJWTInput(signingInput, secret), nil
+}
+
+// ParseJWT verifies the signature, issuer and expiry of the token and returns
+// its claims.
+func ParseJWT(token string, secret []byte) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidJWT
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "HS256" {
+		return nil, ErrInvalidJWT
+	}
+
+	expected := signJWTInput(parts[0]+"."+parts[1], secret)
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidJWT
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+	var claims JWTClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidJWT
+	}
+	if claims.Issuer != JWTIssuer {
+		return nil, ErrInvalidJWT
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredJWT
+	}
+	return &claims, nil
+}
+
+func signJWTInput(signingInput string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}